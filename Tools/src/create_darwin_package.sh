@@ -0,0 +1,21 @@
+#!/usr/bin/env bash
+echo "*******************************************************"
+echo "Creating tar file for macOS amd64"
+echo "*******************************************************"
+
+DARWIN_DIR=${BGO_SPACE}/bin/darwin_amd64
+
+cp ${BGO_SPACE}/seelog_unix.xml ${DARWIN_DIR}/seelog.xml.template
+cp ${BGO_SPACE}/amazon-ssm-agent.json.template ${DARWIN_DIR}/
+cp ${BGO_SPACE}/packaging/darwin/com.amazon.aws.ssm.plist ${DARWIN_DIR}/
+cp ${BGO_SPACE}/Tools/src/update/darwin/install.sh ${DARWIN_DIR}/
+cp ${BGO_SPACE}/Tools/src/update/darwin/uninstall.sh ${DARWIN_DIR}/
+
+chmod 755 ${DARWIN_DIR}/install.sh ${DARWIN_DIR}/uninstall.sh
+chmod 755 ${DARWIN_DIR}/updater
+
+tar -zcvf ${BGO_SPACE}/bin/updates/amazon-ssm-agent/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-darwin-amd64.tar.gz -C ${DARWIN_DIR}/ amazon-ssm-agent ssm-cli ssm-document-worker amazon-ssm-agent.json.template seelog.xml.template com.amazon.aws.ssm.plist install.sh uninstall.sh
+tar -zcvf ${BGO_SPACE}/bin/updates/amazon-ssm-agent-updater/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-updater-darwin-amd64.tar.gz -C ${DARWIN_DIR}/ updater
+
+rm ${DARWIN_DIR}/install.sh
+rm ${DARWIN_DIR}/uninstall.sh
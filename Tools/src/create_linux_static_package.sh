@@ -0,0 +1,21 @@
+#!/usr/bin/env bash
+echo "*******************************************************"
+echo "Creating tar file for statically linked Linux amd64"
+echo "*******************************************************"
+
+STATIC_DIR=${BGO_SPACE}/bin/linux_static_amd64
+
+cp ${BGO_SPACE}/seelog_unix.xml ${STATIC_DIR}/seelog.xml.template
+cp ${BGO_SPACE}/amazon-ssm-agent.json.template ${STATIC_DIR}/
+cp ${BGO_SPACE}/packaging/alpine/amazon-ssm-agent.initd ${STATIC_DIR}/
+cp ${BGO_SPACE}/Tools/src/update/linux_static/install.sh ${STATIC_DIR}/
+cp ${BGO_SPACE}/Tools/src/update/linux_static/uninstall.sh ${STATIC_DIR}/
+
+chmod 755 ${STATIC_DIR}/install.sh ${STATIC_DIR}/uninstall.sh
+chmod 755 ${STATIC_DIR}/updater
+
+tar -zcvf ${BGO_SPACE}/bin/updates/amazon-ssm-agent/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-linux-static-amd64.tar.gz -C ${STATIC_DIR}/ amazon-ssm-agent ssm-cli ssm-document-worker amazon-ssm-agent.json.template seelog.xml.template amazon-ssm-agent.initd install.sh uninstall.sh
+tar -zcvf ${BGO_SPACE}/bin/updates/amazon-ssm-agent-updater/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-updater-linux-static-amd64.tar.gz -C ${STATIC_DIR}/ updater
+
+rm ${STATIC_DIR}/install.sh
+rm ${STATIC_DIR}/uninstall.sh
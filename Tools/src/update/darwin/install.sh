@@ -0,0 +1,55 @@
+#!/bin/sh
+
+# installs the agent on macOS and registers it with launchd.
+# System Integrity Protection keeps /usr/bin read-only, so the binaries are installed in /usr/local/bin.
+
+# helper function to set error output
+error_exit()
+{
+	echo "$1" 1>&2
+	exit 1
+}
+
+LAUNCHD_LABEL=com.amazon.aws.ssm
+LAUNCHD_PLIST=/Library/LaunchDaemons/$LAUNCHD_LABEL.plist
+
+# check parameters for registering managed instance
+DO_REGISTER=false
+if [ "$1" = "register-managed-instance" ]; then
+	if [ $# -eq 4 ]; then
+		DO_REGISTER=true
+		RMI_CODE=$2
+		RMI_ID=$3
+		RMI_REGION=$4
+	else
+		error_exit '[ERROR] Not enough parameters for RegisterManagedInstance.'
+	fi
+fi
+
+# allow ssm-agent to finish it's work
+sleep 2
+
+if launchctl list "$LAUNCHD_LABEL" > /dev/null 2>&1; then
+	echo "-> Agent is running in the instance"
+	echo "Stopping the agent"
+	launchctl unload "$LAUNCHD_PLIST"
+fi
+
+echo "Installing agent"
+mkdir -p /usr/local/bin /etc/amazon/ssm /var/lib/amazon/ssm || error_exit "Failed to create the agent directories"
+for binary in amazon-ssm-agent ssm-cli ssm-document-worker; do
+	install -m 755 "$binary" /usr/local/bin/ || error_exit "Failed to install $binary"
+done
+install -m 644 amazon-ssm-agent.json.template seelog.xml.template /etc/amazon/ssm/ || error_exit "Failed to install the configuration templates"
+if [ ! -f /etc/amazon/ssm/seelog.xml ]; then
+	cp /etc/amazon/ssm/seelog.xml.template /etc/amazon/ssm/seelog.xml
+fi
+install -m 644 -o root -g wheel "$LAUNCHD_LABEL.plist" "$LAUNCHD_PLIST" || error_exit "Failed to install the launchd job"
+
+if [ "$DO_REGISTER" = true ]; then
+	/usr/local/bin/amazon-ssm-agent -register -code "$RMI_CODE" -id "$RMI_ID" -region "$RMI_REGION"
+fi
+
+echo "Starting agent"
+launchctl load -w "$LAUNCHD_PLIST" || error_exit "Failed to start the agent"
+launchctl list "$LAUNCHD_LABEL"
@@ -0,0 +1,20 @@
+#!/bin/sh
+
+echo "Uninstalling Amazon-ssm-agent"
+
+LAUNCHD_PLIST=/Library/LaunchDaemons/com.amazon.aws.ssm.plist
+
+if [ ! -f /usr/local/bin/amazon-ssm-agent ]; then
+	echo "-> Agent is not installed in this instance"
+	exit 0
+fi
+
+echo "-> Agent is installed in this instance"
+if [ -f "$LAUNCHD_PLIST" ]; then
+	launchctl unload -w "$LAUNCHD_PLIST"
+	rm -f "$LAUNCHD_PLIST"
+fi
+
+echo "Uninstalling the agent"
+rm -f /usr/local/bin/amazon-ssm-agent /usr/local/bin/ssm-cli /usr/local/bin/ssm-document-worker
+sleep 1
@@ -0,0 +1,62 @@
+#!/bin/sh
+
+# installs the statically linked agent, used on platforms without rpm or deb such as alpine.
+# this script must run with busybox sh.
+
+# helper function to set error output
+error_exit()
+{
+	echo "$1" 1>&2
+	exit 1
+}
+
+# check parameters for registering managed instance
+DO_REGISTER=false
+if [ "$1" = "register-managed-instance" ]; then
+	if [ $# -eq 4 ]; then
+		DO_REGISTER=true
+		RMI_CODE=$2
+		RMI_ID=$3
+		RMI_REGION=$4
+	else
+		error_exit '[ERROR] Not enough parameters for RegisterManagedInstance.'
+	fi
+fi
+
+# allow ssm-agent to finish it's work
+sleep 2
+
+HAS_OPENRC=false
+if command -v rc-service > /dev/null 2>&1; then
+	HAS_OPENRC=true
+fi
+
+if [ "$HAS_OPENRC" = true ] && rc-service amazon-ssm-agent status > /dev/null 2>&1; then
+	echo "-> Agent is running in the instance"
+	echo "Stopping the agent"
+	rc-service amazon-ssm-agent stop
+fi
+
+echo "Installing agent"
+mkdir -p /usr/bin /etc/amazon/ssm /var/lib/amazon/ssm || error_exit "Failed to create the agent directories"
+for binary in amazon-ssm-agent ssm-cli ssm-document-worker; do
+	install -m 755 "$binary" /usr/bin/ || error_exit "Failed to install $binary"
+done
+install -m 644 amazon-ssm-agent.json.template seelog.xml.template /etc/amazon/ssm/ || error_exit "Failed to install the configuration templates"
+if [ ! -f /etc/amazon/ssm/seelog.xml ]; then
+	cp /etc/amazon/ssm/seelog.xml.template /etc/amazon/ssm/seelog.xml
+fi
+
+if [ "$DO_REGISTER" = true ]; then
+	amazon-ssm-agent -register -code "$RMI_CODE" -id "$RMI_ID" -region "$RMI_REGION"
+fi
+
+if [ "$HAS_OPENRC" = true ]; then
+	install -m 755 amazon-ssm-agent.initd /etc/init.d/amazon-ssm-agent || error_exit "Failed to install the init script"
+	rc-update add amazon-ssm-agent default
+	echo "Starting agent"
+	rc-service amazon-ssm-agent start
+	rc-service amazon-ssm-agent status
+else
+	echo "OpenRC was not detected, the agent must be started by the container entrypoint"
+fi
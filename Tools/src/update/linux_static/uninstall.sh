@@ -0,0 +1,19 @@
+#!/bin/sh
+
+echo "Uninstalling Amazon-ssm-agent"
+
+if [ ! -f /usr/bin/amazon-ssm-agent ]; then
+	echo "-> Agent is not installed in this instance"
+	exit 0
+fi
+
+echo "-> Agent is installed in this instance"
+if command -v rc-service > /dev/null 2>&1 && [ -f /etc/init.d/amazon-ssm-agent ]; then
+	rc-service amazon-ssm-agent stop
+	rc-update del amazon-ssm-agent default
+	rm -f /etc/init.d/amazon-ssm-agent
+fi
+
+echo "Uninstalling the agent"
+rm -f /usr/bin/amazon-ssm-agent /usr/bin/ssm-cli /usr/bin/ssm-document-worker
+sleep 1
@@ -31,6 +31,10 @@ call :UnZip "%AgentZipFile%" "%InstallingFolder%"
 echo [INFO] Copy uninstall.bat to %InstallingFolder%.
 if exist "%~dp0uninstall.bat" xcopy "%~dp0uninstall.bat" "%InstallingFolder%" /Y
 
+echo [INFO] Record the hashes of the installed binaries for the agent integrity check.
+"%InstallingFolder%\amazon-ssm-agent.exe" -integrityManifest
+if not %errorlevel% == 0 echo [WARN] Failed to record the agent integrity manifest.
+
 echo [INFO] Register %ServiceName% as Windows service.
 sc create %ServiceName% binpath= "%InstallingFolder%\amazon-ssm-agent.exe" start= auto displayname= "Amazon SSM Agent"
 if not %errorlevel% == 0 echo [ERROR] Failed to register %ServiceName% as Windows service. & exit /b 1
@@ -103,6 +103,13 @@ if($unpacked -and (Test-Path $ExtractedPackage)) {
 # Copy UnInstaller to the destination
 Copy-Item $UnInstaller $Destination -Force
 
+# Record the hashes of the installed binaries for the agent integrity check
+Log-Info("Recording agent integrity manifest")
+Invoke-Expression "& '$Executable' -integrityManifest"
+if($LASTEXITCODE -gt 0) {
+    Log-Warning("Failed to record the agent integrity manifest")
+}
+
 # Check if register is set in argument
 if($Register) {
     # Start RegisterManagedInstance process
@@ -17,16 +17,20 @@ package main
 import (
 	"os"
 	"os/signal"
-	"path/filepath"
 	"runtime"
 	"syscall"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/auditlog"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/framework/coremanager"
 	"github.com/aws/amazon-ssm-agent/agent/health"
+	"github.com/aws/amazon-ssm-agent/agent/health/checkpoint"
+	"github.com/aws/amazon-ssm-agent/agent/health/watchdog"
 	"github.com/aws/amazon-ssm-agent/agent/hibernation"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 )
 
@@ -37,19 +41,23 @@ const (
 	registerFlag            = "register"
 	fingerprintFlag         = "fingerprint"
 	similarityThresholdFlag = "similarityThreshold"
+	integrityManifestFlag   = "integrityManifest"
 )
 
 var (
 	instanceIDPtr, regionPtr             *string
 	activationCode, activationID, region string
 	register, clear, force, fpFlag       bool
+	integrityManifest                    bool
 	similarityThreshold                  int
-	registrationFile                     = filepath.Join(appconfig.DefaultDataStorePath, "registration")
 )
 
 func start(log logger.T, instanceIDPtr *string, regionPtr *string) (cpm *coremanager.CoreManager, err error) {
 	log.Infof("Starting Agent: %v", version.String())
 	log.Infof("OS: %s, Arch: %s", runtime.GOOS, runtime.GOARCH)
+	if environment, envErr := platform.Environment(log); envErr == nil {
+		log.Infof("Environment: %s", environment)
+	}
 	log.Flush()
 
 	defer func() {
@@ -65,6 +73,13 @@ func start(log logger.T, instanceIDPtr *string, regionPtr *string) (cpm *coreman
 		return
 	}
 	cpm.Start()
+	checkpoint.Record(log, checkpoint.Registered)
+
+	if config, configErr := appconfig.Config(false); configErr == nil {
+		watchdog.Start(log, config)
+		auditlog.Open(log, config)
+		auditlog.AgentStarted(log)
+	}
 	return
 }
 
@@ -88,7 +103,10 @@ func blockUntilSignaled(log logger.T) {
 func stop(log logger.T, cpm *coremanager.CoreManager) {
 	log.Info("Stopping agent")
 	log.Flush()
+	watchdog.Stop()
 	cpm.Stop()
+	auditlog.AgentStopped(log)
+	auditlog.Close(log)
 	log.Info("Bye.")
 	log.Flush()
 }
@@ -104,6 +122,20 @@ func run(log logger.T) {
 		log.Debugf("appconfig could not be loaded - %v", err)
 		return
 	}
+	// Refuse to start if the agent binaries do not match the install time manifest in enforce mode.
+	if err = integrity.CheckAgent(log, config); err != nil {
+		log.Errorf("amazon-ssm-agent failed the integrity check - %v", err)
+		return
+	}
+
+	// the instance id and region overrides must be applied before the first health ping
+	if regionPtr != nil && *regionPtr != "" {
+		platform.SetRegion(*regionPtr)
+	}
+	if instanceIDPtr != nil && *instanceIDPtr != "" {
+		platform.SetInstanceID(*instanceIDPtr)
+	}
+
 	context := context.Default(log, config) // Add instanceID to context
 	//Initializing the health module to send empty health pings to the service.
 	healthModule := health.NewHealthCheck(context)
@@ -16,18 +16,16 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"strings"
 
-	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/fingerprint"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/activation"
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
-	"github.com/aws/amazon-ssm-agent/agent/ssm/anonauth"
 )
 
 // parseFlags displays flags and handles them
@@ -52,17 +50,30 @@ func parseFlags(log logger.T) {
 	flag.BoolVar(&fpFlag, fingerprintFlag, false, "")
 	flag.IntVar(&similarityThreshold, similarityThresholdFlag, 40, "")
 
+	// integrity manifest generation, run at install time
+	flag.BoolVar(&integrityManifest, integrityManifestFlag, false, "")
+
 	// force flag
 	flag.BoolVar(&force, "y", false, "")
 
 	flag.Parse()
 
-	if flag.NFlag() > 0 {
+	// the instance id and region overrides are used by the running agent, any other flag is a command
+	commandFlags := 0
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "i" && f.Name != "r" {
+			commandFlags++
+		}
+	})
+
+	if commandFlags > 0 {
 		exitCode := 1
 		if register {
 			exitCode = processRegistration(log)
 		} else if fpFlag {
 			exitCode = processFingerprint(log)
+		} else if integrityManifest {
+			exitCode = processIntegrityManifest(log)
 		} else {
 			flagUsage()
 		}
@@ -80,6 +91,7 @@ func flagUsage() {
 	fmt.Fprintln(os.Stderr, "\t\t-code\tSSM activation code\t(REQUIRED)")
 	fmt.Fprintln(os.Stderr, "\t\t-region\tSSM region       \t(REQUIRED)")
 	fmt.Fprintln(os.Stderr, "\n\t\t-clear\tClears the previously saved SSM registration")
+	fmt.Fprintln(os.Stderr, "\n\t-integrityManifest\trecord the hashes of the agent binaries for the startup integrity check")
 	fmt.Fprintln(os.Stderr, "\n\t-y\tAnswer yes for all questions")
 }
 
@@ -108,7 +120,7 @@ func processRegistration(log logger.T) (exitCode int) {
 		}
 	}
 
-	managedInstanceID, err := registerManagedInstance()
+	managedInstanceID, err := activation.Register(activationCode, activationID, region)
 	if err != nil {
 		log.Errorf("Registration failed due to %v", err)
 		return 1
@@ -128,61 +140,19 @@ func processFingerprint(log logger.T) (exitCode int) {
 	return 0
 }
 
-// registerManagedInstance checks for activation credentials and performs managed instance registration when present
-func registerManagedInstance() (managedInstanceID string, err error) {
-	// try to activate the instance with the activation credentials
-	publicKey, privateKey, keyType, err := registration.GenerateKeyPair()
-	if err != nil {
-		return managedInstanceID, fmt.Errorf("error generating signing keys. %v", err)
-	}
-
-	// checking write access before registering
-	err = registration.UpdateServerInfo("", "", privateKey, keyType)
-	if err != nil {
-		return managedInstanceID,
-			fmt.Errorf("Unable to save registration information. %v\nTry running as sudo/administrator.", err)
-	}
-
-	// generate fingerprint
-	fingerprint, err := registration.Fingerprint()
-	if err != nil {
-		return managedInstanceID, fmt.Errorf("error generating instance fingerprint. %v", err)
-	}
-
-	service := anonauth.NewAnonymousService(region)
-	managedInstanceID, err = service.RegisterManagedInstance(
-		activationCode,
-		activationID,
-		publicKey,
-		keyType,
-		fingerprint,
-	)
-
+// processIntegrityManifest records the hashes of the installed agent binaries
+func processIntegrityManifest(log logger.T) (exitCode int) {
+	binaries, err := integrity.Binaries()
 	if err != nil {
-		return managedInstanceID, fmt.Errorf("error registering the instance with AWS SSM. %v", err)
-	}
-
-	err = registration.UpdateServerInfo(managedInstanceID, region, privateKey, keyType)
-	if err != nil {
-		return managedInstanceID, fmt.Errorf("error persisting the instance registration information. %v", err)
-	}
-
-	// saving registration information to the registration file
-	reg := map[string]string{
-		"ManagedInstanceID": managedInstanceID,
-		"Region":            region,
-	}
-
-	var regData []byte
-	if regData, err = json.Marshal(reg); err != nil {
-		return "", fmt.Errorf("Failed to marshal registration info. %v", err)
+		log.Errorf("Error locating the agent binaries. %v", err)
+		return 1
 	}
-
-	if err = ioutil.WriteFile(registrationFile, regData, appconfig.ReadWriteAccess); err != nil {
-		return "", fmt.Errorf("Failed to write registration info to file. %v", err)
+	if err = integrity.WriteManifest(log, integrity.ManifestPath(), binaries); err != nil {
+		log.Errorf("Error writing the integrity manifest. %v", err)
+		return 1
 	}
-
-	return managedInstanceID, nil
+	log.Infof("Integrity manifest written to %v", integrity.ManifestPath())
+	return 0
 }
 
 // clearRegistration clears any existing registration data
@@ -203,7 +203,8 @@ loop:
 			continue loop
 		}
 	}
-	s <- svc.Status{State: svc.StopPending}
+	// the running documents get the shutdown grace period to finish
+	s <- svc.Status{State: svc.StopPending, WaitHint: uint32(cpm.StopTimeout() / time.Millisecond)}
 	stop(a.log, cpm)
 	return false, appconfig.SuccessExitCode
 }
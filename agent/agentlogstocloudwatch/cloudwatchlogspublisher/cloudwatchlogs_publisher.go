@@ -21,6 +21,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogsqueue"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/lowpriority"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 )
 
@@ -194,7 +195,7 @@ func (cloudwatchPublisher *CloudWatchPublisher) startPolling(sequenceToken, sequ
 	// Create a ticker for every second
 	cloudwatchPublisher.publisherTicker = time.NewTicker(cloudwatchPublisher.QueuePollingInterval)
 
-	go func() {
+	lowpriority.Go(cloudwatchPublisher.log, func() {
 		for range cloudwatchPublisher.publisherTicker.C {
 
 			//Check If Messages are in the Queue. If Messages are there continue to Push them to CW until empty
@@ -228,7 +229,7 @@ func (cloudwatchPublisher *CloudWatchPublisher) startPolling(sequenceToken, sequ
 				}
 			}
 		}
-	}()
+	})
 }
 
 // getSharingConfigurations gets the sharing configurations structure. Returns nil if configurations incorrect
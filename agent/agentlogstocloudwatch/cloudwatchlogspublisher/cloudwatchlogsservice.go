@@ -39,6 +39,7 @@ const (
 type CloudWatchLogsService struct {
 	cloudWatchLogsClient cloudwatchlogsinterface.CloudWatchLogsClient
 	stopPolicy           *sdkutil.StopPolicy
+	clientConfig         *aws.Config
 }
 
 // createCloudWatchStopPolicy creates a new policy for cloudwatchlogs
@@ -58,6 +59,17 @@ func createCloudWatchClient() cloudwatchlogsinterface.CloudWatchLogsClient {
 	return cloudwatchlogs.New(sess)
 }
 
+// createCloudWatchClientWithConfig creates a client to call CloudWatchLogs APIs using the given aws configuration
+func createCloudWatchClientWithConfig(config *aws.Config) cloudwatchlogsinterface.CloudWatchLogsClient {
+	//Adding the AWS SDK Retrier with Exponential Backoff
+	config = request.WithRetryer(config.Copy(), client.DefaultRetryer{
+		NumMaxRetries: maxRetries,
+	})
+
+	sess := session.New(config)
+	return cloudwatchlogs.New(sess)
+}
+
 // createCloudWatchClientWithCredentials creates a client to call CloudWatchLogs APIs using credentials from the id and secret passed
 func createCloudWatchClientWithCredentials(id, secret string) cloudwatchlogsinterface.CloudWatchLogsClient {
 	config := sdkutil.AwsConfig().WithCredentials(credentials.NewStaticCredentials(id, secret, ""))
@@ -88,6 +100,17 @@ func NewCloudWatchLogsServiceWithCredentials(id, secret string) *CloudWatchLogsS
 	return &cloudWatchLogsService
 }
 
+// NewCloudWatchLogsServiceWithConfig Creates a new instance of the CloudWatchLogsService using the given aws configuration,
+// which allows to publish to a region or with credentials other than the ones of the agent
+func NewCloudWatchLogsServiceWithConfig(config *aws.Config) *CloudWatchLogsService {
+	cloudWatchLogsService := CloudWatchLogsService{
+		cloudWatchLogsClient: createCloudWatchClientWithConfig(config),
+		stopPolicy:           createCloudWatchStopPolicy(),
+		clientConfig:         config,
+	}
+	return &cloudWatchLogsService
+}
+
 // CreateNewServiceIfUnHealthy checks service healthy and create new service if original is unhealthy
 func (service *CloudWatchLogsService) CreateNewServiceIfUnHealthy() {
 	if service.stopPolicy == nil {
@@ -96,7 +119,11 @@ func (service *CloudWatchLogsService) CreateNewServiceIfUnHealthy() {
 
 	if !service.stopPolicy.IsHealthy() {
 		service.stopPolicy.ResetErrorCount()
-		service.cloudWatchLogsClient = createCloudWatchClient()
+		if service.clientConfig != nil {
+			service.cloudWatchLogsClient = createCloudWatchClientWithConfig(service.clientConfig)
+		} else {
+			service.cloudWatchLogsClient = createCloudWatchClient()
+		}
 		return
 	}
 }
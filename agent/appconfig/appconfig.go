@@ -101,13 +101,15 @@ func getAppConfigPath() (path string, err error) {
 func DefaultConfig() SsmagentConfig {
 
 	var credsProfile = CredentialProfile{
-		ShareCreds: true,
+		ShareCreds:      true,
+		RoleSessionName: DefaultRoleSessionName,
 	}
 	var s3 S3Cfg
 	var mds = MdsCfg{
-		CommandWorkersLimit: DefaultCommandWorkersLimit,
-		StopTimeoutMillis:   DefaultStopTimeoutMillis,
-		CommandRetryLimit:   DefaultCommandRetryLimit,
+		CommandWorkersLimit:    DefaultCommandWorkersLimit,
+		StopTimeoutMillis:      DefaultStopTimeoutMillis,
+		CommandRetryLimit:      DefaultCommandRetryLimit,
+		MaxConcurrentDocuments: DefaultMaxConcurrentDocuments,
 	}
 	var ssm = SsmCfg{
 		HealthFrequencyMinutes:                DefaultSsmHealthFrequencyMinutes,
@@ -116,25 +118,67 @@ func DefaultConfig() SsmagentConfig {
 		CustomInventoryDefaultLocation:        DefaultCustomInventoryFolder,
 		AssociationLogsRetentionDurationHours: DefaultAssociationLogsRetentionDurationHours,
 		RunCommandLogsRetentionDurationHours:  DefaultRunCommandLogsRetentionDurationHours,
+		AssociationLogsRetentionCount:         DefaultAssociationLogsRetentionCount,
+		RunCommandLogsRetentionCount:          DefaultRunCommandLogsRetentionCount,
+		DocumentStateRetentionDurationHours:   DefaultDocumentStateRetentionDurationHours,
+		DocumentStateRetentionCount:           DefaultDocumentStateRetentionCount,
+		RetentionPruneFrequencyMinutes:        DefaultRetentionPruneFrequencyMinutes,
+		OrchestrationRetentionMaxSizeMB:       DefaultOrchestrationRetentionMaxSizeMB,
+		RetainedPluginOutputs:                 []string{},
+		InventoryFullRefreshIntervalHours:     DefaultInventoryFullRefreshIntervalHours,
+		AssociationMaxConcurrency:             DefaultSsmAssociationMaxConcurrency,
+		ExecutionHistoryCount:                 DefaultExecutionHistoryCount,
+		MaxDocumentReboots:                    DefaultMaxDocumentReboots,
 	}
 	var agent = AgentInfo{
-		Name:                 "amazon-ssm-agent",
-		OrchestrationRootDir: defaultOrchestrationRootDirName,
+		Name:                          "amazon-ssm-agent",
+		OrchestrationRootDir:          defaultOrchestrationRootDirName,
+		IntegrityCheckMode:            IntegrityCheckModeWarn,
+		VerificationPolicy:            VerificationPolicyNone,
+		UpdateHealthCheckMinutes:      DefaultUpdateHealthCheckMinutes,
+		HibernationMinIntervalSeconds: DefaultHibernationMinIntervalSeconds,
+		HibernationMaxIntervalSeconds: DefaultHibernationMaxIntervalSeconds,
+		WatchdogMaxGoroutines:         DefaultWatchdogMaxGoroutines,
+		ShutdownGracePeriodSeconds:    DefaultShutdownGracePeriodSeconds,
+		RetryMode:                     RetryModeStandard,
+		MaxAttempts:                   DefaultMaxAttempts,
+		DownloadConcurrency:           DefaultDownloadConcurrency,
+		DownloadPartSizeMB:            DefaultDownloadPartSizeMB,
+		Ec2MetadataEndpointMode:       Ec2MetadataEndpointModeIPv4,
+		ScriptSandboxProfile:          DefaultScriptSandboxProfile,
 	}
 	var os = OsInfo{
 		Lang:    "en-US",
 		Version: "1",
 	}
+	var metrics = MetricsCfg{
+		Namespace:              DefaultMetricsNamespace,
+		PublishIntervalSeconds: DefaultMetricsPublishIntervalSeconds,
+	}
+	var statsd = StatsdCfg{
+		ListenAddress:        DefaultStatsdListenAddress,
+		Namespace:            DefaultStatsdNamespace,
+		Dimensions:           map[string]string{},
+		FlushIntervalSeconds: DefaultStatsdFlushIntervalSeconds,
+	}
 	var birdwatcher BirdwatcherCfg
 
 	var ssmagentCfg = SsmagentConfig{
-		Profile:     credsProfile,
-		Mds:         mds,
-		Ssm:         ssm,
-		Agent:       agent,
-		Os:          os,
-		S3:          s3,
-		Birdwatcher: birdwatcher,
+		Profile:           credsProfile,
+		Mds:               mds,
+		Ssm:               ssm,
+		Agent:             agent,
+		Os:                os,
+		S3:                s3,
+		Endpoints:         EndpointsCfg{Failover: EndpointsFailoverCfg{FailbackMinutes: DefaultFailbackMinutes}},
+		Tls:               TlsCfg{MinVersion: DefaultTlsMinVersion},
+		Registration:      RegistrationCfg{ReactivationIntervalMinutes: DefaultReactivationIntervalMinutes},
+		Metrics:           metrics,
+		Statsd:            statsd,
+		Notifications:     NotificationsCfg{TimeoutSeconds: DefaultNotificationTimeoutSeconds},
+		ScriptCredentials: ScriptCredentialsCfg{DurationSeconds: DefaultScriptCredentialsDurationSeconds},
+		Plugins:           PluginsCfg{Timeouts: map[string]PluginTimeoutCfg{}, ResourceLimits: map[string]PluginResourceLimitsCfg{}},
+		Birdwatcher:       birdwatcher,
 	}
 
 	return ssmagentCfg
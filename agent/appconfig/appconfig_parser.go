@@ -28,6 +28,81 @@ func parser(config *SsmagentConfig) {
 	config.Agent.Name = getStringValue(config.Agent.Name, DefaultAgentName)
 	config.Agent.OrchestrationRootDir = getStringValue(config.Agent.OrchestrationRootDir, defaultOrchestrationRootDirName)
 	config.Agent.Region = getStringValue(config.Agent.Region, "")
+	config.Agent.IntegrityCheckMode = getIntegrityCheckMode(config.Agent.IntegrityCheckMode)
+	config.Agent.VerificationPolicy = getVerificationPolicy(config.Agent.VerificationPolicy)
+	config.Agent.LogSink = getLogSink(config.Agent.LogSink)
+	config.Agent.RetryMode = getRetryMode(config.Agent.RetryMode)
+	config.Agent.Ec2MetadataEndpointMode = getEc2MetadataEndpointMode(config.Agent.Ec2MetadataEndpointMode)
+	config.Agent.ScriptSandbox = getScriptSandbox(config.Agent.ScriptSandbox)
+	config.Agent.ScriptSandboxProfile = getStringValue(strings.TrimSpace(config.Agent.ScriptSandboxProfile), DefaultScriptSandboxProfile)
+	config.Agent.UpdateHealthCheckMinutes = getNumericValue(
+		config.Agent.UpdateHealthCheckMinutes,
+		DefaultUpdateHealthCheckMinutesMin,
+		DefaultUpdateHealthCheckMinutesMax,
+		DefaultUpdateHealthCheckMinutes)
+	config.Agent.DownloadConcurrency = getNumericValue(
+		config.Agent.DownloadConcurrency,
+		DefaultDownloadConcurrencyMin,
+		DefaultDownloadConcurrencyMax,
+		DefaultDownloadConcurrency)
+	config.Agent.DownloadPartSizeMB = getNumericValue(
+		config.Agent.DownloadPartSizeMB,
+		DefaultDownloadPartSizeMBMin,
+		DefaultDownloadPartSizeMBMax,
+		DefaultDownloadPartSizeMB)
+	config.Agent.DownloadBandwidthLimitKBps = getNumericValue(
+		config.Agent.DownloadBandwidthLimitKBps,
+		DefaultBandwidthLimitKBpsMin,
+		DefaultBandwidthLimitKBpsMax,
+		DefaultBandwidthLimitKBps)
+	config.Agent.UploadBandwidthLimitKBps = getNumericValue(
+		config.Agent.UploadBandwidthLimitKBps,
+		DefaultBandwidthLimitKBpsMin,
+		DefaultBandwidthLimitKBpsMax,
+		DefaultBandwidthLimitKBps)
+	config.Agent.UpdateWindow.SplayMinutes = getNumericValue(
+		config.Agent.UpdateWindow.SplayMinutes,
+		DefaultUpdateWindowSplayMinutesMin,
+		DefaultUpdateWindowSplayMinutesMax,
+		DefaultUpdateWindowSplayMinutes)
+	config.Agent.HibernationMinIntervalSeconds = getNumericValue(
+		config.Agent.HibernationMinIntervalSeconds,
+		DefaultHibernationMinIntervalSecondsMin,
+		DefaultHibernationMinIntervalSecondsMax,
+		DefaultHibernationMinIntervalSeconds)
+	config.Agent.HibernationMaxIntervalSeconds = getNumericValue(
+		config.Agent.HibernationMaxIntervalSeconds,
+		DefaultHibernationMaxIntervalSecondsMin,
+		DefaultHibernationMaxIntervalSecondsMax,
+		DefaultHibernationMaxIntervalSeconds)
+	if config.Agent.HibernationMaxIntervalSeconds < config.Agent.HibernationMinIntervalSeconds {
+		config.Agent.HibernationMaxIntervalSeconds = config.Agent.HibernationMinIntervalSeconds
+	}
+	config.Agent.FingerprintSimilarityThreshold = getNumericValue(
+		config.Agent.FingerprintSimilarityThreshold,
+		DefaultFingerprintSimilarityThresholdMin,
+		DefaultFingerprintSimilarityThresholdMax,
+		DefaultFingerprintSimilarityThreshold)
+	config.Agent.WatchdogMaxGoroutines = getNumericValue(
+		config.Agent.WatchdogMaxGoroutines,
+		DefaultWatchdogMaxGoroutinesMin,
+		DefaultWatchdogMaxGoroutinesMax,
+		DefaultWatchdogMaxGoroutines)
+	config.Agent.ShutdownGracePeriodSeconds = getNumericValue(
+		config.Agent.ShutdownGracePeriodSeconds,
+		DefaultShutdownGracePeriodSecondsMin,
+		DefaultShutdownGracePeriodSecondsMax,
+		DefaultShutdownGracePeriodSeconds)
+	config.Agent.MaxAttempts = getNumericValue(
+		config.Agent.MaxAttempts,
+		DefaultMaxAttemptsMin,
+		DefaultMaxAttemptsMax,
+		DefaultMaxAttempts)
+
+	// Profile config
+	config.Profile.RoleArn = strings.TrimSpace(config.Profile.RoleArn)
+	config.Profile.ExternalId = strings.TrimSpace(config.Profile.ExternalId)
+	config.Profile.RoleSessionName = getStringValue(strings.TrimSpace(config.Profile.RoleSessionName), DefaultRoleSessionName)
 
 	// MDS config
 	config.Mds.CommandWorkersLimit = getNumericValue(
@@ -45,10 +120,25 @@ func parser(config *SsmagentConfig) {
 		DefaultStopTimeoutMillisMin,
 		DefaultStopTimeoutMillisMax,
 		DefaultStopTimeoutMillis)
+	config.Mds.MaxConcurrentDocuments = getNumericValueAboveMin(
+		config.Mds.MaxConcurrentDocuments,
+		0,
+		DefaultMaxConcurrentDocuments)
 	config.Mds.Endpoint = getStringValue(config.Mds.Endpoint, "")
 
 	// SSM config
 	config.Ssm.Endpoint = getStringValue(config.Ssm.Endpoint, "")
+
+	// Endpoints config
+	config.Endpoints.Failover.Region = strings.TrimSpace(config.Endpoints.Failover.Region)
+	config.Endpoints.Failover.FailbackMinutes = getNumericValue(
+		config.Endpoints.Failover.FailbackMinutes,
+		DefaultFailbackMinutesMin,
+		DefaultFailbackMinutesMax,
+		DefaultFailbackMinutes)
+	config.Ssm.CustomInventoryDefaultLocation = getStringValue(
+		config.Ssm.CustomInventoryDefaultLocation,
+		DefaultCustomInventoryFolder)
 	config.Ssm.HealthFrequencyMinutes = getNumericValue(
 		config.Ssm.HealthFrequencyMinutes,
 		DefaultSsmHealthFrequencyMinutesMin,
@@ -67,7 +157,130 @@ func parser(config *SsmagentConfig) {
 		config.Ssm.RunCommandLogsRetentionDurationHours,
 		DefaultStateOrchestrationLogsRetentionDurationHoursMin,
 		DefaultRunCommandLogsRetentionDurationHours)
+	config.Ssm.DocumentStateRetentionDurationHours = getNumericValueAboveMin(
+		config.Ssm.DocumentStateRetentionDurationHours,
+		DefaultStateOrchestrationLogsRetentionDurationHoursMin,
+		DefaultDocumentStateRetentionDurationHours)
+	config.Ssm.AssociationLogsRetentionCount = getNumericValueAboveMin(
+		config.Ssm.AssociationLogsRetentionCount,
+		0,
+		DefaultAssociationLogsRetentionCount)
+	config.Ssm.RunCommandLogsRetentionCount = getNumericValueAboveMin(
+		config.Ssm.RunCommandLogsRetentionCount,
+		0,
+		DefaultRunCommandLogsRetentionCount)
+	config.Ssm.DocumentStateRetentionCount = getNumericValueAboveMin(
+		config.Ssm.DocumentStateRetentionCount,
+		0,
+		DefaultDocumentStateRetentionCount)
+	config.Ssm.ExecutionHistoryCount = getNumericValueAboveMin(
+		config.Ssm.ExecutionHistoryCount,
+		0,
+		DefaultExecutionHistoryCount)
+	config.Ssm.MaxDocumentReboots = getNumericValue(
+		config.Ssm.MaxDocumentReboots,
+		DefaultMaxDocumentRebootsMin,
+		DefaultMaxDocumentRebootsMax,
+		DefaultMaxDocumentReboots)
+	config.Ssm.OrchestrationRetentionMaxSizeMB = getNumericValueAboveMin(
+		config.Ssm.OrchestrationRetentionMaxSizeMB,
+		0,
+		DefaultOrchestrationRetentionMaxSizeMB)
+	config.Ssm.RetentionPruneFrequencyMinutes = getNumericValue(
+		config.Ssm.RetentionPruneFrequencyMinutes,
+		DefaultRetentionPruneFrequencyMinutesMin,
+		DefaultRetentionPruneFrequencyMinutesMax,
+		DefaultRetentionPruneFrequencyMinutes)
+	config.Ssm.InventoryFullRefreshIntervalHours = getNumericValue(
+		config.Ssm.InventoryFullRefreshIntervalHours,
+		DefaultInventoryFullRefreshIntervalHoursMin,
+		DefaultInventoryFullRefreshIntervalHoursMax,
+		DefaultInventoryFullRefreshIntervalHours)
+	config.Ssm.AssociationMaxConcurrency = getNumericValue(
+		config.Ssm.AssociationMaxConcurrency,
+		DefaultSsmAssociationMaxConcurrencyMin,
+		DefaultSsmAssociationMaxConcurrencyMax,
+		DefaultSsmAssociationMaxConcurrency)
+	config.Ssm.AssociationErrorThreshold = getNumericValueAboveMin(
+		config.Ssm.AssociationErrorThreshold,
+		0,
+		0)
+
+	// TLS config
+	config.Tls.MinVersion = getTlsMinVersion(config.Tls.MinVersion)
+
+	// Registration config
+	config.Registration.ReactivationIntervalMinutes = getNumericValue(
+		config.Registration.ReactivationIntervalMinutes,
+		DefaultReactivationIntervalMinutesMin,
+		DefaultReactivationIntervalMinutesMax,
+		DefaultReactivationIntervalMinutes)
+
+	// Metrics config
+	config.Metrics.Namespace = getStringValue(config.Metrics.Namespace, DefaultMetricsNamespace)
+	config.Metrics.PublishIntervalSeconds = getNumericValue(
+		config.Metrics.PublishIntervalSeconds,
+		DefaultMetricsPublishIntervalSecondsMin,
+		DefaultMetricsPublishIntervalSecondsMax,
+		DefaultMetricsPublishIntervalSeconds)
+
+	// Statsd config
+	config.Statsd.ListenAddress = getStringValue(config.Statsd.ListenAddress, DefaultStatsdListenAddress)
+	config.Statsd.Namespace = getStringValue(config.Statsd.Namespace, DefaultStatsdNamespace)
+	if config.Statsd.Dimensions == nil {
+		config.Statsd.Dimensions = map[string]string{}
+	}
+	config.Statsd.FlushIntervalSeconds = getNumericValue(
+		config.Statsd.FlushIntervalSeconds,
+		DefaultStatsdFlushIntervalSecondsMin,
+		DefaultStatsdFlushIntervalSecondsMax,
+		DefaultStatsdFlushIntervalSeconds)
 
+	// Notifications config
+	config.Notifications.TimeoutSeconds = getNumericValue(
+		config.Notifications.TimeoutSeconds,
+		DefaultNotificationTimeoutSecondsMin,
+		DefaultNotificationTimeoutSecondsMax,
+		DefaultNotificationTimeoutSeconds)
+
+	// Script credentials config
+	config.ScriptCredentials.Mode = getScriptCredentialsMode(config.ScriptCredentials.Mode)
+	config.ScriptCredentials.RoleArn = strings.TrimSpace(config.ScriptCredentials.RoleArn)
+	config.ScriptCredentials.DurationSeconds = getNumericValue(
+		config.ScriptCredentials.DurationSeconds,
+		ScriptCredentialsDurationSecondsMin,
+		ScriptCredentialsDurationSecondsMax,
+		DefaultScriptCredentialsDurationSeconds)
+
+	// Plugins config
+	config.Plugins.Timeouts = getPluginTimeouts(config.Plugins.Timeouts)
+	config.Plugins.ResourceLimits = getPluginResourceLimits(config.Plugins.ResourceLimits)
+}
+
+// getPluginTimeouts drops the timeouts out of bounds and lowers the default timeouts above the max timeout of their plugin
+func getPluginTimeouts(timeouts map[string]PluginTimeoutCfg) map[string]PluginTimeoutCfg {
+	valid := make(map[string]PluginTimeoutCfg, len(timeouts))
+	for pluginName, timeout := range timeouts {
+		timeout.DefaultSeconds = getNumericValue(timeout.DefaultSeconds, PluginTimeoutSecondsMin, PluginTimeoutSecondsMax, 0)
+		timeout.MaxSeconds = getNumericValue(timeout.MaxSeconds, PluginTimeoutSecondsMin, PluginTimeoutSecondsMax, 0)
+		if timeout.MaxSeconds > 0 && timeout.DefaultSeconds > timeout.MaxSeconds {
+			timeout.DefaultSeconds = timeout.MaxSeconds
+		}
+		valid[pluginName] = timeout
+	}
+	return valid
+}
+
+// getPluginResourceLimits drops the resource limits out of bounds, leaving the resource unlimited
+func getPluginResourceLimits(limits map[string]PluginResourceLimitsCfg) map[string]PluginResourceLimitsCfg {
+	valid := make(map[string]PluginResourceLimitsCfg, len(limits))
+	for pluginName, limit := range limits {
+		limit.CpuShares = getNumericValue(limit.CpuShares, PluginCpuSharesMin, PluginCpuSharesMax, 0)
+		limit.MemoryMB = getNumericValue(limit.MemoryMB, PluginMemoryMBMin, PluginMemoryMBMax, 0)
+		limit.MaxProcesses = getNumericValue(limit.MaxProcesses, PluginMaxProcessesMin, PluginMaxProcessesMax, 0)
+		valid[pluginName] = limit
+	}
+	return valid
 }
 
 // TODO https://sim.amazon.com/issues/SSM-3439
@@ -83,6 +296,111 @@ func GetDefaultEndPoint(region string, service string) string {
 	return endpoint
 }
 
+// GetFipsEndPoint returns the FIPS endpoint of a service in the region, or an empty string if the region has none
+func GetFipsEndPoint(region string, service string) string {
+	if !fipsRegions[region] {
+		return ""
+	}
+	return service + "-fips." + region + ".amazonaws.com"
+}
+
+// GetDualStackEndPoint returns the endpoint of the service in the region reachable over both IPv4 and IPv6
+func GetDualStackEndPoint(region string, service string) string {
+	china := strings.HasPrefix(region, "cn-")
+	switch {
+	case service == ServiceNameS3 && china:
+		return "s3.dualstack." + region + ".amazonaws.com.cn"
+	case service == ServiceNameS3:
+		return "s3.dualstack." + region + ".amazonaws.com"
+	case china:
+		return service + "." + region + ".api.amazonwebservices.com.cn"
+	}
+	return service + "." + region + ".api.aws"
+}
+
+// GetEc2MetadataEndpoint returns the url of the instance metadata service selected in appconfig
+func GetEc2MetadataEndpoint(config SsmagentConfig) string {
+	if config.Agent.Ec2MetadataEndpointMode == Ec2MetadataEndpointModeIPv6 {
+		return Ec2MetadataEndpointIPv6
+	}
+	return Ec2MetadataEndpointIPv4
+}
+
+// GetEndpointOverride returns the endpoint of the service configured in appconfig, or an empty string
+func GetEndpointOverride(config SsmagentConfig, service string) string {
+	var endpoint string
+	switch service {
+	case ServiceNameSsm:
+		endpoint = getStringValue(config.Endpoints.Ssm, config.Ssm.Endpoint)
+	case ServiceNameEc2Messages:
+		endpoint = getStringValue(config.Endpoints.Ec2Messages, config.Mds.Endpoint)
+	case ServiceNameS3:
+		endpoint = getStringValue(config.Endpoints.S3, config.S3.Endpoint)
+	case ServiceNameSsmMessages:
+		endpoint = config.Endpoints.SsmMessages
+	}
+	return strings.TrimRight(strings.TrimSpace(endpoint), "/")
+}
+
+// GetFailoverEndpoint returns the secondary endpoint of the service configured in appconfig, or its endpoint in the
+// secondary region, and the region it is in. The endpoint is empty when no failover is configured for the service.
+func GetFailoverEndpoint(config SsmagentConfig, service string) (region string, endpoint string) {
+	failover := config.Endpoints.Failover
+	region = getStringValue(failover.Region, config.Agent.Region)
+	switch service {
+	case ServiceNameSsm:
+		endpoint = failover.Ssm
+	case ServiceNameEc2Messages:
+		endpoint = failover.Ec2Messages
+	}
+	if endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/"); endpoint != "" || failover.Region == "" {
+		return region, endpoint
+	}
+	if endpoint = getRegionalEndpoint(config, service, region); endpoint == "" {
+		endpoint = service + "." + region + ".amazonaws.com"
+	}
+	return region, endpoint
+}
+
+// GetProxyOverride returns the proxy of the service configured in appconfig, or an empty string
+func GetProxyOverride(config SsmagentConfig, service string) string {
+	var proxy string
+	switch service {
+	case ServiceNameSsm:
+		proxy = config.Proxy.Ssm
+	case ServiceNameEc2Messages:
+		proxy = config.Proxy.Ec2Messages
+	case ServiceNameSsmMessages:
+		proxy = config.Proxy.SsmMessages
+	case ServiceNameS3:
+		proxy = config.Proxy.S3
+	}
+	return strings.TrimSpace(proxy)
+}
+
+// GetServiceEndpoint returns the endpoint of the service configured in appconfig, the FIPS endpoint
+// of the service in the region if UseFipsEndpoint is set, its dual-stack endpoint if UseDualStackEndpoint
+// is set, or the default endpoint of the service in the region
+func GetServiceEndpoint(config SsmagentConfig, service string, region string) string {
+	if endpoint := GetEndpointOverride(config, service); endpoint != "" {
+		return endpoint
+	}
+	return getRegionalEndpoint(config, service, region)
+}
+
+// getRegionalEndpoint returns the endpoint of the service in the region selected by the FIPS and dual-stack settings
+func getRegionalEndpoint(config SsmagentConfig, service string, region string) string {
+	if config.Agent.UseFipsEndpoint {
+		if endpoint := GetFipsEndPoint(region, service); endpoint != "" {
+			return endpoint
+		}
+	}
+	if config.Agent.UseDualStackEndpoint && region != "" {
+		return GetDualStackEndPoint(region, service)
+	}
+	return GetDefaultEndPoint(region, service)
+}
+
 // getStringValue returns the default value if config is empty, else the config value
 func getStringValue(configValue string, defaultValue string) string {
 	if configValue == "" {
@@ -91,6 +409,77 @@ func getStringValue(configValue string, defaultValue string) string {
 	return configValue
 }
 
+// getTlsMinVersion returns the minimum TLS version if valid, else the default version
+func getTlsMinVersion(configValue string) string {
+	switch strings.TrimSpace(configValue) {
+	case TlsVersion10, TlsVersion11, TlsVersion12, TlsVersion13:
+		return strings.TrimSpace(configValue)
+	}
+	return DefaultTlsMinVersion
+}
+
+// getIntegrityCheckMode returns the integrity check mode if valid, else the default mode
+func getIntegrityCheckMode(configValue string) string {
+	switch strings.ToLower(configValue) {
+	case IntegrityCheckModeOff, IntegrityCheckModeWarn, IntegrityCheckModeEnforce:
+		return strings.ToLower(configValue)
+	}
+	return IntegrityCheckModeWarn
+}
+
+// getVerificationPolicy returns the verification policy if valid, else the default policy
+func getVerificationPolicy(configValue string) string {
+	switch strings.ToLower(strings.TrimSpace(configValue)) {
+	case VerificationPolicyNone, VerificationPolicyChecksum, VerificationPolicySignature, VerificationPolicyBoth:
+		return strings.ToLower(strings.TrimSpace(configValue))
+	}
+	return VerificationPolicyNone
+}
+
+// getLogSink returns the system log sink if valid, else no sink
+func getLogSink(configValue string) string {
+	switch strings.ToLower(configValue) {
+	case LogSinkJournald, LogSinkSyslog:
+		return strings.ToLower(configValue)
+	}
+	return ""
+}
+
+// getRetryMode returns the retry mode of the AWS service clients if valid, else the standard mode
+func getRetryMode(configValue string) string {
+	if strings.EqualFold(strings.TrimSpace(configValue), RetryModeAdaptive) {
+		return RetryModeAdaptive
+	}
+	return RetryModeStandard
+}
+
+// getEc2MetadataEndpointMode returns the instance metadata endpoint mode if valid, else the IPv4 mode
+func getEc2MetadataEndpointMode(configValue string) string {
+	if strings.EqualFold(strings.TrimSpace(configValue), Ec2MetadataEndpointModeIPv6) {
+		return Ec2MetadataEndpointModeIPv6
+	}
+	return Ec2MetadataEndpointModeIPv4
+}
+
+// getScriptSandbox returns the script sandbox if valid, else an empty string to run the scripts unconfined
+func getScriptSandbox(configValue string) string {
+	switch strings.ToLower(strings.TrimSpace(configValue)) {
+	case ScriptSandboxRestricted, ScriptSandboxAppArmor:
+		return strings.ToLower(strings.TrimSpace(configValue))
+	}
+	return ""
+}
+
+// getScriptCredentialsMode returns the script credentials mode if valid, else an empty string to let the scripts
+// inherit the access of the instance
+func getScriptCredentialsMode(configValue string) string {
+	switch strings.ToLower(strings.TrimSpace(configValue)) {
+	case ScriptCredentialsModeEnvironment, ScriptCredentialsModeEndpoint:
+		return strings.ToLower(strings.TrimSpace(configValue))
+	}
+	return ""
+}
+
 // getNumericValueAboveMin returns the default if config is below minimum
 func getNumericValueAboveMin(configValue int, minValue int, defaultValue int) int {
 	if configValue < minValue {
@@ -116,3 +116,137 @@ func TestGetNumeric64Value(t *testing.T) {
 		assert.Equal(t, test.Output, output)
 	}
 }
+
+func TestGetServiceEndpoint(t *testing.T) {
+	config := DefaultConfig()
+	config.Ssm.Endpoint = "ssm.example.com"
+	config.Mds.Endpoint = "ec2messages.example.com"
+	config.Endpoints.Ec2Messages = "vpce-0123.ec2messages.us-east-1.vpce.amazonaws.com/"
+	config.Endpoints.SsmMessages = " vpce-0123.ssmmessages.us-east-1.vpce.amazonaws.com "
+
+	assert.Equal(t, "ssm.example.com", GetServiceEndpoint(config, ServiceNameSsm, "us-east-1"))
+	assert.Equal(t, "vpce-0123.ec2messages.us-east-1.vpce.amazonaws.com", GetServiceEndpoint(config, ServiceNameEc2Messages, "us-east-1"))
+	assert.Equal(t, "vpce-0123.ssmmessages.us-east-1.vpce.amazonaws.com", GetServiceEndpoint(config, ServiceNameSsmMessages, "us-east-1"))
+	assert.Equal(t, "", GetServiceEndpoint(config, ServiceNameS3, "us-east-1"))
+	assert.Equal(t, "s3.cn-north-1.amazonaws.com.cn", GetServiceEndpoint(config, ServiceNameS3, "cn-north-1"))
+	assert.Equal(t, "", GetEndpointOverride(config, ServiceNameS3))
+}
+
+func TestGetServiceEndpointFips(t *testing.T) {
+	config := DefaultConfig()
+	config.Agent.UseFipsEndpoint = true
+	config.Endpoints.S3 = "vpce-0123.s3.us-east-1.vpce.amazonaws.com"
+
+	assert.Equal(t, "ssm-fips.us-east-1.amazonaws.com", GetServiceEndpoint(config, ServiceNameSsm, "us-east-1"))
+	assert.Equal(t, "ec2messages-fips.us-gov-west-1.amazonaws.com", GetServiceEndpoint(config, ServiceNameEc2Messages, "us-gov-west-1"))
+	assert.Equal(t, "vpce-0123.s3.us-east-1.vpce.amazonaws.com", GetServiceEndpoint(config, ServiceNameS3, "us-east-1"))
+	assert.Equal(t, "", GetServiceEndpoint(config, ServiceNameSsm, "eu-west-1"))
+	assert.Equal(t, "ssm.cn-north-1.amazonaws.com.cn", GetServiceEndpoint(config, ServiceNameSsm, "cn-north-1"))
+}
+
+func TestGetServiceEndpointDualStack(t *testing.T) {
+	config := DefaultConfig()
+	config.Agent.UseDualStackEndpoint = true
+	config.Endpoints.S3 = "vpce-0123.s3.us-east-1.vpce.amazonaws.com"
+
+	assert.Equal(t, "ssm.us-east-1.api.aws", GetServiceEndpoint(config, ServiceNameSsm, "us-east-1"))
+	assert.Equal(t, "ec2messages.cn-north-1.api.amazonwebservices.com.cn", GetServiceEndpoint(config, ServiceNameEc2Messages, "cn-north-1"))
+	assert.Equal(t, "vpce-0123.s3.us-east-1.vpce.amazonaws.com", GetServiceEndpoint(config, ServiceNameS3, "us-east-1"))
+	assert.Equal(t, "s3.dualstack.eu-west-1.amazonaws.com", GetDualStackEndPoint("eu-west-1", ServiceNameS3))
+	assert.Equal(t, "", GetServiceEndpoint(config, ServiceNameSsm, ""))
+
+	// FIPS endpoints take precedence where available
+	config.Agent.UseFipsEndpoint = true
+	assert.Equal(t, "ssm-fips.us-east-1.amazonaws.com", GetServiceEndpoint(config, ServiceNameSsm, "us-east-1"))
+	assert.Equal(t, "ssm.eu-west-1.api.aws", GetServiceEndpoint(config, ServiceNameSsm, "eu-west-1"))
+}
+
+func TestGetEc2MetadataEndpoint(t *testing.T) {
+	config := DefaultConfig()
+	assert.Equal(t, Ec2MetadataEndpointIPv4, GetEc2MetadataEndpoint(config))
+
+	config.Agent.Ec2MetadataEndpointMode = getEc2MetadataEndpointMode(" ipv6")
+	assert.Equal(t, Ec2MetadataEndpointModeIPv6, config.Agent.Ec2MetadataEndpointMode)
+	assert.Equal(t, "http://[fd00:ec2::254]", GetEc2MetadataEndpoint(config))
+
+	assert.Equal(t, Ec2MetadataEndpointModeIPv4, getEc2MetadataEndpointMode("dual"))
+}
+
+func TestGetFailoverEndpoint(t *testing.T) {
+	config := DefaultConfig()
+	config.Agent.Region = "us-east-1"
+
+	region, endpoint := GetFailoverEndpoint(config, ServiceNameEc2Messages)
+	assert.Equal(t, "us-east-1", region)
+	assert.Equal(t, "", endpoint)
+
+	config.Endpoints.Failover.Ec2Messages = " ec2messages.backup.example.com/ "
+	region, endpoint = GetFailoverEndpoint(config, ServiceNameEc2Messages)
+	assert.Equal(t, "us-east-1", region)
+	assert.Equal(t, "ec2messages.backup.example.com", endpoint)
+
+	config.Endpoints.Failover.Region = "us-west-2"
+	region, endpoint = GetFailoverEndpoint(config, ServiceNameSsm)
+	assert.Equal(t, "us-west-2", region)
+	assert.Equal(t, "ssm.us-west-2.amazonaws.com", endpoint)
+
+	config.Agent.UseFipsEndpoint = true
+	_, endpoint = GetFailoverEndpoint(config, ServiceNameSsm)
+	assert.Equal(t, "ssm-fips.us-west-2.amazonaws.com", endpoint)
+
+	config.Endpoints.Failover.Region = "cn-northwest-1"
+	_, endpoint = GetFailoverEndpoint(config, ServiceNameSsm)
+	assert.Equal(t, "ssm.cn-northwest-1.amazonaws.com.cn", endpoint)
+}
+
+func TestGetTlsMinVersion(t *testing.T) {
+	assert.Equal(t, TlsVersion13, getTlsMinVersion(" 1.3"))
+	assert.Equal(t, TlsVersion10, getTlsMinVersion("1.0"))
+	assert.Equal(t, DefaultTlsMinVersion, getTlsMinVersion(""))
+	assert.Equal(t, DefaultTlsMinVersion, getTlsMinVersion("SSLv3"))
+}
+
+func TestGetPluginTimeouts(t *testing.T) {
+	timeouts := getPluginTimeouts(map[string]PluginTimeoutCfg{
+		PluginNameAwsRunShellScript:   {DefaultSeconds: 7200, MaxSeconds: 3600},
+		PluginNameAwsApplications:     {DefaultSeconds: 1, MaxSeconds: 1000000},
+		PluginNameDockerContainer:     {DefaultSeconds: 300},
+		PluginNameAwsPowerShellModule: {},
+	})
+
+	assert.Equal(t, PluginTimeoutCfg{DefaultSeconds: 3600, MaxSeconds: 3600}, timeouts[PluginNameAwsRunShellScript])
+	assert.Equal(t, PluginTimeoutCfg{}, timeouts[PluginNameAwsApplications])
+	assert.Equal(t, PluginTimeoutCfg{DefaultSeconds: 300}, timeouts[PluginNameDockerContainer])
+	assert.Equal(t, PluginTimeoutCfg{}, timeouts[PluginNameAwsPowerShellModule])
+}
+
+func TestGetPluginResourceLimits(t *testing.T) {
+	limits := getPluginResourceLimits(map[string]PluginResourceLimitsCfg{
+		PluginNameAwsRunShellScript: {CpuShares: 512, MemoryMB: 1024, MaxProcesses: 100},
+		PluginNameAwsApplications:   {CpuShares: 1, MemoryMB: 8, MaxProcesses: -1},
+	})
+
+	assert.Equal(t, PluginResourceLimitsCfg{CpuShares: 512, MemoryMB: 1024, MaxProcesses: 100}, limits[PluginNameAwsRunShellScript])
+	assert.Equal(t, PluginResourceLimitsCfg{}, limits[PluginNameAwsApplications])
+}
+
+func TestGetScriptSandbox(t *testing.T) {
+	assert.Equal(t, ScriptSandboxRestricted, getScriptSandbox(" Restricted "))
+	assert.Equal(t, ScriptSandboxAppArmor, getScriptSandbox("apparmor"))
+	assert.Equal(t, "", getScriptSandbox("seccomp"))
+	assert.Equal(t, "", getScriptSandbox(""))
+}
+
+func TestGetScriptCredentialsMode(t *testing.T) {
+	assert.Equal(t, ScriptCredentialsModeEnvironment, getScriptCredentialsMode(" Environment "))
+	assert.Equal(t, ScriptCredentialsModeEndpoint, getScriptCredentialsMode("endpoint"))
+	assert.Equal(t, "", getScriptCredentialsMode("imds"))
+	assert.Equal(t, "", getScriptCredentialsMode(""))
+}
+
+func TestGetRetryMode(t *testing.T) {
+	assert.Equal(t, RetryModeAdaptive, getRetryMode(" Adaptive"))
+	assert.Equal(t, RetryModeStandard, getRetryMode("standard"))
+	assert.Equal(t, RetryModeStandard, getRetryMode("legacy"))
+	assert.Equal(t, RetryModeStandard, getRetryMode(""))
+}
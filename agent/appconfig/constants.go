@@ -27,6 +27,8 @@ const (
 	DefaultCommandRetryLimitMin = 1
 	DefaultCommandRetryLimitMax = 100
 
+	DefaultMaxConcurrentDocuments = 0
+
 	DefaultStopTimeoutMillis    = 20000
 	DefaultStopTimeoutMillisMin = 10000
 	DefaultStopTimeoutMillisMax = 1000000
@@ -40,6 +42,124 @@ const (
 	DefaultSsmAssociationFrequencyMinutesMin = 5
 	DefaultSsmAssociationFrequencyMinutesMax = 60
 
+	DefaultSsmAssociationMaxConcurrency    = 1
+	DefaultSsmAssociationMaxConcurrencyMin = 1
+	DefaultSsmAssociationMaxConcurrencyMax = 10
+
+	// Integrity check modes for the agent binaries
+	IntegrityCheckModeOff     = "off"
+	IntegrityCheckModeWarn    = "warn"
+	IntegrityCheckModeEnforce = "enforce"
+
+	// Verification policies of the downloaded artifacts
+	VerificationPolicyNone      = "none"
+	VerificationPolicyChecksum  = "checksum"
+	VerificationPolicySignature = "signature"
+	VerificationPolicyBoth      = "both"
+
+	// System log sinks the agent logs can be sent to in addition to the log files
+	LogSinkJournald = "journald"
+	LogSinkSyslog   = "syslog"
+
+	// Sandboxes confining the scripts of the run command plugins, and the default AppArmor profile of the scripts
+	ScriptSandboxRestricted     = "restricted"
+	ScriptSandboxAppArmor       = "apparmor"
+	DefaultScriptSandboxProfile = "amazon-ssm-agent-scripts"
+
+	// Endpoints of the instance metadata service
+	Ec2MetadataEndpointModeIPv4 = "IPv4"
+	Ec2MetadataEndpointModeIPv6 = "IPv6"
+	Ec2MetadataEndpointIPv4     = "http://169.254.169.254"
+	Ec2MetadataEndpointIPv6     = "http://[fd00:ec2::254]"
+
+	// Goroutine count above which the watchdog restarts the agent
+	DefaultWatchdogMaxGoroutines    = 10000
+	DefaultWatchdogMaxGoroutinesMin = 1000
+	DefaultWatchdogMaxGoroutinesMax = 1000000
+
+	// Time the running documents get to finish when the agent stops
+	DefaultShutdownGracePeriodSeconds    = 30
+	DefaultShutdownGracePeriodSecondsMin = 0
+	DefaultShutdownGracePeriodSecondsMax = 600
+
+	// Retry modes of the AWS service clients and their maximum attempts of a call, including the first one
+	RetryModeStandard     = "standard"
+	RetryModeAdaptive     = "adaptive"
+	DefaultMaxAttempts    = 4
+	DefaultMaxAttemptsMin = 1
+	DefaultMaxAttemptsMax = 20
+
+	// Minimum TLS versions
+	TlsVersion10         = "1.0"
+	TlsVersion11         = "1.1"
+	TlsVersion12         = "1.2"
+	TlsVersion13         = "1.3"
+	DefaultTlsMinVersion = TlsVersion12
+
+	// Minimum interval between the re-registrations of a managed instance
+	DefaultReactivationIntervalMinutes    = 30
+	DefaultReactivationIntervalMinutesMin = 5
+	DefaultReactivationIntervalMinutesMax = 1440
+
+	// Plugin execution metrics published to CloudWatch
+	DefaultMetricsNamespace                 = "AmazonSSMAgent"
+	DefaultMetricsPublishIntervalSeconds    = 60
+	DefaultMetricsPublishIntervalSecondsMin = 10
+	DefaultMetricsPublishIntervalSecondsMax = 3600
+
+	// StatsD metrics published to CloudWatch
+	DefaultStatsdListenAddress           = "127.0.0.1:8125"
+	DefaultStatsdNamespace               = "StatsD"
+	DefaultStatsdFlushIntervalSeconds    = 60
+	DefaultStatsdFlushIntervalSecondsMin = 10
+	DefaultStatsdFlushIntervalSecondsMax = 3600
+
+	// Timeout of the notifications of the completed documents
+	DefaultNotificationTimeoutSeconds    = 10
+	DefaultNotificationTimeoutSecondsMin = 1
+	DefaultNotificationTimeoutSecondsMax = 300
+
+	// Modes of the credentials vended to the scripts, and their lifetime, bounded by STS to an hour when the agent
+	// itself runs with role credentials
+	ScriptCredentialsModeEnvironment        = "environment"
+	ScriptCredentialsModeEndpoint           = "endpoint"
+	DefaultScriptCredentialsDurationSeconds = 900
+	ScriptCredentialsDurationSecondsMin     = 900
+	ScriptCredentialsDurationSecondsMax     = 3600
+
+	// Bounds of the execution timeouts of the plugins
+	PluginTimeoutSecondsMin = 5
+	PluginTimeoutSecondsMax = 172800
+
+	// Bounds of the resource limits of the plugins
+	PluginCpuSharesMin    = 2
+	PluginCpuSharesMax    = 262144
+	PluginMemoryMBMin     = 16
+	PluginMemoryMBMax     = 4194304
+	PluginMaxProcessesMin = 1
+	PluginMaxProcessesMax = 4194304
+
+	// RegistrationFileName is the name of the file holding the managed instance id and region of the last registration
+	RegistrationFileName = "registration"
+
+	// IntegrityManifestFileName is the name of the manifest holding the hashes of the agent binaries
+	IntegrityManifestFileName = "integrity.json"
+
+	// HealthErrorSummaryFileName is the name of the file holding the errors summarized at the last health update
+	HealthErrorSummaryFileName = "errorsummary.json"
+
+	// AssociationStateFileName is the name of the file holding the local execution state of the associations
+	AssociationStateFileName = "associationstate.json"
+
+	// ExecutionHistoryFileName is the name of the file holding the local history of the executed documents
+	ExecutionHistoryFileName = "executionhistory.json"
+
+	// ProcessedMessagesDirName is the name of the directory holding the messages processed by each message service
+	ProcessedMessagesDirName = "processedmessages"
+
+	// AuditLogFileName is the name of the file holding the hash chained audit log of the executed documents
+	AuditLogFileName = "audit.log"
+
 	//aws-ssm-agent bookkeeping constants
 	DefaultLocationOfPending     = "pending"
 	DefaultLocationOfCurrent     = "current"
@@ -53,6 +173,81 @@ const (
 	DefaultRunCommandLogsRetentionDurationHours            = 336 // 14 days default retention
 	DefaultStateOrchestrationLogsRetentionDurationHoursMin = 8   // Min retention of 8hrs as some processes may not timeout before this and don't want logs to be deleted before the process completes
 
+	//aws-ssm-agent retention of execution history, a count of 0 keeps any number of executions
+	DefaultAssociationLogsRetentionCount       = 1000
+	DefaultRunCommandLogsRetentionCount        = 1000
+	DefaultDocumentStateRetentionDurationHours = 336 // 14 days default retention
+	DefaultDocumentStateRetentionCount         = 1000
+	DefaultRetentionPruneFrequencyMinutes      = 60
+	DefaultRetentionPruneFrequencyMinutesMin   = 5
+	DefaultRetentionPruneFrequencyMinutesMax   = 1440
+	DefaultOrchestrationRetentionMaxSizeMB     = 1024
+
+	// DefaultExecutionHistoryCount is the number of executed documents kept in the local execution history
+	DefaultExecutionHistoryCount = 100
+
+	// DefaultMaxDocumentReboots is the number of reboots a single document may request before it fails
+	DefaultMaxDocumentReboots    = 5
+	DefaultMaxDocumentRebootsMin = 1
+	DefaultMaxDocumentRebootsMax = 100
+
+	//aws-ssm-agent interval after which unchanged inventory data is uploaded again in full
+	DefaultInventoryFullRefreshIntervalHours    = 24
+	DefaultInventoryFullRefreshIntervalHoursMin = 1
+	DefaultInventoryFullRefreshIntervalHoursMax = 168
+
+	//aws-ssm-agent time an updated agent has to pass its health checks before the update is rolled back
+	DefaultUpdateHealthCheckMinutes    = 5
+	DefaultUpdateHealthCheckMinutesMin = 1
+	DefaultUpdateHealthCheckMinutesMax = 60
+
+	//aws-ssm-agent intervals between the health pings while the agent hibernates
+	DefaultHibernationMinIntervalSeconds    = 60
+	DefaultHibernationMinIntervalSecondsMin = 10
+	DefaultHibernationMinIntervalSecondsMax = 3600
+	DefaultHibernationMaxIntervalSeconds    = 3600
+	DefaultHibernationMaxIntervalSecondsMin = 60
+	DefaultHibernationMaxIntervalSecondsMax = 86400
+
+	//aws-ssm-agent name of the sessions of the role assumed by the agent
+	DefaultRoleSessionName = "amazon-ssm-agent"
+
+	//aws-ssm-agent similarity of the hardware required to keep the managed instance fingerprint, 0 keeps the saved one
+	DefaultFingerprintSimilarityThreshold    = 0
+	DefaultFingerprintSimilarityThresholdMin = 1
+	DefaultFingerprintSimilarityThresholdMax = 100
+
+	//aws-ssm-agent names of the services whose endpoints can be overridden in appconfig
+	ServiceNameSsm         = "ssm"
+	ServiceNameEc2Messages = "ec2messages"
+	ServiceNameS3          = "s3"
+	ServiceNameSsmMessages = "ssmmessages"
+
+	//aws-ssm-agent minutes after which the agent fails back to the primary endpoints
+	DefaultFailbackMinutes    = 30
+	DefaultFailbackMinutesMin = 5
+	DefaultFailbackMinutesMax = 1440
+
+	//aws-ssm-agent number of parts of a large artifact downloaded concurrently
+	DefaultDownloadConcurrency    = 4
+	DefaultDownloadConcurrencyMin = 1
+	DefaultDownloadConcurrencyMax = 16
+
+	//aws-ssm-agent size of the parts large artifacts are downloaded in
+	DefaultDownloadPartSizeMB    = 8
+	DefaultDownloadPartSizeMBMin = 1
+	DefaultDownloadPartSizeMBMax = 1024
+
+	//aws-ssm-agent bandwidth caps of the downloads and uploads in KB per second, 0 means unlimited
+	DefaultBandwidthLimitKBps    = 0
+	DefaultBandwidthLimitKBpsMin = 0
+	DefaultBandwidthLimitKBpsMax = 1024 * 1024
+
+	//aws-ssm-agent maximum random delay of self-updates deferred until the update window opens
+	DefaultUpdateWindowSplayMinutes    = 0
+	DefaultUpdateWindowSplayMinutesMin = 0
+	DefaultUpdateWindowSplayMinutesMax = 1440
+
 	//aws-ssm-agent bookkeeping constants for long running plugins
 	LongRunningPluginsLocation         = "longrunningplugins"
 	LongRunningPluginsHealthCheck      = "healthcheck"
@@ -66,6 +261,8 @@ const (
 	FileInventoryRootDirName     = "file"
 	RoleInventoryRootDirName     = "role"
 	InventoryContentHashFileName = "contentHash"
+	InventoryFullUploadFileName  = "lastFullUpload"
+	InventoryScheduleFileName    = "gathererSchedule"
 
 	//aws-ssm-agent bookkeeping constants for failed sent replies
 	RepliesRootDirName = "replies"
@@ -86,6 +283,15 @@ const (
 	// WorkersRootDirName  - the worker folder used in ec2 config
 	WorkersRootDirName = "Workers"
 
+	// UpdateSigningKeysDirName - the folder in the program folder holding the public keys update packages are signed with
+	UpdateSigningKeysDirName = "UpdateSigningKeys"
+
+	// HealthCheckpointsDirName - the folder in the data store recording the health checks passed by the agent
+	HealthCheckpointsDirName = "healthcheckpoints"
+
+	// DaemonLogRootDirName - the folder in the agent log directory capturing the output of ssm daemons
+	DaemonLogRootDirName = "daemons"
+
 	// Permissions defaults
 	//NOTE: Limit READ, WRITE and EXECUTE access to administrators/root.
 	ReadWriteAccess        = 0600
@@ -116,6 +322,33 @@ const (
 	// PluginNameAwsRunPowerShellScript is the name of the run powershell script plugin
 	PluginNameAwsRunPowerShellScript = "aws:runPowerShellScript"
 
+	// PowerShellEngineAuto selects the default PowerShell engine of the platform
+	PowerShellEngineAuto = "auto"
+
+	// PowerShellEngineCore selects PowerShell Core (pwsh)
+	PowerShellEngineCore = "pwsh"
+
+	// PowerShellEngineWindows selects Windows PowerShell (powershell.exe)
+	PowerShellEngineWindows = "powershell"
+
+	// PowerShellArchitecture32 selects the 32-bit Windows PowerShell host
+	PowerShellArchitecture32 = "32"
+
+	// PowerShellArchitecture64 selects the 64-bit Windows PowerShell host
+	PowerShellArchitecture64 = "64"
+
+	// ShellInterpreterSh selects sh, the default interpreter of the runShellScript plugin
+	ShellInterpreterSh = "sh"
+
+	// ShellInterpreterBash selects bash
+	ShellInterpreterBash = "bash"
+
+	// ShellInterpreterZsh selects zsh
+	ShellInterpreterZsh = "zsh"
+
+	// ShellInterpreterPython3 selects python3
+	ShellInterpreterPython3 = "python3"
+
 	// PluginNameAwsAgentUpdate is the name for agent update plugin
 	PluginNameAwsAgentUpdate = "aws:updateSsmAgent"
 
@@ -137,6 +370,9 @@ const (
 	// PluginNameCloudWatch is the name of cloud watch plugin
 	PluginNameCloudWatch = "aws:cloudWatch"
 
+	// PluginNameStatsd is the name of the StatsD listener plugin
+	PluginNameStatsd = "aws:statsd"
+
 	// PluginNameRunDockerAction is the name of the docker container plugin
 	PluginNameDockerContainer = "aws:runDockerAction"
 
@@ -152,6 +388,9 @@ const (
 	// PluginNameAwsApplications is the name of the Applications plugin
 	PluginNameAwsApplications = "aws:applications"
 
+	// PluginNameConfigureKernelParameters is the name of the configure kernel parameters plugin
+	PluginNameConfigureKernelParameters = "aws:configureKernelParameters"
+
 	AppConfigFileName    = "amazon-ssm-agent.json"
 	SeelogConfigFileName = "seelog.xml"
 
@@ -173,3 +412,14 @@ var SupportedDocumentVersions = map[string]struct{}{
 	"2.0.3": {},
 	"2.2":   {},
 }
+
+// fipsRegions lists the regions with FIPS endpoints for the services of the agent
+var fipsRegions = map[string]bool{
+	"us-east-1":     true,
+	"us-east-2":     true,
+	"us-west-1":     true,
+	"us-west-2":     true,
+	"ca-central-1":  true,
+	"us-gov-east-1": true,
+	"us-gov-west-1": true,
+}
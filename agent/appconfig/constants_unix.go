@@ -65,6 +65,9 @@ const (
 	// ManifestCacheDirectory represents the directory for storing all downloaded manifest files
 	ManifestCacheDirectory = "/var/lib/amazon/ssm/manifests"
 
+	// StatusAPIAddress is the unix socket on which the agent serves its local status API
+	StatusAPIAddress = "/var/lib/amazon/ssm/ipc/status.sock"
+
 	// List all plugin names, unfortunately golang doesn't support const arrays of strings
 
 	// RebootExitCode that would trigger a Soft Reboot
@@ -89,6 +92,30 @@ const (
 // PowerShellPluginCommandName is the path of the powershell.exe to be used by the runPowerShellScript plugin
 var PowerShellPluginCommandName string
 
+// DefaultPowerShellEngine is the PowerShell engine used by the runPowerShellScript plugin unless the document selects one
+var DefaultPowerShellEngine = PowerShellEngineCore
+
+// PowerShellCoreCommandName is the name of the PowerShell Core executable looked up in the PATH
+var PowerShellCoreCommandName = "pwsh"
+
+// PowerShellCoreCommandPaths are the known install locations of PowerShell Core on linux and macOS
+var PowerShellCoreCommandPaths = []string{
+	"/usr/bin/pwsh",
+	"/usr/local/bin/pwsh",
+	"/opt/microsoft/powershell/7/pwsh",
+	"/snap/bin/pwsh",
+	"/usr/bin/powershell",
+}
+
+// WindowsPowerShellCommandName is the name of the Windows PowerShell executable, which is not available on this platform
+var WindowsPowerShellCommandName = ""
+
+// WindowsPowerShellCommandPaths are the known install locations of Windows PowerShell, which is not available on this platform
+var WindowsPowerShellCommandPaths []string
+
+// WindowsPowerShellCommandPathsByArchitecture are the paths of the 32-bit and 64-bit powershell.exe, which is not available on this platform
+var WindowsPowerShellCommandPathsByArchitecture = map[string]string{}
+
 // DefaultProgramFolder is the default folder for SSM
 var DefaultProgramFolder = "/etc/amazon/ssm/"
 var DefaultDocumentWorker = "/usr/bin/ssm-document-worker"
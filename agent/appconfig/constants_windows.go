@@ -19,6 +19,7 @@ package appconfig
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 )
 
 const (
@@ -37,6 +38,9 @@ const (
 	// ManifestCacheFolder path under local app data
 	ManifestCacheFolder = "Amazon\\SSM\\Manifests"
 
+	// StatusAPIAddress is the named pipe on which the agent serves its local status API
+	StatusAPIAddress = `\\.\pipe\amazon-ssm-agent-status`
+
 	// Exit Code that would trigger a Soft Reboot
 	RebootExitCode = 3010
 
@@ -69,6 +73,39 @@ const (
 //PowerShellPluginCommandName is the path of the powershell.exe to be used by the runPowerShellScript plugin
 var PowerShellPluginCommandName = filepath.Join(os.Getenv("SystemRoot"), "System32", "WindowsPowerShell", "v1.0", "powershell.exe")
 
+// DefaultPowerShellEngine is the PowerShell engine used by the runPowerShellScript plugin unless the document selects one
+var DefaultPowerShellEngine = PowerShellEngineWindows
+
+// PowerShellCoreCommandName is the name of the PowerShell Core executable looked up in the PATH
+var PowerShellCoreCommandName = "pwsh.exe"
+
+// PowerShellCoreCommandPaths are the known install locations of PowerShell Core on windows
+var PowerShellCoreCommandPaths = []string{
+	filepath.Join(os.Getenv("ProgramFiles"), "PowerShell", "7", "pwsh.exe"),
+	filepath.Join(os.Getenv("ProgramFiles"), "PowerShell", "6", "pwsh.exe"),
+}
+
+// WindowsPowerShellCommandName is the name of the Windows PowerShell executable looked up in the PATH
+var WindowsPowerShellCommandName = "powershell.exe"
+
+// WindowsPowerShellCommandPaths are the known install locations of Windows PowerShell
+var WindowsPowerShellCommandPaths = []string{PowerShellPluginCommandName}
+
+// WindowsPowerShellCommandPathsByArchitecture are the paths of the 32-bit and 64-bit powershell.exe
+var WindowsPowerShellCommandPathsByArchitecture = windowsPowerShellCommandPathsByArchitecture()
+
+// windowsPowerShellCommandPathsByArchitecture returns the paths of powershell.exe as seen by the agent,
+// a 32-bit agent is redirected from System32 to SysWOW64 and reaches the 64-bit host through Sysnative
+func windowsPowerShellCommandPathsByArchitecture() map[string]string {
+	path := func(systemDir string) string {
+		return filepath.Join(os.Getenv("SystemRoot"), systemDir, "WindowsPowerShell", "v1.0", "powershell.exe")
+	}
+	if runtime.GOARCH == "386" {
+		return map[string]string{PowerShellArchitecture32: path("System32"), PowerShellArchitecture64: path("Sysnative")}
+	}
+	return map[string]string{PowerShellArchitecture32: path("SysWOW64"), PowerShellArchitecture64: path("System32")}
+}
+
 // Program Folder
 var DefaultProgramFolder string
 
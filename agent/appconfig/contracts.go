@@ -20,6 +20,13 @@ type CredentialProfile struct {
 	Name         string
 	ShareCreds   bool
 	ShareProfile string
+	// RoleArn is a role the agent assumes with the credentials of the profile, or with the environment, shared
+	// credentials file and instance profile credentials, for devices managed outside of the activations
+	RoleArn string
+	// ExternalId is passed when assuming the role, as required by the trust policy of roles shared across accounts
+	ExternalId string
+	// RoleSessionName names the sessions of the assumed role, amazon-ssm-agent by default
+	RoleSessionName string
 }
 
 // MdsCfg represents configuration for Message delivery service (MDS)
@@ -28,6 +35,9 @@ type MdsCfg struct {
 	CommandWorkersLimit int
 	StopTimeoutMillis   int64
 	CommandRetryLimit   int
+	// MaxConcurrentDocuments limits how many send command documents run at the same time on the instance,
+	// the other documents wait in arrival order, 0 does not limit them
+	MaxConcurrentDocuments int
 }
 
 // SsmCfg represents configuration for Simple system manager (SSM)
@@ -42,15 +52,112 @@ type SsmCfg struct {
 	CustomInventoryDefaultLocation        string
 	AssociationLogsRetentionDurationHours int
 	RunCommandLogsRetentionDurationHours  int
+	AssociationLogsRetentionCount         int
+	RunCommandLogsRetentionCount          int
+	DocumentStateRetentionDurationHours   int
+	DocumentStateRetentionCount           int
+	RetentionPruneFrequencyMinutes        int
+	InventoryFullRefreshIntervalHours     int
+	// OrchestrationRetentionMaxSizeMB is the maximum total size of the orchestration folders, the oldest
+	// folders are removed first once it is exceeded, their size is not limited when 0
+	OrchestrationRetentionMaxSizeMB int
+	// RetainedPluginOutputs lists the plugins whose outputs are never removed from the orchestration folders
+	RetainedPluginOutputs []string
+	// ExecutionHistoryCount is the number of executed documents kept in the local execution history,
+	// no history is kept when 0
+	ExecutionHistoryCount int
+	// MaxDocumentReboots is the maximum number of reboots a single document may request before it fails
+	MaxDocumentReboots int
+	// AssociationMaxConcurrency is the maximum number of associations run at the same time
+	AssociationMaxConcurrency int
+	// AssociationErrorThreshold is the number of consecutive failed runs after which an association is suspended
+	// until its content changes, associations are never suspended when 0
+	AssociationErrorThreshold int
+}
+
+// UpdateWindowCfg represents the maintenance window in which the agent applies self-updates
+type UpdateWindowCfg struct {
+	// AllowedDays lists the days updates can start on (e.g. "Sat"), all days are allowed when empty
+	AllowedDays []string
+	// AllowedHours is the range of hours updates can start in (e.g. "22-04"), any hour is allowed when empty
+	AllowedHours string
+	// TimeZone is the IANA name of the time zone of the window, the local time zone is used when empty
+	TimeZone string
+	// SplayMinutes is the maximum random delay applied to updates deferred until the window opens
+	SplayMinutes int
 }
 
 // AgentInfo represents metadata for amazon-ssm-agent
 type AgentInfo struct {
-	Name                 string
-	Version              string
-	Region               string
-	OrchestrationRootDir string
-	DownloadRootDir      string
+	Name                     string
+	Version                  string
+	Region                   string
+	OrchestrationRootDir     string
+	DownloadRootDir          string
+	IntegrityCheckMode       string
+	PrivateTmp               bool
+	UpdateHealthCheckMinutes int
+	UpdateWindow             UpdateWindowCfg
+	DownloadConcurrency      int
+	DownloadPartSizeMB       int
+	// DownloadBandwidthLimitKBps caps the bandwidth of the artifact and content downloads of each agent process
+	// in KB per second, 0 doesn't limit the downloads
+	DownloadBandwidthLimitKBps int
+	// UploadBandwidthLimitKBps caps the bandwidth of the command outputs uploaded to S3 and of the inventory
+	// uploaded to SSM in KB per second, 0 doesn't limit the uploads
+	UploadBandwidthLimitKBps int
+	// VerificationPolicy is how the packages, content, modules and agent updates are verified once downloaded,
+	// "none", "checksum", "signature" or "both". Documents may only override it with a stricter policy
+	VerificationPolicy string
+	Tags               map[string]string
+	// HibernationMinIntervalSeconds is the interval of the endpoint probes and of the first health ping in hibernation
+	HibernationMinIntervalSeconds int
+	// HibernationMaxIntervalSeconds caps the exponential backoff of the health pings in hibernation
+	HibernationMaxIntervalSeconds int
+	// UseFipsEndpoint switches the AWS service calls to the FIPS endpoints of the region, where available,
+	// and restricts TLS to FIPS approved versions, cipher suites and curves
+	UseFipsEndpoint bool
+	// UseDualStackEndpoint switches the AWS service calls to the endpoints reachable over both IPv4 and IPv6,
+	// as required on IPv6-only subnets
+	UseDualStackEndpoint bool
+	// Ec2MetadataEndpointMode selects the "IPv4" or "IPv6" endpoint of the instance metadata service
+	Ec2MetadataEndpointMode string
+	// DisableWatchdog turns off the watchdog restarting the subsystems of the agent which stopped making progress
+	DisableWatchdog bool
+	// WatchdogMaxGoroutines is the number of goroutines above which the watchdog considers they leak and restarts the agent
+	WatchdogMaxGoroutines int
+	// ShutdownGracePeriodSeconds is how long the agent lets the running documents finish when it stops,
+	// before it cancels them and leaves the documents which did not finish to resume once it starts again
+	ShutdownGracePeriodSeconds int
+	// RetryMode is how the AWS service clients retry the failed calls. "standard" retries with an exponential backoff,
+	// "adaptive" also takes the retries from a token bucket shared by the clients of the process, whose refill rate
+	// drops when the calls are throttled, so that a fleet backs off the APIs it saturates
+	RetryMode string
+	// MaxAttempts is the maximum number of attempts of an AWS service call, including the first one
+	MaxAttempts int
+	// BackgroundLowPriority runs the inventory gathering, the shipping of the agent logs and the hashing of the
+	// downloaded artifacts at a reduced priority, with nice and the idle io class on Linux and the background
+	// mode on Windows, so that the agent housekeeping doesn't compete with the workloads of the instance
+	BackgroundLowPriority bool
+	// AuditToEventLog writes the agent lifecycle events and a summary of every executed document
+	// to the AmazonSSMAgentAudit source of the Windows Application event log, ignored on other platforms
+	AuditToEventLog bool
+	// LocalAuditLog appends every executed document to a hash chained audit log in the data folder of the agent,
+	// which ssm-cli verify-audit checks for modified or removed records
+	LocalAuditLog bool
+	// LogSink additionally sends the agent logs to "journald" or "syslog" on Linux, empty logs to the files only
+	LogSink string
+	// FingerprintSimilarityThreshold is the percentage of the hardware information which must match the saved one
+	// for the managed instance to keep its fingerprint, 0 keeps the threshold saved with the fingerprint
+	FingerprintSimilarityThreshold int
+	// EncryptStateAtRest encrypts the document states and the replies waiting to be sent which the agent persists
+	// in its data folder, with a key generated on the instance
+	EncryptStateAtRest bool
+	// ScriptSandbox confines the scripts of the run command plugins on Linux, empty runs them unconfined.
+	// "restricted" runs them without raw sockets nor mount privileges and with read-only agent directories,
+	// "apparmor" runs them with the AppArmor profile named by ScriptSandboxProfile, which must be loaded
+	ScriptSandbox        string
+	ScriptSandboxProfile string
 }
 
 // MfsCfg represents configuration for HummingBird service (MFS)
@@ -69,10 +176,150 @@ type OsInfo struct {
 
 // S3Cfg represents configurations related to S3 bucket and key for SSM
 type S3Cfg struct {
-	Endpoint  string
-	Region    string
-	LogBucket string
-	LogKey    string
+	Endpoint              string
+	Region                string
+	LogBucket             string
+	LogKey                string
+	UseAccelerateEndpoint bool
+	UseDualStackEndpoint  bool
+	ForcePathStyle        bool
+	// KmsKeyId is the id of the KMS key used to encrypt the outputs uploaded to S3 with SSE-KMS,
+	// the outputs are uploaded without server-side encryption settings when empty
+	KmsKeyId string
+}
+
+// EndpointsCfg overrides the endpoints of the AWS services the agent calls, such as VPC interface endpoints.
+// They take precedence over the endpoints configured in the Ssm, Mds and S3 sections.
+type EndpointsCfg struct {
+	Ssm         string
+	Ec2Messages string
+	S3          string
+	SsmMessages string
+	Failover    EndpointsFailoverCfg
+}
+
+// EndpointsFailoverCfg sets the secondary control plane endpoints the agent fails over to after sustained failures
+// of the primary ones. The services without a secondary endpoint use their endpoint in the secondary Region, there is
+// no failover when neither is set. The agent fails back to the primary endpoints after FailbackMinutes.
+type EndpointsFailoverCfg struct {
+	Region          string
+	Ssm             string
+	Ec2Messages     string
+	FailbackMinutes int
+}
+
+// ProxyCfg sets the proxies used to call the AWS services, they take precedence over the http_proxy and https_proxy
+// environment variables. NoProxy lists the hosts, domain suffixes and CIDR blocks reached without proxy, in addition
+// to the no_proxy environment variable.
+type ProxyCfg struct {
+	Ssm         string
+	Ec2Messages string
+	SsmMessages string
+	S3          string
+	NoProxy     []string
+	// PacUrl is the proxy auto-config file evaluated on Windows, the automatic configuration of the system proxy
+	// settings is used when empty
+	PacUrl string
+}
+
+// TlsCfg restricts the TLS settings of the connections of the agent to the AWS services and download locations
+type TlsCfg struct {
+	// MinVersion is the minimum TLS version, one of 1.0, 1.1, 1.2 and 1.3
+	MinVersion string
+	// CipherSuites lists the names of the cipher suites allowed up to TLS 1.2 (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256),
+	// the secure cipher suites of the go runtime are allowed when empty. TLS 1.3 cipher suites are not configurable.
+	CipherSuites []string
+}
+
+// RegistrationCfg configures the automatic re-registration of a managed instance whose registration is no longer
+// valid, e.g. after it was deregistered. The activation is read from ReactivationFile, or from the standard output of
+// ReactivationCommand, as a JSON object with the ActivationCode, ActivationId and Region to register with.
+type RegistrationCfg struct {
+	ReactivationFile    string
+	ReactivationCommand string
+	// ReactivationIntervalMinutes is the minimum time between two re-registration attempts
+	ReactivationIntervalMinutes int
+}
+
+// MetricsCfg represents configuration of the plugin execution metrics published to CloudWatch
+type MetricsCfg struct {
+	// Enabled publishes the duration, the success and failure counts and the bytes downloaded by the plugins
+	Enabled bool
+	// Namespace is the CloudWatch namespace the metrics are published to
+	Namespace string
+	// PublishIntervalSeconds is the interval between two publications, the metrics are aggregated in between
+	PublishIntervalSeconds int
+}
+
+// StatsdCfg represents configuration of the local StatsD listener, whose metrics are aggregated and published to CloudWatch
+type StatsdCfg struct {
+	// Enabled starts the aws:statsd long running plugin, which listens for StatsD metrics over UDP
+	Enabled bool
+	// ListenAddress is the UDP address the listener binds to, e.g. 127.0.0.1:8125
+	ListenAddress string
+	// Namespace is the CloudWatch namespace the metrics are published to
+	Namespace string
+	// Dimensions are added to every metric, the tags of a metric override the dimensions of the same name
+	Dimensions map[string]string
+	// FlushIntervalSeconds is the interval between two publications, the metrics are aggregated in between
+	FlushIntervalSeconds int
+}
+
+// NotificationsCfg represents configuration of the notifications the agent sends whenever a document finishes
+// on the instance, with a JSON summary of the execution signed with HMAC-SHA256
+type NotificationsCfg struct {
+	// WebhookUrl receives a POST request with the summary of each completed document, empty sends no request
+	WebhookUrl string
+	// SnsTopicArn receives a message with the summary of each completed document, empty publishes no message
+	SnsTopicArn string
+	// SigningKey is the key the summaries are signed with, a key is generated in the data folder of the agent when empty
+	SigningKey string
+	// TimeoutSeconds bounds each webhook request and SNS publication
+	TimeoutSeconds int
+}
+
+// ScriptCredentialsCfg represents configuration of the credentials vended to the scripts of the run command plugins,
+// which otherwise inherit the credentials of the instance profile from the instance metadata service
+type ScriptCredentialsCfg struct {
+	// Mode is "environment" to pass the credentials in the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN variables, "endpoint" to serve refreshing credentials on a loopback endpoint for the duration
+	// of the script, empty lets the scripts inherit the access of the instance. Both modes turn off the instance
+	// metadata credentials of the AWS SDKs and CLI of the scripts, the instance metadata service itself stays
+	// reachable by the scripts.
+	Mode string
+	// RoleArn is the role assumed with the credentials of the agent for the scripts, required by both modes
+	RoleArn string
+	// SessionPolicy is a JSON IAM policy further scoping the permissions of the role, empty grants those of the role
+	SessionPolicy string
+	// DurationSeconds is the lifetime of the credentials, those of the environment mode are not refreshed
+	DurationSeconds int
+}
+
+// PluginsCfg represents configuration shared by the plugins
+type PluginsCfg struct {
+	// Timeouts holds the execution timeouts of the plugins, by plugin name (e.g. aws:runShellScript)
+	Timeouts map[string]PluginTimeoutCfg
+	// ResourceLimits holds the resource limits of the processes started by the plugins, by plugin name
+	ResourceLimits map[string]PluginResourceLimitsCfg
+}
+
+// PluginTimeoutCfg represents the execution timeouts of a plugin, 0 keeps the timeout of the plugin
+type PluginTimeoutCfg struct {
+	// DefaultSeconds is the timeout of the steps which don't set TimeoutSeconds
+	DefaultSeconds int
+	// MaxSeconds caps the TimeoutSeconds of the steps, documents can lower the timeout but not exceed it
+	MaxSeconds int
+}
+
+// PluginResourceLimitsCfg represents the resource limits of the processes started by a plugin and of their descendants,
+// 0 leaves the resource unlimited. Documents can lower the limits but not exceed them
+type PluginResourceLimitsCfg struct {
+	// CpuShares is the relative CPU weight of the processes, 1024 being the weight of the other processes
+	CpuShares int
+	// MemoryMB caps the memory used by the processes, they are killed when it is exceeded
+	MemoryMB int
+	// MaxProcesses caps the number of processes running at the same time
+	MaxProcesses int
 }
 
 // BirdwatcherCfg represents configuration related to ConfigurePackage Birdwatcher integration
@@ -82,12 +329,21 @@ type BirdwatcherCfg struct {
 
 // SsmagentConfig stores agent configuration values.
 type SsmagentConfig struct {
-	Profile     CredentialProfile
-	Mds         MdsCfg
-	Ssm         SsmCfg
-	Mfs         MfsCfg
-	Agent       AgentInfo
-	Os          OsInfo
-	S3          S3Cfg
-	Birdwatcher BirdwatcherCfg
+	Profile           CredentialProfile
+	Mds               MdsCfg
+	Ssm               SsmCfg
+	Mfs               MfsCfg
+	Agent             AgentInfo
+	Os                OsInfo
+	S3                S3Cfg
+	Endpoints         EndpointsCfg
+	Proxy             ProxyCfg
+	Tls               TlsCfg
+	Registration      RegistrationCfg
+	Metrics           MetricsCfg
+	Statsd            StatsdCfg
+	Notifications     NotificationsCfg
+	ScriptCredentials ScriptCredentialsCfg
+	Plugins           PluginsCfg
+	Birdwatcher       BirdwatcherCfg
 }
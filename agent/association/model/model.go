@@ -40,13 +40,21 @@ type InstanceAssociation struct {
 // ParseExpression parses the expression with the given association
 func (newAssoc *InstanceAssociation) ParseExpression(log log.T) error {
 
-	parsedScheduleExpression, err := scheduleexpression.CreateScheduleExpression(log, *newAssoc.Association.ScheduleExpression)
+	expression, splayWindow, err := scheduleexpression.ExtractSplayWindow(*newAssoc.Association.ScheduleExpression)
+	if err != nil {
+		return fmt.Errorf("Failed to parse schedule expression %v, %v", *newAssoc.Association.ScheduleExpression, err)
+	}
+
+	parsedScheduleExpression, err := scheduleexpression.CreateScheduleExpression(log, expression)
 
 	if err != nil {
 		return fmt.Errorf("Failed to parse schedule expression %v, %v", *newAssoc.Association.ScheduleExpression, err)
 	}
 
-	newAssoc.ParsedExpression = parsedScheduleExpression
+	// the splay offset depends on the instance and the association only, so that each instance keeps running
+	// the association at the same point of the window
+	splayKey := aws.StringValue(newAssoc.Association.InstanceId) + "/" + aws.StringValue(newAssoc.Association.AssociationId)
+	newAssoc.ParsedExpression = scheduleexpression.Splay(parsedScheduleExpression, scheduleexpression.SplayOffset(splayWindow, splayKey))
 	return nil
 }
 
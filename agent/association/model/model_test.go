@@ -384,3 +384,36 @@ func TestNextScheduledDateIsNilWhenAssociationExpressionTypeIsUnknown(t *testing
 	// Assert
 	assert.Nil(t, assocRawData.NextScheduledDate)
 }
+
+func TestNextScheduledDateIsSplayedWithinWindowWhenExpressionHasSplay(t *testing.T) {
+	// Assemble
+	logger := log.DefaultLogger()
+
+	testInstanceAssociation := InstanceAssociation{}
+
+	testInstanceAssociation.Association = &ssm.InstanceAssociationSummary{}
+	testAssociationName := "Test"
+	testInstanceAssociation.Association.Name = &testAssociationName
+	assocId := "b2f71a28-cbe1-4429-b848-26c7e1f5ad0d"
+	testInstanceAssociation.Association.AssociationId = &assocId
+	instanceId := "i-1234567890abcdef0"
+	testInstanceAssociation.Association.InstanceId = &instanceId
+	testCronExpression := "cron(SPLAY=30m 0 2 * * ? *)"
+	testInstanceAssociation.Association.ScheduleExpression = &testCronExpression
+
+	lastExecutionDateTime := time.Date(
+		2009, 11, 17, 3, 0, 0, 0, time.UTC)
+	testInstanceAssociation.Association.LastExecutionDate = &lastExecutionDateTime
+
+	// Act
+	testInstanceAssociation.SetNextScheduledDate(logger)
+	firstScheduledDate := *testInstanceAssociation.NextScheduledDate
+	testInstanceAssociation.Association.LastExecutionDate = &firstScheduledDate
+	testInstanceAssociation.SetNextScheduledDate(logger)
+
+	// Assert
+	windowStart := time.Date(2009, 11, 18, 2, 0, 0, 0, time.UTC)
+	assert.False(t, firstScheduledDate.Before(windowStart))
+	assert.True(t, firstScheduledDate.Before(windowStart.Add(30*time.Minute)))
+	assert.Equal(t, firstScheduledDate.Add(24*time.Hour), *testInstanceAssociation.NextScheduledDate)
+}
@@ -15,6 +15,7 @@
 package processor
 
 import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/association/model"
 	"github.com/aws/amazon-ssm-agent/agent/association/parser"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -42,13 +43,13 @@ var sys system = &systemImp{}
 
 // bookkeepingService represents the dependency for docmanager
 type bookkeepingService interface {
-	DeleteOldOrchestrationLogs(log log.T, instanceID, orchestrationRootDirName string, retentionDurationHours int, isIntendedFileNameFormat func(string) bool)
+	PruneExecutionHistory(log log.T, instanceID string, config appconfig.SsmagentConfig)
 }
 
 type assocBookkeepingService struct{}
 
-func (assocBookkeepingService) DeleteOldOrchestrationLogs(log log.T, instanceID, orchestrationRootDirName string, retentionDurationHours int, isIntendedFileNameFormat func(string) bool) {
-	docmanager.DeleteOldOrchestrationFolderLogs(log, instanceID, orchestrationRootDirName, retentionDurationHours, isIntendedFileNameFormat)
+func (assocBookkeepingService) PruneExecutionHistory(log log.T, instanceID string, config appconfig.SsmagentConfig) {
+	docmanager.PruneExecutionHistory(log, instanceID, config)
 }
 
 // system represents the dependency for platform
@@ -19,8 +19,6 @@ import (
 	"sync"
 	"time"
 
-	"regexp"
-
 	"path"
 	"strings"
 
@@ -34,6 +32,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
+	"github.com/aws/amazon-ssm-agent/agent/health/watchdog"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
@@ -53,6 +52,7 @@ const (
 // Processor contains the logic for processing association
 type Processor struct {
 	pollJob            *scheduler.Job
+	pollHeartbeat      *watchdog.Heartbeat
 	assocSvc           service.T
 	complianceUploader complianceUploader.T
 	context            context.T
@@ -60,6 +60,7 @@ type Processor struct {
 	proc               processor.Processor
 	resChan            chan contracts.DocumentResult
 	onBoot             bool
+	maxConcurrency     int
 }
 
 var lock sync.RWMutex
@@ -82,9 +83,15 @@ func NewAssociationProcessor(context context.T) *Processor {
 	assocSvc := service.NewAssociationService(name)
 	uploader := complianceUploader.NewComplianceUploader(context)
 
+	maxConcurrency := config.Ssm.AssociationMaxConcurrency
+	if maxConcurrency < documentWorkersLimit {
+		maxConcurrency = documentWorkersLimit
+	}
+	schedulemanager.SetErrorThreshold(config.Ssm.AssociationErrorThreshold)
+
 	//TODO Rename everything to service and move package to framework
 	//association has no cancel worker
-	proc := processor.NewEngineProcessor(assocContext, documentWorkersLimit, documentWorkersLimit, []contracts.DocumentType{contracts.Association})
+	proc := processor.NewEngineProcessor(assocContext, maxConcurrency, documentWorkersLimit, []contracts.DocumentType{contracts.Association})
 	return &Processor{
 		context:            assocContext,
 		assocSvc:           assocSvc,
@@ -92,6 +99,7 @@ func NewAssociationProcessor(context context.T) *Processor {
 		agentInfo:          &agentInfo,
 		proc:               proc,
 		onBoot:             true,
+		maxConcurrency:     maxConcurrency,
 	}
 }
 
@@ -110,8 +118,15 @@ func (p *Processor) ModuleExecute(context context.T) {
 	}
 	p.InitializeAssociationProcessor()
 	p.SetPollJob(job)
+
+	// the polls can be apart by up to twice the frequency after an agent restart
+	heartbeatTimeout := time.Duration(2*associationFrequenceMinutes+5) * time.Minute
+	p.pollHeartbeat = watchdog.Register(name, heartbeatTimeout, func() {
+		assocScheduler.ScheduleNextRun(p.pollJob)
+	})
 }
 func (p *Processor) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	p.pollHeartbeat.Unregister()
 	assocScheduler.Stop(p.pollJob)
 	signal.Stop()
 	p.proc.Stop(stopType)
@@ -149,6 +164,7 @@ func (p *Processor) SetPollJob(job *scheduler.Job) {
 // ProcessAssociation poll and process all the associations
 func (p *Processor) ProcessAssociation() {
 	log := p.context.Log()
+	defer p.pollHeartbeat.Beat()
 	associations := []*model.InstanceAssociation{}
 
 	log.Debug("running ProcessAssociation")
@@ -278,6 +294,11 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 		err                  error
 	)
 
+	if running := schedulemanager.RunningAssociationCount(); running >= p.maxConcurrency {
+		log.Debugf("%v associations are running, waiting for one of them to complete", running)
+		return
+	}
+
 	if scheduledAssociation, err = schedulemanager.LoadNextScheduledAssociation(log); err != nil {
 		log.Errorf("Unable to get next scheduled association, %v, system will retry later", err)
 		return
@@ -361,6 +382,7 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 			*scheduledAssociation.Association.DocumentVersion,
 			contracts.AssociationStatusFailed,
 			time.Now().UTC())
+		schedulemanager.CompleteAssociation(log, *scheduledAssociation.Association.AssociationId, true)
 		return
 	}
 
@@ -380,9 +402,15 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 
 	log.Debug("runScheduledAssociation submitting document")
 
+	schedulemanager.MarkAssociationRunning(log, docState.DocumentInformation.AssociationID)
 	p.proc.Submit(*docState)
 
 	log.Debug("runScheduledAssociation submitted document")
+
+	// look for other associations due while there are free workers
+	if schedulemanager.RunningAssociationCount() < p.maxConcurrency {
+		signal.ExecuteAssociation(log)
+	}
 }
 
 func isAssociationTimedOut(assoc *model.InstanceAssociation) bool {
@@ -574,14 +602,11 @@ func (r *Processor) listenToResponses() {
 					contracts.AssociationStatusPending,
 				)
 			}
+			schedulemanager.CompleteAssociation(log, res.AssociationID,
+				res.Status == contracts.ResultStatusFailed || res.Status == contracts.ResultStatusTimedOut)
 			instanceID, _ := sys.InstanceID()
-			//clean association logs once the document state is moved to completed
-			//clean completed document state files and orchestration dirs. Takes care of only files generated by association in the folder
-			go assocBookkeeping.DeleteOldOrchestrationLogs(log,
-				instanceID,
-				r.context.AppConfig().Agent.OrchestrationRootDir,
-				r.context.AppConfig().Ssm.AssociationLogsRetentionDurationHours,
-				isAssociationLogFile)
+			//prune the execution history once the document state is moved to completed
+			go assocBookkeeping.PruneExecutionHistory(log, instanceID, r.context.AppConfig())
 			//TODO move this part to service
 			schedulemanager.UpdateNextScheduledDate(log, res.AssociationID)
 			signal.ExecuteAssociation(log)
@@ -591,12 +616,6 @@ func (r *Processor) listenToResponses() {
 	}
 }
 
-// isAssociationLogFile checks whether the file name passed is of the format of Association Files
-func isAssociationLogFile(fileName string) (matched bool) {
-	matched, _ = regexp.MatchString("^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}\\.[0-9]{4}-[0-9]{2}-[0-9]{2}.*$", fileName)
-	return
-}
-
 // buildOutput build the output message for association update
 // TODO: totalNumberOfPlugins is no longer needed, we can get the same value from len(runtimeStatuses)
 func buildOutput(runtimeStatuses map[string]*contracts.PluginRuntimeStatus, totalNumberOfPlugins int) (outputSummary, outputUrl string) {
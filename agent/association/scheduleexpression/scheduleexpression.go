@@ -16,6 +16,7 @@
 package scheduleexpression
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -43,18 +44,18 @@ func CreateScheduleExpression(log log.T, scheduleExpression string) (ScheduleExp
 	if strings.HasPrefix(lowerCasedScheduledExpression, expressionTypeCron) {
 		err := validateCronExpression(log, scheduleExpression)
 		if err != nil {
-			return nil, fmt.Errorf(err.Error())
+			return nil, err
 		}
 
 		cronExpression := scheduleExpression[len(expressionTypeCron)+1 : len(scheduleExpression)-1]
-		parsedCronExpression, err := cronexpr.Parse(cronExpression)
+		parsedCronExpression, err := parseCronExpression(cronExpression)
 
 		if err == nil {
 			return parsedCronExpression, nil
 		} else {
 			message := fmt.Sprintf("Error %v received while parsing cron expression %v", err, scheduleExpression)
 			log.Error(message)
-			return nil, fmt.Errorf(message)
+			return nil, errors.New(message)
 		}
 	}
 
@@ -66,13 +67,57 @@ func CreateScheduleExpression(log log.T, scheduleExpression string) (ScheduleExp
 		} else {
 			message := fmt.Sprintf("An error %v received while parsing rate expression %v", err, scheduleExpression)
 			log.Error(message)
-			return nil, fmt.Errorf(message)
+			return nil, errors.New(message)
 		}
 	}
 
 	return nil, fmt.Errorf("Unknown expression type detected in expression %v", scheduleExpression)
 }
 
+// cronExpression is a cron expression evaluated in a time zone
+type cronExpression struct {
+	expression *cronexpr.Expression
+	location   *time.Location
+}
+
+// Next returns the next time matching the expression in its time zone after fromTime
+func (c *cronExpression) Next(fromTime time.Time) time.Time {
+	return c.expression.Next(fromTime.In(c.location))
+}
+
+// parseCronExpression parses the fields of a cron expression, optionally preceded by the time zone the expression
+// is evaluated in, e.g. "TZ=Europe/Paris 0 2 ? * MON-FRI *" for every weekday at 02:00 in Paris. "TZ=Local" stands for
+// the time zone of the instance. Expressions without time zone are evaluated in UTC.
+func parseCronExpression(expression string) (ScheduleExpression, error) {
+	location := time.UTC
+	fields := strings.Fields(expression)
+	if len(fields) > 0 {
+		if timeZone, found := timeZoneField(fields[0]); found {
+			var err error
+			if location, err = time.LoadLocation(timeZone); err != nil || timeZone == "" {
+				return nil, fmt.Errorf("invalid time zone %v", timeZone)
+			}
+			expression = strings.Join(fields[1:], " ")
+		}
+	}
+
+	parsed, err := cronexpr.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &cronExpression{expression: parsed, location: location}, nil
+}
+
+// timeZoneField returns the time zone of a TZ= or CRON_TZ= field
+func timeZoneField(field string) (timeZone string, found bool) {
+	for _, prefix := range []string{"TZ=", "CRON_TZ="} {
+		if len(field) >= len(prefix) && strings.EqualFold(field[:len(prefix)], prefix) {
+			return field[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
 func validateCronExpression(log log.T, scheduleExpression string) error {
 	cronRegularExpression := regexp.MustCompile("(?i)(cron\\(.*\\))")
 	result := cronRegularExpression.FindAllStringSubmatch(scheduleExpression, -1)
@@ -80,20 +125,20 @@ func validateCronExpression(log log.T, scheduleExpression string) error {
 
 	if len(result) != 1 {
 		log.Error(errorMessage)
-		return fmt.Errorf(errorMessage)
+		return errors.New(errorMessage)
 	}
 
 	match := result[0]
 	if match == nil {
 		log.Error(errorMessage)
-		return fmt.Errorf(errorMessage)
+		return errors.New(errorMessage)
 	}
 
 	if len(match) == 2 && match[1] != "" {
 		// Ensure we do not match cron(0 0 0/1 * * ? *)abc
 		if len(match[1]) != len(scheduleExpression) {
 			log.Error(errorMessage)
-			return fmt.Errorf(errorMessage)
+			return errors.New(errorMessage)
 		}
 	}
 
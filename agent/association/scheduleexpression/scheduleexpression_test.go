@@ -15,6 +15,7 @@ package scheduleexpression
 
 import (
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/stretchr/testify/assert"
@@ -131,3 +132,57 @@ func TestParseReturnsErrorWhenScheduleExpressionIsOfUnknownType(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, "Unknown expression type detected in expression at(12:00)", err.Error())
 }
+
+func TestCronExpressionWithoutTimeZoneIsEvaluatedInUTC(t *testing.T) {
+	// Assemble
+	logger := log.DefaultLogger()
+	parsedExpression, err := CreateScheduleExpression(logger, "cron(0 2 ? * MON-FRI *)")
+	assert.Nil(t, err)
+
+	// Act
+	next := parsedExpression.Next(time.Date(2018, time.March, 2, 3, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.Equal(t, time.Date(2018, time.March, 5, 2, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestCronExpressionWithTimeZoneIsEvaluatedInTimeZone(t *testing.T) {
+	// Assemble
+	logger := log.DefaultLogger()
+	parsedExpression, err := CreateScheduleExpression(logger, "cron(TZ=America/New_York 0 2 ? * MON-FRI *)")
+	assert.Nil(t, err)
+
+	// Act
+	// Friday 2018-03-02 03:00 UTC is Thursday 22:00 in New York
+	next := parsedExpression.Next(time.Date(2018, time.March, 2, 3, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.Equal(t, time.Date(2018, time.March, 2, 7, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestCronExpressionWithTimeZoneFollowsDaylightSavingTime(t *testing.T) {
+	// Assemble
+	logger := log.DefaultLogger()
+	parsedExpression, err := CreateScheduleExpression(logger, "cron(CRON_TZ=Europe/Paris 0 2 ? * MON-FRI *)")
+	assert.Nil(t, err)
+
+	// Act
+	winter := parsedExpression.Next(time.Date(2018, time.March, 23, 3, 0, 0, 0, time.UTC))
+	summer := parsedExpression.Next(time.Date(2018, time.April, 2, 3, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.Equal(t, time.Date(2018, time.March, 26, 0, 0, 0, 0, time.UTC), winter.UTC())
+	assert.Equal(t, time.Date(2018, time.April, 3, 0, 0, 0, 0, time.UTC), summer.UTC())
+}
+
+func TestParseReturnsErrorForUnknownTimeZone(t *testing.T) {
+	// Assemble
+	logger := log.DefaultLogger()
+
+	// Act
+	parsedExpression, err := CreateScheduleExpression(logger, "cron(TZ=Mars/Olympus 0 2 ? * MON-FRI *)")
+
+	// Assert
+	assert.Nil(t, parsedExpression)
+	assert.NotNil(t, err)
+}
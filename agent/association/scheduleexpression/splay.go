@@ -0,0 +1,77 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduleexpression
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// splayFieldExpression matches the SPLAY=<duration> field of a schedule expression, e.g. "rate(SPLAY=10m 30 minutes)"
+var splayFieldExpression = regexp.MustCompile(`(?i)\s*\bsplay=([^\s)]*)`)
+
+// ExtractSplayWindow removes the SPLAY=<duration> field from the schedule expression and returns the remaining
+// expression together with the splay window. The window is zero when the expression has no splay field.
+func ExtractSplayWindow(scheduleExpression string) (expression string, window time.Duration, err error) {
+	matches := splayFieldExpression.FindAllStringSubmatch(scheduleExpression, -1)
+	if len(matches) == 0 {
+		return scheduleExpression, 0, nil
+	}
+	if len(matches) > 1 {
+		return "", 0, fmt.Errorf("Schedule expression %v has more than one splay field", scheduleExpression)
+	}
+
+	window, err = time.ParseDuration(matches[0][1])
+	if err != nil || window <= 0 {
+		return "", 0, fmt.Errorf("Schedule expression %v has an invalid splay window %v", scheduleExpression, matches[0][1])
+	}
+
+	expression = splayFieldExpression.ReplaceAllString(scheduleExpression, "")
+	return strings.Replace(expression, "( ", "(", 1), window, nil
+}
+
+// SplayOffset returns an offset in the range [0, window) which is always the same for the given key, so that
+// instances sharing an association spread their executions over the window without drifting between runs.
+func SplayOffset(window time.Duration, key string) time.Duration {
+	seconds := uint64(window / time.Second)
+	if seconds == 0 {
+		return 0
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return time.Duration(hash.Sum64()%seconds) * time.Second
+}
+
+// splayedExpression delays every occurrence of a schedule expression by a fixed offset
+type splayedExpression struct {
+	expression ScheduleExpression
+	offset     time.Duration
+}
+
+// Splay returns a schedule expression whose occurrences are those of the given expression delayed by offset
+func Splay(expression ScheduleExpression, offset time.Duration) ScheduleExpression {
+	if offset <= 0 {
+		return expression
+	}
+	return &splayedExpression{expression: expression, offset: offset}
+}
+
+// Next returns the next delayed occurrence after fromTime
+func (s *splayedExpression) Next(fromTime time.Time) time.Time {
+	return s.expression.Next(fromTime.Add(-s.offset)).Add(s.offset)
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduleexpression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractSplayWindow(t *testing.T) {
+	testCases := []struct {
+		input      string
+		expression string
+		window     time.Duration
+	}{
+		{"rate(30 minutes)", "rate(30 minutes)", 0},
+		{"rate(SPLAY=10m 30 minutes)", "rate(30 minutes)", 10 * time.Minute},
+		{"cron(TZ=Europe/Paris splay=1h 0 2 ? * MON-FRI *)", "cron(TZ=Europe/Paris 0 2 ? * MON-FRI *)", time.Hour},
+		{"cron(0 2 ? * MON-FRI * SPLAY=90s)", "cron(0 2 ? * MON-FRI *)", 90 * time.Second},
+	}
+
+	for _, testCase := range testCases {
+		expression, window, err := ExtractSplayWindow(testCase.input)
+		assert.Nil(t, err, testCase.input)
+		assert.Equal(t, testCase.expression, expression, testCase.input)
+		assert.Equal(t, testCase.window, window, testCase.input)
+	}
+}
+
+func TestExtractSplayWindowReturnsErrorForInvalidWindow(t *testing.T) {
+	for _, input := range []string{"rate(SPLAY=abc 30 minutes)", "rate(SPLAY= 30 minutes)", "rate(SPLAY=-5m 30 minutes)", "rate(SPLAY=5m SPLAY=6m 30 minutes)"} {
+		_, _, err := ExtractSplayWindow(input)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestSplayOffsetIsStableAndWithinWindow(t *testing.T) {
+	window := 30 * time.Minute
+	offset := SplayOffset(window, "i-1234567890abcdef0/association")
+
+	assert.Equal(t, offset, SplayOffset(window, "i-1234567890abcdef0/association"))
+	assert.True(t, offset >= 0 && offset < window)
+	assert.Equal(t, time.Duration(0), SplayOffset(time.Millisecond, "i-1234567890abcdef0/association"))
+}
+
+func TestSplayDelaysEveryOccurrence(t *testing.T) {
+	parsedExpression, err := CreateScheduleExpression(log.DefaultLogger(), "cron(0 2 * * ? *)")
+	assert.Nil(t, err)
+	splayed := Splay(parsedExpression, 10*time.Minute)
+
+	first := splayed.Next(time.Date(2018, time.March, 1, 0, 0, 0, 0, time.UTC))
+	second := splayed.Next(first)
+
+	assert.Equal(t, time.Date(2018, time.March, 1, 2, 10, 0, 0, time.UTC), first.UTC())
+	assert.Equal(t, time.Date(2018, time.March, 2, 2, 10, 0, 0, time.UTC), second.UTC())
+}
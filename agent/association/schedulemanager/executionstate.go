@@ -0,0 +1,177 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package schedulemanager
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// AssociationExecutionState represents the local execution state of an association
+type AssociationExecutionState struct {
+	AssociationID       string
+	Name                string
+	Running             bool
+	ConsecutiveFailures int
+	Suspended           bool
+	NextScheduledDate   *time.Time `json:",omitempty"`
+}
+
+// executionState holds the local execution bookkeeping of the associations, guarded by lock
+type executionState struct {
+	running        map[string]bool
+	failures       map[string]int
+	checksums      map[string]string
+	errorThreshold int
+}
+
+var state = executionState{
+	running:   make(map[string]bool),
+	failures:  make(map[string]int),
+	checksums: make(map[string]string),
+}
+
+// executionStatePath is the file the execution state is saved to, so that it can be inspected with ssm-cli
+var executionStatePath = filepath.Join(appconfig.DefaultDataStorePath, appconfig.AssociationStateFileName)
+
+// SetErrorThreshold sets the number of consecutive failed runs after which an association is suspended,
+// associations are never suspended when threshold is 0
+func SetErrorThreshold(threshold int) {
+	lock.Lock()
+	defer lock.Unlock()
+	state.errorThreshold = threshold
+}
+
+// MarkAssociationRunning records that the given association has been submitted for execution
+func MarkAssociationRunning(log log.T, associationID string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	state.running[associationID] = true
+	saveExecutionState(log)
+}
+
+// RunningAssociationCount returns the number of associations currently executing
+func RunningAssociationCount() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return len(state.running)
+}
+
+// CompleteAssociation records the outcome of an association run and suspends the association once it has failed
+// as many consecutive times as the error threshold
+func CompleteAssociation(log log.T, associationID string, failed bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	delete(state.running, associationID)
+	if !failed {
+		delete(state.failures, associationID)
+	} else {
+		state.failures[associationID]++
+		if state.isSuspended(associationID) {
+			log.Warnf("Association %v failed %v consecutive times, suspending it until its content changes",
+				associationID, state.failures[associationID])
+		}
+	}
+	saveExecutionState(log)
+}
+
+// IsAssociationSuspended returns true if the given association reached the error threshold
+func IsAssociationSuspended(associationID string) bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return state.isSuspended(associationID)
+}
+
+// ExecutionStates returns the local execution state of all the cached associations
+func ExecutionStates() []AssociationExecutionState {
+	lock.RLock()
+	defer lock.RUnlock()
+	return executionStates()
+}
+
+// isSuspended returns true if the association failed at least errorThreshold consecutive times
+func (s *executionState) isSuspended(associationID string) bool {
+	return s.errorThreshold > 0 && s.failures[associationID] >= s.errorThreshold
+}
+
+// isSchedulable returns true if the association can be picked for execution
+func (s *executionState) isSchedulable(assoc *model.InstanceAssociation) bool {
+	associationID := *assoc.Association.AssociationId
+	return !s.running[associationID] && !s.isSuspended(associationID)
+}
+
+// refresh drops the state of the associations no longer cached and resets the failures of the associations
+// whose content changed
+func (s *executionState) refresh(assocs []*model.InstanceAssociation) {
+	checksums := make(map[string]string)
+	for _, assoc := range assocs {
+		associationID := *assoc.Association.AssociationId
+		checksum := aws.StringValue(assoc.Association.Checksum)
+		if previous, found := s.checksums[associationID]; found && previous != checksum {
+			delete(s.failures, associationID)
+		}
+		checksums[associationID] = checksum
+	}
+
+	for associationID := range s.failures {
+		if _, found := checksums[associationID]; !found {
+			delete(s.failures, associationID)
+		}
+	}
+	s.checksums = checksums
+}
+
+// executionStates builds the execution state of the cached associations, lock must be held by the caller
+func executionStates() []AssociationExecutionState {
+	states := []AssociationExecutionState{}
+	for _, assoc := range associations {
+		associationID := *assoc.Association.AssociationId
+		states = append(states, AssociationExecutionState{
+			AssociationID:       associationID,
+			Name:                aws.StringValue(assoc.Association.Name),
+			Running:             state.running[associationID],
+			ConsecutiveFailures: state.failures[associationID],
+			Suspended:           state.isSuspended(associationID),
+			NextScheduledDate:   assoc.NextScheduledDate,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].AssociationID < states[j].AssociationID })
+	return states
+}
+
+// saveExecutionState saves the execution state for ssm-cli, lock must be held by the caller
+func saveExecutionState(log log.T) {
+	content, err := json.MarshalIndent(executionStates(), "", "  ")
+	if err != nil {
+		log.Errorf("failed to marshal the association execution state: %v", err)
+		return
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(executionStatePath)); err != nil {
+		log.Errorf("failed to create directory %v: %v", filepath.Dir(executionStatePath), err)
+		return
+	}
+	if err = fileutil.WriteAllText(executionStatePath, string(content)); err != nil {
+		log.Errorf("failed to save the association execution state to %v: %v", executionStatePath, err)
+	}
+}
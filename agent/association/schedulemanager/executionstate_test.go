@@ -0,0 +1,120 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package schedulemanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupExecutionState(t *testing.T, threshold int) (logger log.T, cleanup func()) {
+	dir, err := ioutil.TempDir("", "schedulemanager")
+	assert.Nil(t, err)
+	executionStatePath = filepath.Join(dir, "associationstate.json")
+	state = executionState{
+		running:   make(map[string]bool),
+		failures:  make(map[string]int),
+		checksums: make(map[string]string),
+	}
+	SetErrorThreshold(threshold)
+	return log.NewMockLog(), func() { os.RemoveAll(dir) }
+}
+
+func dueAssociation(associationID string, checksum string) *model.InstanceAssociation {
+	return &model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{
+			AssociationId:     aws.String(associationID),
+			Name:              aws.String("document"),
+			Checksum:          aws.String(checksum),
+			DetailedStatus:    aws.String("Pending"),
+			LastExecutionDate: aws.Time(time.Now().Add(-time.Hour)),
+		},
+	}
+}
+
+func TestRunningAssociationIsNotScheduledAgain(t *testing.T) {
+	logger, cleanup := setupExecutionState(t, 0)
+	defer cleanup()
+	Refresh(logger, []*model.InstanceAssociation{dueAssociation("first", "a"), dueAssociation("second", "a")})
+
+	MarkAssociationRunning(logger, "first")
+	next, err := LoadNextScheduledAssociation(logger)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "second", *next.Association.AssociationId)
+	assert.Equal(t, 1, RunningAssociationCount())
+
+	CompleteAssociation(logger, "first", false)
+	assert.Equal(t, 0, RunningAssociationCount())
+}
+
+func TestAssociationIsSuspendedAtErrorThresholdUntilItChanges(t *testing.T) {
+	logger, cleanup := setupExecutionState(t, 2)
+	defer cleanup()
+	Refresh(logger, []*model.InstanceAssociation{dueAssociation("first", "a")})
+
+	CompleteAssociation(logger, "first", true)
+	assert.False(t, IsAssociationSuspended("first"))
+	CompleteAssociation(logger, "first", true)
+	assert.True(t, IsAssociationSuspended("first"))
+
+	next, err := LoadNextScheduledAssociation(logger)
+	assert.Nil(t, err)
+	assert.Nil(t, next)
+	assert.Nil(t, LoadNextScheduledDate(logger))
+
+	// refreshing with the same content keeps the association suspended
+	Refresh(logger, []*model.InstanceAssociation{dueAssociation("first", "a")})
+	assert.True(t, IsAssociationSuspended("first"))
+
+	// a new version of the association is scheduled again
+	Refresh(logger, []*model.InstanceAssociation{dueAssociation("first", "b")})
+	assert.False(t, IsAssociationSuspended("first"))
+}
+
+func TestSuccessfulRunResetsFailures(t *testing.T) {
+	logger, cleanup := setupExecutionState(t, 2)
+	defer cleanup()
+	Refresh(logger, []*model.InstanceAssociation{dueAssociation("first", "a")})
+
+	CompleteAssociation(logger, "first", true)
+	CompleteAssociation(logger, "first", false)
+	CompleteAssociation(logger, "first", true)
+
+	assert.False(t, IsAssociationSuspended("first"))
+	assert.Equal(t, 1, ExecutionStates()[0].ConsecutiveFailures)
+}
+
+func TestExecutionStateIsSaved(t *testing.T) {
+	logger, cleanup := setupExecutionState(t, 1)
+	defer cleanup()
+	Refresh(logger, []*model.InstanceAssociation{dueAssociation("first", "a")})
+
+	CompleteAssociation(logger, "first", true)
+
+	content, err := fileutil.ReadAllText(executionStatePath)
+	assert.Nil(t, err)
+	assert.Contains(t, content, `"AssociationID": "first"`)
+	assert.Contains(t, content, `"Suspended": true`)
+}
@@ -66,6 +66,9 @@ func Refresh(log log.T, assocs []*model.InstanceAssociation) {
 		}
 	}
 
+	state.refresh(associations)
+	saveExecutionState(log)
+
 	complianceModel.RefreshAssociationComplianceItems(associations)
 
 	log.Infof("Schedule manager refreshed with %v associations, %v new assocations associated", len(associations), numberOfNewAssoc)
@@ -82,7 +85,7 @@ func LoadNextScheduledAssociation(log log.T) (*model.InstanceAssociation, error)
 
 	for _, assoc := range associations {
 		currentTime := time.Now().UTC()
-		if assoc.NextScheduledDate == nil {
+		if assoc.NextScheduledDate == nil || !state.isSchedulable(assoc) {
 			continue
 		}
 
@@ -107,7 +110,7 @@ func LoadNextScheduledDate(log log.T) *time.Time {
 
 	var nextScheduleDate *time.Time
 	for _, assoc := range associations {
-		if assoc.NextScheduledDate == nil {
+		if assoc.NextScheduledDate == nil || !state.isSchedulable(assoc) {
 			continue
 		}
 
@@ -135,6 +138,7 @@ func UpdateNextScheduledDate(log log.T, associationID string) {
 			if assoc.NextScheduledDate != nil {
 				log.Infof("Scheduling association %v, setting next ScheduledDate to %v", *assoc.Association.AssociationId, times.ToIsoDashUTC(*assoc.NextScheduledDate))
 			}
+			saveExecutionState(log)
 			break
 		}
 	}
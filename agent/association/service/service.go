@@ -112,9 +112,14 @@ func (s *AssociationService) CreateNewServiceIfUnHealthy(log log.T) {
 
 		// reset stop policy and let the scheduler start the polling after pollMessageFrequencyMinutes timeout
 		s.stopPolicy.ResetErrorCount()
+		ssmsvc.ReportEndpointFailure(log)
 		s.ssmSvc = ssmsvc.NewService()
 		return
 	}
+
+	if ssmsvc.EndpointFailback(log) {
+		s.ssmSvc = ssmsvc.NewService()
+	}
 }
 
 // ListInstanceAssociations will get the Association and related document string
@@ -0,0 +1,161 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package atrest encrypts the document states and the replies the agent persists locally, as the documents may
+// contain sensitive parameters. The content is encrypted with AES-GCM using a key generated on the instance and
+// stored in a file accessible to administrators only, protected with DPAPI on Windows.
+package atrest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+const keySize = 32
+
+// header marks the sealed content, the content without it is plaintext written while the encryption was disabled
+var header = []byte("SSMENC1\n")
+
+var (
+	key     []byte
+	keyLock sync.Mutex
+)
+
+// dependencies, replaced in the tests
+var (
+	loadConfig = appconfig.Config
+	keyPath    = filepath.Join(appconfig.DefaultDataStorePath, "atrest", "key")
+)
+
+// Enabled returns true if the encryption at rest is enabled in appconfig
+func Enabled() bool {
+	config, err := loadConfig(false)
+	return err == nil && config.Agent.EncryptStateAtRest
+}
+
+// Seal encrypts the content when the encryption at rest is enabled, it returns the content unchanged otherwise.
+// The key is generated the first time content is sealed.
+func Seal(content []byte) ([]byte, error) {
+	if !Enabled() {
+		return content, nil
+	}
+	gcm, err := newCipher(true)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := append(append([]byte{}, header...), nonce...)
+	return gcm.Seal(sealed, nonce, content, header), nil
+}
+
+// Open decrypts the sealed content, the content which is not sealed is returned unchanged so that the files
+// written before the encryption was enabled are still read
+func Open(content []byte) ([]byte, error) {
+	if !IsSealed(content) {
+		return content, nil
+	}
+	gcm, err := newCipher(false)
+	if err != nil {
+		return nil, err
+	}
+	content = content[len(header):]
+	if len(content) < gcm.NonceSize() {
+		return nil, errors.New("sealed content is truncated")
+	}
+	nonce := content[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, content[gcm.NonceSize():], header)
+}
+
+// IsSealed returns true if the content was encrypted by Seal
+func IsSealed(content []byte) bool {
+	return bytes.HasPrefix(content, header)
+}
+
+// ReadFile reads the file and decrypts its content if it is sealed
+func ReadFile(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Open(content)
+}
+
+// UnmarshalFile reads the json file, sealed or not, into dest
+func UnmarshalFile(path string, dest interface{}) error {
+	content, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, dest)
+}
+
+// newCipher returns the AES-GCM cipher of the key of the instance, the key is generated if it does not exist and
+// generate is true
+func newCipher(generate bool) (cipher.AEAD, error) {
+	keyLock.Lock()
+	defer keyLock.Unlock()
+
+	if key == nil {
+		loaded, err := loadKey(generate)
+		if err != nil {
+			return nil, err
+		}
+		key = loaded
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadKey reads the key file, or generates the key and writes the file if it does not exist and generate is true
+func loadKey(generate bool) ([]byte, error) {
+	protected, err := ioutil.ReadFile(keyPath)
+	if err == nil {
+		return unprotect(protected)
+	}
+	if !os.IsNotExist(err) || !generate {
+		return nil, err
+	}
+
+	newKey := make([]byte, keySize)
+	if _, err = io.ReadFull(rand.Reader, newKey); err != nil {
+		return nil, err
+	}
+	if protected, err = protect(newKey); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(keyPath), appconfig.ReadWriteExecuteAccess); err != nil {
+		return nil, err
+	}
+	if err = fileutil.HardenedWriteFile(keyPath, protected); err != nil {
+		return nil, err
+	}
+	return newKey, nil
+}
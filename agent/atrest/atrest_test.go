@@ -0,0 +1,120 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package atrest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// setUp enables or disables the encryption and keeps the key in a temporary folder
+func setUp(t *testing.T, enabled bool) func() {
+	dir, err := ioutil.TempDir("", "atrest")
+	assert.NoError(t, err)
+	savedLoadConfig, savedKeyPath := loadConfig, keyPath
+	loadConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.SsmagentConfig{}
+		config.Agent.EncryptStateAtRest = enabled
+		return config, nil
+	}
+	keyPath = filepath.Join(dir, "atrest", "key")
+	key = nil
+	return func() {
+		loadConfig, keyPath, key = savedLoadConfig, savedKeyPath, nil
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSealAndOpen(t *testing.T) {
+	defer setUp(t, true)()
+	content := []byte(`{"Parameters":{"password":"secret"}}`)
+
+	sealed, err := Seal(content)
+	assert.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, string(sealed), "secret")
+
+	opened, err := Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, content, opened)
+
+	// the key is persisted and read again by the next agent process
+	key = nil
+	opened, err = Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, content, opened)
+}
+
+func TestSealWhenDisabled(t *testing.T) {
+	defer setUp(t, false)()
+	content := []byte(`{"Parameters":{}}`)
+
+	sealed, err := Seal(content)
+	assert.NoError(t, err)
+	assert.Equal(t, content, sealed)
+	_, err = os.Stat(keyPath)
+	assert.True(t, os.IsNotExist(err), "no key is generated while the encryption is disabled")
+}
+
+func TestOpenPlaintext(t *testing.T) {
+	defer setUp(t, true)()
+	content := []byte(`{"Parameters":{}}`)
+
+	opened, err := Open(content)
+	assert.NoError(t, err)
+	assert.Equal(t, content, opened)
+}
+
+func TestOpenTamperedContent(t *testing.T) {
+	defer setUp(t, true)()
+	sealed, err := Seal([]byte("content"))
+	assert.NoError(t, err)
+
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = Open(sealed)
+	assert.Error(t, err)
+
+	_, err = Open(header)
+	assert.Error(t, err)
+}
+
+func TestOpenWithoutKey(t *testing.T) {
+	defer setUp(t, true)()
+	sealed, err := Seal([]byte("content"))
+	assert.NoError(t, err)
+
+	key = nil
+	os.Remove(keyPath)
+	_, err = Open(sealed)
+	assert.Error(t, err)
+	_, err = os.Stat(keyPath)
+	assert.True(t, os.IsNotExist(err), "a new key is not generated to open content")
+}
+
+func TestUnmarshalFile(t *testing.T) {
+	defer setUp(t, true)()
+	sealed, err := Seal([]byte(`{"Name":"value"}`))
+	assert.NoError(t, err)
+	path := filepath.Join(filepath.Dir(keyPath), "state")
+	assert.NoError(t, ioutil.WriteFile(path, sealed, 0600))
+
+	var dest struct{ Name string }
+	assert.NoError(t, UnmarshalFile(path, &dest))
+	assert.Equal(t, "value", dest.Name)
+}
@@ -0,0 +1,81 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package atrest
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// cryptProtectUIForbidden fails the calls requiring a user interface instead of prompting
+const cryptProtectUIForbidden = 0x1
+
+var (
+	crypt32                = syscall.NewLazyDLL("crypt32.dll")
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procCryptProtectData   = crypt32.NewProc("CryptProtectData")
+	procCryptUnprotectData = crypt32.NewProc("CryptUnprotectData")
+	procLocalFree          = kernel32.NewProc("LocalFree")
+)
+
+// dataBlob is the DATA_BLOB structure of the DPAPI functions
+type dataBlob struct {
+	cbData uint32
+	pbData *byte
+}
+
+func newDataBlob(data []byte) *dataBlob {
+	if len(data) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{cbData: uint32(len(data)), pbData: &data[0]}
+}
+
+// bytes copies the content of a blob allocated by the DPAPI functions and frees it
+func (b *dataBlob) bytes() []byte {
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(b.pbData)))
+	content := make([]byte, b.cbData)
+	copy(content, (*[1 << 30]byte)(unsafe.Pointer(b.pbData))[:b.cbData:b.cbData])
+	return content
+}
+
+// protect encrypts the key with DPAPI for the account of the agent
+func protect(key []byte) ([]byte, error) {
+	var out dataBlob
+	r, _, err := procCryptProtectData.Call(
+		uintptr(unsafe.Pointer(newDataBlob(key))),
+		0, 0, 0, 0,
+		cryptProtectUIForbidden,
+		uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, err
+	}
+	return out.bytes(), nil
+}
+
+// unprotect decrypts the key protected with DPAPI
+func unprotect(protected []byte) ([]byte, error) {
+	var out dataBlob
+	r, _, err := procCryptUnprotectData.Call(
+		uintptr(unsafe.Pointer(newDataBlob(protected))),
+		0, 0, 0, 0,
+		cryptProtectUIForbidden,
+		uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, err
+	}
+	return out.bytes(), nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package auditlog writes the agent lifecycle events and a summary of the executed documents
+// to a dedicated event log source, so that the pipelines collecting the event logs capture them,
+// and records the executed documents in a local hash chained audit log.
+package auditlog
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// Source is the name of the event source the audit events are written to
+const Source = "AmazonSSMAgentAudit"
+
+// Event ids of the audit events, kept within the range supported by the EventCreate message file
+const (
+	EventIDAgentStarted      uint32 = 100
+	EventIDAgentStopped      uint32 = 101
+	EventIDDocumentSucceeded uint32 = 200
+	EventIDDocumentFailed    uint32 = 201
+	EventIDDocumentCancelled uint32 = 202
+)
+
+// writer is the subset of the event log operations used to write the audit events
+type writer interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+	Close() error
+}
+
+var (
+	mut   sync.Mutex
+	out   writer
+	local *chain
+
+	openWriter   = defaultOpenWriter
+	localLogPath = func() string { return LocalAuditLogPath(appconfig.DefaultDataStorePath) }
+)
+
+// Open registers the event source and starts writing the audit events, and opens the local audit log,
+// when enabled in the config. Without an event log on the platform the audit events are dropped.
+func Open(log log.T, config appconfig.SsmagentConfig) {
+	mut.Lock()
+	defer mut.Unlock()
+	if config.Agent.LocalAuditLog && local == nil {
+		path := localLogPath()
+		c, err := openChain(log, path)
+		if err != nil {
+			log.Warnf("failed to open the audit log %v, executed documents won't be recorded: %v", path, err)
+		} else {
+			local = c
+		}
+	}
+
+	if !config.Agent.AuditToEventLog || out != nil {
+		return
+	}
+	w, err := openWriter()
+	if err != nil {
+		log.Warnf("failed to open the %v event log source, audit events won't be written: %v", Source, err)
+		return
+	}
+	out = w
+}
+
+// Close stops writing the audit events and recording the executed documents
+func Close(log log.T) {
+	mut.Lock()
+	defer mut.Unlock()
+	local = nil
+	if out == nil {
+		return
+	}
+	if err := out.Close(); err != nil {
+		log.Debugf("failed to close the %v event log source: %v", Source, err)
+	}
+	out = nil
+}
+
+// AgentStarted records the start of the agent
+func AgentStarted(log log.T) {
+	write(log, EventIDAgentStarted, fmt.Sprintf("Amazon SSM Agent %v started.", version.Version))
+}
+
+// AgentStopped records the stop of the agent
+func AgentStopped(log log.T) {
+	write(log, EventIDAgentStopped, fmt.Sprintf("Amazon SSM Agent %v stopped.", version.Version))
+}
+
+// DocumentCompleted records the summary of a document which reached a terminal status
+func DocumentCompleted(log log.T, state contracts.DocumentState, result contracts.DocumentResult) {
+	record(log, newRecord(state, result))
+	write(log, documentEventID(result.Status), documentSummary(state.DocumentInformation, result))
+}
+
+// documentEventID returns the event id matching the final status of a document
+func documentEventID(status contracts.ResultStatus) uint32 {
+	switch status {
+	case contracts.ResultStatusFailed, contracts.ResultStatusTimedOut:
+		return EventIDDocumentFailed
+	case contracts.ResultStatusCancelled:
+		return EventIDDocumentCancelled
+	default:
+		return EventIDDocumentSucceeded
+	}
+}
+
+// documentSummary formats the summary of a document as one "Key: Value" pair per line.
+// The service doesn't pass the identity of the requester to the agent, the command or
+// association id identifies the request in CloudTrail instead.
+func documentSummary(docInfo contracts.DocumentInfo, result contracts.DocumentResult) string {
+	var buf bytes.Buffer
+	buf.WriteString("Document execution completed.\r\n")
+	fields := []struct{ key, value string }{
+		{"DocumentName", result.DocumentName},
+		{"DocumentVersion", result.DocumentVersion},
+		{"CommandId", docInfo.CommandID},
+		{"AssociationId", docInfo.AssociationID},
+		{"InstanceId", docInfo.InstanceID},
+		{"Status", string(result.Status)},
+		{"Steps", fmt.Sprint(result.NPlugins)},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			fmt.Fprintf(&buf, "%v: %v\r\n", field.key, field.value)
+		}
+	}
+	return buf.String()
+}
+
+// write writes an audit event with the level matching its id
+func write(log log.T, eid uint32, msg string) {
+	mut.Lock()
+	defer mut.Unlock()
+	if out == nil {
+		return
+	}
+
+	var err error
+	switch eid {
+	case EventIDDocumentFailed:
+		err = out.Error(eid, msg)
+	case EventIDDocumentCancelled:
+		err = out.Warning(eid, msg)
+	default:
+		err = out.Info(eid, msg)
+	}
+	if err != nil {
+		log.Debugf("failed to write audit event %v: %v", eid, err)
+	}
+}
+
+// record appends the record to the local audit log, its hash goes to the agent log to be compared with the audit log
+func record(log log.T, rec Record) {
+	mut.Lock()
+	defer mut.Unlock()
+	if local == nil {
+		return
+	}
+
+	rec, err := local.append(rec)
+	if err != nil {
+		log.Errorf("failed to record execution %v in the audit log %v: %v", rec.ExecutionID, local.path, err)
+		return
+	}
+	log.Infof("recorded execution %v in the audit log as record %v with hash %v", rec.ExecutionID, rec.Sequence, rec.Hash)
+}
@@ -0,0 +1,134 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auditlog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+type event struct {
+	level string
+	eid   uint32
+	msg   string
+}
+
+type fakeWriter struct {
+	events []event
+	closed bool
+}
+
+func (w *fakeWriter) Info(eid uint32, msg string) error {
+	w.events = append(w.events, event{"info", eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Warning(eid uint32, msg string) error {
+	w.events = append(w.events, event{"warning", eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Error(eid uint32, msg string) error {
+	w.events = append(w.events, event{"error", eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func openFake() *fakeWriter {
+	fake := &fakeWriter{}
+	openWriter = func() (writer, error) { return fake, nil }
+	config := appconfig.DefaultConfig()
+	config.Agent.AuditToEventLog = true
+	Open(logger, config)
+	return fake
+}
+
+func TestAuditDisabledWritesNothing(t *testing.T) {
+	fake := &fakeWriter{}
+	openWriter = func() (writer, error) { return fake, nil }
+	defer func() { openWriter = defaultOpenWriter }()
+
+	Open(logger, appconfig.DefaultConfig())
+	AgentStarted(logger)
+
+	assert.Empty(t, fake.events)
+}
+
+func TestAuditOpenFailureWritesNothing(t *testing.T) {
+	openWriter = func() (writer, error) { return nil, errors.New("access denied") }
+	defer func() { openWriter = defaultOpenWriter }()
+
+	config := appconfig.DefaultConfig()
+	config.Agent.AuditToEventLog = true
+	Open(logger, config)
+	AgentStarted(logger)
+	Close(logger)
+}
+
+func TestAuditAgentLifecycle(t *testing.T) {
+	fake := openFake()
+	defer func() { openWriter = defaultOpenWriter }()
+
+	AgentStarted(logger)
+	AgentStopped(logger)
+	Close(logger)
+	AgentStarted(logger)
+
+	assert.True(t, fake.closed)
+	assert.Equal(t, 2, len(fake.events))
+	assert.Equal(t, EventIDAgentStarted, fake.events[0].eid)
+	assert.Equal(t, EventIDAgentStopped, fake.events[1].eid)
+	assert.Equal(t, "info", fake.events[1].level)
+}
+
+func TestAuditDocumentCompleted(t *testing.T) {
+	fake := openFake()
+	defer func() { openWriter = defaultOpenWriter }()
+	defer Close(logger)
+
+	docInfo := contracts.DocumentInfo{CommandID: "command-id", InstanceID: "i-1234"}
+	result := contracts.DocumentResult{DocumentName: "AWS-RunShellScript", NPlugins: 2}
+
+	for _, status := range []contracts.ResultStatus{
+		contracts.ResultStatusSuccess,
+		contracts.ResultStatusFailed,
+		contracts.ResultStatusTimedOut,
+		contracts.ResultStatusCancelled,
+	} {
+		result.Status = status
+		DocumentCompleted(logger, contracts.DocumentState{DocumentInformation: docInfo}, result)
+	}
+
+	assert.Equal(t, []uint32{EventIDDocumentSucceeded, EventIDDocumentFailed, EventIDDocumentFailed, EventIDDocumentCancelled},
+		[]uint32{fake.events[0].eid, fake.events[1].eid, fake.events[2].eid, fake.events[3].eid})
+	assert.Equal(t, []string{"info", "error", "error", "warning"},
+		[]string{fake.events[0].level, fake.events[1].level, fake.events[2].level, fake.events[3].level})
+	assert.Equal(t, "Document execution completed.\r\n"+
+		"DocumentName: AWS-RunShellScript\r\n"+
+		"CommandId: command-id\r\n"+
+		"InstanceId: i-1234\r\n"+
+		"Status: Success\r\n"+
+		"Steps: 2\r\n", fake.events[0].msg)
+}
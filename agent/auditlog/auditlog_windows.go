@@ -0,0 +1,27 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package auditlog
+
+import (
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// defaultOpenWriter registers the audit event source in the Application log and opens it
+func defaultOpenWriter() (writer, error) {
+	// registering fails when the source already exists from a previous run, only a failed open matters
+	eventlog.InstallAsEventCreate(Source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	return eventlog.Open(Source)
+}
@@ -0,0 +1,244 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auditlog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// headSuffix is appended to the path of the audit log to name the file holding its last record
+const headSuffix = ".head"
+
+// Record is an entry of the local audit log, chained to the previous entry by its hash so that modified or removed
+// records are detected. The service doesn't pass the identity of the requester to the agent, the message, command
+// or association id identifies the request in CloudTrail instead.
+type Record struct {
+	Sequence        int64
+	Time            time.Time
+	ExecutionID     string
+	DocumentName    string
+	DocumentVersion string `json:",omitempty"`
+	DocumentType    contracts.DocumentType
+	DocumentHash    string
+	ParametersHash  string
+	MessageID       string
+	CommandID       string `json:",omitempty"`
+	AssociationID   string `json:",omitempty"`
+	InstanceID      string
+	Status          contracts.ResultStatus
+	// PreviousHash is the hash of the previous record, empty for the first record
+	PreviousHash string
+	// Hash is the SHA-256 of the record without its hash
+	Hash string
+}
+
+// head identifies the last record of the audit log, it reveals the records removed from the end of the log
+type head struct {
+	Sequence int64
+	Hash     string
+}
+
+// VerifyResult describes the verification of the local audit log
+type VerifyResult struct {
+	Path     string
+	Valid    bool
+	Records  int64
+	LastHash string `json:",omitempty"`
+	Error    string `json:",omitempty"`
+}
+
+// LocalAuditLogPath returns the local audit log of the given data store
+func LocalAuditLogPath(dataStorePath string) string {
+	return filepath.Join(dataStorePath, appconfig.AuditLogFileName)
+}
+
+// chain appends the records to the local audit log
+type chain struct {
+	path string
+	last head
+}
+
+// openChain opens the local audit log, the next records are chained to its last record. The records of a damaged
+// log are chained to its last readable record, the damage is kept for Verify to report.
+func openChain(log log.T, path string) (*chain, error) {
+	if err := fileutil.MakeDirs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	c := &chain{path: path}
+	records, err := readRecords(path)
+	if err != nil {
+		log.Warnf("audit log %v is damaged, the next records follow its last readable record: %v", path, err)
+	}
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		c.last = head{Sequence: last.Sequence, Hash: last.Hash}
+	}
+	return c, nil
+}
+
+// append chains the record to the last one and appends it to the log, then updates the head of the log
+func (c *chain) append(record Record) (Record, error) {
+	record.Sequence = c.last.Sequence + 1
+	record.PreviousHash = c.last.Hash
+	record.Hash = hashRecord(record)
+	line, err := json.Marshal(record)
+	if err != nil {
+		return record, err
+	}
+
+	if !fileutil.Exists(c.path) {
+		if err = fileutil.HardenedWriteFile(c.path, []byte{}); err != nil {
+			return record, err
+		}
+	}
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_WRONLY, appconfig.ReadWriteAccess)
+	if err != nil {
+		return record, err
+	}
+	if _, err = f.Write(append(line, '\n')); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return record, err
+	}
+
+	c.last = head{Sequence: record.Sequence, Hash: record.Hash}
+	content, err := json.Marshal(c.last)
+	if err != nil {
+		return record, err
+	}
+	return record, fileutil.HardenedWriteFile(c.path+headSuffix, content)
+}
+
+// newRecord describes the executed document from its state and its final result
+func newRecord(state contracts.DocumentState, result contracts.DocumentResult) Record {
+	info := state.DocumentInformation
+	return Record{
+		Time:            time.Now().UTC(),
+		ExecutionID:     info.DocumentID,
+		DocumentName:    info.DocumentName,
+		DocumentVersion: info.DocumentVersion,
+		DocumentType:    state.DocumentType,
+		DocumentHash:    info.DocumentHash,
+		ParametersHash:  docmanager.ParametersHash(state.InstancePluginsInformation),
+		MessageID:       info.MessageID,
+		CommandID:       info.CommandID,
+		AssociationID:   info.AssociationID,
+		InstanceID:      info.InstanceID,
+		Status:          result.Status,
+	}
+}
+
+// hashRecord returns the SHA-256 of the record without its hash
+func hashRecord(record Record) string {
+	record.Hash = ""
+	content, _ := json.Marshal(record)
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// readRecords reads the records of the audit log, none when it does not exist
+func readRecords(path string) (records []Record, err error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, fmt.Errorf("line %v is not a record: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Verify checks that no record of the audit log was modified, inserted or removed: the records are numbered without
+// gaps, each record matches its hash and refers to the hash of the previous record, and the last record is the one
+// written last. The hash of the last record is also written to the agent log, comparing it with a copy of the agent
+// log kept off the instance reveals a log rewritten as a whole.
+func Verify(path string) (result VerifyResult, err error) {
+	result.Path = path
+	var last head
+	if !fileutil.Exists(path + headSuffix) {
+		if !fileutil.Exists(path) {
+			return result, fmt.Errorf("audit log %v not found", path)
+		}
+		result.Error = fmt.Sprintf("the head of the audit log %v is missing", path+headSuffix)
+		return result, nil
+	}
+	if err = jsonutil.UnmarshalFile(path+headSuffix, &last); err != nil {
+		result.Error = fmt.Sprintf("the head of the audit log is corrupted: %v", err)
+		return result, nil
+	}
+
+	records, err := readRecords(path)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	previous := head{}
+	for i, record := range records {
+		line := i + 1
+		if record.Sequence != previous.Sequence+1 {
+			result.Error = fmt.Sprintf("line %v: record %v follows record %v, records were removed or inserted", line, record.Sequence, previous.Sequence)
+			return result, nil
+		}
+		if record.Hash != hashRecord(record) {
+			result.Error = fmt.Sprintf("line %v: record %v does not match its hash, it was modified", line, record.Sequence)
+			return result, nil
+		}
+		if record.PreviousHash != previous.Hash {
+			result.Error = fmt.Sprintf("line %v: record %v does not follow the previous record, the chain was modified", line, record.Sequence)
+			return result, nil
+		}
+		previous = head{Sequence: record.Sequence, Hash: record.Hash}
+		result.Records++
+	}
+	result.LastHash = previous.Hash
+
+	if last.Sequence != previous.Sequence || last.Hash != previous.Hash {
+		if last.Sequence > previous.Sequence {
+			result.Error = fmt.Sprintf("the audit log ends at record %v but record %v was written, it was truncated", previous.Sequence, last.Sequence)
+		} else {
+			result.Error = fmt.Sprintf("the audit log ends at record %v but the last record written is %v, it was modified", previous.Sequence, last.Sequence)
+		}
+		return result, nil
+	}
+	result.Valid = true
+	return result, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auditlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordDocuments records the given statuses in a local audit log of a temporary directory and returns its path
+func recordDocuments(t *testing.T, statuses ...contracts.ResultStatus) (path string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.NoError(t, err)
+	path = filepath.Join(dir, appconfig.AuditLogFileName)
+	localLogPath = func() string { return path }
+
+	config := appconfig.DefaultConfig()
+	config.Agent.LocalAuditLog = true
+	Open(logger, config)
+	state := contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{DocumentID: "command-id", CommandID: "command-id", MessageID: "aws.ssm.command-id.i-1234", DocumentHash: "abcd"},
+		DocumentType:        contracts.SendCommand,
+	}
+	for _, status := range statuses {
+		DocumentCompleted(logger, state, contracts.DocumentResult{Status: status})
+	}
+	Close(logger)
+
+	return path, func() {
+		localLogPath = func() string { return LocalAuditLogPath(appconfig.DefaultDataStorePath) }
+		os.RemoveAll(dir)
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	return strings.SplitAfter(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func writeLines(t *testing.T, path string, lines []string) {
+	assert.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "")), 0600))
+}
+
+func TestLocalAuditLogChainsRecords(t *testing.T) {
+	path, cleanup := recordDocuments(t, contracts.ResultStatusSuccess, contracts.ResultStatusFailed)
+	defer cleanup()
+
+	records, err := readRecords(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, int64(1), records[0].Sequence)
+	assert.Equal(t, "", records[0].PreviousHash)
+	assert.Equal(t, records[0].Hash, records[1].PreviousHash)
+	assert.Equal(t, "abcd", records[1].DocumentHash)
+	assert.Equal(t, contracts.ResultStatusFailed, records[1].Status)
+
+	// the chain continues after a restart of the agent
+	config := appconfig.DefaultConfig()
+	config.Agent.LocalAuditLog = true
+	Open(logger, config)
+	DocumentCompleted(logger, contracts.DocumentState{}, contracts.DocumentResult{Status: contracts.ResultStatusSuccess})
+	Close(logger)
+
+	result, err := Verify(path)
+	assert.NoError(t, err)
+	assert.True(t, result.Valid, result.Error)
+	assert.Equal(t, int64(3), result.Records)
+}
+
+func TestLocalAuditLogDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, appconfig.AuditLogFileName)
+	localLogPath = func() string { return path }
+	defer func() { localLogPath = func() string { return LocalAuditLogPath(appconfig.DefaultDataStorePath) } }()
+
+	Open(logger, appconfig.DefaultConfig())
+	DocumentCompleted(logger, contracts.DocumentState{}, contracts.DocumentResult{Status: contracts.ResultStatusSuccess})
+	Close(logger)
+
+	_, err = Verify(path)
+	assert.Error(t, err)
+}
+
+func TestVerifyDetectsModifiedRecord(t *testing.T) {
+	path, cleanup := recordDocuments(t, contracts.ResultStatusFailed, contracts.ResultStatusSuccess)
+	defer cleanup()
+
+	lines := readLines(t, path)
+	lines[0] = strings.Replace(lines[0], `"Failed"`, `"Success"`, 1)
+	writeLines(t, path, lines)
+
+	result, err := Verify(path)
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Error, "line 1: record 1 does not match its hash")
+}
+
+func TestVerifyDetectsRemovedRecord(t *testing.T) {
+	path, cleanup := recordDocuments(t, contracts.ResultStatusSuccess, contracts.ResultStatusSuccess, contracts.ResultStatusSuccess)
+	defer cleanup()
+
+	lines := readLines(t, path)
+	writeLines(t, path, append(lines[:1], lines[2:]...))
+
+	result, err := Verify(path)
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Error, "line 2: record 3 follows record 1")
+}
+
+func TestVerifyDetectsTruncation(t *testing.T) {
+	path, cleanup := recordDocuments(t, contracts.ResultStatusSuccess, contracts.ResultStatusSuccess)
+	defer cleanup()
+
+	writeLines(t, path, readLines(t, path)[:1])
+
+	result, err := Verify(path)
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(1), result.Records)
+	assert.Contains(t, result.Error, "ends at record 1 but record 2 was written")
+
+	assert.NoError(t, os.Remove(path+headSuffix))
+	result, err = Verify(path)
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Error, "is missing")
+}
+
+func TestVerifyDetectsRewrittenChain(t *testing.T) {
+	path, cleanup := recordDocuments(t, contracts.ResultStatusSuccess, contracts.ResultStatusSuccess)
+	defer cleanup()
+
+	// a record rehashed in place breaks the link of the next record
+	lines := readLines(t, path)
+	records, err := readRecords(path)
+	assert.NoError(t, err)
+	records[0].Status = contracts.ResultStatusFailed
+	records[0].Hash = hashRecord(records[0])
+	lines[0] = strings.Replace(lines[0], `"Success"`, `"Failed"`, 1)
+	lines[0] = strings.Replace(lines[0], records[1].PreviousHash, records[0].Hash, 1)
+	writeLines(t, path, lines)
+
+	result, err := Verify(path)
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Error, "line 2: record 2 does not follow the previous record")
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package bandwidth caps the bandwidth the agent uses for downloads and uploads, so that its transfers
+// don't saturate the small links of edge and hybrid instances.
+package bandwidth
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	bytesPerKB = 1024
+
+	// minChunkSize is the smallest read between two waits, larger reads are split in chunks of a quarter
+	// of a second of bandwidth for the transfers to progress steadily
+	minChunkSize = 512
+)
+
+var (
+	getAppConfig = appconfig.Config
+	sleep        = time.Sleep
+
+	loadOnce        sync.Once
+	downloadLimiter *Limiter
+	uploadLimiter   *Limiter
+)
+
+// Limiter caps the rate of the bytes read through the readers it wraps, which share the bandwidth
+type Limiter struct {
+	bytesPerSecond int64
+	lock           sync.Mutex
+	next           time.Time
+}
+
+// NewLimiter returns a limiter of the given bandwidth in KB per second, or nil when it is 0 which doesn't limit anything
+func NewLimiter(kbPerSecond int) *Limiter {
+	if kbPerSecond <= 0 {
+		return nil
+	}
+	return &Limiter{bytesPerSecond: int64(kbPerSecond) * bytesPerKB}
+}
+
+// Reader returns a reader which reads from r at the bandwidth of the limiter
+func (l *Limiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{reader: r, limiter: l}
+}
+
+// ReadCloser returns a read closer which reads from r at the bandwidth of the limiter and closes r
+func (l *Limiter) ReadCloser(r io.ReadCloser) io.ReadCloser {
+	if l == nil || r == nil {
+		return r
+	}
+	return &limitedReadCloser{Reader: l.Reader(r), Closer: r}
+}
+
+// chunkSize returns the largest read between two waits
+func (l *Limiter) chunkSize() int {
+	if size := l.bytesPerSecond / 4; size > minChunkSize {
+		return int(size)
+	}
+	return minChunkSize
+}
+
+// wait blocks until the given count of bytes fits in the bandwidth, after the bytes read before
+func (l *Limiter) wait(count int) {
+	l.lock.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(count) * int64(time.Second) / l.bytesPerSecond))
+	delay := l.next.Sub(now)
+	l.lock.Unlock()
+
+	sleep(delay)
+}
+
+type limitedReader struct {
+	reader  io.Reader
+	limiter *Limiter
+}
+
+// Read reads at most a chunk and waits for it to fit in the bandwidth
+func (r *limitedReader) Read(p []byte) (n int, err error) {
+	if chunkSize := r.limiter.chunkSize(); len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+	if n, err = r.reader.Read(p); n > 0 {
+		r.limiter.wait(n)
+	}
+	return
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedTransport sends the request bodies at the bandwidth of the limiter
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *Limiter
+}
+
+// RoundTrip sends a copy of the request whose body is read at the bandwidth of the limiter
+func (t *limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return t.base.RoundTrip(request)
+	}
+	limited := *request
+	limited.Body = t.limiter.ReadCloser(request.Body)
+	return t.base.RoundTrip(&limited)
+}
+
+// loadLimiters creates the limiters of the bandwidth caps of the agent configuration, which are shared
+// by all the transfers of the process
+func loadLimiters() {
+	config, err := getAppConfig(false)
+	if err != nil {
+		return
+	}
+	downloadLimiter = NewLimiter(config.Agent.DownloadBandwidthLimitKBps)
+	uploadLimiter = NewLimiter(config.Agent.UploadBandwidthLimitKBps)
+}
+
+// DownloadReader returns a reader which reads a download within the configured download bandwidth
+func DownloadReader(r io.Reader) io.Reader {
+	loadOnce.Do(loadLimiters)
+	return downloadLimiter.Reader(r)
+}
+
+// UploadTransport returns a transport which sends the request bodies within the configured upload bandwidth
+func UploadTransport(base http.RoundTripper) http.RoundTripper {
+	loadOnce.Do(loadLimiters)
+	if uploadLimiter == nil {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &limitedTransport{base: base, limiter: uploadLimiter}
+}
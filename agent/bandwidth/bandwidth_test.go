@@ -0,0 +1,148 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package bandwidth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordSleeps replaces sleep with a function recording the longest delay instead of waiting, as the clock
+// doesn't advance while reading the longest delay is the time the whole transfer takes
+func recordSleeps() (longest *time.Duration, restore func()) {
+	var lock sync.Mutex
+	longest = new(time.Duration)
+	sleep = func(d time.Duration) {
+		lock.Lock()
+		defer lock.Unlock()
+		if d > *longest {
+			*longest = d
+		}
+	}
+	return longest, func() { sleep = time.Sleep }
+}
+
+func TestNewLimiterWithoutLimit(t *testing.T) {
+	assert.Nil(t, NewLimiter(0))
+	assert.Nil(t, NewLimiter(-1))
+
+	var limiter *Limiter
+	reader := strings.NewReader("content")
+	assert.Equal(t, reader, limiter.Reader(reader))
+}
+
+func TestReaderWaitsForBandwidth(t *testing.T) {
+	slept, restore := recordSleeps()
+	defer restore()
+
+	// 10 KB at 4 KB/s take two and a half seconds, read in chunks of a quarter of a second
+	limiter := NewLimiter(4)
+	content := bytes.Repeat([]byte("a"), 10*bytesPerKB)
+	read, err := ioutil.ReadAll(limiter.Reader(bytes.NewReader(content)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, content, read)
+	assert.InDelta(t, 2.5, slept.Seconds(), 0.1)
+}
+
+func TestReaderSplitsReadsInChunks(t *testing.T) {
+	_, restore := recordSleeps()
+	defer restore()
+
+	limiter := NewLimiter(4)
+	buffer := make([]byte, 10*bytesPerKB)
+	n, err := limiter.Reader(bytes.NewReader(buffer)).Read(buffer)
+
+	assert.NoError(t, err)
+	assert.Equal(t, bytesPerKB, n)
+}
+
+func TestReadersShareBandwidth(t *testing.T) {
+	slept, restore := recordSleeps()
+	defer restore()
+
+	// two readers of 4 KB each share 4 KB/s, the last chunk completes two seconds after the first read
+	limiter := NewLimiter(4)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ioutil.ReadAll(limiter.Reader(bytes.NewReader(make([]byte, 4*bytesPerKB))))
+		}()
+	}
+	wg.Wait()
+
+	assert.InDelta(t, 2, slept.Seconds(), 0.1)
+}
+
+type recordingTransport struct {
+	body []byte
+}
+
+func (t *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		t.body, _ = ioutil.ReadAll(request.Body)
+	}
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func TestLimitedTransportLimitsRequestBody(t *testing.T) {
+	slept, restore := recordSleeps()
+	defer restore()
+
+	base := &recordingTransport{}
+	transport := &limitedTransport{base: base, limiter: NewLimiter(1)}
+	request, _ := http.NewRequest("PUT", "https://example.com/object", bytes.NewReader(make([]byte, 2*bytesPerKB)))
+	_, err := transport.RoundTrip(request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2*bytesPerKB, len(base.body))
+	assert.InDelta(t, 2, slept.Seconds(), 0.1)
+
+	// requests without body are sent as they are
+	request, _ = http.NewRequest("GET", "https://example.com/object", nil)
+	_, err = transport.RoundTrip(request)
+	assert.NoError(t, err)
+}
+
+func TestLimitersFromConfig(t *testing.T) {
+	getAppConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Agent.UploadBandwidthLimitKBps = 128
+		return config, nil
+	}
+	defer func() {
+		getAppConfig = appconfig.Config
+		loadOnce = sync.Once{}
+		downloadLimiter, uploadLimiter = nil, nil
+	}()
+	loadOnce = sync.Once{}
+
+	reader := strings.NewReader("content")
+	assert.Equal(t, reader, DownloadReader(reader))
+
+	base := &recordingTransport{}
+	transport, ok := UploadTransport(base).(*limitedTransport)
+	assert.True(t, ok)
+	assert.Equal(t, int64(128*bytesPerKB), transport.limiter.bytesPerSecond)
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi"
+)
+
+const (
+	getAgentStatusCommand = "get-agent-status"
+)
+
+const getAgentStatusCommandHelp = `NAME:
+    {{.GetAgentStatusCommandName}}
+
+DESCRIPTION
+    Returns the status of the running amazon-ssm-agent service: the state of its connection to
+    Systems Manager, the documents pending or running and their steps, the long running plugins
+    and the last agent update.
+
+    The status is requested from the agent through a local socket, the command must be run
+    as root or as an administrator.
+
+SYNOPSIS
+    {{.GetAgentStatusCommandName}} [{{.Sections}}]
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetAgentStatusCommandName}} connectivity
+
+    Output:
+      {
+        "State": "Connected",
+        "LastPollTime": "2018-03-02T07:00:00Z",
+        "LastSuccessfulPollTime": "2018-03-02T07:00:00Z"
+      }
+
+OUTPUT
+    Status of the agent in JSON format, restricted to the given section if any
+`
+
+type getAgentStatusHelpParams struct {
+	SsmCliName                string
+	GetAgentStatusCommandName string
+	Sections                  string
+}
+
+func init() {
+	cliutil.Register(&GetAgentStatusCommand{})
+}
+
+type GetAgentStatusCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-agent-status cli command
+func (c *GetAgentStatusCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateGetAgentStatusCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	section := statusapi.SectionAll
+	if len(subcommands) > 0 {
+		section = subcommands[0]
+	}
+	status, err := statusapi.Query(section)
+	if err != nil {
+		return err, ""
+	}
+	return nil, strings.TrimSpace(status)
+}
+
+// Help prints help for the get-agent-status cli command
+func (c *GetAgentStatusCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetAgentStatusCommandHelp").Parse(getAgentStatusCommandHelp)
+		params := getAgentStatusHelpParams{cliutil.SsmCliName, getAgentStatusCommand, strings.Join(statusapi.Sections, "|")}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetAgentStatusCommand) Name() string {
+	return getAgentStatusCommand
+}
+
+// validateGetAgentStatusCommandInput checks the subcommands and parameters for unsupported values
+func (GetAgentStatusCommand) validateGetAgentStatusCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if len(subcommands) > 1 || (len(subcommands) == 1 && !statusapi.IsSection(subcommands[0])) {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v, valid sections are %v", getAgentStatusCommand, subcommands, strings.Join(statusapi.Sections, ", ")), "")
+		return validation
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	return validation
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+const (
+	getAssociationStateCommand = "get-association-state"
+)
+
+const getAssociationStateCommandHelp = `NAME:
+    {{.GetAssociationStateCommandName}}
+
+DESCRIPTION
+    Returns the local execution state of the associations applied by the amazon-ssm-agent service.
+
+    An association is suspended when it fails as many consecutive times as the AssociationErrorThreshold
+    of the agent configuration, it stays suspended until its content changes. The number of associations
+    running at the same time is limited by the AssociationMaxConcurrency of the agent configuration.
+
+SYNOPSIS
+    {{.GetAssociationStateCommandName}}
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetAssociationStateCommandName}}
+
+    Output:
+      [
+        {
+          "AssociationID": "b2f71a28-cbe1-4429-b848-26c7e1f5ad0d",
+          "Name": "AWS-GatherSoftwareInventory",
+          "Running": false,
+          "ConsecutiveFailures": 3,
+          "Suspended": true,
+          "NextScheduledDate": "2018-03-02T07:00:00Z"
+        }
+      ]
+
+OUTPUT
+    Execution state of the associations in JSON format
+`
+
+type getAssociationStateHelpParams struct {
+	SsmCliName                     string
+	GetAssociationStateCommandName string
+}
+
+func init() {
+	cliutil.Register(&GetAssociationStateCommand{})
+}
+
+type GetAssociationStateCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-association-state cli command
+func (c *GetAssociationStateCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateGetAssociationStateCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	statePath := filepath.Join(appconfig.DefaultDataStorePath, appconfig.AssociationStateFileName)
+	if !fileutil.Exists(statePath) {
+		return errors.New("No association state found, the agent has not processed associations yet"), ""
+	}
+
+	content, err := fileutil.ReadAllText(statePath)
+	if err != nil {
+		return err, ""
+	}
+	return nil, strings.TrimSpace(content)
+}
+
+// Help prints help for the get-association-state cli command
+func (c *GetAssociationStateCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetAssociationStateCommandHelp").Parse(getAssociationStateCommandHelp)
+		params := getAssociationStateHelpParams{cliutil.SsmCliName, getAssociationStateCommand}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetAssociationStateCommand) Name() string {
+	return getAssociationStateCommand
+}
+
+// validateGetAssociationStateCommandInput checks the subcommands and parameters for unsupported values
+func (GetAssociationStateCommand) validateGetAssociationStateCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getAssociationStateCommand, subcommands), "")
+		return validation
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	return validation
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/diagnostics"
+)
+
+const (
+	getDiagnosticsCommand = "get-diagnostics"
+	getDiagnosticsOutput  = "output"
+)
+
+const getDiagnosticsCommandHelp = `NAME:
+    {{.GetDiagnosticsCommandName}}
+
+DESCRIPTION
+    Collects the information needed to troubleshoot the amazon-ssm-agent into a zip archive that can
+    be attached to a support case: the agent version, the configuration without the proxy credentials,
+    the connectivity test of each endpoint the agent calls, the end of the agent logs, the agent status,
+    the hibernation state and the pending and in progress documents.
+
+    The command must be run as root or as an administrator to read the logs and the documents.
+
+SYNOPSIS
+    {{.GetDiagnosticsCommandName}}
+    [{{.OutputFlag}} <value>]
+
+PARAMETERS
+    {{.OutputFlag}} (string) Path of the archive, ssm-agent-diagnostics-<time>.zip in the current
+    directory by default.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetDiagnosticsCommandName}} {{.OutputFlag}} /tmp/diagnostics.zip
+
+    Output:
+      /tmp/diagnostics.zip
+
+OUTPUT
+    Path of the archive
+`
+
+type getDiagnosticsHelpParams struct {
+	SsmCliName                string
+	GetDiagnosticsCommandName string
+	OutputFlag                string
+}
+
+func init() {
+	cliutil.Register(&GetDiagnosticsCommand{})
+}
+
+type GetDiagnosticsCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-diagnostics cli command
+func (c *GetDiagnosticsCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateGetDiagnosticsCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	path := fmt.Sprintf("ssm-agent-diagnostics-%v.zip", time.Now().UTC().Format("20060102T150405Z"))
+	if values, exists := parameters[getDiagnosticsOutput]; exists {
+		path = values[0]
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err, ""
+	}
+	err = diagnostics.Collect(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err, ""
+	}
+	return nil, path
+}
+
+// Help prints help for the get-diagnostics cli command
+func (c *GetDiagnosticsCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetDiagnosticsCommandHelp").Parse(getDiagnosticsCommandHelp)
+		params := getDiagnosticsHelpParams{cliutil.SsmCliName, getDiagnosticsCommand, cliutil.FormatFlag(getDiagnosticsOutput)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetDiagnosticsCommand) Name() string {
+	return getDiagnosticsCommand
+}
+
+// validateGetDiagnosticsCommandInput checks the subcommands and parameters for unsupported values
+func (GetDiagnosticsCommand) validateGetDiagnosticsCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getDiagnosticsCommand, subcommands), "")
+		return validation
+	}
+
+	if values, exists := parameters[getDiagnosticsOutput]; exists && (len(values) != 1 || values[0] == "") {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(getDiagnosticsOutput)))
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != getDiagnosticsOutput {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation
+}
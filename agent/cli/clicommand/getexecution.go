@@ -0,0 +1,140 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+)
+
+const (
+	getExecutionCommand = "get-execution"
+)
+
+const getExecutionCommandHelp = `NAME:
+    {{.GetExecutionCommandName}}
+
+DESCRIPTION
+    Returns an execution from the local execution history of the amazon-ssm-agent service,
+    with the status, exit code and duration of each of its steps.
+
+SYNOPSIS
+    {{.GetExecutionCommandName}} <execution-id>
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetExecutionCommandName}} 01234567-890a-bcde-f012-34567890abcd
+
+    Output:
+      {
+        "ExecutionID": "01234567-890a-bcde-f012-34567890abcd",
+        "DocumentName": "AWS-RunShellScript",
+        "DocumentType": "SendCommand",
+        "CommandID": "01234567-890a-bcde-f012-34567890abcd",
+        "ParametersHash": "5d41402abc4b2a76b9719d911017c592ae2c2b1f5a1b0c1e6f3c2b8a0d6e9f14",
+        "Status": "Failed",
+        "StartDateTime": "2018-03-02T07:00:00Z",
+        "EndDateTime": "2018-03-02T07:00:12Z",
+        "DurationSeconds": 12,
+        "Steps": [
+          {
+            "ID": "0.aws:runShellScript",
+            "Name": "aws:runShellScript",
+            "Status": "Failed",
+            "ExitCode": 1,
+            "StartDateTime": "2018-03-02T07:00:00Z",
+            "EndDateTime": "2018-03-02T07:00:12Z",
+            "DurationSeconds": 12
+          }
+        ]
+      }
+
+OUTPUT
+    Execution and its steps in JSON format, the execution ids are listed by {{.ListExecutionsCommandName}}
+`
+
+type getExecutionHelpParams struct {
+	SsmCliName                string
+	GetExecutionCommandName   string
+	ListExecutionsCommandName string
+}
+
+func init() {
+	cliutil.Register(&GetExecutionCommand{})
+}
+
+type GetExecutionCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-execution cli command
+func (c *GetExecutionCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateGetExecutionCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	record, err := docmanager.FindExecution(docmanager.ExecutionHistoryPath(appconfig.DefaultDataStorePath), subcommands[0])
+	if err != nil {
+		return err, ""
+	}
+	content, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err, ""
+	}
+	return nil, string(content)
+}
+
+// Help prints help for the get-execution cli command
+func (c *GetExecutionCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetExecutionCommandHelp").Parse(getExecutionCommandHelp)
+		params := getExecutionHelpParams{cliutil.SsmCliName, getExecutionCommand, listExecutionsCommand}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetExecutionCommand) Name() string {
+	return getExecutionCommand
+}
+
+// validateGetExecutionCommandInput checks the subcommands and parameters for unsupported values
+func (GetExecutionCommand) validateGetExecutionCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if len(subcommands) != 1 {
+		validation = append(validation, fmt.Sprintf("%v expects the id of an execution, found %v", getExecutionCommand, subcommands), "")
+		return validation
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	return validation
+}
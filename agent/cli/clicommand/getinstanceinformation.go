@@ -23,6 +23,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 )
@@ -49,11 +50,13 @@ EXAMPLES
       {
         "region" : "us-west-2",
         "instance-id" : "i-12345678",
-        "release-version" : "1.0.0"
+        "release-version" : "1.0.0",
+        "environment" : "EC2"
       }
 
 OUTPUT
-    Instance information containing region, instance ID and version in JSON format
+    Instance information containing region, instance ID, version and environment in JSON format.
+    The environment is one of EC2, OnPremises, Docker, ECS, EKS or Fargate
 `
 
 type getInstanceInformationHelpParams struct {
@@ -92,6 +95,10 @@ func (c *GetInstanceInformationCommand) Execute(subcommands []string, parameters
 
 	information["release-version"] = version.Version
 
+	if environment, err := platform.Environment(log.NewMockLog()); err == nil {
+		information["environment"] = environment
+	}
+
 	result, _ := jsonutil.Marshal(information)
 	return nil, result
 }
@@ -0,0 +1,137 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+)
+
+const (
+	listExecutionsCommand = "list-executions"
+)
+
+const listExecutionsCommandHelp = `NAME:
+    {{.ListExecutionsCommandName}}
+
+DESCRIPTION
+    Lists the commands and associations executed by the amazon-ssm-agent service, most recent first.
+
+    The agent keeps the number of executions set by the ExecutionHistoryCount of the agent configuration,
+    use {{.GetExecutionCommandName}} to get the steps of an execution.
+
+SYNOPSIS
+    {{.ListExecutionsCommandName}}
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.ListExecutionsCommandName}}
+
+    Output:
+      [
+        {
+          "ExecutionID": "01234567-890a-bcde-f012-34567890abcd",
+          "DocumentName": "AWS-RunShellScript",
+          "DocumentType": "SendCommand",
+          "CommandID": "01234567-890a-bcde-f012-34567890abcd",
+          "ParametersHash": "5d41402abc4b2a76b9719d911017c592ae2c2b1f5a1b0c1e6f3c2b8a0d6e9f14",
+          "Status": "Success",
+          "StartDateTime": "2018-03-02T07:00:00Z",
+          "EndDateTime": "2018-03-02T07:00:12Z",
+          "DurationSeconds": 12
+        }
+      ]
+
+OUTPUT
+    Executed documents in JSON format
+`
+
+type listExecutionsHelpParams struct {
+	SsmCliName                string
+	ListExecutionsCommandName string
+	GetExecutionCommandName   string
+}
+
+func init() {
+	cliutil.Register(&ListExecutionsCommand{})
+}
+
+type ListExecutionsCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the list-executions cli command
+func (c *ListExecutionsCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateListExecutionsCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	history, err := docmanager.LoadExecutionHistory(docmanager.ExecutionHistoryPath(appconfig.DefaultDataStorePath))
+	if err != nil {
+		return err, ""
+	}
+	// the steps are only shown by get-execution
+	for i := range history {
+		history[i].Steps = nil
+	}
+	content, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err, ""
+	}
+	return nil, string(content)
+}
+
+// Help prints help for the list-executions cli command
+func (c *ListExecutionsCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("ListExecutionsCommandHelp").Parse(listExecutionsCommandHelp)
+		params := listExecutionsHelpParams{cliutil.SsmCliName, listExecutionsCommand, getExecutionCommand}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (ListExecutionsCommand) Name() string {
+	return listExecutionsCommand
+}
+
+// validateListExecutionsCommandInput checks the subcommands and parameters for unsupported values
+func (ListExecutionsCommand) validateListExecutionsCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", listExecutionsCommand, subcommands), "")
+		return validation
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	return validation
+}
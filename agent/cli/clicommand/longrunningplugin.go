@@ -0,0 +1,155 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi"
+)
+
+const (
+	longRunningPluginCommand = "long-running-plugin"
+	longRunningPluginName    = "name"
+)
+
+const longRunningPluginCommandHelp = `NAME:
+    {{.LongRunningPluginCommandName}}
+
+DESCRIPTION
+    Lists the long running plugins registered with the amazon-ssm-agent service, starts, stops or
+    restarts one of them, or returns its state and its recent output, without sending a document.
+
+    A plugin is started with its last configuration, and is started again when the agent restarts
+    until it is stopped. The output is returned for the plugins which capture it, e.g. the daemons.
+
+    The request is sent to the agent through a local socket, the command must be run as root or as
+    an administrator.
+
+SYNOPSIS
+    {{.LongRunningPluginCommandName}} {{.Actions}}
+    [{{.NameFlag}} <value>]
+
+PARAMETERS
+    {{.NameFlag}} (string) Name of the long running plugin, required by every action but list.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.LongRunningPluginCommandName}} restart {{.NameFlag}} aws:cloudWatch
+
+    Output:
+      {
+        "Name": "aws:cloudWatch",
+        "Enabled": true,
+        "Running": true,
+        "LastConfigurationModifiedTime": "2018-03-02T07:00:00Z"
+      }
+
+OUTPUT
+    State of the plugins in JSON format, with the last lines of the output of the plugin for health
+`
+
+type longRunningPluginHelpParams struct {
+	SsmCliName                   string
+	LongRunningPluginCommandName string
+	Actions                      string
+	NameFlag                     string
+}
+
+func init() {
+	cliutil.Register(&LongRunningPluginCommand{})
+}
+
+type LongRunningPluginCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the long-running-plugin cli command
+func (c *LongRunningPluginCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateLongRunningPluginCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	var name string
+	if values, exists := parameters[longRunningPluginName]; exists {
+		name = values[0]
+	}
+	result, err := statusapi.ControlPlugin(subcommands[0], name)
+	if err != nil {
+		return err, ""
+	}
+	return nil, strings.TrimSpace(result)
+}
+
+// Help prints help for the long-running-plugin cli command
+func (c *LongRunningPluginCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("LongRunningPluginCommandHelp").Parse(longRunningPluginCommandHelp)
+		params := longRunningPluginHelpParams{cliutil.SsmCliName, longRunningPluginCommand, strings.Join(statusapi.PluginActions, "|"), cliutil.FormatFlag(longRunningPluginName)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (LongRunningPluginCommand) Name() string {
+	return longRunningPluginCommand
+}
+
+// validateLongRunningPluginCommandInput checks the subcommands and parameters for unsupported values
+func (LongRunningPluginCommand) validateLongRunningPluginCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if len(subcommands) != 1 || !isPluginAction(subcommands[0]) {
+		validation = append(validation, fmt.Sprintf("%v expects one of the actions %v", longRunningPluginCommand, strings.Join(statusapi.PluginActions, ", ")), "")
+		return validation
+	}
+
+	values, exists := parameters[longRunningPluginName]
+	if subcommands[0] == statusapi.PluginActionList {
+		if exists {
+			validation = append(validation, fmt.Sprintf("%v does not take parameter %v", statusapi.PluginActionList, cliutil.FormatFlag(longRunningPluginName)))
+		}
+	} else if !exists || len(values) != 1 || values[0] == "" {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(longRunningPluginName)))
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != longRunningPluginName {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation
+}
+
+// isPluginAction returns true if the action can be requested on the long running plugins
+func isPluginAction(action string) bool {
+	for _, known := range statusapi.PluginActions {
+		if action == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/activation"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
+)
+
+const (
+	regenerateFingerprintCommand        = "regenerate-fingerprint"
+	regenerateFingerprintActivationCode = "activation-code"
+	regenerateFingerprintActivationID   = "activation-id"
+	regenerateFingerprintRegion         = "region"
+)
+
+const regenerateFingerprintCommandHelp = `NAME:
+    {{.RegenerateFingerprintCommandName}}
+
+DESCRIPTION
+    Replaces the fingerprint of the managed instance and registers it again with Systems Manager,
+    for on-premises instances cloned from the same image whose identical hardware information
+    makes them conflict. The previous registration is removed from the instance.
+
+    The activation is either given as parameters or provided by the reactivation file or command
+    configured in the Registration section of the agent configuration. The command must be run as
+    root or as an administrator, and the amazon-ssm-agent service must be restarted afterwards.
+
+    The similarity of the hardware required to keep the fingerprint is configured by
+    Agent.FingerprintSimilarityThreshold in the agent configuration.
+
+SYNOPSIS
+    {{.RegenerateFingerprintCommandName}}
+    [{{.ActivationCodeFlag}} <value> {{.ActivationIDFlag}} <value> [{{.RegionFlag}} <value>]]
+
+PARAMETERS
+    {{.ActivationCodeFlag}} (string) Code of the activation to register with.
+
+    {{.ActivationIDFlag}} (string) ID of the activation to register with.
+
+    {{.RegionFlag}} (string) Region of the activation, the region of the current registration by default.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.RegenerateFingerprintCommandName}} {{.ActivationCodeFlag}} code {{.ActivationIDFlag}} id {{.RegionFlag}} us-east-1
+
+    Output:
+      mi-0123456789abcdef0
+
+OUTPUT
+    The new managed instance id
+`
+
+type regenerateFingerprintHelpParams struct {
+	SsmCliName                       string
+	RegenerateFingerprintCommandName string
+	ActivationCodeFlag               string
+	ActivationIDFlag                 string
+	RegionFlag                       string
+}
+
+func init() {
+	cliutil.Register(&RegenerateFingerprintCommand{})
+}
+
+type RegenerateFingerprintCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the regenerate-fingerprint cli command
+func (c *RegenerateFingerprintCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateRegenerateFingerprintCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	act, err := c.loadActivation(parameters)
+	if err != nil {
+		return err, ""
+	}
+	managedInstanceID, err := activation.ReRegister(act)
+	if err != nil {
+		return err, ""
+	}
+	return nil, managedInstanceID
+}
+
+// loadActivation returns the activation given as parameters, else the one configured for reactivation
+func (RegenerateFingerprintCommand) loadActivation(parameters map[string][]string) (act activation.Activation, err error) {
+	if _, exists := parameters[regenerateFingerprintActivationCode]; !exists {
+		config, err := appconfig.Config(false)
+		if err != nil {
+			return act, err
+		}
+		if act, err = activation.Load(config.Registration); err == activation.ErrNotConfigured {
+			return act, fmt.Errorf("%v and %v are required, %v", cliutil.FormatFlag(regenerateFingerprintActivationCode), cliutil.FormatFlag(regenerateFingerprintActivationID), err)
+		}
+		return act, err
+	}
+
+	act.ActivationCode = parameters[regenerateFingerprintActivationCode][0]
+	act.ActivationId = parameters[regenerateFingerprintActivationID][0]
+	if values, exists := parameters[regenerateFingerprintRegion]; exists {
+		act.Region = values[0]
+	} else if act.Region = registration.Region(); act.Region == "" {
+		return act, fmt.Errorf("%v is required, the instance is not registered", cliutil.FormatFlag(regenerateFingerprintRegion))
+	}
+	return act, nil
+}
+
+// Help prints help for the regenerate-fingerprint cli command
+func (c *RegenerateFingerprintCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("RegenerateFingerprintCommandHelp").Parse(regenerateFingerprintCommandHelp)
+		params := regenerateFingerprintHelpParams{
+			cliutil.SsmCliName,
+			regenerateFingerprintCommand,
+			cliutil.FormatFlag(regenerateFingerprintActivationCode),
+			cliutil.FormatFlag(regenerateFingerprintActivationID),
+			cliutil.FormatFlag(regenerateFingerprintRegion),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (RegenerateFingerprintCommand) Name() string {
+	return regenerateFingerprintCommand
+}
+
+// validateRegenerateFingerprintCommandInput checks the subcommands and parameters for required values and unsupported values
+func (RegenerateFingerprintCommand) validateRegenerateFingerprintCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", regenerateFingerprintCommand, subcommands), "")
+		return validation
+	}
+
+	// the activation code and id go together, the region requires them
+	_, hasCode := parameters[regenerateFingerprintActivationCode]
+	_, hasID := parameters[regenerateFingerprintActivationID]
+	_, hasRegion := parameters[regenerateFingerprintRegion]
+	if hasCode != hasID || (hasRegion && !hasCode) {
+		validation = append(validation, fmt.Sprintf("%v and %v must be given together", cliutil.FormatFlag(regenerateFingerprintActivationCode), cliutil.FormatFlag(regenerateFingerprintActivationID)))
+	}
+
+	for key, values := range parameters {
+		switch key {
+		case regenerateFingerprintActivationCode, regenerateFingerprintActivationID, regenerateFingerprintRegion:
+			if len(values) != 1 || values[0] == "" {
+				validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(key)))
+			}
+		default:
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/auditlog"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+)
+
+const (
+	verifyAuditCommand = "verify-audit"
+	verifyAuditPath    = "path"
+)
+
+const verifyAuditCommandHelp = `NAME:
+    {{.VerifyAuditCommandName}}
+
+DESCRIPTION
+    Verifies the local audit log of the documents executed by the amazon-ssm-agent service, kept when
+    LocalAuditLog is enabled in the agent configuration. The records are chained by their hashes: the
+    verification fails when a record was modified, inserted or removed, or when the log was truncated.
+
+    The hash of each record is also written to the agent log, compare LastHash with the agent log kept
+    off the instance to detect a log rewritten as a whole.
+
+    The command must be run as root or as an administrator to read the audit log.
+
+SYNOPSIS
+    {{.VerifyAuditCommandName}}
+    [{{.PathFlag}} <value>]
+
+PARAMETERS
+    {{.PathFlag}} (string) Path of the audit log, the audit log of the agent by default.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.VerifyAuditCommandName}}
+
+    Output:
+      {
+        "Path": "/var/lib/amazon/ssm/audit.log",
+        "Valid": false,
+        "Records": 41,
+        "LastHash": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+        "Error": "line 42: record 43 follows record 41, records were removed or inserted"
+      }
+
+OUTPUT
+    Result of the verification in JSON format, Records counts the records verified before the first error
+`
+
+type verifyAuditHelpParams struct {
+	SsmCliName             string
+	VerifyAuditCommandName string
+	PathFlag               string
+}
+
+func init() {
+	cliutil.Register(&VerifyAuditCommand{})
+}
+
+type VerifyAuditCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the verify-audit cli command
+func (c *VerifyAuditCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateVerifyAuditCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	path := auditlog.LocalAuditLogPath(appconfig.DefaultDataStorePath)
+	if values, exists := parameters[verifyAuditPath]; exists {
+		path = values[0]
+	}
+	result, err := auditlog.Verify(path)
+	if err != nil {
+		return err, ""
+	}
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err, ""
+	}
+	return nil, string(content)
+}
+
+// Help prints help for the verify-audit cli command
+func (c *VerifyAuditCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("VerifyAuditCommandHelp").Parse(verifyAuditCommandHelp)
+		params := verifyAuditHelpParams{cliutil.SsmCliName, verifyAuditCommand, cliutil.FormatFlag(verifyAuditPath)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (VerifyAuditCommand) Name() string {
+	return verifyAuditCommand
+}
+
+// validateVerifyAuditCommandInput checks the subcommands and parameters for unsupported values
+func (VerifyAuditCommand) validateVerifyAuditCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", verifyAuditCommand, subcommands), "")
+		return validation
+	}
+
+	if values, exists := parameters[verifyAuditPath]; exists && (len(values) != 1 || values[0] == "") {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(verifyAuditPath)))
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != verifyAuditPath {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package cloudwatchmetrics publishes metric data to CloudWatch within the limits of PutMetricData.
+package cloudwatchmetrics
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// MaxMetricDataPerPut is the maximum count of metric data accepted by a single PutMetricData call
+const MaxMetricDataPerPut = 20
+
+// Service is the subset of the CloudWatch operations used to publish metrics
+type Service interface {
+	PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error)
+}
+
+// Publish sends the metric data to the namespace in batches which respect the limits of PutMetricData.
+// It stops at the first batch which fails and returns the count of metric data left unpublished.
+func Publish(service Service, namespace string, data []*cloudwatch.MetricDatum) (unpublished int, err error) {
+	for start := 0; start < len(data); start += MaxMetricDataPerPut {
+		end := start + MaxMetricDataPerPut
+		if end > len(data) {
+			end = len(data)
+		}
+		input := &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(namespace),
+			MetricData: data[start:end],
+		}
+		if _, err = service.PutMetricData(input); err != nil {
+			return len(data) - start, err
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cloudwatchmetrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type serviceMock struct {
+	mock.Mock
+}
+
+func (m *serviceMock) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	args := m.Called(input)
+	return &cloudwatch.PutMetricDataOutput{}, args.Error(0)
+}
+
+func metricData(count int) []*cloudwatch.MetricDatum {
+	data := make([]*cloudwatch.MetricDatum, count)
+	for i := range data {
+		data[i] = &cloudwatch.MetricDatum{MetricName: aws.String(fmt.Sprintf("metric%02d", i)), Value: aws.Float64(1)}
+	}
+	return data
+}
+
+func TestPublishInBatches(t *testing.T) {
+	service := &serviceMock{}
+	service.On("PutMetricData", mock.AnythingOfType("*cloudwatch.PutMetricDataInput")).Return(nil)
+
+	unpublished, err := Publish(service, "Namespace", metricData(45))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, unpublished)
+	service.AssertNumberOfCalls(t, "PutMetricData", 3)
+	for i, expected := range []int{MaxMetricDataPerPut, MaxMetricDataPerPut, 5} {
+		input := service.Calls[i].Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+		assert.Equal(t, "Namespace", aws.StringValue(input.Namespace))
+		assert.Equal(t, expected, len(input.MetricData))
+	}
+}
+
+func TestPublishStopsOnError(t *testing.T) {
+	service := &serviceMock{}
+	service.On("PutMetricData", mock.AnythingOfType("*cloudwatch.PutMetricDataInput")).Return(nil).Once()
+	service.On("PutMetricData", mock.AnythingOfType("*cloudwatch.PutMetricDataInput")).Return(fmt.Errorf("throttled"))
+
+	unpublished, err := Publish(service, "Namespace", metricData(45))
+
+	assert.EqualError(t, err, "throttled")
+	assert.Equal(t, 25, unpublished)
+	service.AssertNumberOfCalls(t, "PutMetricData", 2)
+}
+
+func TestPublishNothing(t *testing.T) {
+	service := &serviceMock{}
+
+	unpublished, err := Publish(service, "Namespace", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, unpublished)
+	service.AssertNotCalled(t, "PutMetricData", mock.Anything)
+}
@@ -42,6 +42,7 @@ func prepareRuntimeStatus(log log.T, pluginResult PluginResult) PluginRuntimeSta
 		EndDateTime:    times.ToIso8601UTC(pluginResult.EndDateTime),
 		StandardOutput: pluginResult.StandardOutput,
 		StandardError:  pluginResult.StandardError,
+		Result:         pluginResult.Result,
 	}
 
 	if pluginResult.OutputS3BucketName != "" {
@@ -91,7 +92,9 @@ func DocumentResultAggregator(log log.T,
 		// Skipped is a form of success
 		successCounts := runtimeStatusCounts[string(ResultStatusSuccess)] + runtimeStatusCounts[string(ResultStatusSkipped)]
 
-		if runtimeStatusCounts[string(ResultStatusSuccessAndReboot)] > 0 {
+		rebootCounts := runtimeStatusCounts[string(ResultStatusSuccessAndReboot)] + runtimeStatusCounts[string(ResultStatusPassedAndReboot)]
+
+		if rebootCounts > 0 {
 			documentStatus = ResultStatusSuccessAndReboot
 		} else if runtimeStatusCounts[string(ResultStatusFailed)] > 0 {
 			documentStatus = ResultStatusFailed
@@ -40,6 +40,24 @@ func TestPrepareRuntimeStatus(t *testing.T) {
 				StandardOutput: "output",
 			},
 		},
+		{
+			Input: PluginResult{
+				PluginName:    "aws:configurePackage",
+				Status:        "Success",
+				Output:        "installed",
+				StartDateTime: times.ParseIso8601UTC("2015-07-09T23:23:39.019Z"),
+				EndDateTime:   times.ParseIso8601UTC("2015-07-09T23:23:39.023Z"),
+				Result:        map[string]interface{}{ResultFieldInstalledVersion: "1.0.0", ResultFieldRebootRequired: false},
+			},
+			Output: PluginRuntimeStatus{
+				Name:          "aws:configurePackage",
+				Status:        "Success",
+				Output:        "installed",
+				StartDateTime: "2015-07-09T23:23:39.019Z",
+				EndDateTime:   "2015-07-09T23:23:39.023Z",
+				Result:        map[string]interface{}{ResultFieldInstalledVersion: "1.0.0", ResultFieldRebootRequired: false},
+			},
+		},
 	}
 
 	// run test cases
@@ -95,6 +113,20 @@ func TestDocumentStatus(t *testing.T) {
 			},
 			Output: ResultStatusSuccessAndReboot,
 		},
+		{
+			Input: map[string]*PluginResult{
+				"aws:applications": &PluginResult{
+					PluginName: "aws:applications",
+					Code:       0,
+					Status:     "PassedAndReboot",
+				},
+				"aws:runScript": &PluginResult{
+					PluginName: "aws:runScript",
+					Status:     "NotStarted",
+				},
+			},
+			Output: ResultStatusSuccessAndReboot,
+		},
 		{
 			Input: map[string]*PluginResult{
 				"aws:runScript": &PluginResult{
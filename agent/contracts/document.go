@@ -67,7 +67,12 @@ type DocumentInfo struct {
 	DocumentVersion string
 	DocumentStatus  ResultStatus
 	RunCount        int
+	RebootCount     int
 	ProcInfo        OSProcInfo
+	// DocumentHash is the SHA-256 of the document content as received, before the parameters are resolved
+	DocumentHash string `json:",omitempty"`
+	// CorrelationID traces the execution of the document across the agent logs, the outputs and the service calls
+	CorrelationID string `json:",omitempty"`
 }
 
 // IOConfiguration represents information relevant to the output sources of a command
@@ -75,6 +80,9 @@ type IOConfiguration struct {
 	OrchestrationDirectory string
 	OutputS3BucketName     string
 	OutputS3KeyPrefix      string
+	OutputS3KmsKeyId       string
+	// CorrelationID is the correlation ID of the document, or of the step whose outputs are written
+	CorrelationID string `json:",omitempty"`
 }
 
 // DocumentState represents information relevant to a command that gets executed by agent
@@ -85,11 +93,12 @@ type DocumentState struct {
 	InstancePluginsInformation []PluginState
 	CancelInformation          CancelCommandInfo
 	IOConfig                   IOConfiguration
+	MaxConcurrentSteps         int
 }
 
 // IsRebootRequired returns if reboot is needed
 func (c *DocumentState) IsRebootRequired() bool {
-	return c.DocumentInformation.DocumentStatus == ResultStatusSuccessAndReboot
+	return c.DocumentInformation.DocumentStatus.IsReboot()
 }
 
 // IsAssociation returns if documentType is association
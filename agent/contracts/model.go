@@ -146,6 +146,19 @@ const (
 	ParamTypeStringList = "StringList"
 	// ParamTypeStringMap represents the param type is StringMap
 	ParamTypeStringMap = "StringMap"
+	// ParamTypeBoolean represents the param type is Boolean
+	ParamTypeBoolean = "Boolean"
+	// ParamTypeInteger represents the param type is Integer
+	ParamTypeInteger = "Integer"
+	// ParamTypeMapList represents the param type is MapList
+	ParamTypeMapList = "MapList"
+)
+
+const (
+	// OnFailureExit stops the document when the step fails, only its finally step still runs
+	OnFailureExit = "exit"
+	// OnFailureSuccessAndExit stops the document when the step fails, the step being reported as successful
+	OnFailureSuccessAndExit = "successAndExit"
 )
 
 type StopType string
@@ -162,6 +175,10 @@ type Parameter struct {
 	ParamType      string      `json:"type" yaml:"type"`
 	AllowedVal     []string    `json:"allowedValues" yaml:"allowedValues"`
 	AllowedPattern string      `json:"allowedPattern" yaml:"allowedPattern"`
+	MinChars       int         `json:"minChars,omitempty" yaml:"minChars,omitempty"`
+	MaxChars       int         `json:"maxChars,omitempty" yaml:"maxChars,omitempty"`
+	MinItems       int         `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+	MaxItems       int         `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
 }
 
 // PluginConfig stores plugin configuration
@@ -181,6 +198,9 @@ type InstancePluginConfig struct {
 	Settings      interface{}         `json:"settings" yaml:"settings"`
 	Timeout       int                 `json:"timeoutSeconds" yaml:"timeoutSeconds"`
 	Preconditions map[string][]string `json:"precondition" yaml:"precondition"`
+	DependsOn     []string            `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+	// FinallyStep runs the last step of the document even when an earlier step exited it or the document was canceled
+	FinallyStep bool `json:"finallyStep,omitempty" yaml:"finallyStep,omitempty"`
 }
 
 // DocumentContent object which represents ssm document content.
@@ -190,6 +210,8 @@ type DocumentContent struct {
 	RuntimeConfig map[string]*PluginConfig `json:"runtimeConfig" yaml:"runtimeConfig"`
 	MainSteps     []*InstancePluginConfig  `json:"mainSteps" yaml:"mainSteps"`
 	Parameters    map[string]*Parameter    `json:"parameters" yaml:"parameters"`
+	// MaxConcurrentSteps opts in to running the steps which do not depend on each other concurrently
+	MaxConcurrentSteps int `json:"maxConcurrentSteps,omitempty" yaml:"maxConcurrentSteps,omitempty"`
 }
 
 // AdditionalInfo section in agent response
@@ -221,6 +243,8 @@ type PluginRuntimeStatus struct {
 	OutputS3KeyPrefix  string       `json:"outputS3KeyPrefix"`
 	StandardOutput     string       `json:"standardOutput"`
 	StandardError      string       `json:"standardError"`
+	// Result is the machine-readable outcome reported by the plugin
+	Result map[string]interface{} `json:"result,omitempty"`
 }
 
 // AgentConfiguration is a struct that stores information about the agent and instance
@@ -239,4 +263,6 @@ type DocumentResult struct {
 	Status          ResultStatus
 	LastPlugin      string
 	NPlugins        int
+	// CorrelationID is the correlation ID of the document execution the result belongs to
+	CorrelationID string `json:",omitempty"`
 }
@@ -40,8 +40,22 @@ type PluginResult struct {
 	Error              error        `json:"-"`
 	StandardOutput     string       `json:"standardOutput"`
 	StandardError      string       `json:"standardError"`
+	// DownloadedBytes counts the bytes downloaded while the plugin ran, including those of the steps running alongside
+	DownloadedBytes int64 `json:"downloadedBytes"`
+	// Result is the machine-readable outcome reported by the plugin alongside its output
+	Result map[string]interface{} `json:"result,omitempty"`
 }
 
+// Fields of the plugin result shared by the plugins reporting the same kind of outcome
+const (
+	// ResultFieldInstalledVersion is the version of the software installed by the plugin
+	ResultFieldInstalledVersion = "installedVersion"
+	// ResultFieldFilesChanged lists the files created or modified by the plugin
+	ResultFieldFilesChanged = "filesChanged"
+	// ResultFieldRebootRequired is true when the changes of the plugin take effect after a reboot
+	ResultFieldRebootRequired = "rebootRequired"
+)
+
 // IPlugin is interface for authoring a functionality of work.
 // Every functionality of work is implemented as a plugin.
 type IPlugin interface {
@@ -76,8 +90,15 @@ type Configuration struct {
 	PluginID                string
 	DefaultWorkingDirectory string
 	Preconditions           map[string][]string
+	PreconditionParameters  map[string]string
+	DependsOn               []string
+	OnFailure               string
+	FinallyStep             bool
 	IsPreconditionEnabled   bool
 	CurrentAssociations     []string
+	// CorrelationID traces the execution of the step, it is the correlation ID of the document followed by
+	// the position of the step
+	CorrelationID string `json:",omitempty"`
 }
 
 // Plugin wraps the plugin configuration and plugin result.
@@ -0,0 +1,55 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package correlation generates the correlation IDs which trace a document execution and each of its steps
+// across the agent logs, the outputs of the plugins and the calls the agent makes to the AWS services.
+package correlation
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// MetadataKey is the metadata of the S3 objects uploaded by the agent which holds the correlation ID
+	MetadataKey = "correlation-id"
+	// userAgentPrefix introduces the correlation ID appended to the user agent of the AWS service calls
+	userAgentPrefix = "correlation/"
+)
+
+// New returns a new correlation ID for a document execution.
+func New() string {
+	return uuid.NewV4().String()
+}
+
+// StepID returns the correlation ID of a step, which is the correlation ID of the document followed by
+// the position of the step in the document, starting at 1.
+func StepID(documentID string, stepIndex int) string {
+	return fmt.Sprintf("%v.%v", documentID, stepIndex+1)
+}
+
+// LogContext returns the context which prefixes the log lines with the correlation ID.
+func LogContext(id string) string {
+	return "[correlationID=" + id + "]"
+}
+
+// RequestOption returns the option which appends the correlation ID to the user agent of an AWS service call,
+// it does nothing when the ID is empty.
+func RequestOption(id string) request.Option {
+	if id == "" {
+		return func(*request.Request) {}
+	}
+	return request.WithAppendUserAgent(userAgentPrefix + id)
+}
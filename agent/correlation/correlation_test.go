@@ -0,0 +1,58 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package correlation
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	first := New()
+	second := New()
+
+	assert.NotEmpty(t, first)
+	assert.NotEqual(t, first, second)
+}
+
+func TestStepID(t *testing.T) {
+	assert.Equal(t, "doc.1", StepID("doc", 0))
+	assert.Equal(t, "doc.3", StepID("doc", 2))
+}
+
+func TestLogContext(t *testing.T) {
+	assert.Equal(t, "[correlationID=doc.1]", LogContext("doc.1"))
+}
+
+func TestRequestOption(t *testing.T) {
+	testCases := []struct {
+		id        string
+		userAgent string
+	}{
+		{"", "agent"},
+		{"doc.1", "agent correlation/doc.1"},
+	}
+	for _, tc := range testCases {
+		req := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+		req.HTTPRequest.Header.Set("User-Agent", "agent")
+
+		req.ApplyOptions(RequestOption(tc.id))
+		req.Handlers.Build.Run(req)
+
+		assert.Equal(t, tc.userAgent, req.HTTPRequest.Header.Get("User-Agent"))
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package diagnostics collects the information needed to troubleshoot the agent into a single archive
+// that can be attached to a support case.
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/atrest"
+	"github.com/aws/amazon-ssm-agent/agent/hibernation"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/redact"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const (
+	probeTimeout = 10 * time.Second
+	// maxLogBytes is the size of the end of each log file included in the bundle
+	maxLogBytes = 1024 * 1024
+
+	versionEntry      = "version.txt"
+	configEntry       = "appconfig.json"
+	connectivityEntry = "connectivity.json"
+	statusEntry       = "agent-status.json"
+	hibernationEntry  = "hibernation.json"
+	errorsEntry       = "errors.txt"
+	logsFolder        = "logs"
+	documentsFolder   = "documents"
+)
+
+// EndpointCheck is the result of the connectivity test of an endpoint the agent calls
+type EndpointCheck struct {
+	Service        string
+	Endpoint       string
+	Reachable      bool
+	StatusCode     int    `json:",omitempty"`
+	Error          string `json:",omitempty"`
+	DurationMillis int64
+}
+
+// services lists the services the agent requires connectivity to
+var services = []string{
+	appconfig.ServiceNameSsm,
+	appconfig.ServiceNameEc2Messages,
+	appconfig.ServiceNameSsmMessages,
+	appconfig.ServiceNameS3,
+}
+
+// dependencies of the collection, replaced in the tests
+var (
+	loadConfig           = func() (appconfig.SsmagentConfig, error) { return appconfig.Config(false) }
+	queryStatus          = statusapi.Query
+	probe                = probeEndpoint
+	region               = platform.Region
+	instanceID           = platform.InstanceID
+	logDir               = log.DefaultLogDir
+	dataStorePath        = appconfig.DefaultDataStorePath
+	hibernationStatePath = hibernation.StatePath()
+)
+
+// bundle writes the entries of the diagnostics archive and records the information that could not be collected
+type bundle struct {
+	writer   *zip.Writer
+	secrets  redact.Values
+	failures []string
+}
+
+// Collect writes the diagnostics zip archive: the agent version, the sanitized configuration, the connectivity
+// to each required endpoint, the end of the logs, the hibernation state and the pending and in progress documents.
+// The collection is best effort, what could not be collected is listed in errors.txt.
+func Collect(w io.Writer) error {
+	b := &bundle{writer: zip.NewWriter(w)}
+
+	b.addText(versionEntry, version.Version+"\n")
+
+	config, err := loadConfig()
+	if err != nil {
+		b.fail("load appconfig", err)
+	}
+	b.secrets = secrets(config)
+	b.addJSON(configEntry, sanitize(config))
+	b.addJSON(connectivityEntry, checkConnectivity(config))
+
+	if status, err := queryStatus(statusapi.SectionAll); err != nil {
+		b.fail("query agent status", err)
+	} else {
+		b.addText(statusEntry, status)
+	}
+
+	if state, err := hibernation.LoadState(hibernationStatePath); err != nil {
+		b.fail("load hibernation state", err)
+	} else if state != nil {
+		b.addJSON(hibernationEntry, state)
+	}
+
+	for _, name := range []string{log.LogFile, log.ErrorFile} {
+		b.addLog(filepath.Join(logDir, name))
+	}
+	b.addDocuments()
+
+	if len(b.failures) > 0 {
+		b.addText(errorsEntry, strings.Join(b.failures, "\n")+"\n")
+	}
+	return b.writer.Close()
+}
+
+// fail records information that could not be collected
+func (b *bundle) fail(what string, err error) {
+	b.failures = append(b.failures, fmt.Sprintf("failed to %v: %v", what, b.secrets.String(err.Error())))
+}
+
+// addText adds an entry with the content masked of the secrets
+func (b *bundle) addText(name string, content string) {
+	b.addBytes(name, []byte(content))
+}
+
+// addJSON adds an entry with the indented json of the value
+func (b *bundle) addJSON(name string, value interface{}) {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		b.fail("marshal "+name, err)
+		return
+	}
+	b.addBytes(name, content)
+}
+
+func (b *bundle) addBytes(name string, content []byte) {
+	entry, err := b.writer.Create(name)
+	if err == nil {
+		_, err = entry.Write(b.secrets.Bytes(content))
+	}
+	if err != nil {
+		b.fail("write "+name, err)
+	}
+}
+
+// addLog adds the end of the log file to the logs folder
+func (b *bundle) addLog(path string) {
+	content, err := tail(path, maxLogBytes)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		b.fail("read log "+path, err)
+		return
+	}
+	b.addBytes(logsFolder+"/"+filepath.Base(path), content)
+}
+
+// addDocuments adds the state of the pending and in progress documents
+func (b *bundle) addDocuments() {
+	id, err := instanceID()
+	if err != nil {
+		b.fail("get instance id", err)
+		return
+	}
+	for _, location := range []string{appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent} {
+		dir := filepath.Join(dataStorePath, id, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState, location)
+		files, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			b.fail("list "+location+" documents", err)
+			continue
+		}
+		for _, file := range files {
+			if !file.Mode().IsRegular() {
+				continue
+			}
+			// the states encrypted at rest are added decrypted, they are useless in the bundle otherwise
+			content, err := atrest.ReadFile(filepath.Join(dir, file.Name()))
+			if err != nil {
+				b.fail("read document state "+file.Name(), err)
+				continue
+			}
+			b.addBytes(documentsFolder+"/"+location+"/"+file.Name(), content)
+		}
+	}
+}
+
+// tail returns at most the last max bytes of the file
+func tail(path string, max int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > max {
+		if _, err = file.Seek(info.Size()-max, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return ioutil.ReadAll(file)
+}
+
+// checkConnectivity tests the connectivity to the endpoint of each required service
+func checkConnectivity(config appconfig.SsmagentConfig) []EndpointCheck {
+	regionName := config.Agent.Region
+	if regionName == "" {
+		regionName, _ = region()
+	}
+	checks := make([]EndpointCheck, 0, len(services))
+	for _, service := range services {
+		check := EndpointCheck{Service: service, Endpoint: endpointURL(config, service, regionName)}
+		start := time.Now()
+		statusCode, err := probe(service, check.Endpoint)
+		check.DurationMillis = int64(time.Since(start) / time.Millisecond)
+		check.StatusCode = statusCode
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Reachable = true
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// endpointURL returns the https url of the endpoint the agent calls for the service
+func endpointURL(config appconfig.SsmagentConfig, service string, region string) string {
+	endpoint := appconfig.GetServiceEndpoint(config, service, region)
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("%v.%v.amazonaws.com", service, region)
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	if u, err := url.Parse(endpoint); err == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	return endpoint
+}
+
+// probeEndpoint sends a https request to the endpoint through the proxy of the service, any response is a success
+func probeEndpoint(service string, endpoint string) (int, error) {
+	client := &http.Client{Transport: proxyconfig.NewTransport(service), Timeout: probeTimeout}
+	resp, err := client.Head(endpoint)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// secrets returns the sensitive values of the configuration, masked everywhere in the bundle
+func secrets(config appconfig.SsmagentConfig) redact.Values {
+	values := []string{config.Registration.ReactivationCommand}
+	for _, proxy := range proxies(&config) {
+		if u, err := url.Parse(*proxy); err == nil && u.User != nil {
+			if password, ok := u.User.Password(); ok {
+				values = append(values, password)
+			}
+		}
+	}
+	return redact.New(values...)
+}
+
+// sanitize returns a copy of the configuration without the credentials of the proxies and the reactivation command
+func sanitize(config appconfig.SsmagentConfig) appconfig.SsmagentConfig {
+	for _, proxy := range proxies(&config) {
+		if u, err := url.Parse(*proxy); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				// url.UserPassword escapes the mask, the escaped user name has no @ so the first one ends the user info
+				u.User = url.User(u.User.Username())
+				*proxy = strings.Replace(u.String(), "@", ":"+redact.Mask+"@", 1)
+			}
+		}
+	}
+	if config.Registration.ReactivationCommand != "" {
+		config.Registration.ReactivationCommand = redact.Mask
+	}
+	return config
+}
+
+// proxies returns pointers to the proxy urls of the configuration
+func proxies(config *appconfig.SsmagentConfig) []*string {
+	return []*string{&config.Proxy.Ssm, &config.Proxy.Ec2Messages, &config.Proxy.SsmMessages, &config.Proxy.S3}
+}
@@ -18,12 +18,16 @@ package docparser
 import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/correlation"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/parameters"
 	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -38,6 +42,7 @@ type DocumentParserInfo struct {
 	OrchestrationDir  string
 	S3Bucket          string
 	S3Prefix          string
+	S3KmsKeyId        string
 	MessageId         string
 	DocumentId        string
 	DefaultWorkingDir string
@@ -55,20 +60,40 @@ func InitializeDocState(log log.T,
 	docState.SchemaVersion = docContent.SchemaVersion
 	docState.DocumentType = documentType
 	docState.DocumentInformation = docInfo
+	docState.DocumentInformation.DocumentHash = documentHash(docContent)
+	if docState.DocumentInformation.CorrelationID == "" {
+		docState.DocumentInformation.CorrelationID = correlation.New()
+	}
 	docState.IOConfig = contracts.IOConfiguration{
 		OrchestrationDirectory: parserInfo.OrchestrationDir,
 		OutputS3BucketName:     parserInfo.S3Bucket,
 		OutputS3KeyPrefix:      parserInfo.S3Prefix,
+		OutputS3KmsKeyId:       parserInfo.S3KmsKeyId,
+		CorrelationID:          docState.DocumentInformation.CorrelationID,
 	}
 
 	pluginInfo, err := ParseDocument(log, docContent, parserInfo, params)
 	if err != nil {
 		return
 	}
+	for i := range pluginInfo {
+		pluginInfo[i].Configuration.CorrelationID = correlation.StepID(docState.DocumentInformation.CorrelationID, i)
+	}
 	docState.InstancePluginsInformation = pluginInfo
+	docState.MaxConcurrentSteps = docContent.MaxConcurrentSteps
 	return docState, nil
 }
 
+// documentHash returns the SHA-256 of the document content, empty if it cannot be marshalled
+func documentHash(docContent *contracts.DocumentContent) string {
+	content, err := json.Marshal(docContent)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
 // ParseDocument is a method used to parse documents that are not received by any service (MDS or State manager)
 func ParseDocument(log log.T,
 	docContent *contracts.DocumentContent,
@@ -78,11 +103,12 @@ func ParseDocument(log log.T,
 	if err = validateSchema(docContent.SchemaVersion); err != nil {
 		return
 	}
-	if err = getValidatedParameters(log, params, docContent); err != nil {
+	var validParameters map[string]interface{}
+	if validParameters, err = getValidatedParameters(log, params, docContent); err != nil {
 		return
 	}
 
-	return parseDocumentContent(*docContent, parserInfo)
+	return parseDocumentContent(*docContent, parserInfo, validParameters)
 }
 
 // ParseParameters is a method to parse the ssm parameters into a string map interface
@@ -113,7 +139,7 @@ func ParseParameters(log log.T, params map[string][]*string, paramsDef map[strin
 }
 
 // parseDocumentContent parses an SSM Document and returns the plugin information
-func parseDocumentContent(docContent contracts.DocumentContent, parserInfo DocumentParserInfo) (pluginsInfo []contracts.PluginState, err error) {
+func parseDocumentContent(docContent contracts.DocumentContent, parserInfo DocumentParserInfo, params map[string]interface{}) (pluginsInfo []contracts.PluginState, err error) {
 
 	switch docContent.SchemaVersion {
 	case "1.0", "1.2":
@@ -121,7 +147,7 @@ func parseDocumentContent(docContent contracts.DocumentContent, parserInfo Docum
 
 	case "2.0", "2.0.1", "2.0.2", "2.0.3", "2.2":
 
-		return parsePluginStateForV20Schema(docContent, parserInfo.OrchestrationDir, parserInfo.S3Bucket, parserInfo.S3Prefix, parserInfo.MessageId, parserInfo.DocumentId, parserInfo.DefaultWorkingDir)
+		return parsePluginStateForV20Schema(docContent, parserInfo.OrchestrationDir, parserInfo.S3Bucket, parserInfo.S3Prefix, parserInfo.MessageId, parserInfo.DocumentId, parserInfo.DefaultWorkingDir, params)
 
 	default:
 		return pluginsInfo, fmt.Errorf("Unsupported document")
@@ -169,7 +195,8 @@ func parsePluginStateForV10Schema(
 // parsePluginStateForV20Schema initializes instancePluginsInfo for the docState. Used by document v2.0.
 func parsePluginStateForV20Schema(
 	docContent contracts.DocumentContent,
-	orchestrationDir, s3Bucket, s3Prefix, messageID, documentID, defaultWorkingDir string) (pluginsInfo []contracts.PluginState, err error) {
+	orchestrationDir, s3Bucket, s3Prefix, messageID, documentID, defaultWorkingDir string,
+	params map[string]interface{}) (pluginsInfo []contracts.PluginState, err error) {
 
 	if len(docContent.MainSteps) == 0 {
 		return pluginsInfo, fmt.Errorf("Unsupported schema format")
@@ -181,7 +208,25 @@ func parsePluginStateForV20Schema(
 	isPreconditionEnabled := isPreconditionEnabled(docContent.SchemaVersion)
 
 	// getPluginConfigurations converts from PluginConfig (structure from the MDS message) to plugin.Configuration (structure expected by the plugin)
+	previousSteps := make(map[string]bool)
 	for _, instancePluginConfig := range docContent.MainSteps {
+		// steps can only depend on the steps before them, which rules out cycles
+		for _, dependency := range instancePluginConfig.DependsOn {
+			if !previousSteps[dependency] {
+				return pluginsInfo, fmt.Errorf("step %v depends on %v which is not a previous step of the document", instancePluginConfig.Name, dependency)
+			}
+		}
+		previousSteps[instancePluginConfig.Name] = true
+		switch instancePluginConfig.OnFailure {
+		case "", contracts.OnFailureExit, contracts.OnFailureSuccessAndExit:
+		default:
+			return pluginsInfo, fmt.Errorf("step %v has invalid onFailure %v, must be %v or %v",
+				instancePluginConfig.Name, instancePluginConfig.OnFailure, contracts.OnFailureExit, contracts.OnFailureSuccessAndExit)
+		}
+		if instancePluginConfig.FinallyStep && instancePluginConfig != docContent.MainSteps[len(docContent.MainSteps)-1] {
+			return pluginsInfo, fmt.Errorf("step %v is a finally step, only the last step of the document can be", instancePluginConfig.Name)
+		}
+
 		pluginName := instancePluginConfig.Action
 		config := contracts.Configuration{
 			Settings:                instancePluginConfig.Settings,
@@ -194,7 +239,11 @@ func parsePluginStateForV20Schema(
 			PluginName:              pluginName,
 			PluginID:                instancePluginConfig.Name,
 			Preconditions:           instancePluginConfig.Preconditions,
+			PreconditionParameters:  preconditionParameters(instancePluginConfig.Preconditions, params),
 			IsPreconditionEnabled:   isPreconditionEnabled,
+			DependsOn:               instancePluginConfig.DependsOn,
+			OnFailure:               instancePluginConfig.OnFailure,
+			FinallyStep:             instancePluginConfig.FinallyStep,
 			DefaultWorkingDirectory: defaultWorkingDir,
 		}
 
@@ -220,7 +269,8 @@ func validateSchema(documentSchemaVersion string) error {
 }
 
 // getValidatedParameters validates the parameters and modifies the document content by replacing all ssm parameters with their actual values.
-func getValidatedParameters(log log.T, params map[string]interface{}, docContent *contracts.DocumentContent) error {
+// It returns the validated parameters, including the default values of the parameters which were not provided.
+func getValidatedParameters(log log.T, params map[string]interface{}, docContent *contracts.DocumentContent) (map[string]interface{}, error) {
 
 	//ValidateParameterNames
 	validParameters := parameters.ValidParameters(log, params)
@@ -232,14 +282,40 @@ func getValidatedParameters(log log.T, params map[string]interface{}, docContent
 		}
 	}
 
+	log.Info("Validating parameters")
+	if err := validateParameterValues(log, docContent.Parameters, validParameters); err != nil {
+		return nil, err
+	}
+
 	log.Info("Validating SSM parameters")
 	// Validates SSM parameters
 	if err := parameterstore.ValidateSSMParameters(log, docContent.Parameters, validParameters); err != nil {
-		return err
+		return nil, err
 	}
 
 	err := replaceValidatedPluginParameters(docContent, validParameters, log)
-	return err
+	return validParameters, err
+}
+
+// preconditionParameters returns the values of the parameters referred to by the preconditions of a step,
+// the preconditions themselves are evaluated by the plugin runner.
+func preconditionParameters(preconditions map[string][]string, params map[string]interface{}) map[string]string {
+	var values map[string]string
+	for _, operands := range preconditions {
+		for _, operand := range operands {
+			paramName, isReference := parameters.ParameterReference(operand)
+			if !isReference {
+				continue
+			}
+			if value, found := params[paramName]; found {
+				if values == nil {
+					values = make(map[string]string)
+				}
+				values[paramName] = fmt.Sprint(value)
+			}
+		}
+	}
+	return values
 }
 
 // replaceValidatedPluginParameters replaces parameters with their values, within the plugin Properties.
@@ -137,6 +137,8 @@ func TestInitializeDocState_Valid(t *testing.T) {
 	pluginInfo := docState.InstancePluginsInformation
 	assert.Equal(t, contracts.SendCommand, docState.DocumentType)
 	assert.Equal(t, "1.2", docState.SchemaVersion)
+	assert.Equal(t, documentHash(&testDocContent), docState.DocumentInformation.DocumentHash)
+	assert.Len(t, docState.DocumentInformation.DocumentHash, 64)
 	assert.Equal(t, 1, len(pluginInfo))
 	assert.Equal(t, filepath.Join(testOrchDir, "awsrunShellScript"), pluginInfo[0].Configuration.OrchestrationDirectory)
 	assert.Equal(t, testS3Bucket, pluginInfo[0].Configuration.OutputS3BucketName)
@@ -188,6 +190,65 @@ func TestParseDocument_Invalid(t *testing.T) {
 	assert.Contains(t, err.Error(), "Unsupported schema format")
 }
 
+func TestParseDocument_DependsOn(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir: testOrchDir,
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+	}
+	testDocContent := contracts.DocumentContent{
+		SchemaVersion:      "2.2",
+		MaxConcurrentSteps: 2,
+		MainSteps: []*contracts.InstancePluginConfig{
+			{Action: "aws:runShellScript", Name: "first"},
+			{Action: "aws:runShellScript", Name: "second", DependsOn: []string{"first"}},
+		},
+	}
+
+	pluginsInfo, err := ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first"}, pluginsInfo[1].Configuration.DependsOn)
+
+	// dependencies must be declared before the step depending on them
+	testDocContent.MainSteps[0].DependsOn = []string{"second"}
+	_, err = ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+	assert.Error(t, err)
+}
+
+func TestParseDocument_CleanupSteps(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir: testOrchDir,
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+	}
+	testDocContent := contracts.DocumentContent{
+		SchemaVersion: "2.2",
+		MainSteps: []*contracts.InstancePluginConfig{
+			{Action: "aws:runShellScript", Name: "install", OnFailure: contracts.OnFailureExit},
+			{Action: "aws:runShellScript", Name: "configure", OnFailure: contracts.OnFailureSuccessAndExit},
+			{Action: "aws:runShellScript", Name: "cleanup", FinallyStep: true},
+		},
+	}
+
+	pluginsInfo, err := ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.OnFailureExit, pluginsInfo[0].Configuration.OnFailure)
+	assert.Equal(t, contracts.OnFailureSuccessAndExit, pluginsInfo[1].Configuration.OnFailure)
+	assert.True(t, pluginsInfo[2].Configuration.FinallyStep)
+
+	// only the last step can be a finally step
+	testDocContent.MainSteps[1].FinallyStep = true
+	_, err = ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+	assert.Error(t, err)
+
+	testDocContent.MainSteps[1].FinallyStep = false
+	testDocContent.MainSteps[1].OnFailure = "continue"
+	_, err = ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+	assert.Error(t, err)
+}
+
 func TestParseDocument_InvalidSchema(t *testing.T) {
 	mockLog := log.NewMockLog()
 	testParserInfo := DocumentParserInfo{
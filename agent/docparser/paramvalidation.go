@@ -0,0 +1,267 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package docparser contains methods for parsing and encoding any type of document,
+// i.e. association document, MDS/SSM messages, offline service documents, etc.
+package docparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// ssmParamReference matches the references to ssm parameters, which are validated once resolved
+var ssmParamReference = regexp.MustCompile(`\{\{ *ssm(-secure)?:`)
+
+// ParameterViolation describes a parameter value which does not satisfy its definition in the document
+type ParameterViolation struct {
+	Name       string
+	Constraint string
+	Message    string
+}
+
+// ParameterValidationError lists all the parameter values which do not satisfy their definitions
+type ParameterValidationError struct {
+	Violations []ParameterViolation
+}
+
+// Error returns the violations, one per line
+func (e *ParameterValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = fmt.Sprintf("%v: %v", violation.Name, violation.Message)
+	}
+	return "Invalid document parameters:\n" + strings.Join(messages, "\n")
+}
+
+// parameterValidator collects the violations of the parameters
+type parameterValidator struct {
+	log        log.T
+	name       string
+	violations []ParameterViolation
+}
+
+func (v *parameterValidator) violate(constraint, format string, params ...interface{}) {
+	v.violations = append(v.violations, ParameterViolation{Name: v.name, Constraint: constraint, Message: fmt.Sprintf(format, params...)})
+}
+
+// validateParameterValues checks the values of the parameters against the type, allowedValues, allowedPattern
+// and the length constraints declared by the document and returns a ParameterValidationError listing every violation.
+// Values referencing ssm parameters are only checked for their type, their resolved values are validated later.
+// An allowedPattern which isn't a valid RE2 expression is accepted by the service, it is not checked.
+func validateParameterValues(log log.T, definitions map[string]*contracts.Parameter, params map[string]interface{}) error {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations []ParameterViolation
+	for _, name := range names {
+		definition := definitions[name]
+		if definition == nil {
+			continue
+		}
+		validator := &parameterValidator{log: log, name: name}
+		validator.validate(definition, params[name])
+		violations = append(violations, validator.violations...)
+	}
+
+	if len(violations) > 0 {
+		return &ParameterValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// validate checks the value against the definition of the parameter
+func (v *parameterValidator) validate(definition *contracts.Parameter, value interface{}) {
+	// the service enforces required parameters, a parameter without value nor default is left as is
+	if value == nil {
+		return
+	}
+
+	var pattern *regexp.Regexp
+	if definition.AllowedPattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(definition.AllowedPattern); err != nil {
+			v.log.Warnf("allowed pattern %v of parameter %v is not supported by the agent, the value isn't checked against it: %v",
+				definition.AllowedPattern, v.name, err)
+		}
+	}
+
+	switch definition.ParamType {
+	case contracts.ParamTypeString:
+		text, ok := value.(string)
+		if !ok {
+			v.violate("type", "expected a String but got %v", describe(value))
+			return
+		}
+		v.validateText(definition, pattern, text)
+
+	case contracts.ParamTypeStringList:
+		items, ok := stringList(value)
+		if !ok {
+			v.violate("type", "expected a StringList but got %v", describe(value))
+			return
+		}
+		v.validateItemCount(definition, len(items))
+		for _, item := range items {
+			v.validateText(definition, pattern, item)
+		}
+
+	case contracts.ParamTypeStringMap:
+		if !isStringMap(value) {
+			v.violate("type", "expected a StringMap but got %v", describe(value))
+		}
+
+	case contracts.ParamTypeMapList:
+		items, ok := value.([]interface{})
+		if !ok {
+			v.violate("type", "expected a MapList but got %v", describe(value))
+			return
+		}
+		for _, item := range items {
+			if !isStringMap(item) {
+				v.violate("type", "expected a MapList but got an item %v", describe(item))
+				return
+			}
+		}
+		v.validateItemCount(definition, len(items))
+
+	case contracts.ParamTypeBoolean:
+		if _, ok := value.(bool); ok {
+			return
+		}
+		if text, ok := value.(string); !ok || (!isSSMReference(text) && text != "true" && text != "false") {
+			v.violate("type", "expected a Boolean but got %v", describe(value))
+		}
+
+	case contracts.ParamTypeInteger:
+		switch number := value.(type) {
+		case int, int64:
+		case float64:
+			if number != math.Trunc(number) {
+				v.violate("type", "expected an Integer but got %v", number)
+			}
+		case string:
+			if _, err := strconv.ParseInt(number, 10, 64); err != nil && !isSSMReference(number) {
+				v.violate("type", "expected an Integer but got %v", describe(value))
+			}
+		default:
+			v.violate("type", "expected an Integer but got %v", describe(value))
+		}
+	}
+}
+
+// validateText checks a String value or an item of a StringList
+func (v *parameterValidator) validateText(definition *contracts.Parameter, pattern *regexp.Regexp, text string) {
+	if isSSMReference(text) {
+		return
+	}
+	if len(definition.AllowedVal) > 0 && !contains(definition.AllowedVal, text) {
+		v.violate("allowedValues", "value %q is not one of the allowed values %v", text, definition.AllowedVal)
+	}
+	if pattern != nil && !pattern.MatchString(text) {
+		v.violate("allowedPattern", "value %q does not match the allowed pattern %v", text, definition.AllowedPattern)
+	}
+	length := utf8.RuneCountInString(text)
+	if definition.MinChars > 0 && length < definition.MinChars {
+		v.violate("minChars", "value %q is shorter than %v characters", text, definition.MinChars)
+	}
+	if definition.MaxChars > 0 && length > definition.MaxChars {
+		v.violate("maxChars", "value of %v characters is longer than %v characters", length, definition.MaxChars)
+	}
+}
+
+// validateItemCount checks the number of items of a StringList or MapList
+func (v *parameterValidator) validateItemCount(definition *contracts.Parameter, count int) {
+	if definition.MinItems > 0 && count < definition.MinItems {
+		v.violate("minItems", "%v items provided, at least %v are required", count, definition.MinItems)
+	}
+	if definition.MaxItems > 0 && count > definition.MaxItems {
+		v.violate("maxItems", "%v items provided, at most %v are allowed", count, definition.MaxItems)
+	}
+}
+
+// stringList returns the items of a StringList value
+func stringList(value interface{}) ([]string, bool) {
+	switch value := value.(type) {
+	case []string:
+		return value, true
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			text, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			items[i] = text
+		}
+		return items, true
+	case string:
+		// a StringList ssm parameter is split into items once resolved
+		return []string{value}, isSSMReference(value)
+	}
+	return nil, false
+}
+
+// isStringMap returns true for a map or the json representation of an object
+func isStringMap(value interface{}) bool {
+	switch value := value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	case string:
+		var object map[string]interface{}
+		return isSSMReference(value) || json.Unmarshal([]byte(value), &object) == nil
+	}
+	return false
+}
+
+func isSSMReference(text string) bool {
+	return ssmParamReference.MatchString(text)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// describe returns the type of a value for the violation messages
+func describe(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64, int, int64:
+		return "a number"
+	case []interface{}, []string:
+		return "a list"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "a map"
+	}
+	return fmt.Sprintf("%T", value)
+}
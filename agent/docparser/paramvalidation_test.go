@@ -0,0 +1,126 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package docparser contains methods for parsing and encoding any type of document,
+// i.e. association document, MDS/SSM messages, offline service documents, etc.
+package docparser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const validationDefinitions = `{
+	"level":    {"type": "String", "allowedValues": ["low", "high"], "default": "low"},
+	"name":     {"type": "String", "allowedPattern": "^[a-z]+$", "minChars": 2, "maxChars": 5},
+	"commands": {"type": "StringList", "minItems": 1, "maxItems": 2, "maxChars": 10},
+	"tags":     {"type": "StringMap"},
+	"enabled":  {"type": "Boolean"},
+	"count":    {"type": "Integer"},
+	"targets":  {"type": "MapList", "maxItems": 1}
+}`
+
+func validationTestDefinitions(t *testing.T) map[string]*contracts.Parameter {
+	var definitions map[string]*contracts.Parameter
+	assert.NoError(t, json.Unmarshal([]byte(validationDefinitions), &definitions))
+	return definitions
+}
+
+func TestValidateParameterValues_Valid(t *testing.T) {
+	var params map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"level": "high",
+		"name": "abc",
+		"commands": ["echo", "{{ssm:commands}}"],
+		"tags": "{\"env\": \"prod\"}",
+		"enabled": true,
+		"count": 3,
+		"targets": [{"key": "value"}]
+	}`), &params)
+
+	assert.NoError(t, validateParameterValues(log.NewMockLog(), validationTestDefinitions(t), params))
+}
+
+func TestValidateParameterValues_ParsedParameters(t *testing.T) {
+	params := map[string]interface{}{
+		"name":     "{{ssm:name}}",
+		"commands": []string{"echo"},
+		"enabled":  "false",
+		"count":    "42",
+	}
+
+	assert.NoError(t, validateParameterValues(log.NewMockLog(), validationTestDefinitions(t), params))
+}
+
+func TestValidateParameterValues_ListsEveryViolation(t *testing.T) {
+	var params map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"level": "medium",
+		"name": "A",
+		"commands": ["echo", "a very long command", "date"],
+		"tags": ["not", "a", "map"],
+		"enabled": "yes",
+		"count": 1.5,
+		"targets": [{"a": "b"}, {"c": "d"}]
+	}`), &params)
+
+	err := validateParameterValues(log.NewMockLog(), validationTestDefinitions(t), params)
+
+	validationErr, ok := err.(*ParameterValidationError)
+	assert.True(t, ok)
+	constraints := map[string][]string{}
+	for _, violation := range validationErr.Violations {
+		constraints[violation.Name] = append(constraints[violation.Name], violation.Constraint)
+	}
+	assert.Equal(t, map[string][]string{
+		"level":    {"allowedValues"},
+		"name":     {"allowedPattern", "minChars"},
+		"commands": {"maxItems", "maxChars"},
+		"tags":     {"type"},
+		"enabled":  {"type"},
+		"count":    {"type"},
+		"targets":  {"maxItems"},
+	}, constraints)
+	assert.Contains(t, err.Error(), "level: value \"medium\" is not one of the allowed values [low high]")
+}
+
+func TestValidateParameterValues_UnsupportedPattern(t *testing.T) {
+	definitions := map[string]*contracts.Parameter{
+		"name": {ParamType: contracts.ParamTypeString, AllowedPattern: "^(?!root$)[a-z]+$", MaxChars: 5},
+	}
+
+	// the lookahead isn't supported by RE2, only the other constraints are checked
+	assert.NoError(t, validateParameterValues(log.NewMockLog(), definitions, map[string]interface{}{"name": "root"}))
+	err := validateParameterValues(log.NewMockLog(), definitions, map[string]interface{}{"name": "administrator"})
+	validationErr, ok := err.(*ParameterValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []ParameterViolation{{Name: "name", Constraint: "maxChars", Message: "value of 13 characters is longer than 5 characters"}}, validationErr.Violations)
+}
+
+func TestParseDocument_InvalidParameterValue(t *testing.T) {
+	docContent := contracts.DocumentContent{
+		SchemaVersion: "2.2",
+		Parameters:    validationTestDefinitions(t),
+		MainSteps: []*contracts.InstancePluginConfig{
+			{Action: "aws:runShellScript", Name: "run", Inputs: map[string]interface{}{"runCommand": "{{ commands }}"}},
+		},
+	}
+
+	_, err := ParseDocument(log.NewMockLog(), &docContent, DocumentParserInfo{}, map[string]interface{}{"level": "medium"})
+
+	assert.IsType(t, &ParameterValidationError{}, err)
+}
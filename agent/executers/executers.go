@@ -43,6 +43,7 @@ type T interface {
 	//TODO: Remove Execute and rename NewExecute to Execute.
 	Execute(log.T, string, string, string, task.CancelFlag, int, string, []string) (io.Reader, io.Reader, int, []error)
 	NewExecute(log.T, string, io.Writer, io.Writer, task.CancelFlag, int, string, []string) (int, error)
+	NewExecuteWithOptions(log.T, string, io.Writer, io.Writer, task.CancelFlag, int, string, []string, ExecuteOptions) (int, error)
 	StartExe(log.T, string, io.Writer, io.Writer, task.CancelFlag, string, []string) (*os.Process, int, error)
 }
 
@@ -50,6 +51,36 @@ type T interface {
 type ShellCommandExecuter struct {
 }
 
+// ExecuteOptions holds the optional settings for the process started by the executer.
+type ExecuteOptions struct {
+	// RunAsUser is the local account the process runs as, the agent account is used when empty.
+	RunAsUser string
+	// RunAsGroup is the group the process runs as, the primary group of RunAsUser is used when empty.
+	RunAsGroup string
+	// RunAsPassword is the password of RunAsUser, only used on windows where it allows access to network resources.
+	// NOTE: Do not log it
+	RunAsPassword string
+	// Environment holds additional environment variables for the process, overriding inherited values.
+	Environment map[string]string
+	// PrivateTmp runs the process with its own empty temporary directories where supported.
+	PrivateTmp bool
+	// Sandbox confines the process where supported, either "restricted" or "apparmor", it is not confined when empty.
+	Sandbox string
+	// SandboxProfile is the AppArmor profile of the process in the apparmor sandbox.
+	SandboxProfile string
+	// ResourceLimits caps the resources of the process and its descendants where supported,
+	// the process is killed when it exceeds its memory or process limit
+	ResourceLimits ResourceLimits
+	// ProcessTreeKilled is called with the number of processes killed when the process is cancelled or times out.
+	ProcessTreeKilled func(processesKilled int)
+}
+
+// IsManagedServiceAccount returns true if the account is a (group) managed service account.
+// Their names end with $ and their password is managed by the domain.
+func IsManagedServiceAccount(account string) bool {
+	return strings.HasSuffix(account, "$")
+}
+
 type timeoutSignal struct {
 	// process kill doesn't send proper signal to the process status
 	// Setting the execInterruptedOnWindows to indicate execution was interrupted
@@ -173,6 +204,22 @@ func (ShellCommandExecuter) NewExecute(
 	return
 }
 
+// NewExecuteWithOptions executes a list of shell commands in the given working directory with the given execute options.
+func (ShellCommandExecuter) NewExecuteWithOptions(
+	log log.T,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	cancelFlag task.CancelFlag,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	options ExecuteOptions,
+) (exitCode int, err error) {
+	exitCode, err = ExecuteCommandWithOptions(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, options)
+	return
+}
+
 // StartExe starts a list of shell commands in the given working directory.
 // Returns process started, an exit code (0 if successfully launch, 1 if error launching process), and a set of errors.
 // The errors need not be fatal - the output streams may still have data
@@ -254,6 +301,21 @@ func ExecuteCommand(log log.T,
 	commandName string,
 	commandArguments []string,
 ) (exitCode int, err error) {
+	return ExecuteCommandWithOptions(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, ExecuteOptions{})
+}
+
+// ExecuteCommandWithOptions executes the given commands using the given working directory and execute options.
+// Standard output and standard error are sent to the given writers.
+func ExecuteCommandWithOptions(log log.T,
+	cancelFlag task.CancelFlag,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	options ExecuteOptions,
+) (exitCode int, err error) {
 
 	stdoutInterruptable, stopStdout := newWriter(stdoutWriter)
 	stderrInterruptable, stopStderr := newWriter(stderrWriter)
@@ -285,17 +347,71 @@ func ExecuteCommand(log log.T,
 	// configure environment variables
 	prepareEnvironment(command)
 
+	// configure the account the process runs as
+	if err = prepareRunAs(command, options); err != nil {
+		log.Error("error occurred preparing the command to run as another user", err)
+		exitCode = 1
+		return
+	}
+
+	// inject the environment variables requested for this execution
+	injectEnvironment(command, options.Environment)
+
+	// confine the process, before the private temporary directories are mounted with the privileges of the agent
+	if err = prepareSandbox(log, command, options); err != nil {
+		log.Error("error occurred preparing the sandbox", err)
+		exitCode = 1
+		return
+	}
+
+	// isolate the temporary directories of the process
+	if err = preparePrivateTmp(log, command, options); err != nil {
+		log.Error("error occurred preparing private temporary directories", err)
+		exitCode = 1
+		return
+	}
+
+	// cap the resources of the process and its descendants
+	limiter, err := prepareResourceLimits(log, command, options)
+	if err != nil {
+		log.Error("error occurred preparing the resource limits", err)
+		exitCode = 1
+		return
+	}
+	defer limiter.release(log)
+
 	log.Debug()
 	log.Debugf("Running in directory %v, command: %v %v", workingDir, commandName, commandArguments)
 	log.Debug()
-	if err = command.Start(); err != nil {
+	err = command.Start()
+	releaseRunAs(command)
+	if err != nil {
 		log.Error("error occurred starting the command", err)
 		exitCode = 1
 		return
 	}
 
+	tree, treeErr := newProcessTree(command)
+	if treeErr != nil {
+		log.Warnf("unable to track the processes started by the command, only the command itself can be stopped: %v", treeErr)
+	}
+	defer tree.release()
+
 	signal := timeoutSignal{}
 
+	if err = limiter.attach(command, tree); err != nil {
+		// do not let the process run without the limits it was asked to run with
+		log.Error("error occurred applying the resource limits", err)
+		tree.kill(&signal)
+		command.Wait()
+		exitCode = 1
+		return
+	}
+	limitExceeded := make(chan string, 1)
+	stopWatching := make(chan bool)
+	defer close(stopWatching)
+	go watchResourceLimits(limiter, limitExceeded, stopWatching)
+
 	cancelled := make(chan bool, 1)
 	go func() {
 		cancelState := cancelFlag.Wait()
@@ -315,7 +431,7 @@ func ExecuteCommand(log log.T,
 	case <-time.After(time.Duration(executionTimeout) * time.Second):
 		stopStdout <- true
 		stopStderr <- true
-		if err = killProcess(command.Process, &signal); err != nil {
+		if err = killProcessTree(log, tree, &signal, options); err != nil {
 			exitCode = 1
 			log.Error(err)
 		} else {
@@ -329,7 +445,7 @@ func ExecuteCommand(log log.T,
 		log.Debug("Process cancelled. Attempting to stop process.")
 		stopStdout <- true
 		stopStderr <- true
-		if err = killProcess(command.Process, &signal); err != nil {
+		if err = killProcessTree(log, tree, &signal, options); err != nil {
 			exitCode = 1
 			log.Error(err)
 		} else {
@@ -338,6 +454,17 @@ func ExecuteCommand(log log.T,
 			err = &exec.ExitError{Stderr: []byte("Cancelled process")}
 			log.Infof("The execution of command was cancelled.")
 		}
+	case limit := <-limitExceeded:
+		// the process exceeded one of its limits, kill what is left of it
+		log.Infof("The command exceeded its %v. Attempting to stop process.", limit)
+		stopStdout <- true
+		stopStderr <- true
+		if err = killProcessTree(log, tree, &signal, options); err != nil {
+			log.Error(err)
+		} else {
+			err = fmt.Errorf("process exceeded its %v and was stopped", limit)
+		}
+		exitCode = 1
 	case err = <-done:
 		log.Debug("Process completed.")
 		if err != nil {
@@ -375,6 +502,12 @@ func ExecuteCommand(log log.T,
 				// do not return as the command could have been cancelled and also timedout
 			}
 		}
+		if limit := limiter.exceeded(); limit != "" {
+			// the processes killed for exceeding the limit may not be the command itself
+			log.Infof("The command exceeded its %v.", limit)
+			err = fmt.Errorf("process exceeded its %v and was stopped", limit)
+			exitCode = 1
+		}
 	}
 	return
 }
@@ -412,21 +545,26 @@ func StartCommand(log log.T,
 	}
 
 	process = command.Process
+	tree, treeErr := newProcessTree(command)
+	if treeErr != nil {
+		log.Warnf("unable to track the processes started by the command, only the command itself can be stopped: %v", treeErr)
+	}
 	signal := timeoutSignal{}
 	// Async commands don't use cancellable writers because we rely on the process having an independent copy of
 	// the writer when it is a file handle and when the cancellable writer is assigned, it doesn't (by design) give
 	// a reference to the file handle to the process
 	cancelChannel := make(chan bool, 2)
-	go killProcessOnCancel(log, command, cancelChannel, cancelChannel, cancelFlag, &signal)
+	go killProcessOnCancel(log, tree, cancelChannel, cancelChannel, cancelFlag, &signal)
 
 	return
 }
 
 // killProcessOnCancel waits for a cancel request.
 // If a cancel request is received, this method kills the underlying
-// process of the command. This will unblock the command.Wait() call.
+// process tree of the command. This will unblock the command.Wait() call.
 // If the task completed successfully this method returns with no action.
-func killProcessOnCancel(log log.T, command *exec.Cmd, cancelStdout chan bool, cancelStderr chan bool, cancelFlag task.CancelFlag, signal *timeoutSignal) {
+func killProcessOnCancel(log log.T, tree *processTree, cancelStdout chan bool, cancelStderr chan bool, cancelFlag task.CancelFlag, signal *timeoutSignal) {
+	defer tree.release()
 	cancelFlag.Wait()
 	if cancelFlag.Canceled() {
 		log.Debug("Process cancelled. Attempting to stop process.")
@@ -436,7 +574,7 @@ func killProcessOnCancel(log log.T, command *exec.Cmd, cancelStdout chan bool, c
 		runtime.Gosched()
 
 		// task has been asked to cancel, kill process
-		if err := killProcess(command.Process, signal); err != nil {
+		if err := killProcessTree(log, tree, signal, ExecuteOptions{}); err != nil {
 			log.Error(err)
 		} else {
 			log.Debug("Process stopped successfully.")
@@ -445,6 +583,19 @@ func killProcessOnCancel(log log.T, command *exec.Cmd, cancelStdout chan bool, c
 	}
 }
 
+// killProcessTree kills the process of the command along with all its descendants and reports the number of processes killed.
+func killProcessTree(log log.T, tree *processTree, signal *timeoutSignal, options ExecuteOptions) error {
+	killed, err := tree.kill(signal)
+	if err != nil {
+		return err
+	}
+	log.Infof("Killed %v process(es) started by the command.", killed)
+	if options.ProcessTreeKilled != nil {
+		options.ProcessTreeKilled(killed)
+	}
+	return nil
+}
+
 // prepareEnvironment adds ssm agent standard environment variables to the command
 func prepareEnvironment(command *exec.Cmd) {
 	env := os.Environ()
@@ -460,6 +611,25 @@ func prepareEnvironment(command *exec.Cmd) {
 	validateEnvironmentVariables(command)
 }
 
+// injectEnvironment adds the given variables to the environment of the command, replacing inherited values.
+func injectEnvironment(command *exec.Cmd, environment map[string]string) {
+	for name, value := range environment {
+		command.Env = setEnvVariable(command.Env, name, value)
+	}
+}
+
+// setEnvVariable sets the environment variable to the given value, replacing any previous value.
+func setEnvVariable(env []string, name string, val string) []string {
+	prefix := name + "="
+	result := make([]string, 0, len(env)+1)
+	for _, e := range env {
+		if !strings.HasPrefix(e, prefix) {
+			result = append(result, e)
+		}
+	}
+	return append(result, fmtEnvVariable(name, val))
+}
+
 // fmtEnvVariable creates the string to append to the current set of environment variables.
 func fmtEnvVariable(name string, val string) string {
 	return fmt.Sprintf("%s=%s", name, val)
@@ -90,6 +90,18 @@ func TestEnvironmentVariables_None(t *testing.T) {
 	assert.Empty(t, getEnvVariableValue(command.Env, envVarRegionName))
 }
 
+func TestInjectEnvironment(t *testing.T) {
+	command := getTestCommand(t)
+	command.Env = []string{"FOO=inherited", "BAR=kept"}
+
+	injectEnvironment(command, map[string]string{"FOO": "injected", "NEW": "value"})
+
+	assert.Equal(t, "injected", getEnvVariableValue(command.Env, "FOO"))
+	assert.Equal(t, "kept", getEnvVariableValue(command.Env, "BAR"))
+	assert.Equal(t, "value", getEnvVariableValue(command.Env, "NEW"))
+	assert.Equal(t, 3, len(command.Env))
+}
+
 func TestQuoteShString(t *testing.T) {
 	var result string
 
@@ -132,3 +144,11 @@ func TestQuotePsStringWithQuotes(t *testing.T) {
 	result = QuotePsString("`abc`")
 	assert.Equal(t, "\"``abc``\"", result)
 }
+
+// TestIsManagedServiceAccount tests the detection of (group) managed service accounts.
+func TestIsManagedServiceAccount(t *testing.T) {
+	assert.True(t, IsManagedServiceAccount(`CORP\svc-deploy$`))
+	assert.True(t, IsManagedServiceAccount("svc-deploy$"))
+	assert.False(t, IsManagedServiceAccount(`CORP\deployer`))
+	assert.False(t, IsManagedServiceAccount(""))
+}
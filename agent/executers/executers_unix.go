@@ -16,37 +16,31 @@
 package executers
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/user"
 )
 
+// defaultRunAsPath is the PATH given to processes that run as another user
+const defaultRunAsPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
 func prepareProcess(command *exec.Cmd) {
 	// make the process the leader of its process group
 	// (otherwise we cannot kill it properly)
 	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
-func killProcess(process *os.Process, signal *timeoutSignal) error {
-	//   NOTE: go only kills the process but not its sub processes.
-	//   The consequence is that command.Wait() does not return, for some reason.
-	//   As a workaround we use some (platform specific) magic:
-	//     syscall.Kill(-pid, syscall.SIGKILL)
-	//   Here '-pid' means that the KILL signal is sent to all processes
-	//   in the process group whose id is 'pid'. 'prepareProcess' makes
-	//   the shell we spawn the leader of its own process group and so
-	//   the kill here not just kills the shell but all its descendant
-	//   processes. [See manpage for kill(2)]
-	return syscall.Kill(-process.Pid, syscall.SIGKILL) // note the minus sign
-}
-
 // Running powershell on linux erquired the HOME env variable to be set and to remove the TERM env variable
 func validateEnvironmentVariables(command *exec.Cmd) {
 
-	if command.Path == appconfig.PowerShellPluginCommandName {
+	if name := filepath.Base(command.Path); command.Path == appconfig.PowerShellPluginCommandName || name == "pwsh" || name == "powershell" {
 		env := command.Env
 		env = append(env, fmtEnvVariable("HOME", "/"))
 		i := 0
@@ -64,3 +58,71 @@ func validateEnvironmentVariables(command *exec.Cmd) {
 		command.Env = env
 	}
 }
+
+// prepareRunAs sets the credentials and the login environment of the process when it should run as another user
+func prepareRunAs(command *exec.Cmd, options ExecuteOptions) error {
+	if options.RunAsUser == "" {
+		return nil
+	}
+	if options.RunAsPassword != "" {
+		return fmt.Errorf("a password for runAsUser %v is only supported on windows", options.RunAsUser)
+	}
+
+	runAsUser, err := user.Lookup(options.RunAsUser)
+	if err != nil {
+		return fmt.Errorf("runAsUser %v does not exist on the instance: %v", options.RunAsUser, err)
+	}
+	uid, err := strconv.ParseUint(runAsUser.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid uid %v for user %v", runAsUser.Uid, options.RunAsUser)
+	}
+
+	gidStr := runAsUser.Gid
+	if options.RunAsGroup != "" {
+		runAsGroup, err := user.LookupGroup(options.RunAsGroup)
+		if err != nil {
+			return fmt.Errorf("runAsGroup %v does not exist on the instance: %v", options.RunAsGroup, err)
+		}
+		gidStr = runAsGroup.Gid
+	}
+	gid, err := strconv.ParseUint(gidStr, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid gid %v for user %v", gidStr, options.RunAsUser)
+	}
+
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+
+	env := command.Env
+	env = setEnvVariable(env, "HOME", runAsUser.HomeDir)
+	env = setEnvVariable(env, "USER", runAsUser.Username)
+	env = setEnvVariable(env, "LOGNAME", runAsUser.Username)
+	env = setEnvVariable(env, "PATH", defaultRunAsPath)
+	command.Env = env
+	return nil
+}
+
+// GrantRunAsAccess makes the given path and its content owned by the user the process runs as
+func GrantRunAsAccess(path string, options ExecuteOptions) error {
+	if options.RunAsUser == "" {
+		return nil
+	}
+	runAsUser, err := user.Lookup(options.RunAsUser)
+	if err != nil {
+		return fmt.Errorf("runAsUser %v does not exist on the instance: %v", options.RunAsUser, err)
+	}
+	uid, _ := strconv.Atoi(runAsUser.Uid)
+	gid, _ := strconv.Atoi(runAsUser.Gid)
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chown(p, uid, gid)
+	})
+}
+
+// releaseRunAs releases resources held for running the process as another user, nothing to do on unix
+func releaseRunAs(command *exec.Cmd) {
+}
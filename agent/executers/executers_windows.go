@@ -16,25 +16,289 @@
 package executers
 
 import (
-	"os"
+	"fmt"
 	"os/exec"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/user"
 )
 
 const (
 	CWConfigIndex = 2
+
+	msv1_0PackageName = "MICROSOFT_AUTHENTICATION_PACKAGE_V1_0"
+	msv1_0S4ULogon    = 12
+	logonTypeNetwork  = 3
+
+	logon32LogonBatch      = 4
+	logon32LogonService    = 5
+	logon32ProviderDefault = 0
 )
 
-func prepareProcess(command *exec.Cmd) {
-	// nothing to do on windows
+// Windows APIs
+var (
+	secur32                        = syscall.NewLazyDLL("secur32.dll")
+	lsaConnectUntrusted            = secur32.NewProc("LsaConnectUntrusted")
+	lsaDeregisterLogonProcess      = secur32.NewProc("LsaDeregisterLogonProcess")
+	lsaLookupAuthenticationPackage = secur32.NewProc("LsaLookupAuthenticationPackage")
+	lsaLogonUser                   = secur32.NewProc("LsaLogonUser")
+	lsaFreeReturnBuffer            = secur32.NewProc("LsaFreeReturnBuffer")
+
+	advapi32   = syscall.NewLazyDLL("advapi32.dll")
+	logonUserW = advapi32.NewProc("LogonUserW")
+)
+
+type lsaString struct {
+	Length        uint16
+	MaximumLength uint16
+	Buffer        *byte
+}
+
+type unicodeString struct {
+	Length        uint16
+	MaximumLength uint16
+	Buffer        uintptr
 }
 
-func killProcess(process *os.Process, signal *timeoutSignal) error {
-	// process kill doesn't send proper signal to the process status
-	// Setting the signal to indicate execution was interrupted
-	signal.execInterruptedOnWindows = true
-	return process.Kill()
+type msv1_0S4ULogonInfo struct {
+	MessageType       uint32
+	Flags             uint32
+	UserPrincipalName unicodeString
+	DomainName        unicodeString
+}
+
+type luid struct {
+	LowPart  uint32
+	HighPart int32
+}
+
+type tokenSource struct {
+	SourceName       [8]byte
+	SourceIdentifier luid
+}
+
+type quotaLimits struct {
+	PagedPoolLimit        uintptr
+	NonPagedPoolLimit     uintptr
+	MinimumWorkingSetSize uintptr
+	MaximumWorkingSetSize uintptr
+	PagefileLimit         uintptr
+	TimeLimit             int64
+}
+
+func prepareProcess(command *exec.Cmd) {
+	// nothing to do on windows
 }
 
 // Running powershell on linux required the HOME env variable to be set and to remove the TERM env variable
 func validateEnvironmentVariables(command *exec.Cmd) {
 }
+
+// prepareRunAs sets the logon token of the process when it should run as another local or domain account.
+// RunAsGroup is not applicable on windows, the process gets the groups of the account.
+func prepareRunAs(command *exec.Cmd, options ExecuteOptions) error {
+	if options.RunAsUser == "" {
+		return nil
+	}
+
+	runAsUser, err := user.Lookup(options.RunAsUser)
+	if err != nil {
+		return fmt.Errorf("runAsUser %v does not exist on the instance: %v", options.RunAsUser, err)
+	}
+
+	token, err := logonRunAsUser(options)
+	if err != nil {
+		return fmt.Errorf("failed to logon as %v: %v", options.RunAsUser, err)
+	}
+
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Token = token
+
+	env := command.Env
+	env = setEnvVariable(env, "USERNAME", runAsUser.Username)
+	env = setEnvVariable(env, "USERPROFILE", runAsUser.HomeDir)
+	command.Env = env
+	return nil
+}
+
+// GrantRunAsAccess grants the account the process runs as modify access to the given directory
+func GrantRunAsAccess(path string, options ExecuteOptions) error {
+	if options.RunAsUser == "" {
+		return nil
+	}
+	grant := fmt.Sprintf("%v:(OI)(CI)M", options.RunAsUser)
+	if output, err := exec.Command("icacls", path, "/grant", grant, "/T").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to grant %v access to %v: %v %v", options.RunAsUser, path, err, string(output))
+	}
+	return nil
+}
+
+// releaseRunAs closes the logon token once the process has been started.
+func releaseRunAs(command *exec.Cmd) {
+	if command.SysProcAttr != nil && command.SysProcAttr.Token != 0 {
+		command.SysProcAttr.Token.Close()
+	}
+}
+
+// logonRunAsUser creates the logon token of the account the process runs as.
+// Group managed service accounts logon as a service, windows retrieves their password from the domain.
+// Accounts with a password logon as a batch job, the other accounts through an S4U logon which does not
+// require the password but gives a token without access to network resources.
+func logonRunAsUser(options ExecuteOptions) (syscall.Token, error) {
+	switch {
+	case IsManagedServiceAccount(options.RunAsUser):
+		return logonUser(options.RunAsUser, "", logon32LogonService)
+	case options.RunAsPassword != "":
+		return logonUser(options.RunAsUser, options.RunAsPassword, logon32LogonBatch)
+	default:
+		return s4uLogon(options.RunAsUser)
+	}
+}
+
+// logonUser creates a logon token for the given account through LogonUser.
+// Accounts without a domain are looked up in the local account database unless given in the UPN format.
+func logonUser(username string, password string, logonType uint32) (token syscall.Token, err error) {
+	domain, name := splitAccountName(username)
+	if domain == "" && !strings.Contains(name, "@") {
+		domain = "."
+	}
+
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return
+	}
+	var domainPtr *uint16
+	if domain != "" {
+		if domainPtr, err = syscall.UTF16PtrFromString(domain); err != nil {
+			return
+		}
+	}
+	passwordPtr, err := syscall.UTF16PtrFromString(password)
+	if err != nil {
+		return
+	}
+
+	if ret, _, callErr := logonUserW.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		uintptr(unsafe.Pointer(domainPtr)),
+		uintptr(unsafe.Pointer(passwordPtr)),
+		uintptr(logonType),
+		logon32ProviderDefault,
+		uintptr(unsafe.Pointer(&token))); ret == 0 {
+		return 0, fmt.Errorf("LogonUser failed: %v", callErr)
+	}
+	return
+}
+
+// s4uLogon creates a logon token for the given account through the MSV1_0 authentication package.
+func s4uLogon(username string) (token syscall.Token, err error) {
+	var lsaHandle syscall.Handle
+	if status, _, _ := lsaConnectUntrusted.Call(uintptr(unsafe.Pointer(&lsaHandle))); status != 0 {
+		return 0, fmt.Errorf("LsaConnectUntrusted failed with status 0x%x", status)
+	}
+	defer lsaDeregisterLogonProcess.Call(uintptr(lsaHandle))
+
+	var authPackage uint32
+	packageName := newLsaString(msv1_0PackageName)
+	if status, _, _ := lsaLookupAuthenticationPackage.Call(
+		uintptr(lsaHandle),
+		uintptr(unsafe.Pointer(&packageName)),
+		uintptr(unsafe.Pointer(&authPackage))); status != 0 {
+		return 0, fmt.Errorf("LsaLookupAuthenticationPackage failed with status 0x%x", status)
+	}
+
+	domain, name := splitAccountName(username)
+	if domain == "" || domain == "." {
+		if domain, err = syscall.ComputerName(); err != nil {
+			return
+		}
+	}
+	authInfo := newS4ULogonInfo(name, domain)
+
+	origin := newLsaString(appconfig.DefaultAgentName)
+	source := tokenSource{}
+	copy(source.SourceName[:], "ssmagent")
+
+	var profileBuffer uintptr
+	var profileBufferLength uint32
+	var logonID luid
+	var quotas quotaLimits
+	var subStatus int32
+	status, _, _ := lsaLogonUser.Call(
+		uintptr(lsaHandle),
+		uintptr(unsafe.Pointer(&origin)),
+		logonTypeNetwork,
+		uintptr(authPackage),
+		uintptr(unsafe.Pointer(&authInfo[0])),
+		uintptr(len(authInfo)),
+		0,
+		uintptr(unsafe.Pointer(&source)),
+		uintptr(unsafe.Pointer(&profileBuffer)),
+		uintptr(unsafe.Pointer(&profileBufferLength)),
+		uintptr(unsafe.Pointer(&logonID)),
+		uintptr(unsafe.Pointer(&token)),
+		uintptr(unsafe.Pointer(&quotas)),
+		uintptr(unsafe.Pointer(&subStatus)))
+	if profileBuffer != 0 {
+		lsaFreeReturnBuffer.Call(profileBuffer)
+	}
+	if status != 0 {
+		return 0, fmt.Errorf("LsaLogonUser failed with status 0x%x, sub status 0x%x", status, subStatus)
+	}
+	return
+}
+
+// splitAccountName splits an account name in the DOMAIN\user format
+func splitAccountName(account string) (domain string, name string) {
+	if i := strings.Index(account, "\\"); i >= 0 {
+		return account[:i], account[i+1:]
+	}
+	return "", account
+}
+
+// newS4ULogonInfo lays out a MSV1_0_S4U_LOGON structure followed by the strings it points to in a single buffer
+func newS4ULogonInfo(name string, domain string) []byte {
+	name16 := utf16.Encode([]rune(name))
+	domain16 := utf16.Encode([]rune(domain))
+	headerSize := int(unsafe.Sizeof(msv1_0S4ULogonInfo{}))
+	buf := make([]byte, headerSize+2*(len(name16)+len(domain16)))
+
+	offset := headerSize
+	for _, c := range append(name16, domain16...) {
+		buf[offset] = byte(c)
+		buf[offset+1] = byte(c >> 8)
+		offset += 2
+	}
+
+	info := (*msv1_0S4ULogonInfo)(unsafe.Pointer(&buf[0]))
+	info.MessageType = msv1_0S4ULogon
+	info.UserPrincipalName = unicodeString{
+		Length:        uint16(2 * len(name16)),
+		MaximumLength: uint16(2 * len(name16)),
+		Buffer:        uintptr(unsafe.Pointer(&buf[headerSize])),
+	}
+	info.DomainName = unicodeString{
+		Length:        uint16(2 * len(domain16)),
+		MaximumLength: uint16(2 * len(domain16)),
+	}
+	if len(domain16) > 0 {
+		info.DomainName.Buffer = uintptr(unsafe.Pointer(&buf[headerSize+2*len(name16)]))
+	}
+	return buf
+}
+
+// newLsaString creates an ANSI LSA_STRING for the given value
+func newLsaString(value string) lsaString {
+	b := append([]byte(value), 0)
+	return lsaString{
+		Length:        uint16(len(value)),
+		MaximumLength: uint16(len(b)),
+		Buffer:        &b[0],
+	}
+}
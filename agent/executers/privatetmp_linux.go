@@ -0,0 +1,51 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// privateTmpScript mounts empty temporary directories in the new mount namespace before running the command
+const privateTmpScript = `mount -t tmpfs -o mode=1777,nosuid,nodev tmpfs /tmp && mount -t tmpfs -o mode=1777,nosuid,nodev tmpfs /var/tmp && exec "$@"`
+
+var lookPath = exec.LookPath
+
+// preparePrivateTmp wraps the command so that it runs in its own mount namespace with private /tmp and /var/tmp,
+// files created there are not visible to other processes and are discarded when the command exits.
+func preparePrivateTmp(log log.T, command *exec.Cmd, options ExecuteOptions) error {
+	if !options.PrivateTmp {
+		return nil
+	}
+	if options.RunAsUser != "" {
+		// mounting requires the privileges of the agent, which are dropped before the command starts
+		log.Warnf("private temporary directories are not supported for runAsUser %v, using the shared ones", options.RunAsUser)
+		return nil
+	}
+
+	unshare, err := lookPath("unshare")
+	if err != nil {
+		return fmt.Errorf("unshare is required to run commands with private temporary directories: %v", err)
+	}
+
+	args := []string{unshare, "--mount", "--propagation", "private", "/bin/sh", "-c", privateTmpScript, "sh", command.Path}
+	command.Args = append(args, command.Args[1:]...)
+	command.Path = unshare
+	return nil
+}
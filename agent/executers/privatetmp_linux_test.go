@@ -0,0 +1,43 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreparePrivateTmp(t *testing.T) {
+	lookPathTemp := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = lookPathTemp }()
+
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}}
+	assert.NoError(t, preparePrivateTmp(log.NewMockLog(), command, ExecuteOptions{PrivateTmp: true}))
+
+	assert.Equal(t, "/usr/bin/unshare", command.Path)
+	assert.Equal(t, []string{"/usr/bin/unshare", "--mount", "--propagation", "private", "/bin/sh", "-c", privateTmpScript, "sh", "/bin/sh", "script.sh"}, command.Args)
+}
+
+func TestPreparePrivateTmpSkippedForRunAs(t *testing.T) {
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}}
+	assert.NoError(t, preparePrivateTmp(log.NewMockLog(), command, ExecuteOptions{PrivateTmp: true, RunAsUser: "user"}))
+
+	assert.Equal(t, "/bin/sh", command.Path)
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd netbsd openbsd
+
+package executers
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// listProcesses returns the processes running on the instance as reported by ps
+func listProcesses() (processes []processInfo, err error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=", "-o", "pgid=").Output()
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		var process processInfo
+		if process.pid, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, err
+		}
+		if process.ppid, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, err
+		}
+		if process.pgid, err = strconv.Atoi(fields[2]); err != nil {
+			return nil, err
+		}
+		processes = append(processes, process)
+	}
+	return
+}
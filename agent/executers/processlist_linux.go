@@ -0,0 +1,69 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procDir is the mount point of the proc filesystem
+var procDir = "/proc"
+
+// listProcesses returns the processes running on the instance from the proc filesystem
+func listProcesses() (processes []processInfo, err error) {
+	entries, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		// processes may exit while being listed
+		stat, err := ioutil.ReadFile(filepath.Join(procDir, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		if process, err := parseProcStat(pid, string(stat)); err == nil {
+			processes = append(processes, process)
+		}
+	}
+	return
+}
+
+// parseProcStat parses the content of /proc/<pid>/stat, formatted as "pid (comm) state ppid pgrp ..."
+func parseProcStat(pid int, stat string) (process processInfo, err error) {
+	// the command name may contain spaces and parentheses, the fields of interest follow its last closing parenthesis
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return process, fmt.Errorf("invalid stat of process %v", pid)
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 3 {
+		return process, fmt.Errorf("invalid stat of process %v", pid)
+	}
+	process.pid = pid
+	if process.ppid, err = strconv.Atoi(fields[1]); err != nil {
+		return
+	}
+	process.pgid, err = strconv.Atoi(fields[2])
+	return
+}
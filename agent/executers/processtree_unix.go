@@ -0,0 +1,91 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package executers
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// processInfo identifies a running process and its position in the process tree
+type processInfo struct {
+	pid  int
+	ppid int
+	pgid int
+}
+
+// processTree tracks a started command so that it can be killed along with all its descendants
+type processTree struct {
+	process *os.Process
+}
+
+// newProcessTree starts tracking the processes of a started command.
+// prepareProcess made the command the leader of its own process group which all its descendants join by default.
+func newProcessTree(command *exec.Cmd) (*processTree, error) {
+	return &processTree{process: command.Process}, nil
+}
+
+// kill kills the command and all its descendants, including the ones which started a new process group or session,
+// and returns the number of processes killed.
+// Killing only the command is not enough, command.Wait() does not return while its descendants hold the output open.
+func (t *processTree) kill(signal *timeoutSignal) (killed int, err error) {
+	// descendants are looked up before killing anything since orphaned processes are adopted by init
+	members := t.members()
+
+	// '-pid' sends the signal to all processes in the process group whose id is 'pid'. [See manpage for kill(2)]
+	if err = syscall.Kill(-t.process.Pid, syscall.SIGKILL); err != nil {
+		return
+	}
+	for _, pid := range members {
+		syscall.Kill(pid, syscall.SIGKILL)
+	}
+	return len(members), nil
+}
+
+// members returns the processes in the process group of the command and all their descendants
+func (t *processTree) members() (members []int) {
+	processes, err := listProcesses()
+	if err != nil {
+		return []int{t.process.Pid}
+	}
+
+	children := make(map[int][]int)
+	pending := []int{t.process.Pid}
+	for _, process := range processes {
+		children[process.ppid] = append(children[process.ppid], process.pid)
+		if process.pgid == t.process.Pid && process.pid != t.process.Pid {
+			pending = append(pending, process.pid)
+		}
+	}
+
+	visited := make(map[int]bool)
+	for len(pending) > 0 {
+		pid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if visited[pid] {
+			continue
+		}
+		visited[pid] = true
+		members = append(members, pid)
+		pending = append(pending, children[pid]...)
+	}
+	return
+}
+
+// release releases the resources used to track the processes, nothing to do on unix
+func (t *processTree) release() {
+}
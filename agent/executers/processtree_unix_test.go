@@ -0,0 +1,53 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package executers
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessTreeKill(t *testing.T) {
+	// job control starts the second background job in its own process group
+	command := exec.Command("sh", "-c", "sleep 60 & set -m; sleep 60 & wait")
+	prepareProcess(command)
+	assert.NoError(t, command.Start())
+
+	tree, err := newProcessTree(command)
+	assert.NoError(t, err)
+	defer tree.release()
+
+	// wait for the shell to start both children
+	for i := 0; i < 50 && len(tree.members()) < 3; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Len(t, tree.members(), 3)
+
+	killed, err := tree.kill(&timeoutSignal{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, killed)
+
+	done := make(chan error, 1)
+	go func() { done <- command.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "command did not exit after the process tree was killed")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package executers
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	jobObjectBasicAccountingInformation = 1
+	processSetQuotaAccess               = 0x100
+	processTerminateAccess              = 0x1
+	// terminatedExitCode is the exit code of the processes killed with the job, the same os.Process.Kill uses
+	terminatedExitCode = 1
+)
+
+// Windows APIs
+var (
+	kernel32                  = syscall.NewLazyDLL("kernel32.dll")
+	createJobObjectW          = kernel32.NewProc("CreateJobObjectW")
+	assignProcessToJobObject  = kernel32.NewProc("AssignProcessToJobObject")
+	terminateJobObject        = kernel32.NewProc("TerminateJobObject")
+	queryInformationJobObject = kernel32.NewProc("QueryInformationJobObject")
+)
+
+type jobObjectBasicAccounting struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// processTree tracks a started command so that it can be killed along with all its descendants
+type processTree struct {
+	process *os.Process
+	job     syscall.Handle
+}
+
+// newProcessTree assigns the started command to a new job object, the processes it creates are added to the job too.
+// When the job cannot be created only the command itself can be killed.
+func newProcessTree(command *exec.Cmd) (tree *processTree, err error) {
+	tree = &processTree{process: command.Process}
+
+	r1, _, e1 := createJobObjectW.Call(0, 0)
+	if r1 == 0 {
+		return tree, e1
+	}
+	job := syscall.Handle(r1)
+
+	process, err := syscall.OpenProcess(processSetQuotaAccess|processTerminateAccess, false, uint32(command.Process.Pid))
+	if err != nil {
+		syscall.CloseHandle(job)
+		return
+	}
+	defer syscall.CloseHandle(process)
+	if r1, _, e1 := assignProcessToJobObject.Call(uintptr(job), uintptr(process)); r1 == 0 {
+		syscall.CloseHandle(job)
+		return tree, e1
+	}
+	tree.job = job
+	return
+}
+
+// kill terminates all the processes of the job and returns the number of processes killed.
+func (t *processTree) kill(signal *timeoutSignal) (killed int, err error) {
+	// process kill doesn't send proper signal to the process status
+	// Setting the signal to indicate execution was interrupted
+	signal.execInterruptedOnWindows = true
+	if t.job == 0 {
+		if err = t.process.Kill(); err != nil {
+			return
+		}
+		return 1, nil
+	}
+
+	var accounting jobObjectBasicAccounting
+	if r1, _, _ := queryInformationJobObject.Call(
+		uintptr(t.job),
+		jobObjectBasicAccountingInformation,
+		uintptr(unsafe.Pointer(&accounting)),
+		unsafe.Sizeof(accounting),
+		0); r1 != 0 {
+		killed = int(accounting.ActiveProcesses)
+	}
+	if r1, _, e1 := terminateJobObject.Call(uintptr(t.job), terminatedExitCode); r1 == 0 {
+		return 0, e1
+	}
+	return
+}
+
+// release closes the job object, processes still running in the job keep running.
+func (t *processTree) release() {
+	if t.job != 0 {
+		syscall.CloseHandle(t.job)
+		t.job = 0
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package executers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// limitPollInterval is the interval at which the processes are checked against their resource limits
+var limitPollInterval = time.Second
+
+// ResourceLimits caps the resources of the process started by the executer and of all its descendants,
+// 0 leaves the resource unlimited.
+type ResourceLimits struct {
+	// CpuShares is the relative CPU weight of the processes, 1024 being the weight of the other processes
+	CpuShares int
+	// MemoryMB caps the memory used by the processes
+	MemoryMB int
+	// MaxProcesses caps the number of processes running at the same time
+	MaxProcesses int
+}
+
+// IsEmpty returns true when no resource is limited.
+func (l ResourceLimits) IsEmpty() bool {
+	return l.CpuShares <= 0 && l.MemoryMB <= 0 && l.MaxProcesses <= 0
+}
+
+// String describes the limits for the logs.
+func (l ResourceLimits) String() string {
+	var limits []string
+	if l.CpuShares > 0 {
+		limits = append(limits, fmt.Sprintf("cpu shares %v", l.CpuShares))
+	}
+	if l.MemoryMB > 0 {
+		limits = append(limits, fmt.Sprintf("memory %v MB", l.MemoryMB))
+	}
+	if l.MaxProcesses > 0 {
+		limits = append(limits, fmt.Sprintf("processes %v", l.MaxProcesses))
+	}
+	return strings.Join(limits, ", ")
+}
+
+// memoryLimitExceeded and processLimitExceeded describe the limit a process exceeded
+func memoryLimitExceeded(limits ResourceLimits) string {
+	return fmt.Sprintf("memory limit of %v MB", limits.MemoryMB)
+}
+
+func processLimitExceeded(limits ResourceLimits) string {
+	return fmt.Sprintf("limit of %v processes", limits.MaxProcesses)
+}
+
+// watchResourceLimits checks the processes against their limits until one is exceeded, which is sent on exceeded,
+// or stop is closed.
+func watchResourceLimits(limiter *resourceLimiter, exceeded chan<- string, stop <-chan bool) {
+	if limiter == nil {
+		return
+	}
+	ticker := time.NewTicker(limitPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if limit := limiter.exceeded(); limit != "" {
+				exceeded <- limit
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// cgroupParent is the control group holding the control groups of the limited processes
+	cgroupParent = "amazon-ssm-agent"
+	// defaultCpuWeight is the cpu weight of the processes without limits in cgroup v2
+	defaultCpuWeight = 100
+	// defaultCpuShares is the cpu shares of the processes without limits in cgroup v1
+	defaultCpuShares = 1024
+	// cgroupScript moves the shell into the control groups given before -- and runs the command after it
+	cgroupScript = `while [ "$1" != -- ]; do echo $$ > "$1" || exit 1; shift; done; shift; exec "$@"`
+)
+
+// cgroupRoot is where the control group file systems are mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+var cgroupCount uint64
+
+// activeCgroups holds the control groups of the commands running, which are not removed with the ones left behind
+var activeCgroups = struct {
+	sync.Mutex
+	dirs map[string]bool
+}{dirs: map[string]bool{}}
+
+// resourceLimiter holds the control groups limiting the resources of a command
+type resourceLimiter struct {
+	limits ResourceLimits
+	// unified is true on cgroup v2, where a single control group holds all the limits
+	unified bool
+	// memory, pids and cpu are the control groups of each controller, the same directory on cgroup v2
+	memory string
+	pids   string
+	cpu    string
+	// placed is true when the command moves itself into its control groups before it runs
+	placed bool
+}
+
+// prepareResourceLimits creates the control groups of the command with its limits. The command moves itself into them
+// before it runs, except when it runs as another user, which cannot write to the control groups, it is then moved
+// once started.
+func prepareResourceLimits(log log.T, command *exec.Cmd, options ExecuteOptions) (limiter *resourceLimiter, err error) {
+	if options.ResourceLimits.IsEmpty() {
+		return nil, nil
+	}
+	limiter = &resourceLimiter{
+		limits:  options.ResourceLimits,
+		unified: fileutil.Exists(filepath.Join(cgroupRoot, "cgroup.controllers")),
+	}
+	name := fmt.Sprintf("%v-%v", os.Getpid(), atomic.AddUint64(&cgroupCount, 1))
+	if limiter.unified {
+		err = limiter.createUnified(name)
+	} else {
+		err = limiter.createHierarchies(name)
+	}
+	if err != nil {
+		limiter.release(log)
+		return nil, fmt.Errorf("failed to create the control group limiting %v: %v", options.ResourceLimits, err)
+	}
+	log.Debugf("Limiting the resources of the command to %v", options.ResourceLimits)
+
+	if options.RunAsUser != "" {
+		return limiter, nil
+	}
+	args := []string{"/bin/sh", "-c", cgroupScript, "sh"}
+	for _, dir := range limiter.dirs() {
+		args = append(args, filepath.Join(dir, "cgroup.procs"))
+	}
+	args = append(args, "--", command.Path)
+	command.Args = append(args, command.Args[1:]...)
+	command.Path = "/bin/sh"
+	limiter.placed = true
+	return limiter, nil
+}
+
+// createUnified creates the cgroup v2 control group of the command, with the controllers of its limits enabled
+func (l *resourceLimiter) createUnified(name string) error {
+	parent := filepath.Join(cgroupRoot, cgroupParent)
+	if err := makeCgroup(parent); err != nil {
+		return err
+	}
+	removeEmptyCgroups(parent)
+
+	controllers := []string{}
+	if l.limits.CpuShares > 0 {
+		controllers = append(controllers, "+cpu")
+	}
+	if l.limits.MemoryMB > 0 {
+		controllers = append(controllers, "+memory")
+	}
+	if l.limits.MaxProcesses > 0 {
+		controllers = append(controllers, "+pids")
+	}
+	for _, dir := range []string{cgroupRoot, parent} {
+		if err := writeCgroupFile(dir, "cgroup.subtree_control", strings.Join(controllers, " ")); err != nil {
+			return err
+		}
+	}
+
+	dir := filepath.Join(parent, name)
+	if err := makeCommandCgroup(dir); err != nil {
+		return err
+	}
+	l.memory, l.pids, l.cpu = dir, dir, dir
+	if l.limits.MemoryMB > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatInt(int64(l.limits.MemoryMB)<<20, 10)); err != nil {
+			return err
+		}
+		// kill all the processes of the command when one runs out of memory, not supported by older kernels
+		writeCgroupFile(dir, "memory.oom.group", "1")
+		// do not let the processes swap to exceed their memory, the file only exists when swap is accounted
+		writeCgroupFile(dir, "memory.swap.max", "0")
+	}
+	if l.limits.MaxProcesses > 0 {
+		if err := writeCgroupFile(dir, "pids.max", strconv.Itoa(l.limits.MaxProcesses)); err != nil {
+			return err
+		}
+	}
+	if l.limits.CpuShares > 0 {
+		if err := writeCgroupFile(dir, "cpu.weight", strconv.Itoa(cpuWeight(l.limits.CpuShares))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createHierarchies creates the cgroup v1 control groups of the command in the hierarchies of its limits
+func (l *resourceLimiter) createHierarchies(name string) (err error) {
+	if l.limits.MemoryMB > 0 {
+		if l.memory, err = makeHierarchyCgroup("memory", name); err != nil {
+			return err
+		}
+		if err = writeCgroupFile(l.memory, "memory.limit_in_bytes", strconv.FormatInt(int64(l.limits.MemoryMB)<<20, 10)); err != nil {
+			return err
+		}
+	}
+	if l.limits.MaxProcesses > 0 {
+		if l.pids, err = makeHierarchyCgroup("pids", name); err != nil {
+			return err
+		}
+		if err = writeCgroupFile(l.pids, "pids.max", strconv.Itoa(l.limits.MaxProcesses)); err != nil {
+			return err
+		}
+	}
+	if l.limits.CpuShares > 0 {
+		if l.cpu, err = makeHierarchyCgroup("cpu", name); err != nil {
+			return err
+		}
+		if err = writeCgroupFile(l.cpu, "cpu.shares", strconv.Itoa(l.limits.CpuShares)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attach moves the started command into its control groups, unless it moved itself before it ran.
+func (l *resourceLimiter) attach(command *exec.Cmd, tree *processTree) error {
+	if l == nil || l.placed {
+		return nil
+	}
+	for _, dir := range l.dirs() {
+		if err := writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(command.Process.Pid)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exceeded returns the limit the processes of the command exceeded, or an empty string.
+func (l *resourceLimiter) exceeded() string {
+	if l == nil {
+		return ""
+	}
+	if l.limits.MemoryMB > 0 {
+		file := "memory.events"
+		if !l.unified {
+			file = "memory.oom_control"
+		}
+		if readCgroupCounter(l.memory, file, "oom_kill") > 0 {
+			return memoryLimitExceeded(l.limits)
+		}
+	}
+	if l.limits.MaxProcesses > 0 && readCgroupCounter(l.pids, "pids.events", "max") > 0 {
+		return processLimitExceeded(l.limits)
+	}
+	return ""
+}
+
+// release removes the control groups of the command. Those still holding processes which outlived the command are
+// removed by the next commands once the processes exit.
+func (l *resourceLimiter) release(log log.T) {
+	if l == nil {
+		return
+	}
+	activeCgroups.Lock()
+	defer activeCgroups.Unlock()
+	for _, dir := range l.dirs() {
+		delete(activeCgroups.dirs, dir)
+		if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+			log.Debugf("control group %v is still in use: %v", dir, err)
+		}
+	}
+}
+
+// dirs returns the distinct control groups of the command
+func (l *resourceLimiter) dirs() (dirs []string) {
+	for _, dir := range []string{l.memory, l.pids, l.cpu} {
+		if dir != "" && (len(dirs) == 0 || dirs[len(dirs)-1] != dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return
+}
+
+// cpuWeight converts cpu shares to the cpu weight of cgroup v2, keeping the ratio to the default
+func cpuWeight(shares int) int {
+	weight := shares * defaultCpuWeight / defaultCpuShares
+	if weight < 1 {
+		return 1
+	}
+	if weight > 10000 {
+		return 10000
+	}
+	return weight
+}
+
+// makeHierarchyCgroup creates the control group of the command in the cgroup v1 hierarchy of the controller
+func makeHierarchyCgroup(controller string, name string) (string, error) {
+	root := filepath.Join(cgroupRoot, controller)
+	if !fileutil.Exists(root) {
+		return "", fmt.Errorf("the %v controller is not mounted", controller)
+	}
+	parent := filepath.Join(root, cgroupParent)
+	if err := makeCgroup(parent); err != nil {
+		return "", err
+	}
+	removeEmptyCgroups(parent)
+	dir := filepath.Join(parent, name)
+	return dir, makeCommandCgroup(dir)
+}
+
+// makeCgroup creates the control group if it does not exist
+func makeCgroup(dir string) error {
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
+
+// makeCommandCgroup creates the control group of a command, it is active until the command releases it
+func makeCommandCgroup(dir string) error {
+	activeCgroups.Lock()
+	defer activeCgroups.Unlock()
+	if err := makeCgroup(dir); err != nil {
+		return err
+	}
+	activeCgroups.dirs[dir] = true
+	return nil
+}
+
+// removeEmptyCgroups removes the control groups left under parent by the previous commands,
+// the ones still holding processes are kept
+func removeEmptyCgroups(parent string) {
+	files, err := ioutil.ReadDir(parent)
+	if err != nil {
+		return
+	}
+	activeCgroups.Lock()
+	defer activeCgroups.Unlock()
+	for _, file := range files {
+		dir := filepath.Join(parent, file.Name())
+		if file.IsDir() && !activeCgroups.dirs[dir] {
+			os.Remove(dir)
+		}
+	}
+}
+
+// writeCgroupFile writes the value to the control file of the control group
+func writeCgroupFile(dir string, file string, value string) error {
+	return ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+}
+
+// readCgroupCounter returns the value of the key in a flat keyed control file, 0 when it cannot be read
+func readCgroupCounter(dir string, file string, key string) int64 {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			value, _ := strconv.ParseInt(fields[1], 10, 64)
+			return value
+		}
+	}
+	return 0
+}
@@ -0,0 +1,157 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// setCgroupRoot points the control groups to a temporary directory, with the files of a cgroup v2 or v1 mount
+func setCgroupRoot(t *testing.T, unified bool) (root string, restore func()) {
+	root, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	if unified {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory pids"), 0644))
+	} else {
+		for _, controller := range []string{"memory", "pids", "cpu"} {
+			assert.NoError(t, os.Mkdir(filepath.Join(root, controller), 0755))
+		}
+	}
+	cgroupRootTemp := cgroupRoot
+	cgroupRoot = root
+	return root, func() {
+		cgroupRoot = cgroupRootTemp
+		os.RemoveAll(root)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestPrepareResourceLimitsUnified(t *testing.T) {
+	root, restore := setCgroupRoot(t, true)
+	defer restore()
+
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}}
+	limiter, err := prepareResourceLimits(log.NewMockLog(), command, ExecuteOptions{ResourceLimits: ResourceLimits{CpuShares: 512, MemoryMB: 64, MaxProcesses: 10}})
+	assert.NoError(t, err)
+
+	dir := limiter.memory
+	assert.Equal(t, filepath.Join(root, cgroupParent), filepath.Dir(dir))
+	assert.Equal(t, []string{dir}, limiter.dirs())
+	assert.Equal(t, "+cpu +memory +pids", readFile(t, filepath.Join(root, "cgroup.subtree_control")))
+	assert.Equal(t, "+cpu +memory +pids", readFile(t, filepath.Join(root, cgroupParent, "cgroup.subtree_control")))
+	assert.Equal(t, "67108864", readFile(t, filepath.Join(dir, "memory.max")))
+	assert.Equal(t, "1", readFile(t, filepath.Join(dir, "memory.oom.group")))
+	assert.Equal(t, "10", readFile(t, filepath.Join(dir, "pids.max")))
+	assert.Equal(t, "50", readFile(t, filepath.Join(dir, "cpu.weight")))
+
+	// the command moves itself into the control group before it runs
+	assert.True(t, limiter.placed)
+	assert.Equal(t, "/bin/sh", command.Path)
+	assert.Equal(t, []string{"/bin/sh", "-c", cgroupScript, "sh", filepath.Join(dir, "cgroup.procs"), "--", "/bin/sh", "script.sh"}, command.Args)
+
+	assert.Equal(t, "", limiter.exceeded())
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pids.events"), []byte("max 3\n"), 0644))
+	assert.Equal(t, "limit of 10 processes", limiter.exceeded())
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "memory.events"), []byte("low 0\nhigh 0\nmax 12\noom 1\noom_kill 1\n"), 0644))
+	assert.Equal(t, "memory limit of 64 MB", limiter.exceeded())
+}
+
+func TestPrepareResourceLimitsHierarchies(t *testing.T) {
+	root, restore := setCgroupRoot(t, false)
+	defer restore()
+
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}}
+	limiter, err := prepareResourceLimits(log.NewMockLog(), command, ExecuteOptions{RunAsUser: "user", ResourceLimits: ResourceLimits{MemoryMB: 16, MaxProcesses: 5}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(root, "memory", cgroupParent), filepath.Dir(limiter.memory))
+	assert.Equal(t, filepath.Join(root, "pids", cgroupParent), filepath.Dir(limiter.pids))
+	assert.Equal(t, []string{limiter.memory, limiter.pids}, limiter.dirs())
+	assert.Equal(t, "16777216", readFile(t, filepath.Join(limiter.memory, "memory.limit_in_bytes")))
+	assert.Equal(t, "5", readFile(t, filepath.Join(limiter.pids, "pids.max")))
+
+	// the other users cannot write to the control groups, the command is moved once started
+	assert.False(t, limiter.placed)
+	assert.Equal(t, []string{"sh", "script.sh"}, command.Args)
+	command.Process = &os.Process{Pid: 1234}
+	assert.NoError(t, limiter.attach(command, nil))
+	assert.Equal(t, "1234", readFile(t, filepath.Join(limiter.memory, "cgroup.procs")))
+	assert.Equal(t, "1234", readFile(t, filepath.Join(limiter.pids, "cgroup.procs")))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(limiter.memory, "memory.oom_control"), []byte("oom_kill_disable 0\nunder_oom 0\noom_kill 2\n"), 0644))
+	assert.Equal(t, "memory limit of 16 MB", limiter.exceeded())
+}
+
+func TestPrepareResourceLimitsMissingController(t *testing.T) {
+	root, restore := setCgroupRoot(t, false)
+	defer restore()
+	assert.NoError(t, os.RemoveAll(filepath.Join(root, "pids")))
+
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}}
+	_, err := prepareResourceLimits(log.NewMockLog(), command, ExecuteOptions{ResourceLimits: ResourceLimits{MemoryMB: 16, MaxProcesses: 5}})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"sh", "script.sh"}, command.Args)
+}
+
+func TestPrepareResourceLimitsSkipped(t *testing.T) {
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}}
+	limiter, err := prepareResourceLimits(log.NewMockLog(), command, ExecuteOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, limiter)
+	assert.Equal(t, "", limiter.exceeded())
+	assert.NoError(t, limiter.attach(command, nil))
+	limiter.release(log.NewMockLog())
+}
+
+func TestRemoveEmptyCgroupsKeepsActive(t *testing.T) {
+	parent, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(parent)
+
+	active := filepath.Join(parent, "active")
+	assert.NoError(t, makeCommandCgroup(active))
+	assert.NoError(t, os.Mkdir(filepath.Join(parent, "left"), 0755))
+
+	removeEmptyCgroups(parent)
+	assert.True(t, exists(active))
+	assert.False(t, exists(filepath.Join(parent, "left")))
+
+	(&resourceLimiter{memory: active}).release(log.NewMockLog())
+	assert.False(t, exists(active))
+}
+
+func TestCpuWeight(t *testing.T) {
+	assert.Equal(t, 100, cpuWeight(1024))
+	assert.Equal(t, 1, cpuWeight(2))
+	assert.Equal(t, 10000, cpuWeight(262144))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build !linux,!windows
+
+package executers
+
+import (
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// resourceLimiter is not used, resource limits are only supported on Linux and Windows
+type resourceLimiter struct{}
+
+// prepareResourceLimits is a no-op, the processes run without limits.
+func prepareResourceLimits(log log.T, command *exec.Cmd, options ExecuteOptions) (*resourceLimiter, error) {
+	if !options.ResourceLimits.IsEmpty() {
+		log.Debug("resource limits are only supported on Linux and Windows")
+	}
+	return nil, nil
+}
+
+func (l *resourceLimiter) attach(command *exec.Cmd, tree *processTree) error {
+	return nil
+}
+
+func (l *resourceLimiter) exceeded() string {
+	return ""
+}
+
+func (l *resourceLimiter) release(log log.T) {
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package executers
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	jobObjectAssociateCompletionPortInformation = 7
+	jobObjectExtendedLimitInformation           = 9
+	jobObjectCpuRateControlInformation          = 15
+	jobObjectLimitActiveProcess                 = 0x8
+	jobObjectLimitJobMemory                     = 0x200
+	jobObjectCpuRateControlEnable               = 0x1
+	jobObjectCpuRateControlWeightBased          = 0x2
+	jobObjectMsgActiveProcessLimit              = 3
+	jobObjectMsgJobMemoryLimit                  = 10
+	// defaultCpuRateWeight is the cpu weight of the jobs without limits
+	defaultCpuRateWeight = 5
+	// defaultCpuShares is the cpu shares matching the default weight
+	defaultCpuShares   = 1024
+	invalidHandleValue = ^syscall.Handle(0)
+)
+
+var setInformationJobObject = kernel32.NewProc("SetInformationJobObject")
+
+type jobObjectBasicLimit struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+type jobObjectExtendedLimit struct {
+	BasicLimitInformation jobObjectBasicLimit
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+type jobObjectCpuRateControl struct {
+	ControlFlags uint32
+	Weight       uint32
+}
+
+type jobObjectAssociateCompletionPort struct {
+	CompletionKey  uintptr
+	CompletionPort syscall.Handle
+}
+
+// resourceLimiter sets the limits on the job object of a command and receives its notifications
+type resourceLimiter struct {
+	limits ResourceLimits
+	// port receives the notifications of the job, among which the limits exceeded
+	port syscall.Handle
+	// limit is the limit exceeded, once notified
+	limit string
+	lock  sync.Mutex
+}
+
+// prepareResourceLimits returns the limiter of the command, the limits are set on its job object once started.
+func prepareResourceLimits(log log.T, command *exec.Cmd, options ExecuteOptions) (*resourceLimiter, error) {
+	if options.ResourceLimits.IsEmpty() {
+		return nil, nil
+	}
+	log.Debugf("Limiting the resources of the command to %v", options.ResourceLimits)
+	return &resourceLimiter{limits: options.ResourceLimits}, nil
+}
+
+// attach sets the limits on the job object of the started command, the limits exceeded are notified on a completion port.
+func (l *resourceLimiter) attach(command *exec.Cmd, tree *processTree) (err error) {
+	if l == nil {
+		return nil
+	}
+	if tree.job == 0 {
+		return fmt.Errorf("the job object of the command is required to limit %v", l.limits)
+	}
+
+	if l.port, err = syscall.CreateIoCompletionPort(invalidHandleValue, 0, 0, 1); err != nil {
+		return err
+	}
+	port := jobObjectAssociateCompletionPort{CompletionPort: l.port}
+	if err = setJobInformation(tree.job, jobObjectAssociateCompletionPortInformation, unsafe.Pointer(&port), unsafe.Sizeof(port)); err != nil {
+		return err
+	}
+
+	var limit jobObjectExtendedLimit
+	if l.limits.MemoryMB > 0 {
+		limit.BasicLimitInformation.LimitFlags |= jobObjectLimitJobMemory
+		limit.JobMemoryLimit = uintptr(l.limits.MemoryMB) << 20
+	}
+	if l.limits.MaxProcesses > 0 {
+		limit.BasicLimitInformation.LimitFlags |= jobObjectLimitActiveProcess
+		limit.BasicLimitInformation.ActiveProcessLimit = uint32(l.limits.MaxProcesses)
+	}
+	if limit.BasicLimitInformation.LimitFlags != 0 {
+		if err = setJobInformation(tree.job, jobObjectExtendedLimitInformation, unsafe.Pointer(&limit), unsafe.Sizeof(limit)); err != nil {
+			return err
+		}
+	}
+	if l.limits.CpuShares > 0 {
+		rate := jobObjectCpuRateControl{
+			ControlFlags: jobObjectCpuRateControlEnable | jobObjectCpuRateControlWeightBased,
+			Weight:       uint32(cpuRateWeight(l.limits.CpuShares)),
+		}
+		if err = setJobInformation(tree.job, jobObjectCpuRateControlInformation, unsafe.Pointer(&rate), unsafe.Sizeof(rate)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exceeded returns the limit the processes of the job exceeded, from the notifications queued on the completion port.
+func (l *resourceLimiter) exceeded() string {
+	if l == nil {
+		return ""
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for l.limit == "" && l.port != 0 {
+		var message, key uint32
+		var overlapped *syscall.Overlapped
+		if err := syscall.GetQueuedCompletionStatus(l.port, &message, &key, &overlapped, 0); err != nil {
+			// no more notifications
+			break
+		}
+		switch message {
+		case jobObjectMsgJobMemoryLimit:
+			l.limit = memoryLimitExceeded(l.limits)
+		case jobObjectMsgActiveProcessLimit:
+			l.limit = processLimitExceeded(l.limits)
+		}
+	}
+	return l.limit
+}
+
+// release closes the completion port, the limits are released with the job object.
+func (l *resourceLimiter) release(log log.T) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.port != 0 {
+		syscall.CloseHandle(l.port)
+		l.port = 0
+	}
+}
+
+// cpuRateWeight converts cpu shares to the cpu rate weight of a job, from 1 to 9, keeping the ratio to the default
+func cpuRateWeight(shares int) int {
+	weight := shares * defaultCpuRateWeight / defaultCpuShares
+	if weight < 1 {
+		return 1
+	}
+	if weight > 9 {
+		return 9
+	}
+	return weight
+}
+
+// setJobInformation sets a class of information of the job object
+func setJobInformation(job syscall.Handle, class uint32, info unsafe.Pointer, size uintptr) error {
+	if r1, _, e1 := setInformationJobObject.Call(uintptr(job), uintptr(class), uintptr(info), size); r1 == 0 {
+		return e1
+	}
+	return nil
+}
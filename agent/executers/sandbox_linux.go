@@ -0,0 +1,120 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// droppedCapabilities are removed from the restricted processes: no raw sockets, no mounts, no kernel modules
+const droppedCapabilities = "-net_raw,-sys_admin,-sys_module"
+
+// readOnlyDirs returns the agent directories the restricted processes cannot write to
+var readOnlyDirs = func() []string {
+	return []string{appconfig.DefaultDataStorePath, appconfig.DefaultProgramFolder, log.DefaultLogDir}
+}
+
+// prepareSandbox wraps the command so that it runs confined. The restricted sandbox runs it in its own mount
+// namespace where the agent directories are read-only, except its working directory, without the capabilities to
+// open raw sockets, mount file systems or load kernel modules and without gaining privileges. The apparmor sandbox
+// runs it with the AppArmor profile of the scripts.
+func prepareSandbox(log log.T, command *exec.Cmd, options ExecuteOptions) error {
+	switch options.Sandbox {
+	case "":
+		return nil
+	case appconfig.ScriptSandboxAppArmor:
+		return prepareAppArmor(command, options.SandboxProfile)
+	case appconfig.ScriptSandboxRestricted:
+	default:
+		return fmt.Errorf("unknown sandbox %v", options.Sandbox)
+	}
+
+	if options.RunAsUser != "" {
+		// the mounts require the privileges of the agent, which are dropped before the command starts, and
+		// the other users have neither the capabilities nor write access to the agent directories
+		log.Debugf("runAsUser %v runs without the privileges of the agent, the restricted sandbox is not needed", options.RunAsUser)
+		return nil
+	}
+
+	unshare, err := lookPath("unshare")
+	if err != nil {
+		return fmt.Errorf("unshare is required to run commands in the restricted sandbox: %v", err)
+	}
+	setpriv, err := lookPath("setpriv")
+	if err != nil {
+		return fmt.Errorf("setpriv is required to run commands in the restricted sandbox: %v", err)
+	}
+
+	args := []string{unshare, "--mount", "--propagation", "private", "/bin/sh", "-c", restrictedScript(command.Dir), "sh",
+		setpriv, "--no-new-privs", "--bounding-set", droppedCapabilities, "--inh-caps", droppedCapabilities, "--", command.Path}
+	command.Args = append(args, command.Args[1:]...)
+	command.Path = unshare
+	return nil
+}
+
+// restrictedScript returns the script remounting the agent directories read-only in the new mount namespace,
+// the working directory is mounted back read-write, before running the command
+func restrictedScript(workingDir string) string {
+	mounts := []string{}
+	writable := false
+	for _, dir := range readOnlyDirs() {
+		dir = filepath.Clean(dir)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		mounts = append(mounts, fmt.Sprintf("mount --bind %[1]v %[1]v && mount -o remount,bind,ro %[1]v", shellQuote(dir)))
+		if workingDir != "" && isSubPath(dir, workingDir) {
+			writable = true
+		}
+	}
+	if writable {
+		mounts = append(mounts, fmt.Sprintf("mount --bind %[1]v %[1]v && mount -o remount,bind,rw %[1]v", shellQuote(filepath.Clean(workingDir))))
+	}
+	return strings.Join(append(mounts, `exec "$@"`), " && ")
+}
+
+// isSubPath returns true if path is dir or is under dir
+func isSubPath(dir, path string) bool {
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// shellQuote quotes the value for /bin/sh
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// prepareAppArmor wraps the command so that it runs with the AppArmor profile
+func prepareAppArmor(command *exec.Cmd, profile string) error {
+	if profile == "" {
+		profile = appconfig.DefaultScriptSandboxProfile
+	}
+	aaExec, err := lookPath("aa-exec")
+	if err != nil {
+		return fmt.Errorf("aa-exec is required to run commands in the apparmor sandbox: %v", err)
+	}
+	args := []string{aaExec, "--profile", profile, "--", command.Path}
+	command.Args = append(args, command.Args[1:]...)
+	command.Path = aaExec
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareSandboxRestricted(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "sandbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	lookPathTemp, readOnlyDirsTemp := lookPath, readOnlyDirs
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	readOnlyDirs = func() []string { return []string{dataDir, filepath.Join(dataDir, "missing")} }
+	defer func() { lookPath, readOnlyDirs = lookPathTemp, readOnlyDirsTemp }()
+
+	workingDir := filepath.Join(dataDir, "orchestration", "it's")
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}, Dir: workingDir}
+	assert.NoError(t, prepareSandbox(log.NewMockLog(), command, ExecuteOptions{Sandbox: appconfig.ScriptSandboxRestricted}))
+
+	script := "mount --bind '" + dataDir + "' '" + dataDir + "' && mount -o remount,bind,ro '" + dataDir + "' && " +
+		`mount --bind '` + dataDir + `/orchestration/it'\''s' '` + dataDir + `/orchestration/it'\''s' && ` +
+		`mount -o remount,bind,rw '` + dataDir + `/orchestration/it'\''s' && exec "$@"`
+	assert.Equal(t, "/usr/bin/unshare", command.Path)
+	assert.Equal(t, []string{"/usr/bin/unshare", "--mount", "--propagation", "private", "/bin/sh", "-c", script, "sh",
+		"/usr/bin/setpriv", "--no-new-privs", "--bounding-set", droppedCapabilities, "--inh-caps", droppedCapabilities, "--",
+		"/bin/sh", "script.sh"}, command.Args)
+}
+
+func TestRestrictedScriptWorkingDirOutsideAgentDirs(t *testing.T) {
+	readOnlyDirsTemp := readOnlyDirs
+	readOnlyDirs = func() []string { return []string{"/"} }
+	defer func() { readOnlyDirs = readOnlyDirsTemp }()
+
+	assert.Equal(t, `mount --bind '/' '/' && mount -o remount,bind,ro '/' && mount --bind '/home/user' '/home/user' && mount -o remount,bind,rw '/home/user' && exec "$@"`, restrictedScript("/home/user"))
+
+	readOnlyDirs = func() []string { return []string{"/var/lib/amazon/ssm-missing"} }
+	assert.Equal(t, `exec "$@"`, restrictedScript("/home/user"))
+}
+
+func TestPrepareSandboxAppArmor(t *testing.T) {
+	lookPathTemp := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/sbin/" + file, nil }
+	defer func() { lookPath = lookPathTemp }()
+
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}}
+	assert.NoError(t, prepareSandbox(log.NewMockLog(), command, ExecuteOptions{Sandbox: appconfig.ScriptSandboxAppArmor, SandboxProfile: "scripts"}))
+
+	assert.Equal(t, "/usr/sbin/aa-exec", command.Path)
+	assert.Equal(t, []string{"/usr/sbin/aa-exec", "--profile", "scripts", "--", "/bin/sh", "script.sh"}, command.Args)
+}
+
+func TestPrepareSandboxSkipped(t *testing.T) {
+	command := &exec.Cmd{Path: "/bin/sh", Args: []string{"sh", "script.sh"}}
+	assert.NoError(t, prepareSandbox(log.NewMockLog(), command, ExecuteOptions{}))
+	assert.NoError(t, prepareSandbox(log.NewMockLog(), command, ExecuteOptions{Sandbox: appconfig.ScriptSandboxRestricted, RunAsUser: "user"}))
+	assert.Equal(t, "/bin/sh", command.Path)
+
+	assert.Error(t, prepareSandbox(log.NewMockLog(), command, ExecuteOptions{Sandbox: "seccomp"}))
+}
@@ -0,0 +1,30 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build !linux
+
+package executers
+
+import (
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// prepareSandbox is a no-op, the sandboxes rely on Linux namespaces, capabilities and AppArmor.
+func prepareSandbox(log log.T, command *exec.Cmd, options ExecuteOptions) error {
+	if options.Sandbox != "" {
+		log.Debug("script sandboxes are only supported on Linux")
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package fileutil contains utilities for working with the file system.
+package fileutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// ExtractOptions controls how archives are extracted
+type ExtractOptions struct {
+	// Strict fails the extraction on links, special files, unsupported entries and setuid/setgid permissions.
+	// Otherwise special files and unsupported entries are skipped, links are only created when they point inside
+	// the destination and setuid/setgid permissions are dropped.
+	Strict bool
+}
+
+// StrictExtraction is meant for archives which are expected to only hold directories and regular files
+var StrictExtraction = ExtractOptions{Strict: true}
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// entry describes an item of an archive independently of the archive format
+type entry struct {
+	name     string
+	mode     os.FileMode
+	linkname string
+	hardlink bool
+	special  bool
+}
+
+// extractor places the entries of an archive under the destination directory
+type extractor struct {
+	archive  string
+	dest     string
+	realDest string
+	options  ExtractOptions
+}
+
+// Extract extracts a zip, tar, tar.gz or tar.xz archive to the destination directory. The format is detected from
+// the content of the archive. Entries which would be placed outside the destination, directly or through symbolic
+// links, fail the extraction and device nodes are never created.
+func Extract(src, dest string, options ExtractOptions) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header, err := bufio.NewReader(file).Peek(len(xzMagic))
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return extractZip(src, dest, options)
+	case bytes.HasPrefix(header, gzipMagic):
+		gr, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		return extractTar(src, gr, dest, options)
+	case bytes.HasPrefix(header, xzMagic):
+		return extractTarXz(src, file, dest, options)
+	default:
+		return extractTar(src, file, dest, options)
+	}
+}
+
+// UncompressStrict extracts a package which may only hold directories and regular files
+func UncompressStrict(src, dest string) error {
+	return Extract(src, dest, StrictExtraction)
+}
+
+// extractTarXz decompresses the archive with the xz utility of the system, no xz decoder being available in go
+func extractTarXz(src string, file io.Reader, dest string, options ExtractOptions) error {
+	xzPath, err := exec.LookPath("xz")
+	if err != nil {
+		return fmt.Errorf("xz is required to extract %v - %v", src, err)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(xzPath, "--decompress", "--stdout")
+	cmd.Stdin = file
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	err = extractTar(src, stdout, dest, options)
+	// drain the output so xz doesn't block when the extraction stopped early
+	io.Copy(ioutil.Discard, stdout)
+	if waitErr := cmd.Wait(); waitErr != nil && err == nil {
+		err = fmt.Errorf("failed to decompress %v - %v, %v", src, waitErr, stderr.String())
+	}
+	return err
+}
+
+// extractTar extracts a tar stream
+func extractTar(src string, r io.Reader, dest string, options ExtractOptions) error {
+	x, err := newExtractor(src, dest, options)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		item := entry{name: hdr.Name, mode: hdr.FileInfo().Mode(), linkname: hdr.Linkname}
+		switch hdr.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeRegA, tar.TypeSymlink:
+		case tar.TypeLink:
+			item.hardlink = true
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			item.special = true
+		default:
+			if options.Strict {
+				return fmt.Errorf("%v contains %v of unsupported type %v", src, hdr.Name, hdr.Typeflag)
+			}
+			continue
+		}
+		if err = x.extract(item, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts a zip archive
+func extractZip(src, dest string, options ExtractOptions) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	x, err := newExtractor(src, dest, options)
+	if err != nil {
+		return err
+	}
+
+	// Closure to address file descriptors issue with all the deferred .Close() methods
+	extractFile := func(f *zip.File) error {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		item := entry{name: f.Name, mode: f.Mode()}
+		if item.mode&os.ModeSymlink != 0 {
+			// the target of a symbolic link is stored as the content of the entry
+			target, err := ioutil.ReadAll(io.LimitReader(rc, 4096))
+			if err != nil {
+				return err
+			}
+			item.linkname = string(target)
+		} else if item.mode&(os.ModeDevice|os.ModeNamedPipe|os.ModeSocket) != 0 {
+			item.special = true
+		}
+		return x.extract(item, rc)
+	}
+	for _, f := range r.File {
+		if err := extractFile(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newExtractor(src, dest string, options ExtractOptions) (*extractor, error) {
+	if err := os.MkdirAll(dest, appconfig.ReadWriteExecuteAccess); err != nil {
+		return nil, err
+	}
+	realDest, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return nil, err
+	}
+	return &extractor{archive: src, dest: filepath.Clean(dest), realDest: realDest, options: options}, nil
+}
+
+// extract creates the entry under the destination
+func (x *extractor) extract(item entry, content io.Reader) (err error) {
+	if filepath.IsAbs(item.name) || filepath.VolumeName(item.name) != "" {
+		return fmt.Errorf("%v contains %v with an absolute path", x.archive, item.name)
+	}
+	path := filepath.Join(x.dest, item.name)
+	if !isUnderDir(path, x.dest) {
+		return fmt.Errorf("%v attepts to place files outside %v subtree", x.archive, x.dest)
+	}
+	if path == x.dest {
+		return nil
+	}
+
+	if item.special {
+		if x.options.Strict {
+			return fmt.Errorf("%v contains special file %v", x.archive, item.name)
+		}
+		return nil
+	}
+	if item.mode&(os.ModeSetuid|os.ModeSetgid) != 0 && x.options.Strict {
+		return fmt.Errorf("%v contains %v with setuid or setgid permission", x.archive, item.name)
+	}
+
+	// an earlier entry may have made a parent a symbolic link leading out of the destination
+	if err = x.checkParent(path); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), appconfig.ReadWriteExecuteAccess); err != nil {
+		return err
+	}
+	// never write through an existing symbolic link
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case item.mode.IsDir():
+		if info, err := os.Lstat(path); err == nil && info.IsDir() {
+			return nil
+		}
+		return os.Mkdir(path, item.mode.Perm()|0700)
+	case item.hardlink:
+		return x.link(item, path)
+	case item.mode&os.ModeSymlink != 0:
+		return x.symlink(item, path)
+	default:
+		return writeEntry(path, item.mode.Perm(), content)
+	}
+}
+
+// checkParent verifies the deepest existing parent of the path resolves under the destination
+func (x *extractor) checkParent(path string) error {
+	parent := filepath.Dir(path)
+	for {
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		if parent == x.dest {
+			return nil
+		}
+		parent = filepath.Dir(parent)
+	}
+	realParent, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return err
+	}
+	if !isUnderDir(realParent, x.realDest) {
+		return fmt.Errorf("%v attepts to place files outside %v subtree through a symbolic link", x.archive, x.dest)
+	}
+	return nil
+}
+
+// symlink creates a symbolic link if its target stays under the destination
+func (x *extractor) symlink(item entry, path string) error {
+	if x.options.Strict {
+		return fmt.Errorf("%v contains symbolic link %v", x.archive, item.name)
+	}
+	if item.linkname == "" || filepath.IsAbs(item.linkname) || filepath.VolumeName(item.linkname) != "" ||
+		!isUnderDir(filepath.Join(filepath.Dir(path), item.linkname), x.dest) {
+		return fmt.Errorf("%v contains symbolic link %v pointing outside %v subtree", x.archive, item.name, x.dest)
+	}
+	return os.Symlink(item.linkname, path)
+}
+
+// link creates a hard link to a regular file previously extracted under the destination
+func (x *extractor) link(item entry, path string) error {
+	if x.options.Strict {
+		return fmt.Errorf("%v contains hard link %v", x.archive, item.name)
+	}
+	target := filepath.Join(x.dest, item.linkname)
+	if item.linkname == "" || filepath.IsAbs(item.linkname) || !isUnderDir(target, x.dest) {
+		return fmt.Errorf("%v contains hard link %v pointing outside %v subtree", x.archive, item.name, x.dest)
+	}
+	realTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Lstat(realTarget); err != nil || !info.Mode().IsRegular() || !isUnderDir(realTarget, x.realDest) {
+		return fmt.Errorf("%v contains hard link %v to %v which isn't an extracted file", x.archive, item.name, item.linkname)
+	}
+	return os.Link(realTarget, path)
+}
+
+// writeEntry writes the content of a regular file, only keeping the permission bits of its mode
+func writeEntry(path string, perm os.FileMode, content io.Reader) error {
+	file, err := os.OpenFile(path, appconfig.FileFlagsCreateOrTruncate, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, content)
+	return err
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package fileutil contains utilities for working with the file system.
+package fileutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tarItem is an entry written to the test archives
+type tarItem struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+	mode     int64
+}
+
+func writeTar(t *testing.T, items []tarItem) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, item := range items {
+		mode := item.mode
+		if mode == 0 {
+			mode = 0644
+		}
+		hdr := &tar.Header{Name: item.name, Typeflag: item.typeflag, Linkname: item.linkname, Mode: mode, Size: int64(len(item.content))}
+		assert.NoError(t, tw.WriteHeader(hdr))
+		tw.Write([]byte(item.content))
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func gzipped(content []byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write(content)
+	gw.Close()
+	return buf.Bytes()
+}
+
+// extractTest writes the archive to a temporary directory and extracts it there
+func extractTest(t *testing.T, archive []byte, options ExtractOptions) (dest string, err error) {
+	dir, _ := ioutil.TempDir("", "archive")
+	src := filepath.Join(dir, "archive")
+	assert.NoError(t, ioutil.WriteFile(src, archive, 0600))
+	dest = filepath.Join(dir, "dest")
+	return dest, Extract(src, dest, options)
+}
+
+func TestExtract_Formats(t *testing.T) {
+	plain := writeTar(t, []tarItem{
+		{name: "dir/", typeflag: tar.TypeDir, mode: 0755},
+		{name: "dir/file.txt", typeflag: tar.TypeReg, content: "content"},
+	})
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	w, _ := zw.Create("dir/file.txt")
+	w.Write([]byte("content"))
+	zw.Close()
+
+	archives := map[string][]byte{"tar": plain, "tar.gz": gzipped(plain), "zip": zipped.Bytes()}
+	if _, err := exec.LookPath("xz"); err == nil {
+		cmd := exec.Command("xz", "--compress", "--stdout")
+		cmd.Stdin = bytes.NewReader(plain)
+		xzipped, err := cmd.Output()
+		assert.NoError(t, err)
+		archives["tar.xz"] = xzipped
+	}
+
+	for format, archive := range archives {
+		dest, err := extractTest(t, archive, StrictExtraction)
+		defer os.RemoveAll(filepath.Dir(dest))
+		assert.NoError(t, err, format)
+		content, _ := ioutil.ReadFile(filepath.Join(dest, "dir", "file.txt"))
+		assert.Equal(t, "content", string(content), format)
+	}
+}
+
+func TestExtract_RejectsTraversal(t *testing.T) {
+	for _, name := range []string{"../escape.txt", "dir/../../escape.txt", "/abs.txt"} {
+		dest, err := extractTest(t, writeTar(t, []tarItem{{name: name, typeflag: tar.TypeReg, content: "x"}}), ExtractOptions{})
+		defer os.RemoveAll(filepath.Dir(dest))
+		assert.Error(t, err, name)
+		assert.False(t, Exists(filepath.Join(filepath.Dir(dest), "escape.txt")))
+	}
+}
+
+func TestExtract_Symlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on windows")
+	}
+	outside, _ := ioutil.TempDir("", "outside")
+	defer os.RemoveAll(outside)
+
+	// a link out of the destination followed by a file written through it
+	escape := writeTar(t, []tarItem{
+		{name: "link", typeflag: tar.TypeSymlink, linkname: outside},
+		{name: "link/file.txt", typeflag: tar.TypeReg, content: "x"},
+	})
+	dest, err := extractTest(t, escape, ExtractOptions{})
+	defer os.RemoveAll(filepath.Dir(dest))
+	assert.Error(t, err)
+	assert.False(t, Exists(filepath.Join(outside, "file.txt")))
+
+	// a link inside the destination is kept unless extraction is strict
+	inside := writeTar(t, []tarItem{
+		{name: "file.txt", typeflag: tar.TypeReg, content: "x"},
+		{name: "link.txt", typeflag: tar.TypeSymlink, linkname: "file.txt"},
+		{name: "hard.txt", typeflag: tar.TypeLink, linkname: "file.txt"},
+	})
+	dest, err = extractTest(t, inside, ExtractOptions{})
+	defer os.RemoveAll(filepath.Dir(dest))
+	assert.NoError(t, err)
+	target, _ := os.Readlink(filepath.Join(dest, "link.txt"))
+	assert.Equal(t, "file.txt", target)
+	content, _ := ioutil.ReadFile(filepath.Join(dest, "hard.txt"))
+	assert.Equal(t, "x", string(content))
+
+	dest, err = extractTest(t, inside, StrictExtraction)
+	defer os.RemoveAll(filepath.Dir(dest))
+	assert.Error(t, err)
+}
+
+func TestExtract_ExistingSymlinkIsReplaced(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on windows")
+	}
+	outside, _ := ioutil.TempFile("", "outside")
+	outside.Close()
+	defer os.Remove(outside.Name())
+
+	dir, _ := ioutil.TempDir("", "archive")
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "dest")
+	os.MkdirAll(dest, 0700)
+	os.Symlink(outside.Name(), filepath.Join(dest, "file.txt"))
+	src := filepath.Join(dir, "archive.tar")
+	ioutil.WriteFile(src, writeTar(t, []tarItem{{name: "file.txt", typeflag: tar.TypeReg, content: "x"}}), 0600)
+
+	assert.NoError(t, Extract(src, dest, ExtractOptions{}))
+	content, _ := ioutil.ReadFile(outside.Name())
+	assert.Empty(t, content)
+	content, _ = ioutil.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.Equal(t, "x", string(content))
+}
+
+func TestExtract_SpecialFilesAndSetuid(t *testing.T) {
+	device := writeTar(t, []tarItem{
+		{name: "null", typeflag: tar.TypeChar},
+		{name: "file.txt", typeflag: tar.TypeReg, content: "x", mode: 04755},
+	})
+
+	dest, err := extractTest(t, device, ExtractOptions{})
+	defer os.RemoveAll(filepath.Dir(dest))
+	assert.NoError(t, err)
+	assert.False(t, Exists(filepath.Join(dest, "null")))
+	info, err := os.Stat(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0), info.Mode()&os.ModeSetuid)
+
+	dest, err = extractTest(t, device, StrictExtraction)
+	defer os.RemoveAll(filepath.Dir(dest))
+	assert.Error(t, err)
+}
@@ -26,11 +26,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
-	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
@@ -52,11 +54,38 @@ type DownloadInput struct {
 	SourceChecksums      map[string]string
 }
 
+var checkDiskSpace = fileutil.CheckDiskSpace
+
+// downloadedBytes counts the bytes downloaded by the process, it is only updated atomically
+var downloadedBytes int64
+
+// RecordDownloadedBytes adds count to the bytes downloaded by the process
+func RecordDownloadedBytes(count int64) {
+	atomic.AddInt64(&downloadedBytes, count)
+}
+
+// DownloadedBytes returns the bytes downloaded by the process so far
+func DownloadedBytes() int64 {
+	return atomic.LoadInt64(&downloadedBytes)
+}
+
 // httpDownload attempts to download a file via http/s call
 func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOutput, err error) {
 	log.Debugf("attempting to download as http/https download %v", destFile)
 	eTagFile := destFile + ".etag"
-	var check http.Client
+	check := http.Client{
+		Transport: proxyconfig.NewTransport(appconfig.ServiceNameS3),
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			r.URL.Opaque = r.URL.Path
+			return nil
+		},
+	}
+
+	// large files are downloaded in resumable parts
+	if output, err = partedDownload(log, &httpRangeSource{client: &check, fileURL: fileURL}, destFile); err != errNotParted {
+		return
+	}
+
 	var request *http.Request
 	request, err = http.NewRequest("GET", fileURL, nil)
 	if err != nil {
@@ -68,13 +97,6 @@ func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOu
 		request.Header.Add("If-None-Match", existingETag)
 	}
 
-	check = http.Client{
-		CheckRedirect: func(r *http.Request, via []*http.Request) error {
-			r.URL.Opaque = r.URL.Path
-			return nil
-		},
-	}
-
 	var resp *http.Response
 	resp, err = check.Do(request)
 	if err != nil {
@@ -97,6 +119,9 @@ func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOu
 		return
 	}
 	defer resp.Body.Close()
+	if err = checkDiskSpace(destFile, resp.ContentLength); err != nil {
+		return
+	}
 	eTagValue := resp.Header.Get("Etag")
 	if eTagValue != "" {
 		log.Debug("file eTagValue is ", eTagValue)
@@ -119,24 +144,9 @@ func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOu
 // awsConfig creates a config and sets region and credential information given an S3 URL
 func awsConfig(log log.T, amazonS3URL s3util.AmazonS3URL) (config *aws.Config, err error) {
 	config = sdkutil.AwsConfig()
-	var appConfig appconfig.SsmagentConfig
-	appConfig, errConfig := appconfig.Config(false)
-	if errConfig != nil {
-		log.Error("failed to read appconfig.")
-	} else {
-		if appConfig.S3.Endpoint != "" {
-			config.Endpoint = &appConfig.S3.Endpoint
-		} else {
-			if region, err := platform.Region(); err == nil {
-				if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "s3"); defaultEndpoint != "" {
-					config.Endpoint = &defaultEndpoint
-				}
-			} else {
-				log.Errorf("error fetching the region, %v", err)
-			}
-		}
-	}
+	config.HTTPClient = &http.Client{Transport: proxyconfig.NewTransport(appconfig.ServiceNameS3)}
 	config.S3ForcePathStyle = aws.Bool(amazonS3URL.IsPathStyle)
+	s3util.ConfigureEndpoint(log, config, amazonS3URL.Bucket, amazonS3URL.Region)
 	config.Region = aws.String(amazonS3URL.Region)
 	return config, nil
 }
@@ -236,6 +246,14 @@ func s3Download(log log.T, amazonS3URL s3util.AmazonS3URL, destFile string) (out
 	eTagFile := destFile + ".etag"
 
 	config, _ := awsConfig(log, amazonS3URL)
+	s3client := s3.New(session.New(config))
+
+	// large objects are downloaded in resumable parts
+	source := &s3RangeSource{client: s3client, bucket: amazonS3URL.Bucket, key: amazonS3URL.Key}
+	if output, err = partedDownload(log, source, destFile); err != errNotParted {
+		return
+	}
+
 	params := &s3.GetObjectInput{
 		Bucket: aws.String(amazonS3URL.Bucket),
 		Key:    aws.String(amazonS3URL.Key),
@@ -251,8 +269,6 @@ func s3Download(log log.T, amazonS3URL s3util.AmazonS3URL, destFile string) (out
 		params.IfNoneMatch = aws.String(existingETag)
 	}
 
-	s3client := s3.New(session.New(config))
-
 	req, resp := s3client.GetObjectRequest(params)
 	err = req.Send()
 	if err != nil {
@@ -269,6 +285,11 @@ func s3Download(log log.T, amazonS3URL s3util.AmazonS3URL, destFile string) (out
 		return output, nil
 	}
 
+	if err = checkDiskSpace(destFile, aws.Int64Value(resp.ContentLength)); err != nil {
+		resp.Body.Close()
+		return
+	}
+
 	if *resp.ETag != "" {
 		log.Debug("files etag is ", *resp.ETag)
 		err = fileutil.WriteAllText(eTagFile, *resp.ETag)
@@ -300,7 +321,8 @@ func FileCopy(log log.T, destinationPath string, src io.Reader) (written int64,
 	}
 	defer file.Close()
 	var size int64
-	size, err = io.Copy(file, src)
+	size, err = io.Copy(file, bandwidth.DownloadReader(src))
+	RecordDownloadedBytes(size)
 	log.Infof("%s with %v bytes downloaded", destinationPath, size)
 	return
 }
@@ -0,0 +1,499 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+const (
+	// partialFileSuffix is appended to the destination of a download in progress
+	partialFileSuffix = ".partial"
+	// partsFileSuffix is appended to the destination of a download in progress to name the file tracking its parts
+	partsFileSuffix = ".parts"
+	// maxPartAttempts is the number of times the download of a part is attempted, resuming where the last attempt stopped
+	maxPartAttempts = 5
+	// maxDownloadParts limits the number of parts, the part size is increased for larger content
+	maxDownloadParts = 10000
+	// bytesPerMB converts the part size configured in MB
+	bytesPerMB = 1024 * 1024
+)
+
+// errNotParted is returned when a source can't be downloaded in parts
+var errNotParted = errors.New("source can't be downloaded in parts")
+
+// errContentChanged is returned when the content changed while its parts were downloaded
+var errContentChanged = errors.New("content changed during the download")
+
+// Assign to global variables to allow unittest to override
+var getAppConfig = appconfig.Config
+var partRetryDelay = 2 * time.Second
+
+// rangeSource is a download source which can serve ranges of its content
+type rangeSource interface {
+	// stat returns the size and the ETag of the content, and false if ranges can't be requested.
+	// The ETag is empty when the version of the content can't be identified, such a download isn't resumed.
+	stat() (size int64, eTag string, ranged bool, err error)
+	// fetch returns the length bytes of the content starting at offset, errContentChanged if the content changed
+	fetch(offset, length int64) (io.ReadCloser, error)
+}
+
+// checksumSource is a range source which can verify the assembled content against its checksum
+type checksumSource interface {
+	// verify returns an error if the downloaded file doesn't match the checksum of the content
+	verify(log log.T, filePath string) error
+}
+
+// partedDownloadState is persisted next to a download in progress so an interrupted download can be resumed
+type partedDownloadState struct {
+	ETag      string
+	Size      int64
+	PartSize  int64
+	Completed []bool
+}
+
+// partedDownload downloads the source in parts, several of them concurrently.
+// The parts already downloaded by an interrupted download of the same content are kept, and a part which fails
+// is resumed from where it stopped. errNotParted is returned for sources which are small or don't support ranges.
+func partedDownload(log log.T, source rangeSource, destFile string) (output DownloadOutput, err error) {
+	size, eTag, ranged, err := source.stat()
+	if err != nil {
+		log.Debugf("unable to get the size of %v, %v", destFile, err)
+		return output, errNotParted
+	}
+	concurrency, partSize := downloadSettings(size)
+	if !ranged || size <= partSize {
+		return output, errNotParted
+	}
+
+	eTagFile := destFile + ".etag"
+	if fileutil.Exists(destFile) && fileutil.Exists(eTagFile) {
+		if existingETag, readErr := fileutil.ReadAllText(eTagFile); readErr == nil && eTag != "" && existingETag == eTag {
+			log.Debugf("Unchanged file.")
+			output.LocalFilePath = destFile
+			return output, nil
+		}
+	}
+
+	partialFile := destFile + partialFileSuffix
+	partsFile := destFile + partsFileSuffix
+	state := loadPartedDownloadState(log, partsFile, partialFile, eTag, size, partSize)
+	if err = checkDiskSpace(partialFile, size-state.completedBytes()); err != nil {
+		return output, err
+	}
+
+	file, err := os.OpenFile(partialFile, os.O_CREATE|os.O_WRONLY, appconfig.ReadWriteAccess)
+	if err != nil {
+		return output, fmt.Errorf("failed to create file %v, %v", partialFile, err)
+	}
+	if err = file.Truncate(size); err != nil {
+		file.Close()
+		return output, fmt.Errorf("failed to allocate file %v, %v", partialFile, err)
+	}
+
+	resumedBytes := state.completedBytes()
+	err = downloadParts(log, source, file, state, partsFile, concurrency)
+	RecordDownloadedBytes(state.completedBytes() - resumedBytes)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == errContentChanged {
+		// the parts downloaded so far belong to another version of the content, the download starts over
+		log.Infof("%v changed during the download, downloading it again", destFile)
+		fileutil.DeleteFile(partialFile)
+		fileutil.DeleteFile(partsFile)
+		return output, errNotParted
+	}
+	if err != nil {
+		log.Errorf("failed to download %v, the download will be resumed next time, %v", destFile, err)
+		return
+	}
+
+	// corrupted content can't be resumed, the next download starts over
+	if checksum, ok := source.(checksumSource); ok {
+		if err = checksum.verify(log, partialFile); err != nil {
+			fileutil.DeleteFile(partialFile)
+			fileutil.DeleteFile(partsFile)
+			return output, fmt.Errorf("failed to verify the download of %v, %v", destFile, err)
+		}
+	}
+
+	fileutil.DeleteFile(destFile)
+	if err = os.Rename(partialFile, destFile); err != nil {
+		return output, fmt.Errorf("failed to move %v to %v, %v", partialFile, destFile, err)
+	}
+	fileutil.DeleteFile(partsFile)
+	if eTag != "" {
+		if err = fileutil.WriteAllText(eTagFile, eTag); err != nil {
+			log.Errorf("failed to write eTagfile %v, %v ", eTagFile, err)
+			return
+		}
+	}
+
+	log.Infof("%s with %v bytes downloaded in %v parts", destFile, size, len(state.Completed))
+	output.LocalFilePath = destFile
+	output.IsUpdated = true
+	return
+}
+
+// downloadSettings returns the configured number of concurrent parts, and the part size for content of the given size
+func downloadSettings(size int64) (concurrency int, partSize int64) {
+	concurrency = appconfig.DefaultDownloadConcurrency
+	partSize = appconfig.DefaultDownloadPartSizeMB * bytesPerMB
+	if config, err := getAppConfig(false); err == nil {
+		concurrency = config.Agent.DownloadConcurrency
+		partSize = int64(config.Agent.DownloadPartSizeMB) * bytesPerMB
+	}
+	if minPartSize := (size + maxDownloadParts - 1) / maxDownloadParts; partSize < minPartSize {
+		partSize = minPartSize
+	}
+	return
+}
+
+// loadPartedDownloadState returns the state of an interrupted download of the same content, or a new state.
+// A download is only resumed when the ETag identifies the content it started with.
+func loadPartedDownloadState(log log.T, partsFile, partialFile, eTag string, size, partSize int64) *partedDownloadState {
+	var state partedDownloadState
+	if eTag != "" && fileutil.Exists(partsFile) && fileutil.Exists(partialFile) {
+		if err := jsonutil.UnmarshalFile(partsFile, &state); err == nil &&
+			state.ETag == eTag && state.Size == size && state.PartSize > 0 &&
+			int64(len(state.Completed)) == (size+state.PartSize-1)/state.PartSize {
+			log.Infof("resuming the download of %v", partialFile)
+			return &state
+		}
+	}
+
+	fileutil.DeleteFile(partialFile)
+	state = partedDownloadState{
+		ETag:      eTag,
+		Size:      size,
+		PartSize:  partSize,
+		Completed: make([]bool, (size+partSize-1)/partSize),
+	}
+	return &state
+}
+
+// completedBytes returns the number of bytes of the parts already downloaded
+func (state *partedDownloadState) completedBytes() (completed int64) {
+	for part, done := range state.Completed {
+		if done {
+			end := int64(part+1) * state.PartSize
+			if end > state.Size {
+				end = state.Size
+			}
+			completed += end - int64(part)*state.PartSize
+		}
+	}
+	return completed
+}
+
+// downloadParts downloads the parts which aren't completed yet, recording the progress in the parts file
+func downloadParts(log log.T, source rangeSource, file *os.File, state *partedDownloadState, partsFile string, concurrency int) (err error) {
+	pending := make(chan int, len(state.Completed))
+	for part, completed := range state.Completed {
+		if !completed {
+			pending <- part
+		}
+	}
+	close(pending)
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range pending {
+				partErr := downloadPart(log, source, file, state, part)
+
+				lock.Lock()
+				if partErr != nil {
+					if err == nil {
+						err = partErr
+					}
+				} else {
+					state.Completed[part] = true
+					if content, marshalErr := jsonutil.Marshal(state); marshalErr == nil {
+						fileutil.WriteAllText(partsFile, content)
+					}
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return
+}
+
+// downloadPart downloads one part of the content, resuming from where the previous attempt stopped
+func downloadPart(log log.T, source rangeSource, file *os.File, state *partedDownloadState, part int) (err error) {
+	offset := int64(part) * state.PartSize
+	end := offset + state.PartSize
+	if end > state.Size {
+		end = state.Size
+	}
+
+	for attempt := 1; ; attempt++ {
+		var written int64
+		written, err = copyRange(source, file, offset, end-offset)
+		if offset += written; offset == end {
+			return nil
+		}
+		if err == errContentChanged {
+			return err
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		if attempt == maxPartAttempts {
+			return fmt.Errorf("failed to download part %v, %v", part, err)
+		}
+		log.Debugf("download of part %v interrupted, resuming at offset %v, %v", part, offset, err)
+		time.Sleep(time.Duration(attempt) * partRetryDelay)
+	}
+}
+
+// copyRange copies length bytes of the content starting at offset into the same position of the file
+func copyRange(source rangeSource, file *os.File, offset, length int64) (written int64, err error) {
+	reader, err := source.fetch(offset, length)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	return io.Copy(&offsetWriter{file: file, offset: offset}, bandwidth.DownloadReader(io.LimitReader(reader, length)))
+}
+
+// offsetWriter writes sequentially to a file starting at an offset
+type offsetWriter struct {
+	file   *os.File
+	offset int64
+}
+
+// Write writes the bytes at the current offset of the writer
+func (w *offsetWriter) Write(p []byte) (n int, err error) {
+	n, err = w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return
+}
+
+// rangeHeader returns the value of the Range header requesting length bytes starting at offset
+func rangeHeader(offset, length int64) string {
+	return fmt.Sprintf("bytes=%v-%v", offset, offset+length-1)
+}
+
+// httpRangeSource serves ranges of a file downloaded via http/s
+type httpRangeSource struct {
+	client  *http.Client
+	fileURL string
+	// validator is the strong ETag or the Last-Modified date of the file, sent in If-Range so a changed file isn't mixed with the parts downloaded
+	validator string
+}
+
+// stat returns the size and the version of the file, ranges are supported if the server advertises them.
+// The version is the strong ETag of the file, or its Last-Modified date when it has none.
+func (s *httpRangeSource) stat() (size int64, eTag string, ranged bool, err error) {
+	resp, err := s.client.Head(s.fileURL)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", false, fmt.Errorf("http request failed. status:%v statuscode:%v", resp.Status, resp.StatusCode)
+	}
+	if size, err = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err != nil {
+		return 0, "", false, nil
+	}
+	// weak ETags can't be used in If-Range
+	if eTag := resp.Header.Get("Etag"); eTag != "" && !strings.HasPrefix(eTag, "W/") {
+		s.validator = eTag
+	} else {
+		s.validator = resp.Header.Get("Last-Modified")
+	}
+	return size, s.validator, resp.Header.Get("Accept-Ranges") == "bytes", nil
+}
+
+// fetch requests a range of the file
+func (s *httpRangeSource) fetch(offset, length int64) (io.ReadCloser, error) {
+	request, err := http.NewRequest("GET", s.fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("Range", rangeHeader(offset, length))
+	if s.validator != "" {
+		request.Header.Add("If-Range", s.validator)
+	}
+	resp, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		// the server sends the whole file instead of the range when it no longer matches the validator
+		resp.Body.Close()
+		return nil, errContentChanged
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("http range request failed. status:%v statuscode:%v", resp.Status, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// s3RangeSource serves ranges of an s3 object
+type s3RangeSource struct {
+	client s3iface.S3API
+	bucket string
+	key    string
+	head   *s3.HeadObjectOutput
+}
+
+// stat returns the size and the ETag of the object, s3 always supports ranges
+func (s *s3RangeSource) stat() (size int64, eTag string, ranged bool, err error) {
+	if s.head, err = s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	}); err != nil {
+		return
+	}
+	return aws.Int64Value(s.head.ContentLength), aws.StringValue(s.head.ETag), true, nil
+}
+
+// maxVerifiedUploadParts is the number of uploaded parts above which the object isn't verified,
+// the size of each part being requested separately
+const maxVerifiedUploadParts = 1000
+
+// verify compares the MD5 of the file with the ETag of the object. The ETag of an object uploaded in parts
+// is the MD5 of the MD5 of each of the uploaded parts, followed by the number of parts.
+// The ETag of objects encrypted with KMS or customer keys isn't an MD5, they can't be verified.
+func (s *s3RangeSource) verify(log log.T, filePath string) (err error) {
+	if aws.StringValue(s.head.ServerSideEncryption) == s3.ServerSideEncryptionAwsKms || s.head.SSECustomerAlgorithm != nil {
+		log.Debugf("ETag of encrypted object %v isn't a checksum, skipping verification", s.key)
+		return nil
+	}
+	eTag := strings.Trim(aws.StringValue(s.head.ETag), `"`)
+
+	var partSizes []int64
+	if separator := strings.LastIndex(eTag, "-"); separator >= 0 {
+		partCount, parseErr := strconv.Atoi(eTag[separator+1:])
+		if parseErr != nil || partCount <= 0 || partCount > maxVerifiedUploadParts {
+			log.Debugf("ETag %v of object %v doesn't give a number of parts which can be verified, skipping verification", eTag, s.key)
+			return nil
+		}
+		var sizeErr error
+		if partSizes, sizeErr = s.uploadPartSizes(partCount); sizeErr != nil {
+			log.Warnf("size of the uploaded parts of object %v can't be determined, skipping verification: %v", s.key, sizeErr)
+			return nil
+		}
+	}
+
+	checksum, err := partedMd5(filePath, partSizes)
+	if err != nil {
+		return
+	}
+	if checksum != eTag {
+		return fmt.Errorf("checksum %v doesn't match the ETag %v", checksum, eTag)
+	}
+	return nil
+}
+
+// uploadPartSizes returns the size of each of the parts the object was uploaded in, requested part by part
+// as the parts of an upload can have different sizes
+func (s *s3RangeSource) uploadPartSizes(partCount int) (partSizes []int64, err error) {
+	var total int64
+	for partNumber := 1; partNumber <= partCount; partNumber++ {
+		var part *s3.HeadObjectOutput
+		if part, err = s.client.HeadObject(&s3.HeadObjectInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(s.key),
+			PartNumber: aws.Int64(int64(partNumber)),
+		}); err != nil {
+			return nil, err
+		}
+		if partsCount := aws.Int64Value(part.PartsCount); partsCount != int64(partCount) {
+			return nil, fmt.Errorf("object has %v parts, %v according to its ETag", partsCount, partCount)
+		}
+		partSizes = append(partSizes, aws.Int64Value(part.ContentLength))
+		total += aws.Int64Value(part.ContentLength)
+	}
+	if total != aws.Int64Value(s.head.ContentLength) {
+		return nil, fmt.Errorf("parts add up to %v bytes, the object has %v", total, aws.Int64Value(s.head.ContentLength))
+	}
+	return partSizes, nil
+}
+
+// partedMd5 returns the MD5 of the file, or when it was uploaded in parts of partSizes,
+// the MD5 of the MD5 of each part followed by the number of parts
+func partedMd5(filePath string, partSizes []int64) (checksum string, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if len(partSizes) == 0 {
+		hash := md5.New()
+		if _, err = io.Copy(hash, file); err != nil {
+			return
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	var digests []byte
+	for i, partSize := range partSizes {
+		hash := md5.New()
+		if i == len(partSizes)-1 {
+			// content beyond the uploaded parts is hashed with the last part, so the checksum doesn't match
+			_, err = io.Copy(hash, file)
+		} else {
+			_, err = io.CopyN(hash, file, partSize)
+		}
+		if err != nil && err != io.EOF {
+			return
+		}
+		digests = append(digests, hash.Sum(nil)...)
+	}
+	sum := md5.Sum(digests)
+	return fmt.Sprintf("%v-%v", hex.EncodeToString(sum[:]), len(partSizes)), nil
+}
+
+// fetch requests a range of the object
+func (s *s3RangeSource) fetch(offset, length int64) (io.ReadCloser, error) {
+	resp, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+		Range:  aws.String(rangeHeader(offset, length)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,438 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLog = log.NewMockLog()
+
+// testPartSize is the part size configured by setupPartedDownload
+const testPartSize = bytesPerMB
+
+// memoryRangeSource serves ranges of an in-memory content, failing the parts listed in failingParts
+type memoryRangeSource struct {
+	content      []byte
+	eTag         string
+	failingParts map[int64]int
+	lock         sync.Mutex
+	fetched      []int64
+}
+
+func (s *memoryRangeSource) stat() (int64, string, bool, error) {
+	return int64(len(s.content)), s.eTag, true, nil
+}
+
+func (s *memoryRangeSource) fetch(offset, length int64) (io.ReadCloser, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.fetched = append(s.fetched, offset)
+	part := offset / testPartSize
+	if s.failingParts[part] > 0 {
+		s.failingParts[part]--
+		// serve half of the range then fail, as an interrupted connection would
+		return ioutil.NopCloser(io.MultiReader(bytes.NewReader(s.content[offset:offset+length/2]), errorReader{})), nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(s.content[offset : offset+length])), nil
+}
+
+type errorReader struct{}
+
+func (errorReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func setupPartedDownload(t *testing.T) (dir string, content []byte) {
+	partRetryDelay = 0
+	checkDiskSpace = func(path string, size int64) error { return nil }
+	getAppConfig = func(bool) (config appconfig.SsmagentConfig, err error) {
+		config.Agent.DownloadConcurrency = 3
+		config.Agent.DownloadPartSizeMB = 1
+		return
+	}
+
+	content = make([]byte, 10*testPartSize+100)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	dir, err := ioutil.TempDir("", "multipart")
+	assert.NoError(t, err)
+	return
+}
+
+func TestPartedDownloadFromHttp(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Etag", `"v1"`)
+		http.ServeContent(w, r, "artifact", time.Now(), bytes.NewReader(content))
+	}))
+	defer server.Close()
+	destFile := filepath.Join(dir, "artifact")
+
+	output, err := httpDownload(testLog, server.URL, destFile)
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsUpdated)
+	downloaded, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, content, downloaded)
+	assert.False(t, fileutil.Exists(destFile+partsFileSuffix))
+	assert.False(t, fileutil.Exists(destFile+partialFileSuffix))
+
+	// the same content isn't downloaded again
+	output, err = httpDownload(testLog, server.URL, destFile)
+
+	assert.NoError(t, err)
+	assert.False(t, output.IsUpdated)
+	assert.Equal(t, destFile, output.LocalFilePath)
+}
+
+func TestPartedDownloadFromHttpRestartsChangedContent(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	changed := append([]byte("changed"), content...)
+	var ifRange []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the content changes between the HEAD request and the download of its parts
+		if r.Method == http.MethodHead {
+			w.Header().Set("Etag", `"v1"`)
+			http.ServeContent(w, r, "artifact", time.Now(), bytes.NewReader(content))
+			return
+		}
+		if r.Header.Get("Range") != "" {
+			ifRange = append(ifRange, r.Header.Get("If-Range"))
+		}
+		w.Header().Set("Etag", `"v2"`)
+		http.ServeContent(w, r, "artifact", time.Now(), bytes.NewReader(changed))
+	}))
+	defer server.Close()
+	destFile := filepath.Join(dir, "artifact")
+
+	output, err := httpDownload(testLog, server.URL, destFile)
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsUpdated)
+	downloaded, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, changed, downloaded)
+	assert.NotEmpty(t, ifRange)
+	for _, value := range ifRange {
+		assert.Equal(t, `"v1"`, value)
+	}
+	assert.False(t, fileutil.Exists(destFile+partsFileSuffix))
+	assert.False(t, fileutil.Exists(destFile+partialFileSuffix))
+}
+
+func TestHttpRangeSourceValidator(t *testing.T) {
+	modTime := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+	data := []struct {
+		name              string
+		eTag              string
+		lastModified      bool
+		expectedValidator string
+	}{
+		{"strong ETag", `"v1"`, true, `"v1"`},
+		{"weak ETag", `W/"v1"`, true, modTime.Format(http.TimeFormat)},
+		{"no ETag", "", true, modTime.Format(http.TimeFormat)},
+		{"no validator", "", false, ""},
+	}
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if testdata.eTag != "" {
+					w.Header().Set("Etag", testdata.eTag)
+				}
+				fileModTime := time.Time{}
+				if testdata.lastModified {
+					fileModTime = modTime
+				}
+				http.ServeContent(w, r, "artifact", fileModTime, bytes.NewReader(make([]byte, 100)))
+			}))
+			defer server.Close()
+			source := &httpRangeSource{client: http.DefaultClient, fileURL: server.URL}
+
+			_, eTag, ranged, err := source.stat()
+
+			assert.NoError(t, err)
+			assert.True(t, ranged)
+			assert.Equal(t, testdata.expectedValidator, eTag)
+			assert.Equal(t, testdata.expectedValidator, source.validator)
+		})
+	}
+}
+
+func TestPartedDownloadResumesInterruptedPart(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	source := &memoryRangeSource{content: content, eTag: "v1", failingParts: map[int64]int{2: 2}}
+	destFile := filepath.Join(dir, "artifact")
+
+	output, err := partedDownload(testLog, source, destFile)
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsUpdated)
+	downloaded, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, content, downloaded)
+	// part 2 is resumed from the middle of the part twice
+	assert.Contains(t, source.fetched, int64(2*testPartSize+testPartSize/2))
+	assert.Contains(t, source.fetched, int64(2*testPartSize+3*testPartSize/4))
+}
+
+func TestPartedDownloadResumesInterruptedDownload(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	source := &memoryRangeSource{content: content, eTag: "v1", failingParts: map[int64]int{4: maxPartAttempts}}
+	destFile := filepath.Join(dir, "artifact")
+
+	_, err := partedDownload(testLog, source, destFile)
+
+	assert.Error(t, err)
+	assert.False(t, fileutil.Exists(destFile))
+	assert.True(t, fileutil.Exists(destFile+partsFileSuffix))
+
+	// only the failed part is downloaded again
+	source.fetched = nil
+	output, err := partedDownload(testLog, source, destFile)
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsUpdated)
+	assert.Equal(t, []int64{4 * testPartSize}, source.fetched)
+	downloaded, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, content, downloaded)
+}
+
+func TestPartedDownloadRestartsChangedContent(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	source := &memoryRangeSource{content: content, eTag: "v1", failingParts: map[int64]int{4: maxPartAttempts}}
+	destFile := filepath.Join(dir, "artifact")
+	partedDownload(testLog, source, destFile)
+
+	source.eTag = "v2"
+	source.fetched = nil
+	_, err := partedDownload(testLog, source, destFile)
+
+	assert.NoError(t, err)
+	assert.Len(t, source.fetched, 11)
+}
+
+func TestPartedDownloadDoesNotResumeUnidentifiedContent(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	source := &memoryRangeSource{content: content, failingParts: map[int64]int{4: maxPartAttempts}}
+	destFile := filepath.Join(dir, "artifact")
+	partedDownload(testLog, source, destFile)
+
+	// without an ETag the parts downloaded may belong to another content, all of them are downloaded again
+	source.fetched = nil
+	_, err := partedDownload(testLog, source, destFile)
+
+	assert.NoError(t, err)
+	assert.Len(t, source.fetched, 11)
+	downloaded, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, content, downloaded)
+}
+
+func TestPartedDownloadChecksDiskSpace(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	source := &memoryRangeSource{content: content, eTag: "v1", failingParts: map[int64]int{4: maxPartAttempts}}
+	destFile := filepath.Join(dir, "artifact")
+	var required []int64
+	checkDiskSpace = func(path string, size int64) error {
+		required = append(required, size)
+		return nil
+	}
+	partedDownload(testLog, source, destFile)
+
+	// only the parts left are required when the download is resumed
+	checkDiskSpace = func(path string, size int64) error {
+		required = append(required, size)
+		return errors.New("insufficient disk space")
+	}
+	source.fetched = nil
+	_, err := partedDownload(testLog, source, destFile)
+
+	assert.Error(t, err)
+	assert.Equal(t, []int64{int64(len(content)), testPartSize}, required)
+	assert.Empty(t, source.fetched)
+	assert.False(t, fileutil.Exists(destFile))
+}
+
+func TestPartedDownloadSkipsSmallContent(t *testing.T) {
+	dir, _ := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	source := &memoryRangeSource{content: make([]byte, 100), eTag: "v1"}
+
+	_, err := partedDownload(testLog, source, filepath.Join(dir, "artifact"))
+
+	assert.Equal(t, errNotParted, err)
+	assert.Empty(t, source.fetched)
+}
+
+// memoryS3Client serves an object uploaded in parts of uploadPartSizes from memory
+type memoryS3Client struct {
+	s3iface.S3API
+	source          *memoryRangeSource
+	uploadPartSizes []int64
+	partHeadErr     error
+}
+
+func (c *memoryS3Client) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	if input.PartNumber != nil {
+		if c.partHeadErr != nil {
+			return nil, c.partHeadErr
+		}
+		return &s3.HeadObjectOutput{
+			ContentLength: aws.Int64(c.uploadPartSizes[aws.Int64Value(input.PartNumber)-1]),
+			PartsCount:    aws.Int64(int64(len(c.uploadPartSizes))),
+		}, nil
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(c.source.content))),
+		ETag:          aws.String(c.source.eTag),
+	}, nil
+}
+
+func (c *memoryS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	var offset, end int64
+	fmt.Sscanf(aws.StringValue(input.Range), "bytes=%d-%d", &offset, &end)
+	body, err := c.source.fetch(offset, end-offset+1)
+	return &s3.GetObjectOutput{Body: body}, err
+}
+
+// uploadETag returns the ETag of the content uploaded in parts of partSizes
+func uploadETag(content []byte, partSizes []int64) string {
+	var digests []byte
+	var offset int64
+	for _, partSize := range partSizes {
+		sum := md5.Sum(content[offset : offset+partSize])
+		digests = append(digests, sum[:]...)
+		offset += partSize
+	}
+	sum := md5.Sum(digests)
+	return fmt.Sprintf(`"%x-%v"`, sum, len(partSizes))
+}
+
+func TestPartedDownloadFromS3VerifiesChecksum(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	sum := md5.Sum(content)
+	size := int64(len(content))
+	data := []struct {
+		name      string
+		partSizes []int64
+	}{
+		{"single upload", nil},
+		{"parts of the same size", []int64{5 * testPartSize, 5 * testPartSize, size - 10*testPartSize}},
+		{"parts of different sizes", []int64{3 * testPartSize, 6 * testPartSize, size - 9*testPartSize}},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			destFile := filepath.Join(dir, "artifact")
+			defer fileutil.DeleteFile(destFile + ".etag")
+			defer fileutil.DeleteFile(destFile)
+			eTag := fmt.Sprintf(`"%x"`, sum)
+			if testdata.partSizes != nil {
+				eTag = uploadETag(content, testdata.partSizes)
+			}
+			client := &memoryS3Client{source: &memoryRangeSource{content: content, eTag: eTag}, uploadPartSizes: testdata.partSizes}
+
+			output, err := partedDownload(testLog, &s3RangeSource{client: client, bucket: "bucket", key: "key"}, destFile)
+
+			assert.NoError(t, err)
+			assert.True(t, output.IsUpdated)
+		})
+	}
+}
+
+func TestPartedDownloadFromS3RejectsCorruptedContent(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	destFile := filepath.Join(dir, "artifact")
+	partSizes := []int64{5 * testPartSize, 5 * testPartSize, int64(len(content)) - 10*testPartSize}
+	corrupted := append([]byte{content[0] + 1}, content[1:]...)
+	client := &memoryS3Client{source: &memoryRangeSource{content: content, eTag: uploadETag(corrupted, partSizes)}, uploadPartSizes: partSizes}
+
+	_, err := partedDownload(testLog, &s3RangeSource{client: client, bucket: "bucket", key: "key"}, destFile)
+
+	assert.Error(t, err)
+	assert.False(t, fileutil.Exists(destFile))
+	assert.False(t, fileutil.Exists(destFile+partialFileSuffix))
+	assert.False(t, fileutil.Exists(destFile+partsFileSuffix))
+}
+
+func TestPartedDownloadFromS3SkipsVerificationOfUnknownParts(t *testing.T) {
+	dir, content := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+	size := int64(len(content))
+	data := []struct {
+		name        string
+		partSizes   []int64
+		partHeadErr error
+	}{
+		{"part sizes not available", []int64{5 * testPartSize, size - 5*testPartSize}, errors.New("access denied")},
+		{"part sizes don't add up", []int64{5 * testPartSize, 5 * testPartSize}, nil},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			destFile := filepath.Join(dir, "artifact")
+			defer fileutil.DeleteFile(destFile + ".etag")
+			defer fileutil.DeleteFile(destFile)
+			// the ETag doesn't match the content, it isn't verified
+			client := &memoryS3Client{
+				source:          &memoryRangeSource{content: content, eTag: uploadETag(content[1:], []int64{5 * testPartSize, 5 * testPartSize})},
+				uploadPartSizes: testdata.partSizes,
+				partHeadErr:     testdata.partHeadErr,
+			}
+
+			output, err := partedDownload(testLog, &s3RangeSource{client: client, bucket: "bucket", key: "key"}, destFile)
+
+			assert.NoError(t, err)
+			assert.True(t, output.IsUpdated)
+		})
+	}
+}
+
+func TestDownloadSettingsLimitsPartCount(t *testing.T) {
+	dir, _ := setupPartedDownload(t)
+	defer os.RemoveAll(dir)
+
+	concurrency, partSize := downloadSettings(20000 * testPartSize)
+
+	assert.Equal(t, 3, concurrency)
+	assert.Equal(t, int64(2*testPartSize), partSize)
+}
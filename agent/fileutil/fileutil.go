@@ -15,7 +15,6 @@
 package fileutil
 
 import (
-	"archive/zip"
 	"bytes"
 	"fmt"
 	"io"
@@ -47,6 +46,41 @@ type DiskSpaceInfo struct {
 	TotalBytes int64
 }
 
+// DiskSpaceMarginBytes is the disk space kept available in addition to the size of the files written, 50 MB
+const DiskSpaceMarginBytes int64 = 52428800
+
+var getDiskSpaceInfoOf = GetDiskSpaceInfoOf
+
+// CheckDiskSpace returns an error if the file system of the path doesn't have the size plus a margin available.
+// The check passes when the size is unknown (not positive) or the available space cannot be determined.
+func CheckDiskSpace(path string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	info, err := getDiskSpaceInfoOf(path)
+	if err != nil {
+		return nil
+	}
+	if required := size + DiskSpaceMarginBytes; info.AvailBytes < required {
+		return fmt.Errorf("insufficient disk space to write %v, %d MB available and %d MB required",
+			path, info.AvailBytes/(1024*1024), (required+1024*1024-1)/(1024*1024))
+	}
+	return nil
+}
+
+// existingParent returns the path, or its closest parent directory that exists
+func existingParent(path string) string {
+	path = filepath.Clean(path)
+	for !Exists(path) {
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+	return path
+}
+
 // DeleteFile deletes the specified file
 func DeleteFile(filepath string) (err error) {
 	return fs.Remove(filepath)
@@ -303,61 +337,5 @@ func isUnderDir(childPath, parentDirPath string) bool {
 // Unzip unzips the installation package (using platform agnostic zip functionality)
 // For platform specific implementation that uses tar.gz on Linux, use Uncompress
 func Unzip(src, dest string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := r.Close(); err != nil {
-			return
-		}
-	}()
-
-	os.MkdirAll(dest, appconfig.ReadWriteExecuteAccess)
-	// Closure to address file descriptors issue with all the deferred .Close() methods
-	extractAndWriteFile := func(f *zip.File) error {
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-		defer func() {
-			if err := rc.Close(); err != nil {
-				return
-			}
-		}()
-
-		path := filepath.Join(dest, f.Name)
-
-		if !isUnderDir(path, dest) {
-			return fmt.Errorf("%v attepts to place files outside %v subtree", f.Name, dest)
-		}
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.Mode())
-		} else {
-			os.MkdirAll(filepath.Dir(path), f.Mode())
-			f, err := os.OpenFile(path, appconfig.FileFlagsCreateOrTruncate, f.Mode())
-			if err != nil {
-				return err
-			}
-			defer func() {
-				if err := f.Close(); err != nil {
-					return
-				}
-			}()
-
-			_, err = io.Copy(f, rc)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	}
-	for _, f := range r.File {
-		err := extractAndWriteFile(f)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return extractZip(src, dest, ExtractOptions{})
 }
@@ -15,6 +15,7 @@ package fileutil
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -262,10 +263,43 @@ func (a ioUtilStub) ReadFile(filename string) ([]byte, error) {
 }
 
 func TestAppendToFile(t *testing.T) {
-	// Valid file
-	var file = "testdata/file.txt"
+	// Valid file, copied so that the test data is left unchanged
+	content, err := ioutil.ReadFile("testdata/file.txt")
+	assert.NoError(t, err)
+	dir, err := ioutil.TempDir("", "fileutil")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	var file = "file.txt"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), content, 0600))
+
 	// call method
-	filePath, err := AppendToFile("", file, " This is a sample text")
+	filePath, err := AppendToFile(dir, file, " This is a sample text")
 	assert.NoError(t, err, "expected no error")
-	fmt.Println(filePath)
+	appended, err := ioutil.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello World. This is a sample text", string(appended))
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	defer func() { getDiskSpaceInfoOf = GetDiskSpaceInfoOf }()
+	getDiskSpaceInfoOf = func(path string) (DiskSpaceInfo, error) {
+		return DiskSpaceInfo{AvailBytes: 100 * 1024 * 1024}, nil
+	}
+
+	assert.NoError(t, CheckDiskSpace("/var/lib/amazon/ssm/download/package.zip", 0))
+	assert.NoError(t, CheckDiskSpace("/var/lib/amazon/ssm/download/package.zip", 50*1024*1024))
+	err := CheckDiskSpace("/var/lib/amazon/ssm/download/package.zip", 50*1024*1024+1)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "100 MB available and 101 MB required")
+
+	getDiskSpaceInfoOf = func(path string) (DiskSpaceInfo, error) {
+		return DiskSpaceInfo{}, fmt.Errorf("statfs failed")
+	}
+	assert.NoError(t, CheckDiskSpace("/var/lib/amazon/ssm/download/package.zip", 50*1024*1024))
+}
+
+func TestGetDiskSpaceInfoOfMissingPath(t *testing.T) {
+	info, err := GetDiskSpaceInfoOf(filepath.Join(os.TempDir(), "missing", "dir", "file"))
+	assert.NoError(t, err)
+	assert.True(t, info.TotalBytes > 0)
 }
@@ -17,73 +17,35 @@
 package fileutil
 
 import (
-	"archive/tar"
-	"compress/gzip"
-	"fmt"
-	"io"
 	"os"
 	"syscall"
-
-	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 )
 
 // Uncompress untar the installation package
 func Uncompress(src, dest string) error {
-	file, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	gr, err := gzip.NewReader(file)
-	if err != nil {
-		return err
-	}
-	defer gr.Close()
-
-	os.MkdirAll(dest, appconfig.ReadWriteExecuteAccess)
-
-	tr := tar.NewReader(gr)
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
-		itemPath := dest + string(os.PathSeparator) + hdr.Name
-		if !isUnderDir(itemPath, dest) {
-			return fmt.Errorf("%v attepts to place files outside %v subtree", file.Name(), dest)
-		}
-		if hdr.FileInfo().IsDir() {
-			os.MkdirAll(itemPath, hdr.FileInfo().Mode())
-		} else {
-			fw, err := os.OpenFile(itemPath, appconfig.FileFlagsCreateOrTruncate, hdr.FileInfo().Mode())
-			if err != nil {
-				return err
-			}
-			defer fw.Close()
-			_, err = io.Copy(fw, tr)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+	return Extract(src, dest, ExtractOptions{})
 }
 
 // GetDiskSpaceInfo returns DiskSpaceInfo with available, free, and total bytes from system disk space
 func GetDiskSpaceInfo() (diskSpaceInfo DiskSpaceInfo, err error) {
-	var stat syscall.Statfs_t
 	var wd string
 
 	// get a rooted path name
 	if wd, err = os.Getwd(); err != nil {
 		return
 	}
+	return GetDiskSpaceInfoOf(wd)
+}
+
+// GetDiskSpaceInfoOf returns DiskSpaceInfo with available, free, and total bytes of the file system of the path,
+// which doesn't need to exist yet
+func GetDiskSpaceInfoOf(path string) (diskSpaceInfo DiskSpaceInfo, err error) {
+	var stat syscall.Statfs_t
 
 	// get filesystem statistics
-	syscall.Statfs(wd, &stat)
+	if err = syscall.Statfs(existingParent(path), &stat); err != nil {
+		return
+	}
 
 	// get block size
 	bSize := uint64(stat.Bsize)
@@ -32,22 +32,30 @@ func Uncompress(src, dest string) error {
 // GetDiskSpaceInfo returns available, free, and total bytes respectively from system disk space
 func GetDiskSpaceInfo() (diskSpaceInfo DiskSpaceInfo, err error) {
 	var wd string
-	var availBytes, totalBytes, freeBytes int64
 
 	// Get a rooted path name
 	if wd, err = os.Getwd(); err != nil {
 		return
 	}
+	return GetDiskSpaceInfoOf(wd)
+}
+
+// GetDiskSpaceInfoOf returns available, free, and total bytes of the volume of the path, which doesn't need to exist yet
+func GetDiskSpaceInfoOf(path string) (diskSpaceInfo DiskSpaceInfo, err error) {
+	var availBytes, totalBytes, freeBytes int64
 
 	// Load kernel32.dll and find GetDiskFreeSpaceEX function
 	getDiskFreeSpace := syscall.MustLoadDLL("kernel32.dll").MustFindProc("GetDiskFreeSpaceExW")
 
 	// Get the available bytes (for arguments, GetDiskFreeSpace function takes dir name, avail, total, and free respectively)
-	_, _, err = getDiskFreeSpace.Call(
-		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(wd))),
+	ret, _, callErr := getDiskFreeSpace.Call(
+		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(existingParent(path)))),
 		uintptr(unsafe.Pointer(&availBytes)),
 		uintptr(unsafe.Pointer(&totalBytes)),
 		uintptr(unsafe.Pointer(&freeBytes)))
+	if ret == 0 {
+		return diskSpaceInfo, callErr
+	}
 
 	return DiskSpaceInfo{
 		AvailBytes: availBytes,
@@ -0,0 +1,97 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// TempDir creates a new private temporary directory in dir (the system temp directory when empty).
+// It refuses to create the directory when dir or any of its parents could be modified by other users
+// or is a symbolic link that could be redirected, and makes sure only the owner can access the new directory.
+func TempDir(dir, prefix string) (name string, err error) {
+	if dir, err = secureTempParent(dir); err != nil {
+		return
+	}
+	if name, err = ioutil.TempDir(dir, prefix); err != nil {
+		return
+	}
+	if err = restrictAccess(name, true); err != nil {
+		os.RemoveAll(name)
+		return "", err
+	}
+	return
+}
+
+// TempFile creates a new temporary file in dir (the system temp directory when empty), opened for reading and writing.
+// The same checks as TempDir apply to dir and only the owner can access the new file.
+func TempFile(dir, prefix string) (f *os.File, err error) {
+	if dir, err = secureTempParent(dir); err != nil {
+		return
+	}
+	if f, err = ioutil.TempFile(dir, prefix); err != nil {
+		return
+	}
+	if err = restrictAccess(f.Name(), false); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return
+}
+
+// CheckSecureDirectory returns an error when the directory or one of its parents is writable by other users,
+// or is a symbolic link which is not owned by an administrator.
+func CheckSecureDirectory(dir string) (err error) {
+	if dir, err = filepath.Abs(dir); err != nil {
+		return
+	}
+	if err = checkSecurePath(dir); err != nil {
+		return
+	}
+
+	// the checked path may contain trusted links, the directories they point to need to be secure too
+	var resolved string
+	if resolved, err = filepath.EvalSymlinks(dir); err != nil {
+		return
+	}
+	if resolved != dir {
+		return checkSecurePath(resolved)
+	}
+	return
+}
+
+// secureTempParent returns the directory to create temporary files in after verifying it is secure.
+func secureTempParent(dir string) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := CheckSecureDirectory(dir); err != nil {
+		return "", fmt.Errorf("refusing to create temporary file in %v: %v", dir, err)
+	}
+	return dir, nil
+}
+
+// tempMode returns the permission of a new temporary directory or file
+func tempMode(isDir bool) os.FileMode {
+	if isDir {
+		return appconfig.ReadWriteExecuteAccess
+	}
+	return appconfig.ReadWriteAccess
+}
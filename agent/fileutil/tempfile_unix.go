@@ -0,0 +1,72 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// writableByOthers is the permission mask allowing users other than the owner to modify a directory
+const writableByOthers os.FileMode = 0022
+
+// checkSecurePath verifies every component of an absolute path, from the path up to the root.
+func checkSecurePath(path string) error {
+	euid := uint32(os.Geteuid())
+	for current := path; ; current = filepath.Dir(current) {
+		fi, err := os.Lstat(current)
+		if err != nil {
+			return err
+		}
+		owner := fi.Sys().(*syscall.Stat_t).Uid
+
+		if fi.Mode()&os.ModeSymlink != 0 {
+			// only administrators can create links in directories that passed the checks below
+			if owner != rootUid {
+				return fmt.Errorf("%v is a symbolic link owned by uid %v", current, owner)
+			}
+		} else {
+			if owner != rootUid && owner != euid {
+				return fmt.Errorf("%v is owned by uid %v", current, owner)
+			}
+			// world writable directories such as /tmp are safe only if the sticky bit prevents renaming other users' entries
+			if fi.Mode()&writableByOthers != 0 && fi.Mode()&os.ModeSticky == 0 {
+				return fmt.Errorf("%v is writable by other users", current)
+			}
+		}
+
+		if parent := filepath.Dir(current); parent == current {
+			return nil
+		}
+	}
+}
+
+// restrictAccess verifies the newly created path is a regular entry owned by the agent and restricts its mode.
+func restrictAccess(path string, isDir bool) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() != isDir || fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%v was replaced while being created", path)
+	}
+	if owner := fi.Sys().(*syscall.Stat_t).Uid; owner != uint32(os.Geteuid()) {
+		return fmt.Errorf("%v is owned by uid %v", path, owner)
+	}
+	return os.Chmod(path, tempMode(isDir))
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTempDirRestrictsAccess(t *testing.T) {
+	dir, err := TempDir("", "tempfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fi, err := os.Stat(dir)
+	assert.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+
+	f, err := TempFile(dir, "file")
+	assert.NoError(t, err)
+	defer f.Close()
+	fi, err = f.Stat()
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+}
+
+func TestTempDirRefusesWorldWritableParent(t *testing.T) {
+	parent, err := TempDir("", "tempfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(parent)
+
+	assert.NoError(t, os.Chmod(parent, 0777))
+	_, err = TempDir(parent, "child")
+	assert.Error(t, err)
+
+	// the sticky bit prevents other users from replacing entries, as for /tmp
+	assert.NoError(t, os.Chmod(parent, 0777|os.ModeSticky))
+	child, err := TempDir(parent, "child")
+	assert.NoError(t, err)
+	assert.True(t, Exists(child))
+}
+
+func TestTempDirRefusesSymlinkedParent(t *testing.T) {
+	parent, err := TempDir("", "tempfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(parent)
+
+	target := filepath.Join(parent, "target")
+	link := filepath.Join(parent, "link")
+	assert.NoError(t, os.Mkdir(target, 0700))
+	assert.NoError(t, os.Symlink(target, link))
+
+	if os.Geteuid() == 0 {
+		// links owned by root are trusted
+		_, err = TempDir(link, "child")
+		assert.NoError(t, err)
+		assert.NoError(t, os.Lchown(link, 1, 1))
+	}
+	_, err = TempDir(link, "child")
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkSecurePath verifies that no component of an absolute path is a symbolic link or junction.
+// Windows directories inherit their ACL from the parent, new entries are hardened by restrictAccess instead.
+func checkSecurePath(path string) error {
+	for current := path; ; current = filepath.Dir(current) {
+		fi, err := os.Lstat(current)
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%v is a symbolic link", current)
+		}
+
+		if parent := filepath.Dir(current); parent == current {
+			return nil
+		}
+	}
+}
+
+// restrictAccess verifies the newly created path is a regular entry and restricts it to administrators.
+func restrictAccess(path string, isDir bool) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() != isDir || fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%v was replaced while being created", path)
+	}
+	return Harden(path)
+}
@@ -64,6 +64,32 @@ func InstanceFingerprint() (string, error) {
 	return fingerprint, nil
 }
 
+// Regenerate replaces the fingerprint with a new one even if the hardware matches the saved information,
+// such as on VMs cloned from the same image. The instance must be registered again with the new fingerprint.
+func Regenerate() (string, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	savedHwInfo, err := fetch()
+	if err != nil {
+		return "", err
+	}
+
+	uuid.SwitchFormat(uuid.CleanHyphen)
+	updatedHwInfo := hwInfo{
+		Fingerprint:         uuid.NewV4().String(),
+		HardwareHash:        currentHwHash(),
+		SimilarityThreshold: similarityThreshold(savedHwInfo),
+	}
+	if err = save(updatedHwInfo); err != nil {
+		return "", fmt.Errorf("Unable to save the new fingerprint due to, %v", err)
+	}
+
+	fingerprint = updatedHwInfo.Fingerprint
+	loaded = true
+	return fingerprint, nil
+}
+
 func SetSimilarityThreshold(value int) (err error) {
 	if value < 1 || value > 100 { // zero not allowed
 		return fmt.Errorf("Invalid Similarity Threshold value of %v. Value must be between 0 and 100.", value)
@@ -95,10 +121,7 @@ func generateFingerprint() (string, error) {
 		return "", err
 	}
 
-	threshold := minimumMatchPercent
-	if savedHwInfo.SimilarityThreshold >= 0 {
-		threshold = savedHwInfo.SimilarityThreshold
-	}
+	threshold := similarityThreshold(savedHwInfo)
 
 	// check if this is the first time we are generating the fingerprint
 	// or if there is no match
@@ -121,6 +144,17 @@ func generateFingerprint() (string, error) {
 	return result, nil
 }
 
+// similarityThreshold returns the threshold configured in appconfig, else the threshold saved with the fingerprint
+func similarityThreshold(savedHwInfo hwInfo) int {
+	if config, err := loadAppConfig(false); err == nil && config.Agent.FingerprintSimilarityThreshold > 0 {
+		return config.Agent.FingerprintSimilarityThreshold
+	}
+	if savedHwInfo.SimilarityThreshold >= 0 {
+		return savedHwInfo.SimilarityThreshold
+	}
+	return minimumMatchPercent
+}
+
 func fetch() (hwInfo, error) {
 	savedHwInfo := hwInfo{}
 
@@ -206,15 +240,19 @@ func primaryIpInfo() (value string, err error) {
 	if err != nil {
 		return "", err
 	}
+	// the first IPv4 address which is not a loopback, or the first global IPv6 address on IPv6-only hosts
+	var ipv6 string
 	for _, address := range addrs {
-		// check the address type and if it is not a loopback then return it
 		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
 			if ipnet.IP.To4() != nil {
 				return ipnet.IP.String(), nil
 			}
+			if ipv6 == "" && ipnet.IP.IsGlobalUnicast() {
+				ipv6 = ipnet.IP.String()
+			}
 		}
 	}
-	return "", err
+	return ipv6, err
 }
 
 func macAddrInfo() (value string, err error) {
@@ -14,7 +14,13 @@
 // package fingerprint contains functions that helps identify an instance
 package fingerprint
 
-import "github.com/aws/amazon-ssm-agent/agent/vault/fsvault"
+import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/vault/fsvault"
+)
+
+// dependency for appconfig
+var loadAppConfig = appconfig.Config
 
 // dependency for vault
 var vault fpVault = &fpFsVault{}
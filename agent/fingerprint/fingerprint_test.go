@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -162,6 +163,74 @@ func TestGenerateFingerprint_ReturnSavedWhenMatched(t *testing.T) {
 	assert.Equal(t, sampleFingerprint, actual, "expected the instance to generate a fingerprint")
 }
 
+func TestGenerateFingerprint_UsesThresholdOfAppConfig(t *testing.T) {
+	currentHwHash = func() map[string]string {
+		return map[string]string{hardwareID: "hardware", ipAddressID: "10.0.0.2", "sample": "sample"}
+	}
+	saved := hwInfo{
+		Fingerprint:         sampleFingerprint,
+		HardwareHash:        map[string]string{hardwareID: "hardware", ipAddressID: "10.0.0.1", "sample": "sample"},
+		SimilarityThreshold: 40,
+	}
+	savedJson, _ := json.Marshal(saved)
+	vault = vaultStub{rKey: vaultKey, data: savedJson}
+	defer func() { loadAppConfig = appconfig.Config }()
+
+	// 2 out of 3 items matched, similar with the saved threshold
+	loadAppConfig = func(bool) (appconfig.SsmagentConfig, error) { return appconfig.SsmagentConfig{}, nil }
+	actual, err := generateFingerprint()
+	assert.NoError(t, err)
+	assert.Equal(t, sampleFingerprint, actual)
+
+	config := appconfig.SsmagentConfig{}
+	config.Agent.FingerprintSimilarityThreshold = 100
+	loadAppConfig = func(bool) (appconfig.SsmagentConfig, error) { return config, nil }
+	actual, err = generateFingerprint()
+	assert.NoError(t, err)
+	assert.NotEqual(t, sampleFingerprint, actual)
+}
+
+func TestRegenerate(t *testing.T) {
+	currentHwHash = func() map[string]string {
+		return map[string]string{"sample": "sample"}
+	}
+	saved := hwInfo{
+		Fingerprint:         sampleFingerprint,
+		HardwareHash:        currentHwHash(),
+		SimilarityThreshold: 60,
+	}
+	savedJson, _ := json.Marshal(saved)
+	stub := &recordingVaultStub{data: savedJson}
+	vault = stub
+	defer setLoaded(false)
+
+	actual, err := Regenerate()
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, sampleFingerprint, actual, "the fingerprint is replaced although the hardware matches")
+	var stored hwInfo
+	assert.NoError(t, json.Unmarshal(stub.data, &stored))
+	assert.Equal(t, actual, stored.Fingerprint)
+	assert.Equal(t, 60, stored.SimilarityThreshold)
+	current, err := InstanceFingerprint()
+	assert.NoError(t, err)
+	assert.Equal(t, actual, current)
+}
+
+// recordingVaultStub keeps the stored data
+type recordingVaultStub struct {
+	data []byte
+}
+
+func (v *recordingVaultStub) Store(key string, data []byte) error {
+	v.data = data
+	return nil
+}
+
+func (v *recordingVaultStub) Retrieve(key string) ([]byte, error) {
+	return v.data, nil
+}
+
 type vaultStub struct {
 	rKey string
 	data []byte
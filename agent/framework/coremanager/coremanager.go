@@ -35,6 +35,8 @@ import (
 const (
 	rebootPollingInterval = time.Second
 	hardStopTimeout       = time.Second * 5
+	// shutdownDrainTimeout is the time to cancel the documents still running after the grace period and send their status
+	shutdownDrainTimeout = time.Second * 20
 )
 
 // CoreManager encapsulates the logic for configuring, starting and stopping core modules
@@ -242,6 +244,11 @@ func (c *CoreManager) Stop() {
 	c.stopCoreModules(contracts.StopTypeHardStop)
 }
 
+// StopTimeout returns the longest time Stop waits for the core modules to stop
+func (c *CoreManager) StopTimeout() time.Duration {
+	return hardStopWaitTimeout(c.context.AppConfig())
+}
+
 // executeCoreModules launches all the core modules
 func (c *CoreManager) executeCoreModules() {
 	var wg sync.WaitGroup
@@ -272,11 +279,9 @@ func (c *CoreManager) stopCoreModules(stopType contracts.StopType) {
 	var wg sync.WaitGroup
 	l := len(c.coreModules)
 	for i := 0; i < l; i++ {
+		wg.Add(1)
 		go func(wgc *sync.WaitGroup, i int) {
-			if stopType == contracts.StopTypeSoftStop {
-				wgc.Add(1)
-				defer wgc.Done()
-			}
+			defer wgc.Done()
 
 			module := c.coreModules[i]
 			if err := module.ModuleRequestStop(stopType); err != nil {
@@ -292,9 +297,29 @@ func (c *CoreManager) stopCoreModules(stopType contracts.StopType) {
 	// use timeout for hardstop and return control
 	if stopType == contracts.StopTypeSoftStop {
 		wg.Wait()
-	} else {
-		time.Sleep(hardStopTimeout)
+		return
+	}
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		log.Info("core modules stopped")
+	case <-time.After(hardStopWaitTimeout(c.context.AppConfig())):
+		log.Warn("core modules did not stop in time")
+	}
+}
+
+// hardStopWaitTimeout returns how long a hard stop waits for the core modules, which includes the grace period
+// of the running documents and the time to cancel the ones which did not finish and send their final status
+func hardStopWaitTimeout(config appconfig.SsmagentConfig) time.Duration {
+	gracePeriod := time.Duration(config.Agent.ShutdownGracePeriodSeconds) * time.Second
+	if gracePeriod == 0 {
+		return hardStopTimeout
 	}
+	return hardStopTimeout + gracePeriod + shutdownDrainTimeout
 }
 
 // watchForReboot watches for reboot events and request core modules to stop when necessary
@@ -17,10 +17,14 @@ package coremodules
 import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/pruner"
+	"github.com/aws/amazon-ssm-agent/agent/framework/updatescheduler"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
+	"github.com/aws/amazon-ssm-agent/agent/pluginmetrics/publisher"
 	"github.com/aws/amazon-ssm-agent/agent/runcommand"
 	"github.com/aws/amazon-ssm-agent/agent/startup"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi/server"
 )
 
 // ModuleRegistry stores a set of core modules.
@@ -49,6 +53,10 @@ func loadCoreModules(context context.T) {
 	}
 
 	registeredCoreModules = append(registeredCoreModules, startup.NewProcessor(context))
+	registeredCoreModules = append(registeredCoreModules, pruner.NewPruner(context))
+	registeredCoreModules = append(registeredCoreModules, updatescheduler.NewUpdateScheduler(context))
+	registeredCoreModules = append(registeredCoreModules, server.NewServer(context))
+	registeredCoreModules = append(registeredCoreModules, publisher.NewPublisher(context))
 
 	// registering the long running plugin manager as a core module
 	manager.EnsureInitialization(context)
@@ -15,22 +15,17 @@
 package docmanager
 
 import (
-	"os"
 	"path"
 	"path/filepath"
-	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/atrest"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
-const (
-	maxLogFileDeletions int = 100
-)
-
 type validString func(string) bool
 type modifyString func(string) string
 
@@ -39,6 +34,8 @@ type DocumentMgr interface {
 	PersistDocumentState(log log.T, fileName, instanceID, locationFolder string, state contracts.DocumentState)
 	GetDocumentState(log log.T, fileName, instanceID, locationFolder string) contracts.DocumentState
 	RemoveDocumentState(log log.T, fileName, instanceID, locationFolder string)
+	RecordExecution(log log.T, state contracts.DocumentState, result contracts.DocumentResult, maxEntries int)
+	RecoverDocumentStates(log log.T, instanceID string)
 }
 
 //TODO use class lock instead of global lock?
@@ -58,35 +55,20 @@ func NewDocumentFileMgr(dataStorePath, rootDirName, stateLocation string) *Docum
 	}
 }
 
+// MoveDocumentState moves the document state to another location, the move is journaled so that it is completed
+// by RecoverDocumentStates if the agent stops in the middle of it
 func (d *DocumentFileMgr) MoveDocumentState(log log.T, fileName, instanceID, srcLocationFolder, dstLocationFolder string) {
-
-	absoluteSource := path.Join(d.dataStorePath,
-		instanceID,
-		d.rootDirName,
-		d.stateLocation,
-		srcLocationFolder)
-
-	absoluteDestination := path.Join(d.dataStorePath,
-		instanceID,
-		d.rootDirName,
-		d.stateLocation,
-		dstLocationFolder)
-
-	if s, err := fileutil.MoveFile(fileName, absoluteSource, absoluteDestination); s && err == nil {
+	if err := d.moveState(fileName, instanceID, srcLocationFolder, dstLocationFolder); err == nil {
 		log.Debugf("moved file %v from %v to %v successfully", fileName, srcLocationFolder, dstLocationFolder)
 	} else {
 		log.Debugf("moving file %v from %v to %v failed with error %v", fileName, srcLocationFolder, dstLocationFolder, err)
 	}
-
 }
 
+// PersistDocumentState saves the document state, the state on disk is replaced only once the new one is synced
 func (d *DocumentFileMgr) PersistDocumentState(log log.T, fileName, instanceID, locationFolder string, state contracts.DocumentState) {
 
-	absoluteFileName := path.Join(path.Join(d.dataStorePath,
-		instanceID,
-		d.rootDirName,
-		d.stateLocation,
-		locationFolder), fileName)
+	absoluteFileName := filepath.Join(d.stateDir(instanceID, locationFolder), fileName)
 
 	content, err := jsonutil.Marshal(state)
 	if err != nil {
@@ -96,7 +78,12 @@ func (d *DocumentFileMgr) PersistDocumentState(log log.T, fileName, instanceID,
 			log.Debugf("overwriting contents of %v", absoluteFileName)
 		}
 		log.Tracef("persisting interim state %v in file %v", jsonutil.Indent(content), absoluteFileName)
-		if s, err := fileutil.WriteIntoFileWithPermissions(absoluteFileName, jsonutil.Indent(content), os.FileMode(int(appconfig.ReadWriteAccess))); s && err == nil {
+		sealed, err := atrest.Seal([]byte(jsonutil.Indent(content)))
+		if err != nil {
+			log.Errorf("encountered error with message %v while encrypting interim state", err)
+			return
+		}
+		if err := writeFileSync(absoluteFileName, sealed, appconfig.ReadWriteAccess); err == nil {
 			log.Debugf("successfully persisted interim state in %v", locationFolder)
 		} else {
 			log.Debugf("persisting interim state in %v failed with error %v", locationFolder, err)
@@ -113,7 +100,7 @@ func (d *DocumentFileMgr) GetDocumentState(log log.T, fileName, instanceID, loca
 		locationFolder), fileName)
 
 	var commandState contracts.DocumentState
-	err := jsonutil.UnmarshalFile(absoluteFileName, &commandState)
+	err := atrest.UnmarshalFile(absoluteFileName, &commandState)
 	if err != nil {
 		log.Errorf("encountered error with message %v while reading Interim state of command from file - %v", err, fileName)
 	} else {
@@ -134,7 +121,7 @@ func (d *DocumentFileMgr) RemoveDocumentState(log log.T, commandID, instanceID,
 
 	absoluteFileName := docStateFileName(commandID, instanceID, locationFolder)
 
-	err := fileutil.DeleteFile(absoluteFileName)
+	err := removeFileSync(absoluteFileName)
 	if err != nil {
 		log.Errorf("encountered error %v while deleting file %v", err, absoluteFileName)
 	} else {
@@ -160,75 +147,6 @@ func orchestrationDir(instanceID, orchestrationRootDirName string) string {
 		orchestrationRootDirName)
 }
 
-// DeleteOldOrchestrationFolderLogs deletes the logs from document/state/completed and document/orchestration folders older than retention duration which satisfy the file name format
-func DeleteOldOrchestrationFolderLogs(log log.T, instanceID, orchestrationRootDirName string, retentionDurationHours int, isIntendedFileNameFormat validString) {
-	defer func() {
-		// recover in case the function panics
-		if msg := recover(); msg != nil {
-			log.Errorf("DeleteOldOrchestrationFolderLogs failed with message %v", msg)
-		}
-	}()
-
-	// Form the path for orchestration logs dir
-	orchestrationRootDir := orchestrationDir(instanceID, orchestrationRootDirName)
-
-	if !fileutil.Exists(orchestrationRootDir) {
-		log.Debugf("Completed log directory doesn't exist: %v", orchestrationRootDir)
-		return
-	}
-
-	outputFiles, err := fileutil.GetFileNames(orchestrationRootDir)
-	if err != nil {
-		log.Debugf("Failed to read files under %v", err)
-		return
-	}
-
-	if outputFiles == nil || len(outputFiles) == 0 {
-		log.Debugf("Completed log directory %v is invalid or empty", orchestrationRootDir)
-		return
-	}
-
-	// Go through all log files in the completed logs dir, delete max maxLogFileDeletions files and the corresponding dirs from orchestration folder
-	countOfDeletions := 0
-	for _, completedFile := range outputFiles {
-
-		outputLogFullPath := filepath.Join(orchestrationRootDir, completedFile)
-
-		//Checking for the file name format so that the function only deletes the files it is called to do. Also checking whether the file is beyond retention time.
-		if isIntendedFileNameFormat(completedFile) && isOlderThan(log, outputLogFullPath, retentionDurationHours) {
-			log.Debugf("Attempting Deletion of folder : %v", outputLogFullPath)
-
-			err := fileutil.DeleteDirectory(outputLogFullPath)
-			if err != nil {
-				log.Debugf("Error deleting dir %v: %v", outputLogFullPath, err)
-				continue
-			}
-
-			// Deletion of both document state and orchestration file was successful
-			countOfDeletions += 1
-			if countOfDeletions > maxLogFileDeletions {
-				break
-			}
-		}
-
-	}
-
-	log.Debugf("Completed DeleteOldOrchestrationFolderLogs")
-}
-
-// isOlderThan checks whether the file is older than the retention duration
-func isOlderThan(log log.T, fileFullPath string, retentionDurationHours int) bool {
-	modificationTime, err := fileutil.GetFileModificationTime(fileFullPath)
-
-	if err != nil {
-		log.Debugf("Failed to get modification time %v", err)
-		return false
-	}
-
-	// Check whether the current time is after modification time plus the retention duration
-	return modificationTime.Add(time.Hour * time.Duration(retentionDurationHours)).Before(time.Now())
-}
-
 // docStateFileName returns absolute filename where command states are persisted
 func docStateFileName(fileName, instanceID, locationFolder string) string {
 	return path.Join(DocumentStateDir(instanceID, locationFolder), fileName)
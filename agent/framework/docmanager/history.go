@@ -0,0 +1,186 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docmanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// historyLock serializes the updates of the execution history, which is shared by all the processors
+var historyLock sync.Mutex
+
+// ExecutionRecord is the entry of the local execution history describing an executed document
+type ExecutionRecord struct {
+	ExecutionID     string
+	DocumentName    string
+	DocumentVersion string `json:",omitempty"`
+	DocumentType    contracts.DocumentType
+	CommandID       string `json:",omitempty"`
+	AssociationID   string `json:",omitempty"`
+	// ParametersHash is the SHA-256 of the resolved parameters of the steps, used to compare executions
+	ParametersHash  string
+	Status          contracts.ResultStatus
+	StartDateTime   time.Time
+	EndDateTime     time.Time
+	DurationSeconds float64
+	Steps           []StepRecord `json:",omitempty"`
+}
+
+// StepRecord describes the execution of a step of a document
+type StepRecord struct {
+	ID              string
+	Name            string
+	Status          contracts.ResultStatus
+	ExitCode        int
+	StartDateTime   time.Time
+	EndDateTime     time.Time
+	DurationSeconds float64
+}
+
+// ExecutionHistoryPath returns the file holding the execution history of the given data store
+func ExecutionHistoryPath(dataStorePath string) string {
+	return filepath.Join(dataStorePath, appconfig.ExecutionHistoryFileName)
+}
+
+// RecordExecution adds the executed document to the execution history, which keeps the maxEntries most recent executions
+func (d *DocumentFileMgr) RecordExecution(log log.T, state contracts.DocumentState, result contracts.DocumentResult, maxEntries int) {
+	if maxEntries <= 0 {
+		return
+	}
+	historyLock.Lock()
+	defer historyLock.Unlock()
+
+	historyPath := ExecutionHistoryPath(d.dataStorePath)
+	history, err := LoadExecutionHistory(historyPath)
+	if err != nil {
+		log.Warnf("discarding unreadable execution history %v: %v", historyPath, err)
+		history = []ExecutionRecord{}
+	}
+
+	// the most recent execution comes first
+	history = append([]ExecutionRecord{NewExecutionRecord(state, result)}, history...)
+	if len(history) > maxEntries {
+		history = history[:maxEntries]
+	}
+
+	content, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		log.Errorf("failed to marshal the execution history: %v", err)
+		return
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(historyPath)); err != nil {
+		log.Errorf("failed to create directory %v: %v", filepath.Dir(historyPath), err)
+		return
+	}
+	if err = fileutil.WriteAllText(historyPath, string(content)); err != nil {
+		log.Errorf("failed to save the execution history to %v: %v", historyPath, err)
+	}
+}
+
+// LoadExecutionHistory reads the execution history, most recent execution first
+func LoadExecutionHistory(historyPath string) (history []ExecutionRecord, err error) {
+	history = []ExecutionRecord{}
+	if !fileutil.Exists(historyPath) {
+		return history, nil
+	}
+	err = jsonutil.UnmarshalFile(historyPath, &history)
+	return
+}
+
+// FindExecution returns the execution with the given id from the execution history, ids are not case sensitive
+func FindExecution(historyPath, executionID string) (record ExecutionRecord, err error) {
+	history, err := LoadExecutionHistory(historyPath)
+	if err != nil {
+		return
+	}
+	for _, record = range history {
+		if strings.EqualFold(record.ExecutionID, executionID) {
+			return record, nil
+		}
+	}
+	return ExecutionRecord{}, fmt.Errorf("execution %v not found in the execution history", executionID)
+}
+
+// NewExecutionRecord describes the executed document from its state and its final result
+func NewExecutionRecord(state contracts.DocumentState, result contracts.DocumentResult) ExecutionRecord {
+	info := state.DocumentInformation
+	record := ExecutionRecord{
+		ExecutionID:     info.DocumentID,
+		DocumentName:    info.DocumentName,
+		DocumentVersion: info.DocumentVersion,
+		DocumentType:    state.DocumentType,
+		CommandID:       info.CommandID,
+		AssociationID:   info.AssociationID,
+		ParametersHash:  ParametersHash(state.InstancePluginsInformation),
+		Status:          result.Status,
+	}
+
+	for _, plugin := range state.InstancePluginsInformation {
+		step := StepRecord{
+			ID:     plugin.Id,
+			Name:   plugin.Name,
+			Status: contracts.ResultStatusNotStarted,
+		}
+		if pluginResult, found := result.PluginResults[plugin.Id]; found && pluginResult != nil {
+			step.Status = pluginResult.Status
+			step.ExitCode = pluginResult.Code
+			step.StartDateTime = pluginResult.StartDateTime
+			step.EndDateTime = pluginResult.EndDateTime
+			step.DurationSeconds = durationSeconds(step.StartDateTime, step.EndDateTime)
+		}
+		if !step.StartDateTime.IsZero() && (record.StartDateTime.IsZero() || step.StartDateTime.Before(record.StartDateTime)) {
+			record.StartDateTime = step.StartDateTime
+		}
+		if step.EndDateTime.After(record.EndDateTime) {
+			record.EndDateTime = step.EndDateTime
+		}
+		record.Steps = append(record.Steps, step)
+	}
+	record.DurationSeconds = durationSeconds(record.StartDateTime, record.EndDateTime)
+	return record
+}
+
+// ParametersHash hashes the resolved parameters of the steps in order
+func ParametersHash(plugins []contracts.PluginState) string {
+	properties := make([]interface{}, 0, len(plugins))
+	for _, plugin := range plugins {
+		properties = append(properties, plugin.Configuration.Properties)
+	}
+	content, err := json.Marshal(properties)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func durationSeconds(start, end time.Time) float64 {
+	if start.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Seconds()
+}
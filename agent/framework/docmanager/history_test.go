@@ -0,0 +1,102 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func executedDocument(documentID string, properties interface{}) (contracts.DocumentState, contracts.DocumentResult) {
+	state := contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{DocumentID: documentID, CommandID: documentID, DocumentName: "AWS-RunShellScript"},
+		DocumentType:        contracts.SendCommand,
+		InstancePluginsInformation: []contracts.PluginState{
+			{Id: "step1", Name: "aws:runShellScript", Configuration: contracts.Configuration{Properties: properties}},
+			{Id: "step2", Name: "aws:runShellScript"},
+		},
+	}
+	start := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+	result := contracts.DocumentResult{
+		Status: contracts.ResultStatusFailed,
+		PluginResults: map[string]*contracts.PluginResult{
+			"step1": {Status: contracts.ResultStatusFailed, Code: 2, StartDateTime: start, EndDateTime: start.Add(90 * time.Second)},
+		},
+	}
+	return state, result
+}
+
+func TestRecordExecution(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	docMgr := NewDocumentFileMgr(dir, "document", "state")
+
+	for _, documentID := range []string{"command-1", "command-2", "command-3"} {
+		state, result := executedDocument(documentID, map[string]interface{}{"commands": documentID})
+		docMgr.RecordExecution(logger, state, result, 2)
+	}
+
+	history, err := LoadExecutionHistory(ExecutionHistoryPath(dir))
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "command-3", history[0].ExecutionID)
+	assert.Equal(t, "command-2", history[1].ExecutionID)
+	assert.NotEqual(t, history[0].ParametersHash, history[1].ParametersHash)
+
+	record := history[0]
+	assert.Equal(t, contracts.ResultStatusFailed, record.Status)
+	assert.Equal(t, float64(90), record.DurationSeconds)
+	assert.Len(t, record.Steps, 2)
+	assert.Equal(t, StepRecord{
+		ID:              "step1",
+		Name:            "aws:runShellScript",
+		Status:          contracts.ResultStatusFailed,
+		ExitCode:        2,
+		StartDateTime:   record.StartDateTime,
+		EndDateTime:     record.EndDateTime,
+		DurationSeconds: 90,
+	}, record.Steps[0])
+	assert.Equal(t, contracts.ResultStatusNotStarted, record.Steps[1].Status)
+
+	_, err = FindExecution(ExecutionHistoryPath(dir), "command-2")
+	assert.NoError(t, err)
+	_, err = FindExecution(ExecutionHistoryPath(dir), "command-1")
+	assert.Error(t, err)
+}
+
+func TestRecordExecutionDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	state, result := executedDocument("command-1", nil)
+	NewDocumentFileMgr(dir, "document", "state").RecordExecution(logger, state, result, 0)
+
+	history, err := LoadExecutionHistory(ExecutionHistoryPath(dir))
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestParametersHash(t *testing.T) {
+	first, _ := executedDocument("command-1", map[string]interface{}{"commands": []string{"ls"}})
+	second, _ := executedDocument("command-2", map[string]interface{}{"commands": []string{"ls"}})
+	assert.Equal(t, ParametersHash(first.InstancePluginsInformation), ParametersHash(second.InstancePluginsInformation))
+	assert.Len(t, ParametersHash(first.InstancePluginsInformation), 64)
+}
@@ -0,0 +1,220 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docmanager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/atrest"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// journalLocation is the folder of the state holding the moves which are not complete yet
+	journalLocation = "journal"
+	// tempFileSuffix marks the files being written, they replace the target once they are synced to disk
+	tempFileSuffix = ".tmp"
+	// stepJournalFileName is the file of the orchestration directory recording the steps which started
+	stepJournalFileName = ".stepjournal"
+)
+
+// stepJournalLock serializes the appends to the step journals, as steps can run concurrently
+var stepJournalLock sync.Mutex
+
+// moveIntent is the journal entry written before a document state is moved, so that an
+// interrupted move is completed the same way when the agent restarts
+type moveIntent struct {
+	FileName            string
+	SourceLocation      string
+	DestinationLocation string
+}
+
+// stateDir returns the folder of the given location of the document states of the instance
+func (d *DocumentFileMgr) stateDir(instanceID, locationFolder string) string {
+	return filepath.Join(d.dataStorePath, instanceID, d.rootDirName, d.stateLocation, locationFolder)
+}
+
+// moveState moves a document state between locations, the move is journaled first
+func (d *DocumentFileMgr) moveState(fileName, instanceID, srcLocationFolder, dstLocationFolder string) error {
+	intent := moveIntent{FileName: fileName, SourceLocation: srcLocationFolder, DestinationLocation: dstLocationFolder}
+	content, err := jsonutil.Marshal(intent)
+	if err != nil {
+		return err
+	}
+	journalDir := d.stateDir(instanceID, journalLocation)
+	if err = writeFileSync(filepath.Join(journalDir, fileName), []byte(content), appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to journal the move: %v", err)
+	}
+	if err = d.applyMove(instanceID, intent); err != nil {
+		return err
+	}
+	return removeFileSync(filepath.Join(journalDir, fileName))
+}
+
+// applyMove renames the document state and syncs both locations, it is idempotent
+func (d *DocumentFileMgr) applyMove(instanceID string, intent moveIntent) error {
+	srcDir := d.stateDir(instanceID, intent.SourceLocation)
+	dstDir := d.stateDir(instanceID, intent.DestinationLocation)
+	source := filepath.Join(srcDir, intent.FileName)
+	if !fileutil.Exists(source) {
+		// the move already happened before the agent stopped
+		return nil
+	}
+	if err := os.MkdirAll(dstDir, appconfig.ReadWriteExecuteAccess); err != nil {
+		return err
+	}
+	if err := os.Rename(source, filepath.Join(dstDir, intent.FileName)); err != nil {
+		return err
+	}
+	if err := syncDir(dstDir); err != nil {
+		return err
+	}
+	return syncDir(srcDir)
+}
+
+// RecoverDocumentStates brings the document states of the instance back to a consistent state after the agent stopped
+// unexpectedly: the journaled moves are completed, the partially written files are discarded and the states which
+// cannot be read are moved to the corrupt location, so that every document is either resumed or failed deterministically.
+func (d *DocumentFileMgr) RecoverDocumentStates(log log.T, instanceID string) {
+	journalDir := d.stateDir(instanceID, journalLocation)
+	entries, _ := fileutil.GetFileNames(journalDir)
+	for _, entry := range entries {
+		entryPath := filepath.Join(journalDir, entry)
+		if strings.HasSuffix(entry, tempFileSuffix) {
+			os.Remove(entryPath)
+			continue
+		}
+		var intent moveIntent
+		if err := jsonutil.UnmarshalFile(entryPath, &intent); err != nil {
+			log.Warnf("discarding unreadable journal entry %v: %v", entry, err)
+		} else if err = d.applyMove(instanceID, intent); err != nil {
+			log.Errorf("failed to complete the move of %v from %v to %v: %v", intent.FileName, intent.SourceLocation, intent.DestinationLocation, err)
+			continue
+		} else {
+			log.Infof("completed the interrupted move of %v from %v to %v", intent.FileName, intent.SourceLocation, intent.DestinationLocation)
+		}
+		if err := removeFileSync(entryPath); err != nil {
+			log.Errorf("failed to remove journal entry %v: %v", entry, err)
+		}
+	}
+
+	for _, location := range []string{appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent} {
+		dir := d.stateDir(instanceID, location)
+		fileNames, _ := fileutil.GetFileNames(dir)
+		for _, fileName := range fileNames {
+			if strings.HasSuffix(fileName, tempFileSuffix) {
+				log.Infof("discarding partially written document state %v", fileName)
+				os.Remove(filepath.Join(dir, fileName))
+				continue
+			}
+			var state contracts.DocumentState
+			if err := atrest.UnmarshalFile(filepath.Join(dir, fileName), &state); err != nil {
+				log.Errorf("document state %v cannot be read, moving it to %v: %v", fileName, appconfig.DefaultLocationOfCorrupt, err)
+				if err = d.moveState(fileName, instanceID, location, appconfig.DefaultLocationOfCorrupt); err != nil {
+					log.Errorf("failed to move document state %v: %v", fileName, err)
+				}
+			}
+		}
+	}
+}
+
+// writeFileSync replaces the file with the given content once the content is synced to disk,
+// the file holds either the previous or the new content if the agent stops while writing it
+func writeFileSync(filePath string, content []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(filePath)
+	if err = os.MkdirAll(dir, appconfig.ReadWriteExecuteAccess); err != nil {
+		return
+	}
+	tempPath := filePath + tempFileSuffix
+	file, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return
+	}
+	if _, err = file.Write(content); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return
+	}
+	if err = os.Rename(tempPath, filePath); err != nil {
+		return
+	}
+	return syncDir(dir)
+}
+
+// removeFileSync removes the file and syncs its directory
+func removeFileSync(filePath string) error {
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return syncDir(filepath.Dir(filePath))
+}
+
+// MarkStepStarted records in the orchestration directory of a document that a step is about to run.
+// The record is synced to disk before the step runs, so that a step interrupted by the agent stopping is never run twice.
+func MarkStepStarted(orchestrationDir, pluginID string) (err error) {
+	stepJournalLock.Lock()
+	defer stepJournalLock.Unlock()
+
+	if err = os.MkdirAll(orchestrationDir, appconfig.ReadWriteExecuteAccess); err != nil {
+		return
+	}
+	journalPath := filepath.Join(orchestrationDir, stepJournalFileName)
+	file, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, appconfig.ReadWriteAccess)
+	if err != nil {
+		return
+	}
+	if _, err = file.WriteString(pluginID + "\n"); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	return syncDir(orchestrationDir)
+}
+
+// IsStepStarted returns true if the step was recorded as started in the orchestration directory of the document
+func IsStepStarted(orchestrationDir, pluginID string) bool {
+	stepJournalLock.Lock()
+	defer stepJournalLock.Unlock()
+
+	content, err := ioutil.ReadFile(filepath.Join(orchestrationDir, stepJournalFileName))
+	if err != nil {
+		return false
+	}
+	records := strings.Split(string(content), "\n")
+	// the last element is either empty or a record which was not completely written, so the step did not start
+	for _, record := range records[:len(records)-1] {
+		if record == pluginID {
+			return true
+		}
+	}
+	return false
+}
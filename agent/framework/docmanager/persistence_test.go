@@ -0,0 +1,102 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const testInstanceID = "i-1234"
+
+func newTestDocumentFileMgr(t *testing.T) (*DocumentFileMgr, string) {
+	dir, err := ioutil.TempDir("", "docmanager")
+	assert.NoError(t, err)
+	return NewDocumentFileMgr(dir, "document", "state"), dir
+}
+
+func TestPersistAndMoveDocumentState(t *testing.T) {
+	docMgr, dir := newTestDocumentFileMgr(t)
+	defer os.RemoveAll(dir)
+	state := contracts.DocumentState{DocumentInformation: contracts.DocumentInfo{DocumentID: "command-1"}}
+
+	docMgr.PersistDocumentState(logger, "command-1", testInstanceID, appconfig.DefaultLocationOfPending, state)
+	docMgr.MoveDocumentState(logger, "command-1", testInstanceID, appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
+
+	assert.False(t, fileutil.Exists(filepath.Join(docMgr.stateDir(testInstanceID, appconfig.DefaultLocationOfPending), "command-1")))
+	assert.Equal(t, state, docMgr.GetDocumentState(logger, "command-1", testInstanceID, appconfig.DefaultLocationOfCurrent))
+
+	// neither temporary files nor journal entries are left behind
+	entries, _ := fileutil.GetFileNames(docMgr.stateDir(testInstanceID, appconfig.DefaultLocationOfCurrent))
+	assert.Equal(t, []string{"command-1"}, entries)
+	entries, _ = fileutil.GetFileNames(docMgr.stateDir(testInstanceID, journalLocation))
+	assert.Empty(t, entries)
+}
+
+func TestRecoverDocumentStates(t *testing.T) {
+	docMgr, dir := newTestDocumentFileMgr(t)
+	defer os.RemoveAll(dir)
+	pendingDir := docMgr.stateDir(testInstanceID, appconfig.DefaultLocationOfPending)
+	currentDir := docMgr.stateDir(testInstanceID, appconfig.DefaultLocationOfCurrent)
+	corruptDir := docMgr.stateDir(testInstanceID, appconfig.DefaultLocationOfCorrupt)
+	journalDir := docMgr.stateDir(testInstanceID, journalLocation)
+
+	// a move journaled but not done, a partially written state and a torn state
+	assert.NoError(t, os.MkdirAll(currentDir, appconfig.ReadWriteExecuteAccess))
+	docMgr.PersistDocumentState(logger, "moving", testInstanceID, appconfig.DefaultLocationOfPending, contracts.DocumentState{})
+	assert.NoError(t, writeFileSync(filepath.Join(journalDir, "moving"),
+		[]byte(`{"FileName":"moving","SourceLocation":"pending","DestinationLocation":"current"}`), appconfig.ReadWriteAccess))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(currentDir, "partial"+tempFileSuffix), []byte("{"), appconfig.ReadWriteAccess))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(currentDir, "torn"), []byte(`{"DocumentInformation":`), appconfig.ReadWriteAccess))
+
+	docMgr.RecoverDocumentStates(logger, testInstanceID)
+
+	assert.False(t, fileutil.Exists(filepath.Join(pendingDir, "moving")))
+	assert.True(t, fileutil.Exists(filepath.Join(currentDir, "moving")))
+	assert.False(t, fileutil.Exists(filepath.Join(currentDir, "partial"+tempFileSuffix)))
+	assert.False(t, fileutil.Exists(filepath.Join(currentDir, "torn")))
+	assert.True(t, fileutil.Exists(filepath.Join(corruptDir, "torn")))
+	entries, _ := fileutil.GetFileNames(journalDir)
+	assert.Empty(t, entries)
+
+	// recovering again changes nothing
+	docMgr.RecoverDocumentStates(logger, testInstanceID)
+	assert.True(t, fileutil.Exists(filepath.Join(currentDir, "moving")))
+}
+
+func TestStepJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stepjournal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	orchestrationDir := filepath.Join(dir, "command-1")
+
+	assert.False(t, IsStepStarted(orchestrationDir, "step1"))
+	assert.NoError(t, MarkStepStarted(orchestrationDir, "step1"))
+	assert.True(t, IsStepStarted(orchestrationDir, "step1"))
+	assert.False(t, IsStepStarted(orchestrationDir, "step2"))
+
+	// a record which was not completely written does not count
+	file, err := os.OpenFile(filepath.Join(orchestrationDir, stepJournalFileName), os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(t, err)
+	file.WriteString("step2")
+	file.Close()
+	assert.False(t, IsStepStarted(orchestrationDir, "step2"))
+}
@@ -0,0 +1,253 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docmanager
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+var (
+	runCommandLogPattern  = regexp.MustCompile("^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}$")
+	associationLogPattern = regexp.MustCompile("^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}\\.[0-9]{4}-[0-9]{2}-[0-9]{2}.*$")
+
+	// pruneLock makes sure only one pruning pass runs at a time
+	pruneLock sync.Mutex
+
+	// errFoundRetainedOutput stops walking an entry once a retained output is found
+	errFoundRetainedOutput = errors.New("found retained output")
+)
+
+// retainedOutputMarker is the file marking an orchestration folder which is never removed
+const retainedOutputMarker = ".retain"
+
+// RetentionPolicy defines which completed executions are kept on disk.
+// An entry is removed when it is older than MaxAgeHours or when more recent entries already fill MaxCount
+// or MaxTotalSizeMB, a zero value disables the corresponding limit.
+type RetentionPolicy struct {
+	MaxAgeHours    int
+	MaxCount       int
+	MaxTotalSizeMB int
+}
+
+// IsRunCommandLogFile checks whether the file name format satisfies the format for RunCommand generated log files
+func IsRunCommandLogFile(fileName string) bool {
+	return runCommandLogPattern.MatchString(fileName)
+}
+
+// IsAssociationLogFile checks whether the file name passed is of the format of Association Files
+func IsAssociationLogFile(fileName string) bool {
+	return associationLogPattern.MatchString(fileName)
+}
+
+// PruneExecutionHistory applies the configured retention policies to the orchestration folders and
+// the completed document states of the instance. Documents which are still pending or running are never removed.
+func PruneExecutionHistory(log log.T, instanceID string, config appconfig.SsmagentConfig) {
+	defer func() {
+		// recover in case the function panics
+		if msg := recover(); msg != nil {
+			log.Errorf("PruneExecutionHistory failed with message %v", msg)
+		}
+	}()
+
+	pruneLock.Lock()
+	defer pruneLock.Unlock()
+
+	inFlight := inFlightDocuments(instanceID)
+	orchestrationRootDir := orchestrationDir(instanceID, config.Agent.OrchestrationRootDir)
+
+	runCommandPolicy := RetentionPolicy{
+		MaxAgeHours: config.Ssm.RunCommandLogsRetentionDurationHours,
+		MaxCount:    config.Ssm.RunCommandLogsRetentionCount,
+	}
+	associationPolicy := RetentionPolicy{
+		MaxAgeHours: config.Ssm.AssociationLogsRetentionDurationHours,
+		MaxCount:    config.Ssm.AssociationLogsRetentionCount,
+	}
+	statePolicy := RetentionPolicy{
+		MaxAgeHours: config.Ssm.DocumentStateRetentionDurationHours,
+		MaxCount:    config.Ssm.DocumentStateRetentionCount,
+	}
+
+	sizePolicy := RetentionPolicy{
+		MaxTotalSizeMB: config.Ssm.OrchestrationRetentionMaxSizeMB,
+	}
+
+	removed := PruneDirectory(log, orchestrationRootDir, runCommandPolicy, IsRunCommandLogFile, inFlight)
+	removed += PruneDirectory(log, orchestrationRootDir, associationPolicy, IsAssociationLogFile, inFlight)
+	removed += PruneDirectory(log, orchestrationRootDir, sizePolicy, isOrchestrationFolder, inFlight)
+	for _, location := range []string{appconfig.DefaultLocationOfCompleted, appconfig.DefaultLocationOfCorrupt} {
+		removed += PruneDirectory(log, DocumentStateDir(instanceID, location), statePolicy, isAnyFile, inFlight)
+	}
+	log.Debugf("Completed PruneExecutionHistory, removed %v entries", removed)
+}
+
+// PruneDirectory removes the entries of dir matching isIntendedFileNameFormat which are not retained by the policy.
+// The most recently modified entries are retained first, entries for which isInFlight returns true are never removed
+// and only the parts of an entry which are not marked with MarkOutputRetained are removed.
+func PruneDirectory(log log.T, dir string, policy RetentionPolicy, isIntendedFileNameFormat validString, isInFlight validString) (removed int) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Debugf("Failed to read entries under %v: %v", dir, err)
+		return
+	}
+
+	var candidates []os.FileInfo
+	for _, entry := range entries {
+		if isIntendedFileNameFormat(entry.Name()) {
+			candidates = append(candidates, entry)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ModTime().After(candidates[j].ModTime())
+	})
+
+	oldest := time.Now().Add(-time.Hour * time.Duration(policy.MaxAgeHours))
+	maxTotalSize := int64(policy.MaxTotalSizeMB) * 1024 * 1024
+	var totalSize int64
+	for index, entry := range candidates {
+		entryPath := filepath.Join(dir, entry.Name())
+		var size int64
+		if maxTotalSize > 0 {
+			size = diskUsage(entryPath)
+		}
+		tooMany := policy.MaxCount > 0 && index >= policy.MaxCount
+		tooOld := policy.MaxAgeHours > 0 && entry.ModTime().Before(oldest)
+		tooBig := maxTotalSize > 0 && totalSize+size > maxTotalSize
+		if !tooMany && !tooOld && !tooBig {
+			totalSize += size
+			continue
+		}
+		if isInFlight(entry.Name()) {
+			log.Debugf("Retaining %v as the document is still in progress", entry.Name())
+			totalSize += size
+			continue
+		}
+
+		log.Debugf("Attempting Deletion of : %v", entryPath)
+		if retained, err := removeUnretained(entryPath); err != nil {
+			log.Debugf("Error deleting %v: %v", entryPath, err)
+			totalSize += diskUsage(entryPath)
+			continue
+		} else if retained {
+			log.Debugf("Retaining the outputs of %v marked to be kept", entryPath)
+			totalSize += diskUsage(entryPath)
+			continue
+		}
+		removed++
+	}
+	return
+}
+
+// MarkOutputRetained marks the orchestration folder of a plugin so that the pruning never removes it
+func MarkOutputRetained(orchestrationDir string) error {
+	if err := fileutil.MakeDirs(orchestrationDir); err != nil {
+		return err
+	}
+	return fileutil.WriteAllText(filepath.Join(orchestrationDir, retainedOutputMarker), "")
+}
+
+// removeUnretained removes the entry except for the folders marked as retained,
+// retained is true when part of the entry was kept
+func removeUnretained(entryPath string) (retained bool, err error) {
+	if !hasRetainedOutput(entryPath) {
+		return false, fileutil.DeleteDirectory(entryPath)
+	}
+	if fileutil.Exists(filepath.Join(entryPath, retainedOutputMarker)) {
+		return true, nil
+	}
+	children, err := ioutil.ReadDir(entryPath)
+	if err != nil {
+		return true, err
+	}
+	for _, child := range children {
+		if _, err = removeUnretained(filepath.Join(entryPath, child.Name())); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// hasRetainedOutput returns true if the entry contains a folder marked as retained
+func hasRetainedOutput(entryPath string) (found bool) {
+	filepath.Walk(entryPath, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && info.Name() == retainedOutputMarker {
+			found = true
+			return errFoundRetainedOutput
+		}
+		return nil
+	})
+	return
+}
+
+// diskUsage returns the total size of the files under path
+func diskUsage(path string) (size int64) {
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return
+}
+
+// inFlightDocuments returns a function reporting whether an entry belongs to a document
+// which is pending or currently executing.
+func inFlightDocuments(instanceID string) validString {
+	var documents []string
+	for _, location := range []string{appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent} {
+		if files, err := fileutil.GetFileNames(DocumentStateDir(instanceID, location)); err == nil {
+			documents = append(documents, files...)
+		}
+	}
+	return matchDocumentIDs(documents)
+}
+
+// matchDocumentIDs returns a function reporting whether an entry is named after one of the document ids.
+// The id of a run command is the command id, the id of an association is AssociationID.RunID
+// and its orchestration folder is named after the association id.
+func matchDocumentIDs(documentIDs []string) validString {
+	ids := make(map[string]bool)
+	for _, documentID := range documentIDs {
+		ids[documentID] = true
+		if index := strings.Index(documentID, "."); index > 0 {
+			ids[documentID[:index]] = true
+		}
+	}
+
+	return func(name string) bool {
+		return ids[name]
+	}
+}
+
+// isOrchestrationFolder accepts the orchestration folders of both run commands and associations
+func isOrchestrationFolder(name string) bool {
+	return IsRunCommandLogFile(name) || IsAssociationLogFile(name)
+}
+
+// isAnyFile accepts every entry name
+func isAnyFile(string) bool {
+	return true
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// createEntries creates one directory per name, the first name being the most recent one
+func createEntries(t *testing.T, names ...string) string {
+	dir, err := ioutil.TempDir("", "retention")
+	assert.NoError(t, err)
+	for i, name := range names {
+		entryPath := filepath.Join(dir, name)
+		assert.NoError(t, os.Mkdir(entryPath, 0700))
+		modTime := time.Now().Add(-time.Duration(i) * 24 * time.Hour)
+		assert.NoError(t, os.Chtimes(entryPath, modTime, modTime))
+	}
+	return dir
+}
+
+func remainingEntries(t *testing.T, dir string) (names []string) {
+	entries, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return
+}
+
+func noneInFlight(string) bool { return false }
+
+func TestPruneDirectoryByCount(t *testing.T) {
+	dir := createEntries(t, "a", "b", "c", "d")
+	defer os.RemoveAll(dir)
+
+	removed := PruneDirectory(logger, dir, RetentionPolicy{MaxCount: 2}, isAnyFile, noneInFlight)
+
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []string{"a", "b"}, remainingEntries(t, dir))
+}
+
+func TestPruneDirectoryByAge(t *testing.T) {
+	dir := createEntries(t, "a", "b", "c", "d")
+	defer os.RemoveAll(dir)
+
+	removed := PruneDirectory(logger, dir, RetentionPolicy{MaxAgeHours: 36}, isAnyFile, noneInFlight)
+
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []string{"a", "b"}, remainingEntries(t, dir))
+}
+
+func TestPruneDirectoryRetainsInFlightAndUnmatched(t *testing.T) {
+	dir := createEntries(t, "a", "b", "c", "other")
+	defer os.RemoveAll(dir)
+
+	isInFlight := func(name string) bool { return name == "b" }
+	isIntended := func(name string) bool { return name != "other" }
+	removed := PruneDirectory(logger, dir, RetentionPolicy{MaxCount: 1}, isIntended, isInFlight)
+
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []string{"a", "b", "other"}, remainingEntries(t, dir))
+}
+
+func TestMatchDocumentIDs(t *testing.T) {
+	isInFlight := matchDocumentIDs([]string{
+		"0d3d8fc2-4a6c-4b4c-8a4c-0d3d8fc24a6c",
+		"1e4e9ad3-5b7d-4c5d-9b5d-1e4e9ad35b7d.2018-01-01T00-00-00",
+	})
+
+	assert.True(t, isInFlight("0d3d8fc2-4a6c-4b4c-8a4c-0d3d8fc24a6c"))
+	assert.True(t, isInFlight("1e4e9ad3-5b7d-4c5d-9b5d-1e4e9ad35b7d.2018-01-01T00-00-00"))
+	assert.True(t, isInFlight("1e4e9ad3-5b7d-4c5d-9b5d-1e4e9ad35b7d"))
+	// the other executions of the association and the partial ids are not in flight
+	assert.False(t, isInFlight("1e4e9ad3-5b7d-4c5d-9b5d-1e4e9ad35b7d.2017-12-31T00-00-00"))
+	assert.False(t, isInFlight("0d3d8fc2"))
+	assert.False(t, isInFlight("0d3d8fc2-4a6c-4b4c-8a4c-0d3d8fc24a6c.2018-01-01T00-00-00"))
+	assert.False(t, isInFlight(""))
+}
+
+func TestLogFileNameFormats(t *testing.T) {
+	assert.True(t, IsRunCommandLogFile("0d3d8fc2-4a6c-4b4c-8a4c-0d3d8fc24a6c"))
+	assert.False(t, IsRunCommandLogFile("0d3d8fc2-4a6c-4b4c-8a4c-0d3d8fc24a6c.2018-01-01"))
+	assert.True(t, IsAssociationLogFile("0d3d8fc2-4a6c-4b4c-8a4c-0d3d8fc24a6c.2018-01-01T00-00-00"))
+}
+
+func TestPruneDirectoryBySize(t *testing.T) {
+	dir := createEntries(t, "a", "b", "c")
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a", "b", "c"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name, "stdout"), make([]byte, 600*1024), 0600))
+	}
+
+	removed := PruneDirectory(logger, dir, RetentionPolicy{MaxTotalSizeMB: 1}, isAnyFile, noneInFlight)
+
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []string{"a"}, remainingEntries(t, dir))
+}
+
+func TestPruneDirectoryKeepsRetainedOutputs(t *testing.T) {
+	dir := createEntries(t, "a", "b")
+	defer os.RemoveAll(dir)
+	retainedDir := filepath.Join(dir, "b", "awsrunShellScript")
+	assert.NoError(t, MarkOutputRetained(retainedDir))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b", "stdout"), []byte("output"), 0600))
+	oldTime := time.Now().Add(-24 * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "b"), oldTime, oldTime))
+
+	removed := PruneDirectory(logger, dir, RetentionPolicy{MaxCount: 1}, isAnyFile, noneInFlight)
+
+	assert.Equal(t, 0, removed)
+	assert.Equal(t, []string{"a", "b"}, remainingEntries(t, dir))
+	assert.Equal(t, []string{"awsrunShellScript"}, remainingEntries(t, filepath.Join(dir, "b")))
+}
@@ -15,8 +15,11 @@
 package basicexecuter
 
 import (
+	"fmt"
+
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/correlation"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 
 	"sync"
@@ -37,7 +40,7 @@ var pluginRunner = func(context context.T,
 	docState contracts.DocumentState,
 	resChan chan contracts.PluginResult,
 	cancelFlag task.CancelFlag) (pluginOutputs map[string]*contracts.PluginResult) {
-	return runpluginutil.RunPlugins(context, docState.InstancePluginsInformation, docState.IOConfig, runpluginutil.SSMPluginRegistry, resChan, cancelFlag)
+	return runpluginutil.RunPluginsConcurrently(context, docState.InstancePluginsInformation, docState.IOConfig, runpluginutil.SSMPluginRegistry, resChan, cancelFlag, docState.MaxConcurrentSteps)
 
 }
 
@@ -51,6 +54,10 @@ func run(context context.T,
 		}
 	}()
 	docState := docStore.Load()
+	correlationID := docState.DocumentInformation.CorrelationID
+	if correlationID != "" {
+		context = context.With(correlation.LogContext(correlationID))
+	}
 	//document information summary
 	messageID := docState.DocumentInformation.MessageID
 	associationID := docState.DocumentInformation.AssociationID
@@ -83,9 +90,12 @@ func run(context context.T,
 				NPlugins:        nPlugins,
 				DocumentName:    documentName,
 				DocumentVersion: documentVersion,
+				CorrelationID:   correlationID,
 			}
 			resChan <- docResult
 			contracts.UpdateDocState(&docResult, state)
+			// save the result of each step, so that a completed step is not run again if the agent stops
+			docStore.Save(*state)
 		}
 	}(&docState)
 
@@ -95,8 +105,19 @@ func run(context context.T,
 	wg.Wait()
 	pluginOutputContent, _ := jsonutil.Marshal(outputs)
 	context.Log().Debugf("Plugin outputs %v", jsonutil.Indent(pluginOutputContent))
-	//send DocLevel response
 	status, _, _ := contracts.DocumentResultAggregator(context.Log(), "", outputs)
+	if cancelFlag.ShutDown() && len(outputs) < nPlugins && !status.IsReboot() {
+		// the agent stopped before all the plugins ran, the document stays in progress and resumes once it starts again
+		context.Log().Infof("document %v did not complete before the agent stopped, saving its state", messageID)
+		docStore.Save(docState)
+		close(resChan)
+		return
+	}
+	if status == contracts.ResultStatusSuccessAndReboot {
+		// the reboot is counted in the state saved below, before the processor reads it to resume the document
+		status, outputs = countDocumentReboot(context, &docState, status, outputs)
+	}
+	//send DocLevel response
 	result := contracts.DocumentResult{
 		Status:          status,
 		PluginResults:   outputs,
@@ -106,6 +127,7 @@ func run(context context.T,
 		NPlugins:        nPlugins,
 		DocumentName:    documentName,
 		DocumentVersion: documentVersion,
+		CorrelationID:   correlationID,
 	}
 	resChan <- result
 	docState.DocumentInformation.DocumentStatus = status
@@ -115,6 +137,41 @@ func run(context context.T,
 	close(resChan)
 }
 
+// countDocumentReboot counts the reboot requested by a document so that it resumes after the restart,
+// the document and its rebooting steps are failed instead once it requested more reboots than allowed
+func countDocumentReboot(context context.T,
+	docState *contracts.DocumentState,
+	status contracts.ResultStatus,
+	outputs map[string]*contracts.PluginResult) (contracts.ResultStatus, map[string]*contracts.PluginResult) {
+	log := context.Log()
+	maxReboots := context.AppConfig().Ssm.MaxDocumentReboots
+	messageID := docState.DocumentInformation.MessageID
+	docState.DocumentInformation.RebootCount++
+	if docState.DocumentInformation.RebootCount <= maxReboots {
+		log.Infof("document %v requested reboot %v of %v", messageID, docState.DocumentInformation.RebootCount, maxReboots)
+		return status, outputs
+	}
+
+	log.Errorf("document %v requested more than %v reboots, failing it", messageID, maxReboots)
+	failedOutputs := make(map[string]*contracts.PluginResult, len(outputs))
+	for pluginID, pluginRes := range outputs {
+		if pluginRes != nil && pluginRes.Status.IsReboot() {
+			failed := *pluginRes
+			failed.Status = contracts.ResultStatusFailed
+			failed.Code = 1
+			failed.Output = fmt.Sprintf("Step requested a reboot after the document reached the maximum of %v reboots", maxReboots)
+			pluginRes = &failed
+			for i := range docState.InstancePluginsInformation {
+				if docState.InstancePluginsInformation[i].Id == pluginID {
+					docState.InstancePluginsInformation[i].Result = failed
+				}
+			}
+		}
+		failedOutputs[pluginID] = pluginRes
+	}
+	return contracts.ResultStatusFailed, failedOutputs
+}
+
 // NewBasicExecuter returns a pointer that impl the Executer interface
 // using a pointer so that it can be shared among multiple threads(go-routines)
 func NewBasicExecuter(context context.T) *BasicExecuter {
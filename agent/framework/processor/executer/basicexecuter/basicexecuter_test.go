@@ -17,12 +17,14 @@ package basicexecuter
 import (
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	executermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 var logger = log.NewMockLog()
@@ -145,3 +147,103 @@ func testBasicExecuter(t *testing.T, testCase TestCase) {
 	dataStoreMock.AssertExpectations(t)
 
 }
+
+// TestBasicExecuterShutdown tests that a document which did not complete before the agent stopped
+// is saved without sending its final response.
+func TestBasicExecuterShutdown(t *testing.T) {
+	docState := contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{MessageID: "MessageID"},
+		DocumentType:        "SendCommand",
+		InstancePluginsInformation: []contracts.PluginState{
+			{Name: "aws:runScript", Id: "plugin1"},
+			{Name: "aws:runScript", Id: "plugin2"},
+		},
+	}
+	result := contracts.PluginResult{
+		PluginID:   "plugin1",
+		PluginName: "aws:runScript",
+		Status:     contracts.ResultStatusSuccess,
+	}
+	dataStoreMock := new(executermock.MockDocumentStore)
+	dataStoreMock.On("Load").Return(docState)
+	dataStoreMock.On("Save", mock.AnythingOfType("contracts.DocumentState")).Return()
+	pluginRunner = func(context context.T,
+		docState contracts.DocumentState,
+		resChan chan contracts.PluginResult,
+		cancelFlag task.CancelFlag) map[string]*contracts.PluginResult {
+		resChan <- result
+		cancelFlag.Set(task.ShutDown)
+		return map[string]*contracts.PluginResult{"plugin1": &result}
+	}
+
+	e := NewBasicExecuter(context.NewMockDefault())
+	var responses []contracts.DocumentResult
+	for res := range e.Run(task.NewChanneledCancelFlag(), dataStoreMock) {
+		responses = append(responses, res)
+	}
+
+	assert.Len(t, responses, 1)
+	assert.Equal(t, "plugin1", responses[0].LastPlugin)
+	saved := dataStoreMock.Calls[len(dataStoreMock.Calls)-1].Arguments.Get(0).(contracts.DocumentState)
+	assert.Equal(t, contracts.ResultStatusSuccess, saved.InstancePluginsInformation[0].Result.Status)
+	assert.Equal(t, contracts.ResultStatusInProgress, saved.DocumentInformation.DocumentStatus)
+}
+
+// TestBasicExecuterCountsReboot tests that the reboot requested by a document is counted in the state saved
+// with its final response, and that the document fails once it requested more reboots than allowed.
+func TestBasicExecuterCountsReboot(t *testing.T) {
+	testCases := []struct {
+		name           string
+		rebootCount    int
+		expectedStatus contracts.ResultStatus
+	}{
+		{"reboot allowed", 1, contracts.ResultStatusSuccessAndReboot},
+		{"maximum reboots reached", 2, contracts.ResultStatusFailed},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := appconfig.SsmagentConfig{}
+			config.Ssm.MaxDocumentReboots = 2
+			ctx := new(context.Mock)
+			ctx.On("Log").Return(logger)
+			ctx.On("AppConfig").Return(config)
+			ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+
+			docState := contracts.DocumentState{
+				DocumentInformation:        contracts.DocumentInfo{MessageID: "MessageID", RebootCount: testCase.rebootCount},
+				DocumentType:               "SendCommand",
+				InstancePluginsInformation: []contracts.PluginState{{Name: "aws:applications", Id: "install"}},
+			}
+			result := contracts.PluginResult{
+				PluginID:   "install",
+				PluginName: "aws:applications",
+				Status:     contracts.ResultStatusPassedAndReboot,
+			}
+			dataStoreMock := new(executermock.MockDocumentStore)
+			dataStoreMock.On("Load").Return(docState)
+			dataStoreMock.On("Save", mock.AnythingOfType("contracts.DocumentState")).Return()
+			pluginRunner = func(context context.T,
+				docState contracts.DocumentState,
+				resChan chan contracts.PluginResult,
+				cancelFlag task.CancelFlag) map[string]*contracts.PluginResult {
+				resChan <- result
+				return map[string]*contracts.PluginResult{"install": &result}
+			}
+
+			var final contracts.DocumentResult
+			for res := range NewBasicExecuter(ctx).Run(task.NewChanneledCancelFlag(), dataStoreMock) {
+				final = res
+			}
+
+			assert.Equal(t, testCase.expectedStatus, final.Status)
+			saved := dataStoreMock.Calls[len(dataStoreMock.Calls)-1].Arguments.Get(0).(contracts.DocumentState)
+			assert.Equal(t, testCase.rebootCount+1, saved.DocumentInformation.RebootCount)
+			assert.Equal(t, testCase.expectedStatus, saved.DocumentInformation.DocumentStatus)
+			if testCase.expectedStatus == contracts.ResultStatusFailed {
+				assert.Equal(t, contracts.ResultStatusFailed, final.PluginResults["install"].Status)
+				assert.Equal(t, 1, final.PluginResults["install"].Code)
+				assert.Equal(t, contracts.ResultStatusFailed, saved.InstancePluginsInformation[0].Result.Status)
+			}
+		})
+	}
+}
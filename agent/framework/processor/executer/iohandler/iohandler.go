@@ -16,14 +16,19 @@ package iohandler
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redact"
 )
 
 const (
@@ -33,6 +38,12 @@ const (
 	truncateOut = "\n---Output truncated---"
 	// truncateError represents the string appended when error is truncated
 	truncateError = "\n---Error truncated----"
+	// outputErrorTitle separates stdout from stderr in the output
+	outputErrorTitle = "\n----------ERROR-------\n"
+	// fullOutputTitle introduces the location of the complete output when the output was truncated
+	fullOutputTitle = "\n----------FULL OUTPUT-------\n"
+	// correlationIDResultField is the field of the plugin result holding the correlation ID of the step
+	correlationIDResultField = "CorrelationId"
 )
 
 // PluginConfig is used for initializing plugins with default values
@@ -41,6 +52,7 @@ type PluginConfig struct {
 	StderrFileName        string
 	StdoutConsoleFileName string
 	StderrConsoleFileName string
+	ResultFileName        string
 	MaxStdoutLength       int
 	MaxStderrLength       int
 	OutputTruncatedSuffix string
@@ -53,6 +65,7 @@ func DefaultOutputConfig() PluginConfig {
 		StderrFileName:        "stderr",
 		StdoutConsoleFileName: "stdoutConsole",
 		StderrConsoleFileName: "stderrConsole",
+		ResultFileName:        "result.json",
 		MaxStdoutLength:       24000,
 		MaxStderrLength:       8000,
 		OutputTruncatedSuffix: "--output truncated--",
@@ -69,6 +82,7 @@ type IOHandler interface {
 	MarkAsSucceeded()
 	MarkAsInProgress()
 	MarkAsSuccessWithReboot()
+	MarkAsPassedAndReboot()
 	MarkAsCancelled()
 	MarkAsShutdown()
 
@@ -85,12 +99,14 @@ type IOHandler interface {
 	GetStdoutWriter() multiwriter.DocumentIOMultiWriter
 	GetStderrWriter() multiwriter.DocumentIOMultiWriter
 	GetIOConfig() contracts.IOConfiguration
+	GetResult() map[string]interface{}
 
 	SetStatus(contracts.ResultStatus)
 	SetExitCode(int)
 	SetOutput(interface{})
 	SetStdout(string)
 	SetStderr(string)
+	SetResultField(string, interface{})
 }
 
 // DefaultIOHandler is used for writing output by the plugins
@@ -101,12 +117,21 @@ type DefaultIOHandler struct {
 	stdout   string
 	stderr   string
 	ioConfig contracts.IOConfiguration
+	// s3KeyPrefix is the location of the output files in the output bucket
+	s3KeyPrefix string
 	//refreshassociation and invoker write a different output rather than merging stdout and stderr
 	output interface{}
+	// result is the machine-readable outcome of the plugin
+	result map[string]interface{}
+	// outputDirectory is the location of the output files of the plugin
+	outputDirectory string
 
 	// List of Writers attached to the IOHandler instance
 	StdoutWriter multiwriter.DocumentIOMultiWriter
 	StderrWriter multiwriter.DocumentIOMultiWriter
+
+	// redacted are the sensitive values masked before the output reaches the output modules
+	redacted redact.Values
 }
 
 // NewDefaultIOHandler returns a new instance of the IOHandler
@@ -118,6 +143,11 @@ func NewDefaultIOHandler(log log.T, ioConfig contracts.IOConfiguration) *Default
 	return out
 }
 
+// SetRedactedValues sets the sensitive values to mask in the output, it must be called before Init
+func (out *DefaultIOHandler) SetRedactedValues(values redact.Values) {
+	out.redacted = values
+}
+
 // Init initializes the plugin output object by creating the necessary writers
 func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 
@@ -129,6 +159,8 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		fullPath = fileutil.BuildPath(fullPath, element)
 		s3KeyPrefix = fileutil.BuildS3Path(s3KeyPrefix, element)
 	}
+	out.s3KeyPrefix = s3KeyPrefix
+	out.outputDirectory = fullPath
 
 	// Initialize file output module
 	stdoutFile := iomodule.File{
@@ -136,6 +168,8 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		OrchestrationDirectory: fullPath,
 		OutputS3BucketName:     out.ioConfig.OutputS3BucketName,
 		OutputS3KeyPrefix:      s3KeyPrefix,
+		OutputS3KmsKeyId:       out.ioConfig.OutputS3KmsKeyId,
+		CorrelationID:          out.ioConfig.CorrelationID,
 	}
 
 	// Initialize console output module
@@ -157,6 +191,8 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		OrchestrationDirectory: fullPath,
 		OutputS3BucketName:     out.ioConfig.OutputS3BucketName,
 		OutputS3KeyPrefix:      s3KeyPrefix,
+		OutputS3KmsKeyId:       out.ioConfig.OutputS3KmsKeyId,
+		CorrelationID:          out.ioConfig.CorrelationID,
 	}
 
 	// Initialize console error module
@@ -184,6 +220,9 @@ func (out *DefaultIOHandler) RegisterOutputSource(log log.T, multiWriter multiwr
 	for _, module := range IOModules {
 		r, w := io.Pipe()
 		multiWriter.AddWriter(w)
+		if len(out.redacted) > 0 {
+			r = out.redactedPipe(r)
+		}
 		// Run the reader for each module
 		log.Debug("Starting a new stream reader go routing")
 		go func(module iomodule.IOModule, r *io.PipeReader) {
@@ -195,6 +234,22 @@ func (out *DefaultIOHandler) RegisterOutputSource(log log.T, multiWriter multiwr
 	return
 }
 
+// redactedPipe returns a pipe with the content of the reader where the sensitive values are masked
+func (out *DefaultIOHandler) redactedPipe(r *io.PipeReader) *io.PipeReader {
+	redactedReader, redactedWriter := io.Pipe()
+	go func() {
+		writer := redact.NewWriter(redactedWriter, out.redacted)
+		_, err := io.Copy(writer, r)
+		if err == nil {
+			err = writer.Close()
+		}
+		// stop the writes of the multi-writer if the module stopped reading
+		r.CloseWithError(err)
+		redactedWriter.CloseWithError(err)
+	}()
+	return redactedReader
+}
+
 // Close closes all the attached writers.
 func (out *DefaultIOHandler) Close(log log.T) {
 	log.Debug("IOHandler closing all subscribed writers.")
@@ -205,11 +260,76 @@ func (out *DefaultIOHandler) Close(log log.T) {
 	if out.StderrWriter != nil {
 		out.StderrWriter.Close()
 	}
+
+	if len(out.result) > 0 && out.outputDirectory != "" {
+		out.writeResult(log)
+	}
+}
+
+// writeResult writes the result of the plugin as json next to its output files and uploads it to s3
+func (out *DefaultIOHandler) writeResult(log log.T) {
+	result := out.result
+	if out.ioConfig.CorrelationID != "" {
+		result = make(map[string]interface{}, len(out.result)+1)
+		for name, value := range out.result {
+			result[name] = value
+		}
+		result[correlationIDResultField] = out.ioConfig.CorrelationID
+	}
+	content, err := json.Marshal(result)
+	if err != nil {
+		log.Errorf("Failed to marshal the plugin result: %v", err)
+		return
+	}
+	content = out.redacted.Bytes(content)
+
+	resultFile := iomodule.File{
+		FileName:               DefaultOutputConfig().ResultFileName,
+		OrchestrationDirectory: out.outputDirectory,
+		OutputS3BucketName:     out.ioConfig.OutputS3BucketName,
+		OutputS3KeyPrefix:      out.s3KeyPrefix,
+		OutputS3KmsKeyId:       out.ioConfig.OutputS3KmsKeyId,
+		CorrelationID:          out.ioConfig.CorrelationID,
+	}
+	// the file module appends, replace the result written by an earlier run of the plugin
+	if err := os.Remove(filepath.Join(out.outputDirectory, resultFile.FileName)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to remove the previous plugin result: %v", err)
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		_, err := w.Write(content)
+		w.CloseWithError(err)
+	}()
+	resultFile.Read(log, r)
 }
 
 // String returns the output by concatenating stdout and stderr
+// When the output is truncated and uploaded to S3, the location of the complete output is appended.
 func (out DefaultIOHandler) String() (response string) {
-	return TruncateOutput(out.stdout, out.stderr, MaximumPluginOutputSize)
+	if out.ioConfig.OutputS3BucketName == "" || outputFits(out.stdout, out.stderr, MaximumPluginOutputSize) {
+		return TruncateOutput(out.stdout, out.stderr, MaximumPluginOutputSize)
+	}
+	pointer := fullOutputTitle + out.s3OutputLocation()
+	return TruncateOutput(out.stdout, out.stderr, MaximumPluginOutputSize-len(pointer)) + pointer
+}
+
+// s3OutputLocation returns the S3 urls of the uploaded stdout and stderr files.
+func (out DefaultIOHandler) s3OutputLocation() string {
+	pluginConfig := DefaultOutputConfig()
+	s3KeyPrefix := out.s3KeyPrefix
+	if s3KeyPrefix == "" {
+		s3KeyPrefix = out.ioConfig.OutputS3KeyPrefix
+	}
+
+	var locations []string
+	if len(out.stdout) > 0 {
+		locations = append(locations, fmt.Sprintf("s3://%v/%v", out.ioConfig.OutputS3BucketName, fileutil.BuildS3Path(s3KeyPrefix, pluginConfig.StdoutFileName)))
+	}
+	if len(out.stderr) > 0 {
+		locations = append(locations, fmt.Sprintf("s3://%v/%v", out.ioConfig.OutputS3BucketName, fileutil.BuildS3Path(s3KeyPrefix, pluginConfig.StderrFileName)))
+	}
+	return strings.Join(locations, "\n")
 }
 
 // GetOutput returns the output to be appended to the response
@@ -246,6 +366,11 @@ func (out DefaultIOHandler) GetIOConfig() contracts.IOConfiguration {
 	return out.ioConfig
 }
 
+// GetResult returns the machine-readable result of the plugin
+func (out DefaultIOHandler) GetResult() map[string]interface{} {
+	return out.result
+}
+
 // GetStdoutWriter returns the stdout writer
 func (out DefaultIOHandler) GetStdoutWriter() multiwriter.DocumentIOMultiWriter {
 	return out.StdoutWriter
@@ -281,6 +406,14 @@ func (out *DefaultIOHandler) SetOutput(output interface{}) {
 	out.output = output
 }
 
+// SetResultField sets a field of the machine-readable result of the plugin
+func (out *DefaultIOHandler) SetResultField(name string, value interface{}) {
+	if out.result == nil {
+		out.result = make(map[string]interface{})
+	}
+	out.result[name] = value
+}
+
 // Merge plugin output objects
 func (out *DefaultIOHandler) Merge(log log.T, mergeOutput *DefaultIOHandler) {
 
@@ -304,6 +437,11 @@ func (out *DefaultIOHandler) Merge(log log.T, mergeOutput *DefaultIOHandler) {
 		out.ExitCode = mergeOutput.GetExitCode()
 	}
 	out.Status = contracts.MergeResultStatus(out.Status, mergeOutput.GetStatus())
+
+	// Fields reported by a later property set override the earlier ones
+	for name, value := range mergeOutput.GetResult() {
+		out.SetResultField(name, value)
+	}
 }
 
 // MarkAsFailed Failed marks plugin as Failed
@@ -336,6 +474,12 @@ func (out *DefaultIOHandler) MarkAsSuccessWithReboot() {
 	out.Status = contracts.ResultStatusSuccessAndReboot
 }
 
+// MarkAsPassedAndReboot marks plugin as completed and requests a reboot before the next step runs.
+func (out *DefaultIOHandler) MarkAsPassedAndReboot() {
+	out.ExitCode = 0
+	out.Status = contracts.ResultStatusPassedAndReboot
+}
+
 // MarkAsCancelled marks a plugin as Cancelled.
 func (out *DefaultIOHandler) MarkAsCancelled() {
 	out.ExitCode = 1
@@ -357,6 +501,7 @@ func (out *DefaultIOHandler) AppendInfo(message string) {
 		out.StdoutWriter.WriteString(message)
 	} else {
 		// Write to stdout if the writer is not defined.
+		message = out.redacted.String(message)
 		if len(out.stdout) > 0 {
 			out.stdout = fmt.Sprintf("%v\n%v", out.stdout, message)
 		} else {
@@ -382,6 +527,7 @@ func (out *DefaultIOHandler) AppendError(message string) {
 		out.StderrWriter.WriteString(message)
 	} else {
 		// Write to stderr if the writer is not defined.
+		message = out.redacted.String(message)
 		if len(out.stderr) > 0 {
 			out.stderr = fmt.Sprintf("%v\n%v", out.stderr, message)
 		} else {
@@ -407,7 +553,7 @@ func TruncateOutput(stdout string, stderr string, capacity int) (response string
 	errorTitle := ""
 	lenErrorTitle := 0
 	if errorSize > 0 {
-		errorTitle = "\n----------ERROR-------\n"
+		errorTitle = outputErrorTitle
 		lenErrorTitle = len(errorTitle)
 	}
 
@@ -415,7 +561,7 @@ func TruncateOutput(stdout string, stderr string, capacity int) (response string
 	availableSpace := capacity - lenErrorTitle
 
 	// all fits within availableSpace
-	if (outputSize + errorSize) < availableSpace {
+	if outputFits(stdout, stderr, capacity) {
 		return fmt.Sprint(stdout, errorTitle, stderr)
 	}
 
@@ -435,3 +581,12 @@ func TruncateOutput(stdout string, stderr string, capacity int) (response string
 	truncateSize := availableSpace - len(truncateOut)
 	return fmt.Sprint(stdout[:truncateSize-errorSize], truncateOut, errorTitle, stderr)
 }
+
+// outputFits returns true if stdout and stderr fit within the capacity without truncation
+func outputFits(stdout string, stderr string, capacity int) bool {
+	availableSpace := capacity
+	if len(stderr) > 0 {
+		availableSpace -= len(outputErrorTitle)
+	}
+	return (len(stdout) + len(stderr)) < availableSpace
+}
@@ -15,6 +15,10 @@ package iohandler
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"sync"
@@ -24,6 +28,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redact"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -93,6 +98,24 @@ func TestRegisterOutputSource(t *testing.T) {
 	time.Sleep(250 * time.Millisecond)
 }
 
+func TestRedactedOutput(t *testing.T) {
+	orchestrationDir, _ := ioutil.TempDir("", "iohandler")
+	defer os.RemoveAll(orchestrationDir)
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir})
+	output.SetRedactedValues(redact.New("s3cr3t"))
+	output.Init(logger, "plugin")
+
+	output.AppendInfo("password is s3")
+	output.GetStdoutWriter().Write([]byte("cr3t"))
+	output.AppendError("failed with s3cr3t")
+	output.Close(logger)
+
+	assert.Equal(t, "password is ***", output.GetStdout())
+	assert.Equal(t, "failed with ***", output.GetStderr())
+	stdout, _ := ioutil.ReadFile(filepath.Join(orchestrationDir, "plugin", "stdout"))
+	assert.Equal(t, "password is ***", string(stdout))
+}
+
 func TestSucceeded(t *testing.T) {
 	output := DefaultIOHandler{}
 
@@ -174,3 +197,75 @@ func TestAppendFormat(t *testing.T) {
 	assert.Contains(t, output.GetStdout(), testStringFormatted)
 	assert.Contains(t, output.GetStderr(), testStringFormatted)
 }
+
+func TestStringAppendsS3LocationWhenTruncated(t *testing.T) {
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{
+		OutputS3BucketName: "bucket",
+		OutputS3KeyPrefix:  "prefix",
+	})
+	output.SetStdout(strings.Repeat("a", MaximumPluginOutputSize))
+	output.SetStderr("error")
+
+	result := output.String()
+	assert.Equal(t, MaximumPluginOutputSize, len(result))
+	assert.True(t, strings.HasSuffix(result, fullOutputTitle+"s3://bucket/prefix/stdout\ns3://bucket/prefix/stderr"))
+	assert.Contains(t, result, truncateOut)
+}
+
+func TestStringWithoutS3LocationWhenNotTruncated(t *testing.T) {
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{
+		OutputS3BucketName: "bucket",
+		OutputS3KeyPrefix:  "prefix",
+	})
+	output.SetStdout("output")
+
+	assert.Equal(t, "output", output.String())
+}
+
+func TestResultWrittenOnClose(t *testing.T) {
+	orchestrationDir, _ := ioutil.TempDir("", "iohandler")
+	defer os.RemoveAll(orchestrationDir)
+	resultPath := filepath.Join(orchestrationDir, "plugin", DefaultOutputConfig().ResultFileName)
+	os.MkdirAll(filepath.Dir(resultPath), 0700)
+	ioutil.WriteFile(resultPath, []byte(`{"installedVersion":"0.9.0"}`), 0600)
+
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir})
+	output.SetRedactedValues(redact.New("s3cr3t"))
+	output.Init(logger, "plugin")
+	output.SetResultField(contracts.ResultFieldInstalledVersion, "1.0.0")
+	output.SetResultField("token", "s3cr3t")
+	output.Close(logger)
+
+	content, err := ioutil.ReadFile(resultPath)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"installedVersion":"1.0.0","token":"***"}`, string(content))
+}
+
+func TestResultNotWrittenWhenEmpty(t *testing.T) {
+	orchestrationDir, _ := ioutil.TempDir("", "iohandler")
+	defer os.RemoveAll(orchestrationDir)
+
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir})
+	output.Init(logger, "plugin")
+	output.AppendInfo("output")
+	output.Close(logger)
+
+	assert.Nil(t, output.GetResult())
+	_, err := os.Stat(filepath.Join(orchestrationDir, "plugin", DefaultOutputConfig().ResultFileName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMergeResult(t *testing.T) {
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	output.SetResultField(contracts.ResultFieldInstalledVersion, "1.0.0")
+	output.SetResultField(contracts.ResultFieldRebootRequired, false)
+
+	propOutput := NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	propOutput.SetResultField(contracts.ResultFieldRebootRequired, true)
+	output.Merge(logger, propOutput)
+
+	assert.Equal(t, map[string]interface{}{
+		contracts.ResultFieldInstalledVersion: "1.0.0",
+		contracts.ResultFieldRebootRequired:   true,
+	}, output.GetResult())
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"bufio"
+	"io"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+const (
+	// cloudWatchMaxBatchEvents is the number of events after which a batch is sent
+	cloudWatchMaxBatchEvents = 1000
+	// cloudWatchMaxBatchBytes is kept below the 1MB PutLogEvents limit
+	cloudWatchMaxBatchBytes = 1000000
+	// cloudWatchEventOverhead is the number of bytes CloudWatch adds to the size of each event
+	cloudWatchEventOverhead = 26
+	// cloudWatchMaxEventBytes is the maximum size of a single log event
+	cloudWatchMaxEventBytes = 256*1024 - cloudWatchEventOverhead
+	// cloudWatchPutAttempts is the number of attempts made to upload a batch
+	cloudWatchPutAttempts = 3
+)
+
+var (
+	// cloudWatchFlushInterval is the maximum time a line is buffered before it is sent
+	cloudWatchFlushInterval = time.Second
+	// cloudWatchRetryDelay is the delay before the first retry, doubled on every attempt
+	cloudWatchRetryDelay = time.Second
+)
+
+// CloudWatchLogs streams the output line by line to a CloudWatch Logs stream while the command is running.
+type CloudWatchLogs struct {
+	LogGroupName  string
+	LogStreamName string
+	// Service is the CloudWatch Logs service used to upload, a default one is created when nil
+	Service cloudwatchlogsinterface.ICloudWatchLogsService
+}
+
+// cloudWatchBatch holds the events waiting to be uploaded.
+type cloudWatchBatch struct {
+	events []*cloudwatchlogs.InputLogEvent
+	size   int
+}
+
+// Read reads lines from the stream and uploads them in batches to CloudWatch Logs.
+func (cw CloudWatchLogs) Read(log log.T, reader *io.PipeReader) {
+	defer func() { reader.Close() }()
+
+	service := cw.Service
+	if service == nil {
+		service = cloudwatchlogspublisher.NewCloudWatchLogsService()
+	}
+
+	if err := service.CreateLogGroup(log, cw.LogGroupName); err != nil {
+		log.Errorf("Failed to create log group %v for streaming the output: %v", cw.LogGroupName, err)
+		return
+	}
+	if err := service.CreateLogStream(log, cw.LogGroupName, cw.LogStreamName); err != nil {
+		log.Errorf("Failed to create log stream %v for streaming the output: %v", cw.LogStreamName, err)
+		return
+	}
+
+	// scan the pipe on its own goroutine so that slow uploads don't block the command output
+	lines := make(chan *cloudwatchlogs.InputLogEvent, cloudWatchMaxBatchEvents)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), cloudWatchMaxEventBytes)
+		for scanner.Scan() {
+			lines <- &cloudwatchlogs.InputLogEvent{
+				Message:   aws.String(scanner.Text()),
+				Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Errorf("Error with the scanner while streaming the output to CloudWatch: %v", err)
+		}
+	}()
+
+	var sequenceToken *string
+	batch := cloudWatchBatch{}
+	ticker := time.NewTicker(cloudWatchFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, open := <-lines:
+			if !open {
+				sequenceToken = cw.send(log, service, &batch, sequenceToken)
+				return
+			}
+			eventSize := len(*event.Message) + cloudWatchEventOverhead
+			if batch.size+eventSize > cloudWatchMaxBatchBytes {
+				sequenceToken = cw.send(log, service, &batch, sequenceToken)
+			}
+			batch.events = append(batch.events, event)
+			batch.size += eventSize
+			if len(batch.events) >= cloudWatchMaxBatchEvents {
+				sequenceToken = cw.send(log, service, &batch, sequenceToken)
+			}
+		case <-ticker.C:
+			sequenceToken = cw.send(log, service, &batch, sequenceToken)
+		}
+	}
+}
+
+// send uploads the batched events with retries and resets the batch.
+// Events that could not be uploaded after all attempts are dropped.
+func (cw CloudWatchLogs) send(log log.T, service cloudwatchlogsinterface.ICloudWatchLogsService, batch *cloudWatchBatch, sequenceToken *string) *string {
+	if len(batch.events) == 0 {
+		return sequenceToken
+	}
+	defer func() { *batch = cloudWatchBatch{} }()
+
+	delay := cloudWatchRetryDelay
+	for attempt := 1; ; attempt++ {
+		nextSequenceToken, err := service.PutLogEvents(log, batch.events, cw.LogGroupName, cw.LogStreamName, sequenceToken)
+		if err == nil {
+			return nextSequenceToken
+		}
+		if attempt >= cloudWatchPutAttempts {
+			log.Errorf("Failed to stream %v output lines to CloudWatch log stream %v: %v", len(batch.events), cw.LogStreamName, err)
+			return sequenceToken
+		}
+		log.Debugf("Failed to stream output to CloudWatch, retrying in %v: %v", delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
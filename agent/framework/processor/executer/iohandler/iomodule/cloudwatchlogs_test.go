@@ -0,0 +1,95 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testLogGroup  = "group"
+	testLogStream = "command/plugin/stdout"
+)
+
+func streamToCloudWatch(service *cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock, input string) {
+	r, w := io.Pipe()
+	done := make(chan bool)
+	cw := CloudWatchLogs{
+		LogGroupName:  testLogGroup,
+		LogStreamName: testLogStream,
+		Service:       service,
+	}
+	go func() {
+		cw.Read(logger, r)
+		close(done)
+	}()
+	w.Write([]byte(input))
+	w.Close()
+	<-done
+}
+
+func messages(events []*cloudwatchlogs.InputLogEvent) (result []string) {
+	for _, event := range events {
+		result = append(result, *event.Message)
+	}
+	return
+}
+
+func TestCloudWatchLogsStreamsLines(t *testing.T) {
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, testLogGroup).Return(nil)
+	service.On("CreateLogStream", mock.Anything, testLogGroup, testLogStream).Return(nil)
+	var sent []string
+	service.On("PutLogEvents", mock.Anything, mock.Anything, testLogGroup, testLogStream, mock.Anything).Return(aws.String("token"), nil).Run(func(args mock.Arguments) {
+		sent = append(sent, messages(args.Get(1).([]*cloudwatchlogs.InputLogEvent))...)
+	})
+
+	streamToCloudWatch(service, "line one\nline two\nline three")
+
+	assert.Equal(t, []string{"line one", "line two", "line three"}, sent)
+	service.AssertCalled(t, "CreateLogStream", mock.Anything, testLogGroup, testLogStream)
+}
+
+func TestCloudWatchLogsRetriesFailedUpload(t *testing.T) {
+	defer func(delay time.Duration) { cloudWatchRetryDelay = delay }(cloudWatchRetryDelay)
+	cloudWatchRetryDelay = time.Millisecond
+
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, testLogGroup).Return(nil)
+	service.On("CreateLogStream", mock.Anything, testLogGroup, testLogStream).Return(nil)
+	service.On("PutLogEvents", mock.Anything, mock.Anything, testLogGroup, testLogStream, mock.Anything).Return(nil, errors.New("throttled")).Once()
+	service.On("PutLogEvents", mock.Anything, mock.Anything, testLogGroup, testLogStream, mock.Anything).Return(aws.String("token"), nil).Once()
+
+	streamToCloudWatch(service, "line")
+
+	service.AssertNumberOfCalls(t, "PutLogEvents", 2)
+}
+
+func TestCloudWatchLogsStreamCreationFails(t *testing.T) {
+	service := cloudwatchlogspublisher_mock.NewServiceMockDefault()
+	service.On("CreateLogGroup", mock.Anything, testLogGroup).Return(errors.New("access denied"))
+
+	streamToCloudWatch(service, "line")
+
+	service.AssertNotCalled(t, "PutLogEvents", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -31,6 +31,9 @@ type File struct {
 	OrchestrationDirectory string
 	OutputS3BucketName     string
 	OutputS3KeyPrefix      string
+	OutputS3KmsKeyId       string
+	// CorrelationID is set as the metadata and in the user agent of the upload to s3
+	CorrelationID string
 }
 
 // Read reads from the stream and writes to the output file and s3.
@@ -78,7 +81,7 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 	// Upload output file to S3
 	if file.OutputS3BucketName != "" && fi.Size() > 0 {
 		s3Key := fileutil.BuildS3Path(file.OutputS3KeyPrefix, file.FileName)
-		if err := s3util.NewAmazonS3Util(log, file.OutputS3BucketName).S3Upload(log, file.OutputS3BucketName, s3Key, filePath); err != nil {
+		if err := s3util.NewAmazonS3Util(log, file.OutputS3BucketName).WithKmsKeyID(file.OutputS3KmsKeyId).WithCorrelationID(file.CorrelationID).S3Upload(log, file.OutputS3BucketName, s3Key, filePath); err != nil {
 			log.Errorf("Failed to upload the output to s3: %v", err)
 		}
 	}
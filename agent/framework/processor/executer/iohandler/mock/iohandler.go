@@ -68,6 +68,11 @@ func (m *MockIOHandler) MarkAsSuccessWithReboot() {
 	m.Called()
 }
 
+// MarkAsPassedAndReboot is a mocked method that acknowledges that the function has been called.
+func (m *MockIOHandler) MarkAsPassedAndReboot() {
+	m.Called()
+}
+
 // MarkAsCancelled is a mocked method that acknowledges that the function has been called.
 func (m *MockIOHandler) MarkAsCancelled() {
 	m.Called()
@@ -140,6 +145,12 @@ func (m *MockIOHandler) GetIOConfig() contracts.IOConfiguration {
 	return args.Get(0).(contracts.IOConfiguration)
 }
 
+// GetResult is a mocked method that just returns what mock tells it to.
+func (m *MockIOHandler) GetResult() map[string]interface{} {
+	args := m.Called()
+	return args.Get(0).(map[string]interface{})
+}
+
 // SetStatus is a mocked method that acknowledges that the function has been called.
 func (m *MockIOHandler) SetStatus(status contracts.ResultStatus) {
 	m.Called(status)
@@ -164,3 +175,8 @@ func (m *MockIOHandler) SetStdout(stdout string) {
 func (m *MockIOHandler) SetStderr(stderr string) {
 	m.Called(stderr)
 }
+
+// SetResultField is a mocked method that acknowledges that the function has been called.
+func (m *MockIOHandler) SetResultField(name string, value interface{}) {
+	m.Called(name, value)
+}
@@ -8,11 +8,13 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/correlation"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/basicexecuter"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
@@ -68,8 +70,11 @@ func (e *OutOfProcExecuter) Run(
 	e.cancelFlag = cancelFlag
 	documentID := docState.DocumentInformation.DocumentID
 
-	//update context with the document id
+	//update context with the document id and its correlation id
 	e.ctx = e.ctx.With("[" + documentID + "]")
+	if correlationID := docState.DocumentInformation.CorrelationID; correlationID != "" {
+		e.ctx = e.ctx.With(correlation.LogContext(correlationID))
+	}
 	log := e.ctx.Log()
 
 	//stopTimer signals messaging routine to stop, it's buffered because it needs to exit if messaging is already stopped and not receiving anymore
@@ -94,7 +99,7 @@ func (e *OutOfProcExecuter) Run(
 				log.Info("Executer closed")
 				close(resChan)
 			}()
-			e.messaging(log, ipc, resChan, cancelFlag, stopTimer)
+			e.messaging(log, ipc, resChan, store, cancelFlag, stopTimer)
 		}(docStore)
 
 		return resChan
@@ -104,10 +109,10 @@ func (e *OutOfProcExecuter) Run(
 //Executer spins up an ipc transmission worker, it creates a Data processing backend and hands off the backend to the ipc worker
 //ipc worker and data backend act as 2 threads exchange raw json messages, and messaging protocol happened in data backend, data backend is self-contained and exit when command finishes accordingly
 //Executer however does hold a timer to the worker to forcefully termniate both of them
-func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan chan contracts.DocumentResult, cancelFlag task.CancelFlag, stopTimer chan bool) {
+func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan chan contracts.DocumentResult, docStore executer.DocumentStore, cancelFlag task.CancelFlag, stopTimer chan bool) {
 
 	//handoff reply functionalities to data backend.
-	backend := messaging.NewExecuterBackend(resChan, e.docState, cancelFlag)
+	backend := messaging.NewExecuterBackend(resChan, e.docState, docStore, cancelFlag)
 	//handoff the data backend to messaging worker
 	if err := messaging.Messaging(log, ipc, backend, stopTimer); err != nil {
 		//the messaging worker encountered error, either ipc run into error or data backend throws error
@@ -131,6 +136,7 @@ func (e *OutOfProcExecuter) generateUnexpectedFailResult(errMsg string) contract
 	docResult.DocumentName = e.docState.DocumentInformation.DocumentName
 	docResult.NPlugins = len(e.docState.InstancePluginsInformation)
 	docResult.DocumentVersion = e.docState.DocumentInformation.DocumentVersion
+	docResult.CorrelationID = e.docState.DocumentInformation.CorrelationID
 	docResult.Status = contracts.ResultStatusFailed
 	docResult.PluginResults = make(map[string]*contracts.PluginResult)
 	res := e.docState.InstancePluginsInformation[0].Result
@@ -166,8 +172,13 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 		go timeout(stopTimer, stopTime, e.cancelFlag)
 	} else {
 		log.Debug("channel not found, starting a new process...")
+		if err = integrity.CheckBinaries(log, e.ctx.AppConfig().Agent.IntegrityCheckMode, integrity.ManifestPath(), []string{appconfig.DefaultDocumentWorker}); err != nil {
+			log.Errorf("refusing to launch document worker: %v", err)
+			ipc.Destroy()
+			return
+		}
 		var process proc.OSProcess
-		if process, err = processCreator(appconfig.DefaultDocumentWorker, proc.FormArgv(documentID)); err != nil {
+		if process, err = processCreator(appconfig.DefaultDocumentWorker, proc.FormArgv(documentID, e.docState.DocumentInformation.InstanceID)); err != nil {
 			log.Errorf("start process: %v error: %v", appconfig.DefaultDocumentWorker, err)
 			//make sure close the channel
 			ipc.Destroy()
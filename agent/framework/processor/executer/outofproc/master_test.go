@@ -108,7 +108,7 @@ func TestInitializeNewProcess(t *testing.T) {
 	}
 	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
-		assert.Equal(t, argv, []string{testDocumentID})
+		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return testCase.processMock, nil
 	}
 	exe := &OutOfProcExecuter{
@@ -144,7 +144,7 @@ func TestCreateProcessFailed(t *testing.T) {
 	var err = errors.New("failed to create process")
 	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
-		assert.Equal(t, argv, []string{testDocumentID})
+		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return nil, err
 	}
 	exe := &OutOfProcExecuter{
@@ -169,7 +169,7 @@ func TestInitializeProcessUnexpectedExited(t *testing.T) {
 	}
 	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
-		assert.Equal(t, argv, []string{testDocumentID})
+		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return testCase.processMock, nil
 	}
 	cancel := task.NewChanneledCancelFlag()
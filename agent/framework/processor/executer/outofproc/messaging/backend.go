@@ -7,6 +7,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
@@ -23,7 +24,7 @@ type PluginRunner func(
 	cancelFlag task.CancelFlag,
 )
 
-//worker backend receives request messages from master, controls a pluginRunner based off the request and send reponses to Executer
+// worker backend receives request messages from master, controls a pluginRunner based off the request and send reponses to Executer
 type WorkerBackend struct {
 	ctx        context.T
 	input      chan string
@@ -33,22 +34,25 @@ type WorkerBackend struct {
 	stopChan   chan int
 }
 
-//Executer backend formulate the run request to the worker, and collect back the responses from worker
+// Executer backend formulate the run request to the worker, and collect back the responses from worker
 type ExecuterBackend struct {
 	//the shared state object that Executer hand off to data backend
-	docState   *contracts.DocumentState
+	docState *contracts.DocumentState
+	//the store the state is saved to after each plugin result, for crash-recovery
+	docStore   executer.DocumentStore
 	input      chan string
 	cancelFlag task.CancelFlag
 	output     chan contracts.DocumentResult
 	stopChan   chan int
 }
 
-func NewExecuterBackend(output chan contracts.DocumentResult, docState *contracts.DocumentState, cancelFlag task.CancelFlag) *ExecuterBackend {
+func NewExecuterBackend(output chan contracts.DocumentResult, docState *contracts.DocumentState, docStore executer.DocumentStore, cancelFlag task.CancelFlag) *ExecuterBackend {
 	stopChan := make(chan int, defaultBackendChannelSize)
 	inputChan := make(chan string, defaultBackendChannelSize)
 	p := ExecuterBackend{
 		output:     output,
 		docState:   docState,
+		docStore:   docStore,
 		input:      inputChan,
 		cancelFlag: cancelFlag,
 		stopChan:   stopChan,
@@ -79,8 +83,8 @@ func (p *ExecuterBackend) Stop() <-chan int {
 	return p.stopChan
 }
 
-//TODO handle error and logging, when err, ask messaging to stop
-//TODO version handling?
+// TODO handle error and logging, when err, ask messaging to stop
+// TODO version handling?
 func (p *ExecuterBackend) Process(datagram string) error {
 	t, content := ParseDatagram(datagram)
 	switch t {
@@ -106,8 +110,12 @@ func (p *ExecuterBackend) formatDocResult(docResult *contracts.DocumentResult) {
 	docResult.DocumentName = p.docState.DocumentInformation.DocumentName
 	docResult.NPlugins = len(p.docState.InstancePluginsInformation)
 	docResult.DocumentVersion = p.docState.DocumentInformation.DocumentVersion
+	docResult.CorrelationID = p.docState.DocumentInformation.CorrelationID
 	//update current document status
 	contracts.UpdateDocState(docResult, p.docState)
+	if p.docStore != nil && docResult.LastPlugin != "" {
+		p.docStore.Save(*p.docState)
+	}
 }
 
 func NewWorkerBackend(ctx context.T, runner PluginRunner) *WorkerBackend {
@@ -107,6 +107,11 @@ func ParseArgv(argv []string) (string, string, error) {
 
 }
 
-func FormArgv(channelName string) []string {
-	return []string{channelName}
+// FormArgv forms the arguments of the worker process, the instance id is passed along when known
+// so that the worker does not need to query the instance metadata or the registration.
+func FormArgv(channelName string, instanceID string) []string {
+	if instanceID == "" {
+		return []string{channelName}
+	}
+	return []string{channelName, instanceID}
 }
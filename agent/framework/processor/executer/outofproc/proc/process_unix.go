@@ -28,7 +28,12 @@ import (
 //verified on RHEL, Amazon Linux, Ubuntu, Centos, FreeBSD and Darwin
 //TODO optimize this, do not print all processes; what we need is the process belongs to a specific user and no tty attached
 var ps = func() ([]byte, error) {
-	return exec.Command("ps", "-e", "-o", "pid,lstart").CombinedOutput()
+	output, err := exec.Command("ps", "-e", "-o", "pid,lstart").CombinedOutput()
+	if err != nil {
+		// busybox ps, used on alpine, always lists all the processes and does not support the lstart column
+		return exec.Command("ps", "-o", "pid").CombinedOutput()
+	}
+	return output, nil
 }
 
 func prepareProcess(command *exec.Cmd) {
@@ -29,7 +29,7 @@ var pluginRunner = func(
 	resChan chan contracts.PluginResult,
 	cancelFlag task.CancelFlag,
 ) {
-	runpluginutil.RunPlugins(context, docState.InstancePluginsInformation, docState.IOConfig, runpluginutil.SSMPluginRegistry, resChan, cancelFlag)
+	runpluginutil.RunPluginsConcurrently(context, docState.InstancePluginsInformation, docState.IOConfig, runpluginutil.SSMPluginRegistry, resChan, cancelFlag, docState.MaxConcurrentSteps)
 	//make sure to signal the client that job complete
 	close(resChan)
 }
@@ -22,6 +22,8 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurecontainers"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configuredaemon"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurekernelparameters"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/dockercontainer"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent"
@@ -37,22 +39,23 @@ import (
 // This allows us to differentiate between the case where a document asks for a plugin that exists but isn't supported on this platform
 // and the case where a plugin name isn't known at all to this version of the agent (and the user should probably upgrade their agent)
 var allPlugins = map[string]struct{}{
-	appconfig.PluginNameAwsAgentUpdate:         {},
-	appconfig.PluginNameAwsApplications:        {},
-	appconfig.PluginNameAwsConfigureDaemon:     {},
-	appconfig.PluginNameAwsConfigurePackage:    {},
-	appconfig.PluginNameAwsPowerShellModule:    {},
-	appconfig.PluginNameAwsRunPowerShellScript: {},
-	appconfig.PluginNameAwsRunShellScript:      {},
-	appconfig.PluginNameAwsSoftwareInventory:   {},
-	appconfig.PluginNameCloudWatch:             {},
-	appconfig.PluginNameConfigureDocker:        {},
-	appconfig.PluginNameDockerContainer:        {},
-	appconfig.PluginNameDomainJoin:             {},
-	appconfig.PluginEC2ConfigUpdate:            {},
-	appconfig.PluginNameRefreshAssociation:     {},
-	appconfig.PluginDownloadContent:            {},
-	appconfig.PluginRunDocument:                {},
+	appconfig.PluginNameAwsAgentUpdate:            {},
+	appconfig.PluginNameAwsApplications:           {},
+	appconfig.PluginNameAwsConfigureDaemon:        {},
+	appconfig.PluginNameAwsConfigurePackage:       {},
+	appconfig.PluginNameAwsPowerShellModule:       {},
+	appconfig.PluginNameAwsRunPowerShellScript:    {},
+	appconfig.PluginNameAwsRunShellScript:         {},
+	appconfig.PluginNameAwsSoftwareInventory:      {},
+	appconfig.PluginNameCloudWatch:                {},
+	appconfig.PluginNameConfigureDocker:           {},
+	appconfig.PluginNameConfigureKernelParameters: {},
+	appconfig.PluginNameDockerContainer:           {},
+	appconfig.PluginNameDomainJoin:                {},
+	appconfig.PluginEC2ConfigUpdate:               {},
+	appconfig.PluginNameRefreshAssociation:        {},
+	appconfig.PluginDownloadContent:               {},
+	appconfig.PluginRunDocument:                   {},
 }
 
 var once sync.Once
@@ -78,7 +81,15 @@ type RunPowerShellFactory struct {
 }
 
 func (f RunPowerShellFactory) Create(context context.T) (runpluginutil.T, error) {
-	return runscript.NewRunPowerShellPlugin()
+	plugin, err := runscript.NewRunPowerShellPlugin()
+	if err != nil {
+		return nil, err
+	}
+	plugin.PrivateTmp = context.AppConfig().Agent.PrivateTmp
+	plugin.Sandbox = context.AppConfig().Agent.ScriptSandbox
+	plugin.SandboxProfile = context.AppConfig().Agent.ScriptSandboxProfile
+	plugin.ScriptCredentials = context.AppConfig().ScriptCredentials
+	return plugin, nil
 }
 
 type UpdateAgentFactory struct {
@@ -95,6 +106,13 @@ func (f ConfigureContainerFactory) Create(context context.T) (runpluginutil.T, e
 	return configurecontainers.NewPlugin()
 }
 
+type ConfigureDaemonFactory struct {
+}
+
+func (f ConfigureDaemonFactory) Create(context context.T) (runpluginutil.T, error) {
+	return configuredaemon.NewPlugin()
+}
+
 type RunDockerFactory struct {
 }
 
@@ -102,6 +120,13 @@ func (f RunDockerFactory) Create(context context.T) (runpluginutil.T, error) {
 	return dockercontainer.NewPlugin()
 }
 
+type ConfigureKernelParametersFactory struct {
+}
+
+func (f ConfigureKernelParametersFactory) Create(context context.T) (runpluginutil.T, error) {
+	return configurekernelparameters.NewPlugin()
+}
+
 type ConfigurePackageFactory struct {
 }
 
@@ -177,10 +202,18 @@ func loadPlatformIndependentPlugins(context context.T) runpluginutil.PluginRegis
 
 	workerPlugins[configureContainersPluginName] = ConfigureContainerFactory{}
 
+	// registering aws:configureDaemon plugin
+	configureDaemonPluginName := configuredaemon.Name()
+	workerPlugins[configureDaemonPluginName] = ConfigureDaemonFactory{}
+
 	// registering aws:runDockerAction plugin
 	runDockerPluginName := dockercontainer.Name()
 	workerPlugins[runDockerPluginName] = RunDockerFactory{}
 
+	// registering aws:configureKernelParameters plugin
+	configureKernelParametersPluginName := configurekernelparameters.Name()
+	workerPlugins[configureKernelParametersPluginName] = ConfigureKernelParametersFactory{}
+
 	// registering aws:refreshAssociation plugin
 	refreshAssociationPluginName := refreshassociation.Name()
 	workerPlugins[refreshAssociationPluginName] = RefreshAssociationFactory{}
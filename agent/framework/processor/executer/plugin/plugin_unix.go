@@ -19,6 +19,8 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/application"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/domainjoin"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/runscript"
 )
 
@@ -26,7 +28,29 @@ type RunShellScriptFactory struct {
 }
 
 func (f RunShellScriptFactory) Create(context context.T) (runpluginutil.T, error) {
-	return runscript.NewRunShellPlugin(context.Log())
+	plugin, err := runscript.NewRunShellPlugin(context.Log())
+	if err != nil {
+		return nil, err
+	}
+	plugin.PrivateTmp = context.AppConfig().Agent.PrivateTmp
+	plugin.Sandbox = context.AppConfig().Agent.ScriptSandbox
+	plugin.SandboxProfile = context.AppConfig().Agent.ScriptSandboxProfile
+	plugin.ScriptCredentials = context.AppConfig().ScriptCredentials
+	return plugin, nil
+}
+
+type ApplicationFactory struct {
+}
+
+func (f ApplicationFactory) Create(context context.T) (runpluginutil.T, error) {
+	return application.NewPlugin()
+}
+
+type DomainJoinFactory struct {
+}
+
+func (f DomainJoinFactory) Create(context context.T) (runpluginutil.T, error) {
+	return domainjoin.NewPlugin()
 }
 
 // loadPlatformDependentPlugins registers platform dependent plugins
@@ -34,5 +58,11 @@ func loadPlatformDependentPlugins(context context.T) runpluginutil.PluginRegistr
 	var workerPlugins = runpluginutil.PluginRegistry{}
 
 	workerPlugins[appconfig.PluginNameAwsRunShellScript] = RunShellScriptFactory{}
+
+	// registering aws:applications plugin
+	workerPlugins[application.Name()] = ApplicationFactory{}
+
+	// registering aws:domainJoin plugin
+	workerPlugins[domainjoin.Name()] = DomainJoinFactory{}
 	return workerPlugins
 }
@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/auditlog"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
@@ -31,7 +32,9 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
+	"github.com/aws/amazon-ssm-agent/agent/notification"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/pluginmetrics"
 	"github.com/aws/amazon-ssm-agent/agent/rebooter"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/times"
@@ -39,6 +42,12 @@ import (
 
 type ExecuterCreator func(ctx context.T) executer.Executer
 
+var (
+	// documentLimiter limits how many send command documents run at the same time across all the processors
+	documentLimiter     *task.Limiter
+	documentLimiterOnce sync.Once
+)
+
 const (
 
 	// hardstopTimeout is the time before the processor will be shutdown during a hardstop
@@ -124,6 +133,8 @@ func (p *EngineProcessor) InitialProcessing() (err error) {
 	}
 
 	log.Info("Initial processing")
+	//complete the interrupted moves and set aside the states which cannot be read before resuming any document
+	p.documentMgr.RecoverDocumentStates(log, instanceID)
 	//prioritize the ongoing document first
 	p.processInProgressDocuments(instanceID)
 	//deal with the pending jobs that haven't picked up by worker yet
@@ -197,6 +208,12 @@ func (p *EngineProcessor) Stop(stopType contracts.StopType) {
 	} else {
 		waitTimeout = hardStopTimeout
 	}
+	// no new document starts once the pools are shut down, the running ones get the grace period to finish
+	// before they are canceled, the documents which did not finish stay in the current folder to resume later
+	if gracePeriod := time.Duration(p.context.AppConfig().Agent.ShutdownGracePeriodSeconds) * time.Second; gracePeriod > waitTimeout {
+		p.context.Log().Infof("waiting up to %v for the running documents to finish", gracePeriod)
+		waitTimeout = gracePeriod
+	}
 
 	var wg sync.WaitGroup
 
@@ -281,8 +298,9 @@ func (p *EngineProcessor) processInProgressDocuments(instanceID string) {
 		//inspect document state
 		docState := p.documentMgr.GetDocumentState(log, f.Name(), instanceID, appconfig.DefaultLocationOfCurrent)
 
+		// the runs resuming the document after a reboot it requested are not retries
 		retryLimit := config.Mds.CommandRetryLimit
-		if docState.DocumentInformation.RunCount >= retryLimit {
+		if docState.DocumentInformation.RunCount-docState.DocumentInformation.RebootCount >= retryLimit {
 			p.documentMgr.MoveDocumentState(log, f.Name(), instanceID, appconfig.DefaultLocationOfCurrent, appconfig.DefaultLocationOfCorrupt)
 			continue
 		}
@@ -314,12 +332,24 @@ func (p *EngineProcessor) isSupportedDocumentType(documentType contracts.Documen
 
 func processCommand(context context.T, executerCreator ExecuterCreator, cancelFlag task.CancelFlag, resChan chan contracts.DocumentResult, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
 	log := context.Log()
+	if !docState.IsAssociation() {
+		limiter := sendCommandLimiter(context)
+		if limiter.Acquire(cancelFlag) {
+			defer limiter.Release()
+		} else if cancelFlag.ShutDown() {
+			//leave the document in the pending folder to run it once the agent restarts
+			log.Infof("document %v was waiting to start, shutting down...", docState.DocumentInformation.MessageID)
+			return
+		}
+		//a document canceled while waiting runs with the canceled flag set so that its cancellation is reported
+	}
 	//persist the current running document
 	docMgr.MoveDocumentState(log,
 		docState.DocumentInformation.DocumentID,
 		docState.DocumentInformation.InstanceID,
 		appconfig.DefaultLocationOfPending,
 		appconfig.DefaultLocationOfCurrent)
+	retainPluginOutputs(context, docState)
 	log.Debug("Running executer...")
 	documentID := docState.DocumentInformation.DocumentID
 	instanceID := docState.DocumentInformation.InstanceID
@@ -340,6 +370,9 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 
 		}
 		handleCloudwatchPlugin(context, res.PluginResults, documentID)
+		if pluginRes := res.PluginResults[res.LastPlugin]; pluginRes != nil && context.AppConfig().Metrics.Enabled {
+			pluginmetrics.RecordPluginResult(*pluginRes)
+		}
 		//hand off the message to Service
 		resChan <- res
 		final = &res
@@ -355,6 +388,12 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 		return
 	}
 
+	//keep a summary of the execution in the local execution history
+	executedState := docStore.Load()
+	docMgr.RecordExecution(log, executedState, *final, context.AppConfig().Ssm.ExecutionHistoryCount)
+	auditlog.DocumentCompleted(log, executedState, *final)
+	go notification.DocumentCompleted(log, context.AppConfig().Notifications, executedState, *final)
+
 	//persist : commands execution in completed folder (terminal state folder)
 	log.Infof("execution of %v is over. Removing interimState from current folder", messageID)
 
@@ -395,6 +434,35 @@ func processCancelCommand(context context.T, sendCommandPool task.Pool, docState
 
 }
 
+// sendCommandLimiter returns the limiter shared by the processors, created with the limit configured in appconfig
+func sendCommandLimiter(context context.T) *task.Limiter {
+	documentLimiterOnce.Do(func() {
+		maxConcurrentDocuments := context.AppConfig().Mds.MaxConcurrentDocuments
+		if maxConcurrentDocuments > 0 {
+			context.Log().Infof("running up to %v send command documents at the same time", maxConcurrentDocuments)
+		}
+		documentLimiter = task.NewLimiter(maxConcurrentDocuments)
+	})
+	return documentLimiter
+}
+
+// retainPluginOutputs marks the orchestration folders of the plugins configured in RetainedPluginOutputs
+// so that they are never removed by the pruning of old executions
+func retainPluginOutputs(context context.T, docState *contracts.DocumentState) {
+	log := context.Log()
+	for _, retained := range context.AppConfig().Ssm.RetainedPluginOutputs {
+		for _, pluginState := range docState.InstancePluginsInformation {
+			orchestrationDir := pluginState.Configuration.OrchestrationDirectory
+			if pluginState.Name != retained || orchestrationDir == "" {
+				continue
+			}
+			if err := docmanager.MarkOutputRetained(orchestrationDir); err != nil {
+				log.Warnf("failed to mark the outputs of %v as retained: %v", pluginState.Id, err)
+			}
+		}
+	}
+}
+
 //TODO remove this once CloudWatch plugin is reworked
 //temporary solution on plugins with shared responsibility with agent
 func handleCloudwatchPlugin(context context.T, pluginResults map[string]*contracts.PluginResult, documentID string) {
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"fmt"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -94,6 +95,27 @@ func TestEngineProcessor_Stop(t *testing.T) {
 	cancelCommandPoolMock.AssertExpectations(t)
 }
 
+func TestEngineProcessor_StopWaitsForShutdownGracePeriod(t *testing.T) {
+	sendCommandPoolMock := new(task.MockedPool)
+	cancelCommandPoolMock := new(task.MockedPool)
+	config := appconfig.SsmagentConfig{}
+	config.Agent.ShutdownGracePeriodSeconds = 30
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	processor := EngineProcessor{
+		sendCommandPool:   sendCommandPoolMock,
+		cancelCommandPool: cancelCommandPoolMock,
+		context:           ctx,
+		resChan:           make(chan contracts.DocumentResult),
+	}
+	sendCommandPoolMock.On("ShutdownAndWait", 30*time.Second).Return(true)
+	cancelCommandPoolMock.On("ShutdownAndWait", 30*time.Second).Return(true)
+	processor.Stop(contracts.StopTypeHardStop)
+	sendCommandPoolMock.AssertExpectations(t)
+	cancelCommandPoolMock.AssertExpectations(t)
+}
+
 //TODO add shutdown and reboot test once we encapsulate docmanager
 func TestProcessCommand(t *testing.T) {
 	ctx := context.NewMockDefault()
@@ -131,6 +153,7 @@ func TestProcessCommand(t *testing.T) {
 	}()
 	docMock := new(DocumentMgrMock)
 	docMock.On("MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
+	docMock.On("RecordExecution", mock.Anything, mock.AnythingOfType("contracts.DocumentState"), mock.AnythingOfType("contracts.DocumentResult"), mock.Anything)
 	docMock.On("RemoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent)
 	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock)
 	executerMock.AssertExpectations(t)
@@ -214,3 +237,13 @@ func (m *DocumentMgrMock) RemoveDocumentState(log log.T, documentID, instanceID,
 	m.Called(log, documentID, instanceID, location)
 	return
 }
+
+func (m *DocumentMgrMock) RecoverDocumentStates(log log.T, instanceID string) {
+	m.Called(log, instanceID)
+	return
+}
+
+func (m *DocumentMgrMock) RecordExecution(log log.T, state contracts.DocumentState, result contracts.DocumentResult, maxEntries int) {
+	m.Called(log, state, result, maxEntries)
+	return
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package pruner implements the core module which periodically prunes the execution history kept on disk.
+package pruner
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/carlescere/scheduler"
+)
+
+const name = "RetentionPruner"
+
+// Pruner applies the configured retention policies to the execution history in the background
+type Pruner struct {
+	context  context.T
+	pruneJob *scheduler.Job
+}
+
+// NewPruner creates a new retention pruner core module.
+func NewPruner(context context.T) *Pruner {
+	return &Pruner{
+		context: context.With("[" + name + "]"),
+	}
+}
+
+// prune removes the execution history which is not retained by the configured policies
+func (p *Pruner) prune() {
+	log := p.context.Log()
+	instanceID, err := platform.InstanceID()
+	if err != nil {
+		log.Errorf("unable to prune execution history, failed to get instance id: %v", err)
+		return
+	}
+	docmanager.PruneExecutionHistory(log, instanceID, p.context.AppConfig())
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the module name
+func (p *Pruner) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts the scheduling of the pruning job
+func (p *Pruner) ModuleExecute(context context.T) (err error) {
+	frequency := p.context.AppConfig().Ssm.RetentionPruneFrequencyMinutes
+	p.context.Log().Debugf("%v frequency is every %d minutes.", name, frequency)
+	if p.pruneJob, err = scheduler.Every(frequency).Minutes().Run(p.prune); err != nil {
+		p.context.Log().Errorf("unable to schedule execution history pruning. %v", err)
+	}
+	return
+}
+
+// ModuleRequestStop handles the termination of the pruning job
+func (p *Pruner) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if p.pruneJob != nil {
+		p.context.Log().Info("stopping execution history pruning job.")
+		p.pruneJob.Quit <- true
+	}
+	return nil
+}
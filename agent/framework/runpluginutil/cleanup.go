@@ -0,0 +1,54 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// exitsDocument returns whether the result of a step stops the document according to its onFailure property.
+// The failure of a successAndExit step is reported as a success.
+func exitsDocument(configuration contracts.Configuration, pluginOutput *contracts.PluginResult) bool {
+	if configuration.OnFailure == "" || !isFailedStep(pluginOutput.Status) {
+		return false
+	}
+	if configuration.OnFailure == contracts.OnFailureSuccessAndExit {
+		pluginOutput.Status = contracts.ResultStatusSuccess
+		pluginOutput.Code = 0
+		pluginOutput.Error = nil
+	}
+	return true
+}
+
+// isFailedStep returns whether the result of a step is a failure
+func isFailedStep(status contracts.ResultStatus) bool {
+	return status == contracts.ResultStatusFailed ||
+		status == contracts.ResultStatusTimedOut
+}
+
+// isCanceled returns whether the document has been canceled
+func isCanceled(cancelFlag task.CancelFlag) bool {
+	return cancelFlag != nil && cancelFlag.Canceled()
+}
+
+// stepCancelFlag returns the cancel flag a step runs with, once the document is canceled the finally step
+// gets a flag of its own so that it still runs, bounded by its own timeout
+func stepCancelFlag(configuration contracts.Configuration, cancelFlag task.CancelFlag) task.CancelFlag {
+	if configuration.FinallyStep && isCanceled(cancelFlag) {
+		return task.NewChanneledCancelFlag()
+	}
+	return cancelFlag
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// outcomePlugin fails the steps whose name starts with "fail", is canceled with the document and records the steps it ran
+type outcomePlugin struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+func (p *outcomePlugin) Create(context context.T) (T, error) {
+	return p, nil
+}
+
+func (p *outcomePlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	p.mu.Lock()
+	p.ran = append(p.ran, config.PluginID)
+	p.mu.Unlock()
+	if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else if strings.HasPrefix(config.PluginID, "fail") {
+		output.MarkAsFailed(nil)
+	} else {
+		output.MarkAsSucceeded()
+	}
+}
+
+func cleanupSteps(ids ...string) []contracts.PluginState {
+	var steps []contracts.PluginState
+	for _, id := range ids {
+		step := contracts.PluginState{Id: id, Name: testPlugin1}
+		step.Configuration.PluginID = id
+		switch id {
+		case "failExit":
+			step.Configuration.OnFailure = contracts.OnFailureExit
+		case "failSuccessAndExit":
+			step.Configuration.OnFailure = contracts.OnFailureSuccessAndExit
+		case "finally":
+			step.Configuration.FinallyStep = true
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func runCleanupSteps(t *testing.T, cancelFlag task.CancelFlag, maxConcurrentSteps int, ids ...string) (*outcomePlugin, map[string]*contracts.PluginResult) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+
+	plugin := &outcomePlugin{}
+	steps := cleanupSteps(ids...)
+	resChan := make(chan contracts.PluginResult, len(steps))
+	outputs := RunPluginsConcurrently(context.NewMockDefault(), steps, contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}, PluginRegistry{testPlugin1: plugin}, resChan, cancelFlag, maxConcurrentSteps)
+	return plugin, outputs
+}
+
+func TestRunPluginsExitsOnFailure(t *testing.T) {
+	plugin, outputs := runCleanupSteps(t, task.NewChanneledCancelFlag(), 1, "failExit", "next", "finally")
+
+	assert.Equal(t, []string{"failExit", "finally"}, plugin.ran)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs["failExit"].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["next"].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["finally"].Status)
+}
+
+func TestRunPluginsExitsSuccessfullyOnFailure(t *testing.T) {
+	plugin, outputs := runCleanupSteps(t, task.NewChanneledCancelFlag(), 1, "failSuccessAndExit", "next", "finally")
+
+	assert.Equal(t, []string{"failSuccessAndExit", "finally"}, plugin.ran)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["failSuccessAndExit"].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["next"].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["finally"].Status)
+}
+
+func TestRunPluginsContinuesAfterFailureByDefault(t *testing.T) {
+	plugin, outputs := runCleanupSteps(t, task.NewChanneledCancelFlag(), 1, "fail", "next", "finally")
+
+	assert.Equal(t, []string{"fail", "next", "finally"}, plugin.ran)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs["fail"].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["next"].Status)
+}
+
+func TestRunPluginsRunsFinallyStepOfCanceledDocument(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	cancelFlag.Set(task.Canceled)
+
+	plugin, outputs := runCleanupSteps(t, cancelFlag, 1, "succeed", "next", "finally")
+
+	assert.Equal(t, []string{"succeed", "next", "finally"}, plugin.ran)
+	assert.Equal(t, contracts.ResultStatusCancelled, outputs["succeed"].Status)
+	assert.Equal(t, contracts.ResultStatusCancelled, outputs["next"].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["finally"].Status)
+}
+
+func TestRunPluginsConcurrentlyExitsOnFailure(t *testing.T) {
+	plugin, outputs := runCleanupSteps(t, task.NewChanneledCancelFlag(), 3, "succeed", "failExit", "next", "finally")
+
+	// the step after the one which may exit waits for it, the finally step runs last
+	assert.Len(t, plugin.ran, 3)
+	assert.Equal(t, []string{"finally"}, plugin.ran[2:])
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["next"].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["finally"].Status)
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"sync/atomic"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// stepResult is the outcome of a plugin run by RunPluginsConcurrently
+type stepResult struct {
+	index    int
+	output   *contracts.PluginResult
+	executed bool
+}
+
+// RunPluginsConcurrently executes a set of plugins like RunPlugins, running up to maxConcurrentSteps plugins at the same time.
+// A plugin starts once the plugins it depends on completed. The results are sent to resChan in the order of the document,
+// so that the aggregated result does not depend on which plugin completes first.
+func RunPluginsConcurrently(
+	context context.T,
+	plugins []contracts.PluginState,
+	ioConfig contracts.IOConfiguration,
+	pluginRegistry PluginRegistry,
+	resChan chan contracts.PluginResult,
+	cancelFlag task.CancelFlag,
+	maxConcurrentSteps int,
+) (pluginOutputs map[string]*contracts.PluginResult) {
+
+	if maxConcurrentSteps <= 1 {
+		return RunPlugins(context, plugins, ioConfig, pluginRegistry, resChan, cancelFlag)
+	}
+	context.Log().Infof("Running up to %v plugins concurrently", maxConcurrentSteps)
+
+	completed := make(map[string]chan struct{}, len(plugins))
+	for _, pluginState := range plugins {
+		completed[pluginState.Id] = make(chan struct{})
+	}
+	slots := make(chan struct{}, maxConcurrentSteps)
+	results := make(chan stepResult, len(plugins))
+	var rebooting, exited int32
+
+	for index, pluginState := range plugins {
+		go func(index int, pluginState contracts.PluginState) {
+			defer close(completed[pluginState.Id])
+			dependencies := append([]string(nil), pluginState.Configuration.DependsOn...)
+			for _, earlier := range plugins[:index] {
+				// the finally step runs once all the steps before it completed, and the steps run once the earlier
+				// steps which may exit the document completed
+				if pluginState.Configuration.FinallyStep || earlier.Configuration.OnFailure != "" {
+					dependencies = append(dependencies, earlier.Id)
+				}
+			}
+			for _, dependency := range dependencies {
+				if done, found := completed[dependency]; found {
+					<-done
+				}
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			if atomic.LoadInt32(&rebooting) != 0 || (cancelFlag != nil && cancelFlag.ShutDown()) {
+				// like in a sequential run, the plugins which did not start yet run once the instance rebooted
+				// or the agent started again
+				results <- stepResult{index: index}
+				return
+			}
+
+			output, executed := runStep(context, pluginState, ioConfig, pluginRegistry, cancelFlag, atomic.LoadInt32(&exited) != 0)
+			if executed && output.Status.IsReboot() {
+				atomic.StoreInt32(&rebooting, 1)
+			}
+			if executed && exitsDocument(pluginState.Configuration, output) {
+				atomic.StoreInt32(&exited, 1)
+			}
+			results <- stepResult{index: index, output: output, executed: executed}
+		}(index, pluginState)
+	}
+
+	pluginOutputs = make(map[string]*contracts.PluginResult)
+	ordered := make([]*stepResult, len(plugins))
+	next := 0
+	for range plugins {
+		result := <-results
+		ordered[result.index] = &result
+		for ; next < len(plugins) && ordered[next] != nil; next++ {
+			if ordered[next].output == nil {
+				continue
+			}
+			pluginID := plugins[next].Id
+			pluginOutputs[pluginID] = ordered[next].output
+			if ordered[next].executed {
+				context.Log().Infof("Sending plugin %v completion message", pluginID)
+				resChan <- *ordered[next].output
+			}
+		}
+	}
+	return
+}
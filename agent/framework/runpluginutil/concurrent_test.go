@@ -0,0 +1,109 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingPlugin reports when a step starts and completes it once the test releases the step
+type blockingPlugin struct {
+	started  chan string
+	releases map[string]chan struct{}
+}
+
+func (p *blockingPlugin) Create(context context.T) (T, error) {
+	return p, nil
+}
+
+func (p *blockingPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	p.started <- config.PluginID
+	<-p.releases[config.PluginID]
+	output.MarkAsSucceeded()
+}
+
+func waitForStart(t *testing.T, started chan string) string {
+	select {
+	case id := <-started:
+		return id
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "step did not start")
+		return ""
+	}
+}
+
+func TestRunPluginsConcurrently(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+
+	plugin := &blockingPlugin{
+		started:  make(chan string, 3),
+		releases: map[string]chan struct{}{"a": make(chan struct{}), "b": make(chan struct{}), "c": make(chan struct{})},
+	}
+	orchestrationDir, err := ioutil.TempDir("", "concurrent")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	var plugins []contracts.PluginState
+	for _, step := range []struct {
+		id        string
+		dependsOn []string
+	}{{"a", nil}, {"b", nil}, {"c", []string{"a"}}} {
+		plugins = append(plugins, contracts.PluginState{
+			Id:   step.id,
+			Name: testPlugin1,
+			Configuration: contracts.Configuration{
+				PluginID:   step.id,
+				PluginName: testPlugin1,
+				DependsOn:  step.dependsOn,
+			},
+		})
+	}
+
+	resChan := make(chan contracts.PluginResult, len(plugins))
+	done := make(chan map[string]*contracts.PluginResult)
+	go func() {
+		done <- RunPluginsConcurrently(context.NewMockDefault(), plugins, contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}, PluginRegistry{testPlugin1: plugin}, resChan, task.NewChanneledCancelFlag(), 2)
+	}()
+
+	// the independent steps run at the same time, the dependent one waits for its dependency
+	first := []string{waitForStart(t, plugin.started), waitForStart(t, plugin.started)}
+	sort.Strings(first)
+	assert.Equal(t, []string{"a", "b"}, first)
+	close(plugin.releases["a"])
+	assert.Equal(t, "c", waitForStart(t, plugin.started))
+	close(plugin.releases["c"])
+	close(plugin.releases["b"])
+
+	outputs := <-done
+	close(resChan)
+	var order []string
+	for result := range resChan {
+		order = append(order, result.PluginID)
+		assert.Equal(t, contracts.ResultStatusSuccess, result.Status)
+	}
+	// results are reported in the order of the document regardless of completion order
+	assert.Equal(t, []string{"a", "b", "c"}, order)
+	assert.Len(t, outputs, 3)
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	paramutil "github.com/aws/amazon-ssm-agent/agent/parameters"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// tagOperandPrefix is the prefix of the operands referring to the tags of the agent configuration, e.g. "tag:Environment"
+const tagOperandPrefix = "tag:"
+
+// instanceFacts are the variables describing the instance which can be used as precondition operands
+var instanceFacts = map[string]func(log log.T) (string, error){
+	"platformType":    platform.PlatformType,
+	"platformName":    platform.PlatformName,
+	"platformVersion": platform.PlatformVersion,
+	"environment":     platform.Environment,
+	"arch": func(log.T) (string, error) {
+		return runtime.GOARCH, nil
+	},
+	"agentVersion": func(log.T) (string, error) {
+		return version.Version, nil
+	},
+}
+
+// agentTags returns the tags of the agent configuration, loaded when a precondition refers to them
+var agentTags = func() map[string]string {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return nil
+	}
+	return config.Agent.Tags
+}
+
+// preconditionOperators compare the values of the two operands of a precondition
+var preconditionOperators = map[string]func(left, right string) (bool, error){
+	"StringEquals": func(left, right string) (bool, error) {
+		return strings.EqualFold(left, right), nil
+	},
+	"StringNotEquals": func(left, right string) (bool, error) {
+		return !strings.EqualFold(left, right), nil
+	},
+	"StringLike": func(value, pattern string) (bool, error) {
+		return path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	},
+	"VersionGreaterThanOrEqual": func(left, right string) (bool, error) {
+		result, err := updateutil.VersionCompare(left, right)
+		return result >= 0, err
+	},
+	"VersionLessThan": func(left, right string) (bool, error) {
+		result, err := updateutil.VersionCompare(left, right)
+		return result < 0, err
+	},
+}
+
+// Evaluate precondition and return precondition result and unrecognized preconditions (if any).
+// Every precondition compares two operands, at least one of them being a variable: an instance fact,
+// a tag of the agent configuration or a document parameter. All preconditions must hold for the step to run.
+func evaluatePreconditions(
+	log log.T,
+	preconditions map[string][]string,
+	parameters map[string]string,
+) (bool, []string) {
+
+	var isAllowed = true
+	var unrecognizedPreconditionList []string
+
+	for key, value := range preconditions {
+		unrecognized := fmt.Sprintf("\"%s\": %v", key, value)
+		operator, found := preconditionOperators[key]
+		if !found || len(value) != 2 || value[0] == value[1] {
+			unrecognizedPreconditionList = append(unrecognizedPreconditionList, unrecognized)
+			continue
+		}
+
+		left, isLeftVariable, leftErr := resolveOperand(log, value[0], parameters)
+		right, isRightVariable, rightErr := resolveOperand(log, value[1], parameters)
+		if !isLeftVariable && !isRightVariable {
+			unrecognizedPreconditionList = append(unrecognizedPreconditionList, unrecognized)
+			continue
+		}
+		if leftErr != nil || rightErr != nil {
+			// a fact which cannot be determined on this instance does not match any value
+			log.Warnf("failed to evaluate precondition %v: %v %v", unrecognized, leftErr, rightErr)
+			isAllowed = false
+			continue
+		}
+
+		matched, err := operator(left, right)
+		if err != nil {
+			unrecognizedPreconditionList = append(unrecognizedPreconditionList, unrecognized)
+			continue
+		}
+		log.Debugf("precondition %v evaluated to %v with values %v and %v", unrecognized, matched, left, right)
+		if !matched {
+			// if precondition doesn't match, mark step for skip
+			isAllowed = false
+		}
+	}
+
+	return isAllowed, unrecognizedPreconditionList
+}
+
+// resolveOperand returns the value of a precondition operand and whether the operand is a variable.
+func resolveOperand(log log.T, operand string, parameters map[string]string) (value string, isVariable bool, err error) {
+	if fact, found := instanceFacts[operand]; found {
+		value, err = fact(log)
+		return value, true, err
+	}
+	if strings.HasPrefix(operand, tagOperandPrefix) {
+		return agentTags()[strings.TrimPrefix(operand, tagOperandPrefix)], true, nil
+	}
+	if paramName, isReference := paramutil.ParameterReference(operand); isReference {
+		if value, found := parameters[paramName]; found {
+			return value, true, nil
+		}
+		return "", true, fmt.Errorf("parameter %v is not defined", paramName)
+	}
+	return operand, false, nil
+}
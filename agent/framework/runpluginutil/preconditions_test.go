@@ -0,0 +1,78 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatePreconditionsWithFactsAndParameters(t *testing.T) {
+	logger := log.NewMockLog()
+	parameters := map[string]string{"installDocker": "true"}
+
+	isAllowed, unrecognized := evaluatePreconditions(logger, map[string][]string{
+		"StringEquals":              {"{{ installDocker }}", "True"},
+		"StringLike":                {"arch", runtime.GOARCH[:2] + "*"},
+		"VersionGreaterThanOrEqual": {"agentVersion", "1.0"},
+	}, parameters)
+	assert.True(t, isAllowed)
+	assert.Empty(t, unrecognized)
+
+	isAllowed, unrecognized = evaluatePreconditions(logger, map[string][]string{
+		"StringNotEquals": {"{{installDocker}}", "true"},
+	}, parameters)
+	assert.False(t, isAllowed)
+	assert.Empty(t, unrecognized)
+
+	// undefined parameters never match
+	isAllowed, unrecognized = evaluatePreconditions(logger, map[string][]string{
+		"StringEquals": {"{{ missing }}", "true"},
+	}, parameters)
+	assert.False(t, isAllowed)
+	assert.Empty(t, unrecognized)
+}
+
+func TestEvaluatePreconditionsWithTags(t *testing.T) {
+	defer func(tags func() map[string]string) { agentTags = tags }(agentTags)
+	agentTags = func() map[string]string { return map[string]string{"Environment": "production"} }
+
+	isAllowed, unrecognized := evaluatePreconditions(log.NewMockLog(), map[string][]string{
+		"StringEquals": {"tag:Environment", "Production"},
+	}, nil)
+	assert.True(t, isAllowed)
+	assert.Empty(t, unrecognized)
+
+	isAllowed, _ = evaluatePreconditions(log.NewMockLog(), map[string][]string{
+		"StringEquals": {"tag:Team", "platform"},
+	}, nil)
+	assert.False(t, isAllowed)
+}
+
+func TestEvaluatePreconditionsUnrecognized(t *testing.T) {
+	logger := log.NewMockLog()
+	for _, preconditions := range []map[string][]string{
+		{"StringEquals": {"foo", "bar"}},
+		{"StringEquals": {"platformType", "platformType"}},
+		{"StringEquals": {"platformType"}},
+		{"NumberEquals": {"arch", "1"}},
+		{"VersionLessThan": {"agentVersion", "latest"}},
+	} {
+		_, unrecognized := evaluatePreconditions(logger, preconditions, nil)
+		assert.Len(t, unrecognized, 1, "%v", preconditions)
+	}
+}
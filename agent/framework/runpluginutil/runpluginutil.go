@@ -22,12 +22,16 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/correlation"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
-	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/redact"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -54,28 +58,32 @@ var SSMPluginRegistry PluginRegistry
 // This allows us to differentiate between the case where a document asks for a plugin that exists but isn't supported on this platform
 // and the case where a plugin name isn't known at all to this version of the agent (and the user should probably upgrade their agent)
 var allPlugins = map[string]struct{}{
-	appconfig.PluginNameAwsAgentUpdate:         {},
-	appconfig.PluginNameAwsApplications:        {},
-	appconfig.PluginNameAwsConfigureDaemon:     {},
-	appconfig.PluginNameAwsConfigurePackage:    {},
-	appconfig.PluginNameAwsPowerShellModule:    {},
-	appconfig.PluginNameAwsRunPowerShellScript: {},
-	appconfig.PluginNameAwsRunShellScript:      {},
-	appconfig.PluginNameAwsSoftwareInventory:   {},
-	appconfig.PluginNameCloudWatch:             {},
-	appconfig.PluginNameConfigureDocker:        {},
-	appconfig.PluginNameDockerContainer:        {},
-	appconfig.PluginNameDomainJoin:             {},
-	appconfig.PluginEC2ConfigUpdate:            {},
-	appconfig.PluginNameRefreshAssociation:     {},
-	appconfig.PluginDownloadContent:            {},
-	appconfig.PluginRunDocument:                {},
+	appconfig.PluginNameAwsAgentUpdate:            {},
+	appconfig.PluginNameAwsApplications:           {},
+	appconfig.PluginNameAwsConfigureDaemon:        {},
+	appconfig.PluginNameAwsConfigurePackage:       {},
+	appconfig.PluginNameAwsPowerShellModule:       {},
+	appconfig.PluginNameAwsRunPowerShellScript:    {},
+	appconfig.PluginNameAwsRunShellScript:         {},
+	appconfig.PluginNameAwsSoftwareInventory:      {},
+	appconfig.PluginNameCloudWatch:                {},
+	appconfig.PluginNameConfigureDocker:           {},
+	appconfig.PluginNameConfigureKernelParameters: {},
+	appconfig.PluginNameDockerContainer:           {},
+	appconfig.PluginNameDomainJoin:                {},
+	appconfig.PluginEC2ConfigUpdate:               {},
+	appconfig.PluginNameRefreshAssociation:        {},
+	appconfig.PluginDownloadContent:               {},
+	appconfig.PluginRunDocument:                   {},
 }
 
 // Assign method to global variables to allow unittest to override
 var isSupportedPlugin = IsPluginSupportedForCurrentPlatform
+var resolveSecureString = parameterstore.ResolveSecureString
+var markStepStarted = journalStepStart
+var isStepStarted = isJournaledStep
 
-//TODO remove executionID and creation date
+// TODO remove executionID and creation date
 // RunPlugins executes a set of plugins. The plugin configurations are given in a map with pluginId as key.
 // Outputs the results of running the plugins, indexed by pluginId.
 // Make this function private in case everybody tries to reference it everywhere, this is a private member of Executer
@@ -89,110 +97,174 @@ func RunPlugins(
 ) (pluginOutputs map[string]*contracts.PluginResult) {
 
 	pluginOutputs = make(map[string]*contracts.PluginResult)
+	exited := false
 
 	for _, pluginState := range plugins {
-		pluginID := pluginState.Id     // the identifier of the plugin
-		pluginName := pluginState.Name // the name of the plugin
-		pluginOutput := pluginState.Result
-		pluginOutput.PluginID = pluginID
-		pluginOutput.PluginName = pluginName
-		pluginOutputs[pluginID] = &pluginOutput
-		switch pluginOutput.Status {
-		//TODO properly initialize the plugin status
-		case "":
-			context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
-				pluginName)
-			pluginOutput.StartDateTime = time.Now()
-			pluginOutput.Status = contracts.ResultStatusNotStarted
-
-		case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
-			context.Log().Debugf("plugin - %v status %v",
-				pluginName,
-				pluginOutput.Status)
-			pluginOutput.StartDateTime = time.Now()
+		if cancelFlag != nil && cancelFlag.ShutDown() {
+			// the plugins which did not start yet run once the agent starts again and resumes the document
+			context.Log().Infof("agent is stopping, plugin %v is not started", pluginState.Id)
+			break
+		}
+		pluginOutput, executed := runStep(context, pluginState, ioConfig, pluginRegistry, cancelFlag, exited)
+		pluginOutputs[pluginState.Id] = pluginOutput
+		if !executed {
+			continue
+		}
+		exited = exitsDocument(pluginState.Configuration, pluginOutput) || exited
 
-		case contracts.ResultStatusSuccessAndReboot:
-			context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
-				pluginName)
-			pluginOutput.Status = contracts.ResultStatusInProgress
+		context.Log().Infof("Sending plugin %v completion message", pluginState.Id)
+		// send to buffer channel, guaranteed to not block since buffer size is plugin number
+		resChan <- *pluginOutput
 
-		default:
-			context.Log().Debugf("plugin - %v already executed, skipping...",
-				pluginName)
-			continue
+		//TODO handle cancelFlag here
+		if pluginOutput.Status.IsReboot() {
+			// do not execute the the next plugin
+			break
 		}
+	}
 
-		context.Log().Debugf("Executing plugin - %v", pluginName)
+	return
+}
 
-		// populate plugin start time and status
-		configuration := pluginState.Configuration
+// runStep executes one plugin of a document unless it already completed and returns its result,
+// executed is false when the plugin was not run again. Once an earlier step exited the document,
+// only the finally step runs.
+func runStep(
+	context context.T,
+	pluginState contracts.PluginState,
+	ioConfig contracts.IOConfiguration,
+	pluginRegistry PluginRegistry,
+	cancelFlag task.CancelFlag,
+	exited bool,
+) (pluginOutput *contracts.PluginResult, executed bool) {
+	pluginID := pluginState.Id     // the identifier of the plugin
+	pluginName := pluginState.Name // the name of the plugin
+	pluginOutput = &contracts.PluginResult{}
+	*pluginOutput = pluginState.Result
+	pluginOutput.PluginID = pluginID
+	pluginOutput.PluginName = pluginName
+	switch pluginOutput.Status {
+	//TODO properly initialize the plugin status
+	case "":
+		context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
+			pluginName)
+		pluginOutput.StartDateTime = time.Now()
+		pluginOutput.Status = contracts.ResultStatusNotStarted
+
+	case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
+		context.Log().Debugf("plugin - %v status %v",
+			pluginName,
+			pluginOutput.Status)
+		pluginOutput.StartDateTime = time.Now()
 
-		if ioConfig.OutputS3BucketName != "" {
-			pluginOutputs[pluginID].OutputS3BucketName = ioConfig.OutputS3BucketName
-			if ioConfig.OutputS3KeyPrefix != "" {
-				pluginOutputs[pluginID].OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
+	case contracts.ResultStatusSuccessAndReboot:
+		context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
+			pluginName)
+		pluginOutput.Status = contracts.ResultStatusInProgress
 
-			}
-		}
-		var r contracts.PluginResult
-		pluginHandlerFound := false
+	case contracts.ResultStatusPassedAndReboot:
+		context.Log().Debugf("plugin - %v completed before the reboot, resuming at the next step...",
+			pluginName)
+		pluginOutput.Status = contracts.ResultStatusSuccess
+		return pluginOutput, false
 
-		//check if the said plugin is a worker plugin
-		p, pluginHandlerFound := pluginRegistry[pluginName]
+	default:
+		context.Log().Debugf("plugin - %v already executed, skipping...",
+			pluginName)
+		return pluginOutput, false
+	}
 
-		isKnown, isSupported, _ := isSupportedPlugin(context.Log(), pluginName)
-		operation, logMessage := getStepExecutionOperation(
-			context.Log(),
-			pluginName,
-			pluginID,
-			isKnown,
-			isSupported,
-			pluginHandlerFound,
-			configuration.IsPreconditionEnabled,
-			configuration.Preconditions)
-
-		switch operation {
-		case executeStep:
-			context.Log().Infof("Running plugin %s", pluginName)
-			r = runPlugin(context, p, pluginName, configuration, cancelFlag, ioConfig)
-			pluginOutputs[pluginID].Code = r.Code
-			pluginOutputs[pluginID].Status = r.Status
-			pluginOutputs[pluginID].Error = r.Error
-			pluginOutputs[pluginID].Output = r.Output
-			pluginOutputs[pluginID].StandardOutput = r.StandardOutput
-			pluginOutputs[pluginID].StandardError = r.StandardError
-
-		case skipStep:
-			context.Log().Info(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusSkipped
-			pluginOutputs[pluginID].Code = 0
-			pluginOutputs[pluginID].Output = logMessage
-		case failStep:
-			err := fmt.Errorf(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err
-			context.Log().Error(err)
-		default:
-			err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err
-			context.Log().Error(err)
-		}
+	// a step which started before the agent stopped without saving its result is failed rather than run twice,
+	// unlike a step which requested a reboot and expects to run again
+	if pluginState.Result.Status != contracts.ResultStatusSuccessAndReboot && isStepStarted(ioConfig.OrchestrationDirectory, pluginID) {
+		context.Log().Warnf("plugin - %v was interrupted before completing, it is not run again", pluginName)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Code = 1
+		pluginOutput.Output = "Step was interrupted by the agent stopping and is not run again to avoid executing it twice"
+		pluginOutput.EndDateTime = time.Now()
+		return pluginOutput, true
+	}
 
-		// set end time.
-		pluginOutputs[pluginID].EndDateTime = time.Now()
-		context.Log().Infof("Sending plugin %v completion message", pluginID)
-		// send to buffer channel, guaranteed to not block since buffer size is plugin number
-		resChan <- *pluginOutputs[pluginID]
+	context.Log().Debugf("Executing plugin - %v", pluginName)
 
-		//TODO handle cancelFlag here
-		if pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot {
-			// do not execute the the next plugin
+	// populate plugin start time and status
+	configuration := pluginState.Configuration
+
+	if ioConfig.OutputS3BucketName != "" {
+		pluginOutput.OutputS3BucketName = ioConfig.OutputS3BucketName
+		if ioConfig.OutputS3KeyPrefix != "" {
+			pluginOutput.OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
+
+		}
+	}
+	var r contracts.PluginResult
+	pluginHandlerFound := false
+
+	//check if the said plugin is a worker plugin
+	p, pluginHandlerFound := pluginRegistry[pluginName]
+
+	isKnown, isSupported, _ := isSupportedPlugin(context.Log(), pluginName)
+	operation, logMessage := getStepExecutionOperation(
+		context.Log(),
+		pluginName,
+		pluginID,
+		isKnown,
+		isSupported,
+		pluginHandlerFound,
+		configuration.IsPreconditionEnabled,
+		configuration.Preconditions,
+		configuration.PreconditionParameters)
+	if operation == executeStep && exited && !configuration.FinallyStep {
+		operation = skipStep
+		logMessage = fmt.Sprintf("Step execution skipped since an earlier step exited the document. Step name: %s", pluginID)
+	}
+
+	switch operation {
+	case executeStep:
+		if err := markStepStarted(ioConfig.OrchestrationDirectory, pluginID); err != nil {
+			err = fmt.Errorf("failed to record the start of plugin %v: %v", pluginName, err)
+			pluginOutput.Status = contracts.ResultStatusFailed
+			pluginOutput.Error = err
+			context.Log().Error(err)
 			break
 		}
+		context.Log().Infof("Running plugin %s", pluginName)
+		downloadedBefore := artifact.DownloadedBytes()
+		stepFlag := stepCancelFlag(configuration, cancelFlag)
+		r = runPlugin(context, p, pluginName, configuration, stepFlag, ioConfig)
+		if stepFlag != cancelFlag {
+			// wake up the plugin goroutines waiting on the flag of the cleanup step
+			stepFlag.Set(task.Completed)
+		}
+		pluginOutput.DownloadedBytes = artifact.DownloadedBytes() - downloadedBefore
+		pluginOutput.Code = r.Code
+		pluginOutput.Status = r.Status
+		pluginOutput.Error = r.Error
+		pluginOutput.Output = r.Output
+		pluginOutput.StandardOutput = r.StandardOutput
+		pluginOutput.StandardError = r.StandardError
+		pluginOutput.Result = r.Result
+
+	case skipStep:
+		context.Log().Info(logMessage)
+		pluginOutput.Status = contracts.ResultStatusSkipped
+		pluginOutput.Code = 0
+		pluginOutput.Output = logMessage
+	case failStep:
+		err := fmt.Errorf(logMessage)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Error = err
+		context.Log().Error(err)
+	default:
+		err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Error = err
+		context.Log().Error(err)
 	}
 
-	return
+	// set end time.
+	pluginOutput.EndDateTime = time.Now()
+	return pluginOutput, true
 }
 
 func runPlugin(
@@ -202,8 +274,12 @@ func runPlugin(
 	config contracts.Configuration,
 	cancelFlag task.CancelFlag,
 	ioConfig contracts.IOConfiguration) (res contracts.PluginResult) {
-	// create a new context that includes plugin ID
+	// create a new context that includes plugin ID and the correlation ID of the step
 	context = context.With("[pluginName=" + pluginName + "]")
+	if config.CorrelationID != "" {
+		context = context.With(correlation.LogContext(config.CorrelationID))
+		ioConfig.CorrelationID = config.CorrelationID
+	}
 
 	log := context.Log()
 	defer func() {
@@ -230,7 +306,28 @@ func runPlugin(
 	res.StartDateTime = time.Now()
 	defer func() { res.EndDateTime = time.Now() }()
 
+	// SecureString parameters are resolved in memory right before the plugin runs so their values
+	// are never saved with the document, and are masked from the logs and the output of the plugin
+	config, redacted, err := resolveSecureParameters(log, pluginName, config)
+	if err != nil {
+		res.Status = contracts.ResultStatusFailed
+		res.Code = 1
+		res.Error = fmt.Errorf("failed to resolve SecureString parameters - %v", err)
+		log.Error(res.Error)
+		return
+	}
+	if len(redacted) > 0 {
+		context = &redactedContext{T: context, values: redacted}
+		log = context.Log()
+		defer func() {
+			if err := redacted.Directory(config.OrchestrationDirectory); err != nil {
+				log.Warnf("failed to redact SecureString parameters from %v - %v", config.OrchestrationDirectory, err)
+			}
+		}()
+	}
+
 	output := iohandler.NewDefaultIOHandler(log, ioConfig)
+	output.SetRedactedValues(redacted)
 	//check if properties is a list. If true, then unroll
 	switch config.Properties.(type) {
 	case []interface{}:
@@ -242,6 +339,7 @@ func runPlugin(
 		for _, prop := range properties {
 			config.Properties = prop
 			propOutput := iohandler.NewDefaultIOHandler(log, ioConfig)
+			propOutput.SetRedactedValues(redacted)
 			executePlugin(context, p, pluginName, config, cancelFlag, propOutput)
 			output.Merge(log, propOutput)
 		}
@@ -256,9 +354,88 @@ func runPlugin(
 	res.Output = output.GetOutput()
 	res.StandardOutput = pluginutil.StringPrefix(output.GetStdout(), pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
 	res.StandardError = pluginutil.StringPrefix(output.GetStderr(), pluginConfig.MaxStderrLength, pluginConfig.OutputTruncatedSuffix)
+	res.Result = output.GetResult()
 	return
 }
 
+// unresolvedProperties are the properties whose ssm-secure references are resolved by the plugin itself, by plugin name
+var unresolvedProperties = map[string][]string{
+	// the GitHub tokenInfo, the Git repository token and the HTTP headers are references to SecureString parameters
+	appconfig.PluginDownloadContent: {"sourceInfo"},
+}
+
+// resolveSecureParameters resolves the SecureString parameters referenced by the plugin configuration and
+// returns the values to redact
+func resolveSecureParameters(log log.T, pluginName string, config contracts.Configuration) (resolved contracts.Configuration, redacted redact.Values, err error) {
+	var propertyValues, settingValues []string
+	properties, kept := splitUnresolvedProperties(config.Properties, unresolvedProperties[pluginName])
+	if properties, propertyValues, err = resolveSecureString(log, properties); err != nil {
+		return config, nil, err
+	}
+	config.Properties = mergeUnresolvedProperties(properties, kept)
+	if config.Settings, settingValues, err = resolveSecureString(log, config.Settings); err != nil {
+		return config, nil, err
+	}
+	return config, redact.New(append(propertyValues, settingValues...)...), nil
+}
+
+// splitUnresolvedProperties returns a copy of the properties without the given names, and the properties removed
+func splitUnresolvedProperties(properties interface{}, names []string) (interface{}, map[string]interface{}) {
+	propertyMap, ok := properties.(map[string]interface{})
+	if !ok || len(names) == 0 {
+		return properties, nil
+	}
+	resolvable := make(map[string]interface{}, len(propertyMap))
+	kept := make(map[string]interface{})
+	for key, value := range propertyMap {
+		if isUnresolvedProperty(key, names) {
+			kept[key] = value
+		} else {
+			resolvable[key] = value
+		}
+	}
+	return resolvable, kept
+}
+
+// mergeUnresolvedProperties adds the properties removed by splitUnresolvedProperties back to the resolved properties
+func mergeUnresolvedProperties(properties interface{}, kept map[string]interface{}) interface{} {
+	propertyMap, ok := properties.(map[string]interface{})
+	if !ok || len(kept) == 0 {
+		return properties
+	}
+	for key, value := range kept {
+		propertyMap[key] = value
+	}
+	return propertyMap
+}
+
+// isUnresolvedProperty tells if the property is one of names, the names being matched without regard to case
+// like the properties are unmarshalled by the plugins
+func isUnresolvedProperty(key string, names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedContext is a context whose logger masks the values of the SecureString parameters
+type redactedContext struct {
+	context.T
+	values redact.Values
+}
+
+// Log returns the logger which masks the values
+func (c *redactedContext) Log() log.T {
+	return redact.NewLogger(c.T.Log(), c.values)
+}
+
+// With returns a sub context which masks the values
+func (c *redactedContext) With(logContext string) context.T {
+	return &redactedContext{T: c.T.With(logContext), values: c.values}
+}
+
 func executePlugin(context context.T,
 	p T,
 	pluginName string,
@@ -307,6 +484,7 @@ func getStepExecutionOperation(
 	isPluginHandlerFound bool,
 	isPreconditionEnabled bool,
 	preconditions map[string][]string,
+	preconditionParameters map[string]string,
 ) (string, string) {
 	log.Debugf("isSupported flag = %t", isSupported)
 	log.Debugf("isPluginHandlerFound flag = %t", isPluginHandlerFound)
@@ -358,7 +536,7 @@ func getStepExecutionOperation(
 		} else {
 			log.Debugf("Cross-platform Precondition is present, precondition = %v", preconditions)
 
-			isAllowed, unrecognizedPreconditionList := evaluatePreconditions(log, preconditions)
+			isAllowed, unrecognizedPreconditionList := evaluatePreconditions(log, preconditions, preconditionParameters)
 
 			if isAllowed && !isKnown {
 				return failStep, fmt.Sprintf(
@@ -381,49 +559,15 @@ func getStepExecutionOperation(
 	}
 }
 
-// Evaluate precondition and return precondition result and unrecognized preconditions (if any)
-func evaluatePreconditions(
-	log log.T,
-	preconditions map[string][]string,
-) (bool, []string) {
-
-	var isAllowed = true
-	var unrecognizedPreconditionList []string
-
-	// For current release, we only support "StringEquals" operator and "platformType"
-	// operand, so explicitly checking for those and number of operands must be 2
-	for key, value := range preconditions {
-		switch key {
-		case "StringEquals":
-			// Platform type of OS on the instance
-			instancePlatformType, _ := platform.PlatformType(log)
-			log.Debugf("OS platform type of this instance = %s", instancePlatformType)
-
-			if len(value) != 2 ||
-				(strings.Compare(value[0], "platformType") == 0 && strings.Compare(value[1], "platformType") == 0) ||
-				(strings.Compare(value[0], "platformType") != 0 && strings.Compare(value[1], "platformType") != 0) {
-
-				unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %v", key, value))
-			} else {
-				// Variable and value can be in any order, i.e. both "StringEquals": ["platformType", "Windows"]
-				// and "StringEquals": ["Windows", "platformType"] are valid
-				var platformTypeValue string
-				if strings.Compare(value[0], "platformType") == 0 {
-					platformTypeValue = value[1]
-				} else {
-					platformTypeValue = value[0]
-				}
-
-				if strings.Compare(instancePlatformType, strings.ToLower(platformTypeValue)) != 0 {
-					// if precondition doesn't match for platformType, mark step for skip
-					isAllowed = false
-				}
-			}
-		default:
-			// mark for unrecognizedPrecondition (which is a form of failure)
-			unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %v", key, value))
-		}
+// journalStepStart records the start of a step in the orchestration directory of the document, if it has one
+func journalStepStart(orchestrationDir, pluginID string) error {
+	if orchestrationDir == "" {
+		return nil
 	}
+	return docmanager.MarkStepStarted(orchestrationDir, pluginID)
+}
 
-	return isAllowed, unrecognizedPreconditionList
+// isJournaledStep returns true if the start of the step was recorded in the orchestration directory of the document
+func isJournaledStep(orchestrationDir, pluginID string) bool {
+	return orchestrationDir != "" && docmanager.IsStepStarted(orchestrationDir, pluginID)
 }
@@ -0,0 +1,46 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+)
+
+// unsupportedPlugins are the plugins of the unix agent which depend on tools or services that macOS doesn't provide
+var unsupportedPlugins = map[string]struct{}{
+	appconfig.PluginNameAwsApplications:           {},
+	appconfig.PluginNameCloudWatch:                {},
+	appconfig.PluginNameConfigureDocker:           {},
+	appconfig.PluginNameConfigureKernelParameters: {},
+	appconfig.PluginNameDockerContainer:           {},
+	appconfig.PluginNameDomainJoin:                {},
+	appconfig.PluginEC2ConfigUpdate:               {},
+}
+
+// IsPluginSupportedForCurrentPlatform returns true if macOS supports the plugin with given name.
+func IsPluginSupportedForCurrentPlatform(log log.T, pluginName string) (isKnown bool, isSupported bool, message string) {
+	_, known := allPlugins[pluginName]
+	_, unsupported := unsupportedPlugins[pluginName]
+	platformName, _ := platform.PlatformName(log)
+	platformVersion, _ := platform.PlatformVersion(log)
+
+	return known, !unsupported, fmt.Sprintf("%s v%s", platformName, platformVersion)
+}
@@ -0,0 +1,36 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnownUnsupportedOnMacOS(t *testing.T) {
+	isKnown, isSupported, _ := IsPluginSupportedForCurrentPlatform(mockLog, appconfig.PluginNameDomainJoin)
+	assert.True(t, isKnown)
+	assert.False(t, isSupported)
+}
+
+func TestDownloadContentSupportedOnMacOS(t *testing.T) {
+	isKnown, isSupported, _ := IsPluginSupportedForCurrentPlatform(mockLog, appconfig.PluginDownloadContent)
+	assert.True(t, isKnown)
+	assert.True(t, isSupported)
+}
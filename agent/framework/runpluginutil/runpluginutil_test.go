@@ -16,11 +16,16 @@ package runpluginutil
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
@@ -68,7 +73,9 @@ func TestRunPluginsWithNewDocument(t *testing.T) {
 	ctx := context.NewMockDefault()
 	defaultTime := time.Now()
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
-	ioConfig := contracts.IOConfiguration{}
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
 
 	for index, name := range pluginNames {
 
@@ -231,7 +238,6 @@ func TestRunPluginsWithMissingPluginHandler(t *testing.T) {
 
 }
 
-//TODO cancelFlag should not fail subsequent plugins
 func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
@@ -244,7 +250,9 @@ func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 	ctx := context.NewMockDefault()
 	defaultTime := time.Now()
-	ioConfig := contracts.IOConfiguration{}
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
 
 	for index, name := range pluginNames {
 		plugins[name] = new(PluginMock)
@@ -271,8 +279,6 @@ func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 				flag.Set(task.ShutDown)
 			}).Return()
 
-		} else {
-			plugins[name].On("Execute", ctx, pluginState.Configuration, cancelFlag, mock.Anything).Return()
 		}
 		pluginStates[index] = pluginState
 		pluginFactory := new(PluginFactoryMock)
@@ -296,8 +302,9 @@ func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 	}
 	ctx.AssertCalled(t, "Log")
 	assert.Equal(t, pluginResults[testPlugin1], outputs[testPlugin1])
-	//empty struct
-	assert.Equal(t, pluginResults[testPlugin2], outputs[testPlugin2])
+	// the plugin after the shutdown is left to run once the document resumes
+	assert.Len(t, outputs, 1)
+	assert.Len(t, ch, 1)
 }
 
 func TestRunPluginsWithInProgressDocuments(t *testing.T) {
@@ -308,7 +315,9 @@ func TestRunPluginsWithInProgressDocuments(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	plugins := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 	ctx := context.NewMockDefault()
@@ -406,7 +415,9 @@ func TestRunPluginsWithDuplicatePluginType(t *testing.T) {
 	// create an instance of our test object
 	plugin := new(PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
 
 	var cancelFlag task.CancelFlag
 	ctx := context.NewMockDefault()
@@ -491,7 +502,9 @@ func TestRunPluginsWithCompatiblePrecondition(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -581,7 +594,9 @@ func TestRunPluginsWithCompatiblePreconditionWithValueFirst(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -1330,7 +1345,9 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -1427,3 +1444,158 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 
 	assert.Equal(t, pluginResults, outputs)
 }
+
+// echoPlugin writes its command to the output
+type echoPlugin struct {
+	config contracts.Configuration
+}
+
+func (p *echoPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	p.config = config
+	output.AppendInfo(config.Properties.(map[string]interface{})["command"].(string))
+	output.MarkAsSucceeded()
+}
+
+// TestRunPluginResolvesSecureString tests that SecureString parameters are resolved for the plugin and redacted from its output.
+func TestRunPluginResolvesSecureString(t *testing.T) {
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	defer func(original func(log.T, interface{}) (interface{}, []string, error)) { resolveSecureString = original }(resolveSecureString)
+	resolveSecureString = func(log log.T, input interface{}) (interface{}, []string, error) {
+		if properties, ok := input.(map[string]interface{}); ok {
+			return map[string]interface{}{"command": strings.Replace(properties["command"].(string), "{{ssm-secure:pwd}}", "s3cr3t", -1)}, []string{"s3cr3t"}, nil
+		}
+		return input, nil, nil
+	}
+
+	plugin := &echoPlugin{}
+	factory := new(PluginFactoryMock)
+	factory.On("Create", mock.Anything).Return(plugin, nil)
+	properties := map[string]interface{}{"command": "login {{ssm-secure:pwd}}"}
+	config := contracts.Configuration{
+		Properties:             properties,
+		OrchestrationDirectory: orchestrationDir,
+		PluginName:             testPlugin1,
+		PluginID:               "step",
+	}
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+
+	res := runPlugin(context.NewMockDefault(), factory, testPlugin1, config, task.NewChanneledCancelFlag(), ioConfig)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Equal(t, "login s3cr3t", plugin.config.Properties.(map[string]interface{})["command"])
+	assert.Equal(t, "login ***", res.StandardOutput)
+	assert.Equal(t, "login {{ssm-secure:pwd}}", properties["command"])
+}
+
+// TestRunPluginKeepsDownloadContentSourceInfoReferences tests that the ssm-secure references of the sourceInfo of aws:downloadContent
+// are left for the plugin to resolve, while the other properties are resolved.
+func TestRunPluginKeepsDownloadContentSourceInfoReferences(t *testing.T) {
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+	defer func(original func(log.T, interface{}) (interface{}, []string, error)) { resolveSecureString = original }(resolveSecureString)
+	resolveSecureString = func(log log.T, input interface{}) (interface{}, []string, error) {
+		properties, ok := input.(map[string]interface{})
+		if !ok {
+			return input, nil, nil
+		}
+		resolved := map[string]interface{}{}
+		for key, value := range properties {
+			resolved[key] = strings.Replace(value.(string), "{{ssm-secure:dir}}", "/secret", -1)
+		}
+		return resolved, []string{"/secret"}, nil
+	}
+
+	plugin := &recordingPlugin{}
+	factory := new(PluginFactoryMock)
+	factory.On("Create", mock.Anything).Return(plugin, nil)
+	sourceInfo := `{"owner": "owner", "repository": "repository", "tokenInfo": "{{ ssm-secure:token }}", "path": "{{ssm-secure:dir}}"}`
+	config := contracts.Configuration{
+		Properties: map[string]interface{}{
+			"sourceType":      "GitHub",
+			"sourceInfo":      sourceInfo,
+			"destinationPath": "{{ssm-secure:dir}}",
+		},
+		OrchestrationDirectory: orchestrationDir,
+		PluginName:             appconfig.PluginDownloadContent,
+		PluginID:               "step",
+	}
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+
+	res := runPlugin(context.NewMockDefault(), factory, appconfig.PluginDownloadContent, config, task.NewChanneledCancelFlag(), ioConfig)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Equal(t, map[string]interface{}{
+		"sourceType":      "GitHub",
+		"sourceInfo":      sourceInfo,
+		"destinationPath": "/secret",
+	}, plugin.config.Properties)
+}
+
+// recordingPlugin records the configuration it runs with
+type recordingPlugin struct {
+	config contracts.Configuration
+}
+
+func (p *recordingPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	p.config = config
+	output.MarkAsSucceeded()
+}
+
+// TestRunStepInterrupted tests that a step which started before the agent stopped is failed instead of run again,
+// unless it requested a reboot.
+func TestRunStepInterrupted(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+
+	plugin := &echoPlugin{}
+	factory := new(PluginFactoryMock)
+	factory.On("Create", mock.Anything).Return(plugin, nil)
+	registry := PluginRegistry{testPlugin1: factory}
+	pluginState := contracts.PluginState{
+		Id:            "step",
+		Name:          testPlugin1,
+		Configuration: contracts.Configuration{Properties: map[string]interface{}{"command": "echo"}},
+	}
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	ctx := context.NewMockDefault()
+
+	output, executed := runStep(ctx, pluginState, ioConfig, registry, task.NewChanneledCancelFlag(), false)
+	assert.True(t, executed)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.Status)
+	factory.AssertNumberOfCalls(t, "Create", 1)
+
+	// the result of the step was not saved before the agent stopped
+	output, executed = runStep(ctx, pluginState, ioConfig, registry, task.NewChanneledCancelFlag(), false)
+	assert.True(t, executed)
+	assert.Equal(t, contracts.ResultStatusFailed, output.Status)
+	factory.AssertNumberOfCalls(t, "Create", 1)
+
+	pluginState.Result.Status = contracts.ResultStatusSuccessAndReboot
+	output, executed = runStep(ctx, pluginState, ioConfig, registry, task.NewChanneledCancelFlag(), false)
+	assert.True(t, executed)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.Status)
+	factory.AssertNumberOfCalls(t, "Create", 2)
+}
+
+func TestRunStepResumesAfterPassedAndReboot(t *testing.T) {
+	orchestrationDir, _ := ioutil.TempDir("", "runpluginutil")
+	defer os.RemoveAll(orchestrationDir)
+
+	factory := new(PluginFactoryMock)
+	registry := PluginRegistry{testPlugin1: factory}
+	pluginState := contracts.PluginState{
+		Id:   "step",
+		Name: testPlugin1,
+	}
+	pluginState.Result.Status = contracts.ResultStatusPassedAndReboot
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+
+	// the step completed before the reboot, the document resumes at the next step
+	output, executed := runStep(context.NewMockDefault(), pluginState, ioConfig, registry, task.NewChanneledCancelFlag(), false)
+	assert.False(t, executed)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.Status)
+	factory.AssertNotCalled(t, "Create", mock.Anything)
+}
@@ -11,7 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
-// +build darwin freebsd linux netbsd openbsd
+// +build freebsd linux netbsd openbsd
 
 // Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
 package runpluginutil
@@ -0,0 +1,153 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatescheduler implements the core module which starts the agent updates deferred until the update window opens.
+package updatescheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/update/processor"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/carlescere/scheduler"
+)
+
+const (
+	name = "UpdateScheduler"
+
+	// pollFrequencyMinutes is the frequency at which the deferred updates are checked
+	pollFrequencyMinutes = 1
+)
+
+// updateRoot is the folder the deferred updates are saved in by the update plugin
+var updateRoot = appconfig.UpdaterArtifactsRoot
+
+// exeCommand starts the updater
+var exeCommand = (&updateutil.Utility{}).ExeCommand
+
+// saveUpdatePluginResult saves the output of the update plugin for the updater to report it
+var saveUpdatePluginResult = (&updateutil.Utility{}).SaveUpdatePluginResult
+
+// sendReply reports the result of an update command which doesn't reach the updater
+var sendReply = processor.NewService().SendReply
+
+// UpdateScheduler hands the updates deferred by the update plugin over to the updater once the update window opens.
+// The deferred updates are saved on disk, so they are started after an agent restart too. The commands whose deferred
+// update is canceled or superseded by a newer update command are reported by the scheduler.
+type UpdateScheduler struct {
+	context context.T
+	pollJob *scheduler.Job
+}
+
+// NewUpdateScheduler creates a new update scheduler core module.
+func NewUpdateScheduler(context context.T) *UpdateScheduler {
+	return &UpdateScheduler{
+		context: context.With("[" + name + "]"),
+	}
+}
+
+// processDeferredUpdates reports the canceled and superseded deferred updates, and starts the updater for the
+// deferred updates whose update window opened, the updater reporting the result of their command
+func (s *UpdateScheduler) processDeferredUpdates() {
+	log := s.context.Log()
+	deferredUpdates, err := updateutil.LoadDeferredUpdates(log, updateRoot)
+	if err != nil {
+		log.Errorf("unable to load the deferred updates: %v", err)
+		return
+	}
+
+	for _, deferredUpdate := range deferredUpdates {
+		switch {
+		case deferredUpdate.Canceled:
+			s.completeDeferredUpdate(log, deferredUpdate, contracts.ResultStatusCancelled,
+				"Update was canceled before the update window opened")
+		case deferredUpdate.SupersededBy != "":
+			s.completeDeferredUpdate(log, deferredUpdate, contracts.ResultStatusFailed,
+				fmt.Sprintf("Update was superseded by command %v before the update window opened", deferredUpdate.SupersededBy))
+		case !time.Now().Before(deferredUpdate.StartDateTime):
+			s.startDeferredUpdate(log, deferredUpdate)
+		}
+	}
+}
+
+// startDeferredUpdate hands the deferred update over to the updater
+func (s *UpdateScheduler) startDeferredUpdate(log log.T, deferredUpdate *updateutil.DeferredUpdate) {
+	if deferredUpdate.PluginResult != nil {
+		if err := saveUpdatePluginResult(log, updateRoot, deferredUpdate.PluginResult); err != nil {
+			log.Errorf("unable to save the update plugin result of command %v: %v", deferredUpdate.CommandID, err)
+			return
+		}
+	}
+
+	// the deferred update is removed first so the updater is started only once
+	if err := updateutil.RemoveDeferredUpdate(updateRoot, deferredUpdate.CommandID); err != nil {
+		log.Errorf("unable to remove the deferred update of command %v: %v", deferredUpdate.CommandID, err)
+		return
+	}
+	log.Infof("Update window opened, handing the update deferred by command %v over to the updater", deferredUpdate.CommandID)
+	if err := exeCommand(log, deferredUpdate.UpdateCommand, deferredUpdate.WorkingDir, updateRoot, "", "", true); err != nil {
+		log.Errorf("unable to start the deferred update of command %v: %v", deferredUpdate.CommandID, err)
+	}
+}
+
+// completeDeferredUpdate sends the final reply of a command whose deferred update won't be started, the deferred
+// update being kept for the reply to be sent again at the next check when it fails
+func (s *UpdateScheduler) completeDeferredUpdate(log log.T, deferredUpdate *updateutil.DeferredUpdate, status contracts.ResultStatus, message string) {
+	log.Infof("%v, command %v", message, deferredUpdate.CommandID)
+	detail := &processor.UpdateDetail{
+		MessageID:     deferredUpdate.MessageID,
+		Result:        status,
+		StandardOut:   message,
+		StartDateTime: time.Now(),
+	}
+	if deferredUpdate.PluginResult != nil {
+		detail.StandardOut = deferredUpdate.PluginResult.StandOut + message
+		detail.StartDateTime = deferredUpdate.PluginResult.StartDateTime
+	}
+	if err := sendReply(log, detail); err != nil {
+		log.Errorf("unable to send the reply of command %v: %v", deferredUpdate.CommandID, err)
+		return
+	}
+	if err := updateutil.RemoveDeferredUpdate(updateRoot, deferredUpdate.CommandID); err != nil {
+		log.Errorf("unable to remove the deferred update of command %v: %v", deferredUpdate.CommandID, err)
+	}
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the module name
+func (s *UpdateScheduler) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts the scheduling of the deferred update check
+func (s *UpdateScheduler) ModuleExecute(context context.T) (err error) {
+	if s.pollJob, err = scheduler.Every(pollFrequencyMinutes).Minutes().Run(s.processDeferredUpdates); err != nil {
+		s.context.Log().Errorf("unable to schedule the deferred update check. %v", err)
+	}
+	return
+}
+
+// ModuleRequestStop handles the termination of the deferred update check
+func (s *UpdateScheduler) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if s.pollJob != nil {
+		s.context.Log().Info("stopping deferred update check job.")
+		s.pollJob.Quit <- true
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updatescheduler
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/update/processor"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type schedulerTest struct {
+	scheduler *UpdateScheduler
+	started   []string
+	replies   []*processor.UpdateDetail
+	replyErr  error
+}
+
+func newTestScheduler(t *testing.T) (*schedulerTest, func()) {
+	dir, err := ioutil.TempDir("", "updatescheduler")
+	assert.NoError(t, err)
+	updateRoot = dir
+
+	test := &schedulerTest{scheduler: &UpdateScheduler{context: context.NewMockDefault()}}
+	exeCommand = func(log log.T, cmd string, workingDir string, outputRoot string, stdOut string, stdErr string, isAsync bool) error {
+		assert.Equal(t, dir, workingDir)
+		assert.True(t, isAsync)
+		test.started = append(test.started, cmd)
+		return nil
+	}
+	sendReply = func(log log.T, update *processor.UpdateDetail) error {
+		test.replies = append(test.replies, update)
+		return test.replyErr
+	}
+	return test, func() {
+		updateRoot = appconfig.UpdaterArtifactsRoot
+		exeCommand = (&updateutil.Utility{}).ExeCommand
+		sendReply = processor.NewService().SendReply
+		os.RemoveAll(dir)
+	}
+}
+
+func deferUpdate(t *testing.T, commandID string, startDateTime time.Time) {
+	deferredUpdate := &updateutil.DeferredUpdate{
+		CommandID:     commandID,
+		MessageID:     "aws.ssm." + commandID + ".i-1234",
+		UpdateCommand: "updater -update -messageid " + commandID,
+		WorkingDir:    updateRoot,
+		StartDateTime: startDateTime,
+		PluginResult:  &updateutil.UpdatePluginResult{StandOut: "Updating amazon-ssm-agent\n"},
+	}
+	assert.NoError(t, (&updateutil.Utility{}).SaveDeferredUpdate(log.NewMockLog(), updateRoot, deferredUpdate))
+}
+
+func TestStartDeferredUpdateWhenWindowOpened(t *testing.T) {
+	test, cleanup := newTestScheduler(t)
+	defer cleanup()
+	deferUpdate(t, "command1", time.Now().Add(-time.Minute))
+
+	test.scheduler.processDeferredUpdates()
+	test.scheduler.processDeferredUpdates()
+
+	// the deferred update is started once, with the output of its plugin
+	assert.Equal(t, []string{"updater -update -messageid command1"}, test.started)
+	assert.Empty(t, test.replies)
+	assert.False(t, fileutil.Exists(updateutil.DeferredUpdateFilePath(updateRoot, "command1")))
+	pluginResult, err := updateutil.LoadUpdatePluginResult(log.NewMockLog(), updateRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, "Updating amazon-ssm-agent\n", pluginResult.StandOut)
+}
+
+func TestStartDeferredUpdateBeforeWindowOpens(t *testing.T) {
+	test, cleanup := newTestScheduler(t)
+	defer cleanup()
+	deferUpdate(t, "command1", time.Now().Add(time.Hour))
+
+	test.scheduler.processDeferredUpdates()
+
+	assert.Empty(t, test.started)
+	assert.Empty(t, test.replies)
+	assert.True(t, fileutil.Exists(updateutil.DeferredUpdateFilePath(updateRoot, "command1")))
+}
+
+func TestSupersededDeferredUpdateIsReported(t *testing.T) {
+	test, cleanup := newTestScheduler(t)
+	defer cleanup()
+	deferUpdate(t, "command1", time.Now().Add(time.Hour))
+	deferUpdate(t, "command2", time.Now().Add(time.Hour))
+
+	test.scheduler.processDeferredUpdates()
+
+	// the first command gets a final reply, the second one keeps waiting for the update window
+	assert.Empty(t, test.started)
+	assert.Len(t, test.replies, 1)
+	assert.Equal(t, "aws.ssm.command1.i-1234", test.replies[0].MessageID)
+	assert.Equal(t, contracts.ResultStatusFailed, test.replies[0].Result)
+	assert.Contains(t, test.replies[0].StandardOut, "superseded by command command2")
+	assert.False(t, fileutil.Exists(updateutil.DeferredUpdateFilePath(updateRoot, "command1")))
+	assert.True(t, fileutil.Exists(updateutil.DeferredUpdateFilePath(updateRoot, "command2")))
+}
+
+func TestCanceledDeferredUpdateIsReported(t *testing.T) {
+	test, cleanup := newTestScheduler(t)
+	defer cleanup()
+	deferUpdate(t, "command1", time.Now().Add(-time.Minute))
+	found, err := updateutil.CancelDeferredUpdate(log.NewMockLog(), updateRoot, "command1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	test.scheduler.processDeferredUpdates()
+
+	assert.Empty(t, test.started)
+	assert.Len(t, test.replies, 1)
+	assert.Equal(t, contracts.ResultStatusCancelled, test.replies[0].Result)
+	assert.False(t, fileutil.Exists(updateutil.DeferredUpdateFilePath(updateRoot, "command1")))
+}
+
+func TestDeferredUpdateReplyIsRetried(t *testing.T) {
+	test, cleanup := newTestScheduler(t)
+	defer cleanup()
+	deferUpdate(t, "command1", time.Now().Add(time.Hour))
+	_, err := updateutil.CancelDeferredUpdate(log.NewMockLog(), updateRoot, "command1")
+	assert.NoError(t, err)
+	test.replyErr = errors.New("network error")
+
+	test.scheduler.processDeferredUpdates()
+	assert.True(t, fileutil.Exists(updateutil.DeferredUpdateFilePath(updateRoot, "command1")))
+
+	test.replyErr = nil
+	test.scheduler.processDeferredUpdates()
+	assert.Len(t, test.replies, 2)
+	assert.False(t, fileutil.Exists(updateutil.DeferredUpdateFilePath(updateRoot, "command1")))
+}
+
+func TestCancelUnknownDeferredUpdate(t *testing.T) {
+	_, cleanup := newTestScheduler(t)
+	defer cleanup()
+
+	found, err := updateutil.CancelDeferredUpdate(log.NewMockLog(), updateRoot, "command1")
+
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
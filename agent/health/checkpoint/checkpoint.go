@@ -0,0 +1,89 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package checkpoint records the signals showing the agent works, so the updater can verify
+// a newly installed agent is healthy before completing the update.
+package checkpoint
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Check identifies one of the signals showing the agent works
+type Check string
+
+const (
+	// Registered is passed once the agent identified the instance and started its core modules
+	Registered Check = "Registered"
+	// MessagesPolled is passed once the agent successfully polled MDS for commands
+	MessagesPolled Check = "MessagesPolled"
+	// HeartbeatSent is passed once the agent successfully reported its health
+	HeartbeatSent Check = "HeartbeatSent"
+)
+
+// AllChecks lists the checks an agent has to pass to be considered healthy
+var AllChecks = []Check{Registered, MessagesPolled, HeartbeatSent}
+
+// checkpointDir is the directory holding one file per check, containing the time it was last passed
+var checkpointDir = filepath.Join(appconfig.DefaultDataStorePath, appconfig.HealthCheckpointsDirName)
+
+var (
+	lock     sync.Mutex
+	recorded = make(map[Check]bool)
+)
+
+// Record notes the check passed. Only the first time the agent process passes a check is written to disk.
+func Record(log log.T, check Check) {
+	lock.Lock()
+	defer lock.Unlock()
+	if recorded[check] {
+		return
+	}
+	if err := fileutil.MakeDirs(checkpointDir); err != nil {
+		log.Debugf("unable to record health check %v: %v", check, err)
+		return
+	}
+	passed := time.Now().UTC().Format(time.RFC3339Nano)
+	if _, err := fileutil.WriteIntoFileWithPermissions(filepath.Join(checkpointDir, string(check)), passed, appconfig.ReadWriteAccess); err != nil {
+		log.Debugf("unable to record health check %v: %v", check, err)
+		return
+	}
+	recorded[check] = true
+}
+
+// MissingSince returns the checks which weren't passed after the given time
+func MissingSince(since time.Time) (missing []Check) {
+	for _, check := range AllChecks {
+		if passed, ok := lastPassed(check); !ok || passed.Before(since) {
+			missing = append(missing, check)
+		}
+	}
+	return
+}
+
+// lastPassed returns the last time the check was recorded
+func lastPassed(check Check) (time.Time, bool) {
+	content, err := fileutil.ReadAllText(filepath.Join(checkpointDir, string(check)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	passed, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(content))
+	return passed, err == nil
+}
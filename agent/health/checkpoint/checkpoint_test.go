@@ -0,0 +1,68 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package checkpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// useTempCheckpointDir points the checkpoints to a new directory and forgets the recorded checks
+func useTempCheckpointDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+	checkpointDir = dir
+	recorded = make(map[Check]bool)
+	return dir
+}
+
+func TestMissingSinceWithoutCheckpoints(t *testing.T) {
+	dir := useTempCheckpointDir(t)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t, AllChecks, MissingSince(time.Now().Add(-time.Hour)))
+}
+
+func TestMissingSinceAfterRecord(t *testing.T) {
+	dir := useTempCheckpointDir(t)
+	defer os.RemoveAll(dir)
+	logger := log.NewMockLog()
+	since := time.Now().Add(-time.Second)
+
+	Record(logger, Registered)
+	Record(logger, HeartbeatSent)
+
+	assert.Equal(t, []Check{MessagesPolled}, MissingSince(since))
+	assert.Equal(t, AllChecks, MissingSince(time.Now().Add(time.Hour)))
+}
+
+func TestRecordWritesOncePerProcess(t *testing.T) {
+	dir := useTempCheckpointDir(t)
+	defer os.RemoveAll(dir)
+	logger := log.NewMockLog()
+
+	Record(logger, Registered)
+	first, ok := lastPassed(Registered)
+	assert.True(t, ok)
+
+	Record(logger, Registered)
+	second, ok := lastPassed(Registered)
+	assert.True(t, ok)
+	assert.Equal(t, first, second)
+}
@@ -0,0 +1,178 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package errorsummary aggregates repeated identical errors so that they can be reported
+// periodically as a summary instead of being logged on every occurrence.
+package errorsummary
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const (
+	// maxRecords limits the number of distinct errors tracked in one summary window
+	maxRecords = 100
+	// overflowSource is used to aggregate the errors reported once maxRecords is reached
+	overflowSource = "other"
+
+	// InventoryTypeName is the custom inventory type the summary is reported as
+	InventoryTypeName = "Custom:SSMAgentErrorSummary"
+	// inventorySchemaVersion is the version of the content of the inventory type
+	inventorySchemaVersion = "1.0"
+	// maxInventoryRecords and maxInventoryErrorLength keep the item within the size accepted by the service
+	maxInventoryRecords     = 10
+	maxInventoryErrorLength = 256
+)
+
+// Record summarizes the occurrences of one error during a summary window.
+type Record struct {
+	Source    string    `json:"source"`
+	Error     string    `json:"error"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Aggregator tracks the errors reported since the last summary.
+type Aggregator struct {
+	mutex   sync.Mutex
+	records map[string]*Record
+	now     func() time.Time
+}
+
+var defaultAggregator = NewAggregator()
+
+// NewAggregator creates an empty error aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		records: make(map[string]*Record),
+		now:     time.Now,
+	}
+}
+
+// Report records one occurrence of an error raised by source.
+// It returns true when the error has not been seen since the last summary, in which case
+// the caller is expected to log it in full; repeated errors only need to be counted.
+func (a *Aggregator) Report(source string, errorMessage string) (novel bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := a.now()
+	key := source + "\x00" + errorMessage
+	record, found := a.records[key]
+	if !found {
+		if len(a.records) >= maxRecords {
+			source, errorMessage = overflowSource, "too many distinct errors"
+			key = source + "\x00" + errorMessage
+			record, found = a.records[key]
+		}
+		if !found {
+			record = &Record{Source: source, Error: errorMessage, FirstSeen: now}
+			a.records[key] = record
+		}
+		novel = true
+	}
+	record.Count++
+	record.LastSeen = now
+	return novel
+}
+
+// Summarize returns the errors reported since the last summary, most frequent first.
+// The summary window goes on until the records are cleared.
+func (a *Aggregator) Summarize() (records []Record) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, record := range a.records {
+		records = append(records, *record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Count != records[j].Count {
+			return records[i].Count > records[j].Count
+		}
+		return records[i].FirstSeen.Before(records[j].FirstSeen)
+	})
+	return records
+}
+
+// Clear removes the records of a summary once it is reported and starts a new summary window.
+// The occurrences reported after the summary are kept for the next one.
+func (a *Aggregator) Clear(records []Record) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, reported := range records {
+		key := reported.Source + "\x00" + reported.Error
+		record, found := a.records[key]
+		if !found {
+			continue
+		}
+		if record.Count <= reported.Count {
+			delete(a.records, key)
+			continue
+		}
+		// the occurrences since the summary date from its last reported one at the earliest
+		record.Count -= reported.Count
+		record.FirstSeen = reported.LastSeen
+	}
+}
+
+// Report records an error in the agent wide aggregator.
+func Report(source string, errorMessage string) bool {
+	return defaultAggregator.Report(source, errorMessage)
+}
+
+// Summarize returns the errors of the agent wide aggregator.
+func Summarize() []Record {
+	return defaultAggregator.Summarize()
+}
+
+// Clear removes the reported records from the agent wide aggregator.
+func Clear(records []Record) {
+	defaultAggregator.Clear(records)
+}
+
+// InventoryItems returns the custom inventory item reporting the most frequent records of a summary,
+// an empty summary clears the item reported before.
+func InventoryItems(records []Record, captureTime time.Time) []*ssm.InventoryItem {
+	if len(records) > maxInventoryRecords {
+		records = records[:maxInventoryRecords]
+	}
+	content := make([]map[string]*string, len(records))
+	for i, record := range records {
+		errorMessage := record.Error
+		if len(errorMessage) > maxInventoryErrorLength {
+			errorMessage = errorMessage[:maxInventoryErrorLength]
+		}
+		content[i] = map[string]*string{
+			"Source":    aws.String(record.Source),
+			"Error":     aws.String(errorMessage),
+			"Count":     aws.String(strconv.Itoa(record.Count)),
+			"FirstSeen": aws.String(record.FirstSeen.UTC().Format(time.RFC3339)),
+			"LastSeen":  aws.String(record.LastSeen.UTC().Format(time.RFC3339)),
+		}
+	}
+	return []*ssm.InventoryItem{{
+		TypeName:      aws.String(InventoryTypeName),
+		SchemaVersion: aws.String(inventorySchemaVersion),
+		CaptureTime:   aws.String(captureTime.UTC().Format(time.RFC3339)),
+		Content:       content,
+	}}
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package errorsummary
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAggregator() (*Aggregator, *time.Time) {
+	clock := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAggregator()
+	a.now = func() time.Time { return clock }
+	return a, &clock
+}
+
+func TestReportDeduplicatesErrors(t *testing.T) {
+	a, clock := newTestAggregator()
+	first := *clock
+
+	assert.True(t, a.Report("s3", "AccessDenied: Access Denied"))
+	*clock = clock.Add(time.Minute)
+	assert.False(t, a.Report("s3", "AccessDenied: Access Denied"))
+	*clock = clock.Add(time.Minute)
+	assert.False(t, a.Report("s3", "AccessDenied: Access Denied"))
+	assert.True(t, a.Report("ssm", "AccessDenied: Access Denied"))
+
+	records := a.Summarize()
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, Record{Source: "s3", Error: "AccessDenied: Access Denied", Count: 3, FirstSeen: first, LastSeen: *clock}, records[0])
+	assert.Equal(t, "ssm", records[1].Source)
+	assert.Equal(t, 1, records[1].Count)
+}
+
+func TestClearStartsNewWindow(t *testing.T) {
+	a, _ := newTestAggregator()
+
+	assert.True(t, a.Report("s3", "AccessDenied"))
+	records := a.Summarize()
+	assert.Equal(t, 1, len(records))
+	// the records are kept until they are cleared
+	assert.Equal(t, records, a.Summarize())
+	a.Clear(records)
+	assert.Empty(t, a.Summarize())
+
+	// the error is logged again once per summary window
+	assert.True(t, a.Report("s3", "AccessDenied"))
+}
+
+func TestClearKeepsLaterOccurrences(t *testing.T) {
+	a, clock := newTestAggregator()
+	a.Report("s3", "AccessDenied")
+	a.Report("s3", "AccessDenied")
+	records := a.Summarize()
+	reportedAt := *clock
+
+	*clock = clock.Add(time.Minute)
+	assert.False(t, a.Report("s3", "AccessDenied"))
+	a.Report("ssm", "Throttling")
+	a.Clear(records)
+
+	assert.Equal(t, []Record{
+		{Source: "s3", Error: "AccessDenied", Count: 1, FirstSeen: reportedAt, LastSeen: *clock},
+		{Source: "ssm", Error: "Throttling", Count: 1, FirstSeen: *clock, LastSeen: *clock},
+	}, a.Summarize())
+}
+
+func TestReportLimitsDistinctErrors(t *testing.T) {
+	a, _ := newTestAggregator()
+
+	for i := 0; i < maxRecords+10; i++ {
+		a.Report("source", "error "+strconv.Itoa(i))
+	}
+
+	records := a.Summarize()
+	assert.Equal(t, maxRecords+1, len(records))
+	assert.Equal(t, overflowSource, records[0].Source)
+	assert.Equal(t, 10, records[0].Count)
+}
+
+func TestInventoryItems(t *testing.T) {
+	a, clock := newTestAggregator()
+	for i := 0; i < maxInventoryRecords+5; i++ {
+		a.Report("s3", strconv.Itoa(i)+strings.Repeat("x", maxInventoryErrorLength))
+	}
+
+	items := InventoryItems(a.Summarize(), *clock)
+
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, InventoryTypeName, aws.StringValue(items[0].TypeName))
+	assert.Equal(t, "2018-01-01T00:00:00Z", aws.StringValue(items[0].CaptureTime))
+	assert.Equal(t, maxInventoryRecords, len(items[0].Content))
+	entry := items[0].Content[0]
+	assert.Equal(t, "s3", aws.StringValue(entry["Source"]))
+	assert.Equal(t, maxInventoryErrorLength, len(aws.StringValue(entry["Error"])))
+	assert.Equal(t, "1", aws.StringValue(entry["Count"]))
+	assert.Equal(t, "2018-01-01T00:00:00Z", aws.StringValue(entry["FirstSeen"]))
+}
+
+func TestInventoryItemsEmptySummary(t *testing.T) {
+	items := InventoryItems(nil, time.Now())
+
+	assert.Equal(t, 1, len(items))
+	assert.Empty(t, items[0].Content)
+}
@@ -15,11 +15,18 @@
 package health
 
 import (
+	"encoding/json"
 	"math/rand"
+	"path/filepath"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/health/checkpoint"
+	"github.com/aws/amazon-ssm-agent/agent/health/errorsummary"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
 	"github.com/aws/amazon-ssm-agent/agent/version"
@@ -32,6 +39,8 @@ type HealthCheck struct {
 	healthCheckStopPolicy *sdkutil.StopPolicy
 	healthJob             *scheduler.Job
 	service               ssm.Service
+	// errorSummaryReported is true when the last error summary sent wasn't empty
+	errorSummaryReported bool
 }
 
 const (
@@ -90,10 +99,57 @@ func (h *HealthCheck) updateHealth() {
 	// If both ssm config and command is inactive => agent is inactive.
 	if _, err = h.service.UpdateInstanceInformation(log, version.Version, "Active", AgentName); err != nil {
 		sdkutil.HandleAwsError(log, err, h.healthCheckStopPolicy)
+		return
 	}
+	checkpoint.Record(log, checkpoint.HeartbeatSent)
+	h.reportErrorSummary()
 	return
 }
 
+// reportErrorSummary sends the errors repeated since the last summary as a custom inventory type, logs them and
+// saves the summary so that it can be collected from the instance. The errors are kept for the next health update
+// when the summary can't be sent.
+func (h *HealthCheck) reportErrorSummary() {
+	log := h.context.Log()
+	records := errorsummary.Summarize()
+	// an empty summary is only sent to clear the one reported before
+	if len(records) == 0 && !h.errorSummaryReported {
+		return
+	}
+	instanceID, err := platform.InstanceID()
+	if err != nil {
+		log.Errorf("failed to get the instance id to report the error summary: %v", err)
+		return
+	}
+	if _, err = h.service.PutInventory(log, instanceID, errorsummary.InventoryItems(records, time.Now())); err != nil {
+		log.Errorf("failed to report the error summary, it is kept for the next health update: %v", err)
+		return
+	}
+	errorsummary.Clear(records)
+	h.errorSummaryReported = len(records) > 0
+
+	for _, record := range records {
+		if record.Count > 1 {
+			log.Warnf("error occurred %v times between %v and %v in %v: %v",
+				record.Count, record.FirstSeen.Format(time.RFC3339), record.LastSeen.Format(time.RFC3339), record.Source, record.Error)
+		}
+	}
+
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		log.Errorf("failed to marshal the error summary: %v", err)
+		return
+	}
+	if err = fileutil.MakeDirs(appconfig.DefaultDataStorePath); err != nil {
+		log.Errorf("failed to create directory %v: %v", appconfig.DefaultDataStorePath, err)
+		return
+	}
+	summaryPath := filepath.Join(appconfig.DefaultDataStorePath, appconfig.HealthErrorSummaryFileName)
+	if err = fileutil.WriteAllText(summaryPath, string(content)); err != nil {
+		log.Errorf("failed to save the error summary to %v: %v", summaryPath, err)
+	}
+}
+
 // scheduleInMinutes Run Schedule In Minutes
 func (h *HealthCheck) scheduleInMinutes() int {
 	updateHealthFrequencyMins := 5
@@ -148,7 +204,7 @@ func (h *HealthCheck) ModuleRequestStop(stopType contracts.StopType) (err error)
 	return nil
 }
 
-//ping sends an empty ping to the health service to identify if the service exists
+// ping sends an empty ping to the health service to identify if the service exists
 func (h *HealthCheck) ping() (err error) {
 	_, err = h.service.UpdateEmptyInstanceInformation(AgentName)
 	return err
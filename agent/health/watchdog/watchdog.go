@@ -0,0 +1,218 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package watchdog restarts the subsystems of the agent which stopped making progress, and the agent itself when
+// they don't recover or goroutines leak. A goroutine dump is written before each restart for diagnosis.
+package watchdog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// checkInterval is the interval of the checks of the heartbeats and the goroutine count
+	checkInterval = time.Minute
+	// maxRestarts is the number of times a stuck subsystem is restarted before the agent is restarted
+	maxRestarts = 2
+	// agentExitCode is returned by the agent restarted by the watchdog, the service manager starts it again
+	agentExitCode = 1
+)
+
+// Heartbeat tracks the progress of a subsystem, which beats every time it completes an iteration of its loop
+type Heartbeat struct {
+	name     string
+	timeout  time.Duration
+	restart  func()
+	lock     sync.Mutex
+	last     time.Time
+	restarts int
+}
+
+// Beat records the subsystem made progress
+func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.last = now()
+	h.restarts = 0
+}
+
+// Unregister stops monitoring the subsystem, when it stops normally
+func (h *Heartbeat) Unregister() {
+	if h == nil {
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	delete(heartbeats, h.name)
+}
+
+// stuck returns true if the subsystem didn't beat for longer than its timeout
+func (h *Heartbeat) stuck(at time.Time) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return at.Sub(h.last) > h.timeout
+}
+
+var (
+	lock       sync.Mutex
+	heartbeats = make(map[string]*Heartbeat)
+	stopChan   chan bool
+)
+
+// dependencies for testing
+var (
+	now            = time.Now
+	exit           = os.Exit
+	numGoroutine   = runtime.NumGoroutine
+	dumpDir        = log.DefaultLogDir
+	writeDumpFile  = ioutil.WriteFile
+	newCheckTicker = func() (<-chan time.Time, func()) {
+		ticker := time.NewTicker(checkInterval)
+		return ticker.C, ticker.Stop
+	}
+)
+
+// Register starts monitoring a subsystem expected to beat at least once per timeout. The restart function is called
+// when it misses its heartbeats, the agent is restarted instead if restart is nil or the subsystem doesn't recover.
+func Register(name string, timeout time.Duration, restart func()) *Heartbeat {
+	lock.Lock()
+	defer lock.Unlock()
+	h := &Heartbeat{name: name, timeout: timeout, restart: restart, last: now()}
+	heartbeats[name] = h
+	return h
+}
+
+// Start starts the watchdog, unless it is disabled in appconfig
+func Start(log log.T, config appconfig.SsmagentConfig) {
+	if config.Agent.DisableWatchdog {
+		log.Info("Watchdog is disabled")
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if stopChan != nil {
+		return
+	}
+	stopChan = make(chan bool, 1)
+	go watch(log, config.Agent.WatchdogMaxGoroutines, stopChan)
+}
+
+// Stop stops the watchdog
+func Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+	if stopChan != nil {
+		stopChan <- true
+		stopChan = nil
+	}
+}
+
+// watch checks the heartbeats and the goroutine count until stopped
+func watch(log log.T, maxGoroutines int, stop chan bool) {
+	defer func() {
+		if msg := recover(); msg != nil {
+			log.Errorf("Watchdog panic: %v", msg)
+		}
+	}()
+
+	ticks, stopTicker := newCheckTicker()
+	defer stopTicker()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticks:
+			check(log, maxGoroutines)
+		}
+	}
+}
+
+// check restarts the stuck subsystems, or the agent if a subsystem doesn't recover or goroutines leak
+func check(log log.T, maxGoroutines int) {
+	if count := numGoroutine(); count > maxGoroutines {
+		restartAgent(log, fmt.Sprintf("%v goroutines are running, more than the maximum of %v", count, maxGoroutines))
+		return
+	}
+
+	at := now()
+	for _, h := range registered() {
+		if !h.stuck(at) {
+			continue
+		}
+
+		h.lock.Lock()
+		h.restarts++
+		restarts := h.restarts
+		h.last = at
+		h.lock.Unlock()
+
+		reason := fmt.Sprintf("%v made no progress for %v", h.name, h.timeout)
+		if h.restart == nil || restarts > maxRestarts {
+			restartAgent(log, reason)
+			return
+		}
+		log.Errorf("Watchdog: %v, restarting it (attempt %v of %v)", reason, restarts, maxRestarts)
+		writeDump(log, h.name)
+		go h.restart()
+	}
+}
+
+// registered returns the monitored subsystems
+func registered() (list []*Heartbeat) {
+	lock.Lock()
+	defer lock.Unlock()
+	for _, h := range heartbeats {
+		list = append(list, h)
+	}
+	return
+}
+
+// restartAgent exits the agent after writing a goroutine dump, the service manager starts the agent again
+func restartAgent(log log.T, reason string) {
+	log.Errorf("Watchdog: %v, restarting the agent", reason)
+	writeDump(log, "agent")
+	log.Flush()
+	exit(agentExitCode)
+}
+
+// writeDump writes the stacks of all the goroutines to a file of the log directory
+func writeDump(log log.T, name string) {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 64<<20 {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	file := filepath.Join(dumpDir, fmt.Sprintf("watchdog-%v-%v.dump", name, now().UTC().Format("20060102T150405Z")))
+	if err := writeDumpFile(file, buf, appconfig.ReadWriteAccess); err != nil {
+		log.Errorf("Watchdog: unable to write the goroutine dump %v: %v", file, err)
+		return
+	}
+	log.Infof("Watchdog: goroutine dump written to %v", file)
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package watchdog
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// setup stubs the clock, the goroutine dumps and the agent exit, and returns the files dumped and the exit codes
+func setup(t *testing.T) (clock *time.Time, dumps *[]string, exits *[]int) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock = &start
+	dumps = &[]string{}
+	exits = &[]int{}
+	now = func() time.Time { return *clock }
+	numGoroutine = func() int { return 100 }
+	writeDumpFile = func(filename string, data []byte, perm os.FileMode) error {
+		assert.True(t, strings.Contains(string(data), "goroutine"))
+		*dumps = append(*dumps, filename)
+		return nil
+	}
+	exit = func(code int) { *exits = append(*exits, code) }
+	heartbeats = make(map[string]*Heartbeat)
+	return
+}
+
+func TestCheckRestartsStuckSubsystem(t *testing.T) {
+	clock, dumps, exits := setup(t)
+	restarted := make(chan bool, maxRestarts)
+	h := Register("MessageProcessor", 10*time.Minute, func() { restarted <- true })
+
+	// the subsystem beats in time
+	*clock = clock.Add(9 * time.Minute)
+	h.Beat()
+	*clock = clock.Add(9 * time.Minute)
+	check(logger, 1000)
+	assert.Empty(t, *dumps)
+
+	// the subsystem is restarted when it misses its heartbeats
+	*clock = clock.Add(2 * time.Minute)
+	check(logger, 1000)
+	<-restarted
+	assert.Len(t, *dumps, 1)
+	assert.Contains(t, (*dumps)[0], "watchdog-MessageProcessor-")
+	assert.Empty(t, *exits)
+
+	// the agent is restarted when the subsystem doesn't recover
+	*clock = clock.Add(11 * time.Minute)
+	check(logger, 1000)
+	<-restarted
+	*clock = clock.Add(11 * time.Minute)
+	check(logger, 1000)
+	assert.Equal(t, []int{agentExitCode}, *exits)
+	assert.Len(t, *dumps, 3)
+}
+
+func TestCheckRestartsAgentWithoutSubsystemRestart(t *testing.T) {
+	clock, dumps, exits := setup(t)
+	Register("LongRunningPluginsManager", 10*time.Minute, nil)
+
+	*clock = clock.Add(11 * time.Minute)
+	check(logger, 1000)
+
+	assert.Equal(t, []int{agentExitCode}, *exits)
+	assert.Contains(t, (*dumps)[0], "watchdog-agent-")
+}
+
+func TestCheckIgnoresUnregisteredSubsystem(t *testing.T) {
+	clock, _, exits := setup(t)
+	h := Register("Association", 10*time.Minute, nil)
+	h.Unregister()
+
+	*clock = clock.Add(time.Hour)
+	check(logger, 1000)
+
+	assert.Empty(t, *exits)
+}
+
+func TestCheckRestartsAgentWhenGoroutinesLeak(t *testing.T) {
+	_, dumps, exits := setup(t)
+	numGoroutine = func() int { return 1001 }
+
+	check(logger, 1000)
+
+	assert.Equal(t, []int{agentExitCode}, *exits)
+	assert.Len(t, *dumps, 1)
+}
+
+func TestNilHeartbeat(t *testing.T) {
+	var h *Heartbeat
+	h.Beat()
+	h.Unregister()
+}
@@ -13,16 +13,24 @@
 
 // Package hibernation is responsible for the agent in hibernate mode.
 // It depends on health pings in an exponential backoff to check if the agent needs
-// to move to active mode.
+// to move to active mode. Lightweight probes of the SSM endpoint in between the pings
+// detect when connectivity returns so the agent resumes without waiting for the backoff.
 package hibernation
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/log"
-	"github.com/carlescere/scheduler"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/cihub/seelog"
 )
 
@@ -30,26 +38,26 @@ import (
 type Hibernate struct {
 	currentMode  health.AgentState
 	healthModule *health.HealthCheck
-	hibernateJob *scheduler.Job
+	config       appconfig.SsmagentConfig
+	endpoint     string
 
-	currentPingInterval int
-	maxInterval         int
-	scheduleBackOff     func(m *Hibernate)
-	schedulePing        func(m *Hibernate)
+	currentPingInterval time.Duration
+	minInterval         time.Duration
+	maxInterval         time.Duration
+	clock               times.Clock
+	healthPing          func(m *Hibernate) health.AgentState
+	endpointProbe       func(m *Hibernate) error
 
 	seelogger seelog.LoggerInterface
 	isLogged  bool
+	statePath string
 }
 
-// modeChan is a channel that tracks the status of the agent
-var modeChan = make(chan health.AgentState, 10)
-var backOffRate = 3
-
 const (
 	hibernateMode      = "AgentHibernate"
-	maxBackOffInterval = 60 * 60 //Minute conversion
 	multiplier         = 2
-	initialPingRate    = 5 * 60 //Seconds
+	probeTimeout       = 10 * time.Second
+	defaultEndpointFmt = "ssm.%v.amazonaws.com"
 )
 
 // NewHibernateMode creates an object of type NewHibernateMode
@@ -59,92 +67,134 @@ func NewHibernateMode(healthModule *health.HealthCheck, context context.T) *Hibe
 	logger := log.GetLogger(context.Log(), seelogConfig)
 	logger.Info("Agent enters hibernate mode. Reducing logging...")
 
+	config := context.AppConfig()
+	minInterval := time.Duration(config.Agent.HibernationMinIntervalSeconds) * time.Second
+	maxInterval := time.Duration(config.Agent.HibernationMaxIntervalSeconds) * time.Second
+	if minInterval <= 0 {
+		minInterval = appconfig.DefaultHibernationMinIntervalSeconds * time.Second
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+
 	return &Hibernate{
 		healthModule:        healthModule,
 		currentMode:         health.Passive,
+		config:              config,
 		seelogger:           logger,
 		isLogged:            false,
-		currentPingInterval: initialPingRate,
-		maxInterval:         maxBackOffInterval,
-		scheduleBackOff:     scheduleBackOffStrategy,
-		schedulePing:        scheduleEmptyHealthPing,
+		currentPingInterval: minInterval,
+		minInterval:         minInterval,
+		maxInterval:         maxInterval,
+		clock:               times.DefaultClock,
+		healthPing:          healthCheck,
+		endpointProbe:       probeEndpoint,
+		statePath:           StatePath(),
 	}
 }
 
-// ExecuteHibernation Starts the hibernate mode by blocking agent start and by scheduling health pings
+// ExecuteHibernation blocks the agent start until a health ping succeeds.
+// Health pings are sent in an exponential backoff between the min and max intervals, while the SSM endpoint is
+// probed every min interval. A health ping is sent as soon as a probe finds the endpoint reachable again.
 func ExecuteHibernation(m *Hibernate) health.AgentState {
-	next := time.Duration(initialPingRate) * time.Second
-	// Wait backoff time and then schedule health pings
-	<-time.After(next)
-	m.scheduleBackOff(m)
+	reachable := true
+	nextPing := m.clock.Now().Add(m.currentPingInterval)
+	state := State{Since: m.clock.Now(), EndpointReachable: true}
+	m.saveState(&state)
+	defer m.removeState()
 
-loop:
-	// using an infinite loop to block the agent from starting
 	for {
-		// block and wait for health mode to be active
-		status := <-modeChan
-		switch status {
-		case health.Active:
-			//Agent mode is now active. Agent can start. Exit loop
-			m.stopEmptyPing()
+		<-m.clock.After(m.minInterval)
+
+		err := m.endpointProbe(m)
+		state.LastProbe = m.clock.Now()
+		state.EndpointReachable = err == nil
+		m.saveState(&state)
+		if err != nil {
+			if reachable && !m.isLogged {
+				m.seelogger.Infof("SSM endpoint is unreachable - %v", err)
+			}
+			reachable = false
+			if !m.clock.Now().Before(nextPing) {
+				// no need to ping an unreachable endpoint, keep backing off the pings
+				nextPing = m.backOff()
+			}
+			continue
+		}
+
+		regained := !reachable
+		reachable = true
+		if !regained && m.clock.Now().Before(nextPing) {
+			continue
+		}
+		if regained {
+			m.seelogger.Info("SSM endpoint is reachable again, sending health ping")
+		}
+
+		if m.currentMode = m.healthPing(m); m.currentMode == health.Active {
+			//Agent mode is now active. Agent can start.
+			m.seelogger.Info("Health ping succeeded, agent leaves hibernate mode")
 			m.seelogger.Flush()
-			return status //returning status for testing purposes.
-		case health.Passive:
-			continue loop
-		default:
-			continue loop
+			return m.currentMode //returning status for testing purposes.
 		}
+		nextPing = m.backOff()
 	}
 }
 
-func (m *Hibernate) healthCheck() {
+// backOff increases the interval between the health pings up to the max interval and returns the next ping time
+func (m *Hibernate) backOff() time.Time {
+	if m.currentPingInterval < m.maxInterval {
+		m.currentPingInterval = multiplier * m.currentPingInterval
+		if m.currentPingInterval > m.maxInterval {
+			m.currentPingInterval = m.maxInterval
+		}
+		// log once per backoff period
+		m.isLogged = false
+		m.seelogger.Infof("Backing off health ping to every %v", m.currentPingInterval)
+	}
+	return m.clock.Now().Add(m.currentPingInterval)
+}
+
+// healthCheck sends a health ping to the service
+func healthCheck(m *Hibernate) health.AgentState {
 	status, err := health.GetAgentState(m.healthModule)
 	if err != nil && !m.isLogged {
 		m.seelogger.Errorf("Health ping failed with error - %v", err.Error())
 		m.isLogged = true
 	}
-	modeChan <- status
+	return status
 }
 
-func (m *Hibernate) stopEmptyPing() {
-	if m.hibernateJob != nil {
-		m.hibernateJob.Quit <- true
+// probeEndpoint checks that the SSM endpoint answers https requests, whatever the response is
+func probeEndpoint(m *Hibernate) error {
+	if m.endpoint == "" {
+		m.endpoint = ssmEndpoint(m.config)
 	}
-}
-
-func scheduleEmptyHealthPing(m *Hibernate) {
-	var err error
-	if m.hibernateJob, err = scheduler.Every(m.currentPingInterval).Seconds().Run(m.healthCheck); err != nil {
-		m.seelogger.Errorf("Unable to schedule health update. %v", err)
+	client := &http.Client{Transport: proxyconfig.NewTransport(appconfig.ServiceNameSsm), Timeout: probeTimeout}
+	resp, err := client.Head(m.endpoint)
+	if err != nil {
+		return err
 	}
-	return
+	resp.Body.Close()
+	return nil
 }
 
-func scheduleBackOffStrategy(m *Hibernate) {
-	// Scheduler to calculate backoffInterval and call this function in that time every backoff return time.
-	// Also stop the current ping scheduler
-	if m.currentPingInterval == m.maxInterval {
-		return
+// ssmEndpoint returns the https url of the SSM endpoint the agent calls
+func ssmEndpoint(config appconfig.SsmagentConfig) string {
+	region := config.Agent.Region
+	if region == "" {
+		region, _ = platform.Region()
 	}
-	m.stopEmptyPing()
-	m.currentPingInterval = multiplier * m.currentPingInterval
-	if m.currentPingInterval > m.maxInterval {
-		m.currentPingInterval = m.maxInterval
 
+	endpoint := appconfig.GetServiceEndpoint(config, appconfig.ServiceNameSsm, region)
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(defaultEndpointFmt, region)
 	}
-	m.schedulePing(m)
-	backoffInterval := m.currentPingInterval * backOffRate
-
-	next := time.Duration(backoffInterval) * time.Second
-	go func(m *Hibernate) {
-		m.seelogger.Infof("Backing off health check to every %v minutes for %v minutes. Logging will be reduced to one log per backoff period", m.currentPingInterval, backoffInterval)
-		select {
-		case <-time.After(next):
-			// recall scheduleEmptyHealthPing to form a timed loop.
-			// loop is broken when currentPingInterval reaches maxInterval
-			m.isLogged = false
-			go m.scheduleBackOff(m)
-		}
-	}(m)
-	return
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	if u, err := url.Parse(endpoint); err == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	return endpoint
 }
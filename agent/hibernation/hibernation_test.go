@@ -1,72 +1,159 @@
-// // Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
-// //
-// // Licensed under the Apache License, Version 2.0 (the "License"). You may not
-// // use this file except in compliance with the License. A copy of the
-// // License is located at
-// //
-// // http://aws.amazon.com/apache2.0/
-// //
-// // or in the "license" file accompanying this file. This file is distributed
-// // on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
-// // either express or implied. See the License for the specific language governing
-// // permissions and limitations under the License.
-
-// // Package hibernation is responsible for the agent in hibernate mode.
-// // It depends on health pings in an exponential backoff to check if the agent needs
-// // to move to active mode.
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package hibernation is responsible for the agent in hibernate mode.
 package hibernation
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
-	"github.com/stretchr/testify/assert"
-
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/health"
+	"github.com/stretchr/testify/assert"
 )
 
-func TestHibernation_ExecuteHibernation_AgentTurnsActive(t *testing.T) {
+// fakeClock moves forward by the waited duration instead of sleeping
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) chan struct{} {
+	c.now = c.now.Add(d)
+	ch := make(chan struct{}, 1)
+	ch <- struct{}{}
+	return ch
+}
+
+func newTestHibernate(minInterval, maxInterval time.Duration) (*Hibernate, *fakeClock) {
 	ctx := context.NewMockDefault()
-	healthMock := health.NewHealthCheck(ctx)
+	hibernate := NewHibernateMode(nil, ctx)
+	clock := &fakeClock{now: time.Date(2018, time.March, 1, 0, 0, 0, 0, time.UTC)}
+	hibernate.clock = clock
+	hibernate.minInterval = minInterval
+	hibernate.maxInterval = maxInterval
+	hibernate.currentPingInterval = minInterval
+	hibernate.statePath = ""
+	return hibernate, clock
+}
 
-	hibernate := NewHibernateMode(healthMock, ctx)
-	hibernate.scheduleBackOff = fakeScheduler
-	for i := 0; i < 4; i++ {
-		modeChan <- health.Passive
+func TestHibernation_ExecuteHibernation_AgentTurnsActive(t *testing.T) {
+	hibernate, _ := newTestHibernate(time.Minute, time.Hour)
+	pings := 0
+	hibernate.endpointProbe = func(*Hibernate) error { return nil }
+	hibernate.healthPing = func(*Hibernate) health.AgentState {
+		pings++
+		if pings == 4 {
+			return health.Active
+		}
+		return health.Passive
 	}
-	var status health.AgentState
-	go func(h *Hibernate) {
-		status = ExecuteHibernation(h)
-		assert.Equal(t, health.Active, status)
-	}(hibernate)
-	modeChan <- health.Active
+
+	status := ExecuteHibernation(hibernate)
+
+	assert.Equal(t, health.Active, status)
+	assert.Equal(t, 4, pings)
 }
 
-func TestHibernation_scheduleBackOffStrategy(t *testing.T) {
-	ctx := context.NewMockDefault()
-	healthMock := health.NewHealthCheck(ctx)
-
-	hibernate := NewHibernateMode(healthMock, ctx)
-	hibernate.schedulePing = fakeScheduler
-	hibernate.currentPingInterval = 1 //second
-	hibernate.maxInterval = 4         //second
-
-	backOffRate = 2 // reducing time for testing
-
-	go func(h *Hibernate) {
-		scheduleBackOffStrategy(h)
-	}(hibernate)
-
-	assert.Equal(t, 1, hibernate.currentPingInterval)
-	time.Sleep(time.Duration(2) * time.Second)        //backoff rate is 2 in test
-	assert.Equal(t, 2, hibernate.currentPingInterval) // multiplier is 2
-	time.Sleep(time.Duration(4) * time.Second)
-	assert.Equal(t, 4, hibernate.currentPingInterval)
-	time.Sleep(time.Duration(8) * time.Second)
-	assert.Equal(t, 4, hibernate.currentPingInterval) // maxInterval is 4
+func TestHibernation_HealthPingsBackOffExponentially(t *testing.T) {
+	hibernate, clock := newTestHibernate(time.Minute, 4*time.Minute)
+	start := clock.Now()
+	pingTimes := []time.Duration{}
+	hibernate.endpointProbe = func(*Hibernate) error { return nil }
+	hibernate.healthPing = func(*Hibernate) health.AgentState {
+		pingTimes = append(pingTimes, clock.Now().Sub(start))
+		if len(pingTimes) == 5 {
+			return health.Active
+		}
+		return health.Passive
+	}
+
+	ExecuteHibernation(hibernate)
+
+	// pinged after 1 minute, then after 2, 4 and 4 (max interval) more minutes
+	assert.Equal(t, []time.Duration{time.Minute, 3 * time.Minute, 7 * time.Minute, 11 * time.Minute, 15 * time.Minute}, pingTimes)
+}
+
+func TestHibernation_PingsAsSoonAsEndpointIsReachableAgain(t *testing.T) {
+	hibernate, clock := newTestHibernate(time.Minute, time.Hour)
+	start := clock.Now()
+	probes := 0
+	var pingTime time.Duration
+	hibernate.endpointProbe = func(*Hibernate) error {
+		probes++
+		if probes < 10 {
+			return errors.New("unreachable")
+		}
+		return nil
+	}
+	hibernate.healthPing = func(*Hibernate) health.AgentState {
+		pingTime = clock.Now().Sub(start)
+		return health.Active
+	}
+
+	ExecuteHibernation(hibernate)
+
+	// the endpoint came back at the 10th probe, while the backed off ping was only due after 15 minutes
+	assert.Equal(t, 10*time.Minute, pingTime)
+	assert.Equal(t, 8*time.Minute, hibernate.currentPingInterval)
 }
 
-func fakeScheduler(*Hibernate) {
-	//Do nothing
+func TestSsmEndpoint(t *testing.T) {
+	config := appconfig.DefaultConfig()
+	config.Agent.Region = "us-east-1"
+	assert.Equal(t, "https://ssm.us-east-1.amazonaws.com", ssmEndpoint(config))
+
+	config.Endpoints.Ssm = "vpce-0123.ssm.us-east-1.vpce.amazonaws.com"
+	assert.Equal(t, "https://vpce-0123.ssm.us-east-1.vpce.amazonaws.com", ssmEndpoint(config))
+
+	config.Endpoints.Ssm = "https://ssm.example.com/"
+	assert.Equal(t, "https://ssm.example.com", ssmEndpoint(config))
+}
+
+func TestHibernation_StateIsPersistedWhileHibernating(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hibernation")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hibernate, clock := newTestHibernate(time.Minute, time.Hour)
+	hibernate.statePath = filepath.Join(dir, stateFileName)
+	start := clock.Now()
+	var persisted *State
+	hibernate.healthPing = func(*Hibernate) health.AgentState { return health.Active }
+	probes := 0
+	hibernate.endpointProbe = func(*Hibernate) error {
+		probes++
+		if probes == 1 {
+			persisted, _ = LoadState(hibernate.statePath)
+		}
+		return nil
+	}
+
+	ExecuteHibernation(hibernate)
+
+	assert.NotNil(t, persisted)
+	assert.Equal(t, start, persisted.Since.UTC())
+	assert.Equal(t, 60, persisted.PingIntervalSeconds)
+	state, err := LoadState(hibernate.statePath)
+	assert.NoError(t, err)
+	assert.Nil(t, state, "state is removed when the agent leaves hibernate mode")
 }
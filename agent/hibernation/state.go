@@ -0,0 +1,82 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package hibernation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const stateFileName = "hibernation.json"
+
+// State is persisted while the agent hibernates so that tools such as ssm-cli can report it,
+// the agent status api is not served in hibernate mode
+type State struct {
+	Since               time.Time
+	LastProbe           time.Time `json:",omitempty"`
+	EndpointReachable   bool
+	PingIntervalSeconds int
+}
+
+// StatePath returns the file the hibernation state is persisted to
+func StatePath() string {
+	return filepath.Join(appconfig.DefaultDataStorePath, stateFileName)
+}
+
+// LoadState returns the persisted hibernation state, or nil if the agent is not hibernating
+func LoadState(path string) (*State, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state State
+	if err = json.Unmarshal(content, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// saveState persists the hibernation state, failures are logged once as the state is informational only
+func (m *Hibernate) saveState(state *State) {
+	if m.statePath == "" {
+		return
+	}
+	state.PingIntervalSeconds = int(m.currentPingInterval / time.Second)
+	content, err := json.Marshal(state)
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(m.statePath), appconfig.ReadWriteExecuteAccess); err == nil {
+			err = ioutil.WriteFile(m.statePath, content, appconfig.ReadWriteAccess)
+		}
+	}
+	if err != nil && !m.isLogged {
+		m.seelogger.Warnf("Failed to save hibernation state - %v", err)
+	}
+}
+
+// removeState deletes the persisted hibernation state when the agent leaves hibernate mode
+func (m *Hibernate) removeState() {
+	if m.statePath == "" {
+		return
+	}
+	if err := os.Remove(m.statePath); err != nil && !os.IsNotExist(err) {
+		m.seelogger.Warnf("Failed to remove hibernation state - %v", err)
+	}
+}
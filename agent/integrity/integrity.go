@@ -0,0 +1,164 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package integrity verifies the agent binaries against a manifest of hashes written at install time.
+// This gives a basic assurance that the executables launched by the agent have not been tampered with.
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Manifest holds the expected sha256 hash of each agent binary, keyed by absolute path.
+type Manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+var osExecutable = os.Executable
+
+// ManifestPath returns the location of the integrity manifest.
+func ManifestPath() string {
+	return filepath.Join(appconfig.DefaultDataStorePath, appconfig.IntegrityManifestFileName)
+}
+
+// Binaries returns the agent executables that are covered by the integrity check.
+func Binaries() (binaries []string, err error) {
+	var agentPath string
+	if agentPath, err = osExecutable(); err != nil {
+		return
+	}
+	if agentPath, err = filepath.EvalSymlinks(agentPath); err != nil {
+		return
+	}
+	binaries = append(binaries, agentPath)
+	if _, statErr := os.Stat(appconfig.DefaultDocumentWorker); statErr == nil {
+		binaries = append(binaries, appconfig.DefaultDocumentWorker)
+	}
+	return
+}
+
+// WriteManifest computes the hashes of the given binaries and saves them to the manifest file.
+func WriteManifest(log log.T, manifestPath string, binaries []string) (err error) {
+	manifest := Manifest{Files: make(map[string]string)}
+	for _, binary := range binaries {
+		var hash string
+		if hash, err = sha256Hash(binary); err != nil {
+			return fmt.Errorf("failed to compute hash of %v: %v", binary, err)
+		}
+		manifest.Files[binary] = hash
+		log.Debugf("recorded hash %v for %v", hash, binary)
+	}
+
+	var content []byte
+	if content, err = json.MarshalIndent(manifest, "", "  "); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(manifestPath), appconfig.ReadWriteExecuteAccess); err != nil {
+		return
+	}
+	return ioutil.WriteFile(manifestPath, content, appconfig.ReadWriteAccess)
+}
+
+// LoadManifest reads the integrity manifest from disk.
+func LoadManifest(manifestPath string) (manifest Manifest, err error) {
+	var content []byte
+	if content, err = ioutil.ReadFile(manifestPath); err != nil {
+		return
+	}
+	err = json.Unmarshal(content, &manifest)
+	return
+}
+
+// VerifyFile checks the hash of the given file against the manifest.
+func VerifyFile(manifest Manifest, path string) error {
+	expected, found := manifest.Files[path]
+	if !found {
+		return fmt.Errorf("%v is not listed in the integrity manifest", path)
+	}
+	actual, err := sha256Hash(path)
+	if err != nil {
+		return fmt.Errorf("failed to compute hash of %v: %v", path, err)
+	}
+	if !strings.EqualFold(expected, actual) {
+		return fmt.Errorf("hash mismatch for %v: expected %v, found %v", path, expected, actual)
+	}
+	return nil
+}
+
+// CheckBinaries verifies the given binaries according to the configured mode.
+// It returns an error only when the mode is enforce and verification failed,
+// in warn mode failures are logged and the caller is allowed to proceed.
+func CheckBinaries(log log.T, mode string, manifestPath string, binaries []string) error {
+	mode = strings.ToLower(mode)
+	if mode == appconfig.IntegrityCheckModeOff {
+		return nil
+	}
+
+	manifest, err := LoadManifest(manifestPath)
+	if err == nil {
+		for _, binary := range binaries {
+			if err = VerifyFile(manifest, binary); err != nil {
+				break
+			}
+		}
+	} else {
+		err = fmt.Errorf("unable to load integrity manifest %v: %v", manifestPath, err)
+	}
+
+	if err == nil {
+		log.Debugf("integrity check passed for %v", binaries)
+		return nil
+	}
+	if mode == appconfig.IntegrityCheckModeEnforce {
+		log.Errorf("integrity check failed, refusing to continue: %v", err)
+		return err
+	}
+	log.Warnf("integrity check failed: %v", err)
+	return nil
+}
+
+// CheckAgent verifies all agent binaries according to the integrity mode in the app config.
+func CheckAgent(log log.T, config appconfig.SsmagentConfig) error {
+	binaries, err := Binaries()
+	if err != nil {
+		log.Warnf("unable to determine agent binaries for integrity check: %v", err)
+		return nil
+	}
+	return CheckBinaries(log, config.Agent.IntegrityCheckMode, ManifestPath(), binaries)
+}
+
+// sha256Hash returns the hex encoded sha256 hash of a file.
+func sha256Hash(path string) (hash string, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, f); err != nil {
+		return
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package integrity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+func setupBinary(t *testing.T, content string) (dir string, binary string) {
+	dir, err := ioutil.TempDir("", "integrity")
+	assert.NoError(t, err)
+	binary = filepath.Join(dir, "ssm-document-worker")
+	assert.NoError(t, ioutil.WriteFile(binary, []byte(content), appconfig.ReadWriteExecuteAccess))
+	return
+}
+
+func TestWriteAndVerifyManifest(t *testing.T) {
+	dir, binary := setupBinary(t, "original")
+	defer os.RemoveAll(dir)
+	manifestPath := filepath.Join(dir, appconfig.IntegrityManifestFileName)
+
+	assert.NoError(t, WriteManifest(logger, manifestPath, []string{binary}))
+	manifest, err := LoadManifest(manifestPath)
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyFile(manifest, binary))
+	assert.Error(t, VerifyFile(manifest, filepath.Join(dir, "unknown")))
+}
+
+func TestCheckBinariesTampered(t *testing.T) {
+	dir, binary := setupBinary(t, "original")
+	defer os.RemoveAll(dir)
+	manifestPath := filepath.Join(dir, appconfig.IntegrityManifestFileName)
+	assert.NoError(t, WriteManifest(logger, manifestPath, []string{binary}))
+
+	assert.NoError(t, ioutil.WriteFile(binary, []byte("tampered"), appconfig.ReadWriteExecuteAccess))
+
+	assert.Error(t, CheckBinaries(logger, appconfig.IntegrityCheckModeEnforce, manifestPath, []string{binary}))
+	assert.NoError(t, CheckBinaries(logger, appconfig.IntegrityCheckModeWarn, manifestPath, []string{binary}))
+	assert.NoError(t, CheckBinaries(logger, appconfig.IntegrityCheckModeOff, manifestPath, []string{binary}))
+}
+
+func TestCheckBinariesMissingManifest(t *testing.T) {
+	dir, binary := setupBinary(t, "original")
+	defer os.RemoveAll(dir)
+	manifestPath := filepath.Join(dir, appconfig.IntegrityManifestFileName)
+
+	assert.Error(t, CheckBinaries(logger, appconfig.IntegrityCheckModeEnforce, manifestPath, []string{binary}))
+	assert.NoError(t, CheckBinaries(logger, appconfig.IntegrityCheckModeWarn, manifestPath, []string{binary}))
+}
@@ -169,7 +169,7 @@ func initBaseLoggerFromBytes(seelogConfig []byte) (seelogger seelog.LoggerInterf
 	fmt.Println("Initializing new seelog logger")
 	logReceiver := &CloudWatchCustomReceiver{}
 	seelog.RegisterReceiver("cloudwatch_receiver", logReceiver)
-	seelogger, err = seelog.LoggerFromConfigAsBytes(seelogConfig)
+	seelogger, err = seelog.LoggerFromConfigAsBytes(withSystemLog(seelogConfig))
 	if err != nil {
 		fmt.Println("Error parsing logger config. Creating logger from default config:", err)
 		// Create logger with default config
@@ -0,0 +1,76 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	// systemLogReceiverName is the name the system log receiver is registered with in seelog
+	systemLogReceiverName = "systemlog_receiver"
+
+	// systemLogFormatID is the id of the format used by the system log receiver, the system log
+	// records the time and the level of the messages on its own
+	systemLogFormatID = "fmtsystemlog"
+)
+
+var getAppConfig = appconfig.Config
+
+// withSystemLog adds the system log receiver to a seelog configuration when a log sink is set in the agent config
+func withSystemLog(seelogConfig []byte) []byte {
+	config, err := getAppConfig(false)
+	if err != nil || config.Agent.LogSink == "" || !registerSystemLogReceiver() {
+		return seelogConfig
+	}
+	return addSystemLogOutput(seelogConfig, config.Agent.LogSink)
+}
+
+// addSystemLogOutput adds the system log receiver for the given sink to the outputs of a seelog configuration.
+// The configuration is returned unchanged when it has no outputs.
+func addSystemLogOutput(seelogConfig []byte, sink string) []byte {
+	outputsStart := bytes.Index(seelogConfig, []byte("<outputs"))
+	if outputsStart < 0 {
+		return seelogConfig
+	}
+	outputsEnd := bytes.IndexByte(seelogConfig[outputsStart:], '>')
+	if outputsEnd < 0 || seelogConfig[outputsStart+outputsEnd-1] == '/' {
+		return seelogConfig
+	}
+	outputsEnd += outputsStart + 1
+
+	output := fmt.Sprintf(`<custom name="%v" formatid="%v" data-sink="%v"/>`, systemLogReceiverName, systemLogFormatID, sink)
+	format := fmt.Sprintf(`<format id="%v" format="%%Msg"/>`, systemLogFormatID)
+
+	var config bytes.Buffer
+	config.Write(seelogConfig[:outputsEnd])
+	config.WriteString(output)
+	rest := seelogConfig[outputsEnd:]
+	if formatsEnd := bytes.Index(rest, []byte("</formats>")); formatsEnd >= 0 {
+		config.Write(rest[:formatsEnd])
+		config.WriteString(format)
+		config.Write(rest[formatsEnd:])
+	} else if seelogEnd := bytes.LastIndex(rest, []byte("</seelog>")); seelogEnd >= 0 {
+		config.Write(rest[:seelogEnd])
+		config.WriteString("<formats>" + format + "</formats>")
+		config.Write(rest[seelogEnd:])
+	} else {
+		return seelogConfig
+	}
+	return config.Bytes()
+}
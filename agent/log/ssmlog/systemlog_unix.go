@@ -0,0 +1,174 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/syslog"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// journaldSocket is the socket of the journald native protocol
+	journaldSocket = "/run/systemd/journal/socket"
+
+	// systemLogIdentifier is the identifier of the agent messages in the system log
+	systemLogIdentifier = "amazon-ssm-agent"
+)
+
+// assign functions to variables to allow the unit tests to override them
+var (
+	dialJournald = func() (net.Conn, error) {
+		return net.Dial("unixgram", journaldSocket)
+	}
+	dialSyslog = func() (syslogWriter, error) {
+		return syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, systemLogIdentifier)
+	}
+)
+
+// syslogWriter is the subset of the syslog.Writer operations used by the receiver
+type syslogWriter interface {
+	Debug(m string) error
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+	Crit(m string) error
+	Close() error
+}
+
+// registerSystemLogReceiver registers the system log receiver in seelog
+func registerSystemLogReceiver() bool {
+	seelog.RegisterReceiver(systemLogReceiverName, &SystemLogCustomReceiver{})
+	return true
+}
+
+// SystemLogCustomReceiver implements seelog.CustomReceiver, it sends the messages to journald,
+// with the location of the log call as structured fields, or to syslog
+type SystemLogCustomReceiver struct {
+	journald net.Conn
+	syslog   syslogWriter
+}
+
+// AfterParse connects to the sink set in the data-sink attribute. A sink which can't be reached is
+// reported on the console and ignored, so the file logging keeps working.
+func (receiver *SystemLogCustomReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) (err error) {
+	sink := initArgs.XmlCustomAttrs["sink"]
+	switch sink {
+	case appconfig.LogSinkJournald:
+		receiver.journald, err = dialJournald()
+	case appconfig.LogSinkSyslog:
+		receiver.syslog, err = dialSyslog()
+	default:
+		err = fmt.Errorf("unknown sink")
+	}
+	if err != nil {
+		fmt.Printf("Failed to connect to the %v log sink, logging to the files only: %v\n", sink, err)
+	}
+	return nil
+}
+
+// ReceiveMessage sends the message to the connected sink
+func (receiver *SystemLogCustomReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	if receiver.journald != nil {
+		_, err := receiver.journald.Write(journaldEntry(message, level, context))
+		return err
+	}
+	if receiver.syslog != nil {
+		return writeSyslog(receiver.syslog, message, level)
+	}
+	return nil
+}
+
+// Flush does nothing as the messages are not buffered
+func (receiver *SystemLogCustomReceiver) Flush() {
+}
+
+// Close closes the connection to the sink
+func (receiver *SystemLogCustomReceiver) Close() error {
+	if receiver.journald != nil {
+		return receiver.journald.Close()
+	}
+	if receiver.syslog != nil {
+		return receiver.syslog.Close()
+	}
+	return nil
+}
+
+// journaldPriority returns the syslog priority of a seelog level
+func journaldPriority(level seelog.LogLevel) int {
+	switch level {
+	case seelog.CriticalLvl:
+		return 2
+	case seelog.ErrorLvl:
+		return 3
+	case seelog.WarnLvl:
+		return 4
+	case seelog.InfoLvl:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// journaldEntry encodes a message with the journald native protocol
+func journaldEntry(message string, level seelog.LogLevel, context seelog.LogContextInterface) []byte {
+	var entry bytes.Buffer
+	writeJournaldField(&entry, "MESSAGE", strings.TrimRight(message, "\n"))
+	writeJournaldField(&entry, "PRIORITY", strconv.Itoa(journaldPriority(level)))
+	writeJournaldField(&entry, "SYSLOG_IDENTIFIER", systemLogIdentifier)
+	if context != nil && context.IsValid() {
+		writeJournaldField(&entry, "CODE_FILE", context.FileName())
+		writeJournaldField(&entry, "CODE_LINE", strconv.Itoa(context.Line()))
+		writeJournaldField(&entry, "CODE_FUNC", context.Func())
+	}
+	return entry.Bytes()
+}
+
+// writeJournaldField appends a field to a journald entry, values spanning several lines
+// are written as a name line followed by their little endian size and the raw value
+func writeJournaldField(entry *bytes.Buffer, name string, value string) {
+	if !strings.Contains(value, "\n") {
+		entry.WriteString(name + "=" + value + "\n")
+		return
+	}
+	entry.WriteString(name + "\n")
+	binary.Write(entry, binary.LittleEndian, uint64(len(value)))
+	entry.WriteString(value + "\n")
+}
+
+// writeSyslog writes a message to syslog with the severity matching its level
+func writeSyslog(writer syslogWriter, message string, level seelog.LogLevel) error {
+	switch level {
+	case seelog.CriticalLvl:
+		return writer.Crit(message)
+	case seelog.ErrorLvl:
+		return writer.Err(message)
+	case seelog.WarnLvl:
+		return writer.Warning(message)
+	case seelog.InfoLvl:
+		return writer.Info(message)
+	default:
+		return writer.Debug(message)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+// Package ssmlog is used to initialize ssm functional logger
+package ssmlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSyslog struct {
+	messages []string
+}
+
+func (w *fakeSyslog) Debug(m string) error { w.messages = append(w.messages, "debug:"+m); return nil }
+func (w *fakeSyslog) Info(m string) error  { w.messages = append(w.messages, "info:"+m); return nil }
+func (w *fakeSyslog) Warning(m string) error {
+	w.messages = append(w.messages, "warning:"+m)
+	return nil
+}
+func (w *fakeSyslog) Err(m string) error  { w.messages = append(w.messages, "err:"+m); return nil }
+func (w *fakeSyslog) Crit(m string) error { w.messages = append(w.messages, "crit:"+m); return nil }
+func (w *fakeSyslog) Close() error        { return nil }
+
+func TestAddSystemLogOutputToDefaultConfig(t *testing.T) {
+	config := string(addSystemLogOutput(log.DefaultConfig(), appconfig.LogSinkJournald))
+
+	assert.Contains(t, config, `<custom name="systemlog_receiver" formatid="fmtsystemlog" data-sink="journald"/>`)
+	assert.Contains(t, config, `<format id="fmtsystemlog" format="%Msg"/>`)
+	assert.True(t, strings.Index(config, "<custom") > strings.Index(config, "<outputs"))
+	assert.True(t, strings.Index(config, `id="fmtsystemlog"`) < strings.Index(config, "</formats>"))
+}
+
+func TestAddSystemLogOutputWithoutFormats(t *testing.T) {
+	config := string(addSystemLogOutput([]byte(`<seelog><outputs><console/></outputs></seelog>`), appconfig.LogSinkSyslog))
+
+	assert.Equal(t, `<seelog><outputs><custom name="systemlog_receiver" formatid="fmtsystemlog" data-sink="syslog"/><console/></outputs>`+
+		`<formats><format id="fmtsystemlog" format="%Msg"/></formats></seelog>`, config)
+}
+
+func TestAddSystemLogOutputWithoutOutputs(t *testing.T) {
+	config := []byte(`<seelog><outputs/></seelog>`)
+	assert.Equal(t, config, addSystemLogOutput(config, appconfig.LogSinkSyslog))
+}
+
+func TestSyslogReceiver(t *testing.T) {
+	fake := &fakeSyslog{}
+	dialSyslog = func() (syslogWriter, error) { return fake, nil }
+
+	registerSystemLogReceiver()
+	config := addSystemLogOutput([]byte(`<seelog minlevel="debug"><outputs></outputs></seelog>`), appconfig.LogSinkSyslog)
+	logger, err := seelog.LoggerFromConfigAsBytes(config)
+	assert.NoError(t, err)
+
+	logger.Debug("starting")
+	logger.Warn("slow")
+	logger.Error("failed")
+	logger.Flush()
+	logger.Close()
+
+	assert.Equal(t, []string{"debug:starting", "warning:slow", "err:failed"}, fake.messages)
+}
+
+func TestJournaldReceiverIgnoresUnreachableSocket(t *testing.T) {
+	dialJournald = func() (net.Conn, error) { return nil, &net.OpError{Op: "dial"} }
+
+	receiver := &SystemLogCustomReceiver{}
+	err := receiver.AfterParse(seelog.CustomReceiverInitArgs{XmlCustomAttrs: map[string]string{"sink": appconfig.LogSinkJournald}})
+
+	assert.NoError(t, err)
+	assert.NoError(t, receiver.ReceiveMessage("message", seelog.InfoLvl, nil))
+}
+
+func TestJournaldEntry(t *testing.T) {
+	entry := journaldEntry("Document completed\n", seelog.ErrorLvl, nil)
+	assert.Equal(t, "MESSAGE=Document completed\nPRIORITY=3\nSYSLOG_IDENTIFIER=amazon-ssm-agent\n", string(entry))
+}
+
+func TestJournaldEntryMultilineMessage(t *testing.T) {
+	entry := journaldEntry("line1\nline2", seelog.InfoLvl, nil)
+
+	var expected bytes.Buffer
+	expected.WriteString("MESSAGE\n")
+	binary.Write(&expected, binary.LittleEndian, uint64(len("line1\nline2")))
+	expected.WriteString("line1\nline2\n")
+	expected.WriteString("PRIORITY=6\nSYSLOG_IDENTIFIER=amazon-ssm-agent\n")
+	assert.Equal(t, expected.Bytes(), entry)
+}
@@ -34,10 +34,12 @@ func NewMockLog() *Mock {
 	log.On("Error", mock.Anything).Return(nil)
 	log.On("Trace", mock.Anything).Return()
 	log.On("Info", mock.Anything).Return()
+	log.On("Warn", mock.Anything).Return(nil)
 	log.On("Debugf", mock.Anything, mock.Anything).Return()
 	log.On("Errorf", mock.Anything, mock.Anything).Return(nil)
 	log.On("Tracef", mock.Anything, mock.Anything).Return()
 	log.On("Infof", mock.Anything, mock.Anything).Return()
+	log.On("Warnf", mock.Anything, mock.Anything).Return(nil)
 	return log
 }
 
@@ -50,10 +52,12 @@ func NewMockLogWithContext(ctx string) *Mock {
 	log.On("Error", mock.Anything).Return(nil)
 	log.On("Trace", mock.Anything).Return()
 	log.On("Info", mock.Anything).Return()
+	log.On("Warn", mock.Anything).Return(nil)
 	log.On("Debugf", mock.Anything, mock.Anything).Return()
 	log.On("Errorf", mock.Anything, mock.Anything).Return(nil)
 	log.On("Tracef", mock.Anything, mock.Anything).Return()
 	log.On("Infof", mock.Anything, mock.Anything).Return()
+	log.On("Warnf", mock.Anything, mock.Anything).Return(nil)
 	return log
 }
 
@@ -0,0 +1,92 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package manager encapsulates everything related to long running plugin manager that starts, stops & configures long running plugins
+package manager
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/longrunning/plugin/cloudwatch"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// StartRegisteredPlugin starts a registered long running plugin with its last known configuration.
+// It lets operators control the plugins on-box, without sending a document.
+func (m *Manager) StartRegisteredPlugin(name string) (err error) {
+	configuration, err := m.lastConfiguration(name)
+	if err != nil {
+		return err
+	}
+	return m.startWithConfiguration(name, configuration)
+}
+
+// RestartPlugin stops a registered long running plugin if it is running, then starts it again with the same configuration
+func (m *Manager) RestartPlugin(name string) (err error) {
+	configuration, err := m.lastConfiguration(name)
+	if err != nil {
+		return err
+	}
+	if err = m.StopPlugin(name, task.NewChanneledCancelFlag()); err != nil {
+		return err
+	}
+	return m.startWithConfiguration(name, configuration)
+}
+
+// lastConfiguration returns the configuration a registered plugin is running with, or was registered with
+func (m *Manager) lastConfiguration(name string) (configuration string, err error) {
+	lock.RLock()
+	p, isRegistered := m.registeredPlugins[name]
+	configuration = p.Info.Configuration
+	if info, isRunning := m.runningPlugins[name]; isRunning {
+		configuration = info.Configuration
+	}
+	lock.RUnlock()
+
+	if !isRegistered {
+		return "", fmt.Errorf("%v is not a registered long running plugin", name)
+	}
+	if name == appconfig.PluginNameCloudWatch && configuration == "" {
+		// the configuration of cloudwatch is kept in its own file when it is stopped
+		if configuration, err = cloudwatch.Instance().ParseEngineConfiguration(); err != nil {
+			return "", fmt.Errorf("unable to read the configuration of %v: %v", name, err)
+		}
+	}
+	return configuration, nil
+}
+
+// startWithConfiguration starts a plugin the way the lifecycle management job does, outside of any document
+func (m *Manager) startWithConfiguration(name, configuration string) error {
+	log := m.context.Log()
+	instanceID, _ := platform.InstanceID()
+	orchestrationDir := fileutil.BuildPath(filepath.Join(
+		appconfig.DefaultDataStorePath,
+		instanceID,
+		appconfig.DefaultDocumentRootDirName,
+		m.context.AppConfig().Agent.OrchestrationRootDir))
+	ioConfig := contracts.IOConfiguration{
+		OrchestrationDirectory: orchestrationDir,
+		OutputS3BucketName:     "",
+		OutputS3KeyPrefix:      "",
+	}
+	out := iohandler.NewDefaultIOHandler(log, ioConfig)
+	defer out.Close(log)
+	out.Init(log, name)
+	return m.StartPlugin(name, configuration, orchestrationDir, task.NewChanneledCancelFlag(), out)
+}
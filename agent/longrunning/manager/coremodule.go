@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/health/watchdog"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning"
 	managerContracts "github.com/aws/amazon-ssm-agent/agent/longrunning/plugin"
@@ -69,6 +70,8 @@ type T interface {
 	StopPlugin(name string, cancelFlag task.CancelFlag) (err error)
 	StartPlugin(name, configuration string, orchestrationDir string, cancelFlag task.CancelFlag, out iohandler.IOHandler) (err error)
 	EnsurePluginRegistered(name string, plugin managerContracts.Plugin) (err error)
+	StartRegisteredPlugin(name string) (err error)
+	RestartPlugin(name string) (err error)
 }
 
 // Manager is the core module - that manages long running plugins
@@ -90,6 +93,9 @@ type Manager struct {
 	//manages lifecycle of all long running plugins
 	managingLifeCycleJob *scheduler.Job
 
+	//tracks the progress of the lifecycle management job for the watchdog
+	lifeCycleHeartbeat *watchdog.Heartbeat
+
 	//manages file system related functions
 	fileSysUtil longrunning.FileSysUtil
 
@@ -193,8 +199,8 @@ func (m *Manager) ModuleExecute(context context.T) (err error) {
 				continue
 			}
 			p.Info = pluginInfo
-			if pluginName == appconfig.PluginNameCloudWatch {
-				//skip CW plugin since it'll be handled later
+			if pluginName == appconfig.PluginNameCloudWatch || pluginName == appconfig.PluginNameStatsd {
+				//skip CW and statsd plugins since they'll be handled later
 				continue
 			}
 			log.Infof("Detected %s as a previously executing long running plugin. Starting that plugin again", p.Info.Name)
@@ -234,10 +240,15 @@ func (m *Manager) ModuleExecute(context context.T) (err error) {
 		m.configCloudWatch(log)
 	}
 
+	//start or stop the statsd listener based on the agent configuration
+	m.configStatsd(log)
+
 	//schedule periodic health check of all long running plugins
 	if m.managingLifeCycleJob, err = scheduler.Every(PollFrequencyMinutes).Minutes().Run(m.ensurePluginsAreRunning); err != nil {
 		context.Log().Errorf("unable to schedule long running plugins manager. %v", err)
 	}
+	// a stuck check holds the plugins lock, only an agent restart recovers from it
+	m.lifeCycleHeartbeat = watchdog.Register(Name, (2*PollFrequencyMinutes+5)*time.Minute, nil)
 
 	return
 }
@@ -407,6 +418,41 @@ func (m *Manager) configCloudWatch(log log.T) {
 	}
 }
 
+// configStatsd starts the statsd listener if it is enabled in the agent configuration and stops it otherwise.
+// The listener is configured by the agent configuration rather than by the datastore, hence it isn't persisted.
+func (m *Manager) configStatsd(log log.T) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	p, isRegistered := m.registeredPlugins[appconfig.PluginNameStatsd]
+	if !isRegistered {
+		return
+	}
+	_, isRunning := m.runningPlugins[appconfig.PluginNameStatsd]
+
+	if !m.context.AppConfig().Statsd.Enabled {
+		if isRunning {
+			log.Infof("statsd listener is disabled. Stopping the plugin")
+			if err := p.Handler.Stop(m.context, task.NewChanneledCancelFlag()); err != nil {
+				log.Errorf("Failed to stop the statsd plugin because: %s", err)
+			}
+			delete(m.runningPlugins, appconfig.PluginNameStatsd)
+		}
+		return
+	}
+
+	if err := p.Handler.Start(m.context, "", "", task.NewChanneledCancelFlag(), nil); err != nil {
+		log.Errorf("Failed to start the statsd plugin because: %s", err)
+		delete(m.runningPlugins, appconfig.PluginNameStatsd)
+		return
+	}
+	p.Info.State = managerContracts.PluginState{
+		LastConfigurationModifiedTime: time.Now(),
+		IsEnabled:                     true,
+	}
+	m.runningPlugins[appconfig.PluginNameStatsd] = p.Info
+}
+
 // checkLegacyCloudWatchRunCommandConfig checks if ec2config has cloudwatch configuration document running before
 func checkLegacyCloudWatchRunCommandConfig(instanceId string, cwcInstance cloudwatch.CloudWatchConfig, fileSysUtil longrunning.FileSysUtil) (hasConfiguration bool, err error) {
 	var engineConfigurationParser cloudwatch.EngineConfigurationParser
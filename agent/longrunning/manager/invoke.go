@@ -8,6 +8,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/longrunning/plugin/cloudwatch"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -53,6 +54,16 @@ func Invoke(log logger.T, pluginID string, res *contracts.PluginResult, orchestr
 	case "Enabled":
 		enablePlugin(log, orchestrationDir, pluginID, lrpm, cancelFlag, property, res)
 
+	case "ValidateOnly":
+		log.Infof("Validating the configuration of %s", lrpName)
+		if err = cloudwatch.ValidateConfiguration(property); err != nil {
+			log.Errorf("Configuration of %s is invalid: %v", lrpName, err)
+			CreateResult(err.Error(), contracts.ResultStatusFailed, res)
+		} else {
+			CreateResult(fmt.Sprintf("The configuration of %s is valid", lrpName),
+				contracts.ResultStatusSuccess, res)
+		}
+
 	case "Disabled":
 		log.Infof("Disabling %s", lrpName)
 		if err = lrpm.StopPlugin(lrpName, cancelFlag); err != nil {
@@ -67,8 +78,8 @@ func Invoke(log logger.T, pluginID string, res *contracts.PluginResult, orchestr
 		}
 
 	default:
-		log.Errorf("Allowed Values of StartType: Enabled | Disabled but provided value is: %s", startType)
-		CreateResult("Allowed Values of StartType: Enabled | Disabled",
+		log.Errorf("Allowed Values of StartType: Enabled | Disabled | ValidateOnly but provided value is: %s", startType)
+		CreateResult("Allowed Values of StartType: Enabled | Disabled | ValidateOnly",
 			contracts.ResultStatusFailed, res)
 	}
 
@@ -80,7 +91,16 @@ func enablePlugin(log logger.T, orchestrationDirectory string, pluginID string,
 
 	//loading properties as string since aws:cloudWatch uses properties as string. Properties has new configuration for cloudwatch plugin.
 	//For more details refer to AWS-ConfigureCloudWatch
-	// TODO cannot check if string is a valid json for cloudwatch
+	//an invalid configuration is rejected before the plugin is stopped so the running configuration is kept,
+	//no configuration means the plugin restarts with the configuration it persisted
+	if property == "" {
+		log.Debugf("No configuration provided, %s uses its persisted configuration", lrpName)
+	} else if err := cloudwatch.ValidateConfiguration(property); err != nil {
+		log.Errorf("Configuration of %s is invalid: %v", lrpName, err)
+		CreateResult(err.Error(), contracts.ResultStatusFailed, res)
+		return
+	}
+
 	//stop the plugin before reconfiguring it
 	log.Debugf("Stopping %s - before applying new configuration", lrpName)
 	if err := lrpm.StopPlugin(lrpName, cancelFlag); err != nil {
@@ -90,3 +90,13 @@ func (m *Mock) StartPlugin(name, configuration, orchestrationDir string, cancelF
 func (m *Mock) EnsurePluginRegistered(name string, plugin managerContracts.Plugin) (err error) {
 	return nil
 }
+
+// StartRegisteredPlugin starts a registered plugin with its last configuration - returns nil here for testing
+func (m *Mock) StartRegisteredPlugin(name string) (err error) {
+	return nil
+}
+
+// RestartPlugin restarts a registered plugin - returns nil here for testing
+func (m *Mock) RestartPlugin(name string) (err error) {
+	return nil
+}
@@ -37,6 +37,7 @@ var (
 func (m *Manager) ensurePluginsAreRunning() {
 
 	log := m.context.Log()
+	defer m.lifeCycleHeartbeat.Beat()
 
 	lock.RLock()
 	defer lock.RUnlock()
@@ -76,6 +77,7 @@ func (m *Manager) ensurePluginsAreRunning() {
 
 // stopLifeCycleManagementJob stops periodic health checks of long running plugins
 func (m *Manager) stopLifeCycleManagementJob() {
+	m.lifeCycleHeartbeat.Unregister()
 	if m.managingLifeCycleJob != nil {
 		m.managingLifeCycleJob.Quit <- true
 	}
@@ -0,0 +1,120 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"os"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// Plugin is the type for the Cloudwatch plugin. On Linux the performance counters and logs described by the
+// EngineConfiguration are collected by the agent itself instead of AWS.CloudWatch.exe.
+type Plugin struct {
+	Name   string
+	lock   sync.Mutex
+	engine *engine
+}
+
+// Assign method to global variables to allow unittest to override
+var getInstanceId = platform.InstanceID
+var getHostname = os.Hostname
+
+// NewPlugin returns a new instance of Cloudwatch plugin
+func NewPlugin(pluginConfig iohandler.PluginConfig) (*Plugin, error) {
+	return &Plugin{Name: Name()}, nil
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginNameCloudWatch
+}
+
+// IsRunning returns if the said plugin is running or not
+func (p *Plugin) IsRunning(context context.T) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.engine != nil && p.engine.isRunning()
+}
+
+// Start applies the configuration and starts collecting data, replacing the configuration applied before
+func (p *Plugin) Start(context context.T, configuration string, orchestrationDir string, cancelFlag task.CancelFlag, out iohandler.IOHandler) (err error) {
+	log := context.Log()
+
+	// the health check restarts the plugin without configuration, use the one persisted by the last start
+	if configuration == "" {
+		if err = Instance().Update(log); err != nil {
+			log.Errorf("Unable to load the cloudwatch configuration - %v", err)
+			return
+		}
+		if configuration, err = Instance().ParseEngineConfiguration(); err != nil {
+			log.Errorf("Unable to parse the cloudwatch configuration - %v", err)
+			return
+		}
+	}
+	log.Infof("CloudWatch Configuration to be applied - %s ", logger.PrintCWConfig(configuration, log))
+
+	var config engineConfiguration
+	if config, err = parseEngineConfiguration(configuration); err != nil {
+		log.Error(err)
+		return
+	}
+
+	var vars variables
+	if vars.instanceID, err = getInstanceId(); err != nil {
+		log.Error("Cannot get the current instance ID")
+		return
+	}
+	vars.hostname, _ = getHostname()
+
+	var e *engine
+	if e, err = newEngine(log, config, vars); err != nil {
+		log.Errorf("Invalid cloudwatch configuration - %v", err)
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.engine != nil && p.engine.isRunning() {
+		log.Debug("Stopping the collection of the previous configuration")
+		p.engine.stop()
+	}
+	p.engine = e
+	go e.run(log)
+
+	log.Infof("Started collecting %v performance counters and %v logs every %v",
+		len(e.metricInputs), len(e.logInputs), e.pollInterval)
+	return nil
+}
+
+// Stop stops collecting data
+func (p *Plugin) Stop(context context.T, cancelFlag task.CancelFlag) (err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.engine != nil && p.engine.isRunning() {
+		context.Log().Info("Stopping the collection of performance counters and logs")
+		p.engine.stop()
+	}
+	p.engine = nil
+	return nil
+}
@@ -20,7 +20,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -135,7 +134,7 @@ func (p *Plugin) Start(context context.T, configuration string, orchestrationDir
 
 	//var err error
 	if useTempDirectory {
-		if tempDir, err = ioutil.TempDir("", "Ec2RunCommand"); err != nil {
+		if tempDir, err = fileutil.TempDir("", "Ec2RunCommand"); err != nil {
 			log.Error(err)
 			return
 		}
@@ -0,0 +1,180 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// procDir is where the kernel exposes processor and memory statistics
+var procDir = "/proc"
+
+// bytesPerMegabyte is used to report the Windows counters measured in megabytes
+const bytesPerMegabyte = 1024 * 1024
+
+// counter reads the current value of a performance counter, ok is false while no value is available yet
+type counter func() (value float64, ok bool, err error)
+
+// newCounter returns the Linux equivalent of the given Windows performance counter
+func newCounter(categoryName, counterName, instanceName string) (counter, error) {
+	switch strings.ToLower(categoryName) + "/" + strings.ToLower(counterName) {
+	case "processor/% processor time":
+		return newProcessorTimeCounter(), nil
+	case "memory/available mbytes":
+		return memoryCounter(func(total, available float64) float64 {
+			return available / bytesPerMegabyte
+		}), nil
+	case "memory/% committed bytes in use":
+		return memoryCounter(func(total, available float64) float64 {
+			return (total - available) / total * 100
+		}), nil
+	case "logicaldisk/% free space":
+		return diskCounter(diskMountPoint(instanceName), func(total, free float64) float64 {
+			return free / total * 100
+		}), nil
+	case "logicaldisk/free megabytes":
+		return diskCounter(diskMountPoint(instanceName), func(total, free float64) float64 {
+			return free / bytesPerMegabyte
+		}), nil
+	}
+	return nil, fmt.Errorf("performance counter %v\\%v is not supported on this platform", categoryName, counterName)
+}
+
+// newProcessorTimeCounter returns the percentage of time the processors were busy since the previous read
+func newProcessorTimeCounter() counter {
+	var previousTotal, previousIdle float64
+	return func() (value float64, ok bool, err error) {
+		var total, idle float64
+		if total, idle, err = readProcessorTimes(); err != nil {
+			return
+		}
+		defer func() { previousTotal, previousIdle = total, idle }()
+
+		if previousTotal == 0 || total <= previousTotal {
+			return 0, false, nil
+		}
+		busy := (total - previousTotal) - (idle - previousIdle)
+		return busy / (total - previousTotal) * 100, true, nil
+	}
+}
+
+// readProcessorTimes returns the total and idle time of all processors from the first line of /proc/stat
+func readProcessorTimes() (total, idle float64, err error) {
+	var file *os.File
+	if file, err = os.Open(filepath.Join(procDir, "stat")); err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return 0, 0, fmt.Errorf("unable to read processor statistics: %v", scanner.Err())
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected processor statistics %v", scanner.Text())
+	}
+	for i, field := range fields[1:] {
+		var ticks float64
+		if ticks, err = strconv.ParseFloat(field, 64); err != nil {
+			return 0, 0, fmt.Errorf("unexpected processor statistics %v", scanner.Text())
+		}
+		total += ticks
+		// idle and iowait are the 4th and 5th values
+		if i == 3 || i == 4 {
+			idle += ticks
+		}
+	}
+	return
+}
+
+// memoryCounter computes a value from the total and available memory in bytes
+func memoryCounter(compute func(total, available float64) float64) counter {
+	return func() (value float64, ok bool, err error) {
+		var total, available float64
+		if total, available, err = readMemory(); err != nil {
+			return
+		}
+		return compute(total, available), true, nil
+	}
+}
+
+// readMemory returns the total and available memory in bytes from /proc/meminfo
+func readMemory() (total, available float64, err error) {
+	var file *os.File
+	if file, err = os.Open(filepath.Join(procDir, "meminfo")); err != nil {
+		return
+	}
+	defer file.Close()
+
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// e.g. MemAvailable:    1234567 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if value, parseErr := strconv.ParseFloat(fields[1], 64); parseErr == nil {
+			values[strings.TrimSuffix(fields[0], ":")] = value * 1024
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	total = values["MemTotal"]
+	if total == 0 {
+		return 0, 0, fmt.Errorf("unable to read total memory")
+	}
+	var found bool
+	if available, found = values["MemAvailable"]; !found {
+		// kernels older than 3.14 don't estimate the available memory
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return
+}
+
+// diskMountPoint returns the file system to measure, the root file system replaces the Windows total or drives
+func diskMountPoint(instanceName string) string {
+	if !strings.HasPrefix(instanceName, "/") {
+		return "/"
+	}
+	return instanceName
+}
+
+// diskCounter computes a value from the total and free space in bytes of the file system mounted at the given path
+func diskCounter(mountPoint string, compute func(total, free float64) float64) counter {
+	return func() (value float64, ok bool, err error) {
+		var stat syscall.Statfs_t
+		if err = syscall.Statfs(mountPoint, &stat); err != nil {
+			return
+		}
+		total := float64(stat.Blocks) * float64(stat.Bsize)
+		if total == 0 {
+			return 0, false, nil
+		}
+		free := float64(stat.Bavail) * float64(stat.Bsize)
+		return compute(total, free), true, nil
+	}
+}
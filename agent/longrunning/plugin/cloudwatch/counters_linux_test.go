@@ -0,0 +1,110 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func useProcFiles(t *testing.T, files map[string]string) (restore func()) {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	procDirTemp := procDir
+	procDir = dir
+	return func() {
+		procDir = procDirTemp
+		os.RemoveAll(dir)
+	}
+}
+
+func TestProcessorTimeCounter(t *testing.T) {
+	restore := useProcFiles(t, map[string]string{"stat": "cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 100 0 100 700 100 0 0 0 0 0\n"})
+	defer restore()
+
+	read, err := newCounter("Processor", "% Processor Time", "_Total")
+	assert.NoError(t, err)
+
+	// the first read only takes the baseline
+	_, ok, err := read()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ioutil.WriteFile(filepath.Join(procDir, "stat"), []byte("cpu  180 0 100 720 100 0 0 0 0 0\n"), 0600)
+	value, ok, err := read()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 80.0, value)
+}
+
+func TestMemoryCounters(t *testing.T) {
+	restore := useProcFiles(t, map[string]string{"meminfo": "MemTotal:        4194304 kB\nMemFree:          524288 kB\nMemAvailable:    1048576 kB\n"})
+	defer restore()
+
+	read, err := newCounter("Memory", "Available MBytes", "")
+	assert.NoError(t, err)
+	value, ok, err := read()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1024.0, value)
+
+	read, err = newCounter("Memory", "% Committed Bytes In Use", "")
+	assert.NoError(t, err)
+	value, _, err = read()
+	assert.NoError(t, err)
+	assert.Equal(t, 75.0, value)
+}
+
+func TestMemoryCounterWithoutAvailableEstimate(t *testing.T) {
+	restore := useProcFiles(t, map[string]string{"meminfo": "MemTotal: 2048 kB\nMemFree: 512 kB\nBuffers: 256 kB\nCached: 256 kB\n"})
+	defer restore()
+
+	total, available, err := readMemory()
+	assert.NoError(t, err)
+	assert.Equal(t, 2048.0*1024, total)
+	assert.Equal(t, 1024.0*1024, available)
+}
+
+func TestDiskCounter(t *testing.T) {
+	read, err := newCounter("LogicalDisk", "% Free Space", "C:")
+	assert.NoError(t, err)
+
+	value, ok, err := read()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, value >= 0 && value <= 100)
+}
+
+func TestUnsupportedCounter(t *testing.T) {
+	_, err := newCounter("Network Interface", "Bytes Sent/sec", "")
+	assert.Error(t, err)
+}
+
+func TestDiskMountPoint(t *testing.T) {
+	assert.Equal(t, "/", diskMountPoint(""))
+	assert.Equal(t, "/", diskMountPoint("_Total"))
+	assert.Equal(t, "/", diskMountPoint("C:"))
+	assert.Equal(t, "/data", diskMountPoint("/data"))
+}
@@ -0,0 +1,329 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/cloudwatchmetrics"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// defaultNamespace is used when the CloudWatch output component doesn't specify a NameSpace
+const defaultNamespace = "Linux/Default"
+
+// decoupling the creation of the service clients for easy testability
+var newMetricsService = func(component componentConfiguration) cloudwatchmetrics.Service {
+	return cloudwatch.New(session.New(outputAwsConfig(component)))
+}
+
+var newLogsService = func(component componentConfiguration) logsService {
+	return cloudwatchlogspublisher.NewCloudWatchLogsServiceWithConfig(outputAwsConfig(component))
+}
+
+// metricInput collects a performance counter as a CloudWatch metric
+type metricInput struct {
+	metricName string
+	unit       string
+	dimensions []*cloudwatch.Dimension
+	read       counter
+}
+
+// metricOutput publishes metrics to a CloudWatch namespace
+type metricOutput struct {
+	namespace string
+	service   cloudwatchmetrics.Service
+}
+
+// engine collects the inputs of the EngineConfiguration and publishes them to the outputs they flow to
+type engine struct {
+	pollInterval time.Duration
+	metricInputs map[string]*metricInput
+	logInputs    map[string]*logInput
+	metricRoutes map[string][]*metricOutput
+	logRoutes    map[string][]*logOutput
+	stopChan     chan bool
+	stopped      chan bool
+}
+
+// variables which can be used in the parameters of the components
+type variables struct {
+	instanceID string
+	hostname   string
+}
+
+// expand replaces the {instance_id} and {hostname} variables in value
+func (v variables) expand(value string) string {
+	return strings.NewReplacer("{instance_id}", v.instanceID, "{hostname}", v.hostname).Replace(value)
+}
+
+// newEngine validates the EngineConfiguration and creates the inputs and outputs of its flows.
+// Components which have no equivalent on this platform, such as event logs, are ignored.
+func newEngine(log log.T, config engineConfiguration, vars variables) (e *engine, err error) {
+	e = &engine{
+		metricInputs: make(map[string]*metricInput),
+		logInputs:    make(map[string]*logInput),
+		metricRoutes: make(map[string][]*metricOutput),
+		logRoutes:    make(map[string][]*logOutput),
+		stopChan:     make(chan bool),
+		stopped:      make(chan bool),
+	}
+	if e.pollInterval, err = config.pollInterval(); err != nil {
+		return nil, err
+	}
+
+	components := make(map[string]componentConfiguration)
+	for _, component := range config.Components {
+		components[component.Id] = component
+	}
+
+	var flows []flow
+	if flows, err = config.flows(); err != nil {
+		return nil, err
+	}
+
+	metricOutputs := make(map[string]*metricOutput)
+	logOutputs := make(map[string]*logOutput)
+	for _, f := range flows {
+		for _, inputID := range f.Inputs {
+			input, found := components[inputID]
+			if !found {
+				return nil, fmt.Errorf("component %v used in flows is not defined", inputID)
+			}
+
+			switch input.componentType() {
+			case performanceCounterComponent:
+				if _, exists := e.metricInputs[inputID]; !exists {
+					var read counter
+					if read, err = newCounter(input.parameter("CategoryName"), input.parameter("CounterName"), input.parameter("InstanceName")); err != nil {
+						log.Warnf("Ignoring component %v - %v", inputID, err)
+						continue
+					}
+					e.metricInputs[inputID] = newMetricInput(input, vars, read)
+				}
+				for _, outputID := range f.Outputs {
+					var output *metricOutput
+					if output, err = getMetricOutput(components, metricOutputs, outputID); err != nil {
+						return nil, err
+					}
+					e.metricRoutes[inputID] = append(e.metricRoutes[inputID], output)
+				}
+
+			case customLogComponent:
+				if _, exists := e.logInputs[inputID]; !exists {
+					directory := input.parameter("LogDirectoryPath")
+					if directory == "" {
+						return nil, fmt.Errorf("component %v has no LogDirectoryPath", inputID)
+					}
+					e.logInputs[inputID] = newLogInput(directory, input.parameter("Filter"))
+				}
+				for _, outputID := range f.Outputs {
+					var output *logOutput
+					if output, err = getLogOutput(components, logOutputs, outputID, vars); err != nil {
+						return nil, err
+					}
+					e.logRoutes[inputID] = append(e.logRoutes[inputID], output)
+				}
+
+			default:
+				log.Warnf("Ignoring component %v - %v is not supported on this platform", inputID, input.componentType())
+			}
+		}
+	}
+	return e, nil
+}
+
+// newMetricInput creates a metric input, the metric has the instance id as dimension in addition to the configured one
+func newMetricInput(component componentConfiguration, vars variables, read counter) *metricInput {
+	input := &metricInput{
+		metricName: component.parameter("MetricName"),
+		unit:       component.parameter("Unit"),
+		read:       read,
+	}
+	if input.metricName == "" {
+		input.metricName = component.parameter("CounterName")
+	}
+	if input.unit == "" {
+		input.unit = cloudwatch.StandardUnitNone
+	}
+	input.dimensions = append(input.dimensions, &cloudwatch.Dimension{
+		Name:  aws.String("InstanceId"),
+		Value: aws.String(vars.instanceID),
+	})
+	if name, value := component.parameter("DimensionName"), component.parameter("DimensionValue"); name != "" && value != "" {
+		input.dimensions = append(input.dimensions, &cloudwatch.Dimension{
+			Name:  aws.String(name),
+			Value: aws.String(vars.expand(value)),
+		})
+	}
+	return input
+}
+
+// getMetricOutput returns the metric output with the given id, which is created on first use
+func getMetricOutput(components map[string]componentConfiguration, outputs map[string]*metricOutput, id string) (*metricOutput, error) {
+	if output, exists := outputs[id]; exists {
+		return output, nil
+	}
+	component, found := components[id]
+	if !found {
+		return nil, fmt.Errorf("component %v used in flows is not defined", id)
+	}
+	if component.componentType() != cloudWatchOutputComponent {
+		return nil, fmt.Errorf("component %v can't receive performance counters", id)
+	}
+
+	output := &metricOutput{
+		namespace: component.parameter("NameSpace"),
+		service:   newMetricsService(component),
+	}
+	if output.namespace == "" {
+		output.namespace = defaultNamespace
+	}
+	outputs[id] = output
+	return output, nil
+}
+
+// getLogOutput returns the log output with the given id, which is created on first use
+func getLogOutput(components map[string]componentConfiguration, outputs map[string]*logOutput, id string, vars variables) (*logOutput, error) {
+	if output, exists := outputs[id]; exists {
+		return output, nil
+	}
+	component, found := components[id]
+	if !found {
+		return nil, fmt.Errorf("component %v used in flows is not defined", id)
+	}
+	if component.componentType() != cloudWatchLogsOutputComponent {
+		return nil, fmt.Errorf("component %v can't receive logs", id)
+	}
+
+	output := &logOutput{
+		logGroup:  vars.expand(component.parameter("LogGroup")),
+		logStream: vars.expand(component.parameter("LogStream")),
+		service:   newLogsService(component),
+	}
+	if output.logGroup == "" {
+		return nil, fmt.Errorf("component %v has no LogGroup", id)
+	}
+	if output.logStream == "" {
+		output.logStream = vars.instanceID
+	}
+	outputs[id] = output
+	return output, nil
+}
+
+// outputAwsConfig returns the aws configuration of an output component, which can override the region and credentials
+func outputAwsConfig(component componentConfiguration) *aws.Config {
+	config := sdkutil.AwsConfig()
+	if region := component.parameter("Region"); region != "" {
+		config.Region = aws.String(region)
+	}
+	if accessKey, secretKey := component.parameter("AccessKey"), component.parameter("SecretKey"); accessKey != "" && secretKey != "" {
+		config.Credentials = credentials.NewStaticCredentials(accessKey, secretKey, "")
+	}
+	return config
+}
+
+// run polls the inputs until the engine is stopped
+func (e *engine) run(log log.T) {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+
+	e.poll(log)
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-ticker.C:
+			e.poll(log)
+		}
+	}
+}
+
+// stop stops the engine and waits for the current poll to complete
+func (e *engine) stop() {
+	close(e.stopChan)
+	<-e.stopped
+}
+
+// isRunning returns true until the engine is stopped
+func (e *engine) isRunning() bool {
+	select {
+	case <-e.stopped:
+		return false
+	default:
+		return true
+	}
+}
+
+// poll collects all inputs once and publishes the collected data
+func (e *engine) poll(log log.T) {
+	timestamp := time.Now()
+	metricData := make(map[*metricOutput][]*cloudwatch.MetricDatum)
+	for id, input := range e.metricInputs {
+		value, ok, err := input.read()
+		if err != nil {
+			log.Warnf("Unable to read performance counter %v - %v", id, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		datum := &cloudwatch.MetricDatum{
+			MetricName: aws.String(input.metricName),
+			Unit:       aws.String(input.unit),
+			Value:      aws.Float64(value),
+			Timestamp:  aws.Time(timestamp),
+			Dimensions: input.dimensions,
+		}
+		for _, output := range e.metricRoutes[id] {
+			metricData[output] = append(metricData[output], datum)
+		}
+	}
+	for output, data := range metricData {
+		if err := output.publish(data); err != nil {
+			log.Errorf("Unable to publish metrics to CloudWatch namespace %v - %v", output.namespace, err)
+		}
+	}
+
+	for id, input := range e.logInputs {
+		lines, err := input.read()
+		if err != nil {
+			log.Warnf("Unable to read logs of component %v - %v", id, err)
+		}
+		for _, output := range e.logRoutes[id] {
+			if err = output.publish(log, lines); err != nil {
+				log.Errorf("Unable to publish logs to CloudWatch Logs stream %v/%v - %v", output.logGroup, output.logStream, err)
+			}
+		}
+	}
+}
+
+// publish sends the metric data in batches which respect the limits of PutMetricData
+func (output *metricOutput) publish(data []*cloudwatch.MetricDatum) error {
+	_, err := cloudwatchmetrics.Publish(output.service, output.namespace, data)
+	return err
+}
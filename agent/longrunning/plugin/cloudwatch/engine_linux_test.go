@@ -0,0 +1,173 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/cloudwatchmetrics"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type metricsServiceMock struct {
+	mock.Mock
+}
+
+func (m *metricsServiceMock) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	args := m.Called(input)
+	return &cloudwatch.PutMetricDataOutput{}, args.Error(0)
+}
+
+type logsServiceMock struct {
+	mock.Mock
+}
+
+func (m *logsServiceMock) CreateLogGroup(log log.T, logGroup string) error {
+	return m.Called(logGroup).Error(0)
+}
+
+func (m *logsServiceMock) CreateLogStream(log log.T, logGroup, logStream string) error {
+	return m.Called(logGroup, logStream).Error(0)
+}
+
+func (m *logsServiceMock) GetSequenceTokenForStream(log log.T, logGroupName, logStreamName string) *string {
+	m.Called(logGroupName, logStreamName)
+	return nil
+}
+
+func (m *logsServiceMock) PutLogEvents(log log.T, messages []*cloudwatchlogs.InputLogEvent, logGroup, logStream string, sequenceToken *string) (*string, error) {
+	args := m.Called(messages, logGroup, logStream, sequenceToken)
+	return aws.String("next"), args.Error(0)
+}
+
+func mockServices() (metrics *metricsServiceMock, logs *logsServiceMock, restore func()) {
+	metrics = new(metricsServiceMock)
+	logs = new(logsServiceMock)
+
+	newMetricsServiceTemp, newLogsServiceTemp := newMetricsService, newLogsService
+	newMetricsService = func(component componentConfiguration) cloudwatchmetrics.Service { return metrics }
+	newLogsService = func(component componentConfiguration) logsService { return logs }
+	return metrics, logs, func() {
+		newMetricsService, newLogsService = newMetricsServiceTemp, newLogsServiceTemp
+	}
+}
+
+func TestEnginePublishesMetricsAndLogs(t *testing.T) {
+	metrics, logs, restore := mockServices()
+	defer restore()
+
+	logDir, _ := ioutil.TempDir("", "logs")
+	defer os.RemoveAll(logDir)
+	logFile := filepath.Join(logDir, "app.log")
+	ioutil.WriteFile(logFile, []byte("existing line\n"), 0600)
+
+	config, err := parseEngineConfiguration(testEngineConfiguration)
+	assert.NoError(t, err)
+	config.Components[1].Parameters["LogDirectoryPath"] = logDir
+
+	e, err := newEngine(log.NewMockLog(), config, variables{instanceID: "i-1234567890", hostname: "host"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(e.metricInputs))
+	assert.Equal(t, 1, len(e.logInputs))
+
+	metrics.On("PutMetricData", mock.AnythingOfType("*cloudwatch.PutMetricDataInput")).Return(nil)
+	e.poll(log.NewMockLog())
+
+	input := metrics.Calls[0].Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+	assert.Equal(t, "Linux/Test", *input.Namespace)
+	assert.Equal(t, "AvailableMemory", *input.MetricData[0].MetricName)
+	assert.Equal(t, "Megabytes", *input.MetricData[0].Unit)
+	assert.Equal(t, "i-1234567890", *input.MetricData[0].Dimensions[0].Value)
+	// content present before the plugin started is not published
+	logs.AssertNotCalled(t, "PutLogEvents", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	file, _ := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0600)
+	file.WriteString("first\nsecond\npartial")
+	file.Close()
+
+	logs.On("CreateLogGroup", "app").Return(nil)
+	logs.On("CreateLogStream", "app", "i-1234567890").Return(nil)
+	logs.On("GetSequenceTokenForStream", "app", "i-1234567890").Return()
+	logs.On("PutLogEvents", mock.Anything, "app", "i-1234567890", mock.Anything).Return(nil)
+	e.poll(log.NewMockLog())
+
+	logs.AssertNumberOfCalls(t, "PutLogEvents", 1)
+	events := logs.Calls[3].Arguments.Get(0).([]*cloudwatchlogs.InputLogEvent)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "first", *events[0].Message)
+	assert.Equal(t, "second", *events[1].Message)
+}
+
+func TestEngineIgnoresUnsupportedComponents(t *testing.T) {
+	_, _, restore := mockServices()
+	defer restore()
+
+	config, _ := parseEngineConfiguration(testEngineConfiguration)
+	config.Components = append(config.Components, componentConfiguration{
+		Id:       "SystemEventLog",
+		FullName: "AWS.EC2.Windows.CloudWatch.EventLog.EventLogInputComponent,AWS.EC2.Windows.CloudWatch",
+	})
+	config.Flows.Flows = append(config.Flows.Flows, "SystemEventLog,CloudWatchLogs")
+
+	e, err := newEngine(log.NewMockLog(), config, variables{instanceID: "i-1234567890"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(e.logInputs))
+}
+
+func TestEngineRejectsInvalidFlows(t *testing.T) {
+	_, _, restore := mockServices()
+	defer restore()
+
+	config, _ := parseEngineConfiguration(testEngineConfiguration)
+	config.Flows.Flows = []string{"PerformanceCounter,CloudWatchLogs"}
+	_, err := newEngine(log.NewMockLog(), config, variables{})
+	assert.Error(t, err)
+
+	config.Flows.Flows = []string{"PerformanceCounter,Unknown"}
+	_, err = newEngine(log.NewMockLog(), config, variables{})
+	assert.Error(t, err)
+}
+
+func TestLogInputFilter(t *testing.T) {
+	assert.True(t, newLogInput("/var/log", "").matches("syslog"))
+	assert.True(t, newLogInput("/var/log", "ex").matches("ex170101.log"))
+	assert.False(t, newLogInput("/var/log", "ex").matches("app.log"))
+	assert.True(t, newLogInput("/var/log", "*.log").matches("app.log"))
+	assert.False(t, newLogInput("/var/log", "*.log").matches("app.txt"))
+}
+
+func TestLogEventBatches(t *testing.T) {
+	lines := make([]string, maxLogEventsPerPut+1)
+	for i := range lines {
+		lines[i] = "line"
+	}
+
+	batches := logEventBatches(lines, aws.Int64(0))
+
+	assert.Equal(t, 2, len(batches))
+	assert.Equal(t, maxLogEventsPerPut, len(batches[0]))
+	assert.Equal(t, 1, len(batches[1]))
+}
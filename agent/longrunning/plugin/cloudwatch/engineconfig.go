@@ -0,0 +1,165 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+// Types of the components which can be part of the EngineConfiguration, as found at the end of their FullName
+const (
+	performanceCounterComponent   = "PerformanceCounterInputComponent"
+	customLogComponent            = "CustomLogInputComponent"
+	cloudWatchOutputComponent     = "CloudWatchOutputComponent"
+	cloudWatchLogsOutputComponent = "CloudWatchLogsOutput"
+)
+
+// defaultPollInterval is used when the EngineConfiguration doesn't specify a PollInterval
+const defaultPollInterval = time.Minute
+
+// engineConfiguration represents the EngineConfiguration of the cloudwatch plugin
+type engineConfiguration struct {
+	PollInterval string
+	Components   []componentConfiguration
+	Flows        flowsConfiguration
+}
+
+// componentConfiguration represents one of the input or output components of the EngineConfiguration
+type componentConfiguration struct {
+	Id         string
+	FullName   string
+	Parameters map[string]interface{}
+}
+
+// flowsConfiguration holds the flows connecting input components to output components
+type flowsConfiguration struct {
+	Flows []string
+}
+
+// flow connects the data collected by its inputs to its outputs
+type flow struct {
+	Inputs  []string
+	Outputs []string
+}
+
+// parseEngineConfiguration parses the plugin configuration, i.e. {"EngineConfiguration": {...}}
+func parseEngineConfiguration(configuration string) (config engineConfiguration, err error) {
+	var parser EngineConfigurationParser
+	if err = json.Unmarshal([]byte(configuration), &parser); err != nil {
+		return config, fmt.Errorf("invalid cloudwatch configuration: %v", err)
+	}
+
+	engineConfig := parser.EngineConfiguration
+	// the engine configuration might have been escaped into a string by older documents
+	if value, ok := engineConfig.(string); ok {
+		if err = json.Unmarshal([]byte(value), &engineConfig); err != nil {
+			return config, fmt.Errorf("invalid cloudwatch EngineConfiguration: %v", err)
+		}
+	}
+	if engineConfig == nil {
+		return config, fmt.Errorf("cloudwatch configuration has no EngineConfiguration")
+	}
+
+	if err = jsonutil.Remarshal(engineConfig, &config); err != nil {
+		return config, fmt.Errorf("invalid cloudwatch EngineConfiguration: %v", err)
+	}
+	return
+}
+
+// pollInterval returns the interval at which data is collected and published
+func (config engineConfiguration) pollInterval() (time.Duration, error) {
+	if config.PollInterval == "" {
+		return defaultPollInterval, nil
+	}
+
+	// PollInterval is formatted as hh:mm:ss
+	var hours, minutes, seconds int
+	if _, err := fmt.Sscanf(config.PollInterval, "%d:%d:%d", &hours, &minutes, &seconds); err != nil {
+		return 0, fmt.Errorf("invalid PollInterval %v, expected format is hh:mm:ss", config.PollInterval)
+	}
+	interval := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid PollInterval %v, it has to be positive", config.PollInterval)
+	}
+	return interval, nil
+}
+
+// flows parses the flows of the configuration, formatted as "input,output" where both input and output
+// can be a list of component ids in parentheses, e.g. "(PerformanceCounter,PerformanceCounter2),CloudWatch"
+func (config engineConfiguration) flows() (flows []flow, err error) {
+	for _, value := range config.Flows.Flows {
+		parts := splitComponentList(value)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid flow %v, expected format is input,output", value)
+		}
+		f := flow{
+			Inputs:  splitComponentList(strings.TrimSuffix(strings.TrimPrefix(parts[0], "("), ")")),
+			Outputs: splitComponentList(strings.TrimSuffix(strings.TrimPrefix(parts[1], "("), ")")),
+		}
+		if len(f.Inputs) == 0 || len(f.Outputs) == 0 {
+			return nil, fmt.Errorf("invalid flow %v, expected format is input,output", value)
+		}
+		flows = append(flows, f)
+	}
+	return
+}
+
+// splitComponentList splits the value at the commas which are not enclosed in parentheses
+func splitComponentList(value string) (parts []string) {
+	depth, start := 0, 0
+	for i, c := range value {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = appendComponent(parts, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return appendComponent(parts, value[start:])
+}
+
+func appendComponent(parts []string, part string) []string {
+	if part = strings.TrimSpace(part); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// componentType returns the type of the component, e.g. PerformanceCounterInputComponent for
+// AWS.EC2.Windows.CloudWatch.PerformanceCounterComponent.PerformanceCounterInputComponent,AWS.EC2.Windows.CloudWatch
+func (component componentConfiguration) componentType() string {
+	typeName := strings.TrimSpace(strings.Split(component.FullName, ",")[0])
+	return typeName[strings.LastIndex(typeName, ".")+1:]
+}
+
+// parameter returns the value of the given parameter, empty if the component doesn't define it
+func (component componentConfiguration) parameter(name string) string {
+	for key, value := range component.Parameters {
+		if strings.EqualFold(key, name) && value != nil {
+			return strings.TrimSpace(fmt.Sprint(value))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testEngineConfiguration = `{"EngineConfiguration": {
+	"PollInterval": "00:00:15",
+	"Components": [
+		{
+			"Id": "PerformanceCounter",
+			"FullName": "AWS.EC2.Windows.CloudWatch.PerformanceCounterComponent.PerformanceCounterInputComponent,AWS.EC2.Windows.CloudWatch",
+			"Parameters": {"CategoryName": "Memory", "CounterName": "Available MBytes", "InstanceName": "", "MetricName": "AvailableMemory", "Unit": "Megabytes"}
+		},
+		{
+			"Id": "CustomLogs",
+			"FullName": "AWS.EC2.Windows.CloudWatch.CustomLog.CustomLogInputComponent,AWS.EC2.Windows.CloudWatch",
+			"Parameters": {"LogDirectoryPath": "/var/log/app", "Filter": "*.log"}
+		},
+		{
+			"Id": "CloudWatch",
+			"FullName": "AWS.EC2.Windows.CloudWatch.CloudWatch.CloudWatchOutputComponent,AWS.EC2.Windows.CloudWatch",
+			"Parameters": {"Region": "us-west-2", "NameSpace": "Linux/Test"}
+		},
+		{
+			"Id": "CloudWatchLogs",
+			"FullName": "AWS.EC2.Windows.CloudWatch.CloudWatchLogsOutput,AWS.EC2.Windows.CloudWatch",
+			"Parameters": {"Region": "us-west-2", "LogGroup": "app", "LogStream": "{instance_id}"}
+		}
+	],
+	"Flows": {"Flows": ["PerformanceCounter,CloudWatch", "(CustomLogs),CloudWatchLogs"]}
+}}`
+
+func TestParseEngineConfiguration(t *testing.T) {
+	config, err := parseEngineConfiguration(testEngineConfiguration)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(config.Components))
+	assert.Equal(t, performanceCounterComponent, config.Components[0].componentType())
+	assert.Equal(t, customLogComponent, config.Components[1].componentType())
+	assert.Equal(t, cloudWatchOutputComponent, config.Components[2].componentType())
+	assert.Equal(t, cloudWatchLogsOutputComponent, config.Components[3].componentType())
+	assert.Equal(t, "Available MBytes", config.Components[0].parameter("countername"))
+	assert.Equal(t, "", config.Components[0].parameter("DimensionName"))
+
+	interval, err := config.pollInterval()
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Second, interval)
+}
+
+func TestParseEngineConfigurationEscaped(t *testing.T) {
+	config, err := parseEngineConfiguration(`{"EngineConfiguration": "{\"PollInterval\": \"00:01:00\"}"}`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "00:01:00", config.PollInterval)
+}
+
+func TestParseEngineConfigurationInvalid(t *testing.T) {
+	_, err := parseEngineConfiguration(`{"EngineConfiguration": `)
+	assert.Error(t, err)
+
+	_, err = parseEngineConfiguration(`{}`)
+	assert.Error(t, err)
+}
+
+func TestPollInterval(t *testing.T) {
+	interval, err := engineConfiguration{}.pollInterval()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPollInterval, interval)
+
+	interval, err = engineConfiguration{PollInterval: "01:02:03"}.pollInterval()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour+2*time.Minute+3*time.Second, interval)
+
+	_, err = engineConfiguration{PollInterval: "5 minutes"}.pollInterval()
+	assert.Error(t, err)
+
+	_, err = engineConfiguration{PollInterval: "00:00:00"}.pollInterval()
+	assert.Error(t, err)
+}
+
+func TestFlows(t *testing.T) {
+	config := engineConfiguration{Flows: flowsConfiguration{Flows: []string{
+		"PerformanceCounter,CloudWatch",
+		"(PerformanceCounter2, PerformanceCounter3),(CloudWatch,CloudWatch2)",
+	}}}
+
+	flows, err := config.flows()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []flow{
+		{Inputs: []string{"PerformanceCounter"}, Outputs: []string{"CloudWatch"}},
+		{Inputs: []string{"PerformanceCounter2", "PerformanceCounter3"}, Outputs: []string{"CloudWatch", "CloudWatch2"}},
+	}, flows)
+
+	config.Flows.Flows = []string{"PerformanceCounter"}
+	_, err = config.flows()
+	assert.Error(t, err)
+}
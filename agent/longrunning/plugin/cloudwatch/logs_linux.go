@@ -0,0 +1,209 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// Limits of the PutLogEvents API
+const (
+	logEventOverheadBytes = 26
+	maxLogEventBytes      = 256*1024 - logEventOverheadBytes
+	maxLogEventsPerPut    = 10000
+	maxLogBytesPerPut     = 1024 * 1024
+)
+
+// maxLogBytesPerRead limits how much of a single file is read at each poll, the rest is read at the next polls
+const maxLogBytesPerRead = 4 * 1024 * 1024
+
+// logInput tails the log files of a directory, the content present when the plugin starts is not published
+type logInput struct {
+	directory   string
+	filter      string
+	offsets     map[string]int64
+	initialized bool
+}
+
+// newLogInput creates a log input for the files of directory whose name matches filter, which is either
+// a file name prefix or a pattern such as *.log. All files are read when the filter is empty.
+func newLogInput(directory, filter string) *logInput {
+	return &logInput{
+		directory: directory,
+		filter:    filter,
+		offsets:   make(map[string]int64),
+	}
+}
+
+// matches returns true if the file with the given name needs to be read
+func (input *logInput) matches(name string) bool {
+	if strings.ContainsAny(input.filter, "*?[") {
+		matched, _ := filepath.Match(input.filter, name)
+		return matched
+	}
+	return strings.HasPrefix(name, input.filter)
+}
+
+// read returns the complete lines appended to the log files since the previous read
+func (input *logInput) read() (lines []string, err error) {
+	var files []os.FileInfo
+	if files, err = ioutil.ReadDir(input.directory); err != nil {
+		return
+	}
+
+	offsets := make(map[string]int64)
+	for _, file := range files {
+		if !file.Mode().IsRegular() || !input.matches(file.Name()) {
+			continue
+		}
+		path := filepath.Join(input.directory, file.Name())
+
+		offset, known := input.offsets[path]
+		switch {
+		case !input.initialized:
+			offset = file.Size()
+		case !known || file.Size() < offset:
+			// new, truncated or rotated file
+			offset = 0
+		}
+
+		if file.Size() > offset {
+			fileLines, consumed, readErr := readLines(path, offset)
+			if readErr != nil {
+				// the file is read again from the same offset at the next poll
+				err = readErr
+			}
+			lines = append(lines, fileLines...)
+			offset += consumed
+		}
+		offsets[path] = offset
+	}
+
+	// files which are no longer present are forgotten
+	input.offsets = offsets
+	input.initialized = true
+	return
+}
+
+// readLines reads the complete lines of the file from offset and returns how many bytes were consumed,
+// a line which is still being written is left for the next read
+func readLines(path string, offset int64) (lines []string, consumed int64, err error) {
+	var file *os.File
+	if file, err = os.Open(path); err != nil {
+		return
+	}
+	defer file.Close()
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(io.LimitReader(file, maxLogBytesPerRead))
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil {
+			// EOF or incomplete line
+			return lines, consumed, nil
+		}
+		consumed += int64(len(line))
+
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) > maxLogEventBytes {
+			line = line[:maxLogEventBytes]
+		}
+		// CloudWatch Logs doesn't accept empty events
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+}
+
+// logsService is the subset of the CloudWatch Logs operations used to publish logs
+type logsService interface {
+	CreateLogGroup(log log.T, logGroup string) (err error)
+	CreateLogStream(log log.T, logGroup, logStream string) (err error)
+	GetSequenceTokenForStream(log log.T, logGroupName, logStreamName string) (sequenceToken *string)
+	PutLogEvents(log log.T, messages []*cloudwatchlogs.InputLogEvent, logGroup, logStream string, sequenceToken *string) (nextSequenceToken *string, err error)
+}
+
+// logOutput publishes log lines to a CloudWatch Logs stream
+type logOutput struct {
+	logGroup      string
+	logStream     string
+	service       logsService
+	streamReady   bool
+	sequenceToken *string
+}
+
+// publish sends the lines as log events, the log group and stream are created when needed
+func (output *logOutput) publish(log log.T, lines []string) (err error) {
+	if len(lines) == 0 {
+		return
+	}
+
+	if !output.streamReady {
+		if err = output.service.CreateLogGroup(log, output.logGroup); err != nil {
+			return
+		}
+		if err = output.service.CreateLogStream(log, output.logGroup, output.logStream); err != nil {
+			return
+		}
+		output.sequenceToken = output.service.GetSequenceTokenForStream(log, output.logGroup, output.logStream)
+		output.streamReady = true
+	}
+
+	timestamp := aws.Int64(time.Now().UnixNano() / int64(time.Millisecond))
+	for _, batch := range logEventBatches(lines, timestamp) {
+		if output.sequenceToken, err = output.service.PutLogEvents(log, batch, output.logGroup, output.logStream, output.sequenceToken); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// logEventBatches splits the lines into batches of log events which respect the limits of PutLogEvents
+func logEventBatches(lines []string, timestamp *int64) (batches [][]*cloudwatchlogs.InputLogEvent) {
+	var batch []*cloudwatchlogs.InputLogEvent
+	batchBytes := 0
+	for _, line := range lines {
+		eventBytes := len(line) + logEventOverheadBytes
+		if len(batch) > 0 && (len(batch) >= maxLogEventsPerPut || batchBytes+eventBytes > maxLogBytesPerPut) {
+			batches = append(batches, batch)
+			batch = nil
+			batchBytes = 0
+		}
+		batch = append(batch, &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(line),
+			Timestamp: timestamp,
+		})
+		batchBytes += eventBytes
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return
+}
@@ -0,0 +1,358 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cloudwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// eventLogComponent is the type of the windows event log input component
+const eventLogComponent = "EventLogInputComponent"
+
+// requiredParameters lists the parameters each known component type can't work without
+var requiredParameters = map[string][]string{
+	performanceCounterComponent:   {"CategoryName", "CounterName", "MetricName"},
+	customLogComponent:            {"LogDirectoryPath"},
+	eventLogComponent:             {"LogName"},
+	cloudWatchOutputComponent:     {"NameSpace"},
+	cloudWatchLogsOutputComponent: {"LogGroup"},
+}
+
+// outputComponents lists the known component types which can only be used as the output of a flow
+var outputComponents = map[string]bool{
+	cloudWatchOutputComponent:     true,
+	cloudWatchLogsOutputComponent: true,
+}
+
+// standardUnits lists the units accepted by CloudWatch for a metric
+var standardUnits = []string{
+	cloudwatch.StandardUnitSeconds, cloudwatch.StandardUnitMicroseconds, cloudwatch.StandardUnitMilliseconds,
+	cloudwatch.StandardUnitBytes, cloudwatch.StandardUnitKilobytes, cloudwatch.StandardUnitMegabytes,
+	cloudwatch.StandardUnitGigabytes, cloudwatch.StandardUnitTerabytes, cloudwatch.StandardUnitBits,
+	cloudwatch.StandardUnitKilobits, cloudwatch.StandardUnitMegabits, cloudwatch.StandardUnitGigabits,
+	cloudwatch.StandardUnitTerabits, cloudwatch.StandardUnitPercent, cloudwatch.StandardUnitCount,
+	cloudwatch.StandardUnitBytesSecond, cloudwatch.StandardUnitKilobytesSecond, cloudwatch.StandardUnitMegabytesSecond,
+	cloudwatch.StandardUnitGigabytesSecond, cloudwatch.StandardUnitTerabytesSecond, cloudwatch.StandardUnitBitsSecond,
+	cloudwatch.StandardUnitKilobitsSecond, cloudwatch.StandardUnitMegabitsSecond, cloudwatch.StandardUnitGigabitsSecond,
+	cloudwatch.StandardUnitTerabitsSecond, cloudwatch.StandardUnitCountSecond, cloudwatch.StandardUnitNone,
+}
+
+// ConfigurationErrors lists the problems found while validating a cloudwatch configuration
+type ConfigurationErrors []string
+
+// Error returns all the problems, one per line
+func (errs ConfigurationErrors) Error() string {
+	return fmt.Sprintf("invalid cloudwatch configuration:\n%v", strings.Join(errs, "\n"))
+}
+
+// configurationValidator collects the problems found in the configuration
+type configurationValidator struct {
+	errs ConfigurationErrors
+}
+
+func (v *configurationValidator) addError(path string, format string, params ...interface{}) {
+	v.errs = append(v.errs, fmt.Sprintf("%v: %v", path, fmt.Sprintf(format, params...)))
+}
+
+// ValidateConfiguration checks the plugin configuration, i.e. {"EngineConfiguration": {...}}, against the schema
+// of the cloudwatch EngineConfiguration and returns ConfigurationErrors describing every problem found.
+// Syntax errors are reported with their line and column, other problems with the path of the offending field.
+func ValidateConfiguration(configuration string) error {
+	v := &configurationValidator{}
+	root, ok := v.parse("configuration", configuration)
+	if !ok {
+		return v.errs
+	}
+
+	if fields, ok := v.object("configuration", root); ok {
+		v.checkFields("configuration", fields, "EngineConfiguration", "IsEnabled")
+		if engineConfig, ok := v.requiredField("configuration", fields, "EngineConfiguration"); ok {
+			// the engine configuration might have been escaped into a string by older documents
+			if value, isString := engineConfig.(string); isString {
+				engineConfig, ok = v.parse("EngineConfiguration", value)
+			}
+			if ok {
+				v.validateEngineConfiguration(engineConfig)
+			}
+		}
+		if isEnabled, ok := field(fields, "IsEnabled"); ok {
+			if _, isBool := isEnabled.(bool); !isBool {
+				v.addError("IsEnabled", "expected a boolean but found %v", describe(isEnabled))
+			}
+		}
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// parse unmarshals the json document, reporting the position of syntax errors
+func (v *configurationValidator) parse(path string, document string) (value interface{}, ok bool) {
+	if strings.TrimSpace(document) == "" {
+		v.addError(path, "configuration is empty")
+		return nil, false
+	}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		if syntaxErr, isSyntaxErr := err.(*json.SyntaxError); isSyntaxErr {
+			// the offset of a syntax error is the number of bytes read, including the offending one
+			line, column := position(document, syntaxErr.Offset-1)
+			v.addError(path, "line %d, column %d: %v", line, column, syntaxErr)
+		} else {
+			v.addError(path, "%v", err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func (v *configurationValidator) validateEngineConfiguration(engineConfig interface{}) {
+	const path = "EngineConfiguration"
+	fields, ok := v.object(path, engineConfig)
+	if !ok {
+		return
+	}
+	v.checkFields(path, fields, "PollInterval", "Components", "Flows")
+
+	if value, ok := field(fields, "PollInterval"); ok {
+		if pollInterval, ok := v.str(path+".PollInterval", value); ok {
+			if _, err := (engineConfiguration{PollInterval: pollInterval}).pollInterval(); err != nil {
+				v.addError(path+".PollInterval", "%v", err)
+			}
+		}
+	}
+
+	components := map[string]string{}
+	if value, ok := v.requiredField(path, fields, "Components"); ok {
+		if list, ok := value.([]interface{}); !ok {
+			v.addError(path+".Components", "expected an array but found %v", describe(value))
+		} else {
+			for i, component := range list {
+				v.validateComponent(fmt.Sprintf("%v.Components[%d]", path, i), component, components)
+			}
+		}
+	}
+
+	if value, ok := v.requiredField(path, fields, "Flows"); ok {
+		v.validateFlows(path+".Flows", value, components)
+	}
+}
+
+// validateComponent checks the component and records its type by id
+func (v *configurationValidator) validateComponent(path string, value interface{}, components map[string]string) {
+	fields, ok := v.object(path, value)
+	if !ok {
+		return
+	}
+	v.checkFields(path, fields, "Id", "FullName", "Parameters")
+
+	var component componentConfiguration
+	if value, ok := v.requiredField(path, fields, "FullName"); ok {
+		if component.FullName, ok = v.str(path+".FullName", value); ok && strings.TrimSpace(component.FullName) == "" {
+			v.addError(path+".FullName", "must not be empty")
+		}
+	}
+	if value, ok := v.requiredField(path, fields, "Id"); ok {
+		if component.Id, ok = v.str(path+".Id", value); ok {
+			if strings.TrimSpace(component.Id) == "" {
+				v.addError(path+".Id", "must not be empty")
+			} else if _, duplicate := components[component.Id]; duplicate {
+				v.addError(path+".Id", "component %v is defined more than once", component.Id)
+			} else {
+				components[component.Id] = component.componentType()
+			}
+		}
+	}
+
+	if value, ok := field(fields, "Parameters"); ok {
+		parameters, ok := v.object(path+".Parameters", value)
+		if !ok {
+			return
+		}
+		component.Parameters = parameters
+		for name, parameter := range parameters {
+			switch parameter.(type) {
+			case map[string]interface{}, []interface{}:
+				v.addError(path+".Parameters."+name, "expected a value but found %v", describe(parameter))
+			}
+		}
+	}
+
+	componentType := component.componentType()
+	for _, name := range requiredParameters[componentType] {
+		if component.parameter(name) == "" {
+			v.addError(path+".Parameters", "%v is required by %v", name, componentType)
+		}
+	}
+	if componentType == performanceCounterComponent {
+		if unit := component.parameter("Unit"); unit != "" && !contains(standardUnits, unit) {
+			v.addError(path+".Parameters.Unit", "%v is not a valid CloudWatch unit, expected one of %v", unit, strings.Join(standardUnits, ", "))
+		}
+	}
+}
+
+// validateFlows checks every flow connects defined input components to defined output components
+func (v *configurationValidator) validateFlows(path string, value interface{}, components map[string]string) {
+	fields, ok := v.object(path, value)
+	if !ok {
+		return
+	}
+	v.checkFields(path, fields, "Flows")
+
+	value, ok = v.requiredField(path, fields, "Flows")
+	if !ok {
+		return
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		v.addError(path+".Flows", "expected an array but found %v", describe(value))
+		return
+	}
+	for i, item := range list {
+		flowPath := fmt.Sprintf("%v.Flows[%d]", path, i)
+		value, ok := v.str(flowPath, item)
+		if !ok {
+			continue
+		}
+		flows, err := (engineConfiguration{Flows: flowsConfiguration{Flows: []string{value}}}).flows()
+		if err != nil {
+			v.addError(flowPath, "%v", err)
+			continue
+		}
+		for _, id := range flows[0].Inputs {
+			if componentType, defined := components[id]; !defined {
+				v.addError(flowPath, "input component %v is not defined", id)
+			} else if outputComponents[componentType] {
+				v.addError(flowPath, "component %v is an output and can't be used as an input", id)
+			}
+		}
+		for _, id := range flows[0].Outputs {
+			if componentType, defined := components[id]; !defined {
+				v.addError(flowPath, "output component %v is not defined", id)
+			} else if _, known := requiredParameters[componentType]; known && !outputComponents[componentType] {
+				v.addError(flowPath, "component %v is an input and can't be used as an output", id)
+			}
+		}
+	}
+}
+
+// object returns the fields of a json object
+func (v *configurationValidator) object(path string, value interface{}) (map[string]interface{}, bool) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		v.addError(path, "expected an object but found %v", describe(value))
+	}
+	return fields, ok
+}
+
+// str returns the value of a json string
+func (v *configurationValidator) str(path string, value interface{}) (string, bool) {
+	s, ok := value.(string)
+	if !ok {
+		v.addError(path, "expected a string but found %v", describe(value))
+	}
+	return s, ok
+}
+
+// requiredField returns the value of a field which has to be present in the object
+func (v *configurationValidator) requiredField(path string, fields map[string]interface{}, name string) (interface{}, bool) {
+	value, ok := field(fields, name)
+	if !ok || value == nil {
+		v.addError(path, "%v is required", name)
+		return nil, false
+	}
+	return value, true
+}
+
+// checkFields reports the fields of the object which aren't part of the schema
+func (v *configurationValidator) checkFields(path string, fields map[string]interface{}, known ...string) {
+	var unknown []string
+	for name := range fields {
+		if !containsFold(known, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		v.addError(path, "unknown field %v, expected one of %v", name, strings.Join(known, ", "))
+	}
+}
+
+// field returns the value of the field, matching its name case insensitively as the plugin does
+func field(fields map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := fields[name]; ok {
+		return value, true
+	}
+	for key, value := range fields {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// describe returns the json type of the value for error messages
+func describe(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// position returns the line and column, starting at 1, of the byte at the given offset
+func position(document string, offset int64) (line int, column int) {
+	if offset < 0 {
+		offset = 0
+	} else if offset > int64(len(document)) {
+		offset = int64(len(document))
+	}
+	before := document[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = len(before) - strings.LastIndex(before, "\n")
+	return
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package cloudwatch implements cloudwatch plugin and its configuration
+package cloudwatch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// validationErrors returns the problems reported for the configuration
+func validationErrors(t *testing.T, configuration string) ConfigurationErrors {
+	err := ValidateConfiguration(configuration)
+	assert.Error(t, err)
+	errs, ok := err.(ConfigurationErrors)
+	assert.True(t, ok)
+	return errs
+}
+
+func TestValidateConfiguration(t *testing.T) {
+	assert.NoError(t, ValidateConfiguration(testEngineConfiguration))
+}
+
+func TestValidateEscapedEngineConfiguration(t *testing.T) {
+	config, err := parseEngineConfiguration(testEngineConfiguration)
+	assert.NoError(t, err)
+	escaped, err := jsonutil.Marshal(config)
+	assert.NoError(t, err)
+	wrapped, err := jsonutil.Marshal(map[string]string{"EngineConfiguration": escaped})
+	assert.NoError(t, err)
+
+	assert.NoError(t, ValidateConfiguration(wrapped))
+}
+
+func TestValidateConfigurationSyntaxError(t *testing.T) {
+	errs := validationErrors(t, "{\n  \"EngineConfiguration\": {\n    \"PollInterval\" \"00:00:15\"\n  }\n}")
+
+	assert.Equal(t, 1, len(errs))
+	assert.True(t, strings.HasPrefix(errs[0], "configuration: line 3, column 20:"), errs[0])
+}
+
+func TestValidateConfigurationEmpty(t *testing.T) {
+	errs := validationErrors(t, " ")
+
+	assert.Equal(t, ConfigurationErrors{"configuration: configuration is empty"}, errs)
+}
+
+func TestValidateConfigurationFieldErrors(t *testing.T) {
+	configuration := `{"EngineConfiguration": {
+		"PollInterval": "15 seconds",
+		"Components": [
+			{
+				"Id": "PerformanceCounter",
+				"FullName": "AWS.EC2.Windows.CloudWatch.PerformanceCounterComponent.PerformanceCounterInputComponent,AWS.EC2.Windows.CloudWatch",
+				"Parameters": {"CategoryName": "Memory", "MetricName": "AvailableMemory", "Unit": "Megabyte"}
+			},
+			{
+				"Id": "PerformanceCounter",
+				"FullName": "AWS.EC2.Windows.CloudWatch.CloudWatch.CloudWatchOutputComponent,AWS.EC2.Windows.CloudWatch",
+				"Parameters": {"NameSpace": "Test", "Dimensions": ["a"]},
+				"Enabled": true
+			}
+		],
+		"Flows": {"Flows": ["PerformanceCounter,CloudWatch", "PerformanceCounter"]}
+	}}`
+
+	errs := validationErrors(t, configuration)
+
+	assert.Equal(t, ConfigurationErrors{
+		"EngineConfiguration.PollInterval: invalid PollInterval 15 seconds, expected format is hh:mm:ss",
+		"EngineConfiguration.Components[0].Parameters: CounterName is required by PerformanceCounterInputComponent",
+		"EngineConfiguration.Components[0].Parameters.Unit: Megabyte is not a valid CloudWatch unit, expected one of " + strings.Join(standardUnits, ", "),
+		"EngineConfiguration.Components[1]: unknown field Enabled, expected one of Id, FullName, Parameters",
+		"EngineConfiguration.Components[1].Id: component PerformanceCounter is defined more than once",
+		"EngineConfiguration.Components[1].Parameters.Dimensions: expected a value but found an array",
+		"EngineConfiguration.Flows.Flows[0]: output component CloudWatch is not defined",
+		"EngineConfiguration.Flows.Flows[1]: invalid flow PerformanceCounter, expected format is input,output",
+	}, errs)
+}
+
+func TestValidateConfigurationFlowDirection(t *testing.T) {
+	configuration := strings.Replace(testEngineConfiguration,
+		`"PerformanceCounter,CloudWatch"`, `"CloudWatch,PerformanceCounter"`, 1)
+
+	errs := validationErrors(t, configuration)
+
+	assert.Equal(t, ConfigurationErrors{
+		"EngineConfiguration.Flows.Flows[0]: component CloudWatch is an output and can't be used as an input",
+		"EngineConfiguration.Flows.Flows[0]: component PerformanceCounter is an input and can't be used as an output",
+	}, errs)
+}
+
+func TestValidateConfigurationMissingSections(t *testing.T) {
+	errs := validationErrors(t, `{"EngineConfiguration": {"Components": {}}, "IsEnabled": "true"}`)
+
+	assert.Equal(t, ConfigurationErrors{
+		"EngineConfiguration.Components: expected an array but found an object",
+		"EngineConfiguration: Flows is required",
+		"IsEnabled: expected a boolean but found a string",
+	}, errs)
+}
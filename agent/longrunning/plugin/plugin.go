@@ -24,6 +24,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/plugin/rundaemon"
+	"github.com/aws/amazon-ssm-agent/agent/longrunning/plugin/statsd"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -54,6 +55,11 @@ type LongRunningPlugin interface {
 	Stop(context context.T, cancelFlag task.CancelFlag) error
 }
 
+// OutputReporter is implemented by the long running plugins which capture their output in a file, e.g. the daemons
+type OutputReporter interface {
+	OutputPath() string
+}
+
 //PluginSettings reflects settings that can be applied to long running plugins like aws:cloudWatch
 type PluginSettings struct {
 	StartType string
@@ -94,6 +100,15 @@ func loadPlatformIndependentPlugins(context context.T) map[string]Plugin {
 	//long running plugins that can be started/stopped/configured by long running plugin manager
 	longrunningplugins := make(map[string]Plugin)
 
+	//registering statsd plugin, which publishes the metrics of the applications to CloudWatch when enabled in the agent configuration
+	longrunningplugins[appconfig.PluginNameStatsd] = Plugin{
+		Info: PluginInfo{
+			Name:  appconfig.PluginNameStatsd,
+			State: PluginState{},
+		},
+		Handler: statsd.NewPlugin(),
+	}
+
 	for key, value := range loadDaemonPlugins(context) {
 		context.Log().Debugf("Adding long-running plugin for %v", key)
 		longrunningplugins[key] = value
@@ -136,9 +151,11 @@ func loadDaemonPlugins(context context.T) map[string]Plugin {
 						State:         PluginState{IsEnabled: true},
 					},
 					Handler: &rundaemon.Plugin{
-						ExeLocation: input.PackageLocation,
-						Name:        input.Name,
-						CommandLine: input.Command,
+						ExeLocation:   input.PackageLocation,
+						Name:          input.Name,
+						CommandLine:   input.Command,
+						RestartPolicy: input.RestartPolicy,
+						MaxRestarts:   input.MaxRestarts,
 					},
 				}
 				if _, exists := daemonPlugins[input.Name]; exists {
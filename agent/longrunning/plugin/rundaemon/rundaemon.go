@@ -16,6 +16,7 @@ package rundaemon
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
@@ -29,8 +30,17 @@ type ConfigureDaemonPluginInput struct {
 	Action          string `json:"action"`
 	PackageLocation string `json:"packagelocation"`
 	Command         string `json:"command"`
+	RestartPolicy   string `json:"restartpolicy"`
+	MaxRestarts     int    `json:"maxrestarts"`
 }
 
+// Restart policies of a daemon, deciding whether it is started again once its process exits
+const (
+	RestartAlways    = "Always"
+	RestartOnFailure = "OnFailure"
+	RestartNever     = "Never"
+)
+
 // ValidateDaemonInput validates the input given to configure daemon
 func ValidateDaemonInput(input ConfigureDaemonPluginInput) error {
 	if input.Name == "" {
@@ -50,5 +60,13 @@ func ValidateDaemonInput(input ConfigureDaemonPluginInput) error {
 	if input.Action == "Start" && input.Command == "" {
 		return errors.New("daemon launch command is missing")
 	}
+	switch input.RestartPolicy {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return fmt.Errorf("invalid daemon restart policy %v, must be one of %v, %v or %v", input.RestartPolicy, RestartAlways, RestartOnFailure, RestartNever)
+	}
+	if input.MaxRestarts < 0 {
+		return errors.New("daemon max restarts must not be negative")
+	}
 	return nil
 }
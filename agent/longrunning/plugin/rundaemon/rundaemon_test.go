@@ -0,0 +1,67 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package rundaemon implements rundaemon plugin and its configuration
+package rundaemon
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDaemonInputRestartPolicy(t *testing.T) {
+	input := ConfigureDaemonPluginInput{
+		Name:            "daemon",
+		Action:          "Start",
+		PackageLocation: os.TempDir(),
+		Command:         "daemon --foreground",
+	}
+	assert.NoError(t, ValidateDaemonInput(input))
+
+	input.RestartPolicy = RestartOnFailure
+	assert.NoError(t, ValidateDaemonInput(input))
+
+	input.RestartPolicy = "Sometimes"
+	assert.Error(t, ValidateDaemonInput(input))
+
+	input.RestartPolicy = RestartNever
+	input.MaxRestarts = -1
+	assert.Error(t, ValidateDaemonInput(input))
+}
+
+func TestRestartOnExit(t *testing.T) {
+	exitErr := errors.New("daemon exit status 1")
+
+	assert.True(t, (&Plugin{}).restartOnExit(nil))
+	assert.True(t, (&Plugin{RestartPolicy: RestartAlways}).restartOnExit(exitErr))
+	assert.False(t, (&Plugin{RestartPolicy: RestartOnFailure}).restartOnExit(nil))
+	assert.True(t, (&Plugin{RestartPolicy: RestartOnFailure}).restartOnExit(exitErr))
+	assert.False(t, (&Plugin{RestartPolicy: RestartNever}).restartOnExit(exitErr))
+}
+
+func TestRestartDelay(t *testing.T) {
+	assert.Equal(t, MinWaitBetweenRetries, restartDelay(1))
+	assert.Equal(t, 2*MinWaitBetweenRetries, restartDelay(2))
+	assert.Equal(t, 8*MinWaitBetweenRetries, restartDelay(4))
+	assert.Equal(t, MaxWaitBetweenRetries, restartDelay(10))
+	assert.Equal(t, 10*time.Minute, restartDelay(10))
+}
+
+func TestMaxRestarts(t *testing.T) {
+	assert.Equal(t, MaxRetryCountDuringFailures, (&Plugin{}).maxRestarts())
+	assert.Equal(t, 3, (&Plugin{MaxRestarts: 3}).maxRestarts())
+}
@@ -17,39 +17,36 @@
 package rundaemon
 
 import (
-	"github.com/aws/amazon-ssm-agent/agent/context"
-	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
-	"github.com/aws/amazon-ssm-agent/agent/task"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
-// Plugin is the type for the configureDaemon plugin.
-type Plugin struct {
-	iohandler.PluginConfig
-	// ExeLocation is the location directory for a particular daemon
-	ExeLocation string
-	// Name is the name of the daemon
-	Name string
-	// CommandLine is the command line to launch the daemon (On Windows, ame of executable or a powershell script)
-	CommandLine string
+// daemonArguments returns the executable to launch followed by its arguments
+func daemonArguments(configuration string) []string {
+	return strings.Fields(configuration)
 }
 
-// IsRunning checks if the daemon is alive
-func (p *Plugin) IsRunning(context context.T) bool {
-	log := context.Log()
-	log.Infof("IsRunning check for daemon %v", p.Name)
-	return false // TODO:DAEMON check to see if process is alive (false for now to force regular restarts and see the logs
+// prepareDaemonCommand runs the daemon in its own process group so its children can be stopped along with it
+func prepareDaemonCommand(daemonInvoke *exec.Cmd) {
+	daemonInvoke.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
-// Start starts the daemon
-func (p *Plugin) Start(context context.T, configuration string, orchestrationDir string, cancelFlag task.CancelFlag, out iohandler.IOHandler) error {
-	log := context.Log()
-	log.Infof("Starting %v Command: %v Config: %v", p.Name, p.CommandLine, configuration)
-	return nil
+// attachDaemonProcess does nothing, the process group of the daemon is enough to track its children
+func attachDaemonProcess(log log.T, process *os.Process) {
 }
 
-// Stop stops the daemon
-func (p *Plugin) Stop(context context.T, cancelFlag task.CancelFlag) error {
-	log := context.Log()
-	log.Infof("Stopping %v", p.Name)
-	return nil
+// killDaemonProcess kills the process group of the daemon
+func killDaemonProcess(log log.T, process *os.Process) {
+	if err := syscall.Kill(-process.Pid, syscall.SIGKILL); err != nil {
+		log.Infof("Encountered error while trying to stop the process group %v : %s", process.Pid, err.Error())
+		if err = process.Kill(); err != nil {
+			log.Infof("Encountered error while trying to kill the process %v : %s", process.Pid, err.Error())
+			return
+		}
+	}
+	log.Infof("Successfully stopped the process %v", process.Pid)
 }
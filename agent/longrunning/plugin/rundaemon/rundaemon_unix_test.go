@@ -0,0 +1,119 @@
+// +build darwin freebsd linux netbsd openbsd
+
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package rundaemon implements rundaemon plugin and its configuration
+package rundaemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDaemon creates a daemon running the given shell script, capturing its output in a temporary directory
+func newTestDaemon(t *testing.T, script string, restartPolicy string) (p *Plugin, dir string) {
+	dir, err := ioutil.TempDir("", "rundaemon")
+	assert.NoError(t, err)
+	daemonLogRoot = dir
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "daemon.sh"), []byte(script), 0700))
+	return &Plugin{Name: "testdaemon", ExeLocation: dir, RestartPolicy: restartPolicy}, dir
+}
+
+// waitFor polls the condition until it holds or the timeout expires
+func waitFor(condition func() bool, timeout time.Duration) bool {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return condition()
+}
+
+func TestDaemonOutputIsCaptured(t *testing.T) {
+	context := context.NewMockDefault()
+	p, dir := newTestDaemon(t, "echo started daemon\n", RestartNever)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, p.Start(context, "sh daemon.sh", "", task.NewMockDefault(), nil))
+
+	assert.True(t, waitFor(func() bool { return !p.IsRunning(context) }, 5*time.Second))
+	content, err := ioutil.ReadFile(DaemonLogPath(p.Name))
+	assert.NoError(t, err)
+	assert.Equal(t, "started daemon\n", string(content))
+}
+
+func TestStopDaemon(t *testing.T) {
+	context := context.NewMockDefault()
+	p, dir := newTestDaemon(t, "sleep 30\n", RestartAlways)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, p.Start(context, "sh daemon.sh", "", task.NewMockDefault(), nil))
+	assert.True(t, waitFor(func() bool { return IsDaemonRunning(p) }, 5*time.Second))
+	assert.True(t, p.IsRunning(context))
+
+	assert.NoError(t, p.Stop(context, task.NewMockDefault()))
+
+	// the goroutine managing the daemon has exited once Stop returns
+	assert.False(t, p.IsRunning(context))
+	assert.False(t, IsDaemonRunning(p))
+}
+
+func TestStopDaemonWaitingToRestart(t *testing.T) {
+	context := context.NewMockDefault()
+	p, dir := newTestDaemon(t, "exit 1\n", RestartAlways)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, p.Start(context, "sh daemon.sh", "", task.NewMockDefault(), nil))
+	// the daemon exits right away, so it is restarted after the minimum wait between retries
+	assert.True(t, waitFor(func() bool { return p.daemonProcess() != nil && !IsDaemonRunning(p) }, 5*time.Second))
+
+	stopStart := time.Now()
+	assert.NoError(t, p.Stop(context, task.NewMockDefault()))
+
+	assert.True(t, time.Since(stopStart) < MinWaitBetweenRetries)
+	assert.False(t, p.IsRunning(context))
+}
+
+func TestDaemonRestartsWithLatestConfiguration(t *testing.T) {
+	ctx := context.NewMockDefault()
+	p := &Plugin{Name: "testdaemon"}
+	var configurations []string
+	defer func() { StartDaemonHelperExecutor = StartDaemonHelper }()
+	StartDaemonHelperExecutor = func(p *Plugin, context context.T, configuration string) error {
+		configurations = append(configurations, configuration)
+		return nil
+	}
+
+	// the stubbed daemon has no process, so the supervisor exits once it started it
+	assert.NoError(t, p.Start(ctx, "sh daemon.sh", "", task.NewMockDefault(), nil))
+	assert.True(t, waitFor(func() bool { return !p.IsRunning(ctx) }, 5*time.Second))
+	p.Configure(ConfigureDaemonPluginInput{Command: "sh other.sh"})
+	assert.NoError(t, p.Start(ctx, "sh other.sh", "", task.NewMockDefault(), nil))
+	assert.True(t, waitFor(func() bool { return !p.IsRunning(ctx) }, 5*time.Second))
+
+	assert.Equal(t, []string{"sh daemon.sh", "sh other.sh"}, configurations)
+}
+
+func TestStartDaemonWithoutCommand(t *testing.T) {
+	p := &Plugin{Name: "testdaemon"}
+
+	assert.Error(t, p.Start(context.NewMockDefault(), " ", "", task.NewMockDefault(), nil))
+}
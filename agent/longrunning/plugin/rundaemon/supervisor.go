@@ -0,0 +1,459 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package rundaemon implements rundaemon plugin and its configuration
+package rundaemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// Created Executor function interfaces to allow for better testability
+var DaemonCmdExecutor = RunDaemon
+var BlockWhileDaemonRunningExecutor = BlockWhileDaemonRunning
+var StopDaemonExecutor = StopDaemon
+var IsDaemonRunningExecutor = IsDaemonRunning
+var StartDaemonHelperExecutor = StartDaemonHelper
+
+// RequestedDaemonStateType represents whether the user has explicitly requested to start/stop the daemon
+type RequestedDaemonStateType uint
+
+const (
+	RequestedDisabled RequestedDaemonStateType = iota
+	RequestedEnabled
+)
+
+// CurrentDaemonStateType represents whether the daemon is currently running or not.
+type CurrentDaemonStateType uint
+
+const (
+	CurrentStopped CurrentDaemonStateType = iota
+	CurrentRunning
+)
+
+// Plugin is the type for the configureDaemon plugin.
+type Plugin struct {
+	iohandler.PluginConfig
+	// ExeLocation is the directory for a particular daemon package
+	ExeLocation string
+	// Name is name of the daemon
+	Name string
+	// CommandLine is command line to launch the daemon (On Windows, ame of executable or a powershell script)
+	CommandLine string
+	// RestartPolicy decides whether the daemon is started again once its process exits, RestartAlways when empty
+	RestartPolicy string
+	// MaxRestarts is the number of successive quick restarts after which the daemon is given up on,
+	// MaxRetryCountDuringFailures when 0
+	MaxRestarts int
+	Process     *os.Process
+	//ProcessStateLock lock is used to Protect access to daemon state updates
+	ProcessStateLock sync.Mutex
+	// RequestedDaemonState represents whether the user has explicitly requested to start/stop the daemon
+	RequestedDaemonState RequestedDaemonStateType // 1 = Start. 0 = Stop
+	// CurrentDaemonState represents whether the daemon is currently running or not.
+	CurrentDaemonState CurrentDaemonStateType //  1 = Running, 0 = Stopped
+	// supervising is true while a goroutine manages the lifecycle of the daemon
+	supervising bool
+	// stopping is closed to interrupt the supervisor goroutine waiting to restart the daemon
+	stopping chan struct{}
+	// supervisorDone is closed once the supervisor goroutine exits
+	supervisorDone chan struct{}
+}
+
+// Configure applies the settings of the daemon, they are used from its next start
+func (p *Plugin) Configure(input ConfigureDaemonPluginInput) {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	p.ExeLocation = input.PackageLocation
+	p.CommandLine = input.Command
+	p.RestartPolicy = input.RestartPolicy
+	p.MaxRestarts = input.MaxRestarts
+}
+
+// MinWaitBetweenRetries 60seconds
+// Successive Daemon Restarts should be atleast 60sec apart
+const MinWaitBetweenRetries = 60 * time.Second
+
+// MaxWaitBetweenRetries 10 minutes
+// The wait between successive restarts doubles with every quick restart up to this limit
+const MaxWaitBetweenRetries = 600 * time.Second
+
+// MaxThresholdToResetRetryCounter 5 hours
+// If the daemon process exits happens after this specified threshold, then
+// the Retrycount is set back to 0.
+const MaxTimeThresholdToResetRetryCounter = 18000 * time.Second
+
+// MaxRetryCountDuringFailures
+const MaxRetryCountDuringFailures = 10
+
+// MaxWaitForSupervisorExit 30 seconds
+// Stop waits up to this limit for the goroutine managing the daemon to exit
+const MaxWaitForSupervisorExit = 30 * time.Second
+
+// MaxDaemonLogSize 10MB
+// The output of a daemon is moved aside when its log exceeds this size on (re)start
+const MaxDaemonLogSize = 10 * 1024 * 1024
+
+// BlockWhileDaemonRunning checks if the process with the given process id is still running
+// The function will block and the context swapped out while the underlying process is still running.
+// An error is returned when the process could not be waited on or exited with a non zero exit code.
+func BlockWhileDaemonRunning(context context.T, pid int) error {
+	log := context.Log()
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		log.Infof("Daemon Not Running. Pid %v : %s", pid, err.Error())
+		return err
+	}
+	log.Infof("Waiting for the process to die")
+	// Control blocks here until this process stops running (gets killed for example)
+	state, err := process.Wait()
+	if err == nil && !state.Success() {
+		err = fmt.Errorf("daemon %v", state)
+	}
+	return err
+}
+
+// IsRunning returns if the said plugin is running or not, to the long running plugin manager.
+// The plugin is running as long as a goroutine manages the lifecycle of the underlying daemon,
+// including while it waits to restart it.
+func (p *Plugin) IsRunning(context context.T) bool {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	return p.supervising
+}
+
+// This function sets the flag to indicate that daemon stop has been requested via the StopPlugin call.
+func (p *Plugin) stopRequested() bool {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	return p.RequestedDaemonState == RequestedDisabled
+}
+
+// This function sets the daemon current state to being stopped.
+func (p *Plugin) SetDaemonStateStopped() {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	p.CurrentDaemonState = CurrentStopped
+}
+
+// restartOnExit returns whether the restart policy requires to start the daemon again after it exited
+func (p *Plugin) restartOnExit(exitErr error) bool {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	switch p.RestartPolicy {
+	case RestartNever:
+		return false
+	case RestartOnFailure:
+		return exitErr != nil
+	default:
+		return true
+	}
+}
+
+// maxRestarts returns the number of successive quick restarts after which the daemon is given up on
+func (p *Plugin) maxRestarts() int {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	if p.MaxRestarts > 0 {
+		return p.MaxRestarts
+	}
+	return MaxRetryCountDuringFailures
+}
+
+// daemonProcess returns the process of the daemon, nil when it is not started
+func (p *Plugin) daemonProcess() *os.Process {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	return p.Process
+}
+
+// commandLine returns the command line launching the daemon, it is read at every (re)start so the latest configuration is used
+func (p *Plugin) commandLine() string {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	return p.CommandLine
+}
+
+// waitBeforeRestart waits for the given delay, it returns false when the daemon is stopped in the meantime
+func waitBeforeRestart(stopping chan struct{}, delay time.Duration) bool {
+	select {
+	case <-stopping:
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// restartDelay returns the minimum time between the start of the daemon and its next restart,
+// doubling with every successive quick restart
+func restartDelay(retryCount int) time.Duration {
+	delay := MinWaitBetweenRetries
+	for i := 1; i < retryCount && delay < MaxWaitBetweenRetries; i++ {
+		delay *= 2
+	}
+	if delay > MaxWaitBetweenRetries {
+		delay = MaxWaitBetweenRetries
+	}
+	return delay
+}
+
+// daemonLogRoot is the directory capturing the output of the daemons
+var daemonLogRoot = filepath.Join(logger.DefaultLogDir, appconfig.DaemonLogRootDirName)
+
+// DaemonLogPath returns the file the output of the daemon is captured in
+func DaemonLogPath(name string) string {
+	return filepath.Join(daemonLogRoot, name+".log")
+}
+
+// OutputPath returns the file the output of the daemon is captured in
+func (p *Plugin) OutputPath() string {
+	return DaemonLogPath(p.Name)
+}
+
+// openDaemonLog opens the log of the daemon for appending, moving the previous log aside when it grew too large
+func openDaemonLog(name string) (*os.File, error) {
+	logPath := DaemonLogPath(name)
+	if err := fileutil.MakeDirs(filepath.Dir(logPath)); err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(logPath); err == nil && fi.Size() > MaxDaemonLogSize {
+		if err = os.Rename(logPath, logPath+".1"); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, appconfig.ReadWriteAccess)
+}
+
+// Function RunDaemon invokes exec.Cmd.Start with appropriate arguments.
+func RunDaemon(daemonInvoke *exec.Cmd) (err error) {
+	err = daemonInvoke.Start()
+	return err
+}
+
+// Function checks if the daemon is currently running or not.
+func IsDaemonRunning(p *Plugin) bool {
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	return p.CurrentDaemonState == CurrentRunning
+}
+
+// Starts a given executable or a specified powershell script and enables daemon functionality
+func StartDaemonHelper(p *Plugin, context context.T, configuration string) (err error) {
+	log := context.Log()
+	if IsDaemonRunningExecutor(p) {
+		log.Infof("Daemon already running: %v", configuration)
+		return
+	}
+
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	// the daemon may have been stopped since the supervisor decided to start it
+	if p.RequestedDaemonState == RequestedDisabled {
+		log.Infof("Daemon requested to be stopped: %v", configuration)
+		return
+	}
+	log.Infof("Attempting to Start Daemon")
+
+	//TODO Currently pathnames with spaces do not seem to work correctly with the below
+	// usage of exec.command. Given that ConfigurePackage defaults to a directory name which
+	// doesnt have spaces (C:/ProgramData/Amazon/SSM/....), the issue is not currently exposed.
+	// Needs to be fixed regardless.
+
+	commandArguments := daemonArguments(configuration)
+	log.Infof("Running command: %v.", commandArguments)
+
+	daemonInvoke := exec.Command(commandArguments[0], commandArguments[1:]...)
+	daemonInvoke.Dir = p.ExeLocation
+	prepareDaemonCommand(daemonInvoke)
+
+	// the output of the daemon is captured in its own log, the daemon still starts when it can't be
+	if daemonLog, logErr := openDaemonLog(p.Name); logErr != nil {
+		log.Warnf("Unable to capture the output of daemon %v: %v", p.Name, logErr)
+	} else {
+		defer daemonLog.Close()
+		daemonInvoke.Stdout = daemonLog
+		daemonInvoke.Stderr = daemonLog
+	}
+
+	err = DaemonCmdExecutor(daemonInvoke)
+
+	if err != nil {
+		log.Errorf("Error starting Daemon: %s", err.Error())
+		return err
+	}
+	p.Process = daemonInvoke.Process
+	attachDaemonProcess(log, p.Process)
+	p.CurrentDaemonState = CurrentRunning
+	return
+}
+
+// Starts a given executable or a specified powershell script and enables daemon functionality,
+// the command line of the daemon is read from its configuration at every (re)start
+func StartDaemon(p *Plugin, context context.T, stopping chan struct{}, supervisorDone chan struct{}) (err error) {
+	log := context.Log()
+	defer func() {
+		p.ProcessStateLock.Lock()
+		p.supervising = false
+		p.ProcessStateLock.Unlock()
+		close(supervisorDone)
+	}()
+
+	// Bail out if an explicit Stop daemon is requested by the user
+	if p.stopRequested() {
+		log.Infof("Daemon requested to be stopped: %v", p.Name)
+		return
+	}
+
+	// Below loop initiates daemon startup and then goes to sleep once the daemon
+	// starts running. Once the daemon exits, it is launched again as allowed by its
+	// restart policy unless the user has explicitly requested a stop.
+	retryCount := 0
+	var startTime time.Time
+	for {
+		if process := p.daemonProcess(); process != nil {
+			err = BlockWhileDaemonRunningExecutor(context, process.Pid)
+			p.SetDaemonStateStopped()
+			if err != nil {
+				log.Infof("Encountered error: process may not have exited cleanly. Pid %v : %s", process.Pid, err.Error())
+			}
+			if p.stopRequested() {
+				log.Infof("Daemon requested to be stopped: %v", p.Name)
+				return
+			}
+			if !p.restartOnExit(err) {
+				log.Infof("Daemon %v exited, not restarting it as its restart policy is %v", p.Name, p.RestartPolicy)
+				return
+			}
+
+			// Successive daemon restarts are spaced out, more and more as the daemon keeps exiting quickly
+			uptime := time.Since(startTime)
+			if uptime > MaxTimeThresholdToResetRetryCounter {
+				log.Infof("Setting retrycount for %s daemon back to 0", p.Name)
+				retryCount = 0
+			} else if uptime < MinWaitBetweenRetries {
+				if retryCount++; retryCount > p.maxRestarts() {
+					log.Infof("Daemon %v process exited for %v times within the minimum threshold time window from its startup. Bailing out.", p.Name, retryCount)
+					return
+				}
+				log.Infof("Waiting %v to start %s again", restartDelay(retryCount)-uptime, p.Name)
+				if !waitBeforeRestart(stopping, restartDelay(retryCount)-uptime) {
+					log.Infof("Daemon requested to be stopped: %v", p.Name)
+					return
+				}
+			}
+		}
+
+		// Bail out if an explicit Stop daemon is requested by the user
+		if p.stopRequested() {
+			log.Infof("Daemon requested to be stopped: %v", p.Name)
+			return
+		}
+		// Invoke the helper function to start daemon
+		startTime = time.Now()
+		if err = StartDaemonHelperExecutor(p, context, p.commandLine()); err != nil {
+			if retryCount++; retryCount > p.maxRestarts() {
+				log.Infof("Daemon %v failed to start %v times. Bailing out.", p.Name, retryCount)
+				return
+			}
+			log.Infof("Waiting %v to start %s again", restartDelay(retryCount), p.Name)
+			if !waitBeforeRestart(stopping, restartDelay(retryCount)) {
+				log.Infof("Daemon requested to be stopped: %v", p.Name)
+				return
+			}
+			continue
+		}
+		log.Infof("Started Daemon...")
+		if p.daemonProcess() == nil {
+			return
+		}
+	}
+}
+
+// Start launches a goroutine managing the lifecycle of the daemon unless one is already running
+func (p *Plugin) Start(context context.T, configuration string, orchestrationDir string, cancelFlag task.CancelFlag, out iohandler.IOHandler) error {
+	log := context.Log()
+	log.Infof(" Package location - %s", p.ExeLocation)
+	log.Infof(" Command/Script/Executable to be run - %s", configuration)
+
+	if strings.TrimSpace(configuration) == "" {
+		return fmt.Errorf("no command to run daemon %v", p.Name)
+	}
+
+	// Start a new daemon handling goroutine if the goroutine is currently not running
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	// Set the User Requested state to ENABLED
+	p.RequestedDaemonState = RequestedEnabled
+	p.CommandLine = configuration
+	if !p.supervising {
+		log.Infof(" Invoking goroutine to manage daemon lifecycle")
+		p.supervising = true
+		p.stopping = make(chan struct{})
+		p.supervisorDone = make(chan struct{})
+		go StartDaemon(p, context, p.stopping, p.supervisorDone)
+	}
+	return nil
+}
+
+// StopDaemon stops the daemon process along with its children and prevents it from being restarted
+func StopDaemon(p *Plugin, context context.T) {
+	log := context.Log()
+	p.ProcessStateLock.Lock()
+	defer p.ProcessStateLock.Unlock()
+	p.RequestedDaemonState = RequestedDisabled
+	if p.stopping != nil {
+		close(p.stopping)
+		p.stopping = nil
+	}
+	if p.Process != nil {
+		log.Infof("Process id of daemon -> %v", p.Process.Pid)
+		if p.CurrentDaemonState == CurrentRunning {
+			killDaemonProcess(log, p.Process)
+			p.CurrentDaemonState = CurrentStopped
+		}
+		p.Process = nil
+	}
+}
+
+// Stop stops the daemon and waits for the goroutine managing its lifecycle to exit
+func (p *Plugin) Stop(context context.T, cancelFlag task.CancelFlag) error {
+	log := context.Log()
+	log.Infof("Stopping Daemon")
+	p.ProcessStateLock.Lock()
+	supervisorDone := p.supervisorDone
+	p.ProcessStateLock.Unlock()
+
+	StopDaemonExecutor(p, context)
+	if supervisorDone == nil {
+		return nil
+	}
+	select {
+	case <-supervisorDone:
+		return nil
+	case <-time.After(MaxWaitForSupervisorExit):
+		return fmt.Errorf("daemon %v did not stop within %v", p.Name, MaxWaitForSupervisorExit)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statsd
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// maxDimensions is the maximum count of dimensions of a CloudWatch metric, the extra dimensions are dropped by name order
+const maxDimensions = 10
+
+// maxMetrics bounds the count of distinct metrics aggregated between two flushes, the new metrics above are dropped
+const maxMetrics = 10000
+
+// metric holds the values of a metric aggregated since the last flush
+type metric struct {
+	name       string
+	kind       metricType
+	dimensions []*cloudwatch.Dimension
+	// value is the sum of a counter or the last value of a gauge
+	value float64
+	// updated is set when a gauge received a value since the last flush
+	updated bool
+	// statistics of a timer
+	count, sum, minimum, maximum float64
+	// members of a set
+	members map[string]bool
+}
+
+// aggregator aggregates the StatsD samples until they are flushed to CloudWatch
+type aggregator struct {
+	mutex      sync.Mutex
+	dimensions map[string]string
+	metrics    map[string]*metric
+	// gauges are kept between flushes so that relative values apply to the last known value
+	gauges  map[string]*metric
+	dropped int
+}
+
+// newAggregator creates an aggregator which adds the given dimensions to every metric
+func newAggregator(dimensions map[string]string) *aggregator {
+	return &aggregator{
+		dimensions: dimensions,
+		metrics:    make(map[string]*metric),
+		gauges:     make(map[string]*metric),
+	}
+}
+
+// add aggregates a sample with the metric of the same name, type and dimensions
+func (a *aggregator) add(s sample) {
+	kind := s.kind
+	if kind == typeHistogram {
+		kind = typeTimer
+	}
+	dimensions := a.metricDimensions(s.tags)
+	key := metricKey(s.name, kind, dimensions)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	metrics := a.metrics
+	if kind == typeGauge {
+		metrics = a.gauges
+	}
+	m, exists := metrics[key]
+	if !exists {
+		if len(a.metrics)+len(a.gauges) >= maxMetrics {
+			a.dropped++
+			return
+		}
+		m = &metric{name: s.name, kind: kind, dimensions: dimensions}
+		metrics[key] = m
+	}
+
+	switch kind {
+	case typeCounter:
+		m.value += s.value / s.sampleRate
+	case typeGauge:
+		if s.relative {
+			m.value += s.value
+		} else {
+			m.value = s.value
+		}
+		m.updated = true
+	case typeTimer:
+		if m.count == 0 || s.value < m.minimum {
+			m.minimum = s.value
+		}
+		if m.count == 0 || s.value > m.maximum {
+			m.maximum = s.value
+		}
+		m.count += 1 / s.sampleRate
+		m.sum += s.value / s.sampleRate
+	case typeSet:
+		if m.members == nil {
+			m.members = make(map[string]bool)
+		}
+		m.members[s.member] = true
+	}
+}
+
+// flush returns the metrics aggregated since the last flush as CloudWatch metric data, ordered by key, and resets them
+func (a *aggregator) flush(timestamp time.Time) (data []*cloudwatch.MetricDatum, dropped int) {
+	a.mutex.Lock()
+	metrics := a.metrics
+	a.metrics = make(map[string]*metric)
+	for key, gauge := range a.gauges {
+		if gauge.updated {
+			copied := *gauge
+			metrics[key] = &copied
+			gauge.updated = false
+		}
+	}
+	dropped, a.dropped = a.dropped, 0
+	a.mutex.Unlock()
+
+	keys := make([]string, 0, len(metrics))
+	for key := range metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data = make([]*cloudwatch.MetricDatum, 0, len(keys))
+	for _, key := range keys {
+		m := metrics[key]
+		datum := &cloudwatch.MetricDatum{
+			MetricName: aws.String(m.name),
+			Dimensions: m.dimensions,
+			Timestamp:  aws.Time(timestamp),
+		}
+		switch m.kind {
+		case typeCounter:
+			datum.Unit = aws.String(cloudwatch.StandardUnitCount)
+			datum.Value = aws.Float64(m.value)
+		case typeGauge:
+			datum.Unit = aws.String(cloudwatch.StandardUnitNone)
+			datum.Value = aws.Float64(m.value)
+		case typeTimer:
+			datum.Unit = aws.String(cloudwatch.StandardUnitMilliseconds)
+			datum.StatisticValues = &cloudwatch.StatisticSet{
+				SampleCount: aws.Float64(m.count),
+				Sum:         aws.Float64(m.sum),
+				Minimum:     aws.Float64(m.minimum),
+				Maximum:     aws.Float64(m.maximum),
+			}
+		case typeSet:
+			datum.Unit = aws.String(cloudwatch.StandardUnitCount)
+			datum.Value = aws.Float64(float64(len(m.members)))
+		}
+		data = append(data, datum)
+	}
+	return data, dropped
+}
+
+// metricDimensions merges the configured dimensions with the tags of a metric, ordered by name
+func (a *aggregator) metricDimensions(tags map[string]string) []*cloudwatch.Dimension {
+	merged := make(map[string]string, len(a.dimensions)+len(tags))
+	for name, value := range a.dimensions {
+		merged[name] = value
+	}
+	for name, value := range tags {
+		merged[name] = value
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > maxDimensions {
+		names = names[:maxDimensions]
+	}
+
+	dimensions := make([]*cloudwatch.Dimension, 0, len(names))
+	for _, name := range names {
+		dimensions = append(dimensions, &cloudwatch.Dimension{Name: aws.String(name), Value: aws.String(merged[name])})
+	}
+	return dimensions
+}
+
+// metricKey identifies a metric by its name, type and dimensions
+func metricKey(name string, kind metricType, dimensions []*cloudwatch.Dimension) string {
+	parts := []string{name, string(kind)}
+	for _, dimension := range dimensions {
+		parts = append(parts, aws.StringValue(dimension.Name)+"="+aws.StringValue(dimension.Value))
+	}
+	return strings.Join(parts, "|")
+}
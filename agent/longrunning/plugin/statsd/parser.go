@@ -0,0 +1,121 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statsd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// metricType is the type of a StatsD metric
+type metricType string
+
+// StatsD metric types, histograms are aggregated as timers
+const (
+	typeCounter   metricType = "c"
+	typeGauge     metricType = "g"
+	typeTimer     metricType = "ms"
+	typeHistogram metricType = "h"
+	typeSet       metricType = "s"
+)
+
+// sample is a single StatsD metric line, e.g. api.requests:1|c|@0.5|#route:login
+type sample struct {
+	name  string
+	kind  metricType
+	value float64
+	// member is the raw value of a set sample
+	member string
+	// relative is set for the gauges whose value is prefixed by a sign, they are added to the current value
+	relative bool
+	// sampleRate is the fraction of the events sent by the client, the counters and timers are scaled by its inverse
+	sampleRate float64
+	tags       map[string]string
+}
+
+// parsePacket parses the newline separated metrics of a packet, the invalid lines are returned as errors
+func parsePacket(packet string) (samples []sample, errs []error) {
+	for _, line := range strings.Split(packet, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if s, err := parseLine(line); err != nil {
+			errs = append(errs, err)
+		} else {
+			samples = append(samples, s)
+		}
+	}
+	return
+}
+
+// parseLine parses a metric in the StatsD format name:value|type[|@rate][|#tag:value,...]
+func parseLine(line string) (s sample, err error) {
+	colon := strings.LastIndex(strings.SplitN(line, "|", 2)[0], ":")
+	if colon <= 0 {
+		return s, fmt.Errorf("metric %q has no name", line)
+	}
+	s.name = line[:colon]
+	fields := strings.Split(line[colon+1:], "|")
+	if len(fields) < 2 {
+		return s, fmt.Errorf("metric %q has no type", line)
+	}
+	s.kind = metricType(fields[1])
+	s.sampleRate = 1
+	for _, field := range fields[2:] {
+		switch {
+		case strings.HasPrefix(field, "@"):
+			if s.sampleRate, err = strconv.ParseFloat(field[1:], 64); err != nil || s.sampleRate <= 0 || s.sampleRate > 1 {
+				return s, fmt.Errorf("metric %q has an invalid sample rate", line)
+			}
+		case strings.HasPrefix(field, "#"):
+			s.tags = parseTags(field[1:])
+		default:
+			return s, fmt.Errorf("metric %q has an unknown field %q", line, field)
+		}
+	}
+
+	value := fields[0]
+	switch s.kind {
+	case typeSet:
+		if value == "" {
+			return s, fmt.Errorf("metric %q has no value", line)
+		}
+		s.member = value
+		return s, nil
+	case typeGauge:
+		s.relative = strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-")
+	case typeCounter, typeTimer, typeHistogram:
+	default:
+		return s, fmt.Errorf("metric %q has an unsupported type %q", line, s.kind)
+	}
+	if s.value, err = strconv.ParseFloat(value, 64); err != nil {
+		return s, fmt.Errorf("metric %q has an invalid value", line)
+	}
+	return s, nil
+}
+
+// parseTags parses the comma separated name:value tags of a metric, the tags without value are ignored since
+// CloudWatch dimensions require one
+func parseTags(field string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(field, ",") {
+		parts := strings.SplitN(tag, ":", 2)
+		if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+			tags[parts[0]] = parts[1]
+		}
+	}
+	return tags
+}
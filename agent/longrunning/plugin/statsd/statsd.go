@@ -0,0 +1,162 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package statsd implements the aws:statsd long running plugin, a local StatsD listener which aggregates the metrics
+// of the applications and publishes them to CloudWatch.
+package statsd
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cloudwatchmetrics"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// maxPacketSize is the size of the largest UDP packet read by the listener
+const maxPacketSize = 65535
+
+// assign methods to variables to allow the unit tests to override them
+var (
+	newMetricsService = func() cloudwatchmetrics.Service {
+		return cloudwatch.New(session.New(sdkutil.AwsConfig()))
+	}
+	listenPacket = net.ListenPacket
+)
+
+// Plugin listens for StatsD metrics over UDP and publishes them to CloudWatch at every flush interval
+type Plugin struct {
+	mutex    sync.Mutex
+	conn     net.PacketConn
+	stopChan chan bool
+	wg       sync.WaitGroup
+}
+
+// NewPlugin creates a new StatsD listener plugin.
+func NewPlugin() *Plugin {
+	return &Plugin{}
+}
+
+// IsRunning returns true if the listener is started
+func (p *Plugin) IsRunning(context context.T) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.conn != nil
+}
+
+// Start listens on the address of the agent configuration, the configuration of the plugin is ignored
+func (p *Plugin) Start(context context.T, configuration string, orchestrationDir string, cancelFlag task.CancelFlag, out iohandler.IOHandler) (err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	log := context.Log()
+	if p.conn != nil {
+		log.Debug("statsd listener is already running")
+		return nil
+	}
+
+	config := context.AppConfig().Statsd
+	if p.conn, err = listenPacket("udp", config.ListenAddress); err != nil {
+		log.Errorf("unable to listen for statsd metrics on %v: %v", config.ListenAddress, err)
+		return err
+	}
+	log.Infof("listening for statsd metrics on %v, publishing them to CloudWatch namespace %v every %d seconds",
+		p.conn.LocalAddr(), config.Namespace, config.FlushIntervalSeconds)
+
+	aggregator := newAggregator(config.Dimensions)
+	p.stopChan = make(chan bool)
+	p.wg.Add(2)
+	go p.receive(log, p.conn, aggregator)
+	go p.run(log, newMetricsService(), aggregator, config.Namespace, time.Duration(config.FlushIntervalSeconds)*time.Second)
+	return nil
+}
+
+// Stop closes the listener and publishes the remaining metrics
+func (p *Plugin) Stop(context context.T, cancelFlag task.CancelFlag) (err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.conn == nil {
+		return nil
+	}
+	context.Log().Info("stopping statsd listener")
+	close(p.stopChan)
+	err = p.conn.Close()
+	p.wg.Wait()
+	p.conn = nil
+	return err
+}
+
+// receive aggregates the metrics of the packets until the connection is closed
+func (p *Plugin) receive(log log.T, conn net.PacketConn, aggregator *aggregator) {
+	defer p.wg.Done()
+	buffer := make([]byte, maxPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			select {
+			case <-p.stopChan:
+			default:
+				log.Errorf("statsd listener stopped receiving metrics: %v", err)
+			}
+			return
+		}
+		samples, errs := parsePacket(string(buffer[:n]))
+		for _, err := range errs {
+			// the clients don't get any feedback, an invalid metric shouldn't flood the agent log either
+			log.Debugf("dropping invalid statsd metric: %v", err)
+		}
+		for _, s := range samples {
+			aggregator.add(s)
+		}
+	}
+}
+
+// run publishes the aggregated metrics at every interval until the plugin is stopped
+func (p *Plugin) run(log log.T, service cloudwatchmetrics.Service, aggregator *aggregator, namespace string, interval time.Duration) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			publish(log, service, aggregator, namespace)
+		case <-p.stopChan:
+			publish(log, service, aggregator, namespace)
+			return
+		}
+	}
+}
+
+// publish sends the metrics aggregated since the last publication, in batches which respect the limits of PutMetricData
+func publish(log log.T, service cloudwatchmetrics.Service, aggregator *aggregator, namespace string) {
+	data, dropped := aggregator.flush(time.Now())
+	if dropped > 0 {
+		log.Warnf("dropped %v statsd samples, more than %v distinct metrics were received", dropped, maxMetrics)
+	}
+	if unpublished, err := cloudwatchmetrics.Publish(service, namespace, data); err != nil {
+		// the metrics are dropped rather than piled up while CloudWatch can't be reached
+		log.Warnf("unable to publish %v statsd metrics to CloudWatch namespace %v: %v", unpublished, namespace, err)
+		return
+	}
+	if len(data) > 0 {
+		log.Debugf("published %v statsd metrics", len(data))
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cloudwatchmetrics"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type metricsServiceMock struct {
+	mock.Mock
+}
+
+func (m *metricsServiceMock) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	args := m.Called(input)
+	return &cloudwatch.PutMetricDataOutput{}, args.Error(0)
+}
+
+func TestParseLine(t *testing.T) {
+	s, err := parseLine("api.requests:2|c|@0.5|#route:login,region:eu,flag")
+	assert.NoError(t, err)
+	assert.Equal(t, "api.requests", s.name)
+	assert.Equal(t, typeCounter, s.kind)
+	assert.Equal(t, float64(2), s.value)
+	assert.Equal(t, 0.5, s.sampleRate)
+	assert.Equal(t, map[string]string{"route": "login", "region": "eu"}, s.tags)
+
+	s, err = parseLine("queue.depth:-3|g")
+	assert.NoError(t, err)
+	assert.True(t, s.relative)
+	assert.Equal(t, float64(-3), s.value)
+
+	s, err = parseLine("users:alice|s")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", s.member)
+
+	for _, line := range []string{"no.value", ":1|c", "latency:1", "latency:abc|ms", "latency:1|x", "latency:1|c|@2", "latency:1|c|extra"} {
+		_, err = parseLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestParsePacket(t *testing.T) {
+	samples, errs := parsePacket("a:1|c\n\nb:2|ms\ninvalid\n")
+	assert.Equal(t, 2, len(samples))
+	assert.Equal(t, 1, len(errs))
+}
+
+func TestAggregatorFlush(t *testing.T) {
+	a := newAggregator(map[string]string{"host": "web1", "route": "default"})
+	for _, line := range []string{
+		"requests:1|c", "requests:1|c|@0.5",
+		"latency:10|ms", "latency:30|h",
+		"depth:5|g", "depth:+2|g",
+		"users:alice|s", "users:bob|s", "users:alice|s",
+		"requests:1|c|#route:login",
+	} {
+		s, err := parseLine(line)
+		assert.NoError(t, err)
+		a.add(s)
+	}
+
+	data, dropped := a.flush(time.Now())
+	assert.Equal(t, 0, dropped)
+	assert.Equal(t, 5, len(data))
+	byKey := make(map[string]*cloudwatch.MetricDatum)
+	for _, datum := range data {
+		byKey[aws.StringValue(datum.MetricName)+"/"+aws.StringValue(datum.Dimensions[1].Value)] = datum
+	}
+	assert.Equal(t, float64(3), aws.Float64Value(byKey["requests/default"].Value))
+	assert.Equal(t, float64(1), aws.Float64Value(byKey["requests/login"].Value))
+	assert.Equal(t, float64(7), aws.Float64Value(byKey["depth/default"].Value))
+	assert.Equal(t, float64(2), aws.Float64Value(byKey["users/default"].Value))
+	latency := byKey["latency/default"]
+	assert.Equal(t, cloudwatch.StandardUnitMilliseconds, aws.StringValue(latency.Unit))
+	assert.Equal(t, float64(2), aws.Float64Value(latency.StatisticValues.SampleCount))
+	assert.Equal(t, float64(40), aws.Float64Value(latency.StatisticValues.Sum))
+	assert.Equal(t, float64(10), aws.Float64Value(latency.StatisticValues.Minimum))
+	assert.Equal(t, float64(30), aws.Float64Value(latency.StatisticValues.Maximum))
+	assert.Equal(t, "host", aws.StringValue(latency.Dimensions[0].Name))
+
+	// gauges are only published again once updated, relative to their last value
+	data, _ = a.flush(time.Now())
+	assert.Equal(t, 0, len(data))
+	s, _ := parseLine("depth:-1|g")
+	a.add(s)
+	data, _ = a.flush(time.Now())
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, float64(6), aws.Float64Value(data[0].Value))
+}
+
+func TestAggregatorLimits(t *testing.T) {
+	dimensions := make(map[string]string)
+	for i := 0; i < maxDimensions+2; i++ {
+		dimensions[fmt.Sprintf("d%02d", i)] = "v"
+	}
+	a := newAggregator(dimensions)
+	for i := 0; i < maxMetrics+3; i++ {
+		a.add(sample{name: fmt.Sprintf("m%d", i), kind: typeCounter, value: 1, sampleRate: 1})
+	}
+
+	data, dropped := a.flush(time.Now())
+	assert.Equal(t, maxMetrics, len(data))
+	assert.Equal(t, 3, dropped)
+	assert.Equal(t, maxDimensions, len(data[0].Dimensions))
+}
+
+func TestPublishInBatches(t *testing.T) {
+	a := newAggregator(nil)
+	for i := 0; i < 25; i++ {
+		a.add(sample{name: fmt.Sprintf("m%02d", i), kind: typeCounter, value: 1, sampleRate: 1})
+	}
+	service := &metricsServiceMock{}
+	service.On("PutMetricData", mock.AnythingOfType("*cloudwatch.PutMetricDataInput")).Return(nil)
+
+	publish(log.NewMockLog(), service, a, "StatsD")
+
+	service.AssertNumberOfCalls(t, "PutMetricData", 2)
+	input := service.Calls[0].Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+	assert.Equal(t, "StatsD", aws.StringValue(input.Namespace))
+	assert.Equal(t, cloudwatchmetrics.MaxMetricDataPerPut, len(input.MetricData))
+	assert.Equal(t, 5, len(service.Calls[1].Arguments.Get(0).(*cloudwatch.PutMetricDataInput).MetricData))
+}
+
+func TestStartReceivesAndStopPublishes(t *testing.T) {
+	service := &metricsServiceMock{}
+	service.On("PutMetricData", mock.AnythingOfType("*cloudwatch.PutMetricDataInput")).Return(nil)
+	defaultMetricsService := newMetricsService
+	newMetricsService = func() cloudwatchmetrics.Service { return service }
+	defer func() { newMetricsService = defaultMetricsService }()
+
+	config := appconfig.SsmagentConfig{Statsd: appconfig.StatsdCfg{
+		Enabled:              true,
+		ListenAddress:        "127.0.0.1:0",
+		Namespace:            "StatsD",
+		FlushIntervalSeconds: 3600,
+	}}
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+
+	p := NewPlugin()
+	assert.NoError(t, p.Start(ctx, "", "", task.NewChanneledCancelFlag(), nil))
+	assert.True(t, p.IsRunning(ctx))
+
+	conn, err := net.Dial("udp", p.conn.LocalAddr().String())
+	assert.NoError(t, err)
+	_, err = conn.Write([]byte("requests:4|c\n"))
+	assert.NoError(t, err)
+	conn.Close()
+
+	// wait for the packet to be aggregated, UDP gives no acknowledgment
+	time.Sleep(200 * time.Millisecond)
+
+	assert.NoError(t, p.Stop(ctx, task.NewChanneledCancelFlag()))
+	assert.False(t, p.IsRunning(ctx))
+	service.AssertNumberOfCalls(t, "PutMetricData", 1)
+	input := service.Calls[0].Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+	assert.Equal(t, "requests", aws.StringValue(input.MetricData[0].MetricName))
+	assert.Equal(t, float64(4), aws.Float64Value(input.MetricData[0].Value))
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package lowpriority runs the housekeeping of the agent, such as inventory gathering, log shipping and artifact
+// hashing, at a reduced cpu and io priority so that it doesn't compete with the workloads of the instance.
+package lowpriority
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+var (
+	getAppConfig = appconfig.Config
+
+	loadOnce sync.Once
+	enabled  bool
+)
+
+// loadEnabled reads whether the background work runs at low priority from the agent configuration
+func loadEnabled() {
+	config, err := getAppConfig(false)
+	if err != nil {
+		return
+	}
+	enabled = config.Agent.BackgroundLowPriority
+}
+
+// Go starts fn in a new goroutine, which runs at low priority when the agent configuration enables it.
+// The goroutine is locked to a thread whose priority is lowered, and which exits with it rather than running
+// other goroutines at low priority. The processes fn starts inherit the priority of the thread.
+func Go(log log.T, fn func()) {
+	loadOnce.Do(loadEnabled)
+	if !enabled {
+		go fn()
+		return
+	}
+	go func() {
+		runtime.LockOSThread()
+		if err := lowerThreadPriority(); err != nil {
+			log.Debugf("unable to lower the priority of the background work: %v", err)
+		}
+		fn()
+	}()
+}
+
+// Run runs fn at low priority when the agent configuration enables it and waits for it to return.
+// A panic of fn is raised again in the calling goroutine.
+func Run(log log.T, fn func()) {
+	done := make(chan interface{})
+	Go(log, func() {
+		defer func() {
+			done <- recover()
+		}()
+		fn()
+	})
+	if panicked := <-done; panicked != nil {
+		panic(panicked)
+	}
+}
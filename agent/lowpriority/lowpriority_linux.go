@@ -0,0 +1,42 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package lowpriority
+
+import (
+	"syscall"
+)
+
+const (
+	// niceness is the nice value of the background work, 0 being the default and 19 the lowest priority
+	niceness = 10
+
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// lowerThreadPriority sets the nice value of the current thread and moves it to the idle io scheduling class,
+// which only gets disk time when no other process needs it
+func lowerThreadPriority() error {
+	tid := syscall.Gettid()
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, niceness); err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift); errno != 0 {
+		return errno
+	}
+	return nil
+}
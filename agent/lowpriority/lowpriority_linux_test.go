@@ -0,0 +1,44 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package lowpriority
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// threadNiceness returns the nice value of the current thread, the kernel returning 20 minus the nice value
+func threadNiceness() int {
+	priority, _ := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+	return 20 - priority
+}
+
+func TestRunLowersThreadPriority(t *testing.T) {
+	defer func(get func(bool) (appconfig.SsmagentConfig, error)) { getAppConfig = get }(getAppConfig)
+	setEnabled(true)
+
+	before := threadNiceness()
+	var during int
+	Run(log.NewMockLog(), func() { during = threadNiceness() })
+	if before >= niceness {
+		t.Skipf("the tests already run with nice value %v", before)
+	}
+	assert.Equal(t, niceness, during)
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package lowpriority
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// setEnabled reloads the configuration with the background work at low priority or not
+func setEnabled(lowPriority bool) {
+	getAppConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Agent.BackgroundLowPriority = lowPriority
+		return config, nil
+	}
+	loadOnce = sync.Once{}
+	enabled = false
+}
+
+func TestRunDisabled(t *testing.T) {
+	defer func(get func(bool) (appconfig.SsmagentConfig, error)) { getAppConfig = get }(getAppConfig)
+	setEnabled(false)
+
+	ran := false
+	Run(log.NewMockLog(), func() { ran = true })
+	assert.True(t, ran)
+	assert.False(t, enabled)
+}
+
+func TestRunEnabled(t *testing.T) {
+	defer func(get func(bool) (appconfig.SsmagentConfig, error)) { getAppConfig = get }(getAppConfig)
+	setEnabled(true)
+
+	ran := false
+	Run(log.NewMockLog(), func() { ran = true })
+	assert.True(t, ran)
+	assert.True(t, enabled)
+}
+
+func TestRunConfigError(t *testing.T) {
+	defer func(get func(bool) (appconfig.SsmagentConfig, error)) { getAppConfig = get }(getAppConfig)
+	setEnabled(true)
+	getAppConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		return appconfig.SsmagentConfig{}, errors.New("corrupt configuration")
+	}
+
+	ran := false
+	Run(log.NewMockLog(), func() { ran = true })
+	assert.True(t, ran)
+	assert.False(t, enabled)
+}
+
+func TestRunPanic(t *testing.T) {
+	defer func(get func(bool) (appconfig.SsmagentConfig, error)) { getAppConfig = get }(getAppConfig)
+	setEnabled(true)
+
+	defer func() {
+		assert.Equal(t, "gatherer failed", recover())
+	}()
+	Run(log.NewMockLog(), func() { panic("gatherer failed") })
+	assert.Fail(t, "the panic was not raised again")
+}
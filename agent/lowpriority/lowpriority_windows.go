@@ -0,0 +1,38 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package lowpriority
+
+import (
+	"syscall"
+)
+
+// threadModeBackgroundBegin lowers the cpu, io and memory priorities of the thread to the background mode
+const threadModeBackgroundBegin = 0x00010000
+
+var (
+	kernel32          = syscall.NewLazyDLL("kernel32.dll")
+	getCurrentThread  = kernel32.NewProc("GetCurrentThread")
+	setThreadPriority = kernel32.NewProc("SetThreadPriority")
+)
+
+// lowerThreadPriority moves the current thread to the background processing mode
+func lowerThreadPriority() error {
+	thread, _, _ := getCurrentThread.Call()
+	if r1, _, e1 := setThreadPriority.Call(thread, threadModeBackgroundBegin); r1 == 0 {
+		return e1
+	}
+	return nil
+}
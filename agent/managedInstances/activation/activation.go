@@ -0,0 +1,157 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package activation registers managed instances with the activations of SSM
+package activation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
+	"github.com/aws/amazon-ssm-agent/agent/ssm/anonauth"
+)
+
+// reactivationCommandTimeout is the time the reactivation command has to print the activation
+const reactivationCommandTimeout = time.Minute
+
+// ErrNotConfigured is returned when neither a reactivation file nor a reactivation command is configured
+var ErrNotConfigured = errors.New("no reactivation file or command is configured")
+
+// Activation holds the SSM activation a managed instance registers with
+type Activation struct {
+	ActivationCode string
+	ActivationId   string
+	Region         string
+}
+
+// dependencies for testing
+var (
+	newAnonymousService = anonauth.NewAnonymousService
+	readFile            = ioutil.ReadFile
+	runCommand          = func(ctx context.Context, command string) ([]byte, error) {
+		return exec.CommandContext(ctx, shell, append(shellArgs, command)...).Output()
+	}
+	clearRegistration     = func() error { return registration.UpdateServerInfo("", "", "", "") }
+	regenerateFingerprint = registration.RegenerateFingerprint
+	register              = Register
+)
+
+// Register registers the instance with the activation and persists the registration information,
+// it returns the managed instance id.
+func Register(activationCode, activationID, region string) (managedInstanceID string, err error) {
+	// try to activate the instance with the activation credentials
+	publicKey, privateKey, keyType, err := registration.GenerateKeyPair()
+	if err != nil {
+		return managedInstanceID, fmt.Errorf("error generating signing keys. %v", err)
+	}
+
+	// checking write access before registering
+	err = registration.UpdateServerInfo("", "", privateKey, keyType)
+	if err != nil {
+		return managedInstanceID,
+			fmt.Errorf("Unable to save registration information. %v\nTry running as sudo/administrator.", err)
+	}
+
+	// generate fingerprint
+	fingerprint, err := registration.Fingerprint()
+	if err != nil {
+		return managedInstanceID, fmt.Errorf("error generating instance fingerprint. %v", err)
+	}
+
+	service := newAnonymousService(region)
+	managedInstanceID, err = service.RegisterManagedInstance(
+		activationCode,
+		activationID,
+		publicKey,
+		keyType,
+		fingerprint,
+	)
+
+	if err != nil {
+		return managedInstanceID, fmt.Errorf("error registering the instance with AWS SSM. %v", err)
+	}
+
+	err = registration.UpdateServerInfo(managedInstanceID, region, privateKey, keyType)
+	if err != nil {
+		return managedInstanceID, fmt.Errorf("error persisting the instance registration information. %v", err)
+	}
+
+	// saving registration information to the registration file
+	reg := map[string]string{
+		"ManagedInstanceID": managedInstanceID,
+		"Region":            region,
+	}
+
+	var regData []byte
+	if regData, err = json.Marshal(reg); err != nil {
+		return "", fmt.Errorf("Failed to marshal registration info. %v", err)
+	}
+
+	registrationFile := filepath.Join(appconfig.DefaultDataStorePath, appconfig.RegistrationFileName)
+	if err = ioutil.WriteFile(registrationFile, regData, appconfig.ReadWriteAccess); err != nil {
+		return "", fmt.Errorf("Failed to write registration info to file. %v", err)
+	}
+
+	return managedInstanceID, nil
+}
+
+// ReRegister clears the registration of the instance, replaces its fingerprint and registers it again with the
+// activation, such as when VMs cloned from the same image conflict. It returns the new managed instance id.
+func ReRegister(activation Activation) (managedInstanceID string, err error) {
+	if err = clearRegistration(); err != nil {
+		return "", fmt.Errorf("error clearing the instance registration information. %v", err)
+	}
+	if _, err = regenerateFingerprint(); err != nil {
+		return "", fmt.Errorf("error regenerating the instance fingerprint. %v", err)
+	}
+	return register(activation.ActivationCode, activation.ActivationId, activation.Region)
+}
+
+// Load returns the activation provided by the operator in the reactivation file, or printed by the reactivation
+// command, as configured in appconfig. The region defaults to the region of the current registration.
+func Load(config appconfig.RegistrationCfg) (activation Activation, err error) {
+	var content []byte
+	if file := strings.TrimSpace(config.ReactivationFile); file != "" {
+		if content, err = readFile(file); err != nil {
+			return activation, fmt.Errorf("error reading the reactivation file %v. %v", file, err)
+		}
+	} else if command := strings.TrimSpace(config.ReactivationCommand); command != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), reactivationCommandTimeout)
+		defer cancel()
+		if content, err = runCommand(ctx, command); err != nil {
+			return activation, fmt.Errorf("error running the reactivation command. %v", err)
+		}
+	} else {
+		return activation, ErrNotConfigured
+	}
+
+	if err = json.Unmarshal(content, &activation); err != nil {
+		return activation, fmt.Errorf("error parsing the activation. %v", err)
+	}
+	if activation.Region == "" {
+		activation.Region = registration.Region()
+	}
+	if activation.ActivationCode == "" || activation.ActivationId == "" || activation.Region == "" {
+		return activation, errors.New("the activation requires an ActivationCode, ActivationId and Region")
+	}
+	return activation, nil
+}
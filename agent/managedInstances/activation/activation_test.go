@@ -0,0 +1,95 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package activation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFromFile(t *testing.T) {
+	readFile = func(filename string) ([]byte, error) {
+		assert.Equal(t, "/etc/amazon/ssm/activation.json", filename)
+		return []byte(`{"ActivationCode":"code","ActivationId":"id","Region":"us-east-2"}`), nil
+	}
+
+	activation, err := Load(appconfig.RegistrationCfg{ReactivationFile: "/etc/amazon/ssm/activation.json", ReactivationCommand: "unused"})
+	assert.NoError(t, err)
+	assert.Equal(t, Activation{ActivationCode: "code", ActivationId: "id", Region: "us-east-2"}, activation)
+}
+
+func TestLoadFromCommand(t *testing.T) {
+	runCommand = func(ctx context.Context, command string) ([]byte, error) {
+		assert.Equal(t, "fetch-activation --json", command)
+		return []byte(`{"ActivationCode":"code","ActivationId":"id","Region":"eu-west-1"}`), nil
+	}
+
+	activation, err := Load(appconfig.RegistrationCfg{ReactivationCommand: " fetch-activation --json "})
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", activation.Region)
+
+	runCommand = func(ctx context.Context, command string) ([]byte, error) {
+		return nil, errors.New("exit status 1")
+	}
+	_, err = Load(appconfig.RegistrationCfg{ReactivationCommand: "fetch-activation"})
+	assert.Error(t, err)
+}
+
+func TestLoadInvalidActivation(t *testing.T) {
+	_, err := Load(appconfig.RegistrationCfg{})
+	assert.Equal(t, ErrNotConfigured, err)
+
+	readFile = func(filename string) ([]byte, error) {
+		return []byte(`{"ActivationCode":"code","Region":"us-east-2"}`), nil
+	}
+	_, err = Load(appconfig.RegistrationCfg{ReactivationFile: "activation.json"})
+	assert.Error(t, err)
+
+	readFile = func(filename string) ([]byte, error) {
+		return []byte(`not json`), nil
+	}
+	_, err = Load(appconfig.RegistrationCfg{ReactivationFile: "activation.json"})
+	assert.Error(t, err)
+}
+
+func TestReRegister(t *testing.T) {
+	calls := []string{}
+	clearRegistration = func() error {
+		calls = append(calls, "clear")
+		return nil
+	}
+	regenerateFingerprint = func() (string, error) {
+		calls = append(calls, "fingerprint")
+		return "new-fingerprint", nil
+	}
+	register = func(activationCode, activationID, region string) (string, error) {
+		calls = append(calls, "register "+activationCode+" "+activationID+" "+region)
+		return "mi-123", nil
+	}
+
+	instanceID, err := ReRegister(Activation{ActivationCode: "code", ActivationId: "id", Region: "us-east-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "mi-123", instanceID)
+	assert.Equal(t, []string{"clear", "fingerprint", "register code id us-east-2"}, calls)
+
+	calls = []string{}
+	regenerateFingerprint = func() (string, error) { return "", errors.New("vault is not writable") }
+	_, err = ReRegister(Activation{ActivationCode: "code", ActivationId: "id", Region: "us-east-2"})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"clear"}, calls, "the instance is not registered without a new fingerprint")
+}
@@ -69,6 +69,11 @@ func Fingerprint() (string, error) {
 	return fingerprint.InstanceFingerprint()
 }
 
+// RegenerateFingerprint replaces the fingerprint of the managed instance with a new one.
+func RegenerateFingerprint() (string, error) {
+	return fingerprint.Regenerate()
+}
+
 // HasManagedInstancesCredentials returns true when the valid registration information is present
 func HasManagedInstancesCredentials() (bool, error) {
 	info := getInstanceInfo()
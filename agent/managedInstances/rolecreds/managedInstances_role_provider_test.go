@@ -21,6 +21,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/activation"
+	"github.com/aws/amazon-ssm-agent/agent/ssm/rsaauth"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/stretchr/testify/assert"
 )
@@ -145,3 +150,84 @@ func (r registrationStub) GenerateKeyPair() (publicKey, privateKey, keyType stri
 func (r registrationStub) UpdatePrivateKey(privateKey, privateKeyType string) (err error) {
 	return r.err
 }
+
+func TestRetrieve_ShouldReRegisterWhenRegistrationIsInvalid(t *testing.T) {
+	updateKeyPair := false
+	tokenExpirationDate := time.Now().Add(1 * time.Hour)
+	logger = log.NewMockLog()
+	managedInstance = registrationStub{instanceID: "mi-new", region: "us-east-1"}
+	lastReRegistration = time.Time{}
+	loadAppConfig = func(bool) (appconfig.SsmagentConfig, error) { return appconfig.DefaultConfig(), nil }
+	loadActivation = func(appconfig.RegistrationCfg) (activation.Activation, error) {
+		return activation.Activation{ActivationCode: "code", ActivationId: "id", Region: "us-east-1"}, nil
+	}
+	registered := 0
+	register = func(activationCode, activationID, region string) (string, error) {
+		registered++
+		return "mi-new", nil
+	}
+	newRsaService = func(instanceID, region, privateKey string) rsaauth.RsaSignedService {
+		assert.Equal(t, "mi-new", instanceID)
+		return &RsaSignedServiceStub{
+			roleResponse: ssm.RequestManagedInstanceRoleTokenOutput{
+				AccessKeyId:         &accessKeyID,
+				SecretAccessKey:     &secretAccessKey,
+				SessionToken:        &sessionToken,
+				UpdateKeyPair:       &updateKeyPair,
+				TokenExpirationDate: &tokenExpirationDate,
+			},
+		}
+	}
+	testProvider := managedInstancesRoleProvider{
+		Client: &RsaSignedServiceStub{err: awserr.New("InvalidInstanceId", "instance not registered", nil)},
+	}
+
+	cred, err := testProvider.Retrieve()
+	assert.NoError(t, err)
+	assert.Equal(t, accessKeyID, cred.AccessKeyID)
+	assert.Equal(t, 1, registered)
+
+	// attempts are throttled by the reactivation interval
+	testProvider.Client = &RsaSignedServiceStub{err: awserr.New("InvalidInstanceId", "instance not registered", nil)}
+	_, err = testProvider.Retrieve()
+	assert.Error(t, err)
+	assert.Equal(t, 1, registered)
+}
+
+func TestRetrieve_ShouldNotReRegisterWithoutActivation(t *testing.T) {
+	logger = log.NewMockLog()
+	managedInstance = registrationStub{}
+	lastReRegistration = time.Time{}
+	loadAppConfig = func(bool) (appconfig.SsmagentConfig, error) { return appconfig.DefaultConfig(), nil }
+	loadActivation = func(appconfig.RegistrationCfg) (activation.Activation, error) {
+		return activation.Activation{}, activation.ErrNotConfigured
+	}
+	register = func(activationCode, activationID, region string) (string, error) {
+		assert.Fail(t, "unexpected registration")
+		return "", nil
+	}
+	testProvider := managedInstancesRoleProvider{
+		Client: &RsaSignedServiceStub{err: awserr.New("InvalidInstanceId", "instance not registered", nil)},
+	}
+
+	_, err := testProvider.Retrieve()
+	assert.Error(t, err)
+	assert.True(t, lastReRegistration.IsZero())
+}
+
+func TestRetrieve_ShouldNotReRegisterWhenAccessIsDenied(t *testing.T) {
+	logger = log.NewMockLog()
+	managedInstance = registrationStub{}
+	lastReRegistration = time.Time{}
+	loadActivation = func(appconfig.RegistrationCfg) (activation.Activation, error) {
+		assert.Fail(t, "unexpected re-registration")
+		return activation.Activation{}, activation.ErrNotConfigured
+	}
+	testProvider := managedInstancesRoleProvider{
+		Client: &RsaSignedServiceStub{err: awserr.New("AccessDeniedException", "access denied", nil)},
+	}
+
+	_, err := testProvider.Retrieve()
+	assert.Error(t, err)
+	assert.True(t, lastReRegistration.IsZero())
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// package rolecreds contains functions that help procure the managed instance auth credentials
+package rolecreds
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/activation"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/ssm/rsaauth"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// invalidRegistrationErrorCodes are the errors of the SSM Auth service meaning the registration is no longer valid
+var invalidRegistrationErrorCodes = map[string]bool{
+	"InvalidInstanceId": true,
+}
+
+// lastReRegistration is the time of the last re-registration attempt
+var lastReRegistration time.Time
+
+// dependencies for re-registration
+var (
+	loadAppConfig  = appconfig.Config
+	loadActivation = activation.Load
+	register       = activation.Register
+	newRsaService  = rsaauth.NewRsaService
+)
+
+// isRegistrationInvalid returns true if the error means the instance is no longer registered with these credentials
+func isRegistrationInvalid(err error) bool {
+	if aErr, ok := err.(awserr.Error); ok {
+		return invalidRegistrationErrorCodes[aErr.Code()]
+	}
+	return false
+}
+
+// reRegister registers the instance again with the activation provided by the operator, and switches the provider
+// to the new registration. It returns false if no activation is configured, if the previous attempt is more recent
+// than the reactivation interval, or if the registration fails.
+func (m *managedInstancesRoleProvider) reRegister(cause error) bool {
+	config, err := loadAppConfig(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+
+	interval := time.Duration(config.Registration.ReactivationIntervalMinutes) * time.Minute
+	if !lastReRegistration.IsZero() && time.Since(lastReRegistration) < interval {
+		return false
+	}
+
+	act, err := loadActivation(config.Registration)
+	if err == activation.ErrNotConfigured {
+		return false
+	}
+	lastReRegistration = time.Now()
+	logger.Warnf("The registration of managed instance %v is no longer valid, re-registering. %v", managedInstance.InstanceID(), cause)
+	if err != nil {
+		logger.Errorf("Failed to load the activation to re-register with. %v", err)
+		return false
+	}
+
+	instanceID, err := register(act.ActivationCode, act.ActivationId, act.Region)
+	if err != nil {
+		logger.Errorf("Failed to re-register the managed instance. %v", err)
+		return false
+	}
+
+	logger.Infof("Successfully re-registered the instance with AWS SSM using Managed instance-id: %s", instanceID)
+	platform.SetInstanceID(instanceID)
+	platform.SetRegion(act.Region)
+	m.Client = newRsaService(managedInstance.InstanceID(), managedInstance.Region(), managedInstance.PrivateKey())
+	return true
+}
@@ -109,6 +109,9 @@ func (m *managedInstancesRoleProvider) Retrieve() (credentials.Value, error) {
 	}
 
 	roleCreds, err := m.Client.RequestManagedInstanceRoleToken(fingerprint)
+	if err != nil && isRegistrationInvalid(err) && m.reRegister(err) {
+		roleCreds, err = m.Client.RequestManagedInstanceRoleToken(fingerprint)
+	}
 	if err != nil {
 		return emptyCredential, fmt.Errorf("error occurred in RequestManagedInstanceRoleToken: %v", err)
 	}
@@ -0,0 +1,205 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package notification notifies a local webhook or an SNS topic whenever a document finishes on the instance,
+// with a JSON summary of the execution signed with HMAC-SHA256, so that on-box orchestration and alerting
+// don't need to poll the SSM API.
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+const (
+	// SignatureHeader is the header of the webhook requests holding the signature of their body
+	SignatureHeader = "X-Amz-Ssm-Signature"
+	// SignatureAttribute is the attribute of the SNS messages holding the signature of their body
+	SignatureAttribute = "Signature"
+
+	signaturePrefix = "sha256="
+	keySize         = 32
+)
+
+// Summary is the body of the notification sent when a document finishes
+type Summary struct {
+	docmanager.ExecutionRecord
+	InstanceID string
+	// Timestamp is the time the notification was sent at, which lets the receivers reject replayed notifications
+	Timestamp time.Time
+}
+
+// publisher is the subset of the SNS operations used to publish the notifications
+type publisher interface {
+	Publish(input *sns.PublishInput) (*sns.PublishOutput, error)
+}
+
+var (
+	keyLock sync.Mutex
+
+	// keyPath is the file of the key generated when none is configured, its content is the key
+	keyPath      = filepath.Join(appconfig.DefaultDataStorePath, "notification", "key")
+	newPublisher = newSnsPublisher
+)
+
+// DocumentCompleted sends the summary of a document which reached a terminal status to the configured webhook
+// and SNS topic. Failed notifications are logged and not retried.
+func DocumentCompleted(log log.T, config appconfig.NotificationsCfg, state contracts.DocumentState, result contracts.DocumentResult) {
+	if config.WebhookUrl == "" && config.SnsTopicArn == "" {
+		return
+	}
+
+	summary := Summary{
+		ExecutionRecord: docmanager.NewExecutionRecord(state, result),
+		InstanceID:      state.DocumentInformation.InstanceID,
+		Timestamp:       time.Now().UTC(),
+	}
+	body, err := json.Marshal(summary)
+	if err != nil {
+		log.Errorf("failed to marshal the notification of execution %v: %v", summary.ExecutionID, err)
+		return
+	}
+	key, err := signingKey(config)
+	if err != nil {
+		log.Errorf("failed to load the key the notifications are signed with: %v", err)
+		return
+	}
+	signature := Sign(key, body)
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+
+	if config.WebhookUrl != "" {
+		if err = postWebhook(config.WebhookUrl, body, signature, timeout); err != nil {
+			log.Warnf("failed to notify the webhook of execution %v: %v", summary.ExecutionID, err)
+		} else {
+			log.Debugf("notified the webhook of execution %v", summary.ExecutionID)
+		}
+	}
+	if config.SnsTopicArn != "" {
+		if err = publishMessage(config.SnsTopicArn, body, signature, timeout); err != nil {
+			log.Warnf("failed to notify SNS topic %v of execution %v: %v", config.SnsTopicArn, summary.ExecutionID, err)
+		} else {
+			log.Debugf("notified SNS topic %v of execution %v", config.SnsTopicArn, summary.ExecutionID)
+		}
+	}
+}
+
+// Sign returns the signature of the body, "sha256=" followed by the hex encoded HMAC-SHA256 of the body
+func Sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook posts the summary to the webhook, which must answer with a 2xx status
+func postWebhook(url string, body []byte, signature string, timeout time.Duration) error {
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, signature)
+
+	client := http.Client{Timeout: timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook answered with status %v", response.Status)
+	}
+	return nil
+}
+
+// publishMessage publishes the summary to the SNS topic, with its signature as a message attribute
+func publishMessage(topicArn string, body []byte, signature string, timeout time.Duration) error {
+	client, err := newPublisher(topicArn, timeout)
+	if err != nil {
+		return err
+	}
+	_, err = client.Publish(&sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			SignatureAttribute: {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(signature),
+			},
+		},
+	})
+	return err
+}
+
+// newSnsPublisher returns an SNS client of the region of the topic, e.g. arn:aws:sns:us-east-1:123456789012:topic
+func newSnsPublisher(topicArn string, timeout time.Duration) (publisher, error) {
+	parts := strings.Split(topicArn, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[3] == "" {
+		return nil, fmt.Errorf("invalid SNS topic arn %v", topicArn)
+	}
+	config := sdkutil.AwsConfig()
+	config.Region = aws.String(parts[3])
+	config.HTTPClient.Timeout = timeout
+	return sns.New(session.New(config)), nil
+}
+
+// signingKey returns the configured key, or the key generated in the data folder of the agent the first time
+// a notification is sent, which the receivers on the instance read to verify the signatures
+func signingKey(config appconfig.NotificationsCfg) ([]byte, error) {
+	if config.SigningKey != "" {
+		return []byte(config.SigningKey), nil
+	}
+
+	keyLock.Lock()
+	defer keyLock.Unlock()
+	content, err := ioutil.ReadFile(keyPath)
+	if err == nil {
+		return bytes.TrimSpace(content), nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	random := make([]byte, keySize)
+	if _, err = rand.Read(random); err != nil {
+		return nil, err
+	}
+	key := []byte(hex.EncodeToString(random))
+	if err = fileutil.MakeDirs(filepath.Dir(keyPath)); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(keyPath, key, appconfig.ReadWriteAccess); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
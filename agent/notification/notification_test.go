@@ -0,0 +1,169 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+var testState = contracts.DocumentState{
+	DocumentInformation: contracts.DocumentInfo{
+		DocumentID:   "documentID",
+		CommandID:    "commandID",
+		InstanceID:   "i-1234567890abcdef0",
+		DocumentName: "AWS-RunShellScript",
+	},
+	DocumentType: contracts.SendCommand,
+	InstancePluginsInformation: []contracts.PluginState{
+		{Id: "step1", Name: "aws:runShellScript"},
+	},
+}
+
+var testResult = contracts.DocumentResult{
+	DocumentName: "AWS-RunShellScript",
+	Status:       contracts.ResultStatusFailed,
+	PluginResults: map[string]*contracts.PluginResult{
+		"step1": {PluginID: "step1", PluginName: "aws:runShellScript", Status: contracts.ResultStatusFailed, Code: 2},
+	},
+}
+
+type mockPublisher struct {
+	inputs []*sns.PublishInput
+	err    error
+}
+
+func (p *mockPublisher) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	p.inputs = append(p.inputs, input)
+	return &sns.PublishOutput{}, p.err
+}
+
+func TestDocumentCompletedPostsSignedSummary(t *testing.T) {
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer server.Close()
+
+	config := appconfig.NotificationsCfg{WebhookUrl: server.URL, SigningKey: "secret", TimeoutSeconds: 5}
+	DocumentCompleted(logger, config, testState, testResult)
+
+	var summary Summary
+	assert.NoError(t, json.Unmarshal(body, &summary))
+	assert.Equal(t, "documentID", summary.ExecutionID)
+	assert.Equal(t, "commandID", summary.CommandID)
+	assert.Equal(t, "i-1234567890abcdef0", summary.InstanceID)
+	assert.Equal(t, contracts.ResultStatusFailed, summary.Status)
+	assert.Equal(t, 1, len(summary.Steps))
+	assert.Equal(t, 2, summary.Steps[0].ExitCode)
+	assert.WithinDuration(t, time.Now(), summary.Timestamp, time.Minute)
+	assert.Equal(t, Sign([]byte("secret"), body), signature)
+}
+
+func TestDocumentCompletedPublishesToSns(t *testing.T) {
+	mock := &mockPublisher{}
+	var publishedTopic string
+	newPublisher = func(topicArn string, timeout time.Duration) (publisher, error) {
+		publishedTopic = topicArn
+		return mock, nil
+	}
+	defer func() { newPublisher = newSnsPublisher }()
+
+	topicArn := "arn:aws:sns:us-west-2:123456789012:document-completed"
+	config := appconfig.NotificationsCfg{SnsTopicArn: topicArn, SigningKey: "secret", TimeoutSeconds: 5}
+	DocumentCompleted(logger, config, testState, testResult)
+
+	assert.Equal(t, topicArn, publishedTopic)
+	assert.Equal(t, 1, len(mock.inputs))
+	input := mock.inputs[0]
+	assert.Equal(t, topicArn, aws.StringValue(input.TopicArn))
+	message := aws.StringValue(input.Message)
+	assert.Contains(t, message, `"ExecutionID":"documentID"`)
+	assert.Equal(t, Sign([]byte("secret"), []byte(message)), aws.StringValue(input.MessageAttributes[SignatureAttribute].StringValue))
+}
+
+func TestDocumentCompletedWithoutTargets(t *testing.T) {
+	newPublisher = func(topicArn string, timeout time.Duration) (publisher, error) {
+		assert.Fail(t, "no SNS topic is configured")
+		return nil, fmt.Errorf("unexpected")
+	}
+	defer func() { newPublisher = newSnsPublisher }()
+
+	DocumentCompleted(logger, appconfig.NotificationsCfg{}, testState, testResult)
+}
+
+func TestPostWebhookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := postWebhook(server.URL, []byte("{}"), "sha256=00", time.Second)
+	assert.Error(t, err)
+}
+
+func TestNewSnsPublisherRejectsInvalidArn(t *testing.T) {
+	_, err := newSnsPublisher("document-completed", time.Second)
+	assert.Error(t, err)
+	_, err = newSnsPublisher("arn:aws:sqs:us-west-2:123456789012:queue", time.Second)
+	assert.Error(t, err)
+}
+
+func TestSigningKeyIsGeneratedOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notification")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyPath = filepath.Join(dir, "notification", "key")
+	defer func() { keyPath = filepath.Join(appconfig.DefaultDataStorePath, "notification", "key") }()
+
+	key, err := signingKey(appconfig.NotificationsCfg{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2*keySize, len(key))
+
+	content, err := ioutil.ReadFile(keyPath)
+	assert.NoError(t, err)
+	assert.Equal(t, key, content)
+
+	again, err := signingKey(appconfig.NotificationsCfg{})
+	assert.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	configured, err := signingKey(appconfig.NotificationsCfg{SigningKey: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), configured)
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 test vector of RFC 4231, test case 2
+	assert.Equal(t, "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		Sign([]byte("Jefe"), []byte("what do ya want for nothing?")))
+}
@@ -112,6 +112,17 @@ func isSingleParameterString(input string, paramName string) bool {
 	return false
 }
 
+// parameterReferenceRegex matches a string of the form "{{ paramName }}" and captures the parameter name
+var parameterReferenceRegex = regexp.MustCompile(`^{{\s*([a-zA-Z0-9]+)\s*}}$`)
+
+// ParameterReference returns the name of the parameter when the input has the form "{{ paramName }}".
+func ParameterReference(input string) (paramName string, isReference bool) {
+	if match := parameterReferenceRegex.FindStringSubmatch(input); match != nil {
+		return match[1], true
+	}
+	return "", false
+}
+
 // ReplaceParameter replaces all occurrences of "{{ paramName }}" in the input by paramValue.
 func ReplaceParameter(input string, paramName string, paramValue string) string {
 	// this method should be called only on parameter names that have been validated first
@@ -51,6 +51,17 @@ func TestIsSingleParameterString(t *testing.T) {
 	}
 }
 
+func TestParameterReference(t *testing.T) {
+	name, isReference := ParameterReference("{{ command}}")
+	assert.True(t, isReference)
+	assert.Equal(t, "command", name)
+
+	for _, input := range []string{"command", "a {{ command }}", "{{ co!mmand }}", "{{ a }}{{ b }}"} {
+		_, isReference = ParameterReference(input)
+		assert.False(t, isReference, input)
+	}
+}
+
 type ValidateNameTest struct {
 	ParamName string
 	Result    bool
@@ -0,0 +1,129 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package parameterstore contains modules to resolve ssm parameters present in the document.
+package parameterstore
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/ssm"
+)
+
+// secureParamRegex matches references of the format {{ssm-secure:*}}
+var secureParamRegex = regexp.MustCompile("\\{\\{ *ssm-secure:([/\\w.-]+) *\\}\\}")
+
+var callSecureParameterService = callGetDecryptedParameters
+
+// ResolveSecureString resolves parameters of the format {{ssm-secure:*}} with the decrypted value of the
+// SecureString parameters. It's meant to be called right before the plugin runs, so the values are only kept
+// in memory, and returns the values so they can be redacted from the output of the plugin.
+// NOTE: Do not log the resolved input
+func ResolveSecureString(log log.T, input interface{}) (resolved interface{}, values []string, err error) {
+	references := extractSSMParameters(log, input, secureParamRegex)
+	if len(references) == 0 {
+		return input, nil, nil
+	}
+
+	paramNames := []string{}
+	seen := map[string]bool{}
+	for _, reference := range references {
+		name := secureParamRegex.FindStringSubmatch(reference)[1]
+		if !seen[name] {
+			seen[name] = true
+			paramNames = append(paramNames, name)
+		}
+	}
+
+	result, err := callSecureParameterService(log, paramNames)
+	if err != nil {
+		return input, nil, err
+	}
+	if len(result.InvalidParameters) > 0 {
+		return input, nil, fmt.Errorf("Input contains invalid parameters %v", result.InvalidParameters)
+	}
+
+	paramsByName := map[string]Parameter{}
+	for _, paramObj := range result.Parameters {
+		if paramObj.Type != ParamTypeSecureString {
+			return input, nil, fmt.Errorf("Parameter %v referenced as ssm-secure must be of type %v, current type - %v", paramObj.Name, ParamTypeSecureString, paramObj.Type)
+		}
+		paramsByName[paramObj.Name] = paramObj
+		values = append(values, paramObj.Value)
+	}
+
+	resolvedParamMap := map[string]Parameter{}
+	for _, reference := range references {
+		name := secureParamRegex.FindStringSubmatch(reference)[1]
+		paramObj, found := paramsByName[name]
+		if !found {
+			return input, nil, fmt.Errorf("Input contains invalid parameters [%v]", name)
+		}
+		resolvedParamMap[reference] = paramObj
+	}
+
+	if resolved, err = replaceSSMParameters(log, input, resolvedParamMap); err != nil {
+		return input, nil, err
+	}
+	return resolved, values, nil
+}
+
+// GetSecureString returns the decrypted value of the given SecureString parameter.
+// NOTE: Do not log the returned value
+func GetSecureString(log log.T, name string) (string, error) {
+	result, err := callSecureParameterService(log, []string{name})
+	if err != nil {
+		return "", err
+	}
+	if len(result.InvalidParameters) > 0 || len(result.Parameters) == 0 {
+		return "", fmt.Errorf("Parameter %v does not exist", name)
+	}
+	paramObj := result.Parameters[0]
+	if paramObj.Type != ParamTypeSecureString {
+		return "", fmt.Errorf("Parameter %v must be of type %v, current type - %v", paramObj.Name, ParamTypeSecureString, paramObj.Type)
+	}
+	return paramObj.Value, nil
+}
+
+// callGetDecryptedParameters makes GetParameters API calls with decryption to the service
+func callGetDecryptedParameters(log log.T, paramNames []string) (*GetParametersResponse, error) {
+	finalResult := GetParametersResponse{}
+
+	ssmSvc := ssm.NewService()
+
+	for i := 0; i < len(paramNames); i = i + MaxParametersPerCall {
+		limit := i + MaxParametersPerCall
+		if limit > len(paramNames) {
+			limit = len(paramNames)
+		}
+
+		result, err := ssmSvc.GetDecryptedParameters(log, paramNames[i:limit])
+		if err != nil {
+			return nil, err
+		}
+
+		var response GetParametersResponse
+		if err = jsonutil.Remarshal(result, &response); err != nil {
+			log.Debug(err)
+			return nil, fmt.Errorf("%v", ErrorMsg)
+		}
+
+		finalResult.Parameters = append(finalResult.Parameters, response.Parameters...)
+		finalResult.InvalidParameters = append(finalResult.InvalidParameters, response.InvalidParameters...)
+	}
+
+	return &finalResult, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package parameterstore contains modules to resolve ssm parameters present in the document.
+package parameterstore
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubSecureParameterService(parameters []Parameter, invalid []string) *[]string {
+	var requested []string
+	callSecureParameterService = func(log log.T, paramNames []string) (*GetParametersResponse, error) {
+		requested = append(requested, paramNames...)
+		return &GetParametersResponse{Parameters: parameters, InvalidParameters: invalid}, nil
+	}
+	return &requested
+}
+
+func TestResolveSecureString(t *testing.T) {
+	requested := stubSecureParameterService([]Parameter{
+		{Name: "db/password", Type: ParamTypeSecureString, Value: "s3cr3t"},
+		{Name: "token", Type: ParamTypeSecureString, Value: "t0k3n"},
+	}, nil)
+	input := map[string]interface{}{
+		"commands": []interface{}{"connect --password {{ ssm-secure:db/password }}", "echo {{ssm:plain}}"},
+		"header":   "Bearer {{ssm-secure:token}}",
+		"again":    "{{ssm-secure:token}}",
+	}
+
+	resolved, values, err := ResolveSecureString(logger, input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"commands": []string{"connect --password s3cr3t", "echo {{ssm:plain}}"},
+		"header":   "Bearer t0k3n",
+		"again":    "t0k3n",
+	}, resolved)
+	assert.Len(t, values, 2)
+	assert.Contains(t, values, "s3cr3t")
+	assert.Contains(t, values, "t0k3n")
+	assert.Len(t, *requested, 2)
+	// the input is left untouched so the values are never saved with the document
+	assert.Equal(t, "Bearer {{ssm-secure:token}}", input["header"])
+}
+
+func TestResolveSecureString_NoReference(t *testing.T) {
+	requested := stubSecureParameterService(nil, nil)
+
+	resolved, values, err := ResolveSecureString(logger, "echo {{ssm:plain}}")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "echo {{ssm:plain}}", resolved)
+	assert.Empty(t, values)
+	assert.Empty(t, *requested)
+}
+
+func TestResolveSecureString_NotSecure(t *testing.T) {
+	stubSecureParameterService([]Parameter{{Name: "plain", Type: ParamTypeString, Value: "value"}}, nil)
+
+	_, _, err := ResolveSecureString(logger, "{{ssm-secure:plain}}")
+
+	assert.Error(t, err)
+}
+
+func TestResolveSecureString_InvalidParameter(t *testing.T) {
+	stubSecureParameterService(nil, []string{"missing"})
+
+	_, _, err := ResolveSecureString(logger, "{{ssm-secure:missing}}")
+
+	assert.Error(t, err)
+}
+
+func TestGetSecureString(t *testing.T) {
+	requested := stubSecureParameterService([]Parameter{{Name: "svc/password", Type: ParamTypeSecureString, Value: "s3cr3t"}}, nil)
+
+	value, err := GetSecureString(logger, "svc/password")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", value)
+	assert.Equal(t, []string{"svc/password"}, *requested)
+}
+
+func TestGetSecureString_NotSecure(t *testing.T) {
+	stubSecureParameterService([]Parameter{{Name: "svc/password", Type: ParamTypeString, Value: "plain"}}, nil)
+
+	_, err := GetSecureString(logger, "svc/password")
+
+	assert.Error(t, err)
+}
+
+func TestGetSecureString_InvalidParameter(t *testing.T) {
+	stubSecureParameterService(nil, []string{"svc/password"})
+
+	_, err := GetSecureString(logger, "svc/password")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform contains platform specific utilities.
+package platform
+
+import (
+	"os"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// EnvironmentEC2 is reported when the agent runs directly on an EC2 instance
+	EnvironmentEC2 = "EC2"
+	// EnvironmentOnPremises is reported when the agent runs directly on a managed instance
+	EnvironmentOnPremises = "OnPremises"
+	// EnvironmentDocker is reported when the agent runs in a container which is not managed by an orchestrator
+	EnvironmentDocker = "Docker"
+	// EnvironmentECS is reported when the agent runs in an ECS task on an EC2 container instance
+	EnvironmentECS = "ECS"
+	// EnvironmentEKS is reported when the agent runs in a Kubernetes pod, e.g. on EKS
+	EnvironmentEKS = "EKS"
+	// EnvironmentFargate is reported when the agent runs in an ECS task on Fargate
+	EnvironmentFargate = "Fargate"
+
+	executionEnvVariable      = "AWS_EXECUTION_ENV"
+	fargateExecutionEnv       = "AWS_ECS_FARGATE"
+	ecsMetadataVariable       = "ECS_CONTAINER_METADATA_URI"
+	ecsMetadataV4Variable     = "ECS_CONTAINER_METADATA_URI_V4"
+	kubernetesServiceVariable = "KUBERNETES_SERVICE_HOST"
+)
+
+var (
+	environmentOnce   sync.Once
+	cachedEnvironment string
+)
+
+// Makes the environment lookups variables, so that we can mock them for unit tests
+var getEnv = os.Getenv
+var isContainer = isRunningInContainer
+
+// Environment returns where the agent runs: on an EC2 instance, a managed instance or in a container.
+// The environment is detected once, it does not change while the agent runs.
+func Environment(log log.T) (environment string, err error) {
+	environmentOnce.Do(func() {
+		cachedEnvironment = detectEnvironment()
+		log.Debugf("detected environment %v", cachedEnvironment)
+	})
+	return cachedEnvironment, nil
+}
+
+// IsContainerized returns true if the agent runs in a container, where the agent cannot
+// update itself or reboot the host and must be managed through the container image instead.
+func IsContainerized(log log.T) bool {
+	environment, _ := Environment(log)
+	return isContainerEnvironment(environment)
+}
+
+func isContainerEnvironment(environment string) bool {
+	return environment != EnvironmentEC2 && environment != EnvironmentOnPremises
+}
+
+func detectEnvironment() string {
+	if getEnv(executionEnvVariable) == fargateExecutionEnv {
+		return EnvironmentFargate
+	}
+	if getEnv(ecsMetadataVariable) != "" || getEnv(ecsMetadataV4Variable) != "" {
+		return EnvironmentECS
+	}
+	if getEnv(kubernetesServiceVariable) != "" {
+		return EnvironmentEKS
+	}
+	if isContainer() {
+		return EnvironmentDocker
+	}
+	if managedInstance.InstanceID() != "" {
+		return EnvironmentOnPremises
+	}
+	return EnvironmentEC2
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package platform
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectEnvironment(t *testing.T) {
+	testCases := []struct {
+		name        string
+		env         map[string]string
+		container   bool
+		instanceID  string
+		environment string
+	}{
+		{"ec2", map[string]string{}, false, "", EnvironmentEC2},
+		{"managed instance", map[string]string{}, false, "mi-0123456789abcdef0", EnvironmentOnPremises},
+		{"docker", map[string]string{}, true, "", EnvironmentDocker},
+		{"ecs", map[string]string{ecsMetadataVariable: "http://169.254.170.2/v3/abc"}, true, "", EnvironmentECS},
+		{"ecs v4", map[string]string{ecsMetadataV4Variable: "http://169.254.170.2/v4/abc"}, true, "", EnvironmentECS},
+		{"fargate", map[string]string{executionEnvVariable: fargateExecutionEnv, ecsMetadataVariable: "http://169.254.170.2/v3/abc"}, true, "", EnvironmentFargate},
+		{"kubernetes", map[string]string{kubernetesServiceVariable: "10.100.0.1"}, true, "", EnvironmentEKS},
+	}
+	defer func() {
+		getEnv = os.Getenv
+		isContainer = isRunningInContainer
+		managedInstance = instanceInfo{}
+	}()
+
+	for _, test := range testCases {
+		env := test.env
+		container := test.container
+		getEnv = func(key string) string { return env[key] }
+		isContainer = func() bool { return container }
+		managedInstance = registrationStub{instanceID: test.instanceID}
+
+		environment := detectEnvironment()
+		assert.Equal(t, test.environment, environment, test.name)
+		assert.Equal(t, test.environment != EnvironmentEC2 && test.environment != EnvironmentOnPremises, isContainerEnvironment(environment), test.name)
+	}
+}
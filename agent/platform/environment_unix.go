@@ -0,0 +1,64 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build freebsd linux netbsd openbsd
+
+package platform
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+const (
+	dockerEnvFile    = "/.dockerenv"
+	containerEnvFile = "/run/.containerenv"
+	initCgroupFile   = "/proc/1/cgroup"
+)
+
+// containerCgroups are the cgroup paths of the init process set by the container runtimes
+var containerCgroups = []string{"docker", "kubepods", "containerd", "ecs", "lxc"}
+
+var readInitCgroup = func() ([]byte, error) {
+	return ioutil.ReadFile(initCgroupFile)
+}
+
+// isRunningInContainer checks the files created by docker and podman, then the cgroups of the init process.
+func isRunningInContainer() bool {
+	if fileutil.Exists(dockerEnvFile) || fileutil.Exists(containerEnvFile) {
+		return true
+	}
+	content, err := readInitCgroup()
+	if err != nil {
+		return false
+	}
+	return hasContainerCgroup(string(content))
+}
+
+func hasContainerCgroup(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		// each line is hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, cgroup := range containerCgroups {
+			if strings.Contains(parts[2], cgroup) {
+				return true
+			}
+		}
+	}
+	return false
+}
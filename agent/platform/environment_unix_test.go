@@ -0,0 +1,40 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build freebsd linux netbsd openbsd
+
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasContainerCgroup(t *testing.T) {
+	testCases := []struct {
+		content   string
+		container bool
+	}{
+		{"12:pids:/\n11:memory:/\n0::/init.scope\n", false},
+		{"12:pids:/docker/3f2b8e0a2c\n11:memory:/docker/3f2b8e0a2c\n", true},
+		{"11:memory:/kubepods/burstable/pod1234/3f2b8e0a2c\n", true},
+		{"9:cpu:/ecs/8e7c4b0f/3f2b8e0a2c\n", true},
+		{"0::/containerd/3f2b8e0a2c\n", true},
+		{"", false},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.container, hasContainerCgroup(test.content), test.content)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package platform
+
+import (
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	controlKeyPath     = `SYSTEM\CurrentControlSet\Control`
+	containerTypeValue = "ContainerType"
+)
+
+// isRunningInContainer checks the ContainerType value, which is only set in windows containers.
+func isRunningInContainer() bool {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, controlKeyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer key.Close()
+
+	_, _, err = key.GetIntegerValue(containerTypeValue)
+	return err == nil
+}
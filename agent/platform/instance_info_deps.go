@@ -15,8 +15,9 @@
 package platform
 
 import (
+	"sync"
+
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
@@ -38,9 +39,7 @@ func (instanceInfo) InstanceID() string { return registration.InstanceID() }
 func (instanceInfo) Region() string { return registration.Region() }
 
 // dependency for metadata
-var metadata metadataClient = instanceMetadata{
-	Client: ec2metadata.New(session.New(aws.NewConfig().WithMaxRetries(10).WithEC2MetadataDisableTimeoutOverride(false))),
-}
+var metadata metadataClient = &instanceMetadata{}
 
 type metadataClient interface {
 	GetMetadata(p string) (string, error)
@@ -48,16 +47,25 @@ type metadataClient interface {
 }
 
 type instanceMetadata struct {
-	Client *ec2metadata.EC2Metadata
+	once   sync.Once
+	client *ec2metadata.EC2Metadata
+}
+
+// sdkClient creates the sdk client on first use, with the metadata endpoint selected in appconfig
+func (c *instanceMetadata) sdkClient() *ec2metadata.EC2Metadata {
+	c.once.Do(func() {
+		c.client = ec2metadata.New(session.New(Ec2MetadataConfig().WithMaxRetries(10).WithEC2MetadataDisableTimeoutOverride(false)))
+	})
+	return c.client
 }
 
 // GetMetadata uses the path provided to request
-func (c instanceMetadata) GetMetadata(p string) (string, error) {
-	return c.Client.GetMetadata(p)
+func (c *instanceMetadata) GetMetadata(p string) (string, error) {
+	return c.sdkClient().GetMetadata(p)
 }
 
 // Region returns the region the instance is running in.
-func (c instanceMetadata) Region() (string, error) { return c.Client.Region() }
+func (c *instanceMetadata) Region() (string, error) { return c.sdkClient().Region() }
 
 // dependency for metadata
 var dynamicData dynamicDataClient = instanceDynamicData{
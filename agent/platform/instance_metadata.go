@@ -18,11 +18,14 @@ import (
 	"io/ioutil"
 	"net/http"
 	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws"
 )
 
 const (
-	// EC2MetadataServiceURL is url for instance metadata.
-	EC2MetadataServiceURL = "http://169.254.169.254"
+	// EC2MetadataServiceURL is the IPv4 url for instance metadata, the url used is selected in appconfig.
+	EC2MetadataServiceURL = appconfig.Ec2MetadataEndpointIPv4
 	// SecurityCredentialsResource provides iam credentials
 	SecurityCredentialsResource = "/latest/meta-data/iam/security-credentials/"
 	// InstanceIdentityDocumentResource provides instance information like instance id, region, availability
@@ -35,6 +38,17 @@ const (
 	EC2MetadataRequestTimeout = time.Duration(2 * time.Second)
 )
 
+// ec2MetadataEndpoint returns the url of the instance metadata service selected in appconfig
+var ec2MetadataEndpoint = func() string {
+	config, _ := appconfig.Config(false)
+	return appconfig.GetEc2MetadataEndpoint(config)
+}
+
+// Ec2MetadataConfig returns the aws config of the sdk instance metadata clients, with the endpoint selected in appconfig
+func Ec2MetadataConfig() *aws.Config {
+	return aws.NewConfig().WithEndpoint(ec2MetadataEndpoint() + "/latest")
+}
+
 // InstanceIdentityDocument stores the values fetched from querying instance metadata
 /* Sample Result
 {
@@ -115,7 +129,7 @@ func (c EC2MetadataClient) InstanceIdentityDocument() (*InstanceIdentityDocument
 }
 
 func (c EC2MetadataClient) resourceServiceURL(path string) string {
-	return EC2MetadataServiceURL + path
+	return ec2MetadataEndpoint() + path
 }
 
 // ReadResource reads from the url path
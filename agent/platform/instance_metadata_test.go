@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -77,6 +78,14 @@ func TestInstanceIdentityDocument(t *testing.T) {
 	assert.Equal(t, &expectediid, iid)
 }
 
+func TestEc2MetadataEndpoint(t *testing.T) {
+	defer func(original func() string) { ec2MetadataEndpoint = original }(ec2MetadataEndpoint)
+	ec2MetadataEndpoint = func() string { return appconfig.Ec2MetadataEndpointIPv6 }
+
+	assert.Equal(t, "http://[fd00:ec2::254]/latest/dynamic/instance-identity/document", testClient.resourceServiceURL(InstanceIdentityDocumentResource))
+	assert.Equal(t, "http://[fd00:ec2::254]/latest", *Ec2MetadataConfig().Endpoint)
+}
+
 // TestPendingTime tests the parsing/formatting of the pending time field.
 func TestPendingTime(t *testing.T) {
 	mst := time.FixedZone("MST", -7*3600) // seven hours west of UTC
@@ -33,7 +33,7 @@ func PlatformName(log log.T) (name string, err error) {
 	return getPlatformName(log)
 }
 
-// PlatformType gets the OS specific platform type, valid values are windows and linux.
+// PlatformType gets the OS specific platform type, valid values are windows, linux and macos.
 func PlatformType(log log.T) (name string, err error) {
 	return getPlatformType(log)
 }
@@ -86,7 +86,8 @@ func IP() (ip string, err error) {
 		}
 	}
 
-	// search for IPv6
+	// search for IPv6, the link-local addresses are only used when there is no other address
+	var linkLocalIP net.IP
 	for _, i := range interfaces {
 		var addrs []net.Addr
 		if addrs, err = i.Addrs(); err != nil {
@@ -100,11 +101,20 @@ func IP() (ip string, err error) {
 				foundIP = v.IP.To16()
 			}
 
+			if foundIP != nil && foundIP.IsLinkLocalUnicast() {
+				if linkLocalIP == nil {
+					linkLocalIP = foundIP
+				}
+				continue
+			}
 			if foundIP != nil {
 				return foundIP.String(), nil
 			}
 		}
 	}
+	if linkLocalIP != nil {
+		return linkLocalIP.String(), nil
+	}
 
 	return "", fmt.Errorf("No IP addresses found.")
 }
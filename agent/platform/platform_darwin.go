@@ -17,6 +17,7 @@
 package platform
 
 import (
+	"os"
 	"os/exec"
 	"strings"
 
@@ -26,6 +27,7 @@ import (
 const (
 	platformDetailsCommand = "sw_vers"
 	errorOccurredMessage   = "There was an error running %v, err: %v"
+	hostNameCommand        = "/bin/hostname"
 )
 
 func getPlatformName(log log.T) (value string, err error) {
@@ -35,7 +37,7 @@ func getPlatformName(log log.T) (value string, err error) {
 }
 
 func getPlatformType(log log.T) (value string, err error) {
-	return "linux", nil
+	return "macos", nil
 }
 
 func getPlatformVersion(log log.T) (value string, err error) {
@@ -64,8 +66,21 @@ func getPlatformDetail(log log.T, param string) (value string, err error) {
 
 // fullyQualifiedDomainName returns the Fully Qualified Domain Name of the instance, otherwise the hostname
 func fullyQualifiedDomainName() string {
-	//todo: throw an error / panic here - since darwin is not supported yet.
-	return ""
+	var hostName string
+	var err error
+
+	if hostName, err = os.Hostname(); err != nil {
+		return ""
+	}
+
+	// the BSD hostname of macOS has no long options
+	if contentBytes, err := exec.Command(hostNameCommand, "-f").Output(); err == nil {
+		if fqdn := strings.TrimSpace(string(contentBytes)); fqdn != "" {
+			return fqdn
+		}
+	}
+
+	return strings.TrimSpace(hostName)
 }
 
 func isPlatformNanoServer(log log.T) (bool, error) {
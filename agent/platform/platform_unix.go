@@ -32,6 +32,8 @@ const (
 	osReleaseFile          = "/etc/os-release"
 	systemReleaseFile      = "/etc/system-release"
 	redhatReleaseFile      = "/etc/redhat-release"
+	alpineReleaseFile      = "/etc/alpine-release"
+	alpinePlatformName     = "Alpine Linux"
 	unameCommand           = "/usr/bin/uname"
 	lsbReleaseCommand      = "lsb_release"
 	fetchingDetailsMessage = "fetching platform details from %v"
@@ -132,6 +134,19 @@ func getPlatformDetails(log log.T) (name string, version string, err error) {
 			versionData := strings.Split(data[1], "(")
 			version = strings.TrimSpace(versionData[0])
 		}
+	} else if fileutil.Exists(alpineReleaseFile) {
+		// minimal alpine images may not have the os-release file, the release file only contains the version
+		log.Debugf(fetchingDetailsMessage, alpineReleaseFile)
+
+		contents, err = fileutil.ReadAllText(alpineReleaseFile)
+		log.Debugf(commandOutputMessage, contents)
+
+		if err != nil {
+			log.Debugf(errorOccurredMessage, alpineReleaseFile, err)
+			return
+		}
+		name = alpinePlatformName
+		version = strings.TrimSpace(contents)
 	} else if runtime.GOOS == "freebsd" {
 		log.Debugf(fetchingDetailsMessage, unameCommand)
 
@@ -185,11 +200,15 @@ func fullyQualifiedDomainName() string {
 	}
 
 	var contentBytes []byte
-	if contentBytes, err = exec.Command(hostNameCommand, "--fqdn").Output(); err == nil {
-		fqdn = string(contentBytes)
-		//trim whitespaces - since by default above command appends '\n' at the end.
-		//e.g: 'ip-172-31-7-113.ec2.internal\n'
-		fqdn = strings.TrimSpace(fqdn)
+	// busybox hostname, used on alpine, only supports the short option
+	for _, fqdnOption := range []string{"--fqdn", "-f"} {
+		if contentBytes, err = exec.Command(hostNameCommand, fqdnOption).Output(); err == nil {
+			fqdn = string(contentBytes)
+			//trim whitespaces - since by default above command appends '\n' at the end.
+			//e.g: 'ip-172-31-7-113.ec2.internal\n'
+			fqdn = strings.TrimSpace(fqdn)
+			break
+		}
 	}
 
 	if fqdn != "" {
@@ -0,0 +1,118 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package pluginmetrics aggregates the plugin execution metrics published to CloudWatch.
+package pluginmetrics
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// Names and units of the metrics recorded for each plugin
+const (
+	MetricDuration      = "Duration"
+	MetricSuccessCount  = "SuccessCount"
+	MetricFailureCount  = "FailureCount"
+	MetricDownloadBytes = "DownloadBytes"
+
+	UnitSeconds = "Seconds"
+	UnitCount   = "Count"
+	UnitBytes   = "Bytes"
+)
+
+// Key identifies a metric of a plugin
+type Key struct {
+	PluginName string
+	MetricName string
+}
+
+// Unit returns the CloudWatch unit of the metric
+func (k Key) Unit() string {
+	switch k.MetricName {
+	case MetricDuration:
+		return UnitSeconds
+	case MetricDownloadBytes:
+		return UnitBytes
+	}
+	return UnitCount
+}
+
+// Statistics aggregates the values recorded for a metric
+type Statistics struct {
+	SampleCount float64
+	Sum         float64
+	Minimum     float64
+	Maximum     float64
+}
+
+// add records a value in the statistics
+func (s *Statistics) add(value float64) {
+	if s.SampleCount == 0 || value < s.Minimum {
+		s.Minimum = value
+	}
+	if s.SampleCount == 0 || value > s.Maximum {
+		s.Maximum = value
+	}
+	s.SampleCount++
+	s.Sum += value
+}
+
+var (
+	recordedLock sync.Mutex
+	recorded     = make(map[Key]*Statistics)
+)
+
+// RecordPluginResult records the duration, the outcome and the bytes downloaded of a completed plugin.
+func RecordPluginResult(result contracts.PluginResult) {
+	recordedLock.Lock()
+	defer recordedLock.Unlock()
+
+	if !result.StartDateTime.IsZero() && result.EndDateTime.After(result.StartDateTime) {
+		record(result.PluginName, MetricDuration, result.EndDateTime.Sub(result.StartDateTime).Seconds())
+	}
+	switch result.Status {
+	case contracts.ResultStatusSuccess, contracts.ResultStatusSuccessAndReboot, contracts.ResultStatusPassedAndReboot:
+		record(result.PluginName, MetricSuccessCount, 1)
+		record(result.PluginName, MetricFailureCount, 0)
+	case contracts.ResultStatusFailed, contracts.ResultStatusTimedOut:
+		record(result.PluginName, MetricSuccessCount, 0)
+		record(result.PluginName, MetricFailureCount, 1)
+	}
+	record(result.PluginName, MetricDownloadBytes, float64(result.DownloadedBytes))
+}
+
+// record adds a value to a metric, the lock must be held by the caller
+func record(pluginName string, metricName string, value float64) {
+	key := Key{PluginName: pluginName, MetricName: metricName}
+	statistics, found := recorded[key]
+	if !found {
+		statistics = &Statistics{}
+		recorded[key] = statistics
+	}
+	statistics.add(value)
+}
+
+// Collect returns the metrics recorded since the last call.
+func Collect() map[Key]Statistics {
+	recordedLock.Lock()
+	defer recordedLock.Unlock()
+
+	collected := make(map[Key]Statistics, len(recorded))
+	for key, statistics := range recorded {
+		collected[key] = *statistics
+	}
+	recorded = make(map[Key]*Statistics)
+	return collected
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pluginmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordPluginResult(t *testing.T) {
+	Collect()
+	start := time.Now()
+	RecordPluginResult(contracts.PluginResult{
+		PluginName:      "aws:runShellScript",
+		Status:          contracts.ResultStatusSuccess,
+		StartDateTime:   start,
+		EndDateTime:     start.Add(2 * time.Second),
+		DownloadedBytes: 100,
+	})
+	RecordPluginResult(contracts.PluginResult{
+		PluginName:    "aws:runShellScript",
+		Status:        contracts.ResultStatusFailed,
+		StartDateTime: start,
+		EndDateTime:   start.Add(4 * time.Second),
+	})
+
+	collected := Collect()
+
+	duration := collected[Key{PluginName: "aws:runShellScript", MetricName: MetricDuration}]
+	assert.Equal(t, Statistics{SampleCount: 2, Sum: 6, Minimum: 2, Maximum: 4}, duration)
+	assert.Equal(t, float64(1), collected[Key{PluginName: "aws:runShellScript", MetricName: MetricSuccessCount}].Sum)
+	assert.Equal(t, float64(1), collected[Key{PluginName: "aws:runShellScript", MetricName: MetricFailureCount}].Sum)
+	assert.Equal(t, float64(100), collected[Key{PluginName: "aws:runShellScript", MetricName: MetricDownloadBytes}].Sum)
+	assert.Empty(t, Collect())
+}
+
+func TestRecordPluginResultSkipped(t *testing.T) {
+	Collect()
+	RecordPluginResult(contracts.PluginResult{
+		PluginName: "aws:runPowerShellScript",
+		Status:     contracts.ResultStatusSkipped,
+	})
+
+	collected := Collect()
+
+	assert.Equal(t, 1, len(collected))
+	assert.Equal(t, UnitBytes, Key{MetricName: MetricDownloadBytes}.Unit())
+}
+
+func TestRecordPluginResultReboot(t *testing.T) {
+	Collect()
+	RecordPluginResult(contracts.PluginResult{
+		PluginName: "aws:applications",
+		Status:     contracts.ResultStatusPassedAndReboot,
+	})
+	RecordPluginResult(contracts.PluginResult{
+		PluginName: "aws:applications",
+		Status:     contracts.ResultStatusSuccessAndReboot,
+	})
+
+	collected := Collect()
+
+	assert.Equal(t, float64(2), collected[Key{PluginName: "aws:applications", MetricName: MetricSuccessCount}].Sum)
+	assert.Equal(t, float64(0), collected[Key{PluginName: "aws:applications", MetricName: MetricFailureCount}].Sum)
+}
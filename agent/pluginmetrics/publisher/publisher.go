@@ -0,0 +1,156 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package publisher implements the core module which publishes the plugin execution metrics to CloudWatch.
+package publisher
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cloudwatchmetrics"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/pluginmetrics"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+const name = "PluginMetricsPublisher"
+
+// dimensionPluginName is the dimension holding the name of the plugin the metric is about
+const dimensionPluginName = "PluginName"
+
+// assign methods to variables to allow the unit tests to override them
+var (
+	newMetricsService = func() cloudwatchmetrics.Service {
+		return cloudwatch.New(session.New(sdkutil.AwsConfig()))
+	}
+	collect = pluginmetrics.Collect
+)
+
+// Publisher periodically publishes the metrics aggregated since the previous publication
+type Publisher struct {
+	context  context.T
+	service  cloudwatchmetrics.Service
+	stopChan chan bool
+	stopped  chan bool
+}
+
+// NewPublisher creates a new plugin metrics publisher core module.
+func NewPublisher(context context.T) *Publisher {
+	return &Publisher{
+		context: context.With("[" + name + "]"),
+	}
+}
+
+// run publishes the metrics at every interval until the publisher is stopped
+func (p *Publisher) run(interval time.Duration) {
+	defer close(p.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.publish()
+		case <-p.stopChan:
+			p.publish()
+			return
+		}
+	}
+}
+
+// publish sends the metrics collected since the last publication, in batches which respect the limits of PutMetricData
+func (p *Publisher) publish() {
+	log := p.context.Log()
+	data := metricData(collect())
+	namespace := p.context.AppConfig().Metrics.Namespace
+	if unpublished, err := cloudwatchmetrics.Publish(p.service, namespace, data); err != nil {
+		// the metrics are dropped rather than piled up while CloudWatch can't be reached
+		log.Warnf("unable to publish %v plugin metrics to CloudWatch namespace %v: %v", unpublished, namespace, err)
+		return
+	}
+	if len(data) > 0 {
+		log.Debugf("published %v plugin metrics", len(data))
+	}
+}
+
+// metricData converts the aggregated metrics to CloudWatch statistic sets, ordered by plugin and metric name
+func metricData(collected map[pluginmetrics.Key]pluginmetrics.Statistics) []*cloudwatch.MetricDatum {
+	keys := make([]pluginmetrics.Key, 0, len(collected))
+	for key := range collected {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].PluginName != keys[j].PluginName {
+			return keys[i].PluginName < keys[j].PluginName
+		}
+		return keys[i].MetricName < keys[j].MetricName
+	})
+
+	timestamp := time.Now()
+	data := make([]*cloudwatch.MetricDatum, 0, len(keys))
+	for _, key := range keys {
+		statistics := collected[key]
+		data = append(data, &cloudwatch.MetricDatum{
+			MetricName: aws.String(key.MetricName),
+			Unit:       aws.String(key.Unit()),
+			Timestamp:  aws.Time(timestamp),
+			Dimensions: []*cloudwatch.Dimension{
+				{Name: aws.String(dimensionPluginName), Value: aws.String(key.PluginName)},
+			},
+			StatisticValues: &cloudwatch.StatisticSet{
+				SampleCount: aws.Float64(statistics.SampleCount),
+				Sum:         aws.Float64(statistics.Sum),
+				Minimum:     aws.Float64(statistics.Minimum),
+				Maximum:     aws.Float64(statistics.Maximum),
+			},
+		})
+	}
+	return data
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the module name
+func (p *Publisher) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts publishing the plugin metrics if they are enabled
+func (p *Publisher) ModuleExecute(context context.T) (err error) {
+	config := p.context.AppConfig().Metrics
+	if !config.Enabled {
+		p.context.Log().Debug("plugin metrics are disabled.")
+		return nil
+	}
+	p.context.Log().Infof("publishing plugin metrics to CloudWatch namespace %v every %d seconds.", config.Namespace, config.PublishIntervalSeconds)
+	p.service = newMetricsService()
+	p.stopChan = make(chan bool)
+	p.stopped = make(chan bool)
+	go p.run(time.Duration(config.PublishIntervalSeconds) * time.Second)
+	return nil
+}
+
+// ModuleRequestStop publishes the remaining metrics and stops the publisher
+func (p *Publisher) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if p.stopChan != nil {
+		p.context.Log().Info("stopping plugin metrics publisher.")
+		close(p.stopChan)
+		<-p.stopped
+		p.stopChan = nil
+	}
+	return nil
+}
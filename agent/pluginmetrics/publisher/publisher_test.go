@@ -0,0 +1,94 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package publisher
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/cloudwatchmetrics"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/pluginmetrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type metricsServiceMock struct {
+	mock.Mock
+}
+
+func (m *metricsServiceMock) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	args := m.Called(input)
+	return &cloudwatch.PutMetricDataOutput{}, args.Error(0)
+}
+
+func TestMetricData(t *testing.T) {
+	data := metricData(map[pluginmetrics.Key]pluginmetrics.Statistics{
+		{PluginName: "aws:runShellScript", MetricName: pluginmetrics.MetricFailureCount}: {SampleCount: 2, Sum: 1, Maximum: 1},
+		{PluginName: "aws:runShellScript", MetricName: pluginmetrics.MetricDuration}:     {SampleCount: 2, Sum: 6, Minimum: 2, Maximum: 4},
+	})
+
+	assert.Equal(t, 2, len(data))
+	assert.Equal(t, pluginmetrics.MetricDuration, aws.StringValue(data[0].MetricName))
+	assert.Equal(t, pluginmetrics.UnitSeconds, aws.StringValue(data[0].Unit))
+	assert.Equal(t, float64(6), aws.Float64Value(data[0].StatisticValues.Sum))
+	assert.Equal(t, "aws:runShellScript", aws.StringValue(data[0].Dimensions[0].Value))
+	assert.Equal(t, pluginmetrics.MetricFailureCount, aws.StringValue(data[1].MetricName))
+	assert.Equal(t, pluginmetrics.UnitCount, aws.StringValue(data[1].Unit))
+}
+
+func TestPublishInBatches(t *testing.T) {
+	collected := make(map[pluginmetrics.Key]pluginmetrics.Statistics)
+	for i := 0; i < 25; i++ {
+		collected[pluginmetrics.Key{PluginName: fmt.Sprintf("plugin%02d", i), MetricName: pluginmetrics.MetricSuccessCount}] = pluginmetrics.Statistics{SampleCount: 1, Sum: 1, Minimum: 1, Maximum: 1}
+	}
+	collect = func() map[pluginmetrics.Key]pluginmetrics.Statistics { return collected }
+	defer func() { collect = pluginmetrics.Collect }()
+	service := &metricsServiceMock{}
+	service.On("PutMetricData", mock.AnythingOfType("*cloudwatch.PutMetricDataInput")).Return(nil)
+	publisher := &Publisher{context: context.NewMockDefault(), service: service}
+
+	publisher.publish()
+
+	service.AssertNumberOfCalls(t, "PutMetricData", 2)
+	assert.Equal(t, cloudwatchmetrics.MaxMetricDataPerPut, len(service.Calls[0].Arguments.Get(0).(*cloudwatch.PutMetricDataInput).MetricData))
+	assert.Equal(t, 5, len(service.Calls[1].Arguments.Get(0).(*cloudwatch.PutMetricDataInput).MetricData))
+}
+
+func TestPublishStopsOnError(t *testing.T) {
+	collect = func() map[pluginmetrics.Key]pluginmetrics.Statistics {
+		return map[pluginmetrics.Key]pluginmetrics.Statistics{
+			{PluginName: "aws:runShellScript", MetricName: pluginmetrics.MetricSuccessCount}: {SampleCount: 1, Sum: 1},
+		}
+	}
+	defer func() { collect = pluginmetrics.Collect }()
+	service := &metricsServiceMock{}
+	service.On("PutMetricData", mock.AnythingOfType("*cloudwatch.PutMetricDataInput")).Return(fmt.Errorf("throttled"))
+	publisher := &Publisher{context: context.NewMockDefault(), service: service}
+
+	publisher.publish()
+
+	service.AssertNumberOfCalls(t, "PutMetricData", 1)
+}
+
+func TestModuleExecuteDisabled(t *testing.T) {
+	publisher := NewPublisher(context.NewMockDefault())
+
+	assert.Nil(t, publisher.ModuleExecute(context.NewMockDefault()))
+	assert.Nil(t, publisher.stopChan)
+	assert.Nil(t, publisher.ModuleRequestStop(contracts.StopTypeSoftStop))
+}
@@ -1,4 +1,4 @@
-// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License"). You may not
 // use this file except in compliance with the License. A copy of the
@@ -12,21 +12,17 @@
 // permissions and limitations under the License.
 
 // Package application implements the application plugin.
-//
-// +build windows
-
 package application
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
-	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -35,16 +31,18 @@ import (
 )
 
 const (
-	// defaultApplicationExecutionTimeoutInSeconds represents default timeout time for execution of applications in seconds
-	defaultApplicationExecutionTimeoutInSeconds = 3600
+	INSTALL = "Install"
 
+	UNINSTALL = "Uninstall"
+
+	REPAIR = "Repair"
+)
+
+const (
 	// defaultWorkingDirectory represents the default working directory
 	defaultWorkingDirectory = ""
 )
 
-// msiExecCommand is the command for installing msi applications
-var msiExecCommand = filepath.Join(os.Getenv("SystemRoot"), "System32", "msiexec.exe")
-
 // Plugin is the type for the applications plugin.
 type Plugin struct {
 	// ExecuteCommand is an object that can execute commands.
@@ -60,6 +58,18 @@ type ApplicationPluginInput struct {
 	Source         string
 	SourceHash     string
 	SourceHashType string
+	// RunAsUser is the local or domain account running msiexec, group managed service accounts are named DOMAIN\name$
+	RunAsUser string
+	// RunAsPasswordParameter is the name of the SecureString parameter holding the password of RunAsUser
+	RunAsPasswordParameter string
+	// GpgKeySource is the url or s3 path of the public key verifying the signature of a rpm or deb package
+	GpgKeySource string
+	// SignatureSource is the url or s3 path of the detached signature of the package, rpm packages are signed by default
+	SignatureSource string
+	// TimeoutSeconds is the execution timeout of the installer, capped by the timeouts configured for the plugin
+	TimeoutSeconds interface{}
+	// ResourceLimits caps the cpu shares, memory and number of processes of the installer, within the configured limits
+	ResourceLimits pluginutil.ResourceLimitsInput
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -103,62 +113,20 @@ func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput
 	p.runCommands(log, pluginID, pluginInput, orchestrationDirectory, defaultWorkingDirectory, cancelFlag, output)
 }
 
-// runCommands executes one set of commands and returns their output.
-func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput ApplicationPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
-	var err error
-
-	// TODO:MF: This subdirectory is only needed because we could be running multiple sets of properties for the same plugin - otherwise the orchestration directory would already be unique
-	orchestrationDir := fileutil.BuildPath(orchestrationDirectory, pluginInput.ID)
-	log.Debugf("OrchestrationDir %v ", orchestrationDir)
-
-	// create orchestration dir if needed
-	if err = fileutil.MakeDirs(orchestrationDir); err != nil {
-		log.Debug("failed to create orchestrationDir directory", orchestrationDir, err)
-		output.MarkAsFailed(err)
-		return
-	}
-
-	// Get application mode
-	mode, err := getMsiApplicationMode(log, pluginInput)
+// copyFile copies the content of the source file to the destination file.
+func copyFile(sourcePath string, destinationPath string) error {
+	src, err := os.Open(sourcePath)
 	if err != nil {
-		output.MarkAsFailed(err)
-		return
+		return err
 	}
-	log.Debugf("mode is %v", mode)
-
-	var localFilePath string
-	// Download file from source if available
-	downloadOutput, err := pluginutil.DownloadFileFromSource(log, pluginInput.Source, pluginInput.SourceHash, pluginInput.SourceHashType)
-	if err != nil || downloadOutput.IsHashMatched == false || downloadOutput.LocalFilePath == "" {
-		errorString := fmt.Errorf("failed to download file reliably %v", pluginInput.Source)
-		output.MarkAsFailed(errorString)
-		return
-	}
-	localFilePath = downloadOutput.LocalFilePath
-	log.Debugf("local path to file is %v", localFilePath)
-
-	// Create msi related log file
-	localSourceLogFilePath := localFilePath + ".msiexec.log.txt"
-	log.Debugf("log path is %v", localSourceLogFilePath)
-
-	// Construct Command Name and Arguments
-	commandName := msiExecCommand
-	commandArguments := []string{mode, localFilePath, "/quiet", "/norestart", "/log", localSourceLogFilePath}
-	if pluginInput.Parameters != "" {
-		log.Debugf("Got Parameters \"%v\"", pluginInput.Parameters)
-		params := processParams(log, pluginInput.Parameters)
-		commandArguments = append(commandArguments, params...)
-	}
-
-	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, defaultWorkingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, defaultApplicationExecutionTimeoutInSeconds, commandName, commandArguments)
-
-	// Set output status
-	output.SetExitCode(exitCode)
-	setMsiExecStatus(log, pluginInput, cancelFlag, output)
-
+	defer src.Close()
+	dst, err := os.Create(destinationPath)
 	if err != nil {
-		output.MarkAsFailed(fmt.Errorf("failed to run commands: %v", err))
-		return
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
 	}
+	return dst.Close()
 }
@@ -0,0 +1,425 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package application
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	packageTypeRpm = ".rpm"
+	packageTypeDeb = ".deb"
+
+	// rebootRequiredFile is created on debian based distributions by packages requiring a reboot
+	rebootRequiredFile = "/var/run/reboot-required"
+)
+
+// packageManager describes the command line of a package manager.
+type packageManager struct {
+	// name is the command of the package manager
+	name string
+	// args returns the arguments running the action, target is the package file or the package name for uninstall
+	args func(action string, target string) []string
+	// describeExitCode returns the description of a failed exit code
+	describeExitCode func(exitCode int) string
+	// rebootExitCodes are the exit codes of a successful action requiring a reboot
+	rebootExitCodes []int
+	// successExitCodes are the non zero exit codes of a successful action
+	successExitCodes []int
+}
+
+// packageManagers lists the supported package managers of each package type in order of preference,
+// the high level ones resolve the dependencies of the package.
+var packageManagers = map[string][]packageManager{
+	packageTypeRpm: {
+		{name: "dnf", args: yumArgs, describeExitCode: describeYumExitCode},
+		{name: "yum", args: yumArgs, describeExitCode: describeYumExitCode},
+		{name: "zypper", args: zypperArgs, describeExitCode: describeZypperExitCode, rebootExitCodes: []int{102}, successExitCodes: []int{103}},
+		{name: "rpm", args: rpmArgs, describeExitCode: describeRpmExitCode},
+	},
+	packageTypeDeb: {
+		{name: "apt-get", args: aptArgs, describeExitCode: describeAptExitCode},
+		{name: "dpkg", args: dpkgArgs, describeExitCode: describeDpkgExitCode},
+	},
+}
+
+// Makes command as variables, so that we can mock this for unit tests
+var lookPath = exec.LookPath
+var runCommand = func(log log.T, name string, args ...string) (string, error) {
+	log.Debugf("Running %v %v", name, args)
+	output, err := exec.Command(name, args...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+var downloadFile = pluginutil.DownloadFileFromSource
+
+// runCommands installs, repairs or uninstalls the rpm or deb package with the package manager of the instance.
+func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput ApplicationPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	var err error
+
+	if pluginInput.RunAsUser != "" {
+		output.MarkAsFailed(fmt.Errorf("runAsUser is not supported for the installation of packages on this platform"))
+		return
+	}
+	switch pluginInput.Action {
+	case INSTALL, UNINSTALL, REPAIR:
+	default:
+		output.MarkAsFailed(fmt.Errorf("Action is set to unsupported value: %v", pluginInput.Action))
+		return
+	}
+	resourceLimits, err := pluginutil.GetResourceLimits(log, Name(), pluginInput.ResourceLimits)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	packageType, err := getPackageType(pluginInput.Source)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	manager, err := findPackageManager(packageType)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	// TODO:MF: This subdirectory is only needed because we could be running multiple sets of properties for the same plugin - otherwise the orchestration directory would already be unique
+	orchestrationDir := fileutil.BuildPath(orchestrationDirectory, pluginInput.ID)
+	log.Debugf("OrchestrationDir %v ", orchestrationDir)
+	if err = fileutil.MakeDirs(orchestrationDir); err != nil {
+		log.Debug("failed to create orchestrationDir directory", orchestrationDir, err)
+		output.MarkAsFailed(err)
+		return
+	}
+
+	downloadOutput, err := downloadFile(log, pluginInput.Source, pluginInput.SourceHash, pluginInput.SourceHashType)
+	if err != nil || downloadOutput.IsHashMatched == false || downloadOutput.LocalFilePath == "" {
+		output.MarkAsFailed(fmt.Errorf("failed to download file reliably %v", pluginInput.Source))
+		return
+	}
+
+	// package managers recognize local packages by their extension, which the downloaded file does not have
+	packagePath := filepath.Join(orchestrationDir, "package"+packageType)
+	if err = copyFile(downloadOutput.LocalFilePath, packagePath); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to copy the package %v: %v", pluginInput.Source, err))
+		return
+	}
+
+	if pluginInput.GpgKeySource != "" {
+		if err = verifySignature(log, pluginInput, packageType, packagePath, orchestrationDir); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		output.AppendInfof("Verified the signature of %v.", pluginInput.Source)
+	}
+
+	name, version, err := getPackageInfo(log, packageType, packagePath)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to read the package %v: %v", pluginInput.Source, err))
+		return
+	}
+	installedVersion := getInstalledVersion(log, packageType, name)
+
+	// keep the action idempotent like msiexec
+	target := packagePath
+	switch pluginInput.Action {
+	case INSTALL:
+		if installedVersion == version {
+			output.AppendInfof("Package %v %v is already installed.", name, version)
+			output.MarkAsSucceeded()
+			return
+		}
+	case UNINSTALL:
+		if installedVersion == "" {
+			output.AppendInfof("Package %v is not installed.", name)
+			output.MarkAsSucceeded()
+			return
+		}
+		target = name
+	}
+
+	commandArguments := append(manager.args(pluginInput.Action, target), strings.Fields(pluginInput.Parameters)...)
+	options := executers.ExecuteOptions{
+		Environment:    map[string]string{"DEBIAN_FRONTEND": "noninteractive"},
+		ResourceLimits: resourceLimits,
+	}
+	rebootRequired := fileutil.Exists(rebootRequiredFile)
+	executionTimeout := pluginutil.ValidatePluginExecutionTimeout(log, Name(), pluginInput.TimeoutSeconds)
+
+	exitCode, err := p.CommandExecuter.NewExecuteWithOptions(log, defaultWorkingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, manager.name, commandArguments, options)
+
+	output.SetExitCode(exitCode)
+	setPackageManagerStatus(log, pluginInput, manager, cancelFlag, output)
+	if output.GetStatus() == contracts.ResultStatusSuccess && !rebootRequired && fileutil.Exists(rebootRequiredFile) {
+		output.AppendInfof("Package %v requires a reboot.", name)
+		output.SetStatus(contracts.ResultStatusPassedAndReboot)
+	}
+	if err != nil && output.GetStatus() == contracts.ResultStatusFailed {
+		output.MarkAsFailed(fmt.Errorf("failed to run %v: %v", manager.name, err))
+	}
+}
+
+// getPackageType returns the type of the package from the extension of its source.
+func getPackageType(source string) (string, error) {
+	sourcePath := source
+	if sourceURL, err := url.Parse(source); err == nil && sourceURL.Path != "" {
+		sourcePath = sourceURL.Path
+	}
+	packageType := strings.ToLower(path.Ext(sourcePath))
+	if _, ok := packageManagers[packageType]; !ok {
+		return "", fmt.Errorf("source %v must be a %v or %v package", source, packageTypeRpm, packageTypeDeb)
+	}
+	return packageType, nil
+}
+
+// findPackageManager returns the first package manager of the instance supporting the package type.
+func findPackageManager(packageType string) (packageManager, error) {
+	for _, manager := range packageManagers[packageType] {
+		if _, err := lookPath(manager.name); err == nil {
+			return manager, nil
+		}
+	}
+	return packageManager{}, fmt.Errorf("no package manager supporting %v packages is installed", packageType)
+}
+
+// getPackageInfo returns the name and the version of the package file.
+func getPackageInfo(log log.T, packageType string, packagePath string) (name string, version string, err error) {
+	var output string
+	if packageType == packageTypeRpm {
+		output, err = runCommand(log, "rpm", "-qp", "--queryformat", "%{NAME} %{VERSION}-%{RELEASE}", packagePath)
+	} else {
+		output, err = runCommand(log, "dpkg-deb", "--showformat=${Package} ${Version}", "--show", packagePath)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("%v %v", err, output)
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("unexpected package information %v", output)
+	}
+	return fields[0], fields[1], nil
+}
+
+// getInstalledVersion returns the installed version of the package, or an empty string when it is not installed.
+func getInstalledVersion(log log.T, packageType string, name string) string {
+	if packageType == packageTypeRpm {
+		output, err := runCommand(log, "rpm", "-q", "--queryformat", "%{VERSION}-%{RELEASE}", name)
+		if err != nil {
+			return ""
+		}
+		return output
+	}
+	output, err := runCommand(log, "dpkg-query", "--showformat=${Status} ${Version}", "--show", name)
+	if err != nil || !strings.HasPrefix(output, "install ok installed ") {
+		return ""
+	}
+	return strings.TrimPrefix(output, "install ok installed ")
+}
+
+// verifySignature verifies the detached signature of the package, or the signature of a rpm package, with the given key.
+// The key is imported in a temporary keyring, so it is not trusted by the package manager of the instance.
+func verifySignature(log log.T, pluginInput ApplicationPluginInput, packageType string, packagePath string, orchestrationDir string) error {
+	keyPath, err := downloadSignatureFile(log, pluginInput.GpgKeySource)
+	if err != nil {
+		return err
+	}
+	keyring, err := fileutil.TempDir(orchestrationDir, "keyring")
+	if err != nil {
+		return fmt.Errorf("failed to create the keyring: %v", err)
+	}
+	defer os.RemoveAll(keyring)
+
+	if pluginInput.SignatureSource != "" {
+		signaturePath, err := downloadSignatureFile(log, pluginInput.SignatureSource)
+		if err != nil {
+			return err
+		}
+		if output, err := runCommand(log, "gpg", "--homedir", keyring, "--batch", "--import", keyPath); err != nil {
+			return fmt.Errorf("failed to import the key %v: %v %v", pluginInput.GpgKeySource, err, output)
+		}
+		if output, err := runCommand(log, "gpg", "--homedir", keyring, "--batch", "--verify", signaturePath, packagePath); err != nil {
+			return fmt.Errorf("the signature %v of %v is not valid: %v %v", pluginInput.SignatureSource, pluginInput.Source, err, output)
+		}
+		return nil
+	}
+
+	if packageType != packageTypeRpm {
+		return fmt.Errorf("signatureSource is required to verify the signature of a %v package", packageType)
+	}
+	if output, err := runCommand(log, "rpm", "--dbpath", keyring, "--import", keyPath); err != nil {
+		return fmt.Errorf("failed to import the key %v: %v %v", pluginInput.GpgKeySource, err, output)
+	}
+	output, err := runCommand(log, "rpm", "--dbpath", keyring, "--checksig", packagePath)
+	if err != nil || !isRpmSignatureValid(output) {
+		return fmt.Errorf("the signature of %v is not valid: %v", pluginInput.Source, output)
+	}
+	return nil
+}
+
+// isRpmSignatureValid checks the output of rpm --checksig for a valid signature, the digests alone are not enough.
+func isRpmSignatureValid(output string) bool {
+	if strings.Contains(output, "NOT OK") || strings.Contains(output, "NOKEY") {
+		return false
+	}
+	return strings.Contains(output, "signatures OK") || strings.Contains(strings.ToLower(output), " pgp ")
+}
+
+// downloadSignatureFile downloads a key or a signature.
+func downloadSignatureFile(log log.T, source string) (string, error) {
+	downloadOutput, err := downloadFile(log, source, "", "")
+	if err != nil || downloadOutput.LocalFilePath == "" {
+		return "", fmt.Errorf("failed to download file reliably %v", source)
+	}
+	return downloadOutput.LocalFilePath, nil
+}
+
+// setPackageManagerStatus sets the status of the plugin from the exit code of the package manager
+func setPackageManagerStatus(log log.T, pluginInput ApplicationPluginInput, manager packageManager, cancelFlag task.CancelFlag, out iohandler.IOHandler) {
+	out.AppendInfo(pluginInput.Source)
+	exitCode := out.GetExitCode()
+
+	switch {
+	case exitCode == appconfig.SuccessExitCode || containsExitCode(manager.successExitCodes, exitCode):
+		out.SetStatus(contracts.ResultStatusSuccess)
+	case containsExitCode(manager.rebootExitCodes, exitCode):
+		out.SetStatus(contracts.ResultStatusPassedAndReboot)
+	case exitCode == appconfig.CommandStoppedPreemptivelyExitCode:
+		out.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+	default:
+		out.SetStatus(contracts.ResultStatusFailed)
+		out.AppendErrorf("Action:{%v}; Status:{%v}; ErrorCode:{%v}; Description:{%v}; Source:{%v};", pluginInput.Action, out.GetStatus(), exitCode, manager.describeExitCode(exitCode), pluginInput.Source)
+	}
+	log.Debugf("%v exit code %v, status %v", manager.name, exitCode, out.GetStatus())
+}
+
+func containsExitCode(exitCodes []int, exitCode int) bool {
+	for _, code := range exitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+func yumArgs(action string, target string) []string {
+	switch action {
+	case UNINSTALL:
+		return []string{"-y", "remove", target}
+	case REPAIR:
+		return []string{"-y", "reinstall", target}
+	default:
+		return []string{"-y", "install", target}
+	}
+}
+
+func zypperArgs(action string, target string) []string {
+	switch action {
+	case UNINSTALL:
+		return []string{"--non-interactive", "remove", target}
+	case REPAIR:
+		return []string{"--non-interactive", "install", "--force", target}
+	default:
+		return []string{"--non-interactive", "install", target}
+	}
+}
+
+func rpmArgs(action string, target string) []string {
+	switch action {
+	case UNINSTALL:
+		return []string{"-e", target}
+	case REPAIR:
+		return []string{"-U", "-v", "--replacepkgs", target}
+	default:
+		return []string{"-U", "-v", target}
+	}
+}
+
+func aptArgs(action string, target string) []string {
+	switch action {
+	case UNINSTALL:
+		return []string{"-y", "remove", target}
+	case REPAIR:
+		return []string{"-y", "install", "--reinstall", target}
+	default:
+		return []string{"-y", "install", target}
+	}
+}
+
+func dpkgArgs(action string, target string) []string {
+	if action == UNINSTALL {
+		return []string{"--remove", target}
+	}
+	return []string{"--install", target}
+}
+
+func describeYumExitCode(exitCode int) string {
+	switch exitCode {
+	case 200:
+		return "The package database is locked by another process."
+	default:
+		return "An error occurred, see the output of the package manager."
+	}
+}
+
+func describeZypperExitCode(exitCode int) string {
+	switch exitCode {
+	case 4:
+		return "A problem was reported by the package library, like a missing dependency."
+	case 5:
+		return "The package manager requires root privileges."
+	case 7:
+		return "The package database is locked by another process."
+	case 8:
+		return "The transaction failed, the package is not installed."
+	case 104:
+		return "The package or one of its dependencies was not found."
+	case 107:
+		return "The package is installed but one of its scripts failed."
+	default:
+		return "An error occurred, see the output of the package manager."
+	}
+}
+
+func describeRpmExitCode(exitCode int) string {
+	return fmt.Sprintf("%v package(s) failed, dependencies are not resolved by rpm.", exitCode)
+}
+
+func describeAptExitCode(exitCode int) string {
+	return "An error occurred, the database may be locked by another process or a dependency may be missing."
+}
+
+func describeDpkgExitCode(exitCode int) string {
+	switch exitCode {
+	case 1:
+		return "The action failed, dependencies are not resolved by dpkg."
+	default:
+		return "Fatal or unrecoverable error, the database may be locked by another process."
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package application
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logger = log.NewMockLog()
+
+// stubPackageManager stubs the tools of the instance, commands are answered from the given map and fail when missing.
+func stubPackageManager(t *testing.T, tools []string, answers map[string]string) (commands *[]string, dir string, restore func()) {
+	oldLookPath, oldRunCommand, oldDownloadFile := lookPath, runCommand, downloadFile
+
+	dir, err := ioutil.TempDir("", "application")
+	assert.NoError(t, err)
+	downloaded := filepath.Join(dir, "0123456789abcdef")
+	assert.NoError(t, ioutil.WriteFile(downloaded, []byte("package"), 0600))
+
+	commands = &[]string{}
+	lookPath = func(file string) (string, error) {
+		for _, tool := range tools {
+			if tool == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+	runCommand = func(log log.T, name string, args ...string) (string, error) {
+		command := strings.Join(append([]string{name}, args...), " ")
+		*commands = append(*commands, command)
+		for prefix, output := range answers {
+			if strings.HasPrefix(command, prefix) {
+				return output, nil
+			}
+		}
+		return "", errors.New("exit status 1")
+	}
+	downloadFile = func(log log.T, source string, sourceHash string, sourceHashType string) (artifact.DownloadOutput, error) {
+		return artifact.DownloadOutput{LocalFilePath: downloaded, IsHashMatched: true}, nil
+	}
+
+	return commands, dir, func() {
+		lookPath, runCommand, downloadFile = oldLookPath, oldRunCommand, oldDownloadFile
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetPackageType(t *testing.T) {
+	packageType, err := getPackageType("https://example.com/agent-1.0.x86_64.RPM?versionId=2")
+	assert.NoError(t, err)
+	assert.Equal(t, packageTypeRpm, packageType)
+
+	packageType, err = getPackageType("s3://bucket/packages/agent_1.0_amd64.deb")
+	assert.NoError(t, err)
+	assert.Equal(t, packageTypeDeb, packageType)
+
+	_, err = getPackageType("https://example.com/agent.msi")
+	assert.Error(t, err)
+}
+
+func TestInstallRpm(t *testing.T) {
+	_, dir, restore := stubPackageManager(t, []string{"dnf", "rpm"}, map[string]string{
+		"rpm -qp": "agent 1.0-1",
+	})
+	defer restore()
+	input := ApplicationPluginInput{ID: "plugin1", Action: INSTALL, Source: "https://example.com/agent.rpm"}
+	packagePath := filepath.Join(dir, input.ID, "package.rpm")
+
+	mockExecuter := executers.MockCommandExecuter{}
+	mockExecuter.On("NewExecuteWithOptions", logger, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "dnf", []string{"-y", "install", packagePath}, mock.Anything).Return(0, nil)
+	p := &Plugin{CommandExecuter: &mockExecuter}
+	out := &iohandler.DefaultIOHandler{}
+
+	p.runCommands(logger, input.ID, input, dir, "", task.NewChanneledCancelFlag(), out)
+
+	mockExecuter.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, out.GetStatus())
+	assert.True(t, fileExists(packagePath))
+}
+
+func TestInstallAlreadyInstalled(t *testing.T) {
+	_, dir, restore := stubPackageManager(t, []string{"apt-get"}, map[string]string{
+		"dpkg-deb":   "agent 1.0-1",
+		"dpkg-query": "install ok installed 1.0-1",
+	})
+	defer restore()
+	input := ApplicationPluginInput{Action: INSTALL, Source: "https://example.com/agent.deb"}
+
+	mockExecuter := executers.MockCommandExecuter{}
+	p := &Plugin{CommandExecuter: &mockExecuter}
+	out := &iohandler.DefaultIOHandler{}
+
+	p.runCommands(logger, input.ID, input, dir, "", task.NewChanneledCancelFlag(), out)
+
+	mockExecuter.AssertNotCalled(t, "NewExecuteWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, contracts.ResultStatusSuccess, out.GetStatus())
+	assert.Contains(t, out.GetStdout(), "already installed")
+}
+
+func TestUninstallDeb(t *testing.T) {
+	_, dir, restore := stubPackageManager(t, []string{"apt-get"}, map[string]string{
+		"dpkg-deb":   "agent 1.0-1",
+		"dpkg-query": "install ok installed 0.9-1",
+	})
+	defer restore()
+	input := ApplicationPluginInput{Action: UNINSTALL, Source: "https://example.com/agent.deb"}
+
+	mockExecuter := executers.MockCommandExecuter{}
+	mockExecuter.On("NewExecuteWithOptions", logger, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "apt-get", []string{"-y", "remove", "agent"}, mock.Anything).Return(100, errors.New("exit status 100"))
+	p := &Plugin{CommandExecuter: &mockExecuter}
+	out := &iohandler.DefaultIOHandler{}
+
+	p.runCommands(logger, input.ID, input, dir, "", task.NewChanneledCancelFlag(), out)
+
+	mockExecuter.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, out.GetStatus())
+	assert.Contains(t, out.GetStderr(), "ErrorCode:{100}")
+}
+
+func TestUninstallNotInstalled(t *testing.T) {
+	_, dir, restore := stubPackageManager(t, []string{"yum"}, map[string]string{
+		"rpm -qp": "agent 1.0-1",
+	})
+	defer restore()
+	input := ApplicationPluginInput{Action: UNINSTALL, Source: "https://example.com/agent.rpm"}
+
+	mockExecuter := executers.MockCommandExecuter{}
+	p := &Plugin{CommandExecuter: &mockExecuter}
+	out := &iohandler.DefaultIOHandler{}
+
+	p.runCommands(logger, input.ID, input, dir, "", task.NewChanneledCancelFlag(), out)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, out.GetStatus())
+	assert.Contains(t, out.GetStdout(), "is not installed")
+}
+
+func TestSetPackageManagerStatus(t *testing.T) {
+	zypper := packageManagers[packageTypeRpm][2]
+	input := ApplicationPluginInput{Action: INSTALL, Source: "https://example.com/agent.rpm"}
+
+	for exitCode, status := range map[int]contracts.ResultStatus{
+		0:   contracts.ResultStatusSuccess,
+		102: contracts.ResultStatusPassedAndReboot,
+		103: contracts.ResultStatusSuccess,
+		7:   contracts.ResultStatusFailed,
+	} {
+		out := &iohandler.DefaultIOHandler{}
+		out.SetExitCode(exitCode)
+		setPackageManagerStatus(logger, input, zypper, task.NewChanneledCancelFlag(), out)
+		assert.Equal(t, status, out.GetStatus(), "exit code %v", exitCode)
+	}
+}
+
+func TestVerifyDetachedSignature(t *testing.T) {
+	commands, dir, restore := stubPackageManager(t, nil, map[string]string{
+		"gpg": "",
+	})
+	defer restore()
+	input := ApplicationPluginInput{Source: "https://example.com/agent.deb", GpgKeySource: "https://example.com/key.asc", SignatureSource: "https://example.com/agent.deb.sig"}
+
+	assert.NoError(t, verifySignature(logger, input, packageTypeDeb, "/tmp/package.deb", dir))
+	assert.Len(t, *commands, 2)
+	assert.Contains(t, (*commands)[1], "--verify")
+}
+
+func TestVerifyRpmSignature(t *testing.T) {
+	_, dir, restore := stubPackageManager(t, nil, map[string]string{
+		"rpm --dbpath": "package.rpm: digests OK",
+	})
+	defer restore()
+	input := ApplicationPluginInput{Source: "https://example.com/agent.rpm", GpgKeySource: "https://example.com/key.asc"}
+
+	// an unsigned package only has valid digests
+	assert.Error(t, verifySignature(logger, input, packageTypeRpm, "/tmp/package.rpm", dir))
+	assert.Error(t, verifySignature(logger, input, packageTypeDeb, "/tmp/package.deb", dir))
+}
+
+func TestIsRpmSignatureValid(t *testing.T) {
+	assert.True(t, isRpmSignatureValid("package.rpm: digests signatures OK"))
+	assert.True(t, isRpmSignatureValid("package.rpm: rsa sha1 (md5) pgp md5 OK"))
+	assert.False(t, isRpmSignatureValid("package.rpm: digests OK"))
+	assert.False(t, isRpmSignatureValid("package.rpm: digests SIGNATURES NOT OK"))
+	assert.False(t, isRpmSignatureValid("package.rpm: RSA sha1 ((MD5) PGP) md5 NOT OK (MISSING KEYS: (MD5) PGP#fd431d51)"))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package application
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// msiExecCommand is the command for installing msi applications
+var msiExecCommand = filepath.Join(os.Getenv("SystemRoot"), "System32", "msiexec.exe")
+
+var getSecureString = parameterstore.GetSecureString
+
+// runCommands executes one set of commands and returns their output.
+func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput ApplicationPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	var err error
+
+	// TODO:MF: This subdirectory is only needed because we could be running multiple sets of properties for the same plugin - otherwise the orchestration directory would already be unique
+	orchestrationDir := fileutil.BuildPath(orchestrationDirectory, pluginInput.ID)
+	log.Debugf("OrchestrationDir %v ", orchestrationDir)
+
+	// create orchestration dir if needed
+	if err = fileutil.MakeDirs(orchestrationDir); err != nil {
+		log.Debug("failed to create orchestrationDir directory", orchestrationDir, err)
+		output.MarkAsFailed(err)
+		return
+	}
+
+	// Get application mode
+	mode, err := getMsiApplicationMode(log, pluginInput)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	log.Debugf("mode is %v", mode)
+
+	var localFilePath string
+	// Download file from source if available
+	downloadOutput, err := pluginutil.DownloadFileFromSource(log, pluginInput.Source, pluginInput.SourceHash, pluginInput.SourceHashType)
+	if err != nil || downloadOutput.IsHashMatched == false || downloadOutput.LocalFilePath == "" {
+		errorString := fmt.Errorf("failed to download file reliably %v", pluginInput.Source)
+		output.MarkAsFailed(errorString)
+		return
+	}
+	localFilePath = downloadOutput.LocalFilePath
+	log.Debugf("local path to file is %v", localFilePath)
+
+	// Create msi related log file
+	localSourceLogFilePath := localFilePath + ".msiexec.log.txt"
+
+	options, err := getRunAsOptions(log, pluginInput)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	if options.ResourceLimits, err = pluginutil.GetResourceLimits(log, Name(), pluginInput.ResourceLimits); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	if options.RunAsUser != "" {
+		// the downloaded files are shared, give the account its own copy of the package and log next to it
+		if localFilePath, err = stageForRunAs(localFilePath, orchestrationDir, options); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to stage %v for runAsUser %v: %v", pluginInput.Source, options.RunAsUser, err))
+			return
+		}
+		localSourceLogFilePath = localFilePath + ".msiexec.log.txt"
+	}
+	log.Debugf("log path is %v", localSourceLogFilePath)
+
+	// Construct Command Name and Arguments
+	commandName := msiExecCommand
+	commandArguments := []string{mode, localFilePath, "/quiet", "/norestart", "/log", localSourceLogFilePath}
+	if pluginInput.Parameters != "" {
+		log.Debugf("Got Parameters \"%v\"", pluginInput.Parameters)
+		params := processParams(log, pluginInput.Parameters)
+		commandArguments = append(commandArguments, params...)
+	}
+
+	// Execute Command
+	executionTimeout := pluginutil.ValidatePluginExecutionTimeout(log, Name(), pluginInput.TimeoutSeconds)
+	exitCode, err := p.CommandExecuter.NewExecuteWithOptions(log, defaultWorkingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments, options)
+
+	// Set output status
+	output.SetExitCode(exitCode)
+	setMsiExecStatus(log, pluginInput, cancelFlag, output)
+
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to run commands: %v", err))
+		return
+	}
+}
+
+// getRunAsOptions returns the options running msiexec as RunAsUser, with the password read from parameter store.
+func getRunAsOptions(log log.T, pluginInput ApplicationPluginInput) (options executers.ExecuteOptions, err error) {
+	options.RunAsUser = pluginInput.RunAsUser
+	if pluginInput.RunAsPasswordParameter == "" {
+		return
+	}
+	if options.RunAsUser == "" {
+		return options, fmt.Errorf("runAsPasswordParameter requires runAsUser")
+	}
+	if options.RunAsPassword, err = getSecureString(log, pluginInput.RunAsPasswordParameter); err != nil {
+		return options, fmt.Errorf("failed to get the password of runAsUser %v: %v", options.RunAsUser, err)
+	}
+	return
+}
+
+// stageForRunAs copies the package to the given directory and grants the account access to it.
+func stageForRunAs(packagePath string, dir string, options executers.ExecuteOptions) (string, error) {
+	stagedPath := filepath.Join(dir, filepath.Base(packagePath))
+	if err := copyFile(packagePath, stagedPath); err != nil {
+		return "", err
+	}
+	return stagedPath, executers.GrantRunAsAccess(dir, options)
+}
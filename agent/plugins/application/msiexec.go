@@ -11,8 +11,6 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-// Package application implements the application plugin.
-//
 // +build windows
 
 package application
@@ -27,14 +25,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
-const (
-	INSTALL = "Install"
-
-	UNINSTALL = "Uninstall"
-
-	REPAIR = "Repair"
-)
-
 const (
 	ErrorUnknownProduct = 1605
 
@@ -73,7 +63,7 @@ func setMsiExecStatus(log log.T, pluginInput ApplicationPluginInput, cancelFlag
 	case ErrorSuccessRebootInitiated:
 		fallthrough
 	case appconfig.RebootExitCode:
-		out.SetStatus(contracts.ResultStatusSuccessAndReboot)
+		out.SetStatus(contracts.ResultStatusPassedAndReboot)
 	case appconfig.CommandStoppedPreemptivelyExitCode:
 		if cancelFlag.ShutDown() {
 			out.SetStatus(contracts.ResultStatusFailed)
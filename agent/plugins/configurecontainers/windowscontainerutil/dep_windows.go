@@ -57,7 +57,7 @@ func (DepWindows) MakeDirs(destinationDir string) (err error) {
 	return fileutil.MakeDirs(destinationDir)
 }
 func (DepWindows) TempDir(dir, prefix string) (name string, err error) {
-	return ioutil.TempDir(dir, prefix)
+	return fileutil.TempDir(dir, prefix)
 }
 
 func (DepWindows) UpdateUtilExeCommandOutput(
@@ -105,9 +105,11 @@ func runConfigureDaemon(
 				State:         managerContracts.PluginState{IsEnabled: true},
 			},
 			Handler: &rundaemon.Plugin{
-				ExeLocation: input.PackageLocation,
-				Name:        input.Name,
-				CommandLine: input.Command,
+				ExeLocation:   input.PackageLocation,
+				Name:          input.Name,
+				CommandLine:   input.Command,
+				RestartPolicy: input.RestartPolicy,
+				MaxRestarts:   input.MaxRestarts,
 			},
 		}
 
@@ -131,11 +133,18 @@ func runConfigureDaemon(
 
 		p.lrpm.EnsurePluginRegistered(input.Name, plugin)
 		p.lrpm.StopPlugin(input.Name, cancelFlag)
+		// a daemon registered earlier keeps its handler, which has to run with the new settings
+		if registered, ok := p.lrpm.GetRegisteredPlugins()[input.Name]; ok {
+			if daemon, ok := registered.Handler.(*rundaemon.Plugin); ok {
+				daemon.Configure(input)
+			}
+		}
 		if errStart := p.lrpm.StartPlugin(input.Name, input.Command, orchestrationDir, cancelFlag, output); errStart != nil {
-			output.AppendErrorf("\nFailed to start ssm daemon %v: %v", input.Name, err.Error())
+			output.AppendErrorf("\nFailed to start ssm daemon %v: %v", input.Name, errStart.Error())
 			output.SetStatus(contracts.ResultStatusFailed)
 			return
 		}
+		output.AppendInfof("\nDaemon %v started, its output is captured in %v", input.Name, rundaemon.DaemonLogPath(input.Name))
 	case "Stop":
 		if !fileutil.Exists(daemonFilePath) {
 			output.AppendErrorf("\nNo ssm daemon %v exists", input.Name)
@@ -0,0 +1,271 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configurekernelparameters implements the aws:configureKernelParameters plugin,
+// which sets kernel parameters (sysctl on linux, registry values on windows) and reverts them.
+package configurekernelparameters
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	//Action values
+	APPLY  = "Apply"
+	REVERT = "Revert"
+
+	// stateFileName is the file recording the parameters set by the plugin and their previous values
+	stateFileName = "kernelparameters.json"
+)
+
+// stateFilePath is the path of the state file, a variable for the unit tests
+var stateFilePath = filepath.Join(appconfig.DefaultDataStorePath, stateFileName)
+
+// Plugin is the type for the plugin.
+type Plugin struct {
+}
+
+// ConfigureKernelParametersPluginInput represents one set of parameters configured by the plugin.
+type ConfigureKernelParametersPluginInput struct {
+	contracts.PluginInput
+	ID     string
+	Action string
+	// Parameters maps the names of the parameters to their values, all the parameters set by the plugin are reverted when empty
+	Parameters map[string]string
+}
+
+// previousValue is the value a parameter had before the plugin first set it.
+type previousValue struct {
+	Value  string
+	Exists bool
+}
+
+// kernelParametersState records the parameters set by the plugin.
+type kernelParametersState struct {
+	// Applied holds the values set by the plugin, which are persisted across reboots
+	Applied map[string]string
+	// Previous holds the values restored by the revert action
+	Previous map[string]previousValue
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameConfigureKernelParameters
+}
+
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.runCommandsRawInput(log, config.Properties, output)
+	}
+}
+
+// runCommandsRawInput configures one set of parameters.
+// The input is in the default json unmarshal format (e.g. map[string]interface{}).
+func (p *Plugin) runCommandsRawInput(log log.T, rawPluginInput interface{}, output iohandler.IOHandler) {
+	var pluginInput ConfigureKernelParametersPluginInput
+	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
+	log.Debugf("Plugin input %v", pluginInput)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", rawPluginInput, err))
+		return
+	}
+	p.runCommands(log, pluginInput, output)
+}
+
+// runCommands applies or reverts the parameters.
+func (p *Plugin) runCommands(log log.T, pluginInput ConfigureKernelParametersPluginInput, output iohandler.IOHandler) {
+	state, err := loadState()
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to load the previous values of the parameters: %v", err))
+		return
+	}
+
+	switch pluginInput.Action {
+	case APPLY, "":
+		err = apply(log, pluginInput.Parameters, &state, output)
+	case REVERT:
+		err = revert(log, pluginInput.Parameters, &state, output)
+	default:
+		err = fmt.Errorf("Action is set to unsupported value: %v", pluginInput.Action)
+	}
+
+	// the state is saved even after a failure as some parameters may have been changed
+	if saveErr := saveState(state); saveErr != nil && err == nil {
+		err = fmt.Errorf("failed to save the previous values of the parameters: %v", saveErr)
+	}
+	if persistErr := persistParameters(state.Applied); persistErr != nil && err == nil {
+		err = fmt.Errorf("failed to persist the parameters: %v", persistErr)
+	}
+
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	output.MarkAsSucceeded()
+}
+
+// apply sets the parameters which do not have the requested value and records their previous value.
+func apply(log log.T, parameters map[string]string, state *kernelParametersState, output iohandler.IOHandler) error {
+	if len(parameters) == 0 {
+		return fmt.Errorf("parameters are required to apply")
+	}
+	for name, value := range parameters {
+		if err := validateParameter(name, value); err != nil {
+			return err
+		}
+	}
+
+	changed := false
+	for _, name := range sortedNames(parameters) {
+		value := parameters[name]
+		current, exists, err := readParameter(name)
+		if err != nil {
+			return fmt.Errorf("failed to read %v: %v", name, err)
+		}
+
+		if _, recorded := state.Previous[name]; !recorded {
+			state.Previous[name] = previousValue{Value: current, Exists: exists}
+		}
+		state.Applied[name] = value
+
+		if exists && normalizeValue(current) == normalizeValue(value) {
+			output.AppendInfof("%v is already set to %v.", name, value)
+			continue
+		}
+		log.Infof("Setting %v to %v", name, value)
+		if err = writeParameter(name, value); err != nil {
+			return fmt.Errorf("failed to set %v to %v: %v", name, value, err)
+		}
+		output.AppendInfof("Set %v to %v, previous value: %v.", name, value, describeValue(current, exists))
+		changed = true
+	}
+
+	if changed && changesRequireReboot {
+		output.AppendInfo("A reboot is required for some changes to take effect.")
+	}
+	return nil
+}
+
+// revert restores the previous value of the given parameters, or of all the parameters set by the plugin.
+func revert(log log.T, parameters map[string]string, state *kernelParametersState, output iohandler.IOHandler) error {
+	names := sortedNames(parameters)
+	if len(names) == 0 {
+		for name := range state.Previous {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		previous, recorded := state.Previous[name]
+		if !recorded {
+			output.AppendInfof("%v was not set by %v, nothing to revert.", name, Name())
+			continue
+		}
+
+		log.Infof("Reverting %v to %v", name, describeValue(previous.Value, previous.Exists))
+		var err error
+		if previous.Exists {
+			err = writeParameter(name, previous.Value)
+		} else {
+			err = deleteParameter(name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to revert %v: %v", name, err)
+		}
+		delete(state.Previous, name)
+		delete(state.Applied, name)
+		output.AppendInfof("Reverted %v to %v.", name, describeValue(previous.Value, previous.Exists))
+	}
+
+	if len(names) > 0 && changesRequireReboot {
+		output.AppendInfo("A reboot is required for some changes to take effect.")
+	}
+	return nil
+}
+
+// loadState reads the state file, an empty state is returned when the plugin never ran.
+func loadState() (state kernelParametersState, err error) {
+	if fileutil.Exists(stateFilePath) {
+		if err = jsonutil.UnmarshalFile(stateFilePath, &state); err != nil {
+			return
+		}
+	}
+	if state.Applied == nil {
+		state.Applied = map[string]string{}
+	}
+	if state.Previous == nil {
+		state.Previous = map[string]previousValue{}
+	}
+	return
+}
+
+// saveState writes the state file, which is removed once all the parameters are reverted.
+func saveState(state kernelParametersState) error {
+	if len(state.Previous) == 0 {
+		if err := os.Remove(stateFilePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	content, err := jsonutil.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(stateFilePath)); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(stateFilePath, []byte(content), appconfig.ReadWriteAccess)
+}
+
+func sortedNames(parameters map[string]string) []string {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func describeValue(value string, exists bool) string {
+	if !exists {
+		return "not set"
+	}
+	return value
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package configurekernelparameters
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	// procSysPath is the directory exposing the kernel parameters
+	procSysPath = "/proc/sys"
+	// changesRequireReboot is false as sysctl values take effect immediately
+	changesRequireReboot = false
+)
+
+// sysctlConfigPath is the file loaded at boot which persists the parameters set by the plugin
+var sysctlConfigPath = "/etc/sysctl.d/90-amazon-ssm-agent.conf"
+
+var validSysctlName = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)+$`)
+
+// Makes command as variables, so that we can mock this for unit tests
+var readParameter = readSysctl
+var writeParameter = writeSysctl
+var deleteParameter = func(name string) error {
+	return fmt.Errorf("kernel parameters cannot be deleted")
+}
+var persistParameters = writeSysctlConfig
+
+// validateParameter checks the sysctl name, e.g. net.ipv4.ip_forward, and its value.
+func validateParameter(name string, value string) error {
+	if !validSysctlName.MatchString(name) {
+		return fmt.Errorf("%v is not a valid sysctl name", name)
+	}
+	if strings.TrimSpace(value) == "" || strings.ContainsAny(value, "\n\r") {
+		return fmt.Errorf("%v is not a valid value for %v", value, name)
+	}
+	return nil
+}
+
+// normalizeValue ignores the whitespaces of the value, multi value parameters are read separated by tabs.
+func normalizeValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func sysctlPath(name string) string {
+	return filepath.Join(procSysPath, strings.Replace(name, ".", "/", -1))
+}
+
+func readSysctl(name string) (value string, exists bool, err error) {
+	content, err := ioutil.ReadFile(sysctlPath(name))
+	if os.IsNotExist(err) {
+		return "", false, fmt.Errorf("kernel parameter %v does not exist", name)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return normalizeValue(string(content)), true, nil
+}
+
+func writeSysctl(name string, value string) error {
+	file, err := os.OpenFile(sysctlPath(name), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err = file.WriteString(value); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeSysctlConfig writes the parameters set by the plugin to the sysctl configuration, it is removed when empty.
+func writeSysctlConfig(applied map[string]string) error {
+	if len(applied) == 0 {
+		if err := os.Remove(sysctlConfigPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(applied))
+	for name := range applied {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	buffer.WriteString("# Kernel parameters set by the aws:configureKernelParameters plugin of the SSM agent, do not edit.\n")
+	for _, name := range names {
+		buffer.WriteString(fmt.Sprintf("%v = %v\n", name, applied[name]))
+	}
+	return ioutil.WriteFile(sysctlConfigPath, buffer.Bytes(), appconfig.ReadWriteAccess|0044)
+}
@@ -0,0 +1,181 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package configurekernelparameters
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// fakeKernel replaces the kernel parameters with an in-memory store.
+type fakeKernel struct {
+	values    map[string]string
+	writes    int
+	persisted map[string]string
+}
+
+func setupFakeKernel(t *testing.T, values map[string]string) (kernel *fakeKernel, cleanup func()) {
+	dir, err := ioutil.TempDir("", "kernelparameters")
+	assert.NoError(t, err)
+
+	kernel = &fakeKernel{values: values}
+	readParameter = func(name string) (string, bool, error) {
+		value, exists := kernel.values[name]
+		if !exists {
+			return "", false, fmt.Errorf("kernel parameter %v does not exist", name)
+		}
+		return value, true, nil
+	}
+	writeParameter = func(name string, value string) error {
+		kernel.values[name] = value
+		kernel.writes++
+		return nil
+	}
+	persistParameters = func(applied map[string]string) error {
+		kernel.persisted = map[string]string{}
+		for name, value := range applied {
+			kernel.persisted[name] = value
+		}
+		return nil
+	}
+	stateFilePath = filepath.Join(dir, stateFileName)
+
+	return kernel, func() {
+		readParameter = readSysctl
+		writeParameter = writeSysctl
+		persistParameters = writeSysctlConfig
+		os.RemoveAll(dir)
+	}
+}
+
+func runPlugin(action string, parameters map[string]string) iohandler.DefaultIOHandler {
+	p, _ := NewPlugin()
+	output := iohandler.DefaultIOHandler{}
+	p.runCommands(logger, ConfigureKernelParametersPluginInput{Action: action, Parameters: parameters}, &output)
+	return output
+}
+
+func TestApplyIsIdempotent(t *testing.T) {
+	kernel, cleanup := setupFakeKernel(t, map[string]string{"net.ipv4.ip_forward": "0", "vm.swappiness": "60"})
+	defer cleanup()
+
+	parameters := map[string]string{"net.ipv4.ip_forward": "1", "vm.swappiness": "60"}
+	output := runPlugin(APPLY, parameters)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, 1, kernel.writes)
+	assert.Equal(t, "1", kernel.values["net.ipv4.ip_forward"])
+	assert.Equal(t, parameters, kernel.persisted)
+
+	output = runPlugin(APPLY, parameters)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, 1, kernel.writes)
+	assert.Contains(t, output.GetStdout(), "net.ipv4.ip_forward is already set to 1.")
+}
+
+func TestApplyRecordsFirstPreviousValue(t *testing.T) {
+	_, cleanup := setupFakeKernel(t, map[string]string{"vm.swappiness": "60"})
+	defer cleanup()
+
+	runPlugin(APPLY, map[string]string{"vm.swappiness": "10"})
+	runPlugin(APPLY, map[string]string{"vm.swappiness": "20"})
+
+	state, err := loadState()
+	assert.NoError(t, err)
+	assert.Equal(t, previousValue{Value: "60", Exists: true}, state.Previous["vm.swappiness"])
+	assert.Equal(t, "20", state.Applied["vm.swappiness"])
+}
+
+func TestApplyInvalidParameter(t *testing.T) {
+	kernel, cleanup := setupFakeKernel(t, map[string]string{"vm.swappiness": "60"})
+	defer cleanup()
+
+	output := runPlugin(APPLY, map[string]string{"vm.swappiness": "10", "../etc/passwd": "x"})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, 0, kernel.writes)
+}
+
+func TestApplyUnknownParameter(t *testing.T) {
+	_, cleanup := setupFakeKernel(t, map[string]string{})
+	defer cleanup()
+
+	output := runPlugin(APPLY, map[string]string{"net.ipv4.unknown": "1"})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "does not exist")
+}
+
+func TestRevertRestoresPreviousValues(t *testing.T) {
+	kernel, cleanup := setupFakeKernel(t, map[string]string{"net.ipv4.ip_forward": "0", "vm.swappiness": "60"})
+	defer cleanup()
+
+	runPlugin(APPLY, map[string]string{"net.ipv4.ip_forward": "1", "vm.swappiness": "10"})
+
+	output := runPlugin(REVERT, map[string]string{"vm.swappiness": ""})
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, "60", kernel.values["vm.swappiness"])
+	assert.Equal(t, "1", kernel.values["net.ipv4.ip_forward"])
+	assert.Equal(t, map[string]string{"net.ipv4.ip_forward": "1"}, kernel.persisted)
+
+	output = runPlugin(REVERT, nil)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, "0", kernel.values["net.ipv4.ip_forward"])
+	assert.Empty(t, kernel.persisted)
+	_, err := os.Stat(stateFilePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUnsupportedAction(t *testing.T) {
+	_, cleanup := setupFakeKernel(t, map[string]string{})
+	defer cleanup()
+
+	output := runPlugin("Reset", nil)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
+
+func TestSysctlPath(t *testing.T) {
+	assert.Equal(t, "/proc/sys/net/ipv4/tcp_keepalive_time", sysctlPath("net.ipv4.tcp_keepalive_time"))
+}
+
+func TestNormalizeValue(t *testing.T) {
+	assert.Equal(t, "4096 87380 6291456", normalizeValue("4096\t87380   6291456\n"))
+}
+
+func TestWriteSysctlConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sysctl")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sysctlConfigPath = filepath.Join(dir, "90-amazon-ssm-agent.conf")
+
+	err = writeSysctlConfig(map[string]string{"vm.swappiness": "10", "net.ipv4.ip_forward": "1"})
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(sysctlConfigPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "net.ipv4.ip_forward = 1\nvm.swappiness = 10\n")
+
+	err = writeSysctlConfig(map[string]string{})
+	assert.NoError(t, err)
+	_, err = os.Stat(sysctlConfigPath)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package configurekernelparameters
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	tcpipParametersPath    = `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters`
+	memoryManagementPath   = `SYSTEM\CurrentControlSet\Control\Session Manager\Memory Management`
+	fileSystemPath         = `SYSTEM\CurrentControlSet\Control\FileSystem`
+	lanmanServerParamsPath = `SYSTEM\CurrentControlSet\Services\LanmanServer\Parameters`
+	changesRequireReboot   = true
+)
+
+// registryParameters are the DWORD values of HKEY_LOCAL_MACHINE the plugin can set, keyed by their name
+var registryParameters = map[string]string{
+	"TcpTimedWaitDelay":            tcpipParametersPath,
+	"MaxUserPort":                  tcpipParametersPath,
+	"TcpNumConnections":            tcpipParametersPath,
+	"TcpMaxDataRetransmissions":    tcpipParametersPath,
+	"KeepAliveTime":                tcpipParametersPath,
+	"KeepAliveInterval":            tcpipParametersPath,
+	"EnablePMTUDiscovery":          tcpipParametersPath,
+	"DisableTaskOffload":           tcpipParametersPath,
+	"LargeSystemCache":             memoryManagementPath,
+	"DisablePagingExecutive":       memoryManagementPath,
+	"ClearPageFileAtShutdown":      memoryManagementPath,
+	"NtfsDisableLastAccessUpdate":  fileSystemPath,
+	"NtfsDisable8dot3NameCreation": fileSystemPath,
+	"MaxWorkItems":                 lanmanServerParamsPath,
+	"MaxMpxCt":                     lanmanServerParamsPath,
+}
+
+// Makes command as variables, so that we can mock this for unit tests
+var readParameter = readRegistryValue
+var writeParameter = writeRegistryValue
+var deleteParameter = deleteRegistryValue
+var persistParameters = func(applied map[string]string) error {
+	// registry values are persisted by windows
+	return nil
+}
+
+// validateParameter checks the parameter is a supported registry value and its value a DWORD.
+func validateParameter(name string, value string) error {
+	if _, ok := registryParameters[name]; !ok {
+		return fmt.Errorf("%v is not a supported kernel parameter", name)
+	}
+	if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+		return fmt.Errorf("%v is not a valid value for %v, a 32-bit unsigned integer is expected", value, name)
+	}
+	return nil
+}
+
+// normalizeValue returns the DWORD value in decimal
+func normalizeValue(value string) string {
+	if number, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return strconv.FormatUint(number, 10)
+	}
+	return value
+}
+
+func readRegistryValue(name string) (value string, exists bool, err error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, registryParameters[name], registry.QUERY_VALUE)
+	if err == registry.ErrNotExist {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer key.Close()
+
+	number, _, err := key.GetIntegerValue(name)
+	if err == registry.ErrNotExist {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return strconv.FormatUint(number, 10), true, nil
+}
+
+func writeRegistryValue(name string, value string) error {
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	key, _, err := registry.CreateKey(registry.LOCAL_MACHINE, registryParameters[name], registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	return key.SetDWordValue(name, uint32(number))
+}
+
+func deleteRegistryValue(name string) error {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, registryParameters[name], registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	if err = key.DeleteValue(name); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+	return nil
+}
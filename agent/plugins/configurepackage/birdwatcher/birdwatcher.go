@@ -30,6 +30,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -54,24 +55,20 @@ type PackageService struct {
 	manifestCache packageservice.ManifestCache
 	collector     envdetect.Collector
 	timeProvider  NanoTime
+	policy        verification.Policy
 }
 
 // New constructor for PackageService
-func New(endpoint string, manifestCache packageservice.ManifestCache) packageservice.PackageService {
+func New(endpoint string, manifestCache packageservice.ManifestCache, policy verification.Policy) packageservice.PackageService {
 	// TODO: endpoint vs appconfig
 	// TODO: pass in log var to log errs
 	cfg := sdkutil.AwsConfig()
 
 	// overrides ssm client config from appconfig if applicable
 	if appCfg, err := appconfig.Config(false); err == nil {
-		if appCfg.Ssm.Endpoint != "" {
-			cfg.Endpoint = &appCfg.Ssm.Endpoint
-		} else {
-			if region, err := platform.Region(); err == nil {
-				if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "ssm"); defaultEndpoint != "" {
-					cfg.Endpoint = &defaultEndpoint
-				}
-			}
+		region, _ := platform.Region()
+		if endpoint := appconfig.GetServiceEndpoint(appCfg, appconfig.ServiceNameSsm, region); endpoint != "" {
+			cfg.Endpoint = &endpoint
 		}
 		if appCfg.Agent.Region != "" {
 			cfg.Region = &appCfg.Agent.Region
@@ -94,6 +91,7 @@ func New(endpoint string, manifestCache packageservice.ManifestCache) packageser
 		manifestCache: manifestCache,
 		collector:     &envdetect.CollectorImp{},
 		timeProvider:  &TimeImpl{},
+		policy:        policy,
 	}
 }
 
@@ -113,14 +111,10 @@ func (ds *PackageService) DownloadManifest(tracer trace.Tracer, packageName stri
 // DownloadArtifact downloads the platform matching artifact specified in the manifest
 func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName string, version string) (string, error) {
 	trace := tracer.BeginSection("download artifact")
-	manifest, err := readManifestFromCache(ds.manifestCache, packageName, version)
+	manifest, err := cachedManifest(ds, trace, packageName, version)
 	if err != nil {
-		trace.AppendInfof("error when reading the manifest from cache %v", err).End()
-		manifest, _, err = downloadManifest(ds, packageName, version)
-		if err != nil {
-			trace.WithError(err).End()
-			return "", fmt.Errorf("failed to download the manifest: %v", err)
-		}
+		trace.WithError(err).End()
+		return "", err
 	}
 
 	file, err := ds.findFileFromManifest(tracer, manifest)
@@ -130,7 +124,26 @@ func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName stri
 	}
 
 	trace.End()
-	return downloadFile(tracer, file)
+	return downloadFile(tracer, file, ds.policy)
+}
+
+// DownloadDelta downloads the platform matching delta package updating the base version to the version, when the manifest publishes one
+func (ds *PackageService) DownloadDelta(tracer trace.Tracer, packageName string, version string, baseVersion string) (string, error) {
+	trace := tracer.BeginSection("download delta")
+	manifest, err := cachedManifest(ds, trace, packageName, version)
+	if err != nil {
+		trace.WithError(err).End()
+		return "", err
+	}
+
+	file, err := ds.findDeltaFromManifest(tracer, manifest, baseVersion)
+	if err != nil {
+		trace.WithError(err).End()
+		return "", err
+	}
+
+	trace.End()
+	return downloadFile(tracer, file, ds.policy)
 }
 
 // ReportResult sents back the result of the install/upgrade/uninstall run back to Birdwatcher
@@ -194,6 +207,19 @@ func readManifestFromCache(cache packageservice.ManifestCache, packageName strin
 	return parseManifest(&data)
 }
 
+// cachedManifest reads the manifest of a version from the cache, downloading it again when it can't be read
+func cachedManifest(ds *PackageService, trace *trace.Trace, packageName string, version string) (*Manifest, error) {
+	manifest, err := readManifestFromCache(ds.manifestCache, packageName, version)
+	if err != nil {
+		trace.AppendInfof("error when reading the manifest from cache %v", err)
+		manifest, _, err = downloadManifest(ds, packageName, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download the manifest: %v", err)
+		}
+	}
+	return manifest, nil
+}
+
 func downloadManifest(ds *PackageService, packageName string, version string) (*Manifest, bool, error) {
 	isSameAsCache := false
 	resp, err := ds.facadeClient.GetManifest(
@@ -260,7 +286,26 @@ func (ds *PackageService) findFileFromManifest(tracer trace.Tracer, manifest *Ma
 	return file, nil
 }
 
-func downloadFile(tracer trace.Tracer, file *File) (string, error) {
+// findDeltaFromManifest returns the file of the delta package updating the base version for the current instance
+func (ds *PackageService) findDeltaFromManifest(tracer trace.Tracer, manifest *Manifest, baseVersion string) (*File, error) {
+	pkginfo, err := ds.extractPackageInfo(tracer, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find platform: %v", err)
+	}
+
+	name, ok := pkginfo.Deltas[baseVersion]
+	if !ok {
+		return nil, fmt.Errorf("no delta package from version %v is published", baseVersion)
+	}
+	file, ok := manifest.Files[name]
+	if !ok || file == nil {
+		return nil, fmt.Errorf("failed to find delta file %v", name)
+	}
+
+	return file, nil
+}
+
+func downloadFile(tracer trace.Tracer, file *File, policy verification.Policy) (string, error) {
 	downloadInput := artifact.DownloadInput{
 		SourceURL: file.DownloadLocation,
 		// TODO don't hardcode sha256 - use multiple checksums
@@ -280,6 +325,14 @@ func downloadFile(tracer trace.Tracer, file *File) (string, error) {
 		return "", errors.New(errMessage)
 	}
 
+	if err := verifyArtifact(log, policy, verification.Artifact{
+		SourceURL: downloadInput.SourceURL,
+		LocalPath: downloadOutput.LocalFilePath,
+		Checksums: downloadInput.SourceChecksums,
+	}); err != nil {
+		return "", fmt.Errorf("failed to verify installation package %v, %v", downloadInput.SourceURL, err)
+	}
+
 	return downloadOutput.LocalFilePath, nil
 }
 
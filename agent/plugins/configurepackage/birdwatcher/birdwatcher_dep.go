@@ -17,6 +17,7 @@ package birdwatcher
 import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 )
 
 // dependency on S3 and downloaded artifacts
@@ -26,6 +27,9 @@ type networkDep interface {
 
 var networkdep networkDep = &networkDepImp{}
 
+// verifyArtifact verifies the downloaded artifacts as the verification policy requires
+var verifyArtifact = verification.Verify
+
 type networkDepImp struct{}
 
 func (networkDepImp) Download(log log.T, input artifact.DownloadInput) (artifact.DownloadOutput, error) {
@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/envdetect/osdetect"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -678,11 +679,15 @@ func TestDownloadFile(t *testing.T) {
 			true,
 		},
 	}
+	verifyArtifact = func(log log.T, policy verification.Policy, artifact verification.Artifact) error {
+		return nil
+	}
+	defer func() { verifyArtifact = verification.Verify }()
 	for _, testdata := range data {
 		t.Run(testdata.name, func(t *testing.T) {
 			networkdep = &testdata.network
 
-			result, err := downloadFile(tracer, testdata.file)
+			result, err := downloadFile(tracer, testdata.file, verification.PolicyNone)
 			if testdata.expectedErr {
 				assert.Error(t, err)
 			} else {
@@ -774,3 +779,86 @@ func TestDownloadArtifact(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadDelta(t *testing.T) {
+	manifestStr := `
+	{
+		"packages": {
+			"platformName": {
+				"platformVersion": {
+					"architecture": {
+						"file": "test.zip",
+						"deltas": {
+							"1.0.0": "test-1.0.0.zip",
+							"1.1.0": "missing.zip"
+						}
+					}
+				}
+			}
+		},
+		"files": {
+			"test.zip": {
+				"downloadLocation": "https://example.com/agent"
+			},
+			"test-1.0.0.zip": {
+				"downloadLocation": "https://example.com/agent-delta"
+			}
+		}
+	}
+	`
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+
+	data := []struct {
+		name        string
+		baseVersion string
+		expectedErr string
+	}{
+		{
+			"successful download",
+			"1.0.0",
+			"",
+		},
+		{
+			"no delta from the base version",
+			"0.9.0",
+			"no delta package from version 0.9.0 is published",
+		},
+		{
+			"delta file missing",
+			"1.1.0",
+			"failed to find delta file missing.zip",
+		},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			cache := packageservice.ManifestCacheMemNew()
+			cache.WriteManifest("packageName", "1234", []byte(manifestStr))
+
+			mockedCollector := envdetect.CollectorMock{}
+			mockedCollector.On("CollectData", mock.Anything).Return(&envdetect.Environment{
+				&osdetect.OperatingSystem{"platformName", "platformVersion", "", "architecture", "", ""},
+				&ec2infradetect.Ec2Infrastructure{"instanceID", "region", "", "availabilityZone", "instanceType"},
+			}, nil).Once()
+
+			ds := &PackageService{manifestCache: cache, collector: &mockedCollector}
+			network := networkMock{
+				downloadOutput: artifact.DownloadOutput{
+					LocalFilePath: "agent-delta.zip",
+				},
+			}
+			networkdep = &network
+
+			result, err := ds.DownloadDelta(tracer, "packageName", "1234", testdata.baseVersion)
+
+			if testdata.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, "agent-delta.zip", result)
+				assert.Equal(t, "https://example.com/agent-delta", network.downloadInput.SourceURL)
+			} else {
+				assert.EqualError(t, err, testdata.expectedErr)
+			}
+		})
+	}
+}
@@ -24,6 +24,8 @@ type File struct {
 // PackageInfo contains references to Files matching the current platform/version/arch
 type PackageInfo struct {
 	File string `json:"file"`
+	// Deltas names the files of the delta packages updating a base version to this version, by base version
+	Deltas map[string]string `json:"deltas,omitempty"`
 }
 
 // Manifest contains references to all SSM packages for a given agent version
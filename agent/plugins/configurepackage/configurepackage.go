@@ -18,6 +18,8 @@ package configurepackage
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -32,6 +34,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/ssms3"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 )
 
 const (
@@ -39,22 +42,43 @@ const (
 	InstallAction = "Install"
 	// UninstallAction represents the json command to uninstall package
 	UninstallAction = "Uninstall"
+	// RollbackPreviousAction represents the json command to reinstall the version installed before the current one
+	RollbackPreviousAction = "RollbackPrevious"
+	// SetActiveAction represents the json command to switch to a version kept in the local repository, without downloading it
+	SetActiveAction = "SetActive"
+	// ListInstalledAction represents the json command to list the packages installed from the local repository
+	ListInstalledAction = "ListInstalled"
+	// InstallationTypeUninstallReinstall uninstalls the installed version of a package before installing the new one
+	InstallationTypeUninstallReinstall = "Uninstall and reinstall"
+	// InstallationTypeInPlaceUpdate runs the update script of the new version of a package over the installed version
+	InstallationTypeInPlaceUpdate = "In-place update"
+
+	// installedPackagesField reports in the plugin result the packages listed by ListInstalled
+	installedPackagesField = "installedPackages"
 )
 
 // Plugin is the type for the configurepackage plugin.
 type Plugin struct {
-	packageServiceSelector func(tracer trace.Tracer, serviceEndpoint string, localrepo localpackages.Repository) packageservice.PackageService
+	packageServiceSelector func(tracer trace.Tracer, serviceEndpoint string, source string, localrepo localpackages.Repository, policy verification.Policy) packageservice.PackageService
 	localRepository        localpackages.Repository
 }
 
 // ConfigurePackagePluginInput represents one set of commands executed by the ConfigurePackage plugin.
 type ConfigurePackagePluginInput struct {
 	contracts.PluginInput
-	Name       string `json:"name"`
-	Version    string `json:"version"`
-	Action     string `json:"action"`
-	Source     string `json:"source"`
-	Repository string `json:"repository"`
+	Name             string `json:"name"`
+	Version          string `json:"version"`
+	Action           string `json:"action"`
+	InstallationType string `json:"installationType"`
+	Repository       string `json:"repository"`
+	// Source is the url template of a custom bucket the package is downloaded from, see ssms3.NewWithSource
+	Source string `json:"source"`
+	// VerificationPolicy overrides the verification policy of the agent configuration, it may only be stricter
+	VerificationPolicy string `json:"verificationPolicy"`
+	// RolloutPercentage limits the action to the given percentage of the instances receiving the document
+	RolloutPercentage interface{} `json:"rolloutPercentage"`
+	// RolloutSalt selects a different set of instances for the same percentage, it defaults to the package name
+	RolloutSalt string `json:"rolloutSalt"`
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -146,6 +170,67 @@ func prepareConfigurePackage(
 		}
 		trace.End()
 
+	case RollbackPreviousAction:
+		// get version information
+		trace := tracer.BeginSection("determine version to roll back to")
+		installedVersion, installState = getVersionToInstall(tracer, repository, packageArn)
+		history := repository.GetInstallHistory(tracer, packageArn)
+		trace.AppendInfof("installed versions: %v", formatInstallHistory(history))
+		if installedVersion == "" || installState == localpackages.None || installState == localpackages.Uninstalled {
+			trace.AppendErrorf("no version of %v is installed", packageArn).End()
+			output.MarkAsFailed(nil, nil)
+			return
+		}
+		if version = getPreviousVersion(history, installedVersion); version == "" {
+			trace.AppendErrorf("no version of %v was installed before %v", packageArn, installedVersion).End()
+			output.MarkAsFailed(nil, nil)
+			return
+		}
+		trace.AppendDebugf("installed: %v in state %v, to roll back to: %v", installedVersion, installState, version).End()
+
+		// ensure the previous version from the repository, or download it again
+		var err error
+		trace = tracer.BeginSection("ensure previous package is locally available")
+		inst, err = ensurePackage(tracer, repository, packageService, packageArn, version, isSameAsCache, config)
+		if err != nil {
+			trace.WithError(err).End()
+			output.MarkAsFailed(nil, nil)
+			return
+		}
+		trace.End()
+
+		trace = tracer.BeginSection("ensure installed package is locally available")
+		if uninst, err = ensurePackage(tracer, repository, packageService, packageArn, installedVersion, isSameAsCache, config); err != nil {
+			trace.WithError(err)
+		}
+		trace.End()
+
+	case SetActiveAction:
+		// get version information
+		trace := tracer.BeginSection("determine version to activate")
+		installedVersion, installState = getVersionToInstall(tracer, repository, packageArn)
+		trace.AppendInfof("installed versions: %v", formatInstallHistory(repository.GetInstallHistory(tracer, packageArn)))
+		trace.AppendDebugf("installed: %v in state %v, to activate: %v", installedVersion, installState, version).End()
+
+		// the versions are switched between the copies kept side by side in the repository, nothing is downloaded
+		var err error
+		trace = tracer.BeginSection("ensure package is kept in the local repository")
+		inst, err = localPackage(tracer, repository, packageArn, version, config)
+		if err != nil {
+			trace.WithError(err).End()
+			output.MarkAsFailed(nil, nil)
+			return
+		}
+		trace.End()
+
+		trace = tracer.BeginSection("ensure installed package is kept in the local repository")
+		if !(installedVersion == "" || installState == localpackages.None) && installedVersion != version {
+			if uninst, err = localPackage(tracer, repository, packageArn, installedVersion, config); err != nil {
+				trace.WithError(err)
+			}
+		}
+		trace.End()
+
 	default:
 		prepareTrace.AppendErrorf("unsupported action: %v", input.Action)
 		output.MarkAsFailed(nil, nil)
@@ -172,7 +257,11 @@ func ensurePackage(
 		(currentVersion == version && (currentState == localpackages.Failed || !isSameAsCache)) {
 		pkgTrace.AppendDebugf("Current %v Target %v State %v", currentVersion, version, currentState).End()
 		pkgTrace.AppendDebugf("Refreshing package content for %v %v", packageName, version).End()
-		if err = repository.RefreshPackage(tracer, packageName, version, packageService.PackageServiceName(), buildDownloadDelegate(tracer, packageService, packageName, version)); err != nil {
+		installedVersion := ""
+		if currentState == localpackages.Installed {
+			installedVersion = currentVersion
+		}
+		if err = refreshPackage(tracer, repository, packageService, packageName, version, installedVersion); err != nil {
 			pkgTrace.WithError(err).End()
 			return nil, err
 		}
@@ -187,6 +276,20 @@ func ensurePackage(
 	return repository.GetInstaller(tracer, config, packageName, version), nil
 }
 
+// localPackage validates the copy of a package version kept in the repository and returns its installer, it never downloads the package
+func localPackage(
+	tracer trace.Tracer,
+	repository localpackages.Repository,
+	packageName string,
+	version string,
+	config contracts.Configuration) (installer.Installer, error) {
+
+	if err := repository.ValidatePackage(tracer, packageName, version); err != nil {
+		return nil, fmt.Errorf("version %v of %v is not available in the local repository, install it first: %v", version, packageName, err)
+	}
+	return repository.GetInstaller(tracer, config, packageName, version), nil
+}
+
 // buildDownloadDelegate constructs the delegate used by the repository to download a package from the service
 func buildDownloadDelegate(tracer trace.Tracer, packageService packageservice.PackageService, packageName string, version string) func(trace.Tracer, string) error {
 	return func(tracer trace.Tracer, targetDirectory string) error {
@@ -247,6 +350,25 @@ func getVersionToUninstall(
 	return installedVersion, currentState
 }
 
+// getPreviousVersion returns the most recently installed version other than the installed one
+func getPreviousVersion(history []localpackages.InstalledVersion, installedVersion string) string {
+	for _, installed := range history {
+		if installed.Version != installedVersion {
+			return installed.Version
+		}
+	}
+	return ""
+}
+
+// formatInstallHistory lists the installed versions of a package, most recent first
+func formatInstallHistory(history []localpackages.InstalledVersion) string {
+	versions := make([]string, 0, len(history))
+	for _, installed := range history {
+		versions = append(versions, fmt.Sprintf("%v (%v)", installed.Version, installed.InstalledTime.Format(time.RFC3339)))
+	}
+	return strings.Join(versions, ", ")
+}
+
 // parseAndValidateInput marshals raw JSON and returns the result of input validation or an error
 func parseAndValidateInput(rawPluginInput interface{}) (*ConfigurePackagePluginInput, error) {
 	var input ConfigurePackagePluginInput
@@ -264,16 +386,39 @@ func parseAndValidateInput(rawPluginInput interface{}) (*ConfigurePackagePluginI
 
 // validateInput ensures the plugin input matches the defined schema
 func validateInput(input *ConfigurePackagePluginInput) (valid bool, err error) {
-	// source not yet supported
+	// a custom source replaces the repository
 	if input.Source != "" {
-		return false, errors.New("source parameter is not supported in this version")
+		if err := ssms3.ValidateSource(input.Source); err != nil {
+			return false, err
+		}
 	}
 
-	// ensure non-empty name
-	if input.Name == "" {
+	// ensure non-empty name, the installed packages are listed regardless of their name
+	if input.Name == "" && input.Action != ListInstalledAction {
 		return false, errors.New("empty name field")
 	}
 
+	// packages are uninstalled and reinstalled unless an in-place update is requested
+	if input.InstallationType == "" {
+		input.InstallationType = InstallationTypeUninstallReinstall
+	} else if input.InstallationType != InstallationTypeUninstallReinstall && input.InstallationType != InstallationTypeInPlaceUpdate {
+		return false, fmt.Errorf("unsupported installation type %v, expected %v or %v", input.InstallationType, InstallationTypeUninstallReinstall, InstallationTypeInPlaceUpdate)
+	}
+
+	// the version to roll back to is the one installed before the current version
+	if input.Action == RollbackPreviousAction && input.Version != "" {
+		return false, fmt.Errorf("version is not supported with action %v", RollbackPreviousAction)
+	}
+
+	// the version to activate must be one kept in the local repository
+	if input.Action == SetActiveAction && (input.Version == "" || packageservice.IsLatest(input.Version)) {
+		return false, fmt.Errorf("a specific version is required with action %v", SetActiveAction)
+	}
+
+	if _, err := parseRolloutPercentage(input.RolloutPercentage); err != nil {
+		return false, err
+	}
+
 	// dump any unsupported value for Repository
 	if input.Repository != "beta" && input.Repository != "gamma" {
 		input.Repository = ""
@@ -308,7 +453,7 @@ func checkAlreadyInstalled(
 		}
 		if (targetVersion == installedVersion &&
 			(installState == localpackages.Installed || installState == localpackages.Unknown)) ||
-			installState == localpackages.Installing {
+			installState == localpackages.Installing || installState == localpackages.Updating {
 			instToCheck = inst
 		}
 		if instToCheck != nil {
@@ -318,10 +463,10 @@ func checkAlreadyInstalled(
 			validateTrace.WithExitcode(int64(validateOutput.GetExitCode()))
 
 			if validateOutput.GetStatus() == contracts.ResultStatusSuccess {
-				if installState == localpackages.Installing {
+				if installState == localpackages.Installing || installState == localpackages.Updating {
 					validateTrace.AppendInfof("Successfully installed %v %v", packageName, targetVersion)
 					if uninst != nil {
-						cleanupAfterUninstall(tracer, repository, uninst, output)
+						cleanupAfterUpgrade(tracer, repository, inst, uninst, output)
 					}
 					output.MarkAsSucceeded()
 				} else if installState == localpackages.RollbackInstall {
@@ -351,17 +496,22 @@ func checkAlreadyInstalled(
 }
 
 // selectService chooses the implementation of PackageService to use for a given execution of the plugin
-func selectService(tracer trace.Tracer, serviceEndpoint string, localrepo localpackages.Repository) packageservice.PackageService {
+func selectService(tracer trace.Tracer, serviceEndpoint string, source string, localrepo localpackages.Repository, policy verification.Policy) packageservice.PackageService {
 	region, _ := platform.Region()
+
+	if source != "" {
+		tracer.CurrentTrace().AppendInfof("Using the packages of source %v", source)
+		return ssms3.NewWithSource(source, region, policy)
+	}
 	appCfg, err := appconfig.Config(false)
 
 	if (err == nil && appCfg.Birdwatcher.ForceEnable) || !ssms3.UseSSMS3Service(tracer, serviceEndpoint, region) {
 		tracer.CurrentTrace().AppendInfof("S3 repository is not marked active in %v %v", region, serviceEndpoint)
-		return birdwatcher.New(serviceEndpoint, localrepo)
+		return birdwatcher.New(serviceEndpoint, localrepo, policy)
 	}
 
 	tracer.CurrentTrace().AppendInfof("S3 repository is marked active")
-	return ssms3.New(serviceEndpoint, region)
+	return ssms3.New(serviceEndpoint, region, policy)
 }
 
 // Execute runs the plugin operation and returns output
@@ -385,8 +535,20 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
 		tracer.CurrentTrace().WithError(err).End()
 		out.MarkAsFailed(nil, nil)
+	} else if input.Action == ListInstalledAction {
+		listInstalled(tracer, p.localRepository, output, &out)
+	} else if policy, err := verification.EffectivePolicy(input.VerificationPolicy); err != nil {
+		tracer.CurrentTrace().WithError(err).End()
+		out.MarkAsFailed(nil, nil)
+	} else if selected, err := inRollout(tracer, input); err != nil {
+		tracer.CurrentTrace().WithError(err).End()
+		out.MarkAsFailed(nil, nil)
+	} else if !selected {
+		tracer.CurrentTrace().AppendInfof("Instance is not part of the %v%% rollout of %v, skipping %v", input.RolloutPercentage, input.Name, input.Action)
+		output.SetResultField(rolloutSelectedField, false)
+		out.MarkAsSucceeded()
 	} else {
-		packageService := p.packageServiceSelector(tracer, input.Repository, p.localRepository)
+		packageService := p.packageServiceSelector(tracer, input.Repository, input.Source, p.localRepository, policy)
 		//Return failure if the manifest cannot be accessed
 		//Return failure if the package version is installed, but the manifest is no longer available
 		packageArn, manifestVersion, isSameAsCache, err := getPackageArnAndVersion(tracer, packageService, input)
@@ -402,6 +564,11 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 		} else {
 			defer p.localRepository.UnlockPackage(tracer, packageArn)
 
+			if input.Action == RollbackPreviousAction || input.Action == SetActiveAction {
+				// the content of the version kept in the repository is validated by the repository
+				isSameAsCache = true
+			}
+
 			log.Debugf("Prepare for %v %v %v", input.Action, input.Name, input.Version)
 			inst, uninst, installState, installedVersion := prepareConfigurePackage(
 				tracer,
@@ -429,10 +596,13 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 						inst,
 						uninst,
 						installState,
+						input.InstallationType == InstallationTypeInPlaceUpdate,
 						&out)
 				}
 			}
 
+			setPackageResult(output, input.Action, inst, out.GetStatus())
+
 			if err := p.localRepository.LoadTraces(tracer, packageArn); err != nil {
 				log.Errorf("Error loading prior traces: %v", err.Error())
 			}
@@ -443,10 +613,17 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 				}
 			} else {
 				version := manifestVersion
+				operation := input.Action
 				if input.Action == InstallAction {
 					version = inst.Version()
 				} else if input.Action == UninstallAction {
 					version = uninst.Version()
+				} else if input.Action == RollbackPreviousAction || input.Action == SetActiveAction {
+					// the package service only knows about installs and uninstalls
+					operation = InstallAction
+					if inst != nil {
+						version = inst.Version()
+					}
 				}
 
 				startTime := tracer.Traces()[0].Start
@@ -458,7 +635,7 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 
 				err := packageService.ReportResult(tracer, packageservice.PackageResult{
 					Exitcode:               int64(out.GetExitCode()),
-					Operation:              input.Action,
+					Operation:              operation,
 					PackageName:            input.Name,
 					PreviousPackageVersion: installedVersion,
 					Timing:                 startTime,
@@ -483,6 +660,34 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	return
 }
 
+// setPackageResult records the outcome of the action in the machine-readable result of the plugin
+func setPackageResult(output iohandler.IOHandler, action string, inst installer.Installer, status contracts.ResultStatus) {
+	if status != contracts.ResultStatusSuccess && !status.IsReboot() {
+		return
+	}
+	if inst != nil && (action == InstallAction || action == RollbackPreviousAction || action == SetActiveAction) {
+		output.SetResultField(contracts.ResultFieldInstalledVersion, inst.Version())
+	}
+	output.SetResultField(contracts.ResultFieldRebootRequired, status.IsReboot())
+}
+
+// listInstalled reports the packages installed from the local repository as JSON in the result and the output of the plugin
+func listInstalled(tracer trace.Tracer, repository localpackages.Repository, output iohandler.IOHandler, out *trace.PluginOutputTrace) {
+	trace := tracer.BeginSection("list installed packages")
+	defer trace.End()
+
+	installed := repository.ListInstalled(tracer)
+	content, err := jsonutil.MarshalIndent(installed)
+	if err != nil {
+		trace.WithError(err)
+		out.MarkAsFailed(nil, nil)
+		return
+	}
+	trace.AppendInfo(content)
+	output.SetResultField(installedPackagesField, installed)
+	out.MarkAsSucceeded()
+}
+
 // Name returns the name of the plugin.
 func Name() string {
 	return appconfig.PluginNameAwsConfigurePackage
@@ -0,0 +1,170 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configurepackage implements the ConfigurePackage plugin.
+// configurepackage_delta builds a package version from the installed version and a delta package
+package configurepackage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+// deltaManifestName is the file of a delta package describing how it updates the base version
+const deltaManifestName = "_delta.json"
+
+// packageDelta represents the json structure of the delta manifest. A delta package holds the files
+// added or changed since the base version, the files removed are listed and the content of the
+// resulting version is verified against the checksums of all its files.
+type packageDelta struct {
+	BaseVersion string            `json:"baseVersion"`
+	Removed     []string          `json:"removed,omitempty"`
+	Files       map[string]string `json:"files"` // slash separated relative path -> sha256 checksum
+}
+
+// refreshPackage downloads the content of a package version to the repository, from the delta package
+// updating the installed version when one is published and from the full package otherwise
+func refreshPackage(
+	tracer trace.Tracer,
+	repository localpackages.Repository,
+	packageService packageservice.PackageService,
+	packageName string,
+	version string,
+	installedVersion string) error {
+
+	serviceName := packageService.PackageServiceName()
+	if installedVersion != "" && installedVersion != version {
+		deltaTrace := tracer.BeginSection(fmt.Sprintf("update %v from %v to %v with a delta package", packageName, installedVersion, version))
+		err := repository.RefreshPackage(tracer, packageName, version, serviceName, buildDeltaDelegate(tracer, repository, packageService, packageName, version, installedVersion))
+		if err == nil {
+			deltaTrace.End()
+			return nil
+		}
+		deltaTrace.AppendInfof("falling back to the full package: %v", err).End()
+	}
+	return repository.RefreshPackage(tracer, packageName, version, serviceName, buildDownloadDelegate(tracer, packageService, packageName, version))
+}
+
+// buildDeltaDelegate constructs the delegate used by the repository to build a package version from the base version and a delta package,
+// the target directory is left empty when the delta can't be applied
+func buildDeltaDelegate(
+	tracer trace.Tracer,
+	repository localpackages.Repository,
+	packageService packageservice.PackageService,
+	packageName string,
+	version string,
+	baseVersion string) func(trace.Tracer, string) error {
+
+	return func(tracer trace.Tracer, targetDirectory string) error {
+		trace := tracer.BeginSection("apply delta package")
+		if err := repository.ValidatePackage(tracer, packageName, baseVersion); err != nil {
+			trace.WithError(err).End()
+			return fmt.Errorf("base version %v is not available in the local repository: %v", baseVersion, err)
+		}
+
+		filePath, err := packageService.DownloadDelta(tracer, packageName, version, baseVersion)
+		if err != nil {
+			trace.WithError(err).End()
+			return err
+		}
+
+		err = applyDelta(repository.GetPackageVersionPath(tracer, packageName, baseVersion), filePath, targetDirectory, baseVersion)
+		if cleanupErr := filesysdep.RemoveAll(filePath); cleanupErr != nil && err == nil {
+			err = fmt.Errorf("failed to delete delta package %v, %v", filePath, cleanupErr.Error())
+		}
+		if err != nil {
+			// leave the target empty so the full package can be extracted to it
+			if cleanupErr := filesysdep.RemoveAll(targetDirectory); cleanupErr == nil {
+				filesysdep.MakeDirExecute(targetDirectory)
+			}
+			trace.WithError(err).End()
+			return err
+		}
+
+		trace.End()
+		return nil
+	}
+}
+
+// applyDelta copies the base version to the target directory and updates it with the delta package
+func applyDelta(baseDirectory string, deltaPath string, targetDirectory string, baseVersion string) error {
+	if err := filesysdep.CopyDirectory(baseDirectory, targetDirectory); err != nil {
+		return fmt.Errorf("failed to copy base version %v, %v", baseVersion, err.Error())
+	}
+	if err := filesysdep.Uncompress(deltaPath, targetDirectory); err != nil {
+		return fmt.Errorf("failed to extract delta package %v to %v, %v", deltaPath, targetDirectory, err.Error())
+	}
+
+	deltaManifestPath := filepath.Join(targetDirectory, deltaManifestName)
+	content, err := filesysdep.ReadFile(deltaManifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the delta manifest, %v", err.Error())
+	}
+	var delta packageDelta
+	if err = json.Unmarshal(content, &delta); err != nil {
+		return fmt.Errorf("delta manifest is invalid: %v", err)
+	}
+	if delta.BaseVersion != baseVersion {
+		return fmt.Errorf("delta package updates version %v, not %v", delta.BaseVersion, baseVersion)
+	}
+	if err = filesysdep.RemoveAll(deltaManifestPath); err != nil {
+		return err
+	}
+
+	for _, removed := range delta.Removed {
+		relPath := path.Clean(removed)
+		if path.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, "../") {
+			return fmt.Errorf("delta manifest removes %v outside of the package", removed)
+		}
+		if err = filesysdep.RemoveAll(filepath.Join(targetDirectory, filepath.FromSlash(relPath))); err != nil {
+			return err
+		}
+	}
+
+	return verifyDeltaResult(targetDirectory, delta.Files)
+}
+
+// verifyDeltaResult ensures the target directory holds exactly the files of the delta manifest, with the expected checksums
+func verifyDeltaResult(targetDirectory string, expected map[string]string) error {
+	actual, err := filesysdep.HashFiles(targetDirectory)
+	if err != nil {
+		return fmt.Errorf("failed to hash the package content, %v", err.Error())
+	}
+
+	var mismatches []string
+	for name, checksum := range expected {
+		if actualChecksum, ok := actual[name]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%v is missing", name))
+		} else if !strings.EqualFold(actualChecksum, checksum) {
+			mismatches = append(mismatches, fmt.Sprintf("%v has checksum %v, expected %v", name, actualChecksum, checksum))
+		}
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%v is unexpected", name))
+		}
+	}
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("package content built from the delta doesn't match: %v", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configurepackage implements the ConfigurePackage plugin.
+package configurepackage
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	repoMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
+	serviceMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func sha256Hex(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		assert.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	}
+}
+
+func writeTestZip(t *testing.T, zipPath string, files map[string]string) {
+	file, err := os.Create(zipPath)
+	assert.NoError(t, err)
+	writer := zip.NewWriter(file)
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		assert.NoError(t, err)
+		_, err = entry.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, file.Close())
+}
+
+func readTestFiles(t *testing.T, dir string) map[string]string {
+	files := make(map[string]string)
+	assert.NoError(t, filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relPath, _ := filepath.Rel(dir, filePath)
+		content, err := ioutil.ReadFile(filePath)
+		files[filepath.ToSlash(relPath)] = string(content)
+		return err
+	}))
+	return files
+}
+
+func TestApplyDeltaPackage(t *testing.T) {
+	baseFiles := map[string]string{
+		"manifest.json":  "base manifest",
+		"install.sh":     "install base",
+		"lib/common.sh":  "common",
+		"lib/removed.sh": "removed",
+	}
+	expectedFiles := map[string]string{
+		"manifest.json": "new manifest",
+		"install.sh":    "install base",
+		"lib/common.sh": "common",
+		"lib/added.sh":  "added",
+	}
+	checksums := `"files": {"manifest.json": "` + sha256Hex("new manifest") + `", "install.sh": "` + sha256Hex("install base") +
+		`", "lib/common.sh": "` + sha256Hex("common") + `", "lib/added.sh": "` + sha256Hex("added") + `"}`
+
+	data := []struct {
+		name          string
+		deltaManifest string
+		expectedErr   string
+	}{
+		{
+			"successful update",
+			`{"baseVersion": "1.0.0", "removed": ["lib/removed.sh"], ` + checksums + `}`,
+			"",
+		},
+		{
+			"checksum mismatch",
+			`{"baseVersion": "1.0.0", "removed": ["lib/removed.sh"], "files": {"manifest.json": "` + sha256Hex("new manifest") +
+				`", "install.sh": "` + sha256Hex("install new") + `", "lib/common.sh": "` + sha256Hex("common") + `", "lib/added.sh": "` + sha256Hex("added") + `"}}`,
+			"package content built from the delta doesn't match: install.sh has checksum " + sha256Hex("install base") + ", expected " + sha256Hex("install new"),
+		},
+		{
+			"file not removed",
+			`{"baseVersion": "1.0.0", ` + checksums + `}`,
+			"package content built from the delta doesn't match: lib/removed.sh is unexpected",
+		},
+		{
+			"other base version",
+			`{"baseVersion": "0.9.0", "removed": ["lib/removed.sh"], ` + checksums + `}`,
+			"delta package updates version 0.9.0, not 1.0.0",
+		},
+		{
+			"removes outside of the package",
+			`{"baseVersion": "1.0.0", "removed": ["../1.0.0/install.sh"], ` + checksums + `}`,
+			"delta manifest removes ../1.0.0/install.sh outside of the package",
+		},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			stubs := &ConfigurePackageStubs{fileSysDepStub: &fileSysDepImp{}}
+			stubs.Set()
+			defer stubs.Clear()
+
+			dir, err := ioutil.TempDir("", "configurepackage")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+			baseDirectory := filepath.Join(dir, "1.0.0")
+			targetDirectory := filepath.Join(dir, "2.0.0")
+			deltaPath := filepath.Join(dir, "delta.zip")
+			writeTestFiles(t, baseDirectory, baseFiles)
+			assert.NoError(t, os.MkdirAll(targetDirectory, 0755))
+			writeTestZip(t, deltaPath, map[string]string{
+				"manifest.json":   "new manifest",
+				"lib/added.sh":    "added",
+				deltaManifestName: testdata.deltaManifest,
+			})
+
+			tracer := trace.NewTracer(log.NewMockLog())
+			mockRepo := repoMock.MockedRepository{}
+			mockRepo.On("ValidatePackage", tracer, "packageArn", "1.0.0").Return(nil).Once()
+			mockRepo.On("GetPackageVersionPath", tracer, "packageArn", "1.0.0").Return(baseDirectory).Once()
+			mockService := serviceMock.Mock{}
+			mockService.On("DownloadDelta", tracer, "packageArn", "2.0.0", "1.0.0").Return(deltaPath, nil).Once()
+
+			err = buildDeltaDelegate(tracer, &mockRepo, &mockService, "packageArn", "2.0.0", "1.0.0")(tracer, targetDirectory)
+
+			mockRepo.AssertExpectations(t)
+			mockService.AssertExpectations(t)
+			if testdata.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, expectedFiles, readTestFiles(t, targetDirectory))
+			} else {
+				assert.EqualError(t, err, testdata.expectedErr)
+				assert.Empty(t, readTestFiles(t, targetDirectory))
+			}
+			// the base version is left as is and the delta package is deleted
+			assert.Equal(t, baseFiles, readTestFiles(t, baseDirectory))
+			_, err = os.Stat(deltaPath)
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestApplyDeltaPackageBaseNotInRepository(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockRepo := repoMock.MockedRepository{}
+	mockRepo.On("ValidatePackage", tracer, "packageArn", "1.0.0").Return(errors.New("manifest not found")).Once()
+	mockService := serviceMock.Mock{}
+
+	err := buildDeltaDelegate(tracer, &mockRepo, &mockService, "packageArn", "2.0.0", "1.0.0")(tracer, "target")
+
+	mockRepo.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+	assert.EqualError(t, err, "base version 1.0.0 is not available in the local repository: manifest not found")
+}
+
+func TestRefreshPackage(t *testing.T) {
+	data := []struct {
+		name             string
+		installedVersion string
+		deltaErr         error
+		expectedCalls    int
+	}{
+		{
+			"first install downloads the full package",
+			"",
+			nil,
+			1,
+		},
+		{
+			"same version downloads the full package",
+			"2.0.0",
+			nil,
+			1,
+		},
+		{
+			"update applies the delta package",
+			"1.0.0",
+			nil,
+			1,
+		},
+		{
+			"update falls back to the full package",
+			"1.0.0",
+			errors.New("no delta package from version 1.0.0 is published"),
+			2,
+		},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			tracer := trace.NewTracer(log.NewMockLog())
+			tracer.BeginSection("test refresh package")
+			mockRepo := repoMock.MockedRepository{}
+			if testdata.deltaErr != nil {
+				mockRepo.On("RefreshPackage", tracer, "packageArn", "2.0.0", "birdwatcher", mock.Anything).Return(testdata.deltaErr).Once()
+			}
+			mockRepo.On("RefreshPackage", tracer, "packageArn", "2.0.0", "birdwatcher", mock.Anything).Return(nil).Once()
+			mockService := serviceMock.Mock{}
+			mockService.On("PackageServiceName").Return("birdwatcher")
+
+			err := refreshPackage(tracer, &mockRepo, &mockService, "packageArn", "2.0.0", testdata.installedVersion)
+
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+			mockRepo.AssertNumberOfCalls(t, "RefreshPackage", testdata.expectedCalls)
+		})
+	}
+}
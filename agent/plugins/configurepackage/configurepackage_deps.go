@@ -17,7 +17,12 @@
 package configurepackage
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 )
@@ -31,6 +36,9 @@ type fileSysDep interface {
 	WriteFile(filename string, content string) error
 	Uncompress(src, dest string) error
 	RemoveAll(path string) error
+	ReadFile(filename string) ([]byte, error)
+	CopyDirectory(srcPath string, destPath string) error
+	HashFiles(srcPath string) (map[string]string, error)
 }
 
 type fileSysDepImp struct{}
@@ -50,3 +58,74 @@ func (fileSysDepImp) Uncompress(src, dest string) error {
 func (fileSysDepImp) RemoveAll(path string) error {
 	return os.RemoveAll(path)
 }
+
+func (fileSysDepImp) ReadFile(filename string) ([]byte, error) {
+	return ioutil.ReadFile(filename)
+}
+
+// CopyDirectory copies the directories and regular files under srcPath to destPath, keeping their modes
+func (fileSysDepImp) CopyDirectory(srcPath string, destPath string) error {
+	return filepath.Walk(srcPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(srcPath, filePath)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destPath, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(filePath, target, info.Mode().Perm())
+	})
+}
+
+// HashFiles returns the sha256 checksum of every regular file under srcPath by slash separated relative path
+func (fileSysDepImp) HashFiles(srcPath string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.Walk(srcPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(srcPath, filePath)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		hash := sha256.New()
+		if _, err = io.Copy(hash, file); err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(relPath)] = hex.EncodeToString(hash.Sum(nil))
+		return nil
+	})
+	return hashes, err
+}
+
+func copyFile(srcPath string, destPath string, perm os.FileMode) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dest, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dest, src); err != nil {
+		dest.Close()
+		return err
+	}
+	return dest.Close()
+}
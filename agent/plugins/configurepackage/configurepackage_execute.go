@@ -26,7 +26,7 @@ import (
 )
 
 // TODO: consider passing in the timeout and cancel channels - does cancel trigger rollback?
-// executeConfigurePackage performs install and uninstall actions, with rollback support and recovery after reboots
+// executeConfigurePackage performs install, update and uninstall actions, with rollback support and recovery after reboots
 func executeConfigurePackage(
 	tracer trace.Tracer,
 	context context.T,
@@ -34,6 +34,7 @@ func executeConfigurePackage(
 	inst installer.Installer,
 	uninst installer.Installer,
 	initialInstallState localpackages.InstallState,
+	inPlaceUpdate bool,
 	output contracts.PluginOutputter) {
 
 	trace := tracer.BeginSection(fmt.Sprintf("execute configure - state: %s", initialInstallState))
@@ -47,8 +48,17 @@ func executeConfigurePackage(
 		executeInstall(tracer, context, repository, uninst, inst, true, output)
 	case localpackages.RollbackUninstall:
 		executeUninstall(tracer, context, repository, uninst, inst, true, output)
+	case localpackages.Updating:
+		// This is picking up an in-place update after reboot, unless the package is now being uninstalled
+		if inst != nil {
+			executeUpdate(tracer, context, repository, inst, uninst, output)
+		} else {
+			executeUninstall(tracer, context, repository, inst, uninst, false, output)
+		}
 	default:
-		if uninst != nil {
+		if uninst != nil && inst != nil && inPlaceUpdate {
+			executeUpdate(tracer, context, repository, inst, uninst, output)
+		} else if uninst != nil {
 			executeUninstall(tracer, context, repository, inst, uninst, false, output)
 		} else {
 			executeInstall(tracer, context, repository, inst, uninst, false, output)
@@ -113,7 +123,11 @@ func executeInstall(
 		return
 	}
 	if uninst != nil {
-		cleanupAfterUninstall(tracer, repository, uninst, output)
+		if isRollback {
+			cleanupAfterUninstall(tracer, repository, uninst, output)
+		} else {
+			cleanupAfterUpgrade(tracer, repository, inst, uninst, output)
+		}
 	}
 	if isRollback {
 		installtrace.AppendInfof("Failed to install %v %v, successfully rolled back to %v %v", uninst.PackageName(), uninst.Version(), inst.PackageName(), inst.Version())
@@ -127,6 +141,48 @@ func executeInstall(
 	return
 }
 
+// executeUpdate performs an in-place update of a package with the update script of the new version, followed by its validation.
+// The installed version isn't uninstalled and there is no rollback, the update script is expected to handle its own failures.
+func executeUpdate(
+	tracer trace.Tracer,
+	context context.T,
+	repository localpackages.Repository,
+	inst installer.Installer,
+	uninst installer.Installer,
+	output contracts.PluginOutputter) {
+
+	updatetrace := tracer.BeginSection(fmt.Sprintf("update %s to %s", inst.PackageName(), inst.Version()))
+	defer updatetrace.End()
+
+	setNewInstallState(tracer, repository, inst, localpackages.Updating)
+
+	result := inst.Update(tracer, context)
+	updatetrace.WithExitcode(int64(result.GetExitCode()))
+
+	if result.GetStatus() == contracts.ResultStatusSuccess {
+		validatetrace := tracer.BeginSection(fmt.Sprintf("validate %s/%s", inst.PackageName(), inst.Version()))
+		result = inst.Validate(tracer, context)
+		validatetrace.WithExitcode(int64(result.GetExitCode()))
+	}
+	if result.GetStatus().IsReboot() {
+		tracer.BeginSection(fmt.Sprintf("Rebooting to finish update of %v to %v", inst.PackageName(), inst.Version()))
+		output.MarkAsSuccessWithReboot()
+		return
+	}
+	if !result.GetStatus().IsSuccess() {
+		updatetrace.AppendErrorf("Failed to update package; update status %v", result.GetStatus())
+		setNewInstallState(tracer, repository, inst, localpackages.Failed)
+		output.MarkAsFailed(nil, nil)
+		return
+	}
+	if uninst != nil && uninst.Version() != inst.Version() {
+		cleanupAfterUpgrade(tracer, repository, inst, uninst, output)
+	}
+	updatetrace.AppendInfof("Successfully updated %v to %v", inst.PackageName(), inst.Version())
+	setNewInstallState(tracer, repository, inst, localpackages.Installed)
+	output.MarkAsSucceeded()
+}
+
 // executeUninstall performs uninstall of a package
 func executeUninstall(
 	tracer trace.Tracer,
@@ -188,3 +244,19 @@ func cleanupAfterUninstall(tracer trace.Tracer, repository localpackages.Reposit
 
 	trace.End()
 }
+
+// cleanupAfterUpgrade keeps the replaced version and the versions of the install history of a package side by side
+// in the repository for RollbackPrevious and SetActive, and removes the older ones
+func cleanupAfterUpgrade(tracer trace.Tracer, repository localpackages.Repository, inst installer.Installer, uninst installer.Installer, output contracts.PluginOutputter) {
+	trace := tracer.BeginSection(fmt.Sprintf("cleanup %s/%s", uninst.PackageName(), uninst.Version()))
+
+	versions := []string{inst.Version(), uninst.Version()}
+	for _, installed := range repository.GetInstallHistory(tracer, uninst.PackageName()) {
+		versions = append(versions, installed.Version)
+	}
+	if err := repository.RetainVersions(tracer, uninst.PackageName(), versions...); err != nil {
+		trace.WithError(err)
+	}
+
+	trace.End()
+}
@@ -17,10 +17,13 @@ package configurepackage
 import (
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	installerMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/installer/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -33,7 +36,7 @@ func TestInstallNew(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.New, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.New, false, output)
 
 	installerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -46,18 +49,62 @@ func TestUpgrade(t *testing.T) {
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Upgrading).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installing).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installed).Return(nil)
-	repoMock.On("RemovePackage", mock.Anything, "SsmTest", "0.0.1").Return(nil)
+	// the versions of the install history are kept side by side with the installed one
+	repoMock.On("GetInstallHistory", mock.Anything, "SsmTest").Return([]localpackages.InstalledVersion{{Version: "0.0.1"}, {Version: "0.0.0"}})
+	repoMock.On("RetainVersions", mock.Anything, "SsmTest", []string{"0.0.2", "0.0.1", "0.0.1", "0.0.0"}).Return(nil)
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, false, output)
 
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
 }
 
+func TestInPlaceUpdate(t *testing.T) {
+	uninstallerMock := &installerMock.Mock{}
+	uninstallerMock.On("PackageName").Return("SsmTest")
+	uninstallerMock.On("Version").Return("0.0.1")
+	installerMock := updaterSuccessMock("SsmTest", "0.0.2")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Updating).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installed).Return(nil)
+	repoMock.On("GetInstallHistory", mock.Anything, "SsmTest").Return([]localpackages.InstalledVersion{})
+	repoMock.On("RetainVersions", mock.Anything, "SsmTest", []string{"0.0.2", "0.0.1"}).Return(nil)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, true, output)
+
+	installerMock.AssertExpectations(t)
+	uninstallerMock.AssertNotCalled(t, "Uninstall", mock.Anything)
+	repoMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+}
+
+func TestInPlaceUpdateFailed(t *testing.T) {
+	uninstallerMock := &installerMock.Mock{}
+	uninstallerMock.On("PackageName").Return("SsmTest")
+	uninstallerMock.On("Version").Return("0.0.1")
+	installerMock := updaterFailedMock("SsmTest", "0.0.2")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Updating).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Failed).Return(nil)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, true, output)
+
+	installerMock.AssertExpectations(t)
+	uninstallerMock.AssertNotCalled(t, "Uninstall", mock.Anything)
+	repoMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
+
 func TestUpgradeFailedUninstall(t *testing.T) {
 	uninstallerMock := uninstallerFailedMock("SsmTest", "0.0.1")
 	installerMock := installerSuccessMock("SsmTest", "0.0.2")
@@ -65,12 +112,13 @@ func TestUpgradeFailedUninstall(t *testing.T) {
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Upgrading).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installing).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installed).Return(nil)
-	repoMock.On("RemovePackage", mock.Anything, "SsmTest", "0.0.1").Return(nil)
+	repoMock.On("GetInstallHistory", mock.Anything, "SsmTest").Return([]localpackages.InstalledVersion{})
+	repoMock.On("RetainVersions", mock.Anything, "SsmTest", []string{"0.0.2", "0.0.1"}).Return(nil)
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, false, output)
 
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
@@ -87,7 +135,7 @@ func TestUninstall(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, nil, uninstallerMock, localpackages.Installed, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, nil, uninstallerMock, localpackages.Installed, false, output)
 
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -102,7 +150,7 @@ func TestInstall_FailedInstall(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.New, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.New, false, output)
 
 	installerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -117,7 +165,7 @@ func TestInstall_FailedValidate(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.New, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.New, false, output)
 
 	installerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -132,7 +180,7 @@ func TestUninstall_Failed(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, nil, uninstallerMock, localpackages.Installed, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, nil, uninstallerMock, localpackages.Installed, false, output)
 
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -152,7 +200,7 @@ func TestRollback(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, false, output)
 
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
@@ -172,7 +220,7 @@ func TestRollbackFailed(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installed, false, output)
 
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
@@ -187,7 +235,7 @@ func TestUninstallReboot(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, nil, uninstallerMock, localpackages.Installed, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, nil, uninstallerMock, localpackages.Installed, false, output)
 
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -203,7 +251,7 @@ func TestUninstallAfterReboot(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, nil, uninstallerMock, localpackages.Uninstalling, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, nil, uninstallerMock, localpackages.Uninstalling, false, output)
 
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -217,7 +265,7 @@ func TestInstallReboot(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.New, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.New, false, output)
 
 	installerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -232,7 +280,7 @@ func TestInstallAfterReboot(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.Installing, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, nil, localpackages.Installing, false, output)
 
 	installerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
@@ -245,12 +293,13 @@ func TestUpgradeAfterUninstallReboot(t *testing.T) {
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Upgrading).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installing).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installed).Return(nil)
-	repoMock.On("RemovePackage", mock.Anything, "SsmTest", "0.0.1").Return(nil)
+	repoMock.On("GetInstallHistory", mock.Anything, "SsmTest").Return([]localpackages.InstalledVersion{})
+	repoMock.On("RetainVersions", mock.Anything, "SsmTest", []string{"0.0.2", "0.0.1"}).Return(nil)
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Uninstalling, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Uninstalling, false, output)
 
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
@@ -263,12 +312,13 @@ func TestUpgradeAfterInstallReboot(t *testing.T) {
 	repoMock := &repository_mock.MockedRepository{}
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installing).Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installed).Return(nil)
-	repoMock.On("RemovePackage", mock.Anything, "SsmTest", "0.0.1").Return(nil)
+	repoMock.On("GetInstallHistory", mock.Anything, "SsmTest").Return([]localpackages.InstalledVersion{})
+	repoMock.On("RetainVersions", mock.Anything, "SsmTest", []string{"0.0.2", "0.0.1"}).Return(nil)
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installing, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.Installing, false, output)
 
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
@@ -287,7 +337,7 @@ func TestRollbackAfterUninstallReboot(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.RollbackUninstall, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.RollbackUninstall, false, output)
 
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
@@ -305,7 +355,7 @@ func TestRollbackAfterInstallReboot(t *testing.T) {
 	tracer.BeginSection("test segment root")
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
-	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.RollbackInstall, output)
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, localpackages.RollbackInstall, false, output)
 
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
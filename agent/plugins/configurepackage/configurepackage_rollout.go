@@ -0,0 +1,99 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configurepackage implements the ConfigurePackage plugin.
+// configurepackage_rollout selects the instances taking part in a percentage based rollout
+package configurepackage
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+const (
+	// fullRollout is the rollout percentage applied when the input does not set one
+	fullRollout = 100
+	// rolloutSelectedField reports in the plugin result whether the instance takes part in the rollout
+	rolloutSelectedField = "rolloutSelected"
+)
+
+// decoupling platform.InstanceID for easy testability
+var instanceIDProvider = platform.InstanceID
+
+// parseRolloutPercentage converts the rolloutPercentage input, which is a number or a string, to a percentage
+func parseRolloutPercentage(value interface{}) (percentage int, err error) {
+	switch v := value.(type) {
+	case nil:
+		return fullRollout, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("rollout percentage %v is not a whole number", v)
+		}
+		percentage = int(v)
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return fullRollout, nil
+		}
+		if percentage, err = strconv.Atoi(strings.TrimSpace(v)); err != nil {
+			return 0, fmt.Errorf("rollout percentage %v is not a whole number", v)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported rollout percentage %v", value)
+	}
+
+	if percentage < 0 || percentage > fullRollout {
+		return 0, fmt.Errorf("rollout percentage %v should be between 0 and %v", percentage, fullRollout)
+	}
+	return percentage, nil
+}
+
+// rolloutBucket returns the bucket between 0 and 99 of the instance, which stays the same for a given salt
+func rolloutBucket(instanceID string, salt string) int {
+	sum := sha256.Sum256([]byte(salt + "/" + instanceID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % fullRollout)
+}
+
+// inRollout returns true if the instance is among the percentage of the instances the action is rolled out to.
+// The salt defaults to the package name, so the same instances are selected as the percentage of a package grows.
+func inRollout(tracer trace.Tracer, input *ConfigurePackagePluginInput) (bool, error) {
+	percentage, err := parseRolloutPercentage(input.RolloutPercentage)
+	if err != nil {
+		return false, err
+	}
+	if percentage == fullRollout {
+		return true, nil
+	}
+
+	rolloutTrace := tracer.BeginSection("check rollout")
+	defer rolloutTrace.End()
+
+	instanceID, err := instanceIDProvider()
+	if err != nil {
+		return false, fmt.Errorf("failed to get the instance id for the rollout: %v", err)
+	}
+	salt := input.RolloutSalt
+	if salt == "" {
+		salt = input.Name
+	}
+
+	bucket := rolloutBucket(instanceID, salt)
+	rolloutTrace.AppendDebugf("rollout bucket %v for percentage %v", bucket, percentage)
+	return bucket < percentage, nil
+}
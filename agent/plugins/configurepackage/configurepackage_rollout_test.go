@@ -0,0 +1,139 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configurepackage implements the ConfigurePackage plugin.
+package configurepackage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	repoMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
+	serviceMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubInstanceID(instanceID string, err error) func() {
+	original := instanceIDProvider
+	instanceIDProvider = func() (string, error) { return instanceID, err }
+	return func() { instanceIDProvider = original }
+}
+
+func TestParseRolloutPercentage(t *testing.T) {
+	valid := map[interface{}]int{
+		nil:          100,
+		"":           100,
+		" 25 ":       25,
+		"0":          0,
+		float64(40):  40,
+		float64(100): 100,
+	}
+	for value, expected := range valid {
+		percentage, err := parseRolloutPercentage(value)
+		assert.NoError(t, err, "value %v", value)
+		assert.Equal(t, expected, percentage, "value %v", value)
+	}
+
+	for _, value := range []interface{}{"ten", "-1", "101", float64(12.5), float64(150), true} {
+		_, err := parseRolloutPercentage(value)
+		assert.Error(t, err, "value %v", value)
+	}
+}
+
+func TestRolloutBucket(t *testing.T) {
+	bucket := rolloutBucket("i-1234567890abcdef0", "PVDriver")
+	assert.Equal(t, bucket, rolloutBucket("i-1234567890abcdef0", "PVDriver"))
+	assert.True(t, bucket >= 0 && bucket < 100)
+
+	selected := 0
+	for i := 0; i < 1000; i++ {
+		if rolloutBucket(fmt.Sprintf("i-%017d", i), "PVDriver") < 25 {
+			selected++
+		}
+	}
+	assert.InDelta(t, 250, selected, 50)
+}
+
+func TestInRollout(t *testing.T) {
+	defer stubInstanceID("i-1234567890abcdef0", nil)()
+	tracer := trace.NewTracer(log.NewMockLog())
+	bucket := rolloutBucket("i-1234567890abcdef0", "PVDriver")
+
+	selected, err := inRollout(tracer, &ConfigurePackagePluginInput{Name: "PVDriver"})
+	assert.NoError(t, err)
+	assert.True(t, selected)
+
+	selected, err = inRollout(tracer, &ConfigurePackagePluginInput{Name: "PVDriver", RolloutPercentage: "0"})
+	assert.NoError(t, err)
+	assert.False(t, selected)
+
+	selected, err = inRollout(tracer, &ConfigurePackagePluginInput{Name: "PVDriver", RolloutPercentage: float64(bucket + 1)})
+	assert.NoError(t, err)
+	assert.True(t, selected)
+
+	selected, err = inRollout(tracer, &ConfigurePackagePluginInput{Name: "PVDriver", RolloutPercentage: float64(bucket)})
+	assert.NoError(t, err)
+	assert.False(t, selected)
+
+	// the salt replaces the package name
+	saltBucket := rolloutBucket("i-1234567890abcdef0", "wave-1")
+	selected, err = inRollout(tracer, &ConfigurePackagePluginInput{Name: "PVDriver", RolloutPercentage: float64(saltBucket + 1), RolloutSalt: "wave-1"})
+	assert.NoError(t, err)
+	assert.True(t, selected)
+}
+
+func TestInRolloutInstanceIDError(t *testing.T) {
+	defer stubInstanceID("", errors.New("no instance id"))()
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	_, err := inRollout(tracer, &ConfigurePackagePluginInput{Name: "PVDriver", RolloutPercentage: "50"})
+	assert.Error(t, err)
+}
+
+func TestValidateInput_RolloutPercentage(t *testing.T) {
+	input := ConfigurePackagePluginInput{Name: "PVDriver", Action: "Install", RolloutPercentage: "50"}
+	result, err := validateInput(&input)
+	assert.True(t, result)
+	assert.NoError(t, err)
+
+	input.RolloutPercentage = "200"
+	result, err = validateInput(&input)
+	assert.False(t, result)
+	assert.Error(t, err)
+}
+
+func TestExecuteOutsideRollout(t *testing.T) {
+	defer stubInstanceID("i-1234567890abcdef0", nil)()
+
+	pluginInformation := createStubPluginInputInstall()
+	pluginInformation.RolloutPercentage = "0"
+	mockRepo := repoMock.MockedRepository{}
+	mockService := serviceMock.Mock{}
+
+	plugin := &Plugin{
+		localRepository:        &mockRepo,
+		packageServiceSelector: selectMockService(&mockService),
+	}
+	output := createMockIOHandler()
+	plugin.execute(contextMock, buildConfigSimple(pluginInformation), createMockCancelFlag(), output)
+
+	output.(*iohandlermocks.MockIOHandler).AssertCalled(t, "SetStatus", contracts.ResultStatusSuccess)
+	output.(*iohandlermocks.MockIOHandler).AssertCalled(t, "SetResultField", rolloutSelectedField, false)
+	mockRepo.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+}
@@ -16,12 +16,16 @@
 package configurepackage
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	repoMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -67,6 +71,25 @@ func createStubPluginInputUninstallCurrent() *ConfigurePackagePluginInput {
 	return &input
 }
 
+func createStubPluginInputRollbackPrevious() *ConfigurePackagePluginInput {
+	input := ConfigurePackagePluginInput{}
+
+	input.Name = "PVDriver"
+	input.Action = "RollbackPrevious"
+
+	return &input
+}
+
+func createStubPluginInputSetActive() *ConfigurePackagePluginInput {
+	input := ConfigurePackagePluginInput{}
+
+	input.Name = "PVDriver"
+	input.Version = "0.0.1"
+	input.Action = "SetActive"
+
+	return &input
+}
+
 func createStubPluginInputFoo() *ConfigurePackagePluginInput {
 	input := ConfigurePackagePluginInput{}
 
@@ -180,6 +203,126 @@ func TestPrepareUpgrade(t *testing.T) {
 	installerMock.AssertExpectations(t)
 }
 
+func TestPrepareRollbackPrevious(t *testing.T) {
+	pluginInformation := createStubPluginInputRollbackPrevious()
+	repoMock := repoRollbackMock([]localpackages.InstalledVersion{{Version: "0.0.2"}, {Version: "0.0.1"}})
+	// the previous version is no longer in the repository and is downloaded again
+	repoMock.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.1").Return(errors.New("Package is incomplete")).Once()
+	repoMock.On("RefreshPackage", mock.Anything, mock.Anything, "0.0.1", mock.Anything, mock.Anything).Return(nil).Once()
+	repoMock.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.1").Return(nil).Once()
+	serviceMock := serviceUpgradeMock()
+	serviceMock.On("PackageServiceName").Return("ssms3")
+	tracer := trace.NewTracer(log.NewMockLog())
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	inst, uninst, installState, installedVersion := prepareConfigurePackage(
+		tracer,
+		buildConfigSimple(pluginInformation),
+		repoMock,
+		serviceMock,
+		pluginInformation,
+		"packageArn",
+		"0.0.2",
+		true,
+		output)
+
+	repoMock.AssertExpectations(t)
+	assert.Equal(t, "0.0.1", inst.Version())
+	assert.Equal(t, "0.0.2", uninst.Version())
+	assert.Equal(t, localpackages.Installed, installState)
+	assert.Equal(t, "0.0.2", installedVersion)
+	assert.Equal(t, 0, output.GetExitCode())
+	assert.Empty(t, tracer.ToPluginOutput().GetStderr())
+}
+
+func TestPrepareRollbackPreviousWithoutPreviousVersion(t *testing.T) {
+	pluginInformation := createStubPluginInputRollbackPrevious()
+	repoMock := repoRollbackMock([]localpackages.InstalledVersion{{Version: "0.0.2"}})
+	serviceMock := serviceUpgradeMock()
+	tracer := trace.NewTracer(log.NewMockLog())
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	inst, uninst, _, _ := prepareConfigurePackage(
+		tracer,
+		buildConfigSimple(pluginInformation),
+		repoMock,
+		serviceMock,
+		pluginInformation,
+		"packageArn",
+		"0.0.2",
+		true,
+		output)
+
+	assert.Nil(t, inst)
+	assert.Nil(t, uninst)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, tracer.ToPluginOutput().GetStderr(), "no version of packageArn was installed before 0.0.2")
+}
+
+func TestPrepareSetActive(t *testing.T) {
+	pluginInformation := createStubPluginInputSetActive()
+	repoMock := repoRollbackMock([]localpackages.InstalledVersion{{Version: "0.0.2"}, {Version: "0.0.1"}})
+	repoMock.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.1").Return(nil).Once()
+	// nothing is downloaded
+	serviceMock := serviceLocalOnlyMock()
+	tracer := trace.NewTracer(log.NewMockLog())
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	inst, uninst, installState, installedVersion := prepareConfigurePackage(
+		tracer,
+		buildConfigSimple(pluginInformation),
+		repoMock,
+		serviceMock,
+		pluginInformation,
+		"packageArn",
+		"0.0.1",
+		true,
+		output)
+
+	repoMock.AssertExpectations(t)
+	serviceMock.AssertExpectations(t)
+	assert.Equal(t, "0.0.1", inst.Version())
+	assert.Equal(t, "0.0.2", uninst.Version())
+	assert.Equal(t, localpackages.Installed, installState)
+	assert.Equal(t, "0.0.2", installedVersion)
+	assert.Empty(t, tracer.ToPluginOutput().GetStderr())
+}
+
+func TestPrepareSetActiveNotInRepository(t *testing.T) {
+	pluginInformation := createStubPluginInputSetActive()
+	repoMock := repoRollbackMock([]localpackages.InstalledVersion{{Version: "0.0.2"}})
+	repoMock.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.1").Return(errors.New("Package is incomplete")).Once()
+	serviceMock := serviceLocalOnlyMock()
+	tracer := trace.NewTracer(log.NewMockLog())
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	inst, uninst, _, _ := prepareConfigurePackage(
+		tracer,
+		buildConfigSimple(pluginInformation),
+		repoMock,
+		serviceMock,
+		pluginInformation,
+		"packageArn",
+		"0.0.1",
+		true,
+		output)
+
+	serviceMock.AssertExpectations(t)
+	assert.Nil(t, inst)
+	assert.Nil(t, uninst)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, tracer.ToPluginOutput().GetStderr(), "version 0.0.1 of packageArn is not available in the local repository")
+}
+
+func TestGetPreviousVersion(t *testing.T) {
+	history := []localpackages.InstalledVersion{{Version: "0.0.3"}, {Version: "0.0.2"}, {Version: "0.0.1"}}
+	assert.Equal(t, "0.0.2", getPreviousVersion(history, "0.0.3"))
+	// a failed install leaves the previous version installed
+	assert.Equal(t, "0.0.3", getPreviousVersion(history, "0.0.2"))
+	assert.Equal(t, "", getPreviousVersion(history[:1], "0.0.3"))
+	assert.Equal(t, "", getPreviousVersion(nil, "0.0.3"))
+}
+
 func TestPrepareUninstall(t *testing.T) {
 	// file stubs are needed for ensurePackage because it handles the unzip
 	stubs := setSuccessStubs()
@@ -377,7 +520,8 @@ func TestInstallingValid(t *testing.T) {
 	installerMock := installerSuccessMock(pluginInformation.Name, pluginInformation.Version)
 	uninstallerMock := installerNameVersionOnlyMock(pluginInformation.Name, pluginInformation.Version)
 	repoMock := repoInstallMock(pluginInformation, installerMock)
-	repoMock.On("RemovePackage", mock.Anything, pluginInformation.Name, pluginInformation.Version).Return(nil)
+	repoMock.On("GetInstallHistory", mock.Anything, pluginInformation.Name).Return([]localpackages.InstalledVersion{})
+	repoMock.On("RetainVersions", mock.Anything, pluginInformation.Name, []string{pluginInformation.Version, pluginInformation.Version}).Return(nil)
 	tracer := trace.NewTracer(log.NewMockLog())
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
@@ -456,6 +600,49 @@ func TestExecute(t *testing.T) {
 	serviceMock.AssertExpectations(t)
 }
 
+func TestExecuteSetsResult(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	pluginInformation := createStubPluginInputInstall()
+	installerMock := installerSuccessMock(pluginInformation.Name, pluginInformation.Version)
+	repoMock := repoInstallMock(pluginInformation, installerMock)
+	serviceMock := serviceSuccessMock()
+
+	plugin := &Plugin{
+		localRepository:        repoMock,
+		packageServiceSelector: selectMockService(serviceMock),
+	}
+	output := createMockIOHandler()
+	plugin.execute(contextMock, buildConfigSimple(pluginInformation), createMockCancelFlag(), output)
+
+	output.(*iohandlermocks.MockIOHandler).AssertCalled(t, "SetResultField", contracts.ResultFieldInstalledVersion, pluginInformation.Version)
+	output.(*iohandlermocks.MockIOHandler).AssertCalled(t, "SetResultField", contracts.ResultFieldRebootRequired, false)
+}
+
+func TestExecuteListInstalled(t *testing.T) {
+	installed := []localpackages.InstalledPackage{{Name: "PVDriver", Version: "0.0.2", Source: "ssms3"}}
+	mockRepo := repoMock.MockedRepository{}
+	mockRepo.On("ListInstalled", mock.Anything).Return(installed).Once()
+	// nothing is downloaded
+	mockService := serviceLocalOnlyMock()
+
+	plugin := &Plugin{
+		localRepository:        &mockRepo,
+		packageServiceSelector: selectMockService(mockService),
+	}
+	output := createMockIOHandler()
+	plugin.execute(contextMock, buildConfigSimple(&ConfigurePackagePluginInput{Action: "ListInstalled"}), createMockCancelFlag(), output)
+
+	mockRepo.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+	output.(*iohandlermocks.MockIOHandler).AssertCalled(t, "SetStatus", contracts.ResultStatusSuccess)
+	output.(*iohandlermocks.MockIOHandler).AssertCalled(t, "SetResultField", installedPackagesField, installed)
+	output.(*iohandlermocks.MockIOHandler).AssertCalled(t, "AppendInfo", mock.MatchedBy(func(stdout string) bool {
+		return strings.Contains(stdout, `"name": "PVDriver"`) && strings.Contains(stdout, `"source": "ssms3"`)
+	}))
+}
+
 func TestExecuteArrayInput(t *testing.T) {
 	pluginInformation := createStubPluginInputInstall()
 	installerMock := installerSuccessMock(pluginInformation.Name, pluginInformation.Version)
@@ -526,19 +713,41 @@ func TestValidateInput(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestValidateInput_InstallationType(t *testing.T) {
+	input := ConfigurePackagePluginInput{Name: "PVDriver", Action: "Install"}
+
+	result, err := validateInput(&input)
+	assert.True(t, result)
+	assert.NoError(t, err)
+	assert.Equal(t, InstallationTypeUninstallReinstall, input.InstallationType)
+
+	input.InstallationType = InstallationTypeInPlaceUpdate
+	result, err = validateInput(&input)
+	assert.True(t, result)
+	assert.NoError(t, err)
+
+	input.InstallationType = "Replace"
+	result, err = validateInput(&input)
+	assert.False(t, result)
+	assert.Contains(t, err.Error(), "unsupported installation type")
+}
+
 func TestValidateInput_Source(t *testing.T) {
 	input := ConfigurePackagePluginInput{}
 
 	input.Version = "1.0.0"
 	input.Name = "PVDriver"
 	input.Action = "Install"
-	input.Source = "http://amazon.com"
+	input.Source = "https://s3.{Region}.amazonaws.com/my-packages/{PackageName}/{Platform}/{Arch}"
 
 	result, err := validateInput(&input)
+	assert.True(t, result)
+	assert.NoError(t, err)
 
+	input.Source = "http://amazon.com"
+	result, err = validateInput(&input)
 	assert.False(t, result)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "source parameter is not supported")
+	assert.Contains(t, err.Error(), "is not an https url")
 }
 
 func TestValidateInput_NameEmpty(t *testing.T) {
@@ -605,6 +814,45 @@ func TestValidateInput_EmptyVersionWithInstall(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestValidateInput_VersionWithRollbackPrevious(t *testing.T) {
+	input := createStubPluginInputRollbackPrevious()
+
+	result, err := validateInput(input)
+	assert.True(t, result)
+	assert.NoError(t, err)
+
+	input.Version = "0.0.1"
+	result, err = validateInput(input)
+	assert.False(t, result)
+	assert.Error(t, err)
+}
+
+func TestValidateInput_ListInstalledWithoutName(t *testing.T) {
+	input := ConfigurePackagePluginInput{Action: "ListInstalled"}
+
+	result, err := validateInput(&input)
+	assert.True(t, result)
+	assert.NoError(t, err)
+}
+
+func TestValidateInput_VersionWithSetActive(t *testing.T) {
+	input := createStubPluginInputSetActive()
+
+	result, err := validateInput(input)
+	assert.True(t, result)
+	assert.NoError(t, err)
+
+	input.Version = "latest"
+	result, err = validateInput(input)
+	assert.False(t, result)
+	assert.Error(t, err)
+
+	input.Version = ""
+	result, err = validateInput(input)
+	assert.False(t, result)
+	assert.Error(t, err)
+}
+
 func TestValidateInput_EmptyVersionWithUninstall(t *testing.T) {
 	input := ConfigurePackagePluginInput{}
 
@@ -32,6 +32,7 @@ import (
 	serviceMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -74,6 +75,17 @@ func repoUpgradeMock(pluginInformation *ConfigurePackagePluginInput, installerMo
 	return &mockRepo
 }
 
+func repoRollbackMock(history []localpackages.InstalledVersion) *repoMock.MockedRepository {
+	mockRepo := repoMock.MockedRepository{}
+	mockRepo.On("GetInstalledVersion", mock.Anything, mock.Anything).Return("0.0.2")
+	mockRepo.On("GetInstallState", mock.Anything, mock.Anything).Return(localpackages.Installed, "0.0.2")
+	mockRepo.On("GetInstallHistory", mock.Anything, mock.Anything).Return(history)
+	mockRepo.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.2").Return(nil)
+	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.1").Return(installerNameVersionOnlyMock("PVDriver", "0.0.1"))
+	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.2").Return(installerNameVersionOnlyMock("PVDriver", "0.0.2"))
+	return &mockRepo
+}
+
 func repoUninstallMock(pluginInformation *ConfigurePackagePluginInput, installerMock installer.Installer) *repoMock.MockedRepository {
 	mockRepo := repoMock.MockedRepository{}
 	mockRepo.On("GetInstalledVersion", mock.Anything, mock.Anything).Return("0.0.1")
@@ -102,6 +114,23 @@ func installerSuccessMock(packageName string, version string) *installerMock.Moc
 	return &mockInst
 }
 
+func updaterSuccessMock(packageName string, version string) *installerMock.Mock {
+	mockInst := installerMock.Mock{}
+	mockInst.On("Update", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusSuccess)).Once()
+	mockInst.On("Validate", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusSuccess)).Once()
+	mockInst.On("PackageName").Return(packageName)
+	mockInst.On("Version").Return(version)
+	return &mockInst
+}
+
+func updaterFailedMock(packageName string, version string) *installerMock.Mock {
+	mockInst := installerMock.Mock{}
+	mockInst.On("Update", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusFailed)).Once()
+	mockInst.On("PackageName").Return(packageName)
+	mockInst.On("Version").Return(version)
+	return &mockInst
+}
+
 func installerRebootMock(packageName string, version string) *installerMock.Mock {
 	mockInst := installerMock.Mock{}
 	mockInst.On("Install", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusSuccessAndReboot)).Once()
@@ -190,8 +219,8 @@ func installerNotCalledMock() *installerMock.Mock {
 	return &installerMock.Mock{}
 }
 
-func selectMockService(service packageservice.PackageService) func(tracer trace.Tracer, repository string, localrepo localpackages.Repository) packageservice.PackageService {
-	return func(tracer trace.Tracer, repository string, localrepo localpackages.Repository) packageservice.PackageService {
+func selectMockService(service packageservice.PackageService) func(tracer trace.Tracer, repository string, source string, localrepo localpackages.Repository, policy verification.Policy) packageservice.PackageService {
+	return func(tracer trace.Tracer, repository string, source string, localrepo localpackages.Repository, policy verification.Policy) packageservice.PackageService {
 		return service
 	}
 }
@@ -228,6 +257,11 @@ func serviceUpgradeMock() *serviceMock.Mock {
 	return &mockService
 }
 
+// serviceLocalOnlyMock fails on any call, for the actions which only use the local repository
+func serviceLocalOnlyMock() *serviceMock.Mock {
+	return &serviceMock.Mock{}
+}
+
 func createMockCancelFlag() task.CancelFlag {
 	mockCancelFlag := new(task.MockCancelFlag)
 	// Setup mocks
@@ -245,6 +279,7 @@ func createMockIOHandler() iohandler.IOHandler {
 	mockIOHandler.On("SetStatus", mock.Anything).Return()
 	mockIOHandler.On("AppendInfo", mock.Anything).Return()
 	mockIOHandler.On("AppendError", mock.Anything).Return()
+	mockIOHandler.On("SetResultField", mock.Anything, mock.Anything).Return()
 
 	return mockIOHandler
 }
@@ -285,6 +320,11 @@ type FileSysDepStub struct {
 	uncompressError error
 	removeError     error
 	writeError      error
+	readContent     []byte
+	readError       error
+	copyError       error
+	hashes          map[string]string
+	hashError       error
 }
 
 func (m *FileSysDepStub) MakeDirExecute(destinationDir string) (err error) {
@@ -302,3 +342,15 @@ func (m *FileSysDepStub) RemoveAll(path string) error {
 func (m *FileSysDepStub) WriteFile(filename string, content string) error {
 	return m.writeError
 }
+
+func (m *FileSysDepStub) ReadFile(filename string) ([]byte, error) {
+	return m.readContent, m.readError
+}
+
+func (m *FileSysDepStub) CopyDirectory(srcPath string, destPath string) error {
+	return m.copyError
+}
+
+func (m *FileSysDepStub) HashFiles(srcPath string) (map[string]string, error) {
+	return m.hashes, m.hashError
+}
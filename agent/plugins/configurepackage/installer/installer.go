@@ -25,6 +25,7 @@ import (
 type Installer interface {
 	Install(tracer trace.Tracer, context context.T) contracts.PluginOutputter
 	Uninstall(tracer trace.Tracer, context context.T) contracts.PluginOutputter
+	Update(tracer trace.Tracer, context context.T) contracts.PluginOutputter
 	Validate(tracer trace.Tracer, context context.T) contracts.PluginOutputter // TODO:MF consider whether we can remove validate in V1 - I think it depends on having truly idempotent installers for anything that reboots
 	PackageName() string
 	Version() string
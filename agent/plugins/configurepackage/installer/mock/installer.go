@@ -36,6 +36,11 @@ func (inst *Mock) Uninstall(tracer trace.Tracer, context context.T) contracts.Pl
 	return args.Get(0).(contracts.PluginOutputter)
 }
 
+func (inst *Mock) Update(tracer trace.Tracer, context context.T) contracts.PluginOutputter {
+	args := inst.Called(context)
+	return args.Get(0).(contracts.PluginOutputter)
+}
+
 func (inst *Mock) Validate(tracer trace.Tracer, context context.T) contracts.PluginOutputter {
 	args := inst.Called(context)
 	return args.Get(0).(contracts.PluginOutputter)
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -54,6 +55,7 @@ const (
 	Installed         InstallState = iota // Successfully installed version of a package
 	RollbackUninstall InstallState = iota // Uninstalling as part of rollback
 	RollbackInstall   InstallState = iota // Installing as part of rollback
+	Updating          InstallState = iota // Package version being updated in place over the previous version
 )
 
 // Repository represents local storage for packages managed by configurePackage
@@ -66,7 +68,11 @@ type Repository interface {
 	SetInstallState(tracer trace.Tracer, packageArn string, version string, state InstallState) error
 	GetInstallState(tracer trace.Tracer, packageArn string) (state InstallState, version string)
 	RemovePackage(tracer trace.Tracer, packageArn string, version string) error
+	RetainVersions(tracer trace.Tracer, packageArn string, versions ...string) error
+	GetInstallHistory(tracer trace.Tracer, packageArn string) []InstalledVersion
 	GetInventoryData(log log.T) []model.ApplicationData
+	ListInstalled(tracer trace.Tracer) []InstalledPackage
+	GetPackageVersionPath(tracer trace.Tracer, packageArn string, version string) string
 	GetInstaller(tracer trace.Tracer, configuration contracts.Configuration, packageArn string, version string) installer.Installer
 
 	LockPackage(tracer trace.Tracer, packageArn string, action string) error
@@ -100,8 +106,19 @@ type PackageInstallState struct {
 	RetryCount           int          `json:"retrycount"`
 }
 
+// InstalledPackage describes a package installed from the repository
+type InstalledPackage struct {
+	Name          string    `json:"name"`
+	Version       string    `json:"version"`
+	InstalledTime time.Time `json:"installedtime"`
+	// Source is the package service the installed version was downloaded from
+	Source  string             `json:"source,omitempty"`
+	History []InstalledVersion `json:"history,omitempty"`
+}
+
 // PackageManifest represents json structure of package's online configuration file.
 type PackageManifest struct {
+	SchemaVersion   string `json:"schemaVersion,omitempty"` // optional, the schema the manifest is validated against
 	Name            string `json:"name"`
 	Platform        string `json:"platform"`
 	Architecture    string `json:"architecture"`
@@ -110,8 +127,16 @@ type PackageManifest struct {
 	AppPublisher    string `json:"apppublisher"`    // optional inventory attribute
 	AppReferenceURL string `json:"appreferenceurl"` // optional inventory attribute
 	AppType         string `json:"apptype"`         // optional inventory attribute
+
+	// Install parameters exported to the package scripts, the platform parameters
+	// overriding them by platform name -> platform version -> architecture
+	Parameters         map[string]string                                  `json:"parameters,omitempty"`
+	PlatformParameters map[string]map[string]map[string]map[string]string `json:"platformparameters,omitempty"`
 }
 
+// parameterNameRegExpValidator restricts parameter names to names usable in environment variables
+var parameterNameRegExpValidator = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 type localRepository struct {
 	filesysdep        FileSysDep
 	repoRoot          string
@@ -143,11 +168,21 @@ func (repo *localRepository) GetInstaller(tracer trace.Tracer,
 
 	// Give each version an independent orchestration directory to support install and uninstall for two versions during rollback
 	configuration.OrchestrationDirectory = filepath.Join(configuration.OrchestrationDirectory, normalizeDirectory(version))
+
+	// The manifest is validated before the package is installed, a manifest which can't be read has no parameters
+	var parameters ssminstaller.Parameters
+	if manifest, err := repo.openPackageManifest(tracer, repo.filesysdep, packageArn, version); err == nil {
+		parameters = ssminstaller.Parameters{
+			Default:   manifest.Parameters,
+			Platforms: manifest.PlatformParameters,
+		}
+	}
 	return ssminstaller.New(packageArn,
 		version,
 		repo.getPackageVersionPath(tracer, packageArn, version),
 		configuration,
-		&envdetect.CollectorImp{})
+		&envdetect.CollectorImp{},
+		parameters)
 }
 
 // GetInstalledVersion returns the version of the last successfully installed package
@@ -187,11 +222,32 @@ func (repo *localRepository) ValidatePackage(tracer trace.Tracer, packageArn str
 		return fmt.Errorf("Package manifest is invalid: %v", err)
 	}
 
-	hasContent := false
-
 	packageVersionPath := repo.getPackageVersionPath(tracer, packageArn, version)
 	trace.AppendDebugf("package version path for package %v version %v is %v", packageArn, version, packageVersionPath)
 
+	// Package versions in the registry are validated against the content recorded when they were downloaded,
+	// the versions downloaded before the registry or without a recorded content fall back to inspecting the package directory
+	record := repo.loadComponentRecord(repo.filesysdep, tracer, packageArn)
+	if verified, err := repo.verifyArtifact(record, packageArn, version, packageVersionPath); err != nil {
+		trace.WithError(err).End()
+		return err
+	} else if !verified && !repo.hasPackageContent(trace, packageVersionPath) {
+		trace.End()
+		return fmt.Errorf("Package is incomplete")
+	}
+
+	// This is necessary to make sure pre-birdwatcher packages are deemed unsupported, triggering package refresh to birdwatched version of the package.
+	if err := repo.checkPackageIsSupported(tracer, packageArn, version); err != nil {
+		trace.WithError(err).End()
+		return err
+	}
+
+	trace.End()
+	return nil
+}
+
+// hasPackageContent returns true if a package version directory contains anything besides the manifest
+func (repo *localRepository) hasPackageContent(trace *trace.Trace, packageVersionPath string) bool {
 	files, errFiles := repo.filesysdep.GetFileNames(packageVersionPath)
 	if errFiles != nil {
 		trace.WithError(errFiles)
@@ -207,24 +263,7 @@ func (repo *localRepository) ValidatePackage(tracer trace.Tracer, packageArn str
 	}
 
 	// Ensure that at least one other file or folder is present
-	if errFiles == nil && len(files) > 1 {
-		hasContent = true
-	} else if errDirs == nil && len(dirs) > 0 {
-		hasContent = true
-	}
-
-	if !hasContent {
-		return fmt.Errorf("Package is incomplete")
-	}
-
-	// This is necessary to make sure pre-birdwatcher packages are deemed unsupported, triggering package refresh to birdwatched version of the package.
-	if err := repo.checkPackageIsSupported(tracer, packageArn, version); err != nil {
-		trace.WithError(err).End()
-		return err
-	}
-
-	trace.End()
-	return nil
+	return (errFiles == nil && len(files) > 1) || (errDirs == nil && len(dirs) > 0)
 }
 
 // RefreshPackage updates the package binaries.  Used if ValidatePackage returns an error, initially same implementation as AddPackage
@@ -235,42 +274,49 @@ func (repo *localRepository) RefreshPackage(tracer trace.Tracer, packageArn stri
 // AddPackage creates an entry in the repository and downloads artifacts for a package
 func (repo *localRepository) AddPackage(tracer trace.Tracer, packageArn string, version string, packageServiceName string, downloader DownloadDelegate) error {
 	packagePath := repo.getPackageVersionPath(tracer, packageArn, version)
+	// Forget the previous content first so an interrupted download is never taken for a valid package
+	if err := repo.forgetArtifact(tracer, packageArn, version); err != nil {
+		return err
+	}
 	if err := repo.filesysdep.MakeDirExecute(packagePath); err != nil {
 		return err
 	}
 	if err := downloader(tracer, packagePath); err != nil {
 		return err
 	}
+	checksum, err := repo.filesysdep.HashDirectory(packagePath)
+	if err != nil {
+		return err
+	}
 	// if no previous version, set state to new
-	repo.SetInstallState(tracer, packageArn, version, New)
-
-	return nil
+	packageState, err := repo.recordArtifact(tracer, packageArn, version, checksum, packageServiceName)
+	if err != nil || packageState == nil {
+		return err
+	}
+	return repo.writeLegacyInstallState(packageArn, packageState)
 }
 
 // SetInstallState flags the state of a version of a package downloaded to the repository for installation
 func (repo *localRepository) SetInstallState(tracer trace.Tracer, packageArn string, version string, state InstallState) error {
-	var packageState = repo.loadInstallState(repo.filesysdep, tracer, packageArn)
-	if state == New && packageState.State != None {
-		return nil
-	}
-	packageState.Version = version
-	packageState.Time = time.Now()
-	if packageState.State == state {
-		packageState.RetryCount++
-	} else {
-		packageState.RetryCount = 0
-	}
-	packageState.State = state
-	if state == Installed {
-		packageState.LastInstalledVersion = version
-	}
-	if state == Uninstalled {
-		packageState.LastInstalledVersion = ""
+	var packageState *PackageInstallState
+	err := repo.updateRegistry(tracer, func(registry *componentRegistry) bool {
+		record := repo.getOrAddComponentRecord(registry, tracer, packageArn)
+		if !record.setState(version, state) {
+			return false
+		}
+		packageState = record.installState()
+		return true
+	})
+	if err != nil || packageState == nil {
+		return err
 	}
+	return repo.writeLegacyInstallState(packageArn, packageState)
+}
 
-	var installStateContent string
-	var err error
-	if installStateContent, err = jsonutil.Marshal(packageState); err != nil {
+// writeLegacyInstallState keeps the installstate file in step with the registry so an older agent reads the same state after a downgrade
+func (repo *localRepository) writeLegacyInstallState(packageArn string, packageState *PackageInstallState) error {
+	installStateContent, err := jsonutil.Marshal(packageState)
+	if err != nil {
 		return err
 	}
 	return repo.filesysdep.WriteFile(repo.getInstallStatePath(packageArn), installStateContent)
@@ -284,9 +330,44 @@ func (repo *localRepository) GetInstallState(tracer trace.Tracer, packageArn str
 
 // RemovePackage deletes an entry in the repository and removes package artifacts
 func (repo *localRepository) RemovePackage(tracer trace.Tracer, packageArn string, version string) error {
+	if err := repo.forgetArtifact(tracer, packageArn, version); err != nil {
+		return err
+	}
 	return repo.filesysdep.RemoveAll(repo.getPackageVersionPath(tracer, packageArn, version))
 }
 
+// RetainVersions removes the content of every version of a package but the given ones
+func (repo *localRepository) RetainVersions(tracer trace.Tracer, packageArn string, versions ...string) error {
+	keep := make(map[string]bool, len(versions))
+	for _, version := range versions {
+		keep[normalizeDirectory(version)] = true
+	}
+	packageRoot := repo.getPackageRoot(packageArn)
+	directoryNames, err := repo.filesysdep.GetDirectoryNames(packageRoot)
+	if err != nil {
+		return err
+	}
+	if err = repo.forgetOtherArtifacts(tracer, packageArn, keep); err != nil {
+		return err
+	}
+	for _, directoryName := range directoryNames {
+		if !keep[directoryName] {
+			if err = repo.filesysdep.RemoveAll(filepath.Join(packageRoot, directoryName)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetInstallHistory returns the last versions of a package which were successfully installed, most recent first
+func (repo *localRepository) GetInstallHistory(tracer trace.Tracer, packageArn string) []InstalledVersion {
+	if record := repo.loadComponentRecord(repo.filesysdep, tracer, packageArn); record != nil {
+		return record.History
+	}
+	return newComponentRecord(packageArn, repo.loadLegacyInstallState(repo.filesysdep, tracer, packageArn)).History
+}
+
 // GetInventoryData returns ApplicationData for every successfully and currently installed package in the repository
 // that has inventory fields in its manifest
 func (repo *localRepository) GetInventoryData(log log.T) []model.ApplicationData {
@@ -303,9 +384,15 @@ func (repo *localRepository) GetInventoryData(log log.T) []model.ApplicationData
 		return result
 	}
 
+	registry, registryErr := repo.readRegistry(repo.filesysdep)
+	if registryErr != nil {
+		tracer.CurrentTrace().AppendErrorf("%v", registryErr)
+		registry = &componentRegistry{Components: make(map[string]*componentRecord)}
+	}
+
 	for _, packageDirectoryName := range dirs {
 		var packageState *PackageInstallState
-		if packageState = repo.loadInstallStateByDirectoryName(repo.filesysdep, tracer, packageDirectoryName); packageState == nil || packageState.State != Installed {
+		if packageState = repo.loadInstallStateByDirectoryName(repo.filesysdep, tracer, registry, packageDirectoryName); packageState == nil || packageState.State != Installed {
 			continue
 		}
 		// NOTE: We could put inventory info in the installstate file.  That might be simpler than opening two files in this method.
@@ -319,6 +406,46 @@ func (repo *localRepository) GetInventoryData(log log.T) []model.ApplicationData
 	return result
 }
 
+// ListInstalled returns every package installed from the repository, with the versions kept side by side with the installed one
+func (repo *localRepository) ListInstalled(tracer trace.Tracer) []InstalledPackage {
+	result := make([]InstalledPackage, 0)
+
+	dirs, err := repo.filesysdep.GetDirectoryNames(repo.repoRoot)
+	if err != nil {
+		tracer.CurrentTrace().AppendErrorf("%v", err)
+		return result
+	}
+
+	registry, err := repo.readRegistry(repo.filesysdep)
+	if err != nil {
+		tracer.CurrentTrace().AppendErrorf("%v", err)
+		registry = &componentRegistry{Components: make(map[string]*componentRecord)}
+	}
+
+	for _, packageDirectoryName := range dirs {
+		var packageState *PackageInstallState
+		if packageState = repo.loadInstallStateByDirectoryName(repo.filesysdep, tracer, registry, packageDirectoryName); packageState == nil || packageState.State != Installed {
+			continue
+		}
+		installed := InstalledPackage{
+			Name:          packageState.Name,
+			Version:       packageState.Version,
+			InstalledTime: packageState.Time,
+		}
+		// packages installed before the registry only have their install state
+		if record, ok := registry.Components[packageDirectoryName]; ok {
+			installed.InstalledTime = record.InstalledTime
+			installed.History = record.History
+			if artifact, ok := record.Artifacts[record.Version]; ok {
+				installed.Source = artifact.Source
+			}
+		}
+		result = append(result, installed)
+	}
+
+	return result
+}
+
 // manifest cache
 
 // filePath will return the manifest file path for a package name and package version
@@ -393,6 +520,11 @@ func (repo *localRepository) getInstallStatePathByDirectoryName(directoryName st
 	return filepath.Join(repo.getPackageRootByDirectoryName(directoryName), "installstate")
 }
 
+// GetPackageVersionPath returns the directory holding the content of the given version of a package
+func (repo *localRepository) GetPackageVersionPath(tracer trace.Tracer, packageArn string, version string) string {
+	return repo.getPackageVersionPath(tracer, packageArn, version)
+}
+
 // getPackageVersionPath is a helper function that builds a path to the directory containing the given version of a package
 func (repo *localRepository) getPackageVersionPath(tracer trace.Tracer, packageArn string, version string) string {
 	return filepath.Join(repo.getPackageRoot(packageArn), normalizeDirectory(version))
@@ -407,14 +539,25 @@ func (repo *localRepository) getTracesPath(packageArn string) string {
 	return filepath.Join(repo.getPackageRoot(packageArn), "traces")
 }
 
-// loadInstallState loads the existing installstate file or returns an appropriate default state
+// loadInstallState loads the package state from the registry, falling back to the installstate file for packages not yet in the registry
 func (repo *localRepository) loadInstallState(filesysdep FileSysDep, tracer trace.Tracer, packageArn string) *PackageInstallState {
+	if record := repo.loadComponentRecord(filesysdep, tracer, packageArn); record != nil {
+		return record.installState()
+	}
+	return repo.loadLegacyInstallState(filesysdep, tracer, packageArn)
+}
+
+// loadLegacyInstallState loads the existing installstate file or returns an appropriate default state
+func (repo *localRepository) loadLegacyInstallState(filesysdep FileSysDep, tracer trace.Tracer, packageArn string) *PackageInstallState {
 	var filePath = repo.getInstallStatePath(packageArn)
 	return repo.parseInstallState(tracer, filesysdep, filePath, packageArn)
 }
 
-// loadInstallState loads the existing installstate file given a package folder name or returns an appropriate default state
-func (repo *localRepository) loadInstallStateByDirectoryName(filesysdep FileSysDep, tracer trace.Tracer, packageDirectoryName string) *PackageInstallState {
+// loadInstallState loads the package state given a package folder name or returns an appropriate default state
+func (repo *localRepository) loadInstallStateByDirectoryName(filesysdep FileSysDep, tracer trace.Tracer, registry *componentRegistry, packageDirectoryName string) *PackageInstallState {
+	if record, ok := registry.Components[packageDirectoryName]; ok {
+		return record.installState()
+	}
 	var filePath = repo.getInstallStatePathByDirectoryName(packageDirectoryName)
 	return repo.parseInstallState(tracer, filesysdep, filePath, "")
 }
@@ -492,6 +635,16 @@ func parsePackageManifest(tracer trace.Tracer, filesysdep FileSysDep, filePath s
 		return nil, err
 	}
 
+	// ensure the manifest matches its schema before reading it
+	warnings, err := validateManifestSchema(result)
+	if err != nil {
+		trace.WithError(err).End()
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		trace.AppendInfof("manifest of %v %v has fields unknown to its schema: %v", packageArn, version, strings.Join(warnings, "; "))
+	}
+
 	// parse package's JSON configuration file
 	if err = json.Unmarshal(result, &parsedManifest); err != nil {
 		trace.WithError(err).End()
@@ -527,6 +680,28 @@ func validatePackageManifest(parsedManifest *PackageManifest, packageArn string,
 			return fmt.Errorf("manifest version (%v) does not match expected package version (%v)", manifestVersion, version)
 		}
 	}
+	if err := validateParameterNames(parsedManifest.Parameters); err != nil {
+		return err
+	}
+	for _, versions := range parsedManifest.PlatformParameters {
+		for _, architectures := range versions {
+			for _, parameters := range architectures {
+				if err := validateParameterNames(parameters); err != nil {
+					return err
+				}
+			}
+		}
+	}
 
 	return nil
 }
+
+// validateParameterNames ensures the parameters can be exported to the package scripts as environment variables
+func validateParameterNames(parameters map[string]string) error {
+	for name := range parameters {
+		if !parameterNameRegExpValidator.MatchString(name) {
+			return fmt.Errorf("invalid parameter name (%v)", name)
+		}
+	}
+	return nil
+}
@@ -16,8 +16,13 @@
 package localpackages
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 )
@@ -31,6 +36,8 @@ type FileSysDep interface {
 	RemoveAll(path string) error
 	ReadFile(filename string) ([]byte, error)
 	WriteFile(filename string, content string) error
+	Rename(oldPath string, newPath string) error
+	HashDirectory(srcPath string) (string, error)
 }
 
 type fileSysDepImp struct{}
@@ -62,3 +69,36 @@ func (fileSysDepImp) ReadFile(filename string) ([]byte, error) {
 func (fileSysDepImp) WriteFile(filename string, content string) error {
 	return fileutil.WriteAllText(filename, content)
 }
+
+func (fileSysDepImp) Rename(oldPath string, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+// HashDirectory returns a sha256 checksum over the relative path, size and content of every regular file under srcPath
+func (fileSysDepImp) HashDirectory(srcPath string) (string, error) {
+	hash := sha256.New()
+	err := filepath.Walk(srcPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(srcPath, filePath)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(relPath), info.Size())
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(hash, file)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,337 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localpackages implements the local storage for packages managed by the ConfigurePackage plugin.
+package localpackages
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+// Normalized package directory names never start with an underscore unless they carry a hash suffix,
+// so these names cannot collide with a package directory or package lock file
+const (
+	registryFileName             = "_registry.json"
+	registryLockFileName         = "_registry.lockfile"
+	registryLockTimeoutInSeconds = 60 // a lock older than this was left behind by a crashed process
+	installHistoryLength         = 5  // number of installed versions remembered per package
+)
+
+// registryLockWait bounds how long an update waits for another process to release the registry
+var registryLockWait = 2 * time.Minute
+
+// registryLockRetryInterval is the pause between attempts to take the registry lock
+var registryLockRetryInterval = 100 * time.Millisecond
+
+// Serialize registry updates within the process, the file lock only arbitrates between processes
+var lockRegistry = &sync.Mutex{}
+
+// componentRegistry represents the json structure of the registry of packages in the repository
+type componentRegistry struct {
+	Components map[string]*componentRecord `json:"components"`
+}
+
+// componentRecord is the registry entry for a package, keyed by the package's directory name
+type componentRecord struct {
+	Name                 string                     `json:"name"`
+	Version              string                     `json:"version"`
+	State                InstallState               `json:"state"`
+	Time                 time.Time                  `json:"time"`
+	LastInstalledVersion string                     `json:"lastinstalledversion"`
+	RetryCount           int                        `json:"retrycount"`
+	InstalledTime        time.Time                  `json:"installedtime"`
+	Artifacts            map[string]*artifactRecord `json:"artifacts"`
+	History              []InstalledVersion         `json:"history"`
+}
+
+// InstalledVersion is a version of a package which was successfully installed
+type InstalledVersion struct {
+	Version       string    `json:"version"`
+	InstalledTime time.Time `json:"installedtime"`
+}
+
+// artifactRecord describes the downloaded content of a version of a package
+type artifactRecord struct {
+	Checksum       string    `json:"checksum"`
+	Source         string    `json:"source"`
+	DownloadedTime time.Time `json:"downloadedtime"`
+}
+
+// newComponentRecord creates a registry entry from install state read from a legacy installstate file
+func newComponentRecord(packageArn string, legacyState *PackageInstallState) *componentRecord {
+	record := &componentRecord{
+		Name:                 packageArn,
+		Version:              legacyState.Version,
+		State:                legacyState.State,
+		Time:                 legacyState.Time,
+		LastInstalledVersion: legacyState.LastInstalledVersion,
+		RetryCount:           legacyState.RetryCount,
+		Artifacts:            make(map[string]*artifactRecord),
+	}
+	installedVersion := record.LastInstalledVersion
+	if record.State == Installed {
+		record.InstalledTime = record.Time
+		installedVersion = record.Version
+	}
+	if installedVersion != "" {
+		record.History = []InstalledVersion{{Version: installedVersion, InstalledTime: record.InstalledTime}}
+	}
+	return record
+}
+
+// installState returns the install state of the package described by the record
+func (record *componentRecord) installState() *PackageInstallState {
+	return &PackageInstallState{
+		Name:                 record.Name,
+		Version:              record.Version,
+		State:                record.State,
+		Time:                 record.Time,
+		LastInstalledVersion: record.LastInstalledVersion,
+		RetryCount:           record.RetryCount,
+	}
+}
+
+// setState moves the record to a new install state, returning false if the transition is ignored
+func (record *componentRecord) setState(version string, state InstallState) bool {
+	if state == New && record.State != None {
+		return false
+	}
+	record.Version = version
+	record.Time = time.Now()
+	if record.State == state {
+		record.RetryCount++
+	} else {
+		record.RetryCount = 0
+	}
+	record.State = state
+	if state == Installed {
+		record.LastInstalledVersion = version
+		record.InstalledTime = record.Time
+		record.addInstallHistory(version)
+	}
+	if state == Uninstalled {
+		record.LastInstalledVersion = ""
+	}
+	return true
+}
+
+// addInstallHistory moves an installed version to the front of the history of the package, most recent first,
+// keeping the last installHistoryLength versions
+func (record *componentRecord) addInstallHistory(version string) {
+	if len(record.History) > 0 && record.History[0].Version == version {
+		return
+	}
+	history := []InstalledVersion{{Version: version, InstalledTime: record.InstalledTime}}
+	for _, installed := range record.History {
+		if installed.Version != version && len(history) < installHistoryLength {
+			history = append(history, installed)
+		}
+	}
+	record.History = history
+}
+
+// getRegistryPath is a helper function that builds the path to the registry file
+func (repo *localRepository) getRegistryPath() string {
+	return filepath.Join(repo.repoRoot, registryFileName)
+}
+
+// getRegistryLockPath is a helper function that builds the path to the lock file guarding the registry
+func (repo *localRepository) getRegistryLockPath() string {
+	return filepath.Join(repo.lockRoot, registryLockFileName)
+}
+
+// readRegistry loads the registry, returning an empty registry if none has been persisted yet
+func (repo *localRepository) readRegistry(filesysdep FileSysDep) (*componentRegistry, error) {
+	registry := &componentRegistry{}
+	registryPath := repo.getRegistryPath()
+	if filesysdep.Exists(registryPath) {
+		content, err := filesysdep.ReadFile(registryPath)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(content, registry); err != nil {
+			return nil, fmt.Errorf("Package registry is invalid: %v", err)
+		}
+	}
+	if registry.Components == nil {
+		registry.Components = make(map[string]*componentRecord)
+	}
+	return registry, nil
+}
+
+// loadComponentRecord returns the registry entry for a package or nil if the package is not in the registry
+func (repo *localRepository) loadComponentRecord(filesysdep FileSysDep, tracer trace.Tracer, packageArn string) *componentRecord {
+	registry, err := repo.readRegistry(filesysdep)
+	if err != nil {
+		tracer.BeginSection("Load package registry").WithError(err).End()
+		return nil
+	}
+	return registry.Components[normalizeDirectory(packageArn)]
+}
+
+// getOrAddComponentRecord returns the registry entry for a package, seeding it from the legacy installstate file if necessary
+func (repo *localRepository) getOrAddComponentRecord(registry *componentRegistry, tracer trace.Tracer, packageArn string) *componentRecord {
+	key := normalizeDirectory(packageArn)
+	record, ok := registry.Components[key]
+	if !ok {
+		record = newComponentRecord(packageArn, repo.loadLegacyInstallState(repo.filesysdep, tracer, packageArn))
+		registry.Components[key] = record
+	}
+	if record.Artifacts == nil {
+		record.Artifacts = make(map[string]*artifactRecord)
+	}
+	return record
+}
+
+// updateRegistry applies update to the registry while holding the registry lock and atomically persists the result
+// update returns false if it made no change that needs to be persisted
+func (repo *localRepository) updateRegistry(tracer trace.Tracer, update func(registry *componentRegistry) bool) error {
+	lockRegistry.Lock()
+	defer lockRegistry.Unlock()
+
+	lockPath := repo.getRegistryLockPath()
+	ownerId := filelock.GetOwnerIdForProcess()
+	if err := repo.lockRegistryFile(lockPath, ownerId); err != nil {
+		return err
+	}
+	defer repo.fileLocker.Unlock(lockPath, ownerId)
+
+	registry, err := repo.readRegistry(repo.filesysdep)
+	if err != nil {
+		// Entries dropped here are seeded again from the legacy installstate files as packages are used
+		tracer.BeginSection("Discard invalid package registry").WithError(err).End()
+		registry = &componentRegistry{Components: make(map[string]*componentRecord)}
+	}
+	if !update(registry) {
+		return nil
+	}
+
+	var content string
+	if content, err = jsonutil.Marshal(registry); err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it over the registry so a crash never leaves a partially written registry
+	registryPath := repo.getRegistryPath()
+	tempPath := registryPath + ".tmp"
+	if err = repo.filesysdep.WriteFile(tempPath, content); err != nil {
+		return err
+	}
+	return repo.filesysdep.Rename(tempPath, registryPath)
+}
+
+// lockRegistryFile waits until the registry file lock is acquired or registryLockWait elapses
+func (repo *localRepository) lockRegistryFile(lockPath string, ownerId string) error {
+	if err := fileutil.MakeDirs(repo.lockRoot); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(registryLockWait)
+	for {
+		locked, err := repo.fileLocker.Lock(lockPath, ownerId, registryLockTimeoutInSeconds)
+		if err != nil {
+			return fmt.Errorf("Error locking package registry: %v", err)
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timed out waiting for the package registry lock")
+		}
+		time.Sleep(registryLockRetryInterval)
+	}
+}
+
+// recordArtifact records the checksum and source of a downloaded package version and flags the package as new if it has no state yet
+func (repo *localRepository) recordArtifact(tracer trace.Tracer, packageArn string, version string, checksum string, source string) (packageState *PackageInstallState, err error) {
+	err = repo.updateRegistry(tracer, func(registry *componentRegistry) bool {
+		record := repo.getOrAddComponentRecord(registry, tracer, packageArn)
+		record.Artifacts[version] = &artifactRecord{Checksum: checksum, Source: source, DownloadedTime: time.Now()}
+		if record.setState(version, New) {
+			packageState = record.installState()
+		}
+		return true
+	})
+	return packageState, err
+}
+
+// forgetArtifact removes the registry entry for the content of a package version, if there is one
+func (repo *localRepository) forgetArtifact(tracer trace.Tracer, packageArn string, version string) error {
+	// Checking without the lock first avoids taking it for packages that were never recorded
+	if record := repo.loadComponentRecord(repo.filesysdep, tracer, packageArn); record == nil || record.Artifacts[version] == nil {
+		return nil
+	}
+	return repo.updateRegistry(tracer, func(registry *componentRegistry) bool {
+		record, ok := registry.Components[normalizeDirectory(packageArn)]
+		if !ok || record.Artifacts[version] == nil {
+			return false
+		}
+		delete(record.Artifacts, version)
+		return true
+	})
+}
+
+// verifyArtifact returns an error if the content of a package version does not match the content recorded when it was downloaded,
+// verified is false when no content is recorded for the version or the content could not be hashed
+func (repo *localRepository) verifyArtifact(record *componentRecord, packageArn string, version string, packageVersionPath string) (verified bool, err error) {
+	if record == nil {
+		return false, nil
+	}
+	artifact, ok := record.Artifacts[version]
+	if !ok || artifact == nil || artifact.Checksum == "" {
+		return false, nil
+	}
+	checksum, hashErr := repo.filesysdep.HashDirectory(packageVersionPath)
+	if hashErr != nil {
+		return false, nil
+	}
+	if checksum != artifact.Checksum {
+		return true, fmt.Errorf("Package content for %v %v does not match the checksum recorded when it was downloaded", packageArn, version)
+	}
+	return true, nil
+}
+
+// forgetOtherArtifacts removes the registry entries for the content of every version of a package but the given ones
+func (repo *localRepository) forgetOtherArtifacts(tracer trace.Tracer, packageArn string, keep map[string]bool) error {
+	isForgotten := func(record *componentRecord) bool {
+		for version := range record.Artifacts {
+			if !keep[normalizeDirectory(version)] {
+				return true
+			}
+		}
+		return false
+	}
+	// Checking without the lock first avoids taking it when there is nothing to forget
+	if record := repo.loadComponentRecord(repo.filesysdep, tracer, packageArn); record == nil || !isForgotten(record) {
+		return nil
+	}
+	return repo.updateRegistry(tracer, func(registry *componentRegistry) bool {
+		record, ok := registry.Components[normalizeDirectory(packageArn)]
+		if !ok || !isForgotten(record) {
+			return false
+		}
+		for version := range record.Artifacts {
+			if !keep[normalizeDirectory(version)] {
+				delete(record.Artifacts, version)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,373 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localpackages implements the local storage for packages managed by the ConfigurePackage plugin.
+package localpackages
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func installedRecord(version string, artifacts map[string]*artifactRecord) map[string]*componentRecord {
+	return map[string]*componentRecord{
+		testPackage: {
+			Name:                 testPackage,
+			Version:              version,
+			State:                Installed,
+			LastInstalledVersion: version,
+			Artifacts:            artifacts,
+		},
+	}
+}
+
+func TestGetInstallStateFromRegistry(t *testing.T) {
+	// Setup mock with expectations, the installstate file must not be consulted
+	mockFileSys := MockedFileSys{}
+	mockRegistry(t, &mockFileSys, installedRecord("0.0.2", nil))
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot}
+
+	state, version := repo.GetInstallState(tracerMock, testPackage)
+	mockFileSys.AssertExpectations(t)
+	assert.Equal(t, Installed, state)
+	assert.Equal(t, "0.0.2", version)
+	assert.Equal(t, "0.0.2", repo.GetInstalledVersion(tracerMock, testPackage))
+}
+
+func TestGetInstallStateCorruptRegistry(t *testing.T) {
+	// Setup mock with expectations, a corrupt registry falls back to the installstate file
+	mockFileSys := MockedFileSys{}
+	mockFileSys.On("Exists", testRegistryPath).Return(true)
+	mockFileSys.On("ReadFile", testRegistryPath).Return([]byte("{corrupt"), nil)
+	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
+	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, "installstate_success")), nil).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot}
+
+	state, _ := repo.GetInstallState(tracerMock, testPackage)
+	mockFileSys.AssertExpectations(t)
+	assert.Equal(t, Installed, state)
+}
+
+func TestValidatePackageFromRegistry(t *testing.T) {
+	data := []struct {
+		name        string
+		artifacts   map[string]*artifactRecord
+		checksum    string
+		hashErr     error
+		files       []string
+		expectedErr string
+	}{
+		{
+			"matching checksum",
+			map[string]*artifactRecord{"0.0.1": {Checksum: "abc", Source: "ssms3"}},
+			"abc",
+			nil,
+			nil,
+			"",
+		},
+		{
+			"checksum mismatch",
+			map[string]*artifactRecord{"0.0.1": {Checksum: "abc", Source: "ssms3"}},
+			"def",
+			nil,
+			[]string{"manifest.json", "install.ps1"},
+			"Package content for SsmTest 0.0.1 does not match the checksum recorded when it was downloaded",
+		},
+		{
+			"version not recorded falls back to the package content",
+			map[string]*artifactRecord{"0.0.2": {Checksum: "abc", Source: "ssms3"}},
+			"abc",
+			nil,
+			[]string{"manifest.json", "install.ps1"},
+			"",
+		},
+		{
+			"version not recorded without content",
+			map[string]*artifactRecord{"0.0.2": {Checksum: "abc", Source: "ssms3"}},
+			"abc",
+			nil,
+			[]string{"manifest.json"},
+			"Package is incomplete",
+		},
+		{
+			"content not hashed falls back to the package content",
+			map[string]*artifactRecord{"0.0.1": {Checksum: "abc", Source: "ssms3"}},
+			"",
+			errors.New("access denied"),
+			[]string{"manifest.json", "install.ps1"},
+			"",
+		},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			version := "0.0.1"
+			mockFileSys := MockedFileSys{}
+			mockRegistry(t, &mockFileSys, installedRecord(version, testdata.artifacts))
+			mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(true)
+			mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "install.ps1")).Return(true)
+			mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, version, "manifest.json")), nil)
+			mockFileSys.On("HashDirectory", path.Join(testRepoRoot, testPackage, version)).Return(testdata.checksum, testdata.hashErr)
+			mockFileSys.On("GetFileNames", path.Join(testRepoRoot, testPackage, version)).Return(testdata.files, nil)
+			mockFileSys.On("GetDirectoryNames", path.Join(testRepoRoot, testPackage, version)).Return([]string{}, nil)
+
+			repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &filelock.FileLockerNoop{}}
+
+			err := repo.ValidatePackage(tracerMock, testPackage, version)
+			if testdata.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, testdata.expectedErr)
+			}
+		})
+	}
+}
+
+func TestAddPackageRecordsArtifact(t *testing.T) {
+	version := "0.0.1"
+	// Setup mock with expectations
+	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
+	mockRegistryWrite(&mockFileSys)
+	mockFileSys.On("MakeDirExecute", path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
+	mockFileSys.On("HashDirectory", path.Join(testRepoRoot, testPackage, version)).Return("checksum", nil).Once()
+	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(false).Once()
+	mockFileSys.On("GetDirectoryNames", path.Join(testRepoRoot, testPackage)).Return(make([]string, 0), nil).Once()
+	mockFileSys.On("WriteFile", path.Join(testRepoRoot, testPackage, "installstate"), mock.Anything).Return(nil).Once()
+
+	mockDownload := MockedDownloader{}
+	mockDownload.On("Download", tracerMock, path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &filelock.FileLockerNoop{}}
+
+	err := repo.AddPackage(tracerMock, testPackage, version, "ssms3", mockDownload.Download)
+	mockFileSys.AssertExpectations(t)
+	mockDownload.AssertExpectations(t)
+	assert.NoError(t, err)
+
+	var registry componentRegistry
+	assert.NoError(t, jsonutil.Unmarshal(mockFileSys.FilesWritten[testRegistryPath+".tmp"], &registry))
+	record := registry.Components[testPackage]
+	assert.NotNil(t, record)
+	assert.Equal(t, New, record.State)
+	assert.Equal(t, "checksum", record.Artifacts[version].Checksum)
+	assert.Equal(t, "ssms3", record.Artifacts[version].Source)
+}
+
+func TestAddPackageDownloadFailureForgetsArtifact(t *testing.T) {
+	version := "0.0.1"
+	// Setup mock with expectations, the previous artifact must be dropped before downloading
+	mockFileSys := MockedFileSys{}
+	mockRegistry(t, &mockFileSys, installedRecord(version, map[string]*artifactRecord{version: {Checksum: "abc"}}))
+	mockRegistryWrite(&mockFileSys)
+	mockFileSys.On("MakeDirExecute", path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
+
+	mockDownload := MockedDownloader{}
+	mockDownload.On("Download", tracerMock, path.Join(testRepoRoot, testPackage, version)).Return(assert.AnError).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &filelock.FileLockerNoop{}}
+
+	err := repo.AddPackage(tracerMock, testPackage, version, "ssms3", mockDownload.Download)
+	mockFileSys.AssertExpectations(t)
+	assert.Error(t, err)
+
+	var registry componentRegistry
+	assert.NoError(t, jsonutil.Unmarshal(mockFileSys.FilesWritten[testRegistryPath+".tmp"], &registry))
+	assert.Equal(t, Installed, registry.Components[testPackage].State)
+	assert.Nil(t, registry.Components[testPackage].Artifacts[version])
+}
+
+func TestSetInstallStateRegistry(t *testing.T) {
+	// Setup mock with expectations
+	mockFileSys := MockedFileSys{}
+	mockRegistry(t, &mockFileSys, installedRecord("0.0.1", map[string]*artifactRecord{"0.0.2": {Checksum: "abc"}}))
+	mockRegistryWrite(&mockFileSys)
+	mockFileSys.On("WriteFile", path.Join(testRepoRoot, testPackage, "installstate"), mock.Anything).Return(nil).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &filelock.FileLockerNoop{}}
+
+	err := repo.SetInstallState(tracerMock, testPackage, "0.0.2", Installed)
+	mockFileSys.AssertExpectations(t)
+	assert.NoError(t, err)
+
+	var registry componentRegistry
+	assert.NoError(t, jsonutil.Unmarshal(mockFileSys.FilesWritten[testRegistryPath+".tmp"], &registry))
+	record := registry.Components[testPackage]
+	assert.Equal(t, "0.0.2", record.LastInstalledVersion)
+	assert.False(t, record.InstalledTime.IsZero())
+	assert.Equal(t, "abc", record.Artifacts["0.0.2"].Checksum)
+
+	var legacyState PackageInstallState
+	assert.NoError(t, jsonutil.Unmarshal(mockFileSys.FilesWritten[path.Join(testRepoRoot, testPackage, "installstate")], &legacyState))
+	assert.Equal(t, Installed, legacyState.State)
+	assert.Equal(t, "0.0.2", legacyState.Version)
+}
+
+func TestUpdateRegistryLockTimeout(t *testing.T) {
+	defer func(wait time.Duration) { registryLockWait = wait }(registryLockWait)
+	registryLockWait = 0
+
+	mockFileSys := MockedFileSys{}
+	lockerMock := filelock.FileLockerMock{}
+	lockerMock.On("Lock", path.Join(testLockRoot, registryLockFileName), mock.Anything, registryLockTimeoutInSeconds).Return(false, nil)
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &lockerMock}
+	defer os.RemoveAll(testLockRoot)
+
+	err := repo.SetInstallState(tracerMock, testPackage, "0.0.1", Installing)
+	assert.Error(t, err)
+	lockerMock.AssertExpectations(t)
+	mockFileSys.AssertExpectations(t)
+}
+
+func TestHashDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "localpackages")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "install.sh"), []byte("echo install"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "data"), []byte("data"), 0600))
+
+	fileSys := fileSysDepImp{}
+	first, err := fileSys.HashDirectory(dir)
+	assert.NoError(t, err)
+	second, err := fileSys.HashDirectory(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "data"), []byte("changed"), 0600))
+	changed, err := fileSys.HashDirectory(dir)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, changed)
+}
+
+func TestSetInstallStateRecordsHistory(t *testing.T) {
+	// Setup mock with expectations
+	mockFileSys := MockedFileSys{}
+	records := installedRecord("0.0.2", nil)
+	records[testPackage].History = []InstalledVersion{{Version: "0.0.2"}, {Version: "0.0.1"}}
+	mockRegistry(t, &mockFileSys, records)
+	mockRegistryWrite(&mockFileSys)
+	mockFileSys.On("WriteFile", path.Join(testRepoRoot, testPackage, "installstate"), mock.Anything).Return(nil).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &filelock.FileLockerNoop{}}
+
+	err := repo.SetInstallState(tracerMock, testPackage, "0.0.1", Installed)
+	mockFileSys.AssertExpectations(t)
+	assert.NoError(t, err)
+
+	var registry componentRegistry
+	assert.NoError(t, jsonutil.Unmarshal(mockFileSys.FilesWritten[testRegistryPath+".tmp"], &registry))
+	history := registry.Components[testPackage].History
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, "0.0.1", history[0].Version)
+	assert.False(t, history[0].InstalledTime.IsZero())
+	assert.Equal(t, "0.0.2", history[1].Version)
+}
+
+func TestAddInstallHistory(t *testing.T) {
+	record := &componentRecord{}
+	for _, version := range []string{"1", "2", "3", "2", "4", "5", "6", "6"} {
+		record.addInstallHistory(version)
+	}
+
+	versions := make([]string, 0, len(record.History))
+	for _, installed := range record.History {
+		versions = append(versions, installed.Version)
+	}
+	assert.Equal(t, []string{"6", "5", "4", "2", "3"}, versions)
+}
+
+func TestGetInstallHistoryFromLegacyInstallState(t *testing.T) {
+	// Setup mock with expectations, a package installed before the registry has its installed version as history
+	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
+	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
+	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, "installstate_success")), nil).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot}
+
+	history := repo.GetInstallHistory(tracerMock, testPackage)
+	mockFileSys.AssertExpectations(t)
+	assert.Equal(t, 1, len(history))
+	assert.Equal(t, "0.0.1", history[0].Version)
+}
+
+func TestRetainVersions(t *testing.T) {
+	// Setup mock with expectations
+	mockFileSys := MockedFileSys{}
+	mockRegistry(t, &mockFileSys, installedRecord("0.0.3", map[string]*artifactRecord{
+		"0.0.1": {Checksum: "a"},
+		"0.0.2": {Checksum: "b"},
+		"0.0.3": {Checksum: "c"},
+	}))
+	mockRegistryWrite(&mockFileSys)
+	mockFileSys.On("GetDirectoryNames", path.Join(testRepoRoot, testPackage)).Return([]string{"0.0.1", "0.0.2", "0.0.3"}, nil).Once()
+	mockFileSys.On("RemoveAll", path.Join(testRepoRoot, testPackage, "0.0.1")).Return(nil).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &filelock.FileLockerNoop{}}
+
+	err := repo.RetainVersions(tracerMock, testPackage, "0.0.3", "0.0.2")
+	mockFileSys.AssertExpectations(t)
+	assert.NoError(t, err)
+
+	var registry componentRegistry
+	assert.NoError(t, jsonutil.Unmarshal(mockFileSys.FilesWritten[testRegistryPath+".tmp"], &registry))
+	artifacts := registry.Components[testPackage].Artifacts
+	assert.Equal(t, 2, len(artifacts))
+	assert.Nil(t, artifacts["0.0.1"])
+}
+
+func TestListInstalled(t *testing.T) {
+	installedTime := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+	history := []InstalledVersion{{Version: "0.0.2", InstalledTime: installedTime}, {Version: "0.0.1"}}
+	// Setup mock with expectations, packages installed before the registry only have an installstate file
+	mockFileSys := MockedFileSys{}
+	mockRegistry(t, &mockFileSys, map[string]*componentRecord{
+		testPackage: {
+			Name:          testPackage,
+			Version:       "0.0.2",
+			State:         Installed,
+			InstalledTime: installedTime,
+			Artifacts:     map[string]*artifactRecord{"0.0.2": {Checksum: "a", Source: "ssms3"}, "0.0.1": {Checksum: "b", Source: "birdwatcher"}},
+			History:       history,
+		},
+		"Failed": {Name: "Failed", Version: "1.0.0", State: Failed},
+	})
+	legacyState, _ := jsonutil.Marshal(PackageInstallState{Name: "Legacy", Version: "2.0.0", State: Installed, Time: installedTime})
+	mockFileSys.On("Exists", path.Join(testRepoRoot, "Legacy", "installstate")).Return(true).Once()
+	mockFileSys.On("ReadFile", path.Join(testRepoRoot, "Legacy", "installstate")).Return([]byte(legacyState), nil).Once()
+	mockFileSys.On("GetDirectoryNames", testRepoRoot).Return([]string{testPackage, "Failed", "Legacy"}, nil).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot}
+
+	installed := repo.ListInstalled(tracerMock)
+	mockFileSys.AssertExpectations(t)
+	assert.Equal(t, []InstalledPackage{
+		{Name: testPackage, Version: "0.0.2", InstalledTime: installedTime, Source: "ssms3", History: history},
+		{Name: "Legacy", Version: "2.0.0", InstalledTime: installedTime},
+	}, installed)
+}
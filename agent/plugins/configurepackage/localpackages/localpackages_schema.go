@@ -0,0 +1,219 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localpackages implements the local storage for packages managed by the ConfigurePackage plugin.
+package localpackages
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultManifestSchemaVersion is the schema of the package manifests which don't name one
+const defaultManifestSchemaVersion = "1.0"
+
+// Kinds of json values of a manifest schema
+const (
+	schemaString = "string"
+	schemaObject = "object"
+	schemaMap    = "map"
+)
+
+// manifestSchema describes the json value expected at a path of a package manifest
+type manifestSchema struct {
+	kind     string
+	fields   map[string]manifestField // fields of an object
+	elements *manifestSchema          // values of a map
+}
+
+// manifestField is a field of an object of a package manifest
+type manifestField struct {
+	schema   *manifestSchema
+	required bool
+}
+
+var stringSchema = &manifestSchema{kind: schemaString}
+
+// parametersSchema describes the install parameters, name -> value
+var parametersSchema = &manifestSchema{kind: schemaMap, elements: stringSchema}
+
+// manifestSchemas are the schemas of the package manifests by schema version
+var manifestSchemas = map[string]*manifestSchema{
+	"1.0": {
+		kind: schemaObject,
+		fields: map[string]manifestField{
+			"schemaVersion":   {schema: stringSchema},
+			"name":            {schema: stringSchema, required: true},
+			"platform":        {schema: stringSchema},
+			"architecture":    {schema: stringSchema},
+			"version":         {schema: stringSchema, required: true},
+			"appname":         {schema: stringSchema},
+			"apppublisher":    {schema: stringSchema},
+			"appreferenceurl": {schema: stringSchema},
+			"apptype":         {schema: stringSchema},
+			"parameters":      {schema: parametersSchema},
+			// platform name -> platform version -> architecture -> parameters
+			"platformparameters": {schema: &manifestSchema{kind: schemaMap, elements: &manifestSchema{kind: schemaMap, elements: &manifestSchema{kind: schemaMap, elements: parametersSchema}}}},
+		},
+	},
+}
+
+// simplePathElement matches the map keys and field names written without quotes in the json paths of the errors
+var simplePathElement = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateManifestSchema ensures the content of a package manifest matches the schema it names, reporting
+// every type mismatch and missing required field with its json path. The unknown fields are errors only for
+// the manifests naming their schema version, they are returned as warnings for the manifests which don't.
+func validateManifestSchema(content []byte) (warnings []string, err error) {
+	var manifest interface{}
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+
+	schemaVersion := defaultManifestSchemaVersion
+	explicitVersion := false
+	if object, ok := manifest.(map[string]interface{}); ok {
+		if value, ok := lookupField(object, "schemaVersion"); ok {
+			if version, ok := value.(string); ok {
+				schemaVersion = version
+				explicitVersion = true
+			}
+		}
+	}
+	schema, ok := manifestSchemas[schemaVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported manifest schema version %v", schemaVersion)
+	}
+
+	var errs, unknown []string
+	schema.validate("$", manifest, &errs, &unknown)
+	if explicitVersion {
+		errs = append(unknown, errs...)
+	} else {
+		warnings = unknown
+	}
+	if len(errs) > 0 {
+		return warnings, fmt.Errorf("manifest does not match schema version %v: %v", schemaVersion, strings.Join(errs, "; "))
+	}
+	return warnings, nil
+}
+
+// validate appends the errors of the value at path to errs and its unknown fields to unknown
+func (schema *manifestSchema) validate(path string, value interface{}, errs *[]string, unknown *[]string) {
+	switch schema.kind {
+	case schemaString:
+		if _, ok := value.(string); !ok {
+			*errs = append(*errs, fmt.Sprintf("%v: expected string, got %v", path, jsonTypeName(value)))
+		}
+	case schemaObject:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%v: expected object, got %v", path, jsonTypeName(value)))
+			return
+		}
+		for _, name := range sortedKeys(object) {
+			if _, ok := lookupSchemaField(schema.fields, name); !ok {
+				*unknown = append(*unknown, fmt.Sprintf("%v: unknown field", jsonPath(path, name)))
+			}
+		}
+		for _, name := range sortedFieldNames(schema.fields) {
+			field := schema.fields[name]
+			if fieldValue, ok := lookupField(object, name); ok {
+				field.schema.validate(jsonPath(path, name), fieldValue, errs, unknown)
+			} else if field.required {
+				*errs = append(*errs, fmt.Sprintf("%v: missing required field", jsonPath(path, name)))
+			}
+		}
+	case schemaMap:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%v: expected object, got %v", path, jsonTypeName(value)))
+			return
+		}
+		for _, key := range sortedKeys(object) {
+			schema.elements.validate(jsonPath(path, key), object[key], errs, unknown)
+		}
+	}
+}
+
+// lookupField returns the value of a field of a json object, the names being matched without regard to case
+// like the package manifest is unmarshalled
+func lookupField(object map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := object[name]; ok {
+		return value, true
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// lookupSchemaField returns the schema of a field of an object, the names being matched without regard to case
+func lookupSchemaField(fields map[string]manifestField, name string) (manifestField, bool) {
+	for fieldName, field := range fields {
+		if strings.EqualFold(fieldName, name) {
+			return field, true
+		}
+	}
+	return manifestField{}, false
+}
+
+// jsonPath appends a field name or map key to a json path
+func jsonPath(path string, name string) string {
+	if simplePathElement.MatchString(name) {
+		return path + "." + name
+	}
+	return path + "[" + strconv.Quote(name) + "]"
+}
+
+// jsonTypeName returns the json type of a value unmarshalled into an interface{}
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFieldNames(fields map[string]manifestField) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
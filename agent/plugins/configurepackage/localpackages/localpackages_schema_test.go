@@ -0,0 +1,127 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localpackages
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateManifestSchema(t *testing.T) {
+	data := []struct {
+		name             string
+		manifest         string
+		expectedWarnings []string
+		expectedErr      string
+	}{
+		{
+			"minimal manifest",
+			`{"name": "SsmTest", "version": "0.0.1"}`,
+			nil,
+			"",
+		},
+		{
+			"full manifest",
+			`{"schemaVersion": "1.0", "name": "SsmTest", "platform": "windows", "architecture": "amd64", "version": "0.0.1",
+			  "appname": "SSM Test", "apppublisher": "Amazon", "appreferenceurl": "https://aws.amazon.com", "apptype": "Driver",
+			  "parameters": {"ServiceName": "svc"},
+			  "platformparameters": {"windows": {"_any": {"amd64": {"InstallArgs": "/quiet"}}}}}`,
+			nil,
+			"",
+		},
+		{
+			"field names without regard to case",
+			`{"Name": "SsmTest", "Version": "0.0.1"}`,
+			nil,
+			"",
+		},
+		{
+			"not an object",
+			`["SsmTest"]`,
+			nil,
+			"manifest does not match schema version 1.0: $: expected object, got array",
+		},
+		{
+			"missing required fields",
+			`{"platform": "windows"}`,
+			nil,
+			"manifest does not match schema version 1.0: $.name: missing required field; $.version: missing required field",
+		},
+		{
+			"unknown field without schema version",
+			`{"name": "SsmTest", "version": "0.0.1", "installer": "setup.exe"}`,
+			[]string{"$.installer: unknown field"},
+			"",
+		},
+		{
+			"unknown field with schema version",
+			`{"schemaVersion": "1.0", "name": "SsmTest", "version": "0.0.1", "installer": "setup.exe", "platform": 1}`,
+			nil,
+			"manifest does not match schema version 1.0: $.installer: unknown field; $.platform: expected string, got number",
+		},
+		{
+			"type mismatch",
+			`{"name": "SsmTest", "version": 1}`,
+			nil,
+			"manifest does not match schema version 1.0: $.version: expected string, got number",
+		},
+		{
+			"nested type mismatch",
+			`{"name": "SsmTest", "version": "0.0.1", "platformparameters": {"windows": {"_any": {"amd64": {"X": true}}}}}`,
+			nil,
+			"manifest does not match schema version 1.0: $.platformparameters.windows._any.amd64.X: expected string, got boolean",
+		},
+		{
+			"quoted path element",
+			`{"name": "SsmTest", "version": "0.0.1", "platformparameters": {"ubuntu": {"16.04": "amd64"}}}`,
+			nil,
+			`manifest does not match schema version 1.0: $.platformparameters.ubuntu["16.04"]: expected object, got string`,
+		},
+		{
+			"unsupported schema version",
+			`{"schemaVersion": "2.0", "name": "SsmTest", "version": "0.0.1"}`,
+			nil,
+			"unsupported manifest schema version 2.0",
+		},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			warnings, err := validateManifestSchema([]byte(testdata.manifest))
+			assert.Equal(t, testdata.expectedWarnings, warnings)
+			if testdata.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, testdata.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidatePackageSchemaMismatch(t *testing.T) {
+	version := "0.0.1"
+	manifestPath := path.Join(testRepoRoot, testPackage, version, "manifest.json")
+	// Setup mock with expectations, the package content is not inspected
+	mockFileSys := MockedFileSys{}
+	mockFileSys.On("Exists", manifestPath).Return(true)
+	mockFileSys.On("ReadFile", manifestPath).Return([]byte(`{"name": "SsmTest", "version": "0.0.1", "architecture": ["amd64"]}`), nil)
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot}
+
+	err := repo.ValidatePackage(tracerMock, testPackage, version)
+	mockFileSys.AssertExpectations(t)
+	assert.EqualError(t, err, "Package manifest is invalid: manifest does not match schema version 1.0: $.architecture: expected string, got array")
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -38,6 +39,7 @@ import (
 const testRepoRoot = "testdata"
 const testLockRoot = "testlock"
 const testPackage = "SsmTest"
+const testRegistryPath = testRepoRoot + "/" + registryFileName
 
 var tracerMock = trace.NewTracer(log.NewMockLog())
 
@@ -50,6 +52,7 @@ func TestGetInstaller(t *testing.T) {
 func TestGetInstallState(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, "installstate_success")), nil).Once()
 
@@ -66,6 +69,7 @@ func TestGetInstallState(t *testing.T) {
 func TestGetInstallStateMissing(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(false).Once()
 	mockFileSys.On("GetDirectoryNames", path.Join(testRepoRoot, testPackage)).Return(make([]string, 0), nil).Once()
 
@@ -82,6 +86,7 @@ func TestGetInstallStateMissing(t *testing.T) {
 func TestGetInstallStateCompat(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(false).Once()
 	mockFileSys.On("GetDirectoryNames", path.Join(testRepoRoot, testPackage)).Return([]string{"0.0.1"}, nil).Once()
 
@@ -98,6 +103,7 @@ func TestGetInstallStateCompat(t *testing.T) {
 func TestGetInstallStateCorrupt(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, "installstate_corrupt")), nil).Once()
 
@@ -116,6 +122,7 @@ func TestGetInstallStateCorrupt(t *testing.T) {
 func TestGetInstallStateError(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(make([]byte, 0), errors.New("Failed to read file")).Once()
 
@@ -132,6 +139,7 @@ func TestGetInstallStateError(t *testing.T) {
 func TestGetInstalledVersion(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, "installstate_success")), nil).Once()
 
@@ -147,6 +155,7 @@ func TestGetInstalledVersion(t *testing.T) {
 func TestGetInstalledVersionCompat(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(false).Once()
 	mockFileSys.On("GetDirectoryNames", path.Join(testRepoRoot, testPackage)).Return([]string{"0.0.1"}, nil).Once()
 
@@ -162,6 +171,7 @@ func TestGetInstalledVersionCompat(t *testing.T) {
 func TestGetInstalledVersionInstalling(t *testing.T) {
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, "installstate_installing")), nil).Once()
 
@@ -178,6 +188,7 @@ func TestValidatePackage(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(true).Once()
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "install.ps1")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, version, "manifest.json")), nil).Once()
@@ -197,6 +208,7 @@ func TestValidatePackage_Manifest(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(true).Once()
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "install.ps1")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, version, "manifest.json")), nil).Once()
@@ -216,6 +228,7 @@ func TestValidatePackage_NoManifest(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(false).Once()
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "install.ps1")).Return(true).Once()
 	mockFileSys.On("GetFileNames", path.Join(testRepoRoot, testPackage, version)).Return([]string{"install.json", "uninstall.json"}, nil)
@@ -234,6 +247,7 @@ func TestValidatePackageNoContent(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, version, "manifest.json")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, version, "manifest.json")), nil).Once()
 	mockFileSys.On("GetFileNames", path.Join(testRepoRoot, testPackage, version)).Return([]string{"manifest.json"}, nil)
@@ -330,6 +344,36 @@ func TestValidatePackageManifest(t *testing.T) {
 			"version",
 			false,
 		},
+		{
+			"parameters",
+			&PackageManifest{
+				Name:               "arn",
+				Version:            "version",
+				Parameters:         map[string]string{"ServiceName": "svc"},
+				PlatformParameters: map[string]map[string]map[string]map[string]string{"windows": {"2012": {"_any": {"Install_Args": "/quiet"}}}},
+			},
+			"arn",
+			"version",
+			false,
+		},
+		{
+			"invalid parameter name",
+			&PackageManifest{Name: "arn", Version: "version", Parameters: map[string]string{"service-name": "svc"}},
+			"arn",
+			"version",
+			true,
+		},
+		{
+			"invalid platform parameter name",
+			&PackageManifest{
+				Name:               "arn",
+				Version:            "version",
+				PlatformParameters: map[string]map[string]map[string]map[string]string{"windows": {"_any": {"_any": {"1st": "value"}}}},
+			},
+			"arn",
+			"version",
+			true,
+		},
 	}
 
 	for _, testdata := range data {
@@ -349,10 +393,14 @@ func TestAddPackage(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
+	mockRegistryWrite(&mockFileSys)
 	mockFileSys.On("MakeDirExecute", path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, "installstate_success")), nil).Once()
 
+	mockFileSys.On("HashDirectory", path.Join(testRepoRoot, testPackage, version)).Return("checksum", nil).Once()
+
 	mockDownload := MockedDownloader{}
 	mockDownload.On("Download", tracerMock, path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
 
@@ -370,11 +418,15 @@ func TestAddNewPackage(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
+	mockRegistryWrite(&mockFileSys)
 	mockFileSys.On("MakeDirExecute", path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(false).Once()
 	mockFileSys.On("GetDirectoryNames", path.Join(testRepoRoot, testPackage)).Return(make([]string, 0), nil).Once()
 	mockFileSys.On("WriteFile", path.Join(testRepoRoot, testPackage, "installstate"), mock.Anything).Return(nil).Once()
 
+	mockFileSys.On("HashDirectory", path.Join(testRepoRoot, testPackage, version)).Return("checksum", nil).Once()
+
 	mockDownload := MockedDownloader{}
 	mockDownload.On("Download", tracerMock, path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
 
@@ -392,10 +444,14 @@ func TestRefreshPackage(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
+	mockRegistryWrite(&mockFileSys)
 	mockFileSys.On("MakeDirExecute", path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return(loadFile(t, path.Join(testRepoRoot, testPackage, "installstate_success")), nil).Once()
 
+	mockFileSys.On("HashDirectory", path.Join(testRepoRoot, testPackage, version)).Return("checksum", nil).Once()
+
 	mockDownload := MockedDownloader{}
 	mockDownload.On("Download", tracerMock, path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
 
@@ -413,6 +469,7 @@ func TestRemovePackage(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	mockFileSys.On("RemoveAll", path.Join(testRepoRoot, testPackage, version)).Return(nil).Once()
 
 	// Instantiate repository with mock
@@ -600,6 +657,7 @@ func testInventory(t *testing.T, testData []InventoryTestData, expected []model.
 	i := 0
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
 	for _, testItem := range testData {
 		mockPackages[i] = testItem.Name
 		i++
@@ -607,7 +665,7 @@ func testInventory(t *testing.T, testData []InventoryTestData, expected []model.
 		stateContent, _ := jsonutil.Marshal(testItem.State)
 		mockFileSys.On("ReadFile", path.Join(testRepoRoot, testItem.Name, "installstate")).Return([]byte(stateContent), nil).Once()
 
-		if (testItem.Manifest != PackageManifest{}) {
+		if !reflect.DeepEqual(testItem.Manifest, PackageManifest{}) {
 			mockFileSys.On("Exists", path.Join(testRepoRoot, normalizeDirectory(testItem.State.Name), testItem.Version, "manifest.json")).Return(true).Once()
 			manifestContent, _ := jsonutil.Marshal(testItem.Manifest)
 			mockFileSys.On("ReadFile", path.Join(testRepoRoot, normalizeDirectory(testItem.State.Name), testItem.Version, "manifest.json")).Return([]byte(manifestContent), nil).Once()
@@ -633,6 +691,8 @@ func testSetInstall(t *testing.T, initialState PackageInstallState, newState Ins
 
 	// Setup mock with expectations
 	mockFileSys := MockedFileSys{}
+	mockEmptyRegistry(&mockFileSys)
+	mockRegistryWrite(&mockFileSys)
 	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
 	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return([]byte(initialJson), nil).Once()
 	mockFileSys.On("WriteFile", path.Join(testRepoRoot, testPackage, "installstate"), mock.Anything).Return(nil).Once()
@@ -645,7 +705,7 @@ func testSetInstall(t *testing.T, initialState PackageInstallState, newState Ins
 	mockFileSys.AssertExpectations(t)
 	assert.Nil(t, err)
 	var expectedState PackageInstallState
-	jsonutil.Unmarshal(mockFileSys.ContentWritten, &expectedState)
+	jsonutil.Unmarshal(mockFileSys.FilesWritten[path.Join(testRepoRoot, testPackage, "installstate")], &expectedState)
 	assertStateEqual(t, finalState, expectedState)
 }
 
@@ -753,6 +813,7 @@ func (downloadMock *MockedDownloader) Download(tracer trace.Tracer, targetDirect
 type MockedFileSys struct {
 	mock.Mock
 	ContentWritten string
+	FilesWritten   map[string]string
 }
 
 func (fileMock *MockedFileSys) MakeDirExecute(destinationDir string) (err error) {
@@ -788,5 +849,38 @@ func (fileMock *MockedFileSys) ReadFile(filename string) ([]byte, error) {
 func (fileMock *MockedFileSys) WriteFile(filename string, content string) error {
 	args := fileMock.Called(filename, content)
 	fileMock.ContentWritten += content
+	if fileMock.FilesWritten == nil {
+		fileMock.FilesWritten = make(map[string]string)
+	}
+	fileMock.FilesWritten[filename] = content
+	return args.Error(0)
+}
+
+func (fileMock *MockedFileSys) Rename(oldPath string, newPath string) error {
+	args := fileMock.Called(oldPath, newPath)
 	return args.Error(0)
 }
+
+func (fileMock *MockedFileSys) HashDirectory(srcPath string) (string, error) {
+	args := fileMock.Called(srcPath)
+	return args.String(0), args.Error(1)
+}
+
+// mockEmptyRegistry sets up the mock for a repository that has no registry yet
+func mockEmptyRegistry(fileMock *MockedFileSys) {
+	fileMock.On("Exists", testRegistryPath).Return(false)
+}
+
+// mockRegistry sets up the mock for a repository with the given registry entries
+func mockRegistry(t *testing.T, fileMock *MockedFileSys, records map[string]*componentRecord) {
+	content, err := jsonutil.Marshal(componentRegistry{Components: records})
+	assert.NoError(t, err)
+	fileMock.On("Exists", testRegistryPath).Return(true)
+	fileMock.On("ReadFile", testRegistryPath).Return([]byte(content), nil)
+}
+
+// mockRegistryWrite sets up the mock to accept an atomic write of the registry
+func mockRegistryWrite(fileMock *MockedFileSys) {
+	fileMock.On("WriteFile", testRegistryPath+".tmp", mock.Anything).Return(nil).Once()
+	fileMock.On("Rename", testRegistryPath+".tmp", testRegistryPath).Return(nil).Once()
+}
@@ -74,11 +74,31 @@ func (repoMock *MockedRepository) RemovePackage(tracer trace.Tracer, packageName
 	return args.Error(0)
 }
 
+func (repoMock *MockedRepository) RetainVersions(tracer trace.Tracer, packageName string, versions ...string) error {
+	args := repoMock.Called(tracer, packageName, versions)
+	return args.Error(0)
+}
+
+func (repoMock *MockedRepository) GetInstallHistory(tracer trace.Tracer, packageName string) []localpackages.InstalledVersion {
+	args := repoMock.Called(tracer, packageName)
+	return args.Get(0).([]localpackages.InstalledVersion)
+}
+
 func (repoMock *MockedRepository) GetInventoryData(log log.T) []model.ApplicationData {
 	args := repoMock.Called(log)
 	return args.Get(0).([]model.ApplicationData)
 }
 
+func (repoMock *MockedRepository) GetPackageVersionPath(tracer trace.Tracer, packageName string, version string) string {
+	args := repoMock.Called(tracer, packageName, version)
+	return args.String(0)
+}
+
+func (repoMock *MockedRepository) ListInstalled(tracer trace.Tracer) []localpackages.InstalledPackage {
+	args := repoMock.Called(tracer)
+	return args.Get(0).([]localpackages.InstalledPackage)
+}
+
 func (repoMock *MockedRepository) GetInstaller(tracer trace.Tracer,
 	configuration contracts.Configuration,
 	packageName string,
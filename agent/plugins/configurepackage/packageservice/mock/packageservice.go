@@ -39,6 +39,11 @@ func (ds *Mock) DownloadArtifact(tracer trace.Tracer, packageName string, versio
 	return args.String(0), args.Error(1)
 }
 
+func (ds *Mock) DownloadDelta(tracer trace.Tracer, packageName string, version string, baseVersion string) (string, error) {
+	args := ds.Called(tracer, packageName, version, baseVersion)
+	return args.String(0), args.Error(1)
+}
+
 func (ds *Mock) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
 	args := ds.Called(tracer, result)
 	return args.Error(0)
@@ -45,6 +45,8 @@ type PackageService interface {
 	PackageServiceName() string
 	DownloadManifest(tracer trace.Tracer, packageName string, version string) (string, string, bool, error)
 	DownloadArtifact(tracer trace.Tracer, packageName string, version string) (string, error)
+	// DownloadDelta downloads the delta package updating the base version of a package to the version, when one is published
+	DownloadDelta(tracer trace.Tracer, packageName string, version string, baseVersion string) (string, error)
 	ReportResult(tracer trace.Tracer, result PackageResult) error
 }
 
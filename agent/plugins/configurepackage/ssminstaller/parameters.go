@@ -0,0 +1,56 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ssminstaller
+
+// parameterEnvPrefix prefixes the environment variables the install parameters are exported to the package scripts as
+const parameterEnvPrefix = "BWS_PARAMETER_"
+
+// anySelector matches any platform name, platform version or architecture in the parameter overrides
+const anySelector = "_any"
+
+// Parameters are the install parameters a package manifest defines, e.g. install arguments, service names
+// or destination paths, so a single package can serve platforms which need different values
+type Parameters struct {
+	// Default holds the parameters of every platform
+	Default map[string]string
+	// Platforms overrides parameters by platform name -> platform version -> architecture
+	Platforms map[string]map[string]map[string]map[string]string
+}
+
+// Select returns the parameters of the platform, the overrides of the matching platform entry replacing the defaults.
+// Every level is matched exactly first and falls back to the "_any" entry, as the package selectors of the manifests do.
+func (parameters Parameters) Select(platform string, platformVersion string, architecture string) map[string]string {
+	selected := make(map[string]string, len(parameters.Default))
+	for name, value := range parameters.Default {
+		selected[name] = value
+	}
+
+	versions, ok := parameters.Platforms[platform]
+	if !ok {
+		versions = parameters.Platforms[anySelector]
+	}
+	architectures, ok := versions[platformVersion]
+	if !ok {
+		architectures = versions[anySelector]
+	}
+	overrides, ok := architectures[architecture]
+	if !ok {
+		overrides = architectures[anySelector]
+	}
+	for name, value := range overrides {
+		selected[name] = value
+	}
+	return selected
+}
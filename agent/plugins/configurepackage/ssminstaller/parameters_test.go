@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ssminstaller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParametersSelect(t *testing.T) {
+	parameters := Parameters{
+		Default: map[string]string{"ServiceName": "svc", "InstallArgs": "/quiet"},
+		Platforms: map[string]map[string]map[string]map[string]string{
+			"windows": {
+				"2012": {"_any": {"InstallArgs": "/quiet /legacy"}},
+				"_any": {"amd64": {"InstallDir": "C:\\Program Files\\svc"}},
+			},
+			"_any": {
+				"_any": {"arm64": {"ServiceName": "svc-arm"}},
+			},
+		},
+	}
+
+	data := []struct {
+		name            string
+		platform        string
+		platformVersion string
+		architecture    string
+		expected        map[string]string
+	}{
+		{
+			"exact platform version",
+			"windows", "2012", "amd64",
+			map[string]string{"ServiceName": "svc", "InstallArgs": "/quiet /legacy"},
+		},
+		{
+			"any platform version",
+			"windows", "2016", "amd64",
+			map[string]string{"ServiceName": "svc", "InstallArgs": "/quiet", "InstallDir": "C:\\Program Files\\svc"},
+		},
+		{
+			"no matching architecture",
+			"windows", "2016", "386",
+			map[string]string{"ServiceName": "svc", "InstallArgs": "/quiet"},
+		},
+		{
+			"any platform",
+			"ubuntu", "16.04", "arm64",
+			map[string]string{"ServiceName": "svc-arm", "InstallArgs": "/quiet"},
+		},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			assert.Equal(t, testdata.expected, parameters.Select(testdata.platform, testdata.platformVersion, testdata.architecture))
+		})
+	}
+}
+
+func TestParametersSelectEmpty(t *testing.T) {
+	assert.Empty(t, Parameters{}.Select("windows", "2016", "amd64"))
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -38,6 +39,7 @@ type Installer struct {
 	packagePath        string
 	config             contracts.Configuration // TODO:MF: See if we can use a smaller struct that has just the things we need
 	envdetectCollector envdetect.Collector
+	parameters         Parameters
 }
 
 type ActionType uint8
@@ -57,7 +59,8 @@ func New(packageName string,
 	version string,
 	packagePath string,
 	configuration contracts.Configuration,
-	envdetectCollector envdetect.Collector) *Installer {
+	envdetectCollector envdetect.Collector,
+	parameters Parameters) *Installer {
 	return &Installer{
 		filesysdep:         &fileSysDepImp{},
 		execdep:            &execDepImp{},
@@ -66,6 +69,7 @@ func New(packageName string,
 		packagePath:        packagePath,
 		config:             configuration,
 		envdetectCollector: envdetectCollector,
+		parameters:         parameters,
 	}
 }
 
@@ -77,6 +81,18 @@ func (inst *Installer) Uninstall(tracer trace.Tracer, context context.T) contrac
 	return inst.executeAction(tracer, context, "uninstall")
 }
 
+// Update runs the update script of the package over the installed version, the package must contain one
+func (inst *Installer) Update(tracer trace.Tracer, context context.T) contracts.PluginOutputter {
+	if exists, _, _ := inst.resolveAction(tracer, "update"); !exists {
+		output := &trace.PluginOutputTrace{Tracer: tracer}
+		err := fmt.Errorf("%v %v has no update script, it can't be updated in place", inst.packageName, inst.version)
+		tracer.BeginSection("execute action: update").WithError(err).End()
+		output.MarkAsFailed(nil, nil)
+		return output
+	}
+	return inst.executeAction(tracer, context, "update")
+}
+
 func (inst *Installer) Validate(tracer trace.Tracer, context context.T) contracts.PluginOutputter {
 	return inst.executeAction(tracer, context, "validate")
 }
@@ -254,6 +270,11 @@ func (inst *Installer) getEnvVars(actionName string, context context.T) (envVars
 	envVars["BWS_ACCOUNT_ID"] = env.Ec2Infrastructure.AccountID
 	envVars["BWS_AVAILABILITY_ZONE"] = env.Ec2Infrastructure.AvailabilityZone
 
+	// Install parameters of the package selected for the platform
+	for name, value := range inst.parameters.Select(env.OperatingSystem.Platform, env.OperatingSystem.PlatformVersion, env.OperatingSystem.Architecture) {
+		envVars[parameterEnvPrefix+strings.ToUpper(name)] = value
+	}
+
 	return envVars, err
 }
 
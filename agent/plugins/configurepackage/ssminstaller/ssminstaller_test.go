@@ -250,6 +250,72 @@ func TestUninstall_Success(t *testing.T) {
 	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
 }
 
+func TestUpdate_Success(t *testing.T) {
+	// Setup mocks with expectations
+	mockFileSys := MockedFileSys{}
+	actionPathNoExt := path.Join(testPackagePath, "update")
+	mockReadAction(t, &mockFileSys, actionPathNoExt, []byte("echo sh"), []byte{}, false)
+	mockReadAction(t, &mockFileSys, actionPathNoExt, []byte("echo sh"), []byte{}, false)
+
+	mockExec := MockedExec{}
+	mockExec.On("ExecuteDocument", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(map[string]*contracts.PluginResult{"Foo": {Status: contracts.ResultStatusSuccess}}).Once()
+
+	mockEnvdetectCollector := &envdetect.CollectorMock{}
+	mockEnvdetectCollector.On("CollectData", mock.Anything).Return(&environmentStub, nil).Once()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	// Instantiate installer with mock
+	inst := Installer{filesysdep: &mockFileSys, execdep: &mockExec, packagePath: testPackagePath, envdetectCollector: mockEnvdetectCollector}
+
+	// Call and validate mock expectations and return value
+	output := inst.Update(tracer, contextMock)
+	mockFileSys.AssertExpectations(t)
+	mockExec.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+}
+
+func TestUpdate_NoAction(t *testing.T) {
+	// Setup mocks with expectations
+	mockFileSys := MockedFileSys{}
+	actionPathNoExt := path.Join(testPackagePath, "update")
+	mockReadAction(t, &mockFileSys, actionPathNoExt, []byte{}, []byte{}, false)
+	mockExec := MockedExec{}
+
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	// Instantiate installer with mock
+	inst := Installer{filesysdep: &mockFileSys, execdep: &mockExec, packageName: "SsmTest", version: "0.0.2", packagePath: testPackagePath}
+
+	// Call and validate mock expectations and return value
+	output := inst.Update(tracer, contextMock)
+	mockFileSys.AssertExpectations(t)
+	mockExec.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, tracer.ToPluginOutput().GetStderr(), "has no update script")
+}
+
+func TestGetEnvVars_Parameters(t *testing.T) {
+	mockEnvdetectCollector := &envdetect.CollectorMock{}
+	mockEnvdetectCollector.On("CollectData", mock.Anything).Return(&environmentStub, nil).Once()
+
+	inst := Installer{
+		envdetectCollector: mockEnvdetectCollector,
+		parameters: Parameters{
+			Default: map[string]string{"ServiceName": "default", "InstallArgs": "/quiet"},
+			Platforms: map[string]map[string]map[string]map[string]string{
+				"abc": {"567": {"_any": {"ServiceName": "abc"}}},
+			},
+		},
+	}
+
+	envVars, err := inst.getEnvVars("install", contextMock)
+	mockEnvdetectCollector.AssertExpectations(t)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", envVars["BWS_PARAMETER_SERVICENAME"])
+	assert.Equal(t, "/quiet", envVars["BWS_PARAMETER_INSTALLARGS"])
+}
+
 // Load specified file from file system
 func loadFile(t *testing.T, fileName string) (result []byte) {
 	var err error
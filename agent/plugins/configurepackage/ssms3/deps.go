@@ -18,6 +18,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 )
 
 // dependency on S3 and downloaded artifacts
@@ -31,6 +32,9 @@ type networkDepImp struct{}
 
 var networkdep networkDep = &networkDepImp{}
 
+// verifyArtifact verifies the downloaded artifacts as the verification policy requires
+var verifyArtifact = verification.Verify
+
 func (networkDepImp) ListS3Folders(log log.T, amazonS3URL s3util.AmazonS3URL) (folderNames []string, err error) {
 	return artifact.ListS3Folders(log, amazonS3URL)
 }
@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 )
 
 const (
@@ -44,6 +45,12 @@ const (
 	// ArchHolder represents placeholder for Arch
 	ArchHolder = "{Arch}"
 
+	// CompressedHolder represents placeholder for the compress format of the package
+	CompressedHolder = "{Compressed}"
+
+	// PackageCompressFormat is the compress format packages are published in
+	PackageCompressFormat = "zip"
+
 	// PackageNameFormat represents the package name format based
 	PackageNameFormat = "{PackageName}.zip"
 
@@ -69,8 +76,14 @@ const (
 	ActiveServiceURLGamma = "https://s3.amazonaws.com/amazon-ssm-packages-us-east-1-gamma/active-birdwatcher-fallback"
 )
 
+// sourceHolderPattern matches the placeholders of a custom source url
+var sourceHolderPattern = regexp.MustCompile(`\{[A-Za-z]+\}`)
+
 type PackageService struct {
 	packageURL string
+	// packageNameSuffix locates a version of a package in the package url, PackageNameSuffix when empty
+	packageNameSuffix string
+	policy            verification.Policy
 }
 
 // UseSSMS3Service checks for existence of the active service indicator file.  If the file has been removed, it indicates that the new package service should be used
@@ -92,7 +105,7 @@ func UseSSMS3Service(tracer trace.Tracer, repository string, region string) bool
 	return networkdep.CanGetS3Object(logger, s3util.ParseAmazonS3URL(logger, parsedURL))
 }
 
-func New(repository string, region string) *PackageService {
+func New(repository string, region string, policy verification.Policy) *PackageService {
 	var packageURL string
 	if repository == "beta" {
 		packageURL = PackageURLBeta
@@ -101,11 +114,60 @@ func New(repository string, region string) *PackageService {
 	} else {
 		packageURL = PackageURLStandard
 	}
+	return &PackageService{packageURL: expandHolders(packageURL, region), policy: policy}
+}
+
+// NewWithSource returns the package service of packages published in a custom bucket layout, the source having been
+// validated by ValidateSource. The source is the url of the folder holding the versions of a package, laid out like
+// the standard repository, or the url of the package file when it contains {PackageVersion}.
+// {Endpoint}, {Region}, {Platform}, {Arch} and {Compressed} are replaced with the values of the instance,
+// {PackageName} and {PackageVersion} with the package being downloaded.
+func NewWithSource(source string, region string, policy verification.Policy) *PackageService {
+	packageURL := source
+	packageNameSuffix := PackageNameSuffix
+	if i := strings.Index(source, "/"+updateutil.PackageVersionHolder+"/"); i >= 0 {
+		// the versions of the package are listed from the folder holding the version folders
+		packageURL, packageNameSuffix = source[:i], source[i:]
+	}
+	return &PackageService{
+		packageURL:        expandHolders(packageURL, region),
+		packageNameSuffix: expandHolders(packageNameSuffix, region),
+		policy:            policy,
+	}
+}
+
+// ValidateSource ensures a custom source is an https url which only uses the supported placeholders,
+// {PackageVersion} being a folder of its path
+func ValidateSource(source string) error {
+	for _, holder := range sourceHolderPattern.FindAllString(source, -1) {
+		switch holder {
+		case EndpointHolder, RegionHolder, PlatformHolder, ArchHolder, CompressedHolder, updateutil.PackageNameHolder, updateutil.PackageVersionHolder:
+		default:
+			return fmt.Errorf("unsupported placeholder %v in source %v", holder, source)
+		}
+	}
+	if versionHolders := strings.Count(source, updateutil.PackageVersionHolder); versionHolders > 1 ||
+		(versionHolders == 1 && !strings.Contains(source, "/"+updateutil.PackageVersionHolder+"/")) {
+		return fmt.Errorf("%v must appear once as a folder of source %v", updateutil.PackageVersionHolder, source)
+	}
+	parsedURL, err := url.Parse(sourceHolderPattern.ReplaceAllString(source, "holder"))
+	if err != nil {
+		return fmt.Errorf("invalid source %v, %v", source, err)
+	}
+	if parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return fmt.Errorf("source %v is not an https url", source)
+	}
+	return nil
+}
+
+// expandHolders replaces the placeholders of a package url with the values of the instance
+func expandHolders(packageURL string, region string) string {
 	packageURL = strings.Replace(packageURL, EndpointHolder, s3util.GetS3Endpoint(region), -1)
 	packageURL = strings.Replace(packageURL, RegionHolder, region, -1)
 	packageURL = strings.Replace(packageURL, PlatformHolder, appconfig.PackagePlatform, -1)
 	packageURL = strings.Replace(packageURL, ArchHolder, runtime.GOARCH, -1)
-	return &PackageService{packageURL: packageURL}
+	packageURL = strings.Replace(packageURL, CompressedHolder, PackageCompressFormat, -1)
+	return packageURL
 }
 
 func (ds *PackageService) PackageServiceName() string {
@@ -139,8 +201,17 @@ func (ds *PackageService) DownloadManifest(tracer trace.Tracer, packageName stri
 }
 
 func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName string, version string) (string, error) {
-	s3Location := getS3Location(packageName, version, ds.packageURL)
-	return downloadPackageFromS3(tracer, s3Location)
+	packageNameSuffix := ds.packageNameSuffix
+	if packageNameSuffix == "" {
+		packageNameSuffix = PackageNameSuffix
+	}
+	s3Location := getS3Location(packageName, version, ds.packageURL, packageNameSuffix)
+	return downloadPackageFromS3(tracer, s3Location, ds.policy)
+}
+
+// DownloadDelta always fails, the S3 repositories only publish full packages
+func (*PackageService) DownloadDelta(tracer trace.Tracer, packageName string, version string, baseVersion string) (string, error) {
+	return "", fmt.Errorf("delta packages are not supported by %v", packageservice.PackageServiceName_ssms3)
 }
 
 func (*PackageService) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
@@ -151,7 +222,7 @@ func (*PackageService) ReportResult(tracer trace.Tracer, result packageservice.P
 // utils
 
 // downloadPackageFromS3 downloads and uncompresses the installation package from s3 bucket
-func downloadPackageFromS3(tracer trace.Tracer, packageS3Source string) (string, error) {
+func downloadPackageFromS3(tracer trace.Tracer, packageS3Source string, policy verification.Policy) (string, error) {
 	// TODO: deduplicate with birdwatcher download
 	downloadInput := artifact.DownloadInput{
 		SourceURL: packageS3Source,
@@ -171,12 +242,19 @@ func downloadPackageFromS3(tracer trace.Tracer, packageS3Source string) (string,
 		return "", errors.New(errMessage)
 	}
 
+	if err := verifyArtifact(logger, policy, verification.Artifact{
+		SourceURL: downloadInput.SourceURL,
+		LocalPath: downloadOutput.LocalFilePath,
+	}); err != nil {
+		return "", fmt.Errorf("failed to verify installation package %v, %v", downloadInput.SourceURL, err)
+	}
+
 	return downloadOutput.LocalFilePath, nil
 }
 
 // getS3Location constructs the s3 url to locate the package for downloading
-func getS3Location(packageName string, version string, url string) string {
-	s3Location := url + PackageNameSuffix
+func getS3Location(packageName string, version string, url string, packageNameSuffix string) string {
+	s3Location := url + packageNameSuffix
 
 	s3Location = strings.Replace(s3Location, updateutil.PackageNameHolder, packageName, -1)
 	s3Location = strings.Replace(s3Location, updateutil.PackageVersionHolder, version, -1)
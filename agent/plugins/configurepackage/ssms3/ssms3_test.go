@@ -24,6 +24,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -53,47 +54,47 @@ func TestGetLatestVersion_None(t *testing.T) {
 }
 
 func TestEndpointEuCentral1(t *testing.T) {
-	service := New("", "eu-central-1")
+	service := New("", "eu-central-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.eu-central-1.amazonaws.com/amazon-ssm-packages-eu-central-1/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
 func TestEndpointEuCentral1Beta(t *testing.T) {
-	service := New("beta", "eu-central-1")
+	service := New("beta", "eu-central-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.amazonaws.com/amazon-ssm-packages-beta/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
 func TestEndpointEuCentral1Gamma(t *testing.T) {
-	service := New("gamma", "eu-central-1")
+	service := New("gamma", "eu-central-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.amazonaws.com/amazon-ssm-packages-us-east-1-gamma/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
 func TestEndpointCnNorth1(t *testing.T) {
-	service := New("", "cn-north-1")
+	service := New("", "cn-north-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.cn-north-1.amazonaws.com.cn/amazon-ssm-packages-cn-north-1/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
 func TestEndpointCnNorth1Beta(t *testing.T) {
-	service := New("beta", "cn-north-1")
+	service := New("beta", "cn-north-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.amazonaws.com/amazon-ssm-packages-beta/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
 func TestEndpointCnNorth1Gamma(t *testing.T) {
-	service := New("gamma", "cn-north-1")
+	service := New("gamma", "cn-north-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.amazonaws.com/amazon-ssm-packages-us-east-1-gamma/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
 func TestEndpointUsEast1(t *testing.T) {
-	service := New("", "us-east-1")
+	service := New("", "us-east-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.amazonaws.com/amazon-ssm-packages-us-east-1/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
 func TestEndpointUsEast1Beta(t *testing.T) {
-	service := New("beta", "us-east-1")
+	service := New("beta", "us-east-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.amazonaws.com/amazon-ssm-packages-beta/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
 func TestEndpointUsEast1Gamma(t *testing.T) {
-	service := New("gamma", "us-east-1")
+	service := New("gamma", "us-east-1", verification.PolicyNone)
 	assert.Equal(t, fmt.Sprintf("https://s3.amazonaws.com/amazon-ssm-packages-us-east-1-gamma/BirdwatcherPackages/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
 }
 
@@ -127,6 +128,45 @@ func TestGetLatestVersion_NegativeVersionsAreNotValid(t *testing.T) {
 	assert.Equal(t, "", latest)
 }
 
+func TestNewWithSource(t *testing.T) {
+	service := NewWithSource("https://{Endpoint}/my-packages-{Region}/{PackageName}/{Platform}/{Arch}", "eu-central-1", verification.PolicyNone)
+	assert.Equal(t, fmt.Sprintf("https://s3.eu-central-1.amazonaws.com/my-packages-eu-central-1/{PackageName}/%v/%v", appconfig.PackagePlatform, runtime.GOARCH), service.packageURL)
+	assert.Equal(t, PackageNameSuffix, service.packageNameSuffix)
+}
+
+func TestNewWithSourceVersionFolder(t *testing.T) {
+	service := NewWithSource("https://s3.amazonaws.com/my-packages/{Platform}/{PackageName}/{PackageVersion}/{Arch}/package.{Compressed}", "us-east-1", verification.PolicyNone)
+	assert.Equal(t, fmt.Sprintf("https://s3.amazonaws.com/my-packages/%v/{PackageName}", appconfig.PackagePlatform), service.packageURL)
+	assert.Equal(t, fmt.Sprintf("/{PackageVersion}/%v/package.zip", runtime.GOARCH), service.packageNameSuffix)
+}
+
+func TestValidateSource(t *testing.T) {
+	data := []struct {
+		source      string
+		expectedErr string
+	}{
+		{"https://{Endpoint}/my-packages-{Region}/{PackageName}/{Platform}/{Arch}", ""},
+		{"https://s3.amazonaws.com/my-packages/{PackageName}/{PackageVersion}/{PackageName}-{Arch}.{Compressed}", ""},
+		{"https://s3.amazonaws.com/my-packages/{PackageName}/{version}", "unsupported placeholder {version}"},
+		{"https://s3.amazonaws.com/my-packages/{PackageName}-{PackageVersion}.zip", "{PackageVersion} must appear once as a folder"},
+		{"https://s3.amazonaws.com/{PackageVersion}/my-packages/{PackageVersion}/", "{PackageVersion} must appear once as a folder"},
+		{"http://s3.amazonaws.com/my-packages/{PackageName}", "is not an https url"},
+		{"my-packages/{PackageName}", "is not an https url"},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.source, func(t *testing.T) {
+			err := ValidateSource(testdata.source)
+			if testdata.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), testdata.expectedErr)
+			}
+		})
+	}
+}
+
 func TestSuccessfulDownloadManifest(t *testing.T) {
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
@@ -204,6 +244,23 @@ func TestDownloadArtifactWithError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDownloadArtifactWithSource(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+
+	mockObj := new(SSMS3Mock)
+	mockObj.On("Download", mock.Anything, artifact.DownloadInput{SourceURL: "https://s3.amazonaws.com/my-packages/packageName/1234/amd64/package.zip"}).Return(artifact.DownloadOutput{"somePath", false, true}, nil)
+
+	networkdep = mockObj
+
+	ds := &PackageService{packageURL: "https://s3.amazonaws.com/my-packages/{PackageName}", packageNameSuffix: "/{PackageVersion}/amd64/package.zip"}
+	result, err := ds.DownloadArtifact(tracer, "packageName", "1234")
+
+	mockObj.AssertExpectations(t)
+	assert.Equal(t, "somePath", result)
+	assert.NoError(t, err)
+}
+
 func TestUseSSMS3Service_True(t *testing.T) {
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
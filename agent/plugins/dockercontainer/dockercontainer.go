@@ -14,11 +14,13 @@
 package dockercontainer
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"regexp"
-	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -140,169 +142,170 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput DockerConta
 		return
 	}
 	var commandName string = "docker"
-	var commandArguments []string
+	commandArguments, err := makeArguments(log, pluginInput)
+	if err != nil {
+		log.Error(err)
+		output.MarkAsFailed(err)
+		return
+	}
+
+	executionTimeout := pluginutil.ValidatePluginExecutionTimeout(log, Name(), pluginInput.TimeoutSeconds)
+
+	// Execute Command
+	exitCode, err := p.CommandExecuter.NewExecute(log, pluginInput.WorkingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+
+	// Set output status
+	output.SetExitCode(exitCode)
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+
+	if err != nil {
+		status := output.GetStatus()
+		if status != contracts.ResultStatusCancelled &&
+			status != contracts.ResultStatusTimedOut &&
+			status != contracts.ResultStatusSuccessAndReboot {
+			output.MarkAsFailed(fmt.Errorf("failed to run commands: %v", err))
+		}
+	}
+	return
+}
+
+// makeArguments builds the arguments of the docker command line running the action.
+func makeArguments(log log.T, pluginInput DockerContainerPluginInput) (commandArguments []string, err error) {
 	switch pluginInput.Action {
 	case CREATE, RUN:
 		if len(pluginInput.Image) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "image")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "image"))
-			return
+			return nil, fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "image")
 		}
-		commandArguments = make([]string, 0)
 		if pluginInput.Action == RUN {
 			commandArguments = append(commandArguments, "run", "-d")
 		} else {
 			commandArguments = append(commandArguments, "create")
 		}
-		if len(pluginInput.Volume) > 0 && len(pluginInput.Volume[0]) > 0 {
-			output.AppendInfo("pluginInput.Volume:" + strconv.Itoa(len(pluginInput.Volume)))
-
-			log.Info("pluginInput.Volume", len(pluginInput.Volume))
-			commandArguments = append(commandArguments, "--volume")
-			for _, vol := range pluginInput.Volume {
+		for _, vol := range pluginInput.Volume {
+			if len(vol) > 0 {
 				log.Info("pluginInput.Volume item", vol)
-				commandArguments = append(commandArguments, vol)
+				commandArguments = append(commandArguments, "--volume", vol)
 			}
 		}
 		if len(pluginInput.Container) > 0 {
-			commandArguments = append(commandArguments, "--name")
-			commandArguments = append(commandArguments, pluginInput.Container)
+			commandArguments = append(commandArguments, "--name", pluginInput.Container)
 		}
 		if len(pluginInput.Memory) > 0 {
-			commandArguments = append(commandArguments, "--memory")
-			commandArguments = append(commandArguments, pluginInput.Memory)
+			commandArguments = append(commandArguments, "--memory", pluginInput.Memory)
 		}
 		if len(pluginInput.CpuShares) > 0 {
-			commandArguments = append(commandArguments, "--cpu-shares")
-			commandArguments = append(commandArguments, pluginInput.CpuShares)
+			commandArguments = append(commandArguments, "--cpu-shares", pluginInput.CpuShares)
 		}
 		if len(pluginInput.Publish) > 0 {
-			commandArguments = append(commandArguments, "--publish")
-			commandArguments = append(commandArguments, pluginInput.Publish)
+			commandArguments = append(commandArguments, "--publish", pluginInput.Publish)
 		}
-		if len(pluginInput.Env) > 0 {
-			commandArguments = append(commandArguments, "--env")
-			commandArguments = append(commandArguments, pluginInput.Env)
+		for _, env := range splitEnv(pluginInput.Env) {
+			commandArguments = append(commandArguments, "--env", env)
 		}
 		if len(pluginInput.User) > 0 {
-			commandArguments = append(commandArguments, "--user")
-			commandArguments = append(commandArguments, pluginInput.User)
+			commandArguments = append(commandArguments, "--user", pluginInput.User)
 		}
 		commandArguments = append(commandArguments, pluginInput.Image)
-		commandArguments = append(commandArguments, pluginInput.Cmd)
-
-	case START:
-		commandArguments = append(commandArguments, "start")
-		if len(pluginInput.Container) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container"))
-			return
-		}
-		commandArguments = append(commandArguments, pluginInput.Container)
+		commandArguments = append(commandArguments, splitCmd(pluginInput.Cmd)...)
 
-	case RM:
-		commandArguments = append(commandArguments, "rm")
+	case START, RM, STOP, LOGS:
 		if len(pluginInput.Container) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container"))
-			return
+			return nil, fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container")
 		}
-		commandArguments = append(commandArguments, pluginInput.Container)
-
-	case STOP:
-		commandArguments = append(commandArguments, "stop")
-		if len(pluginInput.Container) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container"))
-			return
-		}
-		commandArguments = append(commandArguments, pluginInput.Container)
+		commandArguments = append(commandArguments, strings.ToLower(pluginInput.Action), pluginInput.Container)
 
 	case EXEC:
-		commandArguments = append(commandArguments, "exec")
 		if len(pluginInput.Container) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container"))
-			return
+			return nil, fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container")
 		}
 		if len(pluginInput.Cmd) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "cmd")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "cmd"))
-			return
+			return nil, fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "cmd")
+		}
+		commandArguments = append(commandArguments, "exec")
+		for _, env := range splitEnv(pluginInput.Env) {
+			commandArguments = append(commandArguments, "--env", env)
 		}
 		if len(pluginInput.User) > 0 {
-			commandArguments = append(commandArguments, "--user")
-			commandArguments = append(commandArguments, pluginInput.User)
+			commandArguments = append(commandArguments, "--user", pluginInput.User)
 		}
 		commandArguments = append(commandArguments, pluginInput.Container)
-		commandArguments = append(commandArguments, pluginInput.Cmd)
+		commandArguments = append(commandArguments, splitCmd(pluginInput.Cmd)...)
+
 	case INSPECT:
-		commandArguments = append(commandArguments, "inspect")
 		if len(pluginInput.Container) == 0 && len(pluginInput.Image) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container or image")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container or image"))
-			return
+			return nil, fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container or image")
 		}
-		commandArguments = append(commandArguments, pluginInput.Container)
-		commandArguments = append(commandArguments, pluginInput.Image)
-	case STATS:
-		commandArguments = append(commandArguments, "stats")
-		commandArguments = append(commandArguments, "--no-stream")
+		commandArguments = append(commandArguments, "inspect")
 		if len(pluginInput.Container) > 0 {
 			commandArguments = append(commandArguments, pluginInput.Container)
 		}
-	case LOGS:
-		commandArguments = append(commandArguments, "logs")
-		if len(pluginInput.Container) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "container"))
-			return
+		if len(pluginInput.Image) > 0 {
+			commandArguments = append(commandArguments, pluginInput.Image)
 		}
-		commandArguments = append(commandArguments, pluginInput.Container)
-	case PULL:
-		commandArguments = append(commandArguments, "pull")
+
+	case STATS:
+		commandArguments = append(commandArguments, "stats", "--no-stream")
+		if len(pluginInput.Container) > 0 {
+			commandArguments = append(commandArguments, pluginInput.Container)
+		}
+
+	case PULL, RMI:
 		if len(pluginInput.Image) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "image")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "image"))
-			return
+			return nil, fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "image")
 		}
-		commandArguments = append(commandArguments, pluginInput.Image)
+		commandArguments = append(commandArguments, strings.ToLower(pluginInput.Action), pluginInput.Image)
+
 	case IMAGES:
 		commandArguments = append(commandArguments, "images")
-	case RMI:
-		commandArguments = append(commandArguments, "rmi")
-		if len(pluginInput.Image) == 0 {
-			log.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "image")
-			output.MarkAsFailed(fmt.Errorf(ACTION_REQUIRES_PARAMETER, pluginInput.Action, "image"))
-			return
-		}
-		commandArguments = append(commandArguments, pluginInput.Image)
 
 	case PS:
 		commandArguments = append(commandArguments, "ps", "--all")
+
 	default:
-		output.MarkAsFailed(fmt.Errorf("Docker Action is set to unsupported value: %v", pluginInput.Action))
-		return
+		return nil, fmt.Errorf("Docker Action is set to unsupported value: %v", pluginInput.Action)
 	}
+	return commandArguments, nil
+}
 
-	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
-
-	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, pluginInput.WorkingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
-
-	// Set output status
-	output.SetExitCode(exitCode)
-	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+// splitEnv returns the environment variables given one per line.
+func splitEnv(env string) (vars []string) {
+	for _, line := range strings.Split(env, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			vars = append(vars, line)
+		}
+	}
+	return vars
+}
 
-	if err != nil {
-		status := output.GetStatus()
-		if status != contracts.ResultStatusCancelled &&
-			status != contracts.ResultStatusTimedOut &&
-			status != contracts.ResultStatusSuccessAndReboot {
-			output.MarkAsFailed(fmt.Errorf("failed to run commands: %v", err))
+// splitCmd splits the command into its arguments, single or double quotes group words into one argument.
+func splitCmd(cmd string) (args []string) {
+	var current bytes.Buffer
+	var quote rune
+	inArg := false
+	for _, c := range cmd {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
 		}
 	}
-	return
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
 }
 
 func validateInputs(pluginInput DockerContainerPluginInput) (err error) {
@@ -310,9 +313,10 @@ func validateInputs(pluginInput DockerContainerPluginInput) (err error) {
 	if !validContainerName.MatchString(pluginInput.Container) {
 		return errors.New("Invalid container name, only [a-zA-Z0-9_-] are allowed")
 	}
-	validImageValue := regexp.MustCompile(`^[a-zA-Z0-9_\-\\\/]*$`)
+	// images may be referenced with a registry, a tag or a digest, e.g. registry:5000/repo/image:tag@sha256:digest
+	validImageValue := regexp.MustCompile(`^([a-zA-Z0-9][a-zA-Z0-9_.\-\/:@]*)?$`)
 	if !validImageValue.MatchString(pluginInput.Image) {
-		return errors.New("Invalid image value, only [a-zA-Z0-9_-] are allowed")
+		return errors.New("Invalid image value, only [a-zA-Z0-9_.-/:@] are allowed")
 	}
 	validUserValue := regexp.MustCompile(`^[a-zA-Z0-9_-]*$`)
 	if !validUserValue.MatchString(pluginInput.User) {
@@ -330,7 +334,7 @@ func validateInputs(pluginInput DockerContainerPluginInput) (err error) {
 	}
 	validMemoryValue := regexp.MustCompile(`^[0-9]*[bkmg]?$`)
 	if !validMemoryValue.MatchString(pluginInput.Memory) {
-		return errors.New("Invalid CpuShares value")
+		return errors.New("Invalid Memory value")
 	}
 	validPublishValue := regexp.MustCompile(`^[0-9a-zA-Z:\-\/.]*$`)
 	if !validPublishValue.MatchString(pluginInput.Publish) {
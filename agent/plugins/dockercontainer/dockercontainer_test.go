@@ -0,0 +1,107 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockercontainer
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+func TestMakeArgumentsRun(t *testing.T) {
+	args, err := makeArguments(logger, DockerContainerPluginInput{
+		Action:    RUN,
+		Container: "web",
+		Image:     "123456789012.dkr.ecr.us-east-1.amazonaws.com/web:1.2",
+		Cmd:       `sh -c "echo hello world"`,
+		Env:       "MODE=prod\nPORT=8080\n",
+		Volume:    []string{"/data:/data", "/logs:/var/log/nginx"},
+		Memory:    "512m",
+		CpuShares: "512",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"run", "-d",
+		"--volume", "/data:/data", "--volume", "/logs:/var/log/nginx",
+		"--name", "web",
+		"--memory", "512m",
+		"--cpu-shares", "512",
+		"--env", "MODE=prod", "--env", "PORT=8080",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/web:1.2",
+		"sh", "-c", "echo hello world",
+	}, args)
+}
+
+func TestMakeArgumentsWithoutCmd(t *testing.T) {
+	args, err := makeArguments(logger, DockerContainerPluginInput{Action: CREATE, Image: "nginx"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"create", "nginx"}, args)
+}
+
+func TestMakeArgumentsExec(t *testing.T) {
+	args, err := makeArguments(logger, DockerContainerPluginInput{Action: EXEC, Container: "web", User: "www", Cmd: "ls -l '/var/www/my site'"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"exec", "--user", "www", "web", "ls", "-l", "/var/www/my site"}, args)
+}
+
+func TestMakeArgumentsContainerActions(t *testing.T) {
+	for action, expected := range map[string][]string{
+		START:   {"start", "web"},
+		STOP:    {"stop", "web"},
+		RM:      {"rm", "web"},
+		LOGS:    {"logs", "web"},
+		INSPECT: {"inspect", "web"},
+		STATS:   {"stats", "--no-stream", "web"},
+	} {
+		args, err := makeArguments(logger, DockerContainerPluginInput{Action: action, Container: "web"})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, args, action)
+	}
+
+	for _, action := range []string{START, STOP, RM, LOGS, EXEC, INSPECT} {
+		_, err := makeArguments(logger, DockerContainerPluginInput{Action: action})
+		assert.Error(t, err, action)
+	}
+}
+
+func TestMakeArgumentsImageActions(t *testing.T) {
+	args, err := makeArguments(logger, DockerContainerPluginInput{Action: PULL, Image: "nginx:1.15"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pull", "nginx:1.15"}, args)
+
+	args, err = makeArguments(logger, DockerContainerPluginInput{Action: RMI, Image: "nginx:1.15"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rmi", "nginx:1.15"}, args)
+
+	_, err = makeArguments(logger, DockerContainerPluginInput{Action: PULL})
+	assert.Error(t, err)
+
+	_, err = makeArguments(logger, DockerContainerPluginInput{Action: "Build"})
+	assert.Error(t, err)
+}
+
+func TestValidateInputs(t *testing.T) {
+	assert.NoError(t, validateInputs(DockerContainerPluginInput{Image: "registry.example.com:5000/team/web:1.2"}))
+	assert.NoError(t, validateInputs(DockerContainerPluginInput{Image: "nginx@sha256:0123456789abcdef"}))
+	assert.Error(t, validateInputs(DockerContainerPluginInput{Image: "--privileged"}))
+	assert.Error(t, validateInputs(DockerContainerPluginInput{Image: "nginx latest"}))
+	assert.Error(t, validateInputs(DockerContainerPluginInput{Memory: "lots"}))
+	assert.Error(t, validateInputs(DockerContainerPluginInput{Cmd: "ls; rm -rf /"}))
+}
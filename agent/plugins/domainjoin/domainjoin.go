@@ -0,0 +1,48 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package domainjoin implements the domainjoin plugin.
+package domainjoin
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// Plugin is the type for the domain join plugin.
+type Plugin struct {
+}
+
+// DomainJoinPluginInput represents one set of commands executed by the Domain join plugin.
+type DomainJoinPluginInput struct {
+	contracts.PluginInput
+	DirectoryId    string
+	DirectoryName  string
+	DirectoryOU    string
+	DnsIpAddresses []string
+	// Username is the directory account joining the instance, only used on linux
+	Username string
+	// PasswordParameter is the name of the SecureString parameter holding the password of Username, only used on linux
+	PasswordParameter string
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginNameDomainJoin
+}
@@ -0,0 +1,273 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package domainjoin
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// realmCommand is the realmd command line, it joins the domain and configures sssd
+	realmCommand = "realm"
+	// adcliCommand is used to join the domain when realmd is not installed
+	adcliCommand = "adcli"
+	// sssdConfigPath is the configuration file of sssd written after a join with adcli
+	sssdConfigPath = "/etc/sssd/sssd.conf"
+)
+
+// validDomainName matches the DNS name of a directory
+var validDomainName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)+$`)
+
+// Makes command as variables, so that we can mock this for unit tests
+var lookPath = exec.LookPath
+var getSecureString = parameterstore.GetSecureString
+var runCommand = runJoinCommand
+var sssdConfigExists = func() bool { return fileutil.Exists(sssdConfigPath) }
+var writeSssdConfig = func(content string) error {
+	if err := fileutil.MakeDirs("/etc/sssd"); err != nil {
+		return err
+	}
+	_, err := fileutil.WriteIntoFileWithPermissions(sssdConfigPath, content, appconfig.ReadWriteAccess)
+	return err
+}
+
+// Execute joins the instance to the directory through realmd, or adcli when realmd is not installed.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	var properties map[string]interface{}
+	if properties = pluginutil.LoadParametersAsMap(log, config.Properties, output); output.GetExitCode() != 0 {
+		return
+	}
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.runCommandsRawInput(log, properties, output)
+
+		if output.GetStatus() == contracts.ResultStatusFailed {
+			output.AppendInfo("Domain join failed.")
+		} else if output.GetStatus() == contracts.ResultStatusSuccess {
+			output.AppendInfo("Domain join succeeded.")
+		}
+	}
+}
+
+// runCommandsRawInput joins the domain described by the raw plugin input.
+// The input is in the default json unmarshal format (e.g. map[string]interface{}).
+func (p *Plugin) runCommandsRawInput(log log.T, rawPluginInput map[string]interface{}, output iohandler.IOHandler) {
+	var pluginInput DomainJoinPluginInput
+	if err := jsonutil.Remarshal(rawPluginInput, &pluginInput); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", rawPluginInput, err))
+		return
+	}
+	p.joinDomain(log, pluginInput, output)
+}
+
+// joinDomain joins the domain unless the instance is already a member of it, then verifies the membership.
+func (p *Plugin) joinDomain(log log.T, pluginInput DomainJoinPluginInput, out iohandler.IOHandler) {
+	domain := strings.ToLower(pluginInput.DirectoryName)
+	if domain == "" {
+		out.MarkAsFailed(fmt.Errorf("directoryName is required"))
+		return
+	}
+	if !validDomainName.MatchString(domain) {
+		out.MarkAsFailed(fmt.Errorf("directoryName %v is not a valid domain name", pluginInput.DirectoryName))
+		return
+	}
+	if pluginInput.Username == "" || pluginInput.PasswordParameter == "" {
+		out.MarkAsFailed(fmt.Errorf("username and passwordParameter are required to join %v", domain))
+		return
+	}
+	if len(pluginInput.DnsIpAddresses) > 0 {
+		out.AppendInfof("dnsIpAddresses are not applied on this platform, the DNS servers of the instance must resolve %v.", domain)
+	}
+
+	tool, err := findJoinTool()
+	if err != nil {
+		out.MarkAsFailed(err)
+		return
+	}
+	out.SetStatus(contracts.ResultStatusInProgress)
+
+	if isJoined(log, tool, domain) {
+		out.AppendInfof("The instance is already joined to %v.", domain)
+	} else {
+		password, err := getSecureString(log, pluginInput.PasswordParameter)
+		if err != nil {
+			out.MarkAsFailed(fmt.Errorf("failed to get the password of %v: %v", pluginInput.Username, err))
+			return
+		}
+		if err = join(log, tool, pluginInput, domain, password, out); err != nil {
+			out.MarkAsFailed(err)
+			return
+		}
+	}
+
+	if err = verify(log, tool, pluginInput.Username, domain, out); err != nil {
+		out.MarkAsFailed(err)
+		return
+	}
+	out.MarkAsSucceeded()
+}
+
+// findJoinTool returns the command used to join the domain, realmd is preferred over adcli.
+func findJoinTool() (string, error) {
+	for _, tool := range []string{realmCommand, adcliCommand} {
+		if _, err := lookPath(tool); err == nil {
+			return tool, nil
+		}
+	}
+	return "", fmt.Errorf("joining a domain requires realmd or adcli, neither is installed on the instance")
+}
+
+// isJoined returns true if the instance has a computer account in the domain.
+func isJoined(log log.T, tool string, domain string) bool {
+	if tool == realmCommand {
+		output, err := runCommand(log, realmCommand, []string{"list", "--name-only"}, "")
+		if err != nil {
+			return false
+		}
+		for _, name := range strings.Fields(output) {
+			if strings.EqualFold(name, domain) {
+				return true
+			}
+		}
+		return false
+	}
+	_, err := runCommand(log, adcliCommand, []string{"testjoin", "--domain=" + domain}, "")
+	return err == nil
+}
+
+// join joins the domain with the given tool, the password is passed on the standard input.
+func join(log log.T, tool string, pluginInput DomainJoinPluginInput, domain string, password string, out iohandler.IOHandler) error {
+	var args []string
+	if tool == realmCommand {
+		args = []string{"join", "--verbose", "--user=" + pluginInput.Username}
+		if pluginInput.DirectoryOU != "" {
+			args = append(args, "--computer-ou="+pluginInput.DirectoryOU)
+		}
+		args = append(args, domain)
+	} else {
+		args = []string{"join", "--verbose", "--domain=" + domain, "--login-user=" + pluginInput.Username, "--stdin-password"}
+		if pluginInput.DirectoryOU != "" {
+			args = append(args, "--domain-ou="+pluginInput.DirectoryOU)
+		}
+	}
+
+	output, err := runCommand(log, tool, args, password+"\n")
+	if output != "" {
+		out.AppendInfo(output)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to join %v with %v: %v", domain, tool, err)
+	}
+	out.AppendInfof("Joined %v with %v.", domain, tool)
+
+	// realmd configures sssd as part of the join
+	if tool == adcliCommand {
+		return configureSssd(log, domain, out)
+	}
+	return nil
+}
+
+// configureSssd writes a sssd configuration for the domain and restarts sssd.
+// An existing configuration is kept as it may have been customized.
+func configureSssd(log log.T, domain string, out iohandler.IOHandler) error {
+	if sssdConfigExists() {
+		out.AppendInfof("Keeping the existing sssd configuration %v.", sssdConfigPath)
+	} else if err := writeSssdConfig(sssdConfig(domain)); err != nil {
+		return fmt.Errorf("failed to configure sssd: %v", err)
+	}
+
+	if _, err := runCommand(log, "systemctl", []string{"restart", "sssd"}, ""); err != nil {
+		if output, err := runCommand(log, "service", []string{"sssd", "restart"}, ""); err != nil {
+			return fmt.Errorf("failed to restart sssd: %v %v", err, output)
+		}
+	}
+	out.AppendInfof("Configured sssd for %v.", domain)
+	return nil
+}
+
+// sssdConfig returns the sssd configuration of an active directory domain joined with adcli.
+func sssdConfig(domain string) string {
+	return fmt.Sprintf(`[sssd]
+domains = %[1]v
+config_file_version = 2
+services = nss, pam
+
+[domain/%[1]v]
+ad_domain = %[1]v
+krb5_realm = %[2]v
+id_provider = ad
+access_provider = ad
+cache_credentials = True
+krb5_store_password_if_offline = True
+ldap_id_mapping = True
+use_fully_qualified_names = True
+default_shell = /bin/bash
+fallback_homedir = /home/%%u@%%d
+`, domain, strings.ToUpper(domain))
+}
+
+// verify checks the computer account in the domain and that sssd resolves the directory user.
+// The membership must be verified, the user lookup is only reported as sssd may still be starting.
+func verify(log log.T, tool string, username string, domain string, out iohandler.IOHandler) error {
+	if !isJoined(log, tool, domain) {
+		out.AppendInfof("Verification of the membership in %v: failed", domain)
+		return fmt.Errorf("the instance is not a member of %v after the join", domain)
+	}
+	out.AppendInfof("Verification of the membership in %v: passed", domain)
+
+	account := username
+	if !strings.Contains(account, "@") {
+		account = account + "@" + domain
+	}
+	if output, err := runCommand(log, "id", []string{account}, ""); err != nil {
+		out.AppendInfof("Verification of the lookup of %v: failed (%v %v)", account, err, output)
+	} else {
+		out.AppendInfof("Verification of the lookup of %v: passed (%v)", account, output)
+	}
+	return nil
+}
+
+// runJoinCommand runs the command and returns its combined output, stdin is never logged as it holds the password.
+func runJoinCommand(log log.T, name string, args []string, stdin string) (string, error) {
+	log.Debugf("Running %v %v", name, args)
+	command := exec.Command(name, args...)
+	if stdin != "" {
+		command.Stdin = strings.NewReader(stdin)
+	}
+	output, err := command.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
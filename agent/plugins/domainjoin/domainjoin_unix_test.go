@@ -0,0 +1,202 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package domainjoin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// fakeCommands records the commands run by the plugin and answers them from the given handler.
+type fakeCommands struct {
+	run   []string
+	stdin []string
+}
+
+func stubJoin(t *testing.T, tools []string, handler func(command string) (string, error)) (*fakeCommands, func()) {
+	oldLookPath, oldGetSecureString, oldRunCommand := lookPath, getSecureString, runCommand
+	oldSssdConfigExists, oldWriteSssdConfig := sssdConfigExists, writeSssdConfig
+
+	fake := &fakeCommands{}
+	lookPath = func(file string) (string, error) {
+		for _, tool := range tools {
+			if tool == file {
+				return "/usr/sbin/" + file, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+	getSecureString = func(log log.T, name string) (string, error) {
+		assert.Equal(t, "ad/password", name)
+		return "s3cr3t", nil
+	}
+	runCommand = func(log log.T, name string, args []string, stdin string) (string, error) {
+		command := strings.Join(append([]string{name}, args...), " ")
+		fake.run = append(fake.run, command)
+		fake.stdin = append(fake.stdin, stdin)
+		return handler(command)
+	}
+	sssdConfigExists = func() bool { return false }
+	writeSssdConfig = func(content string) error { return nil }
+
+	return fake, func() {
+		lookPath, getSecureString, runCommand = oldLookPath, oldGetSecureString, oldRunCommand
+		sssdConfigExists, writeSssdConfig = oldSssdConfigExists, oldWriteSssdConfig
+	}
+}
+
+func testInput() DomainJoinPluginInput {
+	return DomainJoinPluginInput{
+		DirectoryName:     "Corp.Example.com",
+		DirectoryOU:       "OU=Servers,DC=corp,DC=example,DC=com",
+		Username:          "admin",
+		PasswordParameter: "ad/password",
+	}
+}
+
+func TestJoinDomainWithRealm(t *testing.T) {
+	joined := false
+	fake, restore := stubJoin(t, []string{realmCommand, adcliCommand}, func(command string) (string, error) {
+		switch {
+		case strings.HasPrefix(command, "realm list"):
+			if joined {
+				return "corp.example.com", nil
+			}
+			return "", nil
+		case strings.HasPrefix(command, "realm join"):
+			joined = true
+			return "Successfully enrolled machine in realm", nil
+		}
+		return "uid=1001(admin@corp.example.com)", nil
+	})
+	defer restore()
+	out := &iohandler.DefaultIOHandler{}
+
+	new(Plugin).joinDomain(logger, testInput(), out)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, out.GetStatus())
+	assert.Contains(t, fake.run, "realm join --verbose --user=admin --computer-ou=OU=Servers,DC=corp,DC=example,DC=com corp.example.com")
+	assert.Contains(t, fake.stdin, "s3cr3t\n")
+	assert.Contains(t, fake.run, "id admin@corp.example.com")
+	assert.Contains(t, out.GetStdout(), "Verification of the membership in corp.example.com: passed")
+	assert.NotContains(t, out.GetStdout(), "s3cr3t")
+}
+
+func TestJoinDomainWithAdcli(t *testing.T) {
+	joined := false
+	written := ""
+	fake, restore := stubJoin(t, []string{adcliCommand}, func(command string) (string, error) {
+		switch {
+		case strings.HasPrefix(command, "adcli testjoin"):
+			if !joined {
+				return "", errors.New("exit status 1")
+			}
+		case strings.HasPrefix(command, "adcli join"):
+			joined = true
+		}
+		return "", nil
+	})
+	defer restore()
+	writeSssdConfig = func(content string) error {
+		written = content
+		return nil
+	}
+	out := &iohandler.DefaultIOHandler{}
+
+	new(Plugin).joinDomain(logger, testInput(), out)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, out.GetStatus())
+	assert.Contains(t, fake.run, "adcli join --verbose --domain=corp.example.com --login-user=admin --stdin-password --domain-ou=OU=Servers,DC=corp,DC=example,DC=com")
+	assert.Contains(t, fake.run, "systemctl restart sssd")
+	assert.Contains(t, written, "[domain/corp.example.com]")
+	assert.Contains(t, written, "krb5_realm = CORP.EXAMPLE.COM")
+}
+
+func TestJoinDomainAlreadyJoined(t *testing.T) {
+	fake, restore := stubJoin(t, []string{realmCommand}, func(command string) (string, error) {
+		return "corp.example.com", nil
+	})
+	defer restore()
+	getSecureString = func(log log.T, name string) (string, error) {
+		assert.Fail(t, "the password should not be read")
+		return "", nil
+	}
+	out := &iohandler.DefaultIOHandler{}
+
+	new(Plugin).joinDomain(logger, testInput(), out)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, out.GetStatus())
+	for _, command := range fake.run {
+		assert.False(t, strings.HasPrefix(command, "realm join"))
+	}
+	assert.Contains(t, out.GetStdout(), "already joined")
+}
+
+func TestJoinDomainFailure(t *testing.T) {
+	_, restore := stubJoin(t, []string{realmCommand}, func(command string) (string, error) {
+		if strings.HasPrefix(command, "realm join") {
+			return "realm: Couldn't join realm: Insufficient permissions", errors.New("exit status 1")
+		}
+		return "", nil
+	})
+	defer restore()
+	out := &iohandler.DefaultIOHandler{}
+
+	new(Plugin).joinDomain(logger, testInput(), out)
+
+	assert.Equal(t, contracts.ResultStatusFailed, out.GetStatus())
+	assert.Contains(t, out.GetStdout(), "Insufficient permissions")
+}
+
+func TestJoinDomainInvalidInput(t *testing.T) {
+	_, restore := stubJoin(t, []string{realmCommand}, func(command string) (string, error) {
+		assert.Fail(t, "no command should run")
+		return "", nil
+	})
+	defer restore()
+
+	for _, input := range []DomainJoinPluginInput{
+		{},
+		{DirectoryName: "corp.example.com\n[sssd]", Username: "admin", PasswordParameter: "ad/password"},
+		{DirectoryName: "corp.example.com", PasswordParameter: "ad/password"},
+		{DirectoryName: "corp.example.com", Username: "admin"},
+	} {
+		out := &iohandler.DefaultIOHandler{}
+		new(Plugin).joinDomain(logger, input, out)
+		assert.Equal(t, contracts.ResultStatusFailed, out.GetStatus())
+	}
+}
+
+func TestJoinDomainWithoutTools(t *testing.T) {
+	_, restore := stubJoin(t, nil, func(command string) (string, error) {
+		return "", nil
+	})
+	defer restore()
+	out := &iohandler.DefaultIOHandler{}
+
+	new(Plugin).joinDomain(logger, testInput(), out)
+
+	assert.Equal(t, contracts.ResultStatusFailed, out.GetStatus())
+	assert.Contains(t, out.GetStderr(), "realmd or adcli")
+}
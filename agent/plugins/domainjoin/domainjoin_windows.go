@@ -13,7 +13,6 @@
 //
 // +build windows
 
-// Package domainjoin implements the domainjoin plugin.
 package domainjoin
 
 import (
@@ -65,30 +64,6 @@ var makeArgs = makeArguments
 var getRegion = platform.Region
 var utilExe convert
 
-// Plugin is the type for the domain join plugin.
-type Plugin struct {
-}
-
-// DomainJoinPluginInput represents one set of commands executed by the Domain join plugin.
-type DomainJoinPluginInput struct {
-	contracts.PluginInput
-	DirectoryId    string
-	DirectoryName  string
-	DirectoryOU    string
-	DnsIpAddresses []string
-}
-
-// NewPlugin returns a new instance of the plugin.
-func NewPlugin() (*Plugin, error) {
-	var plugin Plugin
-	return &plugin, nil
-}
-
-// Name returns the plugin name
-func Name() string {
-	return appconfig.PluginNameDomainJoin
-}
-
 type convert func(log.T, string, []string, string, string, io.Writer, io.Writer, bool) (string, error)
 
 func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
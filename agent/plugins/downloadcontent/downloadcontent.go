@@ -23,12 +23,15 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitreporesource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitresource/privategithub"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/httpresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/s3resource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/ssmdocresource"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 
 	"errors"
 	"fmt"
@@ -41,6 +44,8 @@ import (
 
 const (
 	GitHub      = "GitHub"      //Github represents the source type "GitHub" from where the resource can be downloaded
+	Git         = "Git"         //Git represents the source type "Git", any git repository the resource can be fetched from
+	HTTP        = "HTTP"        //HTTP represents the source type "HTTP", an https server the file is downloaded from
 	S3          = "S3"          //S3 represents the source type "S3" from where the resource is being downloaded
 	SSMDocument = "SSMDocument" //SSMDocument represents the source type as SSM Document
 
@@ -71,6 +76,8 @@ type DownloadContentPlugin struct {
 	SourceType      string `json:"sourceType"`
 	SourceInfo      string `json:"sourceInfo"`
 	DestinationPath string `json:"destinationPath"`
+	// VerificationPolicy overrides the verification policy of the agent configuration, it may only be stricter
+	VerificationPolicy string `json:"verificationPolicy"`
 	// TODO: 08/25/2017 meloniam@ Change the type of SourceInfo and documentParameters to map[string]interface{}
 	// TODO: https://amazon.awsapps.com/workdocs/index.html#/document/7d56a42ea5b040a7c33548d77dc98040f0fb380bbbfb2fd580c861225e2ee1c7
 }
@@ -83,6 +90,10 @@ func newRemoteResource(log log.T, SourceType string, SourceInfo string) (resourc
 		// TODO: https://amazon.awsapps.com/workdocs/index.html#/document/7d56a42ea5b040a7c33548d77dc98040f0fb380bbbfb2fd580c861225e2ee1c7
 		token := privategithub.NewTokenInfoImpl()
 		return gitresource.NewGitResource(log, SourceInfo, token)
+	case Git:
+		return gitreporesource.NewGitRepoResource(log, SourceInfo, privategithub.NewTokenInfoImpl())
+	case HTTP:
+		return httpresource.NewHTTPResource(log, SourceInfo, privategithub.NewTokenInfoImpl())
 	case S3:
 		return s3resource.NewS3Resource(log, SourceInfo)
 	case SSMDocument:
@@ -120,6 +131,12 @@ func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config
 	//Run aws:downloadContent plugin
 	log.Debug("Inside run downloadcontent function")
 
+	policy, err := verification.EffectivePolicy(input.VerificationPolicy)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
 	// remoteResourceCreator makes a call to a function that creates a new remote resource based on the source type
 	log.Debug("Creating resource of type - ", input.SourceType)
 	remoteResource, err := p.remoteResourceCreator(log, input.SourceType, input.SourceInfo)
@@ -154,16 +171,40 @@ func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config
 		return
 	}
 
+	if err := verifyContent(log, policy, result); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
 	if err := setPermissions(log, result); err != nil {
 		output.MarkAsFailed(fmt.Errorf("Failed to set right permissions to the content. Error - %v", err))
 		return
 	}
 
+	filesChanged := result.Files
+	if filesChanged == nil {
+		filesChanged = []string{}
+	}
+	output.SetResultField(contracts.ResultFieldFilesChanged, filesChanged)
 	output.AppendInfof("Content downloaded to %v", destinationPath)
 	output.MarkAsSucceeded()
 	return
 }
 
+// verifyContent verifies the downloaded files as the verification policy requires
+func verifyContent(log log.T, policy verification.Policy, result *remoteresource.DownloadResult) error {
+	for _, path := range result.Files {
+		artifact, ok := result.Artifacts[path]
+		if !ok {
+			artifact = verification.Artifact{LocalPath: path}
+		}
+		if err := verification.Verify(log, policy, artifact); err != nil {
+			return fmt.Errorf("Failed to verify the content. Error - %v", err)
+		}
+	}
+	return nil
+}
+
 func setPermissions(log log.T, result *remoteresource.DownloadResult) error {
 	for _, path := range result.Files {
 		log.Infof("Setting permission for file %v", path)
@@ -205,7 +246,7 @@ func validateInput(input *DownloadContentPlugin) (valid bool, err error) {
 		return false, errors.New("SourceType must be specified")
 	}
 	//ensure all entries are valid
-	if input.SourceType != GitHub && input.SourceType != S3 && input.SourceType != SSMDocument {
+	if input.SourceType != GitHub && input.SourceType != Git && input.SourceType != HTTP && input.SourceType != S3 && input.SourceType != SSMDocument {
 		return false, errors.New("Unsupported source type")
 	}
 	// ensure non-empty source info
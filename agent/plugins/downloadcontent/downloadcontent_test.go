@@ -105,6 +105,7 @@ func TestNewPlugin_RunCopyContent(t *testing.T) {
 	}
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
+	mockIOHandler.On("SetResultField", contracts.ResultFieldFilesChanged, mock.Anything).Return()
 
 	SetPermission = stubChmod
 	p.runCopyContent(logger, &input, config, mockIOHandler)
@@ -131,6 +132,7 @@ func TestNewPlugin_RunCopyContent_absPathDestinationDir(t *testing.T) {
 	}
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
+	mockIOHandler.On("SetResultField", contracts.ResultFieldFilesChanged, mock.Anything).Return()
 
 	SetPermission = stubChmod
 	p.runCopyContent(logger, &input, config, mockIOHandler)
@@ -156,6 +158,7 @@ func TestNewPlugin_RunCopyContent_relativeDirDestinationPath(t *testing.T) {
 	}
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
+	mockIOHandler.On("SetResultField", contracts.ResultFieldFilesChanged, mock.Anything).Return()
 
 	SetPermission = stubChmod
 	p.runCopyContent(logger, &input, config, mockIOHandler)
@@ -216,6 +219,7 @@ func TestPlugin_ExecuteGitHubFile(t *testing.T) {
 
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
+	mockIOHandler.On("SetResultField", contracts.ResultFieldFilesChanged, mock.Anything).Return()
 
 	githubRemoteresourceMock := func(log log.T, locationtype, locationInfo string) (remoteresource.RemoteResource, error) {
 
@@ -256,6 +260,7 @@ func TestPlugin_ExecuteS3File(t *testing.T) {
 	var s3CopyContentFileMock = filemock.FileSystemMock{}
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
+	mockIOHandler.On("SetResultField", contracts.ResultFieldFilesChanged, mock.Anything).Return()
 
 	s3MockRemoteResource := func(log log.T, locationtype, locationInfo string) (remoteresource.RemoteResource, error) {
 
@@ -295,6 +300,7 @@ func TestPlugin_ExecuteSSMDoc(t *testing.T) {
 	var ssmDocCopyContentFileMock = filemock.FileSystemMock{}
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
+	mockIOHandler.On("SetResultField", contracts.ResultFieldFilesChanged, mock.Anything).Return()
 
 	ssmDocMockRemoteResource := func(log log.T, locationtype, locationInfo string) (remoteresource.RemoteResource, error) {
 		ssmDocCopyContentResourceMock.On("ValidateLocationInfo").Return(true, nil).Once()
@@ -392,6 +398,30 @@ func TestValidateInput_NoLocationInfo(t *testing.T) {
 	assert.Contains(t, err.Error(), "SourceInfo must be specified")
 }
 
+func TestValidateInput_GitSourceType(t *testing.T) {
+
+	input := DownloadContentPlugin{}
+	input.SourceType = "Git"
+	input.SourceInfo = `{"repository": "https://git.example.com/team/repo.git"}`
+
+	result, err := validateInput(&input)
+
+	assert.True(t, result)
+	assert.NoError(t, err)
+}
+
+func TestValidateInput_HTTPSourceType(t *testing.T) {
+
+	input := DownloadContentPlugin{}
+	input.SourceType = "HTTP"
+	input.SourceInfo = `{"url": "https://artifacts.example.com/app.zip"}`
+
+	result, err := validateInput(&input)
+
+	assert.True(t, result)
+	assert.NoError(t, err)
+}
+
 func TestName(t *testing.T) {
 	assert.Equal(t, "aws:downloadContent", Name())
 }
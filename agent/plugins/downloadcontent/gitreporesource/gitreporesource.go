@@ -0,0 +1,290 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package gitreporesource implements the methods to download the content of any git repository
+package gitreporesource
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/system"
+
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultRef      = "HEAD"
+	defaultUsername = "git"
+	gitDirName      = ".git"
+)
+
+// credentialsGitVersion is the first version of git reading its configuration from GIT_CONFIG_COUNT, which the
+// credentials are passed in
+var credentialsGitVersion = [2]int{2, 31}
+
+// gitVersionPattern matches the major and minor version in the output of git version, e.g. git version 2.39.2
+var gitVersionPattern = regexp.MustCompile(`git version (\d+)\.(\d+)`)
+
+// gitVersion returns the output of git version, assigned to a global variable to allow unittest to override
+var gitVersion = func() (string, error) {
+	output, err := exec.Command("git", "version").Output()
+	return string(output), err
+}
+
+// runGit runs a git command in the directory, assigned to a global variable to allow unittest to override
+var runGit = func(log log.T, dir string, env []string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %v failed - %v, %v", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// TokenResolver resolves the secure string parameter holding the credentials of the repository
+type TokenResolver interface {
+	GetToken(log log.T, tokenInfo string) (string, error)
+}
+
+// GitRepoResource is a struct for the remote resource of type Git
+type GitRepoResource struct {
+	Info  GitRepoInfo
+	token string
+}
+
+// GitRepoInfo represents the sourceInfo type sent by runcommand
+type GitRepoInfo struct {
+	Repository string `json:"repository"`
+	Ref        string `json:"ref"`
+	Path       string `json:"path"`
+	Username   string `json:"username"`
+	TokenInfo  string `json:"tokenInfo"`
+}
+
+// NewGitRepoResource is a constructor of type GitRepoResource
+func NewGitRepoResource(log log.T, info string, tokenResolver TokenResolver) (repo *GitRepoResource, err error) {
+	var repoInfo GitRepoInfo
+	if err = jsonutil.Unmarshal(info, &repoInfo); err != nil {
+		return nil, fmt.Errorf("Source Info could not be unmarshalled for source type Git. Please check JSON format of sourceInfo - %v", err.Error())
+	}
+	repo = &GitRepoResource{Info: repoInfo}
+
+	// Get the access token from Parameter store
+	if repoInfo.TokenInfo != "" {
+		if repo.token, err = tokenResolver.GetToken(log, repoInfo.TokenInfo); err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
+}
+
+// ValidateLocationInfo ensures that the required parameters of SourceInfo are specified and safe to pass to git
+func (repo *GitRepoResource) ValidateLocationInfo() (valid bool, err error) {
+	if repo.Info.Repository == "" {
+		return false, errors.New("Repository for Git SourceType must be specified")
+	}
+	repoURL, err := url.Parse(repo.Info.Repository)
+	if err != nil || repoURL.Scheme != "https" || repoURL.Host == "" {
+		return false, errors.New("Repository for Git SourceType must be an https URL")
+	}
+	if repoURL.User != nil {
+		return false, errors.New("Repository for Git SourceType must not contain credentials, use tokenInfo instead")
+	}
+	if strings.HasPrefix(repo.Info.Ref, "-") {
+		return false, fmt.Errorf("Invalid ref for Git SourceType - %v", repo.Info.Ref)
+	}
+	if filepath.IsAbs(repo.Info.Path) || strings.HasPrefix(filepath.Clean(filepath.FromSlash(repo.Info.Path)), "..") {
+		return false, errors.New("Path for Git SourceType must be relative to the root of the repository")
+	}
+	return true, nil
+}
+
+// DownloadRemoteResource fetches the repository at the ref and copies the content of path to the destination
+func (repo *GitRepoResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destPath string) (err error, result *remoteresource.DownloadResult) {
+	if destPath == "" {
+		destPath = appconfig.DownloadRoot
+	}
+
+	var cloneDir string
+	if cloneDir, err = fileutil.TempDir("", "gitrepo"); err != nil {
+		return fmt.Errorf("failed to create directory to fetch the repository - %v", err), nil
+	}
+	defer os.RemoveAll(cloneDir)
+
+	if err = repo.fetch(log, cloneDir); err != nil {
+		return err, nil
+	}
+
+	source, err := resolvePath(cloneDir, repo.Info.Path)
+	if err != nil {
+		return err, nil
+	}
+	result = &remoteresource.DownloadResult{}
+	if fileutil.IsDirectory(source) {
+		err = copyDirectory(log, filesys, source, destPath, result)
+	} else {
+		// a single file is placed in the destination directory if it exists or ends with a path separator
+		if (filesys.Exists(destPath) && filesys.IsDirectory(destPath)) || os.IsPathSeparator(destPath[len(destPath)-1]) {
+			destPath = filepath.Join(destPath, filepath.Base(source))
+		}
+		err = copyFile(log, filesys, source, destPath, result)
+	}
+
+	if err != nil {
+		return err, nil
+	}
+	return nil, result
+}
+
+// resolvePath returns the location of the path in the repository, making sure symbolic links don't lead out of it
+func resolvePath(cloneDir, path string) (string, error) {
+	root, err := filepath.EvalSymlinks(cloneDir)
+	if err != nil {
+		return "", err
+	}
+	source, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(path)))
+	if err != nil {
+		return "", fmt.Errorf("Path %v not found in the repository", path)
+	}
+	if source != root && !strings.HasPrefix(source, root+string(os.PathSeparator)) {
+		return "", fmt.Errorf("Path %v leads out of the repository", path)
+	}
+	return source, nil
+}
+
+// fetch checks out the ref of the repository in the directory, fetching only the last commit when possible
+func (repo *GitRepoResource) fetch(log log.T, cloneDir string) (err error) {
+	ref := repo.Info.Ref
+	if ref == "" {
+		ref = defaultRef
+	}
+	if repo.token != "" {
+		if err = checkCredentialsSupported(); err != nil {
+			return
+		}
+	}
+	env := repo.gitEnv()
+
+	if err = runGit(log, cloneDir, env, "init", "--quiet"); err != nil {
+		return
+	}
+	log.Infof("Fetching %v of %v", ref, repo.Info.Repository)
+	if err = runGit(log, cloneDir, env, "fetch", "--quiet", "--depth", "1", "--", repo.Info.Repository, ref); err == nil {
+		return runGit(log, cloneDir, env, "checkout", "--quiet", "--detach", "FETCH_HEAD")
+	}
+
+	// servers may refuse to fetch a commit which isn't the tip of a branch or tag by itself
+	log.Debugf("Shallow fetch of %v failed, fetching the whole repository - %v", ref, err)
+	if err = runGit(log, cloneDir, env, "fetch", "--quiet", "--", repo.Info.Repository,
+		"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"); err != nil {
+		return
+	}
+	return runGit(log, cloneDir, env, "checkout", "--quiet", "--detach", ref)
+}
+
+// gitEnv returns the environment of the git commands. The credentials are passed as an http header in the
+// environment so they aren't visible in the command line of the process nor saved in the repository configuration.
+func (repo *GitRepoResource) gitEnv() (env []string) {
+	env = []string{"GIT_TERMINAL_PROMPT=0"}
+	if repo.token == "" {
+		return
+	}
+	username := repo.Info.Username
+	if username == "" {
+		username = defaultUsername
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + repo.token))
+	return append(env,
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials)
+}
+
+// checkCredentialsSupported returns an error if git is too old to read the credentials from the environment,
+// they aren't passed in the command line instead where they would be visible to the other users of the instance
+func checkCredentialsSupported() error {
+	output, err := gitVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get the version of git - %v", err)
+	}
+	match := gitVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return fmt.Errorf("failed to parse the version of git %v", strings.TrimSpace(output))
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	if major < credentialsGitVersion[0] || (major == credentialsGitVersion[0] && minor < credentialsGitVersion[1]) {
+		return fmt.Errorf("git %v.%v or later is required to fetch a repository with tokenInfo, the instance has %v",
+			credentialsGitVersion[0], credentialsGitVersion[1], strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// copyDirectory copies the files of the directory to the destination, except the git metadata
+func copyDirectory(log log.T, filesys filemanager.FileSystem, sourceDir, destDir string, result *remoteresource.DownloadResult) error {
+	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == gitDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			log.Debugf("Skipping %v which is not a regular file", path)
+			return nil
+		}
+		relativePath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(log, filesys, path, filepath.Join(destDir, relativePath), result)
+	})
+}
+
+// copyFile copies a file of the repository to the destination, keeping its mode so scripts remain executable
+func copyFile(log log.T, filesys filemanager.FileSystem, source, destination string, result *remoteresource.DownloadResult) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	if err = system.SaveFileContent(log, filesys, destination, string(content)); err != nil {
+		return err
+	}
+	if err = os.Chmod(destination, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set the mode of %v - %v", destination, err)
+	}
+	result.Files = append(result.Files, destination)
+	return nil
+}
@@ -0,0 +1,262 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package httpresource implements the methods to download a file from an http server
+package httpresource
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	partialSuffix = ".partial"
+)
+
+// secureParameterPattern matches the references to secure string parameters in the values of the headers
+var secureParameterPattern = regexp.MustCompile(`{{\s*ssm-secure:[\w-/]+\s*}}`)
+
+// anyParameterPattern matches any parameter reference in the values of the headers
+var anyParameterPattern = regexp.MustCompile(`{{.*?}}`)
+
+// TokenResolver resolves the secure string parameters referenced in the headers
+type TokenResolver interface {
+	GetToken(log log.T, tokenInfo string) (string, error)
+}
+
+// HTTPResource is a struct for the remote resource of type HTTP
+type HTTPResource struct {
+	Info    HTTPInfo
+	headers http.Header
+	client  *http.Client
+}
+
+// HTTPInfo represents the sourceInfo type sent by runcommand
+type HTTPInfo struct {
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Checksums map[string]string `json:"checksums"`
+}
+
+// NewHTTPResource is a constructor of type HTTPResource
+func NewHTTPResource(log log.T, info string, tokenResolver TokenResolver) (resource *HTTPResource, err error) {
+	var httpInfo HTTPInfo
+	if err = jsonutil.Unmarshal(info, &httpInfo); err != nil {
+		return nil, fmt.Errorf("Source Info could not be unmarshalled for source type HTTP. Please check JSON format of sourceInfo - %v", err.Error())
+	}
+	httpInfo.URL = strings.TrimSpace(httpInfo.URL)
+
+	resource = &HTTPResource{Info: httpInfo, headers: http.Header{}}
+	resource.client = &http.Client{Transport: proxyconfig.NewTransport(""), CheckRedirect: resource.checkRedirect}
+
+	// Resolve the secure string parameters of the headers from Parameter store.
+	// NOTE: Do not log the values of the headers
+	for name, value := range httpInfo.Headers {
+		if resource.headers[http.CanonicalHeaderKey(name)], err = resolveHeader(log, tokenResolver, name, value); err != nil {
+			return nil, err
+		}
+	}
+	return resource, nil
+}
+
+// resolveHeader replaces the secure string parameters referenced in the value of a header with their values.
+// Only secure string parameters are accepted so credentials aren't stored in plain text parameters.
+func resolveHeader(log log.T, tokenResolver TokenResolver, name, value string) (resolved []string, err error) {
+	for _, reference := range anyParameterPattern.FindAllString(value, -1) {
+		if !secureParameterPattern.MatchString(reference) {
+			return nil, fmt.Errorf("Header %v must reference parameters as '{{ ssm-secure:parameter-name }}'", name)
+		}
+	}
+
+	value = secureParameterPattern.ReplaceAllStringFunc(value, func(reference string) string {
+		if err != nil {
+			return ""
+		}
+		var token string
+		token, err = tokenResolver.GetToken(log, reference)
+		return token
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not resolve the value of header %v - %v", name, err)
+	}
+	return []string{value}, nil
+}
+
+// ValidateLocationInfo ensures that the required parameters of SourceInfo are specified
+func (resource *HTTPResource) ValidateLocationInfo() (valid bool, err error) {
+	if resource.Info.URL == "" {
+		return false, errors.New("URL for HTTP SourceType must be specified")
+	}
+	sourceURL, err := url.Parse(resource.Info.URL)
+	if err != nil || sourceURL.Scheme != "https" || sourceURL.Host == "" {
+		return false, errors.New("URL for HTTP SourceType must be an https URL")
+	}
+	if sourceURL.User != nil {
+		return false, errors.New("URL for HTTP SourceType must not contain credentials, use headers instead")
+	}
+	for algorithm, value := range resource.Info.Checksums {
+		if verification.NewHash(algorithm) == nil {
+			return false, fmt.Errorf("Unsupported checksum algorithm for HTTP SourceType - %v", algorithm)
+		}
+		if value == "" {
+			return false, fmt.Errorf("Checksum %v for HTTP SourceType must not be empty", algorithm)
+		}
+	}
+	return true, nil
+}
+
+// DownloadRemoteResource downloads the file to the destination and verifies its checksums
+func (resource *HTTPResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destPath string) (err error, result *remoteresource.DownloadResult) {
+	if destPath == "" {
+		destPath = appconfig.DownloadRoot
+	}
+	// the file is placed in the destination directory if it exists or ends with a path separator
+	if (filesys.Exists(destPath) && filesys.IsDirectory(destPath)) || os.IsPathSeparator(destPath[len(destPath)-1]) {
+		destPath = filepath.Join(destPath, resource.fileName())
+	}
+	if err = filesys.MakeDirs(filepath.Dir(destPath)); err != nil {
+		return fmt.Errorf("failed to create directory for the download - %v", err), nil
+	}
+
+	// the file is downloaded next to the destination and only moved in place once its checksums are verified
+	partialPath := destPath + partialSuffix
+	defer os.Remove(partialPath)
+
+	log.Infof("Downloading %v to %v", resource.redactedURL(), destPath)
+	checksums, err := resource.download(log, partialPath)
+	if err != nil {
+		return err, nil
+	}
+	for algorithm, expected := range resource.Info.Checksums {
+		if actual := checksums[strings.ToLower(algorithm)]; !strings.EqualFold(strings.TrimSpace(expected), actual) {
+			return fmt.Errorf("%v checksum of %v doesn't match, expected %v but downloaded %v", algorithm, resource.redactedURL(), expected, actual), nil
+		}
+	}
+
+	if err = os.Rename(partialPath, destPath); err != nil {
+		return fmt.Errorf("failed to move the download to %v - %v", destPath, err), nil
+	}
+	result = &remoteresource.DownloadResult{}
+	result.AddArtifact(verification.Artifact{SourceURL: resource.Info.URL, LocalPath: destPath, Checksums: resource.Info.Checksums})
+	return nil, result
+}
+
+// download saves the response to the file and returns the requested checksums of the content
+func (resource *HTTPResource) download(log log.T, filePath string) (checksums map[string]string, err error) {
+	request, err := http.NewRequest(http.MethodGet, resource.Info.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range resource.headers {
+		request.Header[name] = values
+	}
+
+	response, err := resource.client.Do(request)
+	if err != nil {
+		// the error of the client contains the url, which could include a signed query
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to download %v - %v", resource.redactedURL(), err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %v - server returned %v", resource.redactedURL(), response.Status)
+	}
+	if err = fileutil.CheckDiskSpace(filePath, response.ContentLength); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, appconfig.ReadWriteAccess)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hashes := map[string]hash.Hash{}
+	writers := []io.Writer{file}
+	for algorithm := range resource.Info.Checksums {
+		hasher := verification.NewHash(algorithm)
+		hashes[strings.ToLower(algorithm)] = hasher
+		writers = append(writers, hasher)
+	}
+	size, err := io.Copy(io.MultiWriter(writers...), bandwidth.DownloadReader(response.Body))
+	artifact.RecordDownloadedBytes(size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %v - %v", resource.redactedURL(), err)
+	}
+	log.Infof("%v bytes downloaded", size)
+
+	checksums = map[string]string{}
+	for algorithm, hasher := range hashes {
+		checksums[algorithm] = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return checksums, nil
+}
+
+// checkRedirect only follows redirects over https and doesn't send the headers to other hosts
+func (resource *HTTPResource) checkRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if request.URL.Scheme != "https" {
+		return errors.New("redirect to a non https URL is not allowed")
+	}
+	if request.URL.Host != via[0].URL.Host {
+		for name := range resource.headers {
+			request.Header.Del(name)
+		}
+	}
+	return nil
+}
+
+// fileName returns the name of the file in the URL
+func (resource *HTTPResource) fileName() string {
+	if sourceURL, err := url.Parse(resource.Info.URL); err == nil {
+		if name := path.Base(sourceURL.Path); name != "/" && name != "." {
+			return name
+		}
+	}
+	return "download"
+}
+
+// redactedURL returns the URL without its query, which may hold a signature
+func (resource *HTTPResource) redactedURL() string {
+	sourceURL, err := url.Parse(resource.Info.URL)
+	if err != nil {
+		return resource.Info.URL
+	}
+	sourceURL.RawQuery = ""
+	return sourceURL.String()
+}
@@ -18,6 +18,7 @@ package remoteresource
 import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 )
 
 const (
@@ -27,6 +28,17 @@ const (
 
 type DownloadResult struct {
 	Files []string
+	// Artifacts holds the location and checksums of the downloaded files which are known, keyed by file path
+	Artifacts map[string]verification.Artifact
+}
+
+// AddArtifact records the downloaded file with its location and checksums
+func (result *DownloadResult) AddArtifact(artifact verification.Artifact) {
+	if result.Artifacts == nil {
+		result.Artifacts = make(map[string]verification.Artifact)
+	}
+	result.Files = append(result.Files, artifact.LocalPath)
+	result.Artifacts[artifact.LocalPath] = artifact
 }
 
 // RemoteResource is an interface for accessing remote resources. Every type of remote resource is expected to implement RemoteResource interface
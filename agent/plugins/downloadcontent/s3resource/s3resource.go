@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/system"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 
 	"errors"
 	"fmt"
@@ -174,7 +175,10 @@ func (s3 *S3Resource) DownloadRemoteResource(log log.T, filesys filemanager.File
 					nil
 			}
 
-			result.Files = append(result.Files, filepath.Join(input.DestinationDirectory, destinationFile))
+			result.AddArtifact(verification.Artifact{
+				SourceURL: input.SourceURL,
+				LocalPath: filepath.Join(input.DestinationDirectory, destinationFile),
+			})
 		}
 	}
 	return nil, result
@@ -38,9 +38,12 @@ type SSMDocResource struct {
 	ssmdocdep ssmdeps
 }
 
-// S3Info represents the sourceInfo type sent by runcommand
+// SSMDocInfo represents the sourceInfo type sent by runcommand
 type SSMDocInfo struct {
+	// DocName is the name or ARN of the document, optionally followed by :version
 	DocName string `json:"name"`
+	// DocVersion is the version of the document to download, the default version when it is empty
+	DocVersion string `json:"version"`
 }
 
 // NewS3Resource is a constructor of type GitResource
@@ -76,17 +79,20 @@ func (ssmdoc *SSMDocResource) DownloadRemoteResource(log log.T, filesys filemana
 
 	result = &remoteresource.DownloadResult{}
 
-	docName, docVersion := docparser.ParseDocumentNameAndVersion(ssmdoc.Info.DocName)
+	docName, docVersion := ssmdoc.nameAndVersion()
 	log.Debug("Making a call to get document", docName, docVersion)
 	var docResponse *ssm.GetDocumentOutput
 	if docResponse, err = ssmdoc.ssmdocdep.GetDocument(log, docName, docVersion); err != nil {
 		log.Errorf("Unable to get ssm document. %v", err)
 		return err, nil
 	}
+	if docResponse.Content == nil {
+		return fmt.Errorf("SSM Document %v has no content", docName), nil
+	}
 
 	var destinationFilePath string
 	if filesys.Exists(destinationPath) && filesys.IsDirectory(destinationPath) || os.IsPathSeparator(destinationPath[len(destinationPath)-1]) {
-		destinationFilePath = filepath.Join(destinationPath, filepath.Base(docName)+remoteresource.JSONExtension)
+		destinationFilePath = filepath.Join(destinationPath, filepath.Base(docName)+documentExtension(docResponse))
 
 	} else {
 		destinationFilePath = destinationPath
@@ -101,10 +107,30 @@ func (ssmdoc *SSMDocResource) DownloadRemoteResource(log log.T, filesys filemana
 	return nil, result
 }
 
+// nameAndVersion returns the name of the document and the version given either in SourceInfo or after its name
+func (ssmdoc *SSMDocResource) nameAndVersion() (docName, docVersion string) {
+	docName, docVersion = docparser.ParseDocumentNameAndVersion(ssmdoc.Info.DocName)
+	if ssmdoc.Info.DocVersion != "" {
+		docVersion = ssmdoc.Info.DocVersion
+	}
+	return docName, docVersion
+}
+
+// documentExtension returns the file extension matching the format of the downloaded document
+func documentExtension(docResponse *ssm.GetDocumentOutput) string {
+	if docResponse.DocumentFormat != nil && *docResponse.DocumentFormat == ssm.DocumentFormatYaml {
+		return remoteresource.YAMLExtension
+	}
+	return remoteresource.JSONExtension
+}
+
 // ValidateLocationInfo ensures that the required parameters of SourceInfo are specified
 func (s3 *SSMDocResource) ValidateLocationInfo() (valid bool, err error) {
 	if s3.Info.DocName == "" {
 		return false, errors.New("SSM Document name in SourceType must be specified")
 	}
+	if _, nameVersion := docparser.ParseDocumentNameAndVersion(s3.Info.DocName); nameVersion != "" && s3.Info.DocVersion != "" && nameVersion != s3.Info.DocVersion {
+		return false, fmt.Errorf("SSM Document version %v in the name does not match the version %v in SourceInfo", nameVersion, s3.Info.DocVersion)
+	}
 	return true, nil
 }
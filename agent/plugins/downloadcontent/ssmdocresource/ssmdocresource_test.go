@@ -134,6 +134,58 @@ func TestSSMDocResource_ValidateLocationInfoNoName(t *testing.T) {
 	assert.Equal(t, "SSM Document name in SourceType must be specified", err.Error())
 }
 
+func TestSSMDocResource_ValidateLocationInfoVersionMismatch(t *testing.T) {
+
+	locationInfo := `{
+		"name": "mySharedDocument:2",
+		"version": "3"
+	}`
+
+	ssmDocInfo, _ := parseSourceInfo(locationInfo)
+	ssmresource := &SSMDocResource{
+		Info: ssmDocInfo,
+	}
+	_, err := ssmresource.ValidateLocationInfo()
+
+	assert.Error(t, err)
+}
+
+func TestSSMDocResource_DownloadVersionOfYAMLDocument(t *testing.T) {
+	depMock := new(ssmDocDepMock)
+	fileMock := filemock.FileSystemMock{}
+
+	locationInfo := `{
+		"name": "mySharedScripts",
+		"version": "$LATEST"
+	}`
+	content := "content"
+	format := ssm.DocumentFormatYaml
+	docOutput := ssm.GetDocumentOutput{
+		Content:        &content,
+		DocumentFormat: &format,
+	}
+	ssmDocInfo, err := parseSourceInfo(locationInfo)
+	ssmresource := &SSMDocResource{
+		Info: ssmDocInfo,
+	}
+	dir := "destination"
+	depMock.On("GetDocument", logMock, "mySharedScripts", "$LATEST").Return(&docOutput, nil)
+
+	fileMock.On("Exists", "destination").Return(true)
+	fileMock.On("IsDirectory", "destination").Return(true)
+	fileMock.On("MakeDirs", dir).Return(nil)
+	fileMock.On("WriteFile", filepath.Join(dir, "mySharedScripts.yaml"), content).Return(nil)
+
+	ssmresource.ssmdocdep = depMock
+
+	err, result := ssmresource.DownloadRemoteResource(logMock, fileMock, "destination")
+
+	assert.NoError(t, err)
+	depMock.AssertExpectations(t)
+	fileMock.AssertExpectations(t)
+	assert.Equal(t, []string{filepath.Join(dir, "mySharedScripts.yaml")}, result.Files)
+}
+
 func TestSSMDocResource_Download(t *testing.T) {
 	depMock := new(ssmDocDepMock)
 	fileMock := filemock.FileSystemMock{}
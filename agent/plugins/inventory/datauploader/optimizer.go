@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -28,8 +29,9 @@ import (
 )
 
 var (
-	lock             sync.RWMutex
-	contentHashStore map[string]string
+	lock               sync.RWMutex
+	contentHashStore   map[string]string
+	lastFullUploadTime time.Time
 )
 
 //TODO: add unit tests
@@ -45,12 +47,15 @@ func machineInfoProvider() (name string, err error) {
 type Optimizer interface {
 	UpdateContentHash(inventoryItemName, hash string) (err error)
 	GetContentHash(inventoryItemName string) (hash string)
+	UpdateLastFullUploadTime(uploadTime time.Time) (err error)
+	GetLastFullUploadTime() (uploadTime time.Time)
 }
 
 // Impl implements content hash optimizations for inventory plugin
 type Impl struct {
-	log      log.T
-	location string //where the content hash data is persisted in file-systems
+	log                    log.T
+	location               string //where the content hash data is persisted in file-systems
+	lastFullUploadLocation string //where the time of the last upload of all inventory content is persisted
 }
 
 func NewOptimizerImpl(context context.T) (*Impl, error) {
@@ -75,8 +80,10 @@ func NewOptimizerImplWithLocation(log log.T, rootDir string, fileName string) (*
 		machineID,
 		rootDir,
 		fileName)
+	optimizer.lastFullUploadLocation = filepath.Join(filepath.Dir(optimizer.location), appconfig.InventoryFullUploadFileName)
 
 	contentHashStore = make(map[string]string)
+	lastFullUploadTime = time.Time{}
 
 	//read old content hash values from file
 	if fileutil.Exists(optimizer.location) {
@@ -94,6 +101,16 @@ func NewOptimizerImplWithLocation(log log.T, rootDir string, fileName string) (*
 		}
 	}
 
+	//read the time of the last full upload, a full upload happens right away if it can't be read
+	if fileutil.Exists(optimizer.lastFullUploadLocation) {
+		if content, err = fileutil.ReadAllText(optimizer.lastFullUploadLocation); err == nil {
+			if lastFullUploadTime, err = time.Parse(time.RFC3339, content); err != nil {
+				optimizer.log.Debugf("Unable to read time of last full upload of inventory plugin - %v", err)
+				lastFullUploadTime = time.Time{}
+			}
+		}
+	}
+
 	return &optimizer, nil
 }
 
@@ -127,3 +144,25 @@ func (i *Impl) GetContentHash(inventoryItemName string) (hash string) {
 
 	return
 }
+
+// UpdateLastFullUploadTime persists the time at which the content of all inventory types was last uploaded
+func (i *Impl) UpdateLastFullUploadTime(uploadTime time.Time) (err error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	lastFullUploadTime = uploadTime
+
+	if _, err = fileutil.WriteIntoFileWithPermissions(i.lastFullUploadLocation, uploadTime.UTC().Format(time.RFC3339), appconfig.ReadWriteAccess); err != nil {
+		err = fmt.Errorf("Unable to update time of last full upload in file - %v because - %v", i.lastFullUploadLocation, err.Error())
+	}
+
+	return
+}
+
+// GetLastFullUploadTime returns the time at which the content of all inventory types was last uploaded, zero if unknown
+func (i *Impl) GetLastFullUploadTime() (uploadTime time.Time) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return lastFullUploadTime
+}
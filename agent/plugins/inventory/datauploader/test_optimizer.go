@@ -15,6 +15,8 @@
 package datauploader
 
 import (
+	"time"
+
 	"github.com/stretchr/testify/mock"
 )
 
@@ -37,3 +39,13 @@ func (m *MockOptimizer) GetContentHash(inventoryItemName string) (hash string) {
 	args := m.Called(inventoryItemName)
 	return args.String(0)
 }
+
+func (m *MockOptimizer) UpdateLastFullUploadTime(uploadTime time.Time) (err error) {
+	args := m.Called(uploadTime)
+	return args.Error(0)
+}
+
+func (m *MockOptimizer) GetLastFullUploadTime() (uploadTime time.Time) {
+	args := m.Called()
+	return args.Get(0).(time.Time)
+}
@@ -19,9 +19,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
@@ -32,6 +36,11 @@ import (
 const (
 	// Name represents name of this component that uploads data to SSM
 	Name = "InventoryUploader"
+
+	// maxItemsPerPutInventory is the maximum count of inventory types accepted by a single PutInventory call
+	maxItemsPerPutInventory = 30
+	// maxPayloadBytesPerPutInventory keeps the serialized items of a single PutInventory call below the request size limit
+	maxPayloadBytesPerPutInventory = 1024 * 1024
 )
 
 // T represents contracts for SSM Inventory data uploader
@@ -46,39 +55,40 @@ type SSMCaller interface {
 
 // InventoryUploader implements functionality to upload data to SSM Inventory.
 type InventoryUploader struct {
-	ssm       SSMCaller
-	optimizer Optimizer //helps inventory plugin to optimize PutInventory calls
+	ssm                 SSMCaller
+	optimizer           Optimizer     //helps inventory plugin to optimize PutInventory calls
+	fullRefreshInterval time.Duration //after which all content is uploaded again even if it hasn't changed
 }
 
 // NewInventoryUploader creates a new InventoryUploader (which sends data to SSM Inventory)
 func NewInventoryUploader(context context.T) (*InventoryUploader, error) {
-	var uploader = InventoryUploader{}
+	var uploader = InventoryUploader{
+		fullRefreshInterval: time.Duration(appconfig.DefaultInventoryFullRefreshIntervalHours) * time.Hour,
+	}
 	var appCfg appconfig.SsmagentConfig
 	var err error
 
 	c := context.With("[" + Name + "]")
 	log := c.Log()
 
-	// setting ssm client config
+	// setting ssm client config, the inventory is uploaded within the configured upload bandwidth
 	cfg := sdkutil.AwsConfig()
+	cfg.HTTPClient.Transport = bandwidth.UploadTransport(cfg.HTTPClient.Transport)
 
 	// overrides ssm client config from appconfig if applicable
 	if appCfg, err = appconfig.Config(false); err == nil {
 
-		if appCfg.Ssm.Endpoint != "" {
-			cfg.Endpoint = &appCfg.Ssm.Endpoint
-		} else {
-			if region, err := platform.Region(); err == nil {
-				if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "ssm"); defaultEndpoint != "" {
-					cfg.Endpoint = &defaultEndpoint
-				}
-			} else {
-				log.Errorf("error fetching the region, %v", err)
-			}
+		region, err := platform.Region()
+		if err != nil {
+			log.Errorf("error fetching the region, %v", err)
+		}
+		if endpoint := appconfig.GetServiceEndpoint(appCfg, appconfig.ServiceNameSsm, region); endpoint != "" {
+			cfg.Endpoint = &endpoint
 		}
 		if appCfg.Agent.Region != "" {
 			cfg.Region = &appCfg.Agent.Region
 		}
+		uploader.fullRefreshInterval = time.Duration(appCfg.Ssm.InventoryFullRefreshIntervalHours) * time.Hour
 	}
 
 	uploader.ssm = ssm.New(session.New(cfg))
@@ -106,28 +116,98 @@ func (u *InventoryUploader) SendDataToSSM(context context.T, items []*ssm.Invent
 		return
 	}
 
-	//setting up input for PutInventory API call
-	params := &ssm.PutInventoryInput{
-		InstanceId: &instanceID,
-		Items:      items,
+	if u.ssm == nil {
+		return
 	}
-	var resp *ssm.PutInventoryOutput
 
-	log.Debugf("Calling PutInventory API with parameters - %v", params)
-	if u.ssm != nil {
-		resp, err = u.ssm.PutInventory(params)
+	//large inventory data is split across multiple PutInventory calls, each call carrying complete inventory types
+	batches := splitIntoBatches(log, items, maxItemsPerPutInventory, maxPayloadBytesPerPutInventory)
+	for index, batch := range batches {
+		//setting up input for PutInventory API call
+		params := &ssm.PutInventoryInput{
+			InstanceId: &instanceID,
+			Items:      batch,
+		}
+		var resp *ssm.PutInventoryOutput
 
-		if err != nil {
+		log.Debugf("Calling PutInventory API (%v of %v) with parameters - %v", index+1, len(batches), params)
+		if resp, err = u.ssm.PutInventory(params); err != nil {
 			log.Errorf("Encountered error while calling PutInventory API %v", err)
-		} else {
-			log.Debugf("PutInventory was called successfully with response - %v", resp)
-			u.updateContentHash(context, items)
+			return
 		}
+
+		log.Debugf("PutInventory was called successfully with response - %v", resp)
+		u.updateContentHash(context, batch)
+	}
+
+	if len(items) > 0 && isFullUpload(items) {
+		u.updateLastFullUploadTime(context)
 	}
 
 	return
 }
 
+// splitIntoBatches splits the inventory items, sorted by type name, into batches which respect the given limits.
+// An inventory type is never split, as PutInventory replaces all the content of a type. An item which alone exceeds
+// the payload limit is trimmed to the entries which fit in it and sent in a batch of its own.
+func splitIntoBatches(log log.T, items []*ssm.InventoryItem, maxItems, maxPayloadBytes int) (batches [][]*ssm.InventoryItem) {
+	sorted := make([]*ssm.InventoryItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return *sorted[i].TypeName < *sorted[j].TypeName
+	})
+
+	var batch []*ssm.InventoryItem
+	batchBytes := 0
+	for _, item := range sorted {
+		dataB, _ := json.Marshal(item)
+		if len(dataB) > maxPayloadBytes {
+			var dropped int
+			if item, dropped = trimContent(item, maxPayloadBytes); dropped > 0 {
+				log.Warnf("%v exceeds the size limit of PutInventory, %v of its %v entries are not uploaded",
+					*item.TypeName, dropped, dropped+len(item.Content))
+			}
+			dataB, _ = json.Marshal(item)
+		}
+		if len(batch) > 0 && (len(batch) >= maxItems || batchBytes+len(dataB) > maxPayloadBytes) {
+			batches = append(batches, batch)
+			batch = nil
+			batchBytes = 0
+		}
+		batch = append(batch, item)
+		batchBytes += len(dataB)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return
+}
+
+// trimContent returns a copy of the inventory item which keeps the leading entries of its content that fit
+// in the payload limit, and the count of entries it dropped.
+func trimContent(item *ssm.InventoryItem, maxPayloadBytes int) (trimmed *ssm.InventoryItem, dropped int) {
+	trimmedItem := *item
+	trimmedItem.Content = []map[string]*string{}
+	emptyB, _ := json.Marshal(&trimmedItem)
+	size := len(emptyB)
+	kept := 0
+	for _, entry := range item.Content {
+		entryB, _ := json.Marshal(entry)
+		// entries after the first one are preceded by a comma
+		entrySize := len(entryB)
+		if kept > 0 {
+			entrySize++
+		}
+		if size+entrySize > maxPayloadBytes {
+			break
+		}
+		size += entrySize
+		kept++
+	}
+	trimmedItem.Content = item.Content[:kept]
+	return &trimmedItem, len(item.Content) - kept
+}
+
 func (u *InventoryUploader) updateContentHash(context context.T, items []*ssm.InventoryItem) {
 	log := context.Log()
 	log.Debugf("Updating cache")
@@ -139,6 +219,39 @@ func (u *InventoryUploader) updateContentHash(context context.T, items []*ssm.In
 	}
 }
 
+func (u *InventoryUploader) updateLastFullUploadTime(context context.T) {
+	if err := u.optimizer.UpdateLastFullUploadTime(time.Now()); err != nil {
+		context.Log().Errorf("failed to update time of last full upload because of - %v", err.Error())
+	}
+}
+
+// isFullUpload returns true if the content of every inventory type is part of the upload
+func isFullUpload(items []*ssm.InventoryItem) bool {
+	for _, item := range items {
+		if item.Content == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// HasOnlyContentHashes returns true if none of the given inventory items carries content, i.e. the data of every
+// inventory type is the same as the one uploaded before and there is no need to call PutInventory.
+func HasOnlyContentHashes(items []*ssm.InventoryItem) bool {
+	for _, item := range items {
+		if item.Content != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// isFullRefreshDue returns true if unchanged inventory content needs to be uploaded again
+func (u *InventoryUploader) isFullRefreshDue() bool {
+	lastFullUpload := u.optimizer.GetLastFullUploadTime()
+	return lastFullUpload.IsZero() || time.Since(lastFullUpload) >= u.fullRefreshInterval
+}
+
 func calculateCheckSum(data []byte) (checkSum string) {
 	sum := md5.Sum(data)
 	checkSum = base64.StdEncoding.EncodeToString(sum[:])
@@ -158,6 +271,12 @@ func (u *InventoryUploader) ConvertToSsmInventoryItems(context context.T, items
 
 	log.Debugf("Transforming collected inventory data to expected format")
 
+	//content is uploaded for every inventory type once in a while to refresh the data kept by SSM
+	fullRefreshDue := u.isFullRefreshDue()
+	if fullRefreshDue {
+		log.Debugf("Full refresh of inventory data is due - content hashes of earlier uploads are ignored")
+	}
+
 	//iterating over multiple inventory data types.
 	for _, item := range items {
 
@@ -196,7 +315,7 @@ func (u *InventoryUploader) ConvertToSsmInventoryItems(context context.T, items
 
 		log.Debugf("old hash - %v, new hash - %v for the inventory type - %v", oldHash, newHash, itemName)
 
-		if newHash == oldHash {
+		if newHash == oldHash && !fullRefreshDue {
 
 			log.Debugf("Inventory data for %v is same as before - we can just send content hash", itemName)
 
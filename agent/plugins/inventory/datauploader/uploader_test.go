@@ -17,9 +17,12 @@ package datauploader
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/stretchr/testify/assert"
@@ -32,8 +35,10 @@ func MockInventoryUploader() *InventoryUploader {
 	optimizer := NewMockDefault()
 	optimizer.On("GetContentHash", mock.AnythingOfType("string")).Return("RandomInventoryItem")
 	optimizer.On("UpdateContentHash", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	optimizer.On("GetLastFullUploadTime").Return(time.Now())
 
 	uploader.optimizer = optimizer
+	uploader.fullRefreshInterval = time.Hour
 	return &uploader
 }
 
@@ -179,6 +184,7 @@ func testSendData(t *testing.T, putInventorySucceeds bool) {
 		for _, item := range inventoryItems {
 			mockOptimizer.On("UpdateContentHash", *item.TypeName, hash).Return(nil)
 		}
+		mockOptimizer.On("UpdateLastFullUploadTime", mock.AnythingOfType("time.Time")).Return(nil)
 	}
 
 	c := context.NewMockDefault()
@@ -194,3 +200,122 @@ func testSendData(t *testing.T, putInventorySucceeds bool) {
 	mockSSM.AssertExpectations(t)
 	mockOptimizer.AssertExpectations(t)
 }
+
+func testConvertUnchangedItems(t *testing.T, lastFullUpload time.Time) (optimizedInventoryItems []*ssm.InventoryItem) {
+	items := ApplicationInventoryItem()
+	dataB, _ := json.Marshal(items[0].Content)
+
+	optimizer := NewMockDefault()
+	optimizer.On("GetContentHash", items[0].Name).Return(calculateCheckSum(dataB))
+	optimizer.On("GetLastFullUploadTime").Return(lastFullUpload)
+	u := &InventoryUploader{
+		optimizer:           optimizer,
+		fullRefreshInterval: 24 * time.Hour,
+	}
+
+	optimizedInventoryItems, _, err := u.ConvertToSsmInventoryItems(context.NewMockDefault(), items)
+	assert.Nil(t, err)
+	return
+}
+
+func TestConvertUnchangedItemsSendsOnlyContentHash(t *testing.T) {
+	optimizedInventoryItems := testConvertUnchangedItems(t, time.Now().Add(-time.Hour))
+
+	assert.True(t, HasOnlyContentHashes(optimizedInventoryItems))
+}
+
+func TestConvertUnchangedItemsSendsContentWhenFullRefreshIsDue(t *testing.T) {
+	optimizedInventoryItems := testConvertUnchangedItems(t, time.Now().Add(-25*time.Hour))
+	assert.False(t, HasOnlyContentHashes(optimizedInventoryItems))
+
+	optimizedInventoryItems = testConvertUnchangedItems(t, time.Time{})
+	assert.False(t, HasOnlyContentHashes(optimizedInventoryItems))
+}
+
+func fakeSsmInventoryItem(typeName string, entries int) *ssm.InventoryItem {
+	var content []map[string]*string
+	for i := 0; i < entries; i++ {
+		value := "value"
+		content = append(content, map[string]*string{"Name": &value})
+	}
+	hash := "aHash"
+	return &ssm.InventoryItem{
+		TypeName:    &typeName,
+		Content:     content,
+		ContentHash: &hash,
+	}
+}
+
+func batchTypeNames(batches [][]*ssm.InventoryItem) (names [][]string) {
+	for _, batch := range batches {
+		var batchNames []string
+		for _, item := range batch {
+			batchNames = append(batchNames, *item.TypeName)
+		}
+		names = append(names, batchNames)
+	}
+	return
+}
+
+func TestSplitIntoBatches(t *testing.T) {
+	items := []*ssm.InventoryItem{
+		fakeSsmInventoryItem("Custom:C", 1),
+		fakeSsmInventoryItem("AWS:Application", 100),
+		fakeSsmInventoryItem("Custom:B", 1),
+		fakeSsmInventoryItem("Custom:A", 1),
+	}
+
+	assert.Equal(t, [][]string{{"AWS:Application", "Custom:A", "Custom:B", "Custom:C"}},
+		batchTypeNames(splitIntoBatches(log.NewMockLog(), items, 30, 1024*1024)))
+	assert.Equal(t, [][]string{{"AWS:Application", "Custom:A"}, {"Custom:B", "Custom:C"}},
+		batchTypeNames(splitIntoBatches(log.NewMockLog(), items, 2, 1024*1024)))
+	assert.Equal(t, [][]string{{"AWS:Application"}, {"Custom:A", "Custom:B", "Custom:C"}},
+		batchTypeNames(splitIntoBatches(log.NewMockLog(), items, 30, 1024)))
+	assert.Equal(t, 0, len(splitIntoBatches(log.NewMockLog(), nil, 30, 1024)))
+}
+
+func TestSplitIntoBatchesTrimsOversizedType(t *testing.T) {
+	items := []*ssm.InventoryItem{
+		fakeSsmInventoryItem("AWS:Application", 1000),
+		fakeSsmInventoryItem("Custom:A", 1),
+	}
+
+	batches := splitIntoBatches(log.NewMockLog(), items, 30, 1024)
+
+	assert.Equal(t, [][]string{{"AWS:Application"}, {"Custom:A"}}, batchTypeNames(batches))
+	application := batches[0][0]
+	dataB, _ := json.Marshal(application)
+	assert.True(t, len(dataB) <= 1024)
+	assert.Equal(t, items[0].Content[:len(application.Content)], application.Content)
+
+	// one more entry would exceed the limit
+	application.Content = items[0].Content[:len(application.Content)+1]
+	dataB, _ = json.Marshal(application)
+	assert.True(t, len(dataB) > 1024)
+	assert.Equal(t, 1000, len(items[0].Content))
+}
+
+func TestSendDataToSSMInBatches(t *testing.T) {
+	var items []*ssm.InventoryItem
+	for i := 0; i < maxItemsPerPutInventory+1; i++ {
+		items = append(items, fakeSsmInventoryItem(fmt.Sprintf("Custom:Type%02d", i), 1))
+	}
+
+	machineIDProvider = func() (string, error) { return "i-12345678", nil }
+	mockSSM := NewMockSSMCaller()
+	mockSSM.On("PutInventory", mock.AnythingOfType("*ssm.PutInventoryInput")).Return(&ssm.PutInventoryOutput{}, nil).Twice()
+	mockOptimizer := NewMockDefault()
+	mockOptimizer.On("UpdateContentHash", mock.AnythingOfType("string"), "aHash").Return(nil)
+	mockOptimizer.On("UpdateLastFullUploadTime", mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+	u := &InventoryUploader{
+		ssm:       mockSSM,
+		optimizer: mockOptimizer,
+	}
+	err := u.SendDataToSSM(context.NewMockDefault(), items)
+
+	assert.Nil(t, err)
+	mockSSM.AssertExpectations(t)
+	mockOptimizer.AssertNumberOfCalls(t, "UpdateContentHash", len(items))
+	mockOptimizer.AssertExpectations(t)
+}
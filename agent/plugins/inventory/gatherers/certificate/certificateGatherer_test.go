@@ -0,0 +1,51 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package certificate
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCertificates = []model.CertificateData{
+	{
+		Subject:                 "CN=www.example.com",
+		Issuer:                  "CN=Example CA",
+		SubjectAlternativeNames: "www.example.com,example.com",
+		NotAfter:                "2019-05-14T10:11:12Z",
+		Thumbprint:              "2D3A3E5C8A1E0DB1B8C3E1B2C7F1A0E4D5C6B7A8",
+		Location:                "/etc/pki/tls/certs/www.example.com.crt",
+	},
+}
+
+func testCollectCertificateData(context context.T, config model.Config) (data []model.CertificateData, err error) {
+	return testCertificates, nil
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = testCollectCertificateData
+	defer func() { collectData = collectCertificateData }()
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, GathererName, items[0].Name)
+	assert.Equal(t, SchemaVersionOfCertificateGatherer, items[0].SchemaVersion)
+	assert.Equal(t, testCertificates, items[0].Content)
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package certificate
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// maxCertificateFileSize skips the files too large to be certificates or certificate bundles
+	maxCertificateFileSize = 4 * 1024 * 1024
+	pemCertificateType     = "CERTIFICATE"
+	nameSeparator          = ","
+)
+
+// certificateExtensions lists the extensions of the files scanned for certificates
+var certificateExtensions = map[string]bool{".pem": true, ".crt": true, ".cer": true, ".der": true}
+
+// certificateLocations holds the settings of the gatherer, given as a json object instead of Enabled.
+// Paths are files or directories scanned recursively, Stores are certificate stores such as LocalMachine\My on Windows.
+type certificateLocations struct {
+	Paths  []string
+	Stores []string
+}
+
+// collectCertificateData returns the certificates found in the configured files, directories and stores
+func collectCertificateData(context context.T, config model.Config) (data []model.CertificateData, err error) {
+	log := context.Log()
+	log.Infof("collectCertificateData called")
+
+	var locations certificateLocations
+	if locations, err = parseLocations(config.Filters); err != nil {
+		return
+	}
+
+	thumbprints := make(map[string]bool)
+	for _, path := range locations.Paths {
+		for _, certificate := range scanPath(log, path) {
+			// bundles and hashed links of trust stores repeat the same certificates
+			if !thumbprints[certificate.Thumbprint] {
+				thumbprints[certificate.Thumbprint] = true
+				data = append(data, certificate)
+			}
+		}
+	}
+	for _, store := range locations.Stores {
+		var certificates []model.CertificateData
+		if certificates, err = collectStoreCertificates(log, store); err != nil {
+			log.Errorf("Unable to read certificate store %v - %v", store, err)
+			return
+		}
+		data = append(data, certificates...)
+	}
+	return
+}
+
+// parseLocations parses the settings of the gatherer, "Enabled" scans the default locations of the platform
+func parseLocations(input string) (locations certificateLocations, err error) {
+	if input == "" || input == model.Enabled {
+		return certificateLocations{Paths: defaultPaths, Stores: defaultStores}, nil
+	}
+	if err = json.Unmarshal([]byte(input), &locations); err != nil {
+		err = fmt.Errorf("invalid certificate locations %v - %v", input, err)
+	}
+	return
+}
+
+// scanPath returns the certificates of the certificate files found under the given path
+func scanPath(log log.T, root string) (certificates []model.CertificateData) {
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// missing default locations and unreadable directories are skipped
+			log.Debugf("Unable to scan %v for certificates - %v", path, err)
+			return nil
+		}
+		if info.IsDir() || !certificateExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if info.Mode().IsRegular() && info.Size() > maxCertificateFileSize {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Debugf("Unable to read certificate file %v - %v", path, err)
+			return nil
+		}
+		certificates = append(certificates, parseCertificates(content, path)...)
+		return nil
+	})
+	return
+}
+
+// parseCertificates returns the certificates of a PEM file, which may hold several certificates, or of a DER file
+func parseCertificates(content []byte, location string) (certificates []model.CertificateData) {
+	rest := content
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != pemCertificateType {
+			continue
+		}
+		if certificate, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certificates = append(certificates, newCertificateData(certificate, location))
+		}
+	}
+	if len(certificates) == 0 {
+		if certificate, err := x509.ParseCertificate(content); err == nil {
+			certificates = append(certificates, newCertificateData(certificate, location))
+		}
+	}
+	return
+}
+
+// newCertificateData returns the inventory attributes of a certificate, its thumbprint is the SHA-1 hash Windows displays
+func newCertificateData(certificate *x509.Certificate, location string) model.CertificateData {
+	var names []string
+	names = append(names, certificate.DNSNames...)
+	for _, ip := range certificate.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = append(names, certificate.EmailAddresses...)
+
+	thumbprint := sha1.Sum(certificate.Raw)
+	return model.CertificateData{
+		Subject:                 certificate.Subject.String(),
+		Issuer:                  certificate.Issuer.String(),
+		SubjectAlternativeNames: strings.Join(names, nameSeparator),
+		NotAfter:                certificate.NotAfter.UTC().Format(time.RFC3339),
+		Thumbprint:              strings.ToUpper(hex.EncodeToString(thumbprint[:])),
+		Location:                location,
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNotAfter = time.Date(2019, 5, 14, 10, 11, 12, 0, time.UTC)
+
+// createCertificate returns a new self-signed certificate in DER format and its thumbprint
+func createCertificate(t *testing.T, commonName string, dnsNames ...string) ([]byte, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    testNotAfter.AddDate(-1, 0, 0),
+		NotAfter:     testNotAfter,
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	thumbprint := sha1.Sum(der)
+	return der, strings.ToUpper(hex.EncodeToString(thumbprint[:]))
+}
+
+func TestCollectCertificateData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certificate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	webDer, webThumbprint := createCertificate(t, "www.example.com", "www.example.com", "example.com")
+	mailDer, mailThumbprint := createCertificate(t, "mail.example.com")
+	bundle := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: webDer}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: mailDer})...)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bundle.pem"), bundle, 0600))
+	// the same certificate in DER format is only reported once
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "private"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "private", "www.der"), webDer, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "private", "www.key"), []byte("not a certificate"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.crt"), []byte("not a certificate"), 0600))
+
+	config := model.Config{Filters: fmt.Sprintf(`{"Paths": [%q, %q]}`, dir, filepath.Join(dir, "missing"))}
+	data, err := collectCertificateData(context.NewMockDefault(), config)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CertificateData{
+		{
+			Subject:                 "CN=www.example.com",
+			Issuer:                  "CN=www.example.com",
+			SubjectAlternativeNames: "www.example.com,example.com,10.0.0.1",
+			NotAfter:                "2019-05-14T10:11:12Z",
+			Thumbprint:              webThumbprint,
+			Location:                filepath.Join(dir, "bundle.pem"),
+		},
+		{
+			Subject:                 "CN=mail.example.com",
+			Issuer:                  "CN=mail.example.com",
+			SubjectAlternativeNames: "10.0.0.1",
+			NotAfter:                "2019-05-14T10:11:12Z",
+			Thumbprint:              mailThumbprint,
+			Location:                filepath.Join(dir, "bundle.pem"),
+		},
+	}, data)
+}
+
+func TestParseLocations(t *testing.T) {
+	locations, err := parseLocations(model.Enabled)
+	assert.NoError(t, err)
+	assert.Equal(t, certificateLocations{Paths: defaultPaths, Stores: defaultStores}, locations)
+
+	locations, err = parseLocations(`{"Paths": ["/opt/app/tls"]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, certificateLocations{Paths: []string{"/opt/app/tls"}}, locations)
+
+	_, err = parseLocations("Invalid")
+	assert.Error(t, err)
+}
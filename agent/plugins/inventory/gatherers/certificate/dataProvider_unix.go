@@ -0,0 +1,34 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package certificate
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+var (
+	// defaultPaths are the usual locations of the certificates on Debian and Red Hat based distributions
+	defaultPaths  = []string{"/etc/ssl", "/etc/pki/tls"}
+	defaultStores []string
+)
+
+// collectStoreCertificates returns an error, certificate stores only exist on Windows
+func collectStoreCertificates(log log.T, store string) ([]model.CertificateData, error) {
+	return nil, fmt.Errorf("certificate stores are not supported on this platform")
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package certificate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+const (
+	PowershellCmd = "powershell"
+)
+
+var (
+	// storeNamePattern matches store names such as LocalMachine\My, which are passed to PowerShell
+	storeNamePattern = regexp.MustCompile(`^[A-Za-z]+\\[A-Za-z0-9 ._-]+$`)
+
+	defaultPaths  []string
+	defaultStores = []string{`LocalMachine\My`}
+
+	startMarker = "<start" + randomString(8) + ">"
+	endMarker   = "<end" + randomString(8) + ">"
+	// the store name is passed as the first argument of the script
+	certificateInfoScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$store = $args[0]
+$jsonObj = @()
+foreach($c in Get-ChildItem -Path "Cert:\$store" | Where-Object { $_ -is [System.Security.Cryptography.X509Certificates.X509Certificate2] }) {
+$Subject = $c.Subject
+$Issuer = $c.Issuer
+$Names = @($c.DnsNameList | ForEach-Object { $_.Unicode }) -join ","
+$NotAfter = $c.NotAfter.ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")
+$Thumbprint = $c.Thumbprint
+$jsonObj += @"
+{"Subject": "` + mark(`$Subject`) + `", "Issuer": "` + mark(`$Issuer`) + `", "SubjectAlternativeNames": "` + mark(`$Names`) + `", "NotAfter": "$NotAfter", "Thumbprint": "$Thumbprint", "Location": "` + mark(`Cert:\$store`) + `"}
+"@
+}
+$result = $jsonObj -join ","
+$result = "[" + $result + "]"
+[Console]::WriteLine($result)
+`
+)
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectStoreCertificates returns the certificates of a certificate store, e.g. LocalMachine\My
+func collectStoreCertificates(log log.T, store string) (certificates []model.CertificateData, err error) {
+	if !storeNamePattern.MatchString(store) {
+		return nil, fmt.Errorf("invalid certificate store name %v", store)
+	}
+
+	var output []byte
+	if output, err = cmdExecutor(PowershellCmd, "-Command", "& {"+certificateInfoScript+"}", store); err != nil {
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+
+	var cleanOutput string
+	if cleanOutput, err = pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField); err != nil {
+		return
+	}
+	log.Debugf("Command output: %v", cleanOutput)
+
+	if err = json.Unmarshal([]byte(cleanOutput), &certificates); err != nil {
+		err = fmt.Errorf("unable to parse command output - %v", err)
+	}
+	return
+}
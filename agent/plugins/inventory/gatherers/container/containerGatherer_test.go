@@ -0,0 +1,52 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package container
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testContainers = []model.ContainerData{
+	{
+		Runtime:     "docker",
+		ContainerId: "4c01db0b339c",
+		Name:        "web",
+		Image:       "nginx",
+		ImageTag:    "1.13",
+		StartedTime: "2018-05-14T10:11:12Z",
+		Ports:       "0.0.0.0:8080->80/tcp",
+	},
+}
+
+func testCollectContainerData(context context.T, config model.Config) (data []model.ContainerData, err error) {
+	return testContainers, nil
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = testCollectContainerData
+	defer func() { collectData = collectContainerData }()
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, GathererName, items[0].Name)
+	assert.Equal(t, SchemaVersionOfContainerGatherer, items[0].SchemaVersion)
+	assert.Equal(t, testContainers, items[0].Content)
+}
@@ -0,0 +1,274 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	dockerCmd  = "docker"
+	crictlCmd  = "crictl"
+	dockerName = "docker"
+	// containers managed by containerd are listed through its CRI plugin
+	containerdName = "containerd"
+	defaultTag     = "latest"
+	digestSep      = "@"
+)
+
+// dockerContainer holds the fields of interest of docker inspect
+type dockerContainer struct {
+	Id    string
+	Name  string
+	Image string
+	State struct {
+		Running   bool
+		StartedAt string
+	}
+	Config struct {
+		Image string
+	}
+	NetworkSettings struct {
+		Ports map[string][]struct {
+			HostIp   string
+			HostPort string
+		}
+	}
+}
+
+// dockerImage holds the fields of interest of docker image inspect
+type dockerImage struct {
+	Id          string
+	RepoDigests []string
+}
+
+// criContainers holds the fields of interest of crictl ps
+type criContainers struct {
+	Containers []struct {
+		Id       string `json:"id"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Image struct {
+			Image string `json:"image"`
+		} `json:"image"`
+		ImageRef  string `json:"imageRef"`
+		CreatedAt string `json:"createdAt"`
+	} `json:"containers"`
+}
+
+var cmdExecutor = executeCommand
+
+// collectors lists the containers of each supported runtime through its command line tool
+var collectors = []struct {
+	command string
+	collect func(log.T) ([]model.ContainerData, error)
+}{
+	{dockerCmd, collectDockerContainers},
+	{crictlCmd, collectCriContainers},
+}
+
+var lookPath = exec.LookPath
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectContainerData returns the running containers of the docker and containerd runtimes installed on the instance.
+// A runtime which can't be listed is skipped, an error is only returned when none of the installed runtimes can be.
+// A container listed by several runtimes, e.g. by docker and by crictl through cri-dockerd, is reported once.
+func collectContainerData(context context.T, config model.Config) (data []model.ContainerData, err error) {
+	log := context.Log()
+	log.Infof("collectContainerData called")
+
+	collected := make(map[string]bool)
+	installed, failed := 0, 0
+	for _, collector := range collectors {
+		if _, lookErr := lookPath(collector.command); lookErr != nil {
+			log.Debugf("%v is not installed, skipping its containers", collector.command)
+			continue
+		}
+		installed++
+		containers, collectErr := collector.collect(log)
+		if collectErr != nil {
+			log.Errorf("Unable to list containers with %v, skipping them - %v", collector.command, collectErr)
+			failed++
+			err = collectErr
+			continue
+		}
+		for _, container := range containers {
+			if collected[container.ContainerId] {
+				continue
+			}
+			collected[container.ContainerId] = true
+			data = append(data, container)
+		}
+	}
+	if failed < installed {
+		err = nil
+	}
+	return
+}
+
+// collectDockerContainers lists the running docker containers
+func collectDockerContainers(log log.T) (data []model.ContainerData, err error) {
+	var output []byte
+	if output, err = cmdExecutor(dockerCmd, "ps", "--quiet", "--no-trunc"); err != nil {
+		return
+	}
+	ids := strings.Fields(string(output))
+	if len(ids) == 0 {
+		return
+	}
+
+	var containers []dockerContainer
+	if output, err = cmdExecutor(dockerCmd, append([]string{"inspect", "--type", "container"}, ids...)...); err != nil {
+		return
+	}
+	if err = json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("unable to parse docker inspect output - %v", err)
+	}
+
+	digests := dockerImageDigests(log, containers)
+	for _, container := range containers {
+		// containers may stop while being listed
+		if !container.State.Running {
+			continue
+		}
+		image, tag, digest := parseImageReference(container.Config.Image)
+		if digest == "" {
+			digest = digests[container.Image]
+		}
+		data = append(data, model.ContainerData{
+			Runtime:     dockerName,
+			ContainerId: container.Id,
+			Name:        strings.TrimPrefix(container.Name, "/"),
+			Image:       image,
+			ImageTag:    tag,
+			ImageDigest: digest,
+			StartedTime: formatTime(container.State.StartedAt),
+			Ports:       formatDockerPorts(container),
+		})
+	}
+	return
+}
+
+// dockerImageDigests returns the repository digest of the images of the containers, by image id
+func dockerImageDigests(log log.T, containers []dockerContainer) (digests map[string]string) {
+	digests = make(map[string]string)
+	var imageIds []string
+	for _, container := range containers {
+		imageIds = append(imageIds, container.Image)
+	}
+
+	var images []dockerImage
+	output, err := cmdExecutor(dockerCmd, append([]string{"inspect", "--type", "image"}, imageIds...)...)
+	if err == nil {
+		err = json.Unmarshal(output, &images)
+	}
+	if err != nil {
+		// images pulled from a registry have a digest, locally built ones do not
+		log.Debugf("Unable to get image digests - %v", err)
+		return
+	}
+	for _, image := range images {
+		if len(image.RepoDigests) > 0 {
+			_, _, digests[image.Id] = parseImageReference(image.RepoDigests[0])
+		}
+	}
+	return
+}
+
+// formatDockerPorts formats the exposed ports of a container like docker ps does, e.g. 0.0.0.0:8080->80/tcp
+func formatDockerPorts(container dockerContainer) string {
+	var ports []string
+	for port, bindings := range container.NetworkSettings.Ports {
+		if len(bindings) == 0 {
+			ports = append(ports, port)
+		}
+		for _, binding := range bindings {
+			ports = append(ports, fmt.Sprintf("%v:%v->%v", binding.HostIp, binding.HostPort, port))
+		}
+	}
+	sort.Strings(ports)
+	return strings.Join(ports, ", ")
+}
+
+// collectCriContainers lists the running containers of containerd through crictl
+func collectCriContainers(log log.T) (data []model.ContainerData, err error) {
+	var output []byte
+	if output, err = cmdExecutor(crictlCmd, "ps", "--output", "json"); err != nil {
+		return
+	}
+	var containers criContainers
+	if err = json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("unable to parse crictl output - %v", err)
+	}
+
+	for _, container := range containers.Containers {
+		image, tag, digest := parseImageReference(container.Image.Image)
+		if digest == "" && strings.Contains(container.ImageRef, digestSep) {
+			_, _, digest = parseImageReference(container.ImageRef)
+		}
+		// ports are published by the pod sandbox in CRI, not by its containers
+		containerData := model.ContainerData{
+			Runtime:     containerdName,
+			ContainerId: container.Id,
+			Name:        container.Metadata.Name,
+			Image:       image,
+			ImageTag:    tag,
+			ImageDigest: digest,
+		}
+		// CRI only lists the creation time of containers, in nanoseconds since epoch. Running containers start right away.
+		if createdAt, parseErr := strconv.ParseInt(container.CreatedAt, 10, 64); parseErr == nil {
+			containerData.StartedTime = time.Unix(0, createdAt).UTC().Format(time.RFC3339)
+		}
+		data = append(data, containerData)
+	}
+	return
+}
+
+// parseImageReference splits an image reference such as registry:5000/repository:tag@sha256:digest
+func parseImageReference(reference string) (image, tag, digest string) {
+	image = reference
+	if i := strings.Index(image, digestSep); i >= 0 {
+		image, digest = image[:i], image[i+1:]
+	}
+	// a colon after the last slash separates the tag, the one before it is part of the registry host
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, tag = image[:i], image[i+1:]
+	} else if digest == "" {
+		tag = defaultTag
+	}
+	return
+}
+
+// formatTime converts the timestamps reported by the container runtimes to the format expected by SSM
+func formatTime(timestamp string) string {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
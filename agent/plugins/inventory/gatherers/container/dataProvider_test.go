@@ -0,0 +1,168 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package container
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	sampleDockerInspect = `[{
+	"Id": "4c01db0b339c",
+	"Name": "/web",
+	"Image": "sha256:ae513a47849c",
+	"State": {"Running": true, "StartedAt": "2018-05-14T10:11:12.123456789Z"},
+	"Config": {"Image": "registry.example.com:5000/web/nginx:1.13"},
+	"NetworkSettings": {"Ports": {"80/tcp": [{"HostIp": "0.0.0.0", "HostPort": "8080"}], "443/tcp": null}}
+}]`
+	sampleDockerImageInspect = `[{"Id": "sha256:ae513a47849c", "RepoDigests": ["registry.example.com:5000/web/nginx@sha256:0fe6413f3e30"]}]`
+	sampleCrictlOutput       = `{"containers": [{
+	"id": "9d2bc5a1b0f4",
+	"metadata": {"name": "coredns"},
+	"image": {"image": "k8s.gcr.io/coredns:1.1.3"},
+	"imageRef": "k8s.gcr.io/coredns@sha256:db2bf5f5ffb8",
+	"createdAt": "1526292672000000000"
+}]}`
+)
+
+// mockCommands returns the output of the given commands, keyed by command line
+func mockCommands(outputs map[string]string) func(string, ...string) ([]byte, error) {
+	return func(command string, args ...string) ([]byte, error) {
+		commandLine := strings.Join(append([]string{command}, args...), " ")
+		if output, ok := outputs[commandLine]; ok {
+			return []byte(output), nil
+		}
+		return nil, fmt.Errorf("unexpected command %v", commandLine)
+	}
+}
+
+func TestCollectContainerData(t *testing.T) {
+	cmdExecutorTemp, lookPathTemp := cmdExecutor, lookPath
+	cmdExecutor = mockCommands(map[string]string{
+		"docker ps --quiet --no-trunc":                    "4c01db0b339c\n",
+		"docker inspect --type container 4c01db0b339c":    sampleDockerInspect,
+		"docker inspect --type image sha256:ae513a47849c": sampleDockerImageInspect,
+		"crictl ps --output json":                         sampleCrictlOutput,
+	})
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() {
+		cmdExecutor = cmdExecutorTemp
+		lookPath = lookPathTemp
+	}()
+
+	data, err := collectContainerData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ContainerData{
+		{
+			Runtime:     "docker",
+			ContainerId: "4c01db0b339c",
+			Name:        "web",
+			Image:       "registry.example.com:5000/web/nginx",
+			ImageTag:    "1.13",
+			ImageDigest: "sha256:0fe6413f3e30",
+			StartedTime: "2018-05-14T10:11:12Z",
+			Ports:       "0.0.0.0:8080->80/tcp, 443/tcp",
+		},
+		{
+			Runtime:     "containerd",
+			ContainerId: "9d2bc5a1b0f4",
+			Name:        "coredns",
+			Image:       "k8s.gcr.io/coredns",
+			ImageTag:    "1.1.3",
+			ImageDigest: "sha256:db2bf5f5ffb8",
+			StartedTime: "2018-05-14T10:11:12Z",
+		},
+	}, data)
+}
+
+func TestCollectContainerDataSkipsFailingRuntime(t *testing.T) {
+	cmdExecutorTemp, lookPathTemp := cmdExecutor, lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() {
+		cmdExecutor = cmdExecutorTemp
+		lookPath = lookPathTemp
+	}()
+
+	// the docker daemon isn't running
+	cmdExecutor = mockCommands(map[string]string{"crictl ps --output json": sampleCrictlOutput})
+	data, err := collectContainerData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, "containerd", data[0].Runtime)
+
+	// an error is returned when no runtime can be listed
+	cmdExecutor = mockCommands(map[string]string{})
+	_, err = collectContainerData(context.NewMockDefault(), model.Config{})
+
+	assert.Error(t, err)
+}
+
+func TestCollectContainerDataDeduplicatesContainers(t *testing.T) {
+	cmdExecutorTemp, lookPathTemp := cmdExecutor, lookPath
+	// crictl lists the docker container through cri-dockerd
+	cmdExecutor = mockCommands(map[string]string{
+		"docker ps --quiet --no-trunc":                    "4c01db0b339c\n",
+		"docker inspect --type container 4c01db0b339c":    sampleDockerInspect,
+		"docker inspect --type image sha256:ae513a47849c": sampleDockerImageInspect,
+		"crictl ps --output json":                         strings.Replace(sampleCrictlOutput, "9d2bc5a1b0f4", "4c01db0b339c", 1),
+	})
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() {
+		cmdExecutor = cmdExecutorTemp
+		lookPath = lookPathTemp
+	}()
+
+	data, err := collectContainerData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, "docker", data[0].Runtime)
+	assert.Equal(t, "0.0.0.0:8080->80/tcp, 443/tcp", data[0].Ports)
+}
+
+func TestCollectContainerDataWithoutRuntimes(t *testing.T) {
+	lookPathTemp := lookPath
+	lookPath = func(file string) (string, error) { return "", fmt.Errorf("%v not found", file) }
+	defer func() { lookPath = lookPathTemp }()
+
+	data, err := collectContainerData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestParseImageReference(t *testing.T) {
+	for _, test := range []struct {
+		reference, image, tag, digest string
+	}{
+		{"nginx", "nginx", "latest", ""},
+		{"nginx:1.13", "nginx", "1.13", ""},
+		{"localhost:5000/nginx", "localhost:5000/nginx", "latest", ""},
+		{"nginx@sha256:0fe6", "nginx", "", "sha256:0fe6"},
+		{"localhost:5000/nginx:1.13@sha256:0fe6", "localhost:5000/nginx", "1.13", "sha256:0fe6"},
+	} {
+		image, tag, digest := parseImageReference(test.reference)
+		assert.Equal(t, test.image, image, test.reference)
+		assert.Equal(t, test.tag, tag, test.reference)
+		assert.Equal(t, test.digest, digest, test.reference)
+	}
+}
@@ -114,30 +114,33 @@ func (t *T) Run(context context.T, configuration model.Config) (items []model.It
 		}
 	}
 
-	// Get custom inventory files' path
-	fileList, err := getFilePaths(log, customFolder, FileSuffix)
-	if err != nil {
-		LogError(
-			log,
-			fmt.Errorf("Failed to get inventory files from folder %v, error %v", customFolder, err))
-		return
+	// Get custom inventory files' path from the gatherer location and the drop directory
+	var fileList []string
+	folders := customInventoryFolders(customFolder, context.AppConfig().Ssm.CustomInventoryDefaultLocation)
+	for _, folder := range folders {
+		var folderFiles []string
+		if folderFiles, err = getFilePaths(log, folder, FileSuffix); err != nil {
+			LogError(
+				log,
+				fmt.Errorf("Failed to get inventory files from folder %v, error %v", folder, err))
+			return
+		}
+		fileList = append(fileList, folderFiles...)
 	}
 
-	// Get custom inventory item
-	setTypeName := make(map[string]bool)
+	// Get custom inventory item, files with the same TypeName are merged into a single item
+	indexOfTypeName := make(map[string]int)
 	for _, filePath := range fileList {
 
 		if customItem, err := getItemFromFile(log, filePath); err == nil {
 
-			if _, ok := setTypeName[customItem.Name]; ok {
-				err = fmt.Errorf("Custom inventory typeName (%v) from file (%v) already exists,"+
-					" i.e., other file under the same folder contains the same typeName,"+
-					" please remove duplicate custom inventory file.",
-					customItem.Name, filePath)
-				LogError(log, err)
+			if index, ok := indexOfTypeName[customItem.Name]; ok {
+				if err = mergeItems(&items[index], customItem); err != nil {
+					LogError(log, fmt.Errorf("Failed to merge custom inventory file %v, error %v. continue...",
+						filePath, err))
+				}
 			} else {
-				// Only append if current TypeName is not duplicate
-				setTypeName[customItem.Name] = true
+				indexOfTypeName[customItem.Name] = len(items)
 				items = append(items, customItem)
 			}
 		} else {
@@ -150,11 +153,46 @@ func (t *T) Run(context context.T, configuration model.Config) (items []model.It
 	count := len(items)
 	log.Debugf("Count of custom inventory items : %v.", count)
 	if count == 0 {
-		log.Infof("No custom inventory item found under folder: %v", customFolder)
+		log.Infof("No custom inventory item found under folders: %v", folders)
+	}
+	return
+}
+
+// customInventoryFolders returns the folders to read custom inventory files from, i.e. the gatherer location
+// followed by the drop directory operators can place files in without configuring the association.
+func customInventoryFolders(location, dropDirectory string) (folders []string) {
+	folders = append(folders, location)
+	if dropDirectory != "" && filepath.Clean(dropDirectory) != filepath.Clean(location) {
+		folders = append(folders, dropDirectory)
 	}
 	return
 }
 
+// mergeItems appends the content entries of other to item, skipping entries item already contains.
+// Both items need to share the same SchemaVersion to be merged.
+func mergeItems(item *model.Item, other model.Item) error {
+	if item.SchemaVersion != other.SchemaVersion {
+		return fmt.Errorf("Custom inventory typeName (%v) has SchemaVersion %v, which conflicts with SchemaVersion %v"+
+			" found in another file", other.Name, other.SchemaVersion, item.SchemaVersion)
+	}
+
+	entries := item.Content.([]map[string]interface{})
+	for _, entry := range other.Content.([]map[string]interface{}) {
+		duplicate := false
+		for _, existing := range entries {
+			if reflect.DeepEqual(existing, entry) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			entries = append(entries, entry)
+		}
+	}
+	item.Content = entries
+	return nil
+}
+
 // RequestStop stops the execution of custom gatherer
 func (t *T) RequestStop(stopType contracts.StopType) error {
 	//TODO: set a stop flag so Run thread would stop when flag is set to true
@@ -18,16 +18,21 @@ import (
 	"testing"
 
 	"encoding/json"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"time"
 
 	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 type MockFileInfo struct {
@@ -567,3 +572,52 @@ func TestContentLongAttributeValue(t *testing.T) {
 	assert.Nil(t, err, "err shoud be nil as gather continues to load other custom inventory files")
 	assert.Nil(t, items, "Items should be nil")
 }
+
+func writeCustomInventoryFile(t *testing.T, folder, name, content string) {
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(folder, name), []byte(content), 0600))
+}
+
+func TestDropDirectoryItemsAreMerged(t *testing.T) {
+	location, _ := ioutil.TempDir("", "custom")
+	defer os.RemoveAll(location)
+	dropDirectory, _ := ioutil.TempDir("", "drop")
+	defer os.RemoveAll(dropDirectory)
+
+	writeCustomInventoryFile(t, location, "web.json",
+		`{"TypeName": "Custom:WebServer", "SchemaVersion": "1.0", "Content": {"Name": "a"}}`)
+	writeCustomInventoryFile(t, dropDirectory, "web.json",
+		`{"TypeName": "Custom:WebServer", "SchemaVersion": "1.0", "Content": [{"Name": "a"}, {"Name": "b"}]}`)
+	writeCustomInventoryFile(t, dropDirectory, "db.json",
+		`{"TypeName": "Custom:Database", "SchemaVersion": "1.0", "Content": {"Engine": "mysql"}}`)
+	writeCustomInventoryFile(t, dropDirectory, "db2.json",
+		`{"TypeName": "Custom:Database", "SchemaVersion": "2.0", "Content": {"Engine": "postgres"}}`)
+	writeCustomInventoryFile(t, dropDirectory, "invalid.json", `{"TypeName": "Invalid"}`)
+	writeCustomInventoryFile(t, dropDirectory, "notes.txt", "ignored")
+
+	config := appconfig.SsmagentConfig{}
+	config.Ssm.CustomInventoryDefaultLocation = dropDirectory
+	c := new(context.Mock)
+	c.On("Log").Return(log.NewMockLog())
+	c.On("AppConfig").Return(config)
+	c.On("With", mock.AnythingOfType("string")).Return(c)
+
+	readFileFunc = ReadFile
+	readDirFunc = ReadDir
+	items, err := Gatherer(c).Run(c, model.Config{Location: location})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(items))
+	itemsByName := make(map[string]model.Item)
+	for _, item := range items {
+		itemsByName[item.Name] = item
+	}
+	assert.Equal(t, []map[string]interface{}{{"Name": "a"}, {"Name": "b"}}, itemsByName["Custom:WebServer"].Content)
+	assert.Equal(t, "1.0", itemsByName["Custom:Database"].SchemaVersion)
+	assert.Equal(t, 1, len(itemsByName["Custom:Database"].Content.([]map[string]interface{})))
+}
+
+func TestCustomInventoryFolders(t *testing.T) {
+	assert.Equal(t, []string{"/custom"}, customInventoryFolders("/custom", ""))
+	assert.Equal(t, []string{"/custom"}, customInventoryFolders("/custom", "/custom/"))
+	assert.Equal(t, []string{"/custom", "/drop"}, customInventoryFolders("/custom", "/drop"))
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package listeningport
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	protocolTCP = "tcp"
+	protocolUDP = "udp"
+)
+
+// collectListeningPortData returns the TCP sockets listening for connections and the unconnected UDP sockets, with their owning process
+func collectListeningPortData(context context.T, config model.Config) (data []model.ListeningPortData, err error) {
+	log := context.Log()
+	log.Infof("collectListeningPortData called")
+
+	if data, err = collectListeningSockets(context); err != nil {
+		log.Errorf("Unable to collect listening sockets - %v", err)
+		return
+	}
+	sort.Sort(byProtocolAddressPort(data))
+	return
+}
+
+// byProtocolAddressPort sorts the listening ports so that the content hash only changes when the sockets do
+type byProtocolAddressPort []model.ListeningPortData
+
+func (s byProtocolAddressPort) Len() int {
+	return len(s)
+}
+
+func (s byProtocolAddressPort) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s byProtocolAddressPort) Less(i, j int) bool {
+	if s[i].Protocol != s[j].Protocol {
+		return s[i].Protocol < s[j].Protocol
+	}
+	if s[i].Address != s[j].Address {
+		return s[i].Address < s[j].Address
+	}
+	if s[i].Port != s[j].Port {
+		portI, _ := strconv.Atoi(s[i].Port)
+		portJ, _ := strconv.Atoi(s[j].Port)
+		return portI < portJ
+	}
+	return s[i].ProcessId < s[j].ProcessId
+}
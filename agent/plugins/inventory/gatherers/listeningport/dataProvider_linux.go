@@ -0,0 +1,176 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package listeningport
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// tcpListen is the state of listening TCP sockets in /proc/net/tcp
+	tcpListen = "0A"
+	// udpUnconnected is the state of UDP sockets without a remote address in /proc/net/udp
+	udpUnconnected   = "07"
+	socketLinkPrefix = "socket:["
+)
+
+// procDir is the mount point of the proc filesystem
+var procDir = "/proc"
+
+// socketTables lists the socket tables of the proc filesystem with their protocol and the state of the sockets to report
+var socketTables = []struct {
+	file     string
+	protocol string
+	state    string
+}{
+	{"tcp", protocolTCP, tcpListen},
+	{"tcp6", protocolTCP, tcpListen},
+	{"udp", protocolUDP, udpUnconnected},
+	{"udp6", protocolUDP, udpUnconnected},
+}
+
+// socketOwner identifies the process owning a socket
+type socketOwner struct {
+	pid  string
+	name string
+}
+
+// collectListeningSockets reads the listening sockets from /proc/net and finds their owning process through the file descriptors of the processes
+func collectListeningSockets(context context.T) (data []model.ListeningPortData, err error) {
+	log := context.Log()
+	var inodes []string
+	for _, table := range socketTables {
+		content, readErr := ioutil.ReadFile(filepath.Join(procDir, "net", table.file))
+		if readErr != nil {
+			// the IPv6 tables are missing when IPv6 is disabled
+			log.Debugf("Unable to read socket table %v - %v", table.file, readErr)
+			continue
+		}
+		var sockets []model.ListeningPortData
+		var socketInodes []string
+		if sockets, socketInodes, err = parseSocketTable(string(content), table.protocol, table.state); err != nil {
+			return nil, fmt.Errorf("invalid socket table %v - %v", table.file, err)
+		}
+		data = append(data, sockets...)
+		inodes = append(inodes, socketInodes...)
+	}
+
+	owners := findSocketOwners(inodes)
+	for i, inode := range inodes {
+		if owner, found := owners[inode]; found {
+			data[i].ProcessId = owner.pid
+			data[i].ProcessName = owner.name
+		}
+	}
+	return
+}
+
+// parseSocketTable returns the sockets in the given state of a /proc/net socket table, along with their inode
+func parseSocketTable(content, protocol, state string) (sockets []model.ListeningPortData, inodes []string, err error) {
+	lines := strings.Split(content, "\n")
+	// the first line holds the column names: sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[3] != state {
+			continue
+		}
+		var socket = model.ListeningPortData{Protocol: protocol}
+		if socket.Address, socket.Port, err = parseSocketAddress(fields[1]); err != nil {
+			return
+		}
+		sockets = append(sockets, socket)
+		inodes = append(inodes, fields[9])
+	}
+	return
+}
+
+// parseSocketAddress parses an address of a socket table, formatted as the hexadecimal ip address in host byte order followed by the port
+func parseSocketAddress(address string) (ip, port string, err error) {
+	parts := strings.Split(address, ":")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid socket address %v", address)
+	}
+	var portNumber uint64
+	if portNumber, err = strconv.ParseUint(parts[1], 16, 16); err != nil {
+		return
+	}
+	var ipBytes []byte
+	if ipBytes, err = hex.DecodeString(parts[0]); err != nil {
+		return
+	}
+	if len(ipBytes) != net.IPv4len && len(ipBytes) != net.IPv6len {
+		return "", "", fmt.Errorf("invalid socket address %v", address)
+	}
+	// the address is stored as 32 bit words in little endian order
+	for word := 0; word < len(ipBytes); word += 4 {
+		ipBytes[word], ipBytes[word+1], ipBytes[word+2], ipBytes[word+3] = ipBytes[word+3], ipBytes[word+2], ipBytes[word+1], ipBytes[word]
+	}
+	return net.IP(ipBytes).String(), strconv.FormatUint(portNumber, 10), nil
+}
+
+// findSocketOwners returns the processes having a file descriptor of the given socket inodes
+func findSocketOwners(inodes []string) (owners map[string]socketOwner) {
+	owners = make(map[string]socketOwner)
+	wanted := make(map[string]bool)
+	for _, inode := range inodes {
+		wanted[inode] = true
+	}
+
+	processes, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return
+	}
+	for _, process := range processes {
+		if _, err := strconv.Atoi(process.Name()); err != nil {
+			continue
+		}
+		// processes may exit while being inspected
+		fds, err := ioutil.ReadDir(filepath.Join(procDir, process.Name(), "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(procDir, process.Name(), "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, socketLinkPrefix) {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, socketLinkPrefix), "]")
+			if _, found := owners[inode]; wanted[inode] && !found {
+				owners[inode] = socketOwner{pid: process.Name(), name: processName(process.Name())}
+			}
+		}
+	}
+	return
+}
+
+// processName returns the command name of a process
+func processName(pid string) string {
+	comm, err := ioutil.ReadFile(filepath.Join(procDir, pid, "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package listeningport
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	sampleTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 15293 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0019 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 16042 1 0000000000000000 100 0 0 10 0
+   2: 0F02000A:0016 0202000A:C35A 01 00000000:00000000 02:0009CB8E 00000000     0        0 30714 4 0000000000000000 20 4 30 10 -1
+`
+	sampleTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 15295 1 0000000000000000 100 0 0 10 0
+`
+	sampleUDP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  512: 00000000:0044 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 14022 2 0000000000000000 0
+`
+)
+
+// createProcDir creates a proc filesystem with the sample socket tables and a sshd process owning the ssh sockets
+func createProcDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "net"), 0700))
+	for name, content := range map[string]string{"tcp": sampleTCP, "tcp6": sampleTCP6, "udp": sampleUDP} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "net", name), []byte(content), 0600))
+	}
+
+	fdDir := filepath.Join(dir, "1042", "fd")
+	assert.NoError(t, os.MkdirAll(fdDir, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "1042", "comm"), []byte("sshd\n"), 0600))
+	assert.NoError(t, os.Symlink("socket:[15293]", filepath.Join(fdDir, "3")))
+	assert.NoError(t, os.Symlink("socket:[15295]", filepath.Join(fdDir, "4")))
+	assert.NoError(t, os.Symlink("/dev/null", filepath.Join(fdDir, "0")))
+	return dir
+}
+
+func TestCollectListeningPortData(t *testing.T) {
+	procDirTemp := procDir
+	procDir = createProcDir(t)
+	defer func() {
+		os.RemoveAll(procDir)
+		procDir = procDirTemp
+	}()
+
+	data, err := collectListeningPortData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ListeningPortData{
+		{Protocol: "tcp", Address: "0.0.0.0", Port: "22", ProcessId: "1042", ProcessName: "sshd"},
+		{Protocol: "tcp", Address: "127.0.0.1", Port: "25"},
+		{Protocol: "tcp", Address: "::", Port: "22", ProcessId: "1042", ProcessName: "sshd"},
+		{Protocol: "udp", Address: "0.0.0.0", Port: "68"},
+	}, data)
+}
+
+func TestParseSocketAddress(t *testing.T) {
+	ip, port, err := parseSocketAddress("0100007F:1F90")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip)
+	assert.Equal(t, "8080", port)
+
+	ip, port, err = parseSocketAddress("0000000000000000FFFF00000100007F:0050")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip)
+	assert.Equal(t, "80", port)
+
+	_, _, err = parseSocketAddress("0100007F")
+	assert.Error(t, err)
+}
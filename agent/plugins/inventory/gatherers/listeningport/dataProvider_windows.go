@@ -0,0 +1,142 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package listeningport
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	afInet                   = 2
+	afInet6                  = 23
+	tcpTableOwnerPidListener = 3
+	udpTableOwnerPid         = 1
+	errorInsufficientBuffer  = 122
+)
+
+// Windows APIs
+var (
+	iphlpapi            = syscall.NewLazyDLL("iphlpapi.dll")
+	getExtendedTcpTable = iphlpapi.NewProc("GetExtendedTcpTable")
+	getExtendedUdpTable = iphlpapi.NewProc("GetExtendedUdpTable")
+)
+
+// socketTable describes the layout of the rows of a socket table returned by the IP helper API
+type socketTable struct {
+	protocol   string
+	family     uint32
+	getTable   *syscall.LazyProc
+	tableClass uintptr
+	// rowSize is the size of a row, addressOffset and ipLength locate the local address, portOffset and pidOffset the other fields
+	rowSize       int
+	addressOffset int
+	ipLength      int
+	portOffset    int
+	pidOffset     int
+}
+
+// socketTables lists the MIB_TCPROW_OWNER_PID, MIB_TCP6ROW_OWNER_PID, MIB_UDPROW_OWNER_PID and MIB_UDP6ROW_OWNER_PID layouts
+var socketTables = []socketTable{
+	{protocolTCP, afInet, getExtendedTcpTable, tcpTableOwnerPidListener, 24, 4, net.IPv4len, 8, 20},
+	{protocolTCP, afInet6, getExtendedTcpTable, tcpTableOwnerPidListener, 56, 0, net.IPv6len, 20, 52},
+	{protocolUDP, afInet, getExtendedUdpTable, udpTableOwnerPid, 12, 0, net.IPv4len, 4, 8},
+	{protocolUDP, afInet6, getExtendedUdpTable, udpTableOwnerPid, 28, 0, net.IPv6len, 20, 24},
+}
+
+// collectListeningSockets reads the listening TCP sockets and the UDP sockets with their owning process from the IP helper API
+func collectListeningSockets(context context.T) (data []model.ListeningPortData, err error) {
+	names := processNames(context)
+	for _, table := range socketTables {
+		var buffer []byte
+		if buffer, err = readSocketTable(table); err != nil {
+			return nil, fmt.Errorf("unable to read %v sockets - %v", table.protocol, err)
+		}
+		for _, socket := range parseSocketTable(buffer, table) {
+			socket.ProcessName = names[socket.ProcessId]
+			data = append(data, socket)
+		}
+	}
+	return
+}
+
+// readSocketTable returns the raw socket table, growing the buffer until the table fits
+func readSocketTable(table socketTable) (buffer []byte, err error) {
+	size := uint32(0)
+	for {
+		var tablePtr uintptr
+		if len(buffer) > 0 {
+			tablePtr = uintptr(unsafe.Pointer(&buffer[0]))
+		}
+		r1, _, _ := table.getTable.Call(tablePtr, uintptr(unsafe.Pointer(&size)), 0, uintptr(table.family), table.tableClass, 0)
+		switch r1 {
+		case 0:
+			return buffer[:size], nil
+		case errorInsufficientBuffer:
+			buffer = make([]byte, size)
+		default:
+			return nil, syscall.Errno(r1)
+		}
+	}
+}
+
+// parseSocketTable parses a table starting with the number of entries followed by the rows
+func parseSocketTable(buffer []byte, table socketTable) (sockets []model.ListeningPortData) {
+	if len(buffer) < 4 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(buffer))
+	// the rows follow the entry count
+	for i := 0; i < count && 4+(i+1)*table.rowSize <= len(buffer); i++ {
+		row := buffer[4+i*table.rowSize : 4+(i+1)*table.rowSize]
+		ip := make(net.IP, table.ipLength)
+		copy(ip, row[table.addressOffset:table.addressOffset+table.ipLength])
+		// the port is stored in network byte order in the low word
+		port := binary.BigEndian.Uint16(row[table.portOffset : table.portOffset+2])
+		sockets = append(sockets, model.ListeningPortData{
+			Protocol:  table.protocol,
+			Address:   ip.String(),
+			Port:      strconv.Itoa(int(port)),
+			ProcessId: strconv.Itoa(int(binary.LittleEndian.Uint32(row[table.pidOffset:]))),
+		})
+	}
+	return
+}
+
+// processNames returns the executable name of the running processes, by process id
+func processNames(context context.T) (names map[string]string) {
+	names = make(map[string]string)
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		context.Log().Debugf("Unable to list processes - %v", err)
+		return
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		names[strconv.Itoa(int(entry.ProcessID))] = syscall.UTF16ToString(entry.ExeFile[:])
+	}
+	return
+}
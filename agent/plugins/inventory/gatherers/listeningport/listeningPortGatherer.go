@@ -0,0 +1,70 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package listeningport contains a gatherer for the network ports listening on the instance.
+package listeningport
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// GathererName captures name of listening port gatherer, listening ports are reported as a custom inventory type
+	GathererName = "Custom:ListeningPort"
+	// SchemaVersionOfListeningPortGatherer represents schema version of listening port gatherer
+	SchemaVersionOfListeningPortGatherer = "1.0"
+)
+
+// T represents listening port gatherer which implements all contracts for gatherers.
+type T struct{}
+
+// Gatherer returns new listening port gatherer
+func Gatherer(context context.T) *T {
+	return new(T)
+}
+
+var collectData = collectListeningPortData
+
+// Name returns name of listening port gatherer
+func (t *T) Name() string {
+	return GathererName
+}
+
+// Run executes listening port gatherer and returns list of inventory.Item comprising of the listening sockets
+func (t *T) Run(context context.T, configuration model.Config) (items []model.Item, err error) {
+	//CaptureTime must comply with format: 2016-07-30T18:15:37Z to comply with regex at SSM.
+	captureTime := time.Now().UTC().Format(time.RFC3339)
+
+	var data []model.ListeningPortData
+	if data, err = collectData(context, configuration); err != nil {
+		return
+	}
+
+	items = append(items, model.Item{
+		Name:          t.Name(),
+		SchemaVersion: SchemaVersionOfListeningPortGatherer,
+		Content:       data,
+		CaptureTime:   captureTime,
+	})
+	return
+}
+
+// RequestStop stops the execution of listening port gatherer.
+func (t *T) RequestStop(stopType contracts.StopType) error {
+	var err error
+	return err
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localuser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	administratorTrue  = "true"
+	administratorFalse = "false"
+	groupSeparator     = ","
+)
+
+// userOptions holds the settings of the gatherer, given as a json object instead of Enabled
+type userOptions struct {
+	// HashUserNames reports the SHA-256 hash of the user names instead of the names, including user private groups
+	HashUserNames bool
+}
+
+// collectLocalUserData returns the local accounts of the instance with their groups, last login time and administrator rights
+func collectLocalUserData(context context.T, config model.Config) (data []model.LocalUserData, err error) {
+	log := context.Log()
+	log.Infof("collectLocalUserData called")
+
+	var options userOptions
+	if options, err = parseOptions(config.Filters); err != nil {
+		return
+	}
+	if data, err = collectUsers(context); err != nil {
+		log.Errorf("Unable to collect local users - %v", err)
+		return
+	}
+	if options.HashUserNames {
+		hashUserNames(data)
+	}
+	return
+}
+
+// parseOptions parses the settings of the gatherer, "Enabled" uses the default settings
+func parseOptions(input string) (options userOptions, err error) {
+	if input == "" || input == model.Enabled {
+		return
+	}
+	if err = json.Unmarshal([]byte(input), &options); err != nil {
+		err = fmt.Errorf("invalid local user settings %v - %v", input, err)
+	}
+	return
+}
+
+// hashUserNames replaces the user names by their hash, along with the groups named after a user
+func hashUserNames(users []model.LocalUserData) {
+	names := make(map[string]bool)
+	for _, user := range users {
+		names[user.Name] = true
+	}
+	for i, user := range users {
+		users[i].Name = hashName(user.Name)
+		if user.Groups == "" {
+			continue
+		}
+		groups := strings.Split(user.Groups, groupSeparator)
+		for j, group := range groups {
+			if names[group] {
+				groups[j] = hashName(group)
+			}
+		}
+		users[i].Groups = strings.Join(groups, groupSeparator)
+	}
+}
+
+func hashName(name string) string {
+	hash := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(hash[:])
+}
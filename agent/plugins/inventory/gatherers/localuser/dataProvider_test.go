@@ -0,0 +1,49 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localuser
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptions(t *testing.T) {
+	options, err := parseOptions(model.Enabled)
+	assert.NoError(t, err)
+	assert.False(t, options.HashUserNames)
+
+	options, err = parseOptions(`{"HashUserNames": true}`)
+	assert.NoError(t, err)
+	assert.True(t, options.HashUserNames)
+
+	_, err = parseOptions("Invalid")
+	assert.Error(t, err)
+}
+
+func TestHashUserNames(t *testing.T) {
+	users := []model.LocalUserData{
+		{Name: "alice", Id: "1000", Groups: "alice,wheel", Administrator: administratorTrue},
+		{Name: "bob", Id: "1001", Groups: "", Administrator: administratorFalse},
+	}
+
+	hashUserNames(users)
+
+	aliceHash := "2bd806c97f0e00af1a1fc3328fa763a9269723c8db8fac4f93af71db186d6e90"
+	assert.Equal(t, aliceHash, users[0].Name)
+	assert.Equal(t, aliceHash+",wheel", users[0].Groups)
+	assert.Equal(t, "81b637d8fcd2c6da6359e6963113a1170de795e4b725b84d1e0b4cfd9ec58ce9", users[1].Name)
+	assert.Equal(t, "", users[1].Groups)
+}
@@ -0,0 +1,281 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package localuser
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// lastlogRecordSize is the size of the lastlog record of each uid: a 32 bit time followed by the terminal and host names
+	lastlogRecordSize = 4 + 32 + 256
+	rootUid           = "0"
+	allUsers          = "ALL"
+	userAliasKeyword  = "User_Alias"
+)
+
+var (
+	passwdFile  = "/etc/passwd"
+	groupFile   = "/etc/group"
+	lastlogFile = "/var/log/lastlog"
+	sudoersFile = "/etc/sudoers"
+	sudoersDir  = "/etc/sudoers.d"
+)
+
+// localUser holds the fields of interest of an /etc/passwd entry
+type localUser struct {
+	name string
+	uid  string
+	gid  string
+}
+
+// localGroup holds the fields of interest of an /etc/group entry
+type localGroup struct {
+	name    string
+	gid     string
+	members []string
+}
+
+// sudoRules holds the users and groups granted sudo rights by the sudoers policy
+type sudoRules struct {
+	all    bool
+	users  map[string]bool
+	groups map[string]bool
+}
+
+// collectUsers reads the local accounts from the passwd and group databases, their rights from the sudoers policy
+// and their last login time from the lastlog database
+func collectUsers(context context.T) (data []model.LocalUserData, err error) {
+	log := context.Log()
+
+	var users []localUser
+	if users, err = readUsers(passwdFile); err != nil {
+		return
+	}
+	groups, groupErr := readGroups(groupFile)
+	if groupErr != nil {
+		log.Debugf("Unable to read groups - %v", groupErr)
+	}
+	rules := readSudoRules(log)
+	lastlog, lastlogErr := ioutil.ReadFile(lastlogFile)
+	if lastlogErr != nil {
+		log.Debugf("Unable to read last logins - %v", lastlogErr)
+	}
+
+	for _, user := range users {
+		userGroups := groupsOf(user, groups)
+		data = append(data, model.LocalUserData{
+			Name:          user.name,
+			Id:            user.uid,
+			Groups:        strings.Join(userGroups, groupSeparator),
+			LastLoginTime: lastLoginTime(lastlog, user.uid),
+			Administrator: isAdministrator(user, userGroups, rules),
+		})
+	}
+	return
+}
+
+// readColonSeparated returns the fields of the non comment lines of a colon separated database such as /etc/passwd
+func readColonSeparated(path string) (entries [][]string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, strings.Split(line, ":"))
+	}
+	return entries, scanner.Err()
+}
+
+// readUsers reads the name:password:uid:gid:gecos:home:shell entries of the passwd database
+func readUsers(path string) (users []localUser, err error) {
+	entries, err := readColonSeparated(path)
+	for _, fields := range entries {
+		// entries such as +@netgroup refer to remote accounts
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "+") || strings.HasPrefix(fields[0], "-") {
+			continue
+		}
+		users = append(users, localUser{name: fields[0], uid: fields[2], gid: fields[3]})
+	}
+	return
+}
+
+// readGroups reads the name:password:gid:members entries of the group database
+func readGroups(path string) (groups []localGroup, err error) {
+	entries, err := readColonSeparated(path)
+	for _, fields := range entries {
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "+") || strings.HasPrefix(fields[0], "-") {
+			continue
+		}
+		group := localGroup{name: fields[0], gid: fields[2]}
+		for _, member := range strings.Split(fields[3], ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				group.members = append(group.members, member)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return
+}
+
+// groupsOf returns the sorted names of the primary and supplementary groups of the user
+func groupsOf(user localUser, groups []localGroup) (names []string) {
+	for _, group := range groups {
+		if group.gid == user.gid {
+			names = append(names, group.name)
+			continue
+		}
+		for _, member := range group.members {
+			if member == user.name {
+				names = append(names, group.name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// lastLoginTime returns the last login time of the uid recorded in the lastlog database, empty if the user never logged in
+func lastLoginTime(lastlog []byte, uid string) string {
+	id, err := strconv.Atoi(uid)
+	if err != nil || id < 0 {
+		return ""
+	}
+	// the database is a sparse file indexed by uid
+	offset := id * lastlogRecordSize
+	if offset+lastlogRecordSize > len(lastlog) {
+		return ""
+	}
+	loginTime := binary.LittleEndian.Uint32(lastlog[offset:])
+	if loginTime == 0 {
+		return ""
+	}
+	return time.Unix(int64(loginTime), 0).UTC().Format(time.RFC3339)
+}
+
+// isAdministrator returns whether the user is root or granted sudo rights directly or through one of its groups
+func isAdministrator(user localUser, userGroups []string, rules sudoRules) string {
+	if user.uid == rootUid || rules.all || rules.users[user.name] || rules.users["#"+user.uid] {
+		return administratorTrue
+	}
+	for _, group := range userGroups {
+		if rules.groups[group] {
+			return administratorTrue
+		}
+	}
+	return administratorFalse
+}
+
+// readSudoRules reads the users and groups of the user specifications of the sudoers policy and its drop-in directory
+func readSudoRules(log log.T) (rules sudoRules) {
+	rules = sudoRules{users: make(map[string]bool), groups: make(map[string]bool)}
+	paths := []string{sudoersFile}
+	if entries, err := ioutil.ReadDir(sudoersDir); err == nil {
+		for _, entry := range entries {
+			// sudo skips the drop-in files whose name ends with ~ or contains a dot
+			if !entry.IsDir() && !strings.HasSuffix(entry.Name(), "~") && !strings.Contains(entry.Name(), ".") {
+				paths = append(paths, filepath.Join(sudoersDir, entry.Name()))
+			}
+		}
+	}
+
+	aliases := make(map[string][]string)
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Debugf("Unable to read sudoers policy %v - %v", path, err)
+			continue
+		}
+		parseSudoers(string(content), aliases, &rules)
+	}
+	return
+}
+
+// parseSudoers adds the users and groups of the user specifications of a sudoers file to the rules.
+// User aliases are expanded, negated entries are ignored as they restrict rights granted by other entries.
+func parseSudoers(content string, aliases map[string][]string, rules *sudoRules) {
+	for _, line := range strings.Split(strings.Replace(content, "\\\n", " ", -1), "\n") {
+		line = strings.TrimSpace(line)
+		// user specifications starting with a #uid are not told apart from comments
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") || strings.HasPrefix(line, "Defaults") {
+			continue
+		}
+		fields := strings.Fields(strings.Replace(line, ", ", ",", -1))
+		switch {
+		case fields[0] == userAliasKeyword:
+			// User_Alias NAME = user, %group : NAME2 = user
+			for _, definition := range strings.Split(strings.TrimPrefix(line, userAliasKeyword), ":") {
+				parts := strings.SplitN(definition, "=", 2)
+				if len(parts) == 2 {
+					aliases[strings.TrimSpace(parts[0])] = splitList(parts[1])
+				}
+			}
+		case strings.HasSuffix(fields[0], "_Alias"):
+			continue
+		case strings.Contains(line, "="):
+			addSudoUsers(splitList(fields[0]), aliases, rules, 0)
+		}
+	}
+}
+
+// addSudoUsers adds the entries of a user list to the rules, expanding aliases up to a small nesting depth
+func addSudoUsers(entries []string, aliases map[string][]string, rules *sudoRules, depth int) {
+	for _, entry := range entries {
+		switch {
+		case entry == allUsers:
+			rules.all = true
+		case strings.HasPrefix(entry, "!"):
+			continue
+		case strings.HasPrefix(entry, "%"):
+			rules.groups[strings.TrimPrefix(entry, "%")] = true
+		case aliases[entry] != nil:
+			if depth < 8 {
+				addSudoUsers(aliases[entry], aliases, rules, depth+1)
+			}
+		default:
+			rules.users[entry] = true
+		}
+	}
+}
+
+func splitList(list string) (entries []string) {
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package localuser
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	samplePasswd = `root:x:0:0:root:/root:/bin/bash
+# comment
+ec2-user:x:1000:1000:EC2 Default User:/home/ec2-user:/bin/bash
+alice:x:1001:1001::/home/alice:/bin/bash
+bob:x:1002:1002::/home/bob:/bin/bash
+`
+	sampleGroup = `root:x:0:
+adm:x:4:ec2-user
+wheel:x:10:ec2-user
+ec2-user:x:1000:
+alice:x:1001:
+bob:x:1002:
+`
+	sampleSudoers = `Defaults    secure_path = /sbin:/bin:/usr/sbin:/usr/bin
+User_Alias  OPERATORS = alice, \
+            carol
+root        ALL=(ALL)   ALL
+%wheel      ALL=(ALL)   ALL
+#includedir /etc/sudoers.d
+`
+	sampleDropIn = `OPERATORS ALL=(ALL) NOPASSWD: /usr/bin/systemctl
+`
+)
+
+// setupDatabases writes the sample databases in a temporary directory and points the gatherer to them
+func setupDatabases(t *testing.T) (restore func()) {
+	dir, err := ioutil.TempDir("", "localuser")
+	assert.NoError(t, err)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sudoers.d"), 0700))
+	files := map[string]string{
+		"passwd":              samplePasswd,
+		"group":               sampleGroup,
+		"sudoers":             sampleSudoers,
+		"sudoers.d/operators": sampleDropIn,
+		// ignored by sudo
+		"sudoers.d/bob.disabled": "bob ALL=(ALL) ALL\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	// ec2-user logged in at 2018-05-14T10:11:12Z
+	lastlog := make([]byte, 1001*lastlogRecordSize)
+	binary.LittleEndian.PutUint32(lastlog[1000*lastlogRecordSize:], 1526292672)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "lastlog"), lastlog, 0600))
+
+	passwdFileTemp, groupFileTemp, lastlogFileTemp, sudoersFileTemp, sudoersDirTemp := passwdFile, groupFile, lastlogFile, sudoersFile, sudoersDir
+	passwdFile = filepath.Join(dir, "passwd")
+	groupFile = filepath.Join(dir, "group")
+	lastlogFile = filepath.Join(dir, "lastlog")
+	sudoersFile = filepath.Join(dir, "sudoers")
+	sudoersDir = filepath.Join(dir, "sudoers.d")
+	return func() {
+		passwdFile, groupFile, lastlogFile, sudoersFile, sudoersDir = passwdFileTemp, groupFileTemp, lastlogFileTemp, sudoersFileTemp, sudoersDirTemp
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCollectLocalUserData(t *testing.T) {
+	defer setupDatabases(t)()
+
+	data, err := collectLocalUserData(context.NewMockDefault(), model.Config{Filters: model.Enabled})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.LocalUserData{
+		{Name: "root", Id: "0", Groups: "root", Administrator: "true"},
+		{Name: "ec2-user", Id: "1000", Groups: "adm,ec2-user,wheel", LastLoginTime: "2018-05-14T10:11:12Z", Administrator: "true"},
+		{Name: "alice", Id: "1001", Groups: "alice", Administrator: "true"},
+		{Name: "bob", Id: "1002", Groups: "bob", Administrator: "false"},
+	}, data)
+}
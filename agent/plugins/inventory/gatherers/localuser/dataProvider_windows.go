@@ -0,0 +1,100 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package localuser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+const (
+	PowershellCmd = "powershell"
+)
+
+var (
+	startMarker = "<start" + randomString(8) + ">"
+	endMarker   = "<end" + randomString(8) + ">"
+	// the members of the groups are matched by SID since their path names the domain instead of the workgroup
+	userInfoScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$computer = [ADSI]"WinNT://$env:COMPUTERNAME"
+$administrators = (New-Object System.Security.Principal.SecurityIdentifier("S-1-5-32-544")).Translate([System.Security.Principal.NTAccount]).Value.Split("\")[-1]
+$memberships = @{}
+foreach($g in $computer.Children | Where-Object { $_.SchemaClassName -eq "group" }) {
+foreach($m in @($g.Invoke("Members"))) {
+$sidBytes = $m.GetType().InvokeMember("objectSid", "GetProperty", $null, $m, $null)
+$sid = (New-Object System.Security.Principal.SecurityIdentifier($sidBytes, 0)).Value
+$memberships[$sid] = @($memberships[$sid]) + $g.Name.ToString()
+}
+}
+$jsonObj = @()
+foreach($u in $computer.Children | Where-Object { $_.SchemaClassName -eq "user" }) {
+$Name = $u.Name.ToString()
+$Id = (New-Object System.Security.Principal.SecurityIdentifier($u.objectSid.Value, 0)).Value
+$UserGroups = @($memberships[$Id] | Where-Object { $_ } | Sort-Object)
+$Groups = $UserGroups -join ","
+$Administrator = ($UserGroups -contains $administrators).ToString().ToLower()
+$LastLoginTime = ""
+try { $LastLoginTime = ([DateTime]$u.LastLogin.Value).ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ") } catch {}
+$jsonObj += @"
+{"Name": "` + mark(`$Name`) + `", "Id": "$Id", "Groups": "` + mark(`$Groups`) + `", "LastLoginTime": "$LastLoginTime", "Administrator": "$Administrator"}
+"@
+}
+$result = $jsonObj -join ","
+$result = "[" + $result + "]"
+[Console]::WriteLine($result)
+`
+)
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectUsers returns the local accounts with their local groups, administrators being members of the Administrators group
+func collectUsers(context context.T) (users []model.LocalUserData, err error) {
+	log := context.Log()
+	var output []byte
+	if output, err = cmdExecutor(PowershellCmd, userInfoScript); err != nil {
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+
+	var cleanOutput string
+	if cleanOutput, err = pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField); err != nil {
+		return
+	}
+	log.Debugf("Command output: %v", cleanOutput)
+
+	if err = json.Unmarshal([]byte(cleanOutput), &users); err != nil {
+		err = fmt.Errorf("unable to parse command output - %v", err)
+	}
+	return
+}
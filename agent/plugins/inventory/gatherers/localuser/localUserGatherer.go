@@ -0,0 +1,70 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localuser contains a gatherer for the local accounts, their groups and administrator rights.
+package localuser
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// GathererName captures name of local user gatherer, local users are reported as a custom inventory type
+	GathererName = "Custom:LocalUser"
+	// SchemaVersionOfLocalUserGatherer represents schema version of local user gatherer
+	SchemaVersionOfLocalUserGatherer = "1.0"
+)
+
+// T represents local user gatherer which implements all contracts for gatherers.
+type T struct{}
+
+// Gatherer returns new local user gatherer
+func Gatherer(context context.T) *T {
+	return new(T)
+}
+
+var collectData = collectLocalUserData
+
+// Name returns name of local user gatherer
+func (t *T) Name() string {
+	return GathererName
+}
+
+// Run executes local user gatherer and returns list of inventory.Item comprising of the local accounts
+func (t *T) Run(context context.T, configuration model.Config) (items []model.Item, err error) {
+	//CaptureTime must comply with format: 2016-07-30T18:15:37Z to comply with regex at SSM.
+	captureTime := time.Now().UTC().Format(time.RFC3339)
+
+	var data []model.LocalUserData
+	if data, err = collectData(context, configuration); err != nil {
+		return
+	}
+
+	items = append(items, model.Item{
+		Name:          t.Name(),
+		SchemaVersion: SchemaVersionOfLocalUserGatherer,
+		Content:       data,
+		CaptureTime:   captureTime,
+	})
+	return
+}
+
+// RequestStop stops the execution of local user gatherer.
+func (t *T) RequestStop(stopType contracts.StopType) error {
+	var err error
+	return err
+}
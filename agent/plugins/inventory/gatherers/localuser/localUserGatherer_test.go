@@ -0,0 +1,50 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localuser
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLocalUsers = []model.LocalUserData{
+	{
+		Name:          "ec2-user",
+		Id:            "1000",
+		Groups:        "adm,ec2-user,wheel",
+		LastLoginTime: "2018-05-14T10:11:12Z",
+		Administrator: "true",
+	},
+}
+
+func testCollectLocalUserData(context context.T, config model.Config) (data []model.LocalUserData, err error) {
+	return testLocalUsers, nil
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = testCollectLocalUserData
+	defer func() { collectData = collectLocalUserData }()
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, GathererName, items[0].Name)
+	assert.Equal(t, SchemaVersionOfLocalUserGatherer, items[0].SchemaVersion)
+	assert.Equal(t, testLocalUsers, items[0].Content)
+}
@@ -19,13 +19,19 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/servicestate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/updatehistory"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 )
@@ -56,14 +62,20 @@ func InitializeGatherers(context context.T) (SupportedGatherer, InstalledGathere
 	installedGatherer := InstalledGatherer{
 		application.GathererName:                 application.Gatherer(context),
 		awscomponent.GathererName:                awscomponent.Gatherer(context),
+		container.GathererName:                   container.Gatherer(context),
 		custom.GathererName:                      custom.Gatherer(context),
 		network.GathererName:                     network.Gatherer(context),
 		windowsUpdate.GathererName:               windowsUpdate.Gatherer(context),
 		file.GathererName:                        file.Gatherer(context),
 		instancedetailedinformation.GathererName: instancedetailedinformation.Gatherer(context),
+		listeningport.GathererName:               listeningport.Gatherer(context),
+		localuser.GathererName:                   localuser.Gatherer(context),
+		certificate.GathererName:                 certificate.Gatherer(context),
 		role.GathererName:                        role.Gatherer(context),
 		service.GathererName:                     service.Gatherer(context),
+		servicestate.GathererName:                servicestate.Gatherer(context),
 		registry.GathererName:                    registry.Gatherer(context),
+		updatehistory.GathererName:               updatehistory.Gatherer(context),
 	}
 
 	for key := range installedGatherer {
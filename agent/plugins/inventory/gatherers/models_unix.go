@@ -19,17 +19,27 @@ package gatherers
 import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/servicestate"
 )
 
 var supportedGathererNames = []string{
 	application.GathererName,
 	awscomponent.GathererName,
+	container.GathererName,
 	custom.GathererName,
 	network.GathererName,
 	file.GathererName,
 	instancedetailedinformation.GathererName,
+	servicestate.GathererName,
+	listeningport.GathererName,
+	localuser.GathererName,
+	certificate.GathererName,
 }
@@ -20,25 +20,37 @@ package gatherers
 import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/servicestate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/updatehistory"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 )
 
 var supportedGathererNames = []string{
 	application.GathererName,
 	awscomponent.GathererName,
+	container.GathererName,
 	custom.GathererName,
 	network.GathererName,
 	windowsUpdate.GathererName,
 	file.GathererName,
 	instancedetailedinformation.GathererName,
+	servicestate.GathererName,
+	listeningport.GathererName,
+	localuser.GathererName,
+	certificate.GathererName,
 	role.GathererName,
 	service.GathererName,
 	registry.GathererName,
+	updatehistory.GathererName,
 }
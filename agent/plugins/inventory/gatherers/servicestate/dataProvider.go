@@ -0,0 +1,92 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package servicestate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+// serviceFilters holds the name patterns selecting the services to report, all services are reported when Include is empty.
+// Patterns use the path.Match syntax and are not case sensitive.
+type serviceFilters struct {
+	Include []string
+	Exclude []string
+}
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectServiceStateData returns the state of the services of the instance which match the configured filters
+func collectServiceStateData(context context.T, config model.Config) (data []model.ServiceStateData, err error) {
+	log := context.Log()
+	log.Infof("collectServiceStateData called")
+
+	var filters serviceFilters
+	if filters, err = parseFilters(config.Filters); err != nil {
+		return
+	}
+
+	var services []model.ServiceStateData
+	if services, err = collectServices(context); err != nil {
+		log.Errorf("Unable to collect the state of services - %v", err)
+		return
+	}
+	for _, service := range services {
+		if filters.match(service.Name) {
+			data = append(data, service)
+		}
+	}
+	return
+}
+
+// parseFilters parses the filters of the gatherer, "Enabled" collects all services
+func parseFilters(filtersInput string) (filters serviceFilters, err error) {
+	if filtersInput == "" || filtersInput == model.Enabled {
+		return
+	}
+	if err = json.Unmarshal([]byte(filtersInput), &filters); err != nil {
+		return filters, fmt.Errorf("invalid service filters %v - %v", filtersInput, err)
+	}
+	for _, pattern := range append(filters.Include, filters.Exclude...) {
+		if _, err = path.Match(pattern, ""); err != nil {
+			return filters, fmt.Errorf("invalid service name pattern %v - %v", pattern, err)
+		}
+	}
+	return
+}
+
+// match returns true when the service name matches an include pattern, if any, and none of the exclude patterns
+func (f serviceFilters) match(name string) bool {
+	return (len(f.Include) == 0 || matchAny(f.Include, name)) && !matchAny(f.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		// patterns are validated when parsing the filters
+		if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name)); matched {
+			return true
+		}
+	}
+	return false
+}
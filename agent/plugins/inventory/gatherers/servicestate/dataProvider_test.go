@@ -0,0 +1,39 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package servicestate
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilters(t *testing.T) {
+	filters, err := parseFilters(model.Enabled)
+	assert.NoError(t, err)
+	assert.True(t, filters.match("sshd"))
+
+	filters, err = parseFilters(`{"Include": ["ssh*", "Win*"], "Exclude": ["sshd-keygen"]}`)
+	assert.NoError(t, err)
+	assert.True(t, filters.match("sshd"))
+	assert.True(t, filters.match("winrm"))
+	assert.False(t, filters.match("sshd-keygen"))
+	assert.False(t, filters.match("crond"))
+
+	_, err = parseFilters(`{"Include": ["[ssh"]}`)
+	assert.Error(t, err)
+	_, err = parseFilters("Invalid")
+	assert.Error(t, err)
+}
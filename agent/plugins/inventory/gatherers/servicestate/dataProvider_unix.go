@@ -0,0 +1,111 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package servicestate
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	systemctlCmd      = "systemctl"
+	serviceUnitSuffix = ".service"
+	// templateUnitSuffix ends the name of template units, only their instances are actual services
+	templateUnitSuffix = "@" + serviceUnitSuffix
+)
+
+var lookPath = exec.LookPath
+
+// collectServices returns the state of the systemd services, services are named after their unit without the .service suffix
+func collectServices(context context.T) (services []model.ServiceStateData, err error) {
+	log := context.Log()
+	if _, lookErr := lookPath(systemctlCmd); lookErr != nil {
+		log.Infof("systemd is not available on this instance - no service state to collect")
+		return
+	}
+
+	// unit files include the disabled services, units include the instances of template units
+	var unitFiles, units []byte
+	if unitFiles, err = cmdExecutor(systemctlCmd, "list-unit-files", "--type=service", "--no-legend", "--no-pager"); err != nil {
+		return
+	}
+	if units, err = cmdExecutor(systemctlCmd, "list-units", "--type=service", "--all", "--no-legend", "--no-pager"); err != nil {
+		return
+	}
+	names := parseUnitNames(string(unitFiles), string(units))
+	if len(names) == 0 {
+		return
+	}
+
+	var properties []byte
+	args := append([]string{"show", "--property=Id,UnitFileState,SubState,FragmentPath", "--no-pager"}, names...)
+	if properties, err = cmdExecutor(systemctlCmd, args...); err != nil {
+		return
+	}
+	return parseUnitProperties(string(properties)), nil
+}
+
+// parseUnitNames returns the distinct service units listed by systemctl, without the template units
+func parseUnitNames(listings ...string) (names []string) {
+	listed := make(map[string]bool)
+	for _, listing := range listings {
+		for _, line := range strings.Split(listing, "\n") {
+			fields := strings.Fields(line)
+			// failed units are flagged by a leading bullet
+			if len(fields) > 0 && !strings.HasSuffix(fields[0], serviceUnitSuffix) {
+				fields = fields[1:]
+			}
+			if len(fields) == 0 || !strings.HasSuffix(fields[0], serviceUnitSuffix) || strings.HasSuffix(fields[0], templateUnitSuffix) {
+				continue
+			}
+			if !listed[fields[0]] {
+				listed[fields[0]] = true
+				names = append(names, fields[0])
+			}
+		}
+	}
+	return
+}
+
+// parseUnitProperties parses the output of systemctl show, the properties of each unit are separated by an empty line
+func parseUnitProperties(output string) (services []model.ServiceStateData) {
+	for _, block := range strings.Split(strings.Replace(output, "\r\n", "\n", -1), "\n\n") {
+		var service model.ServiceStateData
+		for _, line := range strings.Split(block, "\n") {
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			switch parts[0] {
+			case "Id":
+				service.Name = strings.TrimSuffix(parts[1], serviceUnitSuffix)
+			case "UnitFileState":
+				service.EnabledState = parts[1]
+			case "SubState":
+				service.RunningState = parts[1]
+			case "FragmentPath":
+				service.Path = parts[1]
+			}
+		}
+		if service.Name != "" {
+			services = append(services, service)
+		}
+	}
+	return
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package servicestate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	sampleUnitFiles = `crond.service                 enabled
+getty@.service                enabled
+sshd.service                  enabled
+sshd-keygen.service           static
+`
+	sampleUnits = `crond.service       loaded active   running Command Scheduler
+getty@tty1.service  loaded active   running Getty on tty1
+● kdump.service     loaded failed   failed  Crash recovery kernel arming
+sshd.service        loaded active   running OpenSSH server daemon
+`
+	sampleProperties = `Id=crond.service
+SubState=running
+FragmentPath=/usr/lib/systemd/system/crond.service
+UnitFileState=enabled
+
+Id=getty@tty1.service
+SubState=running
+FragmentPath=/usr/lib/systemd/system/getty@.service
+UnitFileState=enabled
+
+Id=kdump.service
+SubState=failed
+FragmentPath=/usr/lib/systemd/system/kdump.service
+UnitFileState=disabled
+
+Id=sshd.service
+SubState=running
+FragmentPath=/usr/lib/systemd/system/sshd.service
+UnitFileState=enabled
+
+Id=sshd-keygen.service
+SubState=dead
+FragmentPath=/usr/lib/systemd/system/sshd-keygen.service
+UnitFileState=static
+`
+)
+
+func TestCollectServiceStateData(t *testing.T) {
+	cmdExecutorTemp, lookPathTemp := cmdExecutor, lookPath
+	defer func() {
+		cmdExecutor = cmdExecutorTemp
+		lookPath = lookPathTemp
+	}()
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "list-unit-files":
+			return []byte(sampleUnitFiles), nil
+		case "list-units":
+			return []byte(sampleUnits), nil
+		case "show":
+			expected := "crond.service sshd.service sshd-keygen.service getty@tty1.service kdump.service"
+			assert.Equal(t, expected, strings.Join(args[3:], " "))
+			return []byte(sampleProperties), nil
+		}
+		return nil, fmt.Errorf("unexpected command %v", args)
+	}
+
+	data, err := collectServiceStateData(context.NewMockDefault(), model.Config{Filters: `{"Exclude": ["getty*", "*-keygen"]}`})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ServiceStateData{
+		{Name: "crond", EnabledState: "enabled", RunningState: "running", Path: "/usr/lib/systemd/system/crond.service"},
+		{Name: "kdump", EnabledState: "disabled", RunningState: "failed", Path: "/usr/lib/systemd/system/kdump.service"},
+		{Name: "sshd", EnabledState: "enabled", RunningState: "running", Path: "/usr/lib/systemd/system/sshd.service"},
+	}, data)
+}
+
+func TestCollectServiceStateDataWithoutSystemd(t *testing.T) {
+	lookPathTemp := lookPath
+	defer func() { lookPath = lookPathTemp }()
+	lookPath = func(file string) (string, error) { return "", fmt.Errorf("%v not found", file) }
+
+	data, err := collectServiceStateData(context.NewMockDefault(), model.Config{Filters: model.Enabled})
+
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package servicestate
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+const (
+	PowershellCmd = "powershell"
+)
+
+var (
+	startMarker            = "<start" + randomString(8) + ">"
+	endMarker              = "<end" + randomString(8) + ">"
+	serviceStateInfoScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+ = Get-WmiObject Win32_Service | Select-Object Name, StartMode, State, PathName
+ = @()
+foreach( in ) {
+ = .Name
+ = .StartMode
+ = .State
+ = .PathName
+ += @"
+{"Name": "` + mark(``) + `", "EnabledState": "", "RunningState": "", "Path": "` + mark(``) + `"}
+"@
+}
+ =  -join ","
+ = "[" +  + "]"
+[Console]::WriteLine()
+`
+)
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
+
+// collectServices returns the start mode and state of the Windows services
+func collectServices(context context.T) (services []model.ServiceStateData, err error) {
+	log := context.Log()
+	var output []byte
+	if output, err = cmdExecutor(PowershellCmd, serviceStateInfoScript); err != nil {
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+
+	var cleanOutput string
+	if cleanOutput, err = pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField); err != nil {
+		return
+	}
+	log.Debugf("Command output: %v", cleanOutput)
+
+	if err = json.Unmarshal([]byte(cleanOutput), &services); err != nil {
+		err = fmt.Errorf("unable to parse command output - %v", err)
+	}
+	return
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package servicestate
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testServiceStates = []model.ServiceStateData{
+	{
+		Name:         "sshd",
+		EnabledState: "enabled",
+		RunningState: "running",
+		Path:         "/usr/lib/systemd/system/sshd.service",
+	},
+}
+
+func testCollectServiceStateData(context context.T, config model.Config) (data []model.ServiceStateData, err error) {
+	return testServiceStates, nil
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = testCollectServiceStateData
+	defer func() { collectData = collectServiceStateData }()
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, GathererName, items[0].Name)
+	assert.Equal(t, SchemaVersionOfServiceStateGatherer, items[0].SchemaVersion)
+	assert.Equal(t, testServiceStates, items[0].Content)
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updatehistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	PowershellCmd = "powershell"
+
+	// SourceWindowsUpdate marks the entries of the Windows Update history
+	SourceWindowsUpdate = "WindowsUpdate"
+	// SourceHotfix marks the installed hotfixes which Windows Update has no history of, e.g. updates installed from msu files
+	SourceHotfix = "Hotfix"
+
+	operationInstallation   = "Installation"
+	operationUninstallation = "Uninstallation"
+	resultInstalled         = "Installed"
+
+	// updateHistoryScript reports the hotfixes of win32_quickfixengineering and the history of the Windows Update
+	// agent, up to its 1000 most recent entries. The history is left empty when the Windows Update service can't be queried
+	updateHistoryScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$culture = [System.Globalization.CultureInfo]::GetCultureInfo("en-US")
+$hotfixes = @(Get-WmiObject -Class win32_quickfixengineering | Select-Object HotFixId,Description,@{l="InstalledTime";e={try {[DateTime]::Parse($_.psbase.properties["installedon"].value,$culture).ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")} catch {""}}})
+$history = @()
+try {
+$searcher = (New-Object -ComObject Microsoft.Update.Session).CreateUpdateSearcher()
+$count = $searcher.GetTotalHistoryCount()
+if ($count -gt 0) {
+$history = @($searcher.QueryHistory(0, [Math]::Min($count, 1000)) | Where-Object { $_.Title } | Select-Object Title,@{l="Date";e={$_.Date.ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")}},@{l="Operation";e={[int]$_.Operation}},@{l="ResultCode";e={[int]$_.ResultCode}})
+}
+} catch {}
+ConvertTo-Json -Depth 3 -Compress -InputObject @{Hotfixes=$hotfixes; History=$history}
+`
+)
+
+// kbIdPattern matches the KB id in the title of an update, e.g. "2018-05 Cumulative Update for Windows Server 2016 (KB4103723)"
+var kbIdPattern = regexp.MustCompile(`KB\d+`)
+
+// operations and results map the UpdateOperation and OperationResultCode values of the Windows Update agent
+var (
+	operations = map[int]string{
+		1: operationInstallation,
+		2: operationUninstallation,
+	}
+	results = map[int]string{
+		0: "NotStarted",
+		1: "InProgress",
+		2: "Succeeded",
+		3: "SucceededWithErrors",
+		4: "Failed",
+		5: "Aborted",
+	}
+)
+
+// updateHistoryOutput holds the output of updateHistoryScript
+type updateHistoryOutput struct {
+	Hotfixes []hotfix
+	History  []historyEntry
+}
+
+// hotfix is an entry of win32_quickfixengineering
+type hotfix struct {
+	HotFixId      string
+	Description   string
+	InstalledTime string
+}
+
+// historyEntry is an entry of the Windows Update history
+type historyEntry struct {
+	Title      string
+	Date       string
+	Operation  int
+	ResultCode int
+}
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectUpdateHistoryData returns the Windows Update history followed by the hotfixes which are missing from it
+func collectUpdateHistoryData(context context.T) (data []model.WindowsUpdateHistoryData, err error) {
+	log := context.Log()
+	log.Infof("collectUpdateHistoryData called")
+
+	var output []byte
+	if output, err = cmdExecutor(PowershellCmd, updateHistoryScript); err != nil {
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+	log.Debugf("Command output: %v", string(output))
+
+	var parsed updateHistoryOutput
+	if err = json.Unmarshal(output, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to parse command output - %v", err)
+	}
+	data = convertUpdateHistory(parsed)
+	log.Infof("%v windows update history entries found", len(data))
+	return
+}
+
+// convertUpdateHistory returns the inventory entries of the history and of the hotfixes, a hotfix is only reported
+// on its own when no successful installation of the same KB is found in the history
+func convertUpdateHistory(output updateHistoryOutput) (data []model.WindowsUpdateHistoryData) {
+	installed := make(map[string]bool)
+	for _, entry := range output.History {
+		item := model.WindowsUpdateHistoryData{
+			KBId:          kbIdPattern.FindString(entry.Title),
+			Title:         entry.Title,
+			InstalledTime: entry.Date,
+			Operation:     lookup(operations, entry.Operation),
+			Result:        lookup(results, entry.ResultCode),
+			Source:        SourceWindowsUpdate,
+		}
+		if item.KBId != "" && item.Operation == operationInstallation && strings.HasPrefix(item.Result, "Succeeded") {
+			installed[item.KBId] = true
+		}
+		data = append(data, item)
+	}
+	for _, fix := range output.Hotfixes {
+		if fix.HotFixId == "" || installed[fix.HotFixId] {
+			continue
+		}
+		data = append(data, model.WindowsUpdateHistoryData{
+			KBId:          fix.HotFixId,
+			Title:         fix.Description,
+			InstalledTime: fix.InstalledTime,
+			Operation:     operationInstallation,
+			Result:        resultInstalled,
+			Source:        SourceHotfix,
+		})
+	}
+	return
+}
+
+// lookup returns the name of a Windows Update agent code, or the code itself for the codes which aren't known
+func lookup(names map[int]string, code int) string {
+	if name, found := names[code]; found {
+		return name
+	}
+	return strconv.Itoa(code)
+}
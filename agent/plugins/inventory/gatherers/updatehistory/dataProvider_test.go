@@ -0,0 +1,103 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updatehistory
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleOutput = `{"Hotfixes":[` +
+	`{"HotFixId":"KB4103723","Description":"Security Update","InstalledTime":"2018-05-09T00:00:00Z"},` +
+	`{"HotFixId":"KB4093137","Description":"Update","InstalledTime":"2018-04-11T00:00:00Z"}],` +
+	`"History":[` +
+	`{"Title":"2018-05 Cumulative Update for Windows Server 2016 for x64-based Systems (KB4103723)","Date":"2018-05-09T03:12:45Z","Operation":1,"ResultCode":2},` +
+	`{"Title":"Definition Update for Windows Defender Antivirus - KB2267602 (Definition 1.267.1152.0)","Date":"2018-05-08T10:01:02Z","Operation":1,"ResultCode":4},` +
+	`{"Title":"Windows Malicious Software Removal Tool x64","Date":"2018-04-12T08:00:00Z","Operation":2,"ResultCode":7}]}`
+
+func TestCollectUpdateHistoryData(t *testing.T) {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte(sampleOutput), nil
+	}
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectUpdateHistoryData(context.NewMockDefault())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.WindowsUpdateHistoryData{
+		{
+			KBId:          "KB4103723",
+			Title:         "2018-05 Cumulative Update for Windows Server 2016 for x64-based Systems (KB4103723)",
+			InstalledTime: "2018-05-09T03:12:45Z",
+			Operation:     "Installation",
+			Result:        "Succeeded",
+			Source:        SourceWindowsUpdate,
+		},
+		{
+			KBId:          "KB2267602",
+			Title:         "Definition Update for Windows Defender Antivirus - KB2267602 (Definition 1.267.1152.0)",
+			InstalledTime: "2018-05-08T10:01:02Z",
+			Operation:     "Installation",
+			Result:        "Failed",
+			Source:        SourceWindowsUpdate,
+		},
+		{
+			Title:         "Windows Malicious Software Removal Tool x64",
+			InstalledTime: "2018-04-12T08:00:00Z",
+			Operation:     "Uninstallation",
+			Result:        "7",
+			Source:        SourceWindowsUpdate,
+		},
+		// KB4103723 is already part of the history
+		{
+			KBId:          "KB4093137",
+			Title:         "Update",
+			InstalledTime: "2018-04-11T00:00:00Z",
+			Operation:     "Installation",
+			Result:        "Installed",
+			Source:        SourceHotfix,
+		},
+	}, data)
+}
+
+func TestCollectUpdateHistoryDataEmptyHistory(t *testing.T) {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte(`{"Hotfixes":[],"History":[]}`), nil
+	}
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectUpdateHistoryData(context.NewMockDefault())
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestCollectUpdateHistoryDataError(t *testing.T) {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return nil, fmt.Errorf("exit status 1")
+	}
+	defer func() { cmdExecutor = executeCommand }()
+
+	_, err := collectUpdateHistoryData(context.NewMockDefault())
+	assert.Error(t, err)
+
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte("Get-WmiObject : Access denied"), nil
+	}
+	_, err = collectUpdateHistoryData(context.NewMockDefault())
+	assert.Error(t, err)
+}
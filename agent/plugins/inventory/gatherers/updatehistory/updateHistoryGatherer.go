@@ -0,0 +1,71 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatehistory contains a gatherer for the hotfixes and the Windows Update history of the instance.
+package updatehistory
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// GathererName captures name of windows update history gatherer, the updates are reported as a custom inventory type
+	GathererName = "Custom:WindowsUpdateHistory"
+	// SchemaVersionOfUpdateHistoryGatherer represents schema version of windows update history gatherer
+	SchemaVersionOfUpdateHistoryGatherer = "1.0"
+)
+
+// T represents windows update history gatherer which implements all contracts for gatherers.
+type T struct{}
+
+// Gatherer returns new windows update history gatherer
+func Gatherer(context context.T) *T {
+	return new(T)
+}
+
+var collectData = collectUpdateHistoryData
+
+// Name returns name of windows update history gatherer
+func (t *T) Name() string {
+	return GathererName
+}
+
+// Run executes windows update history gatherer and returns list of inventory.Item comprising of the installed
+// hotfixes and of the installations and removals recorded by Windows Update
+func (t *T) Run(context context.T, configuration model.Config) (items []model.Item, err error) {
+	//CaptureTime must comply with format: 2016-07-30T18:15:37Z to comply with regex at SSM.
+	captureTime := time.Now().UTC().Format(time.RFC3339)
+
+	var data []model.WindowsUpdateHistoryData
+	if data, err = collectData(context); err != nil {
+		return
+	}
+
+	items = append(items, model.Item{
+		Name:          t.Name(),
+		SchemaVersion: SchemaVersionOfUpdateHistoryGatherer,
+		Content:       data,
+		CaptureTime:   captureTime,
+	})
+	return
+}
+
+// RequestStop stops the execution of windows update history gatherer.
+func (t *T) RequestStop(stopType contracts.StopType) error {
+	var err error
+	return err
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updatehistory
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testUpdates = []model.WindowsUpdateHistoryData{
+	{
+		KBId:          "KB4103723",
+		Title:         "2018-05 Cumulative Update for Windows Server 2016 for x64-based Systems (KB4103723)",
+		InstalledTime: "2018-05-09T03:12:45Z",
+		Operation:     "Installation",
+		Result:        "Succeeded",
+		Source:        SourceWindowsUpdate,
+	},
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = func(context context.T) ([]model.WindowsUpdateHistoryData, error) {
+		return testUpdates, nil
+	}
+	defer func() { collectData = collectUpdateHistoryData }()
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, GathererName, items[0].Name)
+	assert.Equal(t, SchemaVersionOfUpdateHistoryGatherer, items[0].SchemaVersion)
+	assert.Equal(t, testUpdates, items[0].Content)
+}
+
+func TestGathererError(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = func(context context.T) ([]model.WindowsUpdateHistoryData, error) {
+		return nil, fmt.Errorf("powershell not found")
+	}
+	defer func() { collectData = collectUpdateHistoryData }()
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+	assert.Error(t, err)
+	assert.Empty(t, items)
+}
@@ -27,18 +27,25 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/lowpriority"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/datauploader"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/servicestate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/updatehistory"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
@@ -56,7 +63,9 @@ const (
 	errorMsgForUnableToDetectInvocationType   = "Unable to detect if %v plugin was invoked via ssm-associate because - %v"
 	errorMsgForInabilityToSendDataToSSM       = "Unable to upload inventory data to SSM"
 	msgWhenNoDataToReturnForInventoryPlugin   = "Inventory policy has been successfully applied but there is no inventory data to upload to SSM"
+	msgWhenInventoryDataIsUnchanged           = "Inventory policy has been successfully applied and collected inventory data is unchanged since the last upload to SSM"
 	successfulMsgForInventoryPlugin           = "Inventory policy has been successfully applied and collected inventory data has been uploaded to SSM"
+	msgWhenNoGathererIsDue                    = "Inventory policy has been successfully applied but none of the gatherers is due to collect inventory data"
 )
 
 // PluginInput represents configuration which is applied to inventory plugin during execution.
@@ -71,8 +80,18 @@ type PluginInput struct {
 	WindowsRegistry             string
 	WindowsUpdates              string
 	InstanceDetailedInformation string
+	Containers                  string
+	ServiceStates               string
+	ListeningPorts              string
+	LocalUsers                  string
+	Certificates                string
+	WindowsUpdateHistory        string
 	CustomInventory             string
 	CustomInventoryDirectory    string
+	// GathererSchedules collects with some gatherers less often than the association runs, it maps gatherer names
+	// to rate or cron expressions, e.g. {"AWS:Application": "rate(1 day)"}. The gatherers which are due on a run
+	// upload their data together.
+	GathererSchedules string
 }
 
 // decoupling platform.InstanceID for easy testability
@@ -99,6 +118,9 @@ type Plugin struct {
 
 	// machineID of the machine where agent is running - useful during command detection
 	machineID string
+
+	// scheduleLocation is where the last collections of the gatherers with a schedule are persisted
+	scheduleLocation string
 }
 
 // Name returns the plugin name
@@ -122,6 +144,7 @@ func NewPlugin(context context.T) (*Plugin, error) {
 	}
 
 	p.context = c
+	p.scheduleLocation = scheduleLocation(p.machineID)
 	p.stopPolicy = sdkutil.NewStopPolicy(Name(), model.ErrorThreshold)
 
 	//loads all registered gatherers (for now only a dummy application gatherer is loaded in memory)
@@ -144,6 +167,7 @@ func (p *Plugin) ApplyInventoryPolicy(context context.T, inventoryInput PluginIn
 
 	//map of all valid gatherers & respective configs to run
 	var gatherers map[gatherers.T]model.Config
+	var schedule *gathererSchedule
 
 	//validate all gatherers
 	if gatherers, err = p.ValidateInventoryInput(context, inventoryInput); err != nil {
@@ -153,6 +177,28 @@ func (p *Plugin) ApplyInventoryPolicy(context context.T, inventoryInput PluginIn
 		return
 	}
 
+	//only run the gatherers which are due, their data is uploaded together
+	if schedule, err = newGathererSchedule(log, inventoryInput.GathererSchedules, p.scheduleLocation, p.isKnownGatherer); err != nil {
+		log.Info(err.Error())
+		output.SetExitCode(1)
+		output.AppendError(err.Error())
+		return
+	}
+	collectionTime := time.Now()
+	if gatherers = schedule.dueGatherers(log, gatherers, collectionTime); len(gatherers) == 0 {
+		log.Info(msgWhenNoGathererIsDue)
+		output.SetExitCode(0)
+		output.AppendInfo(msgWhenNoGathererIsDue)
+		return
+	}
+	defer func() {
+		if output.GetExitCode() == 0 {
+			if err := schedule.recordCollection(gatherers, collectionTime); err != nil {
+				log.Warn(err)
+			}
+		}
+	}()
+
 	//execute all eligible gatherers with their respective config
 	if items, err = p.RunGatherers(gatherers); err != nil {
 		log.Info(err.Error())
@@ -185,6 +231,14 @@ func (p *Plugin) ApplyInventoryPolicy(context context.T, inventoryInput PluginIn
 		optimizedInventoryItems,
 		nonOptimizedInventoryItems)
 
+	//no need to call PutInventory if none of the inventory types changed since the last upload
+	if datauploader.HasOnlyContentHashes(optimizedInventoryItems) {
+		log.Info(msgWhenInventoryDataIsUnchanged)
+		output.SetExitCode(0)
+		output.AppendInfo(msgWhenInventoryDataIsUnchanged)
+		return
+	}
+
 	//first send data in optimized fashion
 	if status, retryWithNonOptimized = p.SendDataToInventory(context, optimizedInventoryItems); !status {
 
@@ -259,6 +313,13 @@ func (p *Plugin) CanGathererRun(context context.T, name string) (status bool, ga
 	return
 }
 
+// isKnownGatherer returns true if the gatherer is installed, whether or not it runs on this platform
+func (p *Plugin) isKnownGatherer(name string) bool {
+	_, supported := p.supportedGatherers[name]
+	_, installed := p.installedGatherers[name]
+	return supported || installed
+}
+
 func (p *Plugin) validatePredefinedGatherer(context context.T, collectionPolicy, gathererName string) (status bool, gatherer gatherers.T, policy model.Config, err error) {
 
 	if collectionPolicy == model.Enabled {
@@ -326,11 +387,17 @@ func (p *Plugin) ValidateInventoryInput(context context.T, input PluginInput) (c
 		network.GathererName:                     input.NetworkConfig,
 		windowsUpdate.GathererName:               input.WindowsUpdates,
 		instancedetailedinformation.GathererName: input.InstanceDetailedInformation,
+		container.GathererName:                   input.Containers,
+		listeningport.GathererName:               input.ListeningPorts,
+		updatehistory.GathererName:               input.WindowsUpdateHistory,
 	}
 
 	predefinedGatherersWithFilters := map[string]string{
-		file.GathererName:     input.Files,
-		registry.GathererName: input.WindowsRegistry,
+		file.GathererName:         input.Files,
+		registry.GathererName:     input.WindowsRegistry,
+		servicestate.GathererName: input.ServiceStates,
+		localuser.GathererName:    input.LocalUsers,
+		certificate.GathererName:  input.Certificates,
 	}
 
 	//NOTE:
@@ -383,7 +450,10 @@ func (p *Plugin) RunGatherers(gatherers map[gatherers.T]model.Config) (items []m
 		log.Infof("Invoking gatherer - %v", name)
 		start := time.Now()
 
-		if gItems, err = gatherer.Run(p.context, config); err != nil {
+		lowpriority.Run(log, func() {
+			gItems, err = gatherer.Run(p.context, config)
+		})
+		if err != nil {
 			err = fmt.Errorf("Encountered error while executing %v. Error - %v", name, err.Error())
 			break
 
@@ -169,6 +169,64 @@ type InstanceDetailedInformation struct {
 	OSServicePack         string
 }
 
+// ContainerData captures all attributes present in Custom:Container inventory type
+type ContainerData struct {
+	Runtime     string
+	ContainerId string
+	Name        string
+	Image       string
+	ImageTag    string `json:",omitempty"`
+	ImageDigest string `json:",omitempty"`
+	StartedTime string `json:",omitempty"`
+	Ports       string `json:",omitempty"`
+}
+
+// ServiceStateData captures all attributes present in Custom:ServiceState inventory type
+type ServiceStateData struct {
+	Name         string
+	EnabledState string
+	RunningState string
+	Path         string `json:",omitempty"`
+}
+
+// ListeningPortData captures all attributes present in Custom:ListeningPort inventory type
+type ListeningPortData struct {
+	Protocol    string
+	Address     string
+	Port        string
+	ProcessId   string `json:",omitempty"`
+	ProcessName string `json:",omitempty"`
+}
+
+// LocalUserData captures all attributes present in Custom:LocalUser inventory type
+type LocalUserData struct {
+	Name          string
+	Id            string
+	Groups        string
+	LastLoginTime string `json:",omitempty"`
+	Administrator string
+}
+
+// CertificateData captures all attributes present in Custom:Certificate inventory type
+type CertificateData struct {
+	Subject                 string
+	Issuer                  string
+	SubjectAlternativeNames string `json:",omitempty"`
+	NotAfter                string
+	Thumbprint              string
+	Location                string
+}
+
+// WindowsUpdateHistoryData captures all attributes present in Custom:WindowsUpdateHistory inventory type
+type WindowsUpdateHistoryData struct {
+	KBId          string `json:",omitempty"`
+	Title         string
+	InstalledTime string `json:",omitempty"`
+	Operation     string
+	Result        string
+	Source        string
+}
+
 // Config captures all various properties (including optional) that can be supplied to a gatherer.
 // NOTE: Not all properties will be applicable to all gatherers.
 // E.g: Applications gatherer uses Collection, Files use Filters, Custom uses Collection & Location.
@@ -0,0 +1,130 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package inventory contains implementation of aws:softwareInventory plugin
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/scheduleexpression"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+// scheduleTolerance lets a gatherer collect when the association runs slightly before the gatherer is due,
+// rather than one association interval later
+const scheduleTolerance = time.Minute
+
+// gathererSchedule decides which gatherers collect on a run of the inventory policy, the gatherers with a schedule
+// of their own only collect once it is due since their last collection which reached SSM
+type gathererSchedule struct {
+	location       string
+	expressions    map[string]scheduleexpression.ScheduleExpression
+	lastCollection map[string]time.Time
+}
+
+// scheduleLocation returns the file the times of the last collections are persisted in
+func scheduleLocation(machineID string) string {
+	return filepath.Join(appconfig.DefaultDataStorePath, machineID, appconfig.InventoryRootDirName, appconfig.InventoryScheduleFileName)
+}
+
+// newGathererSchedule parses the schedules of the inventory policy, a JSON object of gatherer names to rate or cron
+// expressions, e.g. {"AWS:Application": "rate(1 day)", "AWS:Network": "rate(1 hour)"}
+func newGathererSchedule(log log.T, schedules string, location string, known func(name string) bool) (schedule *gathererSchedule, err error) {
+	schedule = &gathererSchedule{
+		location:       location,
+		expressions:    make(map[string]scheduleexpression.ScheduleExpression),
+		lastCollection: make(map[string]time.Time),
+	}
+	if schedules == "" {
+		return schedule, nil
+	}
+
+	var expressions map[string]string
+	if err = json.Unmarshal([]byte(schedules), &expressions); err != nil {
+		return nil, fmt.Errorf("Unable to parse gatherer schedules %v - %v", schedules, err)
+	}
+	for name, expression := range expressions {
+		if !known(name) {
+			return nil, fmt.Errorf("Unrecognized inventory gatherer - %v in gatherer schedules", name)
+		}
+		if schedule.expressions[name], err = scheduleexpression.CreateScheduleExpression(log, expression); err != nil {
+			return nil, fmt.Errorf("Invalid schedule of inventory gatherer %v - %v", name, err)
+		}
+	}
+
+	if fileutil.Exists(location) {
+		if err = jsonutil.UnmarshalFile(location, &schedule.lastCollection); err != nil {
+			log.Debugf("Unable to read the last collections of the inventory gatherers - %v, collecting with all of them", err)
+			schedule.lastCollection = make(map[string]time.Time)
+		}
+	}
+	return schedule, nil
+}
+
+// isDue returns true if the gatherer collects on a run at the given time
+func (s *gathererSchedule) isDue(name string, now time.Time) bool {
+	expression, found := s.expressions[name]
+	if !found {
+		return true
+	}
+	last, found := s.lastCollection[name]
+	if !found {
+		return true
+	}
+	return !expression.Next(last).After(now.Add(scheduleTolerance))
+}
+
+// dueGatherers returns the configured gatherers which collect on a run at the given time
+func (s *gathererSchedule) dueGatherers(log log.T, configured map[gatherers.T]model.Config, now time.Time) map[gatherers.T]model.Config {
+	due := make(map[gatherers.T]model.Config)
+	for gatherer, config := range configured {
+		if s.isDue(gatherer.Name(), now) {
+			due[gatherer] = config
+		} else {
+			log.Infof("Skipping gatherer - %v, it last collected at %v and is not due yet", gatherer.Name(), s.lastCollection[gatherer.Name()])
+		}
+	}
+	return due
+}
+
+// recordCollection persists the time at which the gatherers with a schedule collected data which reached SSM
+func (s *gathererSchedule) recordCollection(collected map[gatherers.T]model.Config, collectedAt time.Time) (err error) {
+	updated := false
+	for gatherer := range collected {
+		if _, found := s.expressions[gatherer.Name()]; found {
+			s.lastCollection[gatherer.Name()] = collectedAt
+			updated = true
+		}
+	}
+	if !updated {
+		return nil
+	}
+
+	dataB, _ := json.Marshal(s.lastCollection)
+	if err = fileutil.MakeDirs(filepath.Dir(s.location)); err != nil {
+		return fmt.Errorf("Unable to create directory of file - %v because - %v", s.location, err.Error())
+	}
+	if _, err = fileutil.WriteIntoFileWithPermissions(s.location, string(dataB), appconfig.ReadWriteAccess); err != nil {
+		err = fmt.Errorf("Unable to update the last collections of the inventory gatherers in file - %v because - %v", s.location, err.Error())
+	}
+	return
+}
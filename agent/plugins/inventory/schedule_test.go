@@ -0,0 +1,80 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package inventory contains implementation of aws:softwareInventory plugin
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func knownGatherer(name string) bool {
+	return name == "AWS:Application" || name == "AWS:Network"
+}
+
+func namedGatherer(name string) *gatherers.Mock {
+	gatherer := gatherers.NewMockDefault()
+	gatherer.On("Name").Return(name)
+	return gatherer
+}
+
+func TestNewGathererScheduleRejectsInvalidSchedules(t *testing.T) {
+	_, err := newGathererSchedule(log.NewMockLog(), `{"AWS:Unknown": "rate(1 day)"}`, "", knownGatherer)
+	assert.Error(t, err)
+
+	_, err = newGathererSchedule(log.NewMockLog(), `{"AWS:Application": "daily"}`, "", knownGatherer)
+	assert.Error(t, err)
+
+	_, err = newGathererSchedule(log.NewMockLog(), `not json`, "", knownGatherer)
+	assert.Error(t, err)
+}
+
+func TestGathererScheduleCollectsWhenDue(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "inventory")
+	defer os.RemoveAll(dir)
+	location := filepath.Join(dir, "inventory", "gathererSchedule")
+	application := namedGatherer("AWS:Application")
+	network := namedGatherer("AWS:Network")
+	configured := map[gatherers.T]model.Config{
+		application: {Collection: model.Enabled},
+		network:     {Collection: model.Enabled},
+	}
+	schedules := `{"AWS:Application": "rate(1 day)"}`
+	now := time.Now()
+
+	// every gatherer collects on the first run
+	schedule, err := newGathererSchedule(log.NewMockLog(), schedules, location, knownGatherer)
+	assert.NoError(t, err)
+	due := schedule.dueGatherers(log.NewMockLog(), configured, now)
+	assert.Len(t, due, 2)
+	assert.NoError(t, schedule.recordCollection(due, now))
+
+	// the application gatherer waits for a day, the network gatherer collects on every run
+	schedule, err = newGathererSchedule(log.NewMockLog(), schedules, location, knownGatherer)
+	assert.NoError(t, err)
+	due = schedule.dueGatherers(log.NewMockLog(), configured, now.Add(time.Hour))
+	assert.Equal(t, map[gatherers.T]model.Config{network: {Collection: model.Enabled}}, due)
+
+	// slightly early runs still collect
+	due = schedule.dueGatherers(log.NewMockLog(), configured, now.Add(24*time.Hour-scheduleTolerance/2))
+	assert.Len(t, due, 2)
+}
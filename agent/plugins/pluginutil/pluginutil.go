@@ -15,6 +15,7 @@
 package pluginutil
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"strconv"
@@ -24,11 +25,13 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
 const (
@@ -131,13 +134,40 @@ func LoadParametersAsMap(log log.T, prop interface{}, out iohandler.IOHandler) (
 	return
 }
 
+var getAppConfig = appconfig.Config
+
 // ValidateExecutionTimeout validates the supplied input interface and converts it into a valid int value.
 func ValidateExecutionTimeout(log log.T, input interface{}) int {
+	return validateExecutionTimeout(log, input, defaultExecutionTimeoutInSeconds)
+}
+
+// ValidatePluginExecutionTimeout validates the supplied input interface like ValidateExecutionTimeout and applies
+// the default and max execution timeouts configured for the plugin in the agent config.
+func ValidatePluginExecutionTimeout(log log.T, pluginName string, input interface{}) int {
+	var timeout appconfig.PluginTimeoutCfg
+	if config, err := getAppConfig(false); err == nil {
+		timeout = config.Plugins.Timeouts[pluginName]
+	}
+
+	defaultTimeout := defaultExecutionTimeoutInSeconds
+	if timeout.DefaultSeconds > 0 {
+		defaultTimeout = timeout.DefaultSeconds
+	}
+	num := validateExecutionTimeout(log, input, defaultTimeout)
+	if timeout.MaxSeconds > 0 && num > timeout.MaxSeconds {
+		log.Infof("'TimeoutSeconds' value %v exceeds the maximum of %v configured for %v. Setting 'TimeoutSeconds' to %v", num, timeout.MaxSeconds, pluginName, timeout.MaxSeconds)
+		num = timeout.MaxSeconds
+	}
+	return num
+}
+
+// validateExecutionTimeout converts the supplied input interface into a valid int value, or the given default.
+func validateExecutionTimeout(log log.T, input interface{}, defaultTimeout int) int {
 	var num int
 
 	switch input.(type) {
 	case string:
-		num = extractIntFromString(log, input.(string))
+		num = extractIntFromString(log, input.(string), defaultTimeout)
 	case int:
 		num = input.(int)
 	case float64:
@@ -145,16 +175,130 @@ func ValidateExecutionTimeout(log log.T, input interface{}) int {
 		num = int(f)
 		log.Infof("Unexpected 'TimeoutSeconds' float value %v received. Applying 'TimeoutSeconds' as %v", f, num)
 	default:
-		log.Infof("Unexpected 'TimeoutSeconds' value %v received. Setting 'TimeoutSeconds' to default value %v", input, defaultExecutionTimeoutInSeconds)
+		log.Infof("Unexpected 'TimeoutSeconds' value %v received. Setting 'TimeoutSeconds' to default value %v", input, defaultTimeout)
+		return defaultTimeout
 	}
 
 	if num < minExecutionTimeoutInSeconds || num > maxExecutionTimeoutInSeconds {
-		log.Infof("'TimeoutSeconds' value should be between %v and %v. Setting 'TimeoutSeconds' to default value %v", minExecutionTimeoutInSeconds, maxExecutionTimeoutInSeconds, defaultExecutionTimeoutInSeconds)
-		num = defaultExecutionTimeoutInSeconds
+		log.Infof("'TimeoutSeconds' value should be between %v and %v. Setting 'TimeoutSeconds' to default value %v", minExecutionTimeoutInSeconds, maxExecutionTimeoutInSeconds, defaultTimeout)
+		num = defaultTimeout
 	}
 	return num
 }
 
+// mappableStatuses are the statuses an exit code can be mapped to
+var mappableStatuses = []contracts.ResultStatus{
+	contracts.ResultStatusSuccess,
+	contracts.ResultStatusFailed,
+	contracts.ResultStatusSuccessAndReboot,
+}
+
+// ParseExitCodeStatuses validates and converts the exit code to status mapping of a document, keyed by exit code.
+func ParseExitCodeStatuses(input map[string]string) (map[int]contracts.ResultStatus, error) {
+	statuses := make(map[int]contracts.ResultStatus, len(input))
+	for code, status := range input {
+		exitCode, err := strconv.Atoi(strings.TrimSpace(code))
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit code %q in the exit code statuses", code)
+		}
+		if exitCode == appconfig.CommandStoppedPreemptivelyExitCode {
+			return nil, fmt.Errorf("exit code %v is reserved for the commands stopped by a cancel or a timeout", exitCode)
+		}
+		mapped := false
+		for _, mappable := range mappableStatuses {
+			if strings.EqualFold(string(mappable), strings.TrimSpace(status)) {
+				statuses[exitCode] = mappable
+				mapped = true
+				break
+			}
+		}
+		if !mapped {
+			return nil, fmt.Errorf("invalid status %v for exit code %v, expected one of %v, %v or %v",
+				status, exitCode, mappableStatuses[0], mappableStatuses[1], mappableStatuses[2])
+		}
+	}
+	return statuses, nil
+}
+
+// GetMappedStatus returns the status an exit code is mapped to by the document, or the status of GetStatus.
+// Commands stopped by a cancel or a timeout keep their status.
+func GetMappedStatus(exitCode int, cancelFlag task.CancelFlag, statuses map[int]contracts.ResultStatus) contracts.ResultStatus {
+	if status, ok := statuses[exitCode]; ok && !cancelFlag.Canceled() && !cancelFlag.ShutDown() {
+		return status
+	}
+	return GetStatus(exitCode, cancelFlag)
+}
+
+// ResourceLimitsInput represents the resource limits of the processes requested by a document, 0 or empty leaves
+// the resource unlimited unless limited by the agent config.
+type ResourceLimitsInput struct {
+	CpuShares    interface{}
+	MemoryMB     interface{}
+	MaxProcesses interface{}
+}
+
+// GetResourceLimits validates the resource limits requested by a document and applies the limits configured for
+// the plugin in the agent config, documents can lower the configured limits but not exceed them.
+func GetResourceLimits(log log.T, pluginName string, input ResourceLimitsInput) (limits executers.ResourceLimits, err error) {
+	if limits.CpuShares, err = parseResourceLimit("CpuShares", input.CpuShares, appconfig.PluginCpuSharesMin, appconfig.PluginCpuSharesMax); err != nil {
+		return
+	}
+	if limits.MemoryMB, err = parseResourceLimit("MemoryMB", input.MemoryMB, appconfig.PluginMemoryMBMin, appconfig.PluginMemoryMBMax); err != nil {
+		return
+	}
+	if limits.MaxProcesses, err = parseResourceLimit("MaxProcesses", input.MaxProcesses, appconfig.PluginMaxProcessesMin, appconfig.PluginMaxProcessesMax); err != nil {
+		return
+	}
+
+	var configured appconfig.PluginResourceLimitsCfg
+	if config, err := getAppConfig(false); err == nil {
+		configured = config.Plugins.ResourceLimits[pluginName]
+	}
+	limits.CpuShares = capResourceLimit(log, "CpuShares", limits.CpuShares, configured.CpuShares, pluginName)
+	limits.MemoryMB = capResourceLimit(log, "MemoryMB", limits.MemoryMB, configured.MemoryMB, pluginName)
+	limits.MaxProcesses = capResourceLimit(log, "MaxProcesses", limits.MaxProcesses, configured.MaxProcesses, pluginName)
+	return limits, nil
+}
+
+// parseResourceLimit converts a resource limit of a document to an int within bounds, 0 when not set.
+func parseResourceLimit(name string, input interface{}, min int, max int) (limit int, err error) {
+	switch value := input.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		if strings.TrimSpace(value) == "" {
+			return 0, nil
+		}
+		if limit, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+			return 0, fmt.Errorf("invalid %v %q, expected an integer", name, value)
+		}
+	case float64:
+		limit = int(value)
+		if float64(limit) != value {
+			return 0, fmt.Errorf("invalid %v %v, expected an integer", name, value)
+		}
+	case int:
+		limit = value
+	default:
+		return 0, fmt.Errorf("invalid %v %v, expected an integer", name, value)
+	}
+	if limit != 0 && (limit < min || limit > max) {
+		return 0, fmt.Errorf("%v %v should be between %v and %v", name, limit, min, max)
+	}
+	return limit, nil
+}
+
+// capResourceLimit returns the limit requested by the document, lowered to the configured limit.
+func capResourceLimit(log log.T, name string, limit int, configured int, pluginName string) int {
+	if configured > 0 && (limit == 0 || limit > configured) {
+		if limit > configured {
+			log.Infof("'%v' value %v exceeds the maximum of %v configured for %v. Setting '%v' to %v", name, limit, configured, pluginName, name, configured)
+		}
+		return configured
+	}
+	return limit
+}
+
 // ParseRunCommand checks the command type and convert it to the string array
 func ParseRunCommand(input interface{}, output []string) []string {
 	switch value := input.(type) {
@@ -169,7 +313,7 @@ func ParseRunCommand(input interface{}, output []string) []string {
 }
 
 // extractIntFromString extracts a valid int value from a string.
-func extractIntFromString(log log.T, input string) int {
+func extractIntFromString(log log.T, input string, defaultTimeout int) int {
 	var iNum int
 	var fNum float64
 	var err error
@@ -184,8 +328,8 @@ func extractIntFromString(log log.T, input string) int {
 		iNum = int(fNum)
 		log.Infof("Unexpected 'TimeoutSeconds' float value %v received. Applying 'TimeoutSeconds' as %v", fNum, iNum)
 	} else {
-		log.Errorf("Unexpected 'TimeoutSeconds' string value %v received. Setting 'TimeoutSeconds' to default value %v", input, defaultExecutionTimeoutInSeconds)
-		iNum = defaultExecutionTimeoutInSeconds
+		log.Errorf("Unexpected 'TimeoutSeconds' string value %v received. Setting 'TimeoutSeconds' to default value %v", input, defaultTimeout)
+		iNum = defaultTimeout
 	}
 	return iNum
 }
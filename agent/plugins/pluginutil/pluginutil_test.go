@@ -16,10 +16,15 @@ package pluginutil
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -190,3 +195,100 @@ func TestCleanupJSONField(t *testing.T) {
 		assert.Equal(t, output, result)
 	}
 }
+
+func TestValidatePluginExecutionTimeout(t *testing.T) {
+	logger := log.NewMockLog()
+	defer func() { getAppConfig = appconfig.Config }()
+	getAppConfig = func(reload bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Plugins.Timeouts = map[string]appconfig.PluginTimeoutCfg{
+			appconfig.PluginNameAwsRunShellScript: {DefaultSeconds: 600, MaxSeconds: 1800},
+			appconfig.PluginNameAwsApplications:   {MaxSeconds: 900},
+		}
+		return config, nil
+	}
+
+	// the configured default applies to the steps without timeout
+	assert.Equal(t, 600, ValidatePluginExecutionTimeout(logger, appconfig.PluginNameAwsRunShellScript, nil))
+	assert.Equal(t, 600, ValidatePluginExecutionTimeout(logger, appconfig.PluginNameAwsRunShellScript, "test"))
+
+	// documents can lower the timeout but not exceed the max
+	assert.Equal(t, 60, ValidatePluginExecutionTimeout(logger, appconfig.PluginNameAwsRunShellScript, "60"))
+	assert.Equal(t, 1800, ValidatePluginExecutionTimeout(logger, appconfig.PluginNameAwsRunShellScript, 7200))
+
+	// the max also caps the default timeout of the plugin
+	assert.Equal(t, 900, ValidatePluginExecutionTimeout(logger, appconfig.PluginNameAwsApplications, nil))
+
+	// plugins without configured timeouts keep the defaults
+	assert.Equal(t, defaultExecutionTimeoutInSeconds, ValidatePluginExecutionTimeout(logger, appconfig.PluginNameAwsRunPowerShellScript, nil))
+	assert.Equal(t, 7200, ValidatePluginExecutionTimeout(logger, appconfig.PluginNameAwsRunPowerShellScript, 7200))
+}
+
+func TestGetResourceLimits(t *testing.T) {
+	logger := log.NewMockLog()
+	defer func() { getAppConfig = appconfig.Config }()
+	getAppConfig = func(reload bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Plugins.ResourceLimits = map[string]appconfig.PluginResourceLimitsCfg{
+			appconfig.PluginNameAwsRunShellScript: {MemoryMB: 1024, MaxProcesses: 200},
+		}
+		return config, nil
+	}
+
+	// the configured limits apply to the steps without limits
+	limits, err := GetResourceLimits(logger, appconfig.PluginNameAwsRunShellScript, ResourceLimitsInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, executers.ResourceLimits{MemoryMB: 1024, MaxProcesses: 200}, limits)
+
+	// documents can lower the limits but not exceed them
+	limits, err = GetResourceLimits(logger, appconfig.PluginNameAwsRunShellScript, ResourceLimitsInput{CpuShares: "512", MemoryMB: 256.0, MaxProcesses: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, executers.ResourceLimits{CpuShares: 512, MemoryMB: 256, MaxProcesses: 200}, limits)
+
+	// plugins without configured limits only have the limits of the document
+	limits, err = GetResourceLimits(logger, appconfig.PluginNameAwsApplications, ResourceLimitsInput{MemoryMB: " 2048 ", MaxProcesses: ""})
+	assert.NoError(t, err)
+	assert.Equal(t, executers.ResourceLimits{MemoryMB: 2048}, limits)
+
+	for _, input := range []ResourceLimitsInput{{CpuShares: "many"}, {MemoryMB: 1.5}, {MemoryMB: 8}, {MaxProcesses: true}} {
+		_, err = GetResourceLimits(logger, appconfig.PluginNameAwsApplications, input)
+		assert.Error(t, err, "%v", input)
+	}
+}
+
+func TestParseExitCodeStatuses(t *testing.T) {
+	statuses, err := ParseExitCodeStatuses(map[string]string{"2": "success", " 3 ": "SuccessAndReboot", "0": "Failed"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]contracts.ResultStatus{
+		2: contracts.ResultStatusSuccess,
+		3: contracts.ResultStatusSuccessAndReboot,
+		0: contracts.ResultStatusFailed,
+	}, statuses)
+
+	_, err = ParseExitCodeStatuses(map[string]string{"two": "Success"})
+	assert.Error(t, err)
+
+	_, err = ParseExitCodeStatuses(map[string]string{"2": "InProgress"})
+	assert.Error(t, err)
+
+	_, err = ParseExitCodeStatuses(map[string]string{fmt.Sprint(appconfig.CommandStoppedPreemptivelyExitCode): "Success"})
+	assert.Error(t, err)
+}
+
+func TestGetMappedStatus(t *testing.T) {
+	statuses := map[int]contracts.ResultStatus{
+		2:                         contracts.ResultStatusSuccess,
+		appconfig.SuccessExitCode: contracts.ResultStatusFailed,
+	}
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	assert.Equal(t, contracts.ResultStatusSuccess, GetMappedStatus(2, cancelFlag, statuses))
+	assert.Equal(t, contracts.ResultStatusFailed, GetMappedStatus(appconfig.SuccessExitCode, cancelFlag, statuses))
+	assert.Equal(t, contracts.ResultStatusSuccessAndReboot, GetMappedStatus(appconfig.RebootExitCode, cancelFlag, statuses))
+	assert.Equal(t, contracts.ResultStatusFailed, GetMappedStatus(1, cancelFlag, nil))
+
+	// canceled commands keep their status
+	cancelFlag.Set(task.Canceled)
+	assert.Equal(t, contracts.ResultStatusCancelled, GetMappedStatus(appconfig.CommandStoppedPreemptivelyExitCode, cancelFlag, statuses))
+	assert.Equal(t, contracts.ResultStatusFailed, GetMappedStatus(2, cancelFlag, statuses))
+}
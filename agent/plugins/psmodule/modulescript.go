@@ -0,0 +1,201 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package psmodule
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ActionInstall installs the module unless a matching version is already installed
+	ActionInstall = "Install"
+	// ActionUpgrade updates the module installed from a repository, or installs it
+	ActionUpgrade = "Upgrade"
+	// ActionUninstall removes the given version of the module, or all its versions
+	ActionUninstall = "Uninstall"
+
+	// defaultRepository is the repository modules are installed from when none is given
+	defaultRepository = "PSGallery"
+)
+
+var validModuleName = regexp.MustCompile(`^[\w.-]+$`)
+var validModuleVersion = regexp.MustCompile(`^\d+(\.\d+){1,3}$`)
+
+// validateModuleInput checks the module management parameters of the plugin input.
+func validateModuleInput(pluginInput PSModulePluginInput) error {
+	if pluginInput.ModuleName == "" {
+		return nil
+	}
+	if pluginInput.Source != "" {
+		return fmt.Errorf("source and moduleName cannot be used together")
+	}
+	if !validModuleName.MatchString(pluginInput.ModuleName) {
+		return fmt.Errorf("moduleName %v is not a valid module name", pluginInput.ModuleName)
+	}
+	switch moduleAction(pluginInput) {
+	case ActionInstall, ActionUpgrade, ActionUninstall:
+	default:
+		return fmt.Errorf("action %v is not supported, valid actions are %v, %v and %v", pluginInput.Action, ActionInstall, ActionUpgrade, ActionUninstall)
+	}
+	for name, version := range map[string]string{
+		"requiredVersion": pluginInput.RequiredVersion,
+		"minimumVersion":  pluginInput.MinimumVersion,
+		"maximumVersion":  pluginInput.MaximumVersion,
+	} {
+		if version != "" && !validModuleVersion.MatchString(version) {
+			return fmt.Errorf("%v %v is not a valid module version", name, version)
+		}
+	}
+	if pluginInput.RequiredVersion != "" && (pluginInput.MinimumVersion != "" || pluginInput.MaximumVersion != "") {
+		return fmt.Errorf("requiredVersion cannot be used with minimumVersion or maximumVersion")
+	}
+	if moduleAction(pluginInput) == ActionUninstall && (pluginInput.MinimumVersion != "" || pluginInput.MaximumVersion != "") {
+		return fmt.Errorf("only requiredVersion can be used to uninstall a module")
+	}
+	if pluginInput.Repository != "" && !validModuleName.MatchString(pluginInput.Repository) {
+		return fmt.Errorf("repository %v is not a valid repository name", pluginInput.Repository)
+	}
+	if pluginInput.RepositorySourceLocation != "" && pluginInput.Repository == "" {
+		return fmt.Errorf("repositorySourceLocation requires the name of the repository")
+	}
+	return nil
+}
+
+// moduleAction returns the action of the plugin input, modules are installed by default.
+func moduleAction(pluginInput PSModulePluginInput) string {
+	if pluginInput.Action == "" {
+		return ActionInstall
+	}
+	for _, action := range []string{ActionInstall, ActionUpgrade, ActionUninstall} {
+		if strings.EqualFold(action, pluginInput.Action) {
+			return action
+		}
+	}
+	return pluginInput.Action
+}
+
+// moduleCommands returns the PowerShell commands running the action on the module and verifying the installed versions.
+// The commands exit with 1 when the action or the verification fails, so the commands of the document are not run.
+func moduleCommands(pluginInput PSModulePluginInput) []string {
+	name := psQuote(pluginInput.ModuleName)
+	repository := pluginInput.Repository
+	if repository == "" {
+		repository = defaultRepository
+	}
+
+	commands := []string{
+		"try {",
+		"  $ErrorActionPreference = 'Stop'",
+		"  [Net.ServicePointManager]::SecurityProtocol = [Net.ServicePointManager]::SecurityProtocol -bor [Net.SecurityProtocolType]::Tls12",
+	}
+
+	action := moduleAction(pluginInput)
+	if action != ActionUninstall {
+		commands = append(commands,
+			"  if (-not (Get-PackageProvider -ListAvailable -Name NuGet -ErrorAction SilentlyContinue)) { Install-PackageProvider -Name NuGet -MinimumVersion 2.8.5.201 -Force | Out-Null }")
+		if pluginInput.RepositorySourceLocation != "" {
+			commands = append(commands, fmt.Sprintf(
+				"  if (-not (Get-PSRepository -Name %[1]v -ErrorAction SilentlyContinue)) { Register-PSRepository -Name %[1]v -SourceLocation %[2]v -InstallationPolicy Trusted }",
+				psQuote(repository), psQuote(pluginInput.RepositorySourceLocation)))
+		}
+	}
+
+	versionArgs := versionArguments(pluginInput)
+	install := fmt.Sprintf("Install-Module -Name %v -Repository %v%v -Scope AllUsers -AllowClobber -Force", name, psQuote(repository), versionArgs)
+	switch action {
+	case ActionInstall:
+		commands = append(commands, fmt.Sprintf("  if (-not (%v)) { %v }", matchingVersions(pluginInput), install))
+	case ActionUpgrade:
+		update := fmt.Sprintf("Update-Module -Name %v%v -Force", name, updateVersionArguments(pluginInput))
+		commands = append(commands, fmt.Sprintf("  if (Get-InstalledModule -Name %v -ErrorAction SilentlyContinue) { %v } else { %v }", name, update, install))
+	case ActionUninstall:
+		uninstall := fmt.Sprintf("Uninstall-Module -Name %v -AllVersions -Force", name)
+		if pluginInput.RequiredVersion != "" {
+			uninstall = fmt.Sprintf("Uninstall-Module -Name %v -RequiredVersion %v -Force", name, psQuote(pluginInput.RequiredVersion))
+		}
+		commands = append(commands, fmt.Sprintf("  if (%v) { %v }", matchingVersions(pluginInput), uninstall))
+	}
+
+	// verify the versions available to PowerShell after the action
+	commands = append(commands, fmt.Sprintf("  $versions = @(%v)", matchingVersions(pluginInput)))
+	if action == ActionUninstall {
+		commands = append(commands,
+			fmt.Sprintf("  if ($versions.Count -gt 0) { throw \"Module %v version(s) $($versions -join ', ') are still installed\" }", pluginInput.ModuleName),
+			fmt.Sprintf("  Write-Output \"Module %v is uninstalled.\"", pluginInput.ModuleName))
+	} else {
+		commands = append(commands,
+			fmt.Sprintf("  if ($versions.Count -eq 0) { throw \"No version of module %v matching the requested version is installed\" }", pluginInput.ModuleName),
+			fmt.Sprintf("  Write-Output \"Module %v version(s) $($versions -join ', ') installed.\"", pluginInput.ModuleName))
+	}
+
+	return append(commands,
+		"} catch {",
+		"  Write-Error $_",
+		"  exit 1",
+		"}")
+}
+
+// matchingVersions returns the PowerShell expression listing the available versions of the module matching the requested ones.
+func matchingVersions(pluginInput PSModulePluginInput) string {
+	var conditions []string
+	if pluginInput.RequiredVersion != "" {
+		conditions = append(conditions, fmt.Sprintf("$_ -eq [version]%v", psQuote(pluginInput.RequiredVersion)))
+	}
+	if pluginInput.MinimumVersion != "" {
+		conditions = append(conditions, fmt.Sprintf("$_ -ge [version]%v", psQuote(pluginInput.MinimumVersion)))
+	}
+	if pluginInput.MaximumVersion != "" {
+		conditions = append(conditions, fmt.Sprintf("$_ -le [version]%v", psQuote(pluginInput.MaximumVersion)))
+	}
+	versions := fmt.Sprintf("Get-Module -ListAvailable -Name %v | ForEach-Object { $_.Version }", psQuote(pluginInput.ModuleName))
+	if len(conditions) == 0 {
+		return versions
+	}
+	return fmt.Sprintf("%v | Where-Object { %v }", versions, strings.Join(conditions, " -and "))
+}
+
+// versionArguments returns the version arguments of Install-Module.
+func versionArguments(pluginInput PSModulePluginInput) string {
+	var args string
+	if pluginInput.RequiredVersion != "" {
+		args += " -RequiredVersion " + psQuote(pluginInput.RequiredVersion)
+	}
+	if pluginInput.MinimumVersion != "" {
+		args += " -MinimumVersion " + psQuote(pluginInput.MinimumVersion)
+	}
+	if pluginInput.MaximumVersion != "" {
+		args += " -MaximumVersion " + psQuote(pluginInput.MaximumVersion)
+	}
+	return args
+}
+
+// updateVersionArguments returns the version arguments of Update-Module, which has no minimum version.
+func updateVersionArguments(pluginInput PSModulePluginInput) string {
+	if pluginInput.RequiredVersion != "" {
+		return " -RequiredVersion " + psQuote(pluginInput.RequiredVersion)
+	}
+	if pluginInput.MaximumVersion != "" {
+		return " -MaximumVersion " + psQuote(pluginInput.MaximumVersion)
+	}
+	return ""
+}
+
+// psQuote returns the value as a single quoted PowerShell string, which is not expanded.
+func psQuote(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
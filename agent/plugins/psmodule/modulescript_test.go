@@ -0,0 +1,82 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package psmodule
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateModuleInput(t *testing.T) {
+	assert.NoError(t, validateModuleInput(PSModulePluginInput{}))
+	assert.NoError(t, validateModuleInput(PSModulePluginInput{ModuleName: "AWSPowerShell", RequiredVersion: "3.3.0.0"}))
+	assert.NoError(t, validateModuleInput(PSModulePluginInput{ModuleName: "Pester", Action: "upgrade", MinimumVersion: "4.0", MaximumVersion: "4.99"}))
+	assert.NoError(t, validateModuleInput(PSModulePluginInput{ModuleName: "Pester", Repository: "Internal", RepositorySourceLocation: "https://nuget.corp/api/v2"}))
+
+	for _, input := range []PSModulePluginInput{
+		{ModuleName: "Pester", Source: "https://bucket/pester.zip"},
+		{ModuleName: "Pester; Remove-Item C:\\"},
+		{ModuleName: "Pester", Action: "Reinstall"},
+		{ModuleName: "Pester", RequiredVersion: "latest"},
+		{ModuleName: "Pester", RequiredVersion: "4.0", MinimumVersion: "3.0"},
+		{ModuleName: "Pester", Action: ActionUninstall, MinimumVersion: "3.0"},
+		{ModuleName: "Pester", RepositorySourceLocation: "https://nuget.corp/api/v2"},
+	} {
+		assert.Error(t, validateModuleInput(input), "%+v", input)
+	}
+}
+
+func TestModuleCommandsInstall(t *testing.T) {
+	script := strings.Join(moduleCommands(PSModulePluginInput{
+		ModuleName:               "Pester",
+		RequiredVersion:          "4.3.1",
+		Repository:               "Internal",
+		RepositorySourceLocation: "https://nuget.corp/api/v2",
+	}), "\n")
+
+	assert.Contains(t, script, "Register-PSRepository -Name 'Internal' -SourceLocation 'https://nuget.corp/api/v2' -InstallationPolicy Trusted")
+	assert.Contains(t, script, "Install-Module -Name 'Pester' -Repository 'Internal' -RequiredVersion '4.3.1' -Scope AllUsers -AllowClobber -Force")
+	assert.Contains(t, script, "Where-Object { $_ -eq [version]'4.3.1' }")
+	assert.Contains(t, script, "exit 1")
+}
+
+func TestModuleCommandsUpgrade(t *testing.T) {
+	script := strings.Join(moduleCommands(PSModulePluginInput{
+		ModuleName:     "Pester",
+		Action:         ActionUpgrade,
+		MinimumVersion: "4.0",
+		MaximumVersion: "4.99",
+	}), "\n")
+
+	assert.Contains(t, script, "Update-Module -Name 'Pester' -MaximumVersion '4.99' -Force")
+	assert.Contains(t, script, "Install-Module -Name 'Pester' -Repository 'PSGallery' -MinimumVersion '4.0' -MaximumVersion '4.99'")
+	assert.Contains(t, script, "$_ -ge [version]'4.0' -and $_ -le [version]'4.99'")
+	assert.NotContains(t, script, "Register-PSRepository")
+}
+
+func TestModuleCommandsUninstall(t *testing.T) {
+	script := strings.Join(moduleCommands(PSModulePluginInput{ModuleName: "Pester", Action: "uninstall"}), "\n")
+
+	assert.Contains(t, script, "Uninstall-Module -Name 'Pester' -AllVersions -Force")
+	assert.Contains(t, script, "are still installed")
+	assert.NotContains(t, script, "Install-PackageProvider")
+}
+
+func TestPsQuote(t *testing.T) {
+	assert.Equal(t, "'it''s $home'", psQuote("it's $home"))
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 )
 
 // SourceHashType is set as default sha256.
@@ -57,6 +58,21 @@ type PSModulePluginInput struct {
 	Source           string
 	SourceHash       string
 	SourceHashType   string
+	// ModuleName is the module installed from a repository instead of a zip Source
+	ModuleName string
+	// Action is Install (default), Upgrade or Uninstall of ModuleName
+	Action string
+	// RequiredVersion pins the exact version of the module
+	RequiredVersion string
+	// MinimumVersion and MaximumVersion give the range of accepted versions of the module
+	MinimumVersion string
+	MaximumVersion string
+	// Repository is the registered repository the module is installed from, PSGallery by default
+	Repository string
+	// RepositorySourceLocation registers Repository with this url when it is not registered yet
+	RepositorySourceLocation string
+	// VerificationPolicy overrides the verification policy of the agent configuration for Source, it may only be stricter
+	VerificationPolicy string
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -98,6 +114,25 @@ func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput
 	}
 
 	pluginInput.ParsedCommands = pluginutil.ParseRunCommand(pluginInput.RunCommand, pluginInput.ParsedCommands)
+	if err = validateModuleInput(pluginInput); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	policy, err := verification.EffectivePolicy(pluginInput.VerificationPolicy)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	if pluginInput.ModuleName != "" && policy != verification.PolicyNone {
+		// the modules of a repository are installed by PowerShellGet, the agent can't verify them
+		output.MarkAsFailed(fmt.Errorf("moduleName cannot be installed with the %v verification policy, use a source with its hash instead", policy))
+		return
+	}
+	pluginInput.VerificationPolicy = string(policy)
+	if pluginInput.ModuleName != "" {
+		// the module is managed before the commands of the document run, they may depend on it
+		pluginInput.ParsedCommands = append(moduleCommands(pluginInput), pluginInput.ParsedCommands...)
+	}
 	p.runCommands(log, pluginID, pluginInput, orchestrationDirectory, cancelFlag, output)
 }
 
@@ -134,6 +169,9 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput PSModulePlu
 		if err != nil || downloadOutput.IsHashMatched == false || downloadOutput.LocalFilePath == "" {
 			output.MarkAsFailed(fmt.Errorf("failed to download file reliably %v", pluginInput.Source))
 			return
+		} else if err = verifySource(log, pluginInput, downloadOutput.LocalFilePath); err != nil {
+			output.MarkAsFailed(err)
+			return
 		} else {
 			// Uncompress the zip file received
 			if err = fileutil.Uncompress(downloadOutput.LocalFilePath, PowerShellModulesDirectory); err != nil {
@@ -144,7 +182,7 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput PSModulePlu
 	}
 
 	// Set execution time
-	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
+	executionTimeout := pluginutil.ValidatePluginExecutionTimeout(log, Name(), pluginInput.TimeoutSeconds)
 
 	// Construct Command Name and Arguments
 	commandName := pluginutil.GetShellCommand()
@@ -166,3 +204,16 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput PSModulePlu
 		}
 	}
 }
+
+// verifySource verifies the downloaded module as the verification policy requires
+func verifySource(log log.T, pluginInput PSModulePluginInput, localPath string) error {
+	policy, err := verification.EffectivePolicy(pluginInput.VerificationPolicy)
+	if err != nil {
+		return err
+	}
+	return verification.Verify(log, policy, verification.Artifact{
+		SourceURL: pluginInput.Source,
+		LocalPath: localPath,
+		Checksums: map[string]string{pluginInput.SourceHashType: pluginInput.SourceHash},
+	})
+}
@@ -16,16 +16,27 @@
 package runscript
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
 	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
 // powerShellScriptName is the script name where all downloaded or provided commands will be stored
 var powerShellScriptName = "_script.ps1"
 
+// transcriptFileName is the name of the file the PowerShell transcript is recorded to
+const transcriptFileName = "transcript.txt"
+
+// executionPolicies are the execution policies accepted by PowerShell
+var executionPolicies = []string{"AllSigned", "Bypass", "Default", "RemoteSigned", "Restricted", "Undefined", "Unrestricted"}
+
 // PSPlugin is the type for the RunPowerShellScript plugin and embeds Plugin struct.
 type runPowerShellPlugin struct {
 	Plugin
@@ -46,3 +57,138 @@ func NewRunPowerShellPlugin() (*runPowerShellPlugin, error) {
 
 	return &psplugin, nil
 }
+
+// resolvePowerShell returns the executable of the PowerShell engine requested by the document.
+// The other engine is used when the requested one is not installed, e.g. Windows PowerShell on instances without pwsh.
+func resolvePowerShell(log log.T, engine string) (string, error) {
+	requested := engine
+	if engine == "" || strings.EqualFold(engine, appconfig.PowerShellEngineAuto) {
+		engine = appconfig.DefaultPowerShellEngine
+	}
+
+	var preference []string
+	switch strings.ToLower(engine) {
+	case appconfig.PowerShellEngineCore:
+		preference = []string{appconfig.PowerShellEngineCore, appconfig.PowerShellEngineWindows}
+	case appconfig.PowerShellEngineWindows:
+		preference = []string{appconfig.PowerShellEngineWindows, appconfig.PowerShellEngineCore}
+	default:
+		return "", fmt.Errorf("unsupported PowerShell engine %v, expected one of %v, %v or %v",
+			engine, appconfig.PowerShellEngineAuto, appconfig.PowerShellEngineCore, appconfig.PowerShellEngineWindows)
+	}
+
+	for i, candidate := range preference {
+		if path := findPowerShell(candidate); path != "" {
+			if i > 0 && requested != "" {
+				log.Warnf("PowerShell engine %v is not installed, using %v instead", requested, path)
+			}
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("PowerShell is not installed on the instance, install PowerShell Core (pwsh) to run %v", appconfig.PluginNameAwsRunPowerShellScript)
+}
+
+// findPowerShell returns the path of the executable of an engine, or an empty string when the engine is not installed.
+var findPowerShell = func(engine string) string {
+	name, paths := appconfig.PowerShellCoreCommandName, appconfig.PowerShellCoreCommandPaths
+	if engine == appconfig.PowerShellEngineWindows {
+		name, paths = appconfig.WindowsPowerShellCommandName, appconfig.WindowsPowerShellCommandPaths
+	}
+	for _, path := range paths {
+		if fileutil.Exists(path) {
+			return path
+		}
+	}
+	if name != "" {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// resolvePowerShellArchitecture returns the Windows PowerShell executable of the requested architecture.
+func resolvePowerShellArchitecture(engine string, architecture string) (string, error) {
+	if architecture != appconfig.PowerShellArchitecture32 && architecture != appconfig.PowerShellArchitecture64 {
+		return "", fmt.Errorf("unsupported PowerShell architecture %v, expected %v or %v",
+			architecture, appconfig.PowerShellArchitecture32, appconfig.PowerShellArchitecture64)
+	}
+	if strings.EqualFold(engine, appconfig.PowerShellEngineCore) {
+		return "", fmt.Errorf("powerShellArchitecture is only supported with the %v engine", appconfig.PowerShellEngineWindows)
+	}
+	path, ok := appconfig.WindowsPowerShellCommandPathsByArchitecture[architecture]
+	if !ok || !fileutil.Exists(path) {
+		return "", fmt.Errorf("the %v-bit Windows PowerShell is not installed on the instance", architecture)
+	}
+	return path, nil
+}
+
+// powerShellArguments returns the arguments of the PowerShell host with the execution policy and the profile
+// loading requested by the document in place of the defaults. The defaults are returned when nothing was requested.
+func powerShellArguments(defaults []string, executionPolicy string, noProfile *bool) ([]string, error) {
+	if executionPolicy == "" && noProfile == nil {
+		return defaults, nil
+	}
+
+	var arguments []string
+	hasFile := false
+	defaultNoProfile := false
+	for i := 0; i < len(defaults); i++ {
+		switch {
+		case defaults[i] == "":
+		case strings.EqualFold(defaults[i], "-NoProfile"):
+			defaultNoProfile = true
+		case strings.EqualFold(defaults[i], "-ExecutionPolicy"):
+			if executionPolicy == "" && i+1 < len(defaults) {
+				executionPolicy = defaults[i+1]
+			}
+			i++
+		case i == len(defaults)-1 && (strings.EqualFold(defaults[i], "-f") || strings.EqualFold(defaults[i], "-File")):
+			hasFile = true
+		default:
+			arguments = append(arguments, defaults[i])
+		}
+	}
+
+	if noProfile == nil && defaultNoProfile || noProfile != nil && *noProfile {
+		arguments = append(arguments, "-NoProfile")
+	}
+	if executionPolicy != "" {
+		policy, err := validateExecutionPolicy(executionPolicy)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, "-ExecutionPolicy", policy)
+	}
+	if hasFile {
+		arguments = append(arguments, "-f")
+	}
+	return arguments, nil
+}
+
+// validateExecutionPolicy returns the execution policy with the case used by PowerShell
+func validateExecutionPolicy(executionPolicy string) (string, error) {
+	for _, policy := range executionPolicies {
+		if strings.EqualFold(policy, executionPolicy) {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported execution policy %v, expected one of %v", executionPolicy, strings.Join(executionPolicies, ", "))
+}
+
+// startTranscriptCommand returns the command recording the transcript of the session to path.
+// PowerShell stops the transcript when the session ends, including when the script exits early.
+func startTranscriptCommand(path string) string {
+	return fmt.Sprintf("Start-Transcript -Path '%v' -Force | Out-Null", strings.Replace(path, "'", "''", -1))
+}
+
+// appendTranscript appends the recorded transcript to the output of the plugin
+func appendTranscript(log log.T, path string, output iohandler.IOHandler) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Warnf("failed to read the PowerShell transcript %v: %v", path, err)
+		output.AppendErrorf("PowerShell transcript is not available: %v", err)
+		return
+	}
+	output.AppendInfof("PowerShell transcript:\n%v", strings.TrimPrefix(string(content), "\ufeff"))
+}
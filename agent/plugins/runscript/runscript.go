@@ -16,6 +16,7 @@ package runscript
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"strings"
@@ -26,16 +27,24 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/scriptcredentials"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/user"
 )
 
 const (
-	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+	downloadsDir   = "downloads"  //Directory under the orchestration directory where the downloaded resource resides
+	runAsDirPrefix = "ssm-runas-" //Prefix of the temporary directory holding the script of a runAsUser execution
 )
 
+var getSecureString = parameterstore.GetSecureString
+var vendScriptCredentials = scriptcredentials.Vend
+
 // Plugin is the type for the runscript plugin.
 type Plugin struct {
 	// ExecuteCommand is an object that can execute commands.
@@ -46,6 +55,13 @@ type Plugin struct {
 	ShellCommand   string
 	ShellArguments []string
 	ByteOrderMark  fileutil.ByteOrderMark
+	// PrivateTmp runs the scripts with private temporary directories where supported
+	PrivateTmp bool
+	// Sandbox confines the scripts where supported, with the AppArmor profile SandboxProfile for the apparmor sandbox
+	Sandbox        string
+	SandboxProfile string
+	// ScriptCredentials vends scoped credentials to the scripts instead of the access of the instance when configured
+	ScriptCredentials appconfig.ScriptCredentialsCfg
 }
 
 // RunScriptPluginInput represents one set of commands executed by the RunScript plugin.
@@ -55,6 +71,37 @@ type RunScriptPluginInput struct {
 	ID               string
 	WorkingDirectory string
 	TimeoutSeconds   interface{}
+	RunAsUser        string
+	RunAsGroup       string
+	// RunAsPasswordParameter is the name of the SecureString parameter holding the password of RunAsUser (windows only),
+	// group managed service accounts (DOMAIN\name$) do not need one
+	RunAsPasswordParameter string
+	// Environment holds variables injected into the environment of the script
+	Environment map[string]string
+	// CloudWatchLogGroupName enables streaming of the output to CloudWatch Logs while the commands run
+	CloudWatchLogGroupName string
+	// CreateWorkingDirectory creates the absolute WorkingDirectory when it does not exist instead of failing
+	CreateWorkingDirectory bool
+	// WorkingDirectoryPermissions is the octal mode of the created directories, 0755 by default
+	WorkingDirectoryPermissions string
+	// WorkingDirectoryOwner is the owner ("user" or "user:group") of the created directories, RunAsUser by default
+	WorkingDirectoryOwner string
+	// PowerShellEngine selects the engine running aws:runPowerShellScript (auto, pwsh or powershell)
+	PowerShellEngine string
+	// ExecutionPolicy is the execution policy of the PowerShell session running aws:runPowerShellScript
+	ExecutionPolicy string
+	// NoProfile skips loading the PowerShell profiles, the platform default is kept when not set
+	NoProfile *bool
+	// PowerShellArchitecture selects the 32-bit or 64-bit Windows PowerShell host ("32" or "64")
+	PowerShellArchitecture string
+	// CaptureTranscript records a PowerShell transcript of the session and appends it to the output
+	CaptureTranscript bool
+	// ExitCodeStatuses maps exit codes of the commands to Success, Failed or SuccessAndReboot
+	ExitCodeStatuses map[string]string
+	// Interpreter selects the interpreter running aws:runShellScript (sh, bash, zsh, python3 or an absolute path)
+	Interpreter string
+	// ResourceLimits caps the cpu shares, memory and number of processes of the script, within the configured limits
+	ResourceLimits pluginutil.ResourceLimitsInput
 }
 
 // Execute runs multiple sets of commands and returns their outputs.
@@ -69,13 +116,13 @@ func (p *Plugin) Execute(context context.T, config contracts.Configuration, canc
 	} else if cancelFlag.Canceled() {
 		output.MarkAsCancelled()
 	} else {
-		p.runCommandsRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
+		p.runCommandsRawInput(log, config.PluginID, config.MessageId, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
 	}
 }
 
 // runCommandsRawInput executes one set of commands and returns their output.
 // The input is in the default json unmarshal format (e.g. map[string]interface{}).
-func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput interface{}, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, messageID string, rawPluginInput interface{}, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	var pluginInput RunScriptPluginInput
 	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
 	if err != nil {
@@ -83,11 +130,11 @@ func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput
 		output.MarkAsFailed(errorString)
 		return
 	}
-	p.runCommands(log, pluginID, pluginInput, orchestrationDirectory, defaultWorkingDirectory, cancelFlag, output)
+	p.runCommands(log, pluginID, messageID, pluginInput, orchestrationDirectory, defaultWorkingDirectory, cancelFlag, output)
 }
 
 // runCommands executes one set of commands and returns their output.
-func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+func (p *Plugin) runCommands(log log.T, pluginID string, messageID string, pluginInput RunScriptPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	var err error
 	var workingDir string
 
@@ -102,8 +149,111 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		}
 	}
 
+	if err = validateEnvironment(pluginInput.Environment); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	exitCodeStatuses, err := pluginutil.ParseExitCodeStatuses(pluginInput.ExitCodeStatuses)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	commandName := p.ShellCommand
+	shellArguments := p.ShellArguments
+	if p.Name == appconfig.PluginNameAwsRunPowerShellScript {
+		if pluginInput.PowerShellArchitecture != "" {
+			commandName, err = resolvePowerShellArchitecture(pluginInput.PowerShellEngine, pluginInput.PowerShellArchitecture)
+		} else {
+			commandName, err = resolvePowerShell(log, pluginInput.PowerShellEngine)
+		}
+		if err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		if shellArguments, err = powerShellArguments(p.ShellArguments, pluginInput.ExecutionPolicy, pluginInput.NoProfile); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+	}
+	interpreter := ""
+	if p.Name == appconfig.PluginNameAwsRunShellScript {
+		if interpreter, err = resolveInterpreter(pluginInput.Interpreter); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+	}
+
+	resourceLimits, err := pluginutil.GetResourceLimits(log, p.Name, pluginInput.ResourceLimits)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	options := executers.ExecuteOptions{
+		RunAsUser:      pluginInput.RunAsUser,
+		RunAsGroup:     pluginInput.RunAsGroup,
+		Environment:    pluginInput.Environment,
+		PrivateTmp:     p.PrivateTmp,
+		Sandbox:        p.Sandbox,
+		SandboxProfile: p.SandboxProfile,
+		ResourceLimits: resourceLimits,
+	}
+	if options.RunAsUser != "" {
+		runAsUser, err := user.Lookup(options.RunAsUser)
+		if err != nil {
+			output.MarkAsFailed(fmt.Errorf("runAsUser %v does not exist on the instance: %v", options.RunAsUser, err))
+			return
+		}
+		// run from the home directory of the user unless a working directory was requested
+		if pluginInput.WorkingDirectory == "" {
+			workingDir = runAsUser.HomeDir
+		}
+	}
+	if pluginInput.RunAsPasswordParameter != "" {
+		if options.RunAsUser == "" {
+			output.MarkAsFailed(fmt.Errorf("runAsPasswordParameter requires runAsUser"))
+			return
+		}
+		if options.RunAsPassword, err = getSecureString(log, pluginInput.RunAsPasswordParameter); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to get the password of runAsUser %v: %v", options.RunAsUser, err))
+			return
+		}
+	}
+
+	if p.ScriptCredentials.Mode != "" {
+		vended, err := vendScriptCredentials(log, p.ScriptCredentials, messageID)
+		if err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to vend credentials to the script: %v", err))
+			return
+		}
+		defer vended.Close()
+		options.Environment = vended.Environment(options.Environment)
+	}
+
+	if pluginInput.CreateWorkingDirectory && pluginInput.WorkingDirectory != "" {
+		owner := pluginInput.WorkingDirectoryOwner
+		if owner == "" {
+			owner = options.RunAsUser
+		}
+		if err = createWorkingDirectory(log, pluginInput.WorkingDirectory, pluginInput.WorkingDirectoryPermissions, owner); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		workingDir = pluginInput.WorkingDirectory
+	}
+
 	// TODO:MF: This subdirectory is only needed because we could be running multiple sets of properties for the same plugin - otherwise the orchestration directory would already be unique
 	orchestrationDir := fileutil.BuildPath(orchestrationDirectory, pluginInput.ID)
+	if options.RunAsUser != "" {
+		// the orchestration directory is only accessible by the agent, stage the script where the user can read it
+		if orchestrationDir, err = fileutil.TempDir("", runAsDirPrefix); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to create script directory for runAsUser %v, %v", options.RunAsUser, err))
+			return
+		}
+		defer os.RemoveAll(orchestrationDir)
+	}
 	log.Debugf("Running commands %v in workingDirectory %v; orchestrationDir %v ", pluginInput.RunCommand, workingDir, orchestrationDir)
 
 	// create orchestration dir if needed
@@ -114,34 +264,97 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 
 	// Create script file path
 	scriptPath := filepath.Join(orchestrationDir, p.ScriptName)
-	log.Debugf("Writing commands %v to file %v", pluginInput, scriptPath)
+	log.Debugf("Writing commands %v to file %v", pluginInput.RunCommand, scriptPath)
 
-	// Create script file
-	if err = pluginutil.CreateScriptFile(log, scriptPath, pluginInput.RunCommand, p.ByteOrderMark); err != nil {
+	// Create script file, starting with the shebang of the interpreter when one was selected
+	runCommand := pluginInput.RunCommand
+	if interpreter != "" {
+		runCommand = append([]string{"#!" + interpreter}, runCommand...)
+	}
+	transcriptPath := ""
+	if p.Name == appconfig.PluginNameAwsRunPowerShellScript && pluginInput.CaptureTranscript {
+		transcriptPath = filepath.Join(orchestrationDir, transcriptFileName)
+		runCommand = append([]string{startTranscriptCommand(transcriptPath)}, runCommand...)
+	}
+	if err = pluginutil.CreateScriptFile(log, scriptPath, runCommand, p.ByteOrderMark); err != nil {
 		output.MarkAsFailed(fmt.Errorf("failed to create script file. %v", err))
 		return
 	}
 
+	if err = executers.GrantRunAsAccess(orchestrationDir, options); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to grant access to the script. %v", err))
+		return
+	}
+
+	if pluginInput.CloudWatchLogGroupName != "" {
+		streamOutputToCloudWatch(log, pluginInput.CloudWatchLogGroupName, messageID, pluginID, output)
+	}
+
 	// Set execution time
-	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
+	executionTimeout := pluginutil.ValidatePluginExecutionTimeout(log, p.Name, pluginInput.TimeoutSeconds)
 
-	// Construct Command Name and Arguments
-	commandName := p.ShellCommand
-	commandArguments := append(p.ShellArguments, scriptPath, appconfig.ExitCodeTrap)
+	// Construct Command Arguments
+	commandArguments := append(shellArguments, scriptPath, appconfig.ExitCodeTrap)
+	if interpreter != "" {
+		commandName = interpreter
+		commandArguments = []string{scriptPath}
+	}
+
+	// Report the processes stopped on cancel or timeout in the plugin result
+	options.ProcessTreeKilled = func(processesKilled int) {
+		output.AppendInfof("Killed %v process(es) started by the commands.", processesKilled)
+	}
 
 	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+	exitCode, err := p.CommandExecuter.NewExecuteWithOptions(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments, options)
+
+	if transcriptPath != "" {
+		appendTranscript(log, transcriptPath, output)
+	}
 
 	// Set output status
 	output.SetExitCode(exitCode)
-	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+	output.SetStatus(pluginutil.GetMappedStatus(exitCode, cancelFlag, exitCodeStatuses))
+	if status, ok := exitCodeStatuses[exitCode]; ok && output.GetStatus() == status {
+		output.AppendInfof("Exit code %v is mapped to status %v.", exitCode, status)
+	}
 
 	if err != nil {
 		status := output.GetStatus()
-		if status != contracts.ResultStatusCancelled &&
+		if status != contracts.ResultStatusSuccess &&
+			status != contracts.ResultStatusCancelled &&
 			status != contracts.ResultStatusTimedOut &&
 			status != contracts.ResultStatusSuccessAndReboot {
 			output.MarkAsFailed(fmt.Errorf("failed to run commands: %v", err))
 		}
 	}
 }
+
+// streamOutputToCloudWatch registers output modules sending stdout and stderr to a log stream of the invocation.
+func streamOutputToCloudWatch(log log.T, logGroupName string, messageID string, pluginID string, output iohandler.IOHandler) {
+	// colons are not allowed in log stream names
+	streamPrefix := strings.Replace(messageID+"/"+pluginID, ":", "-", -1)
+	log.Infof("Streaming output to CloudWatch log group %v, log stream prefix %v", logGroupName, streamPrefix)
+
+	output.RegisterOutputSource(log, output.GetStdoutWriter(), iomodule.CloudWatchLogs{
+		LogGroupName:  logGroupName,
+		LogStreamName: streamPrefix + "/stdout",
+	})
+	output.RegisterOutputSource(log, output.GetStderrWriter(), iomodule.CloudWatchLogs{
+		LogGroupName:  logGroupName,
+		LogStreamName: streamPrefix + "/stderr",
+	})
+}
+
+// validateEnvironment checks that the names of the environment variables can be set on the process.
+func validateEnvironment(environment map[string]string) error {
+	for name, value := range environment {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if strings.Contains(value, "\x00") {
+			return fmt.Errorf("value of environment variable %v contains a null character", name)
+		}
+	}
+	return nil
+}
@@ -15,16 +15,22 @@ package runscript
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/scriptcredentials"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -40,10 +46,9 @@ type TestCase struct {
 	MessageID      string
 }
 
-type CommandTester func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler)
+type CommandTester func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string)
 
 const (
-	orchestrationDirectory  = "OrchesDir"
 	defaultWorkingDirectory = ""
 	s3BucketName            = "bucket"
 	s3KeyPrefix             = "key"
@@ -128,7 +133,7 @@ func TestRunScripts(t *testing.T) {
 func testRunScripts(t *testing.T, testCase TestCase, rawInput bool) {
 	logger.On("Error", mock.Anything).Return(nil)
 	logger.Infof("test run commands %v", testCase)
-	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string) {
 		// set expectations
 		setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
 		setIOHandlerExpectations(mockIOHandler, testCase)
@@ -140,9 +145,9 @@ func testRunScripts(t *testing.T, testCase TestCase, rawInput bool) {
 			err := jsonutil.Remarshal(testCase.Input, &rawPluginInput)
 			assert.Nil(t, err)
 
-			p.runCommandsRawInput(logger, pluginID, rawPluginInput, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+			p.runCommandsRawInput(logger, pluginID, testCase.MessageID, rawPluginInput, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
 		} else {
-			p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+			p.runCommands(logger, pluginID, testCase.MessageID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
 		}
 	}
 
@@ -161,13 +166,13 @@ func TestBucketsInDifferentRegions(t *testing.T) {
 func testBucketsInDifferentRegions(t *testing.T, testCase TestCase, testingBucketsInDifferentRegions bool) {
 	logger.On("Error", mock.Anything).Return(nil)
 	logger.Infof("test run commands %v", testCase)
-	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string) {
 		// set expectations
 		setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
 		setIOHandlerExpectations(mockIOHandler, testCase)
 
 		// call method under test
-		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+		p.runCommands(logger, pluginID, testCase.MessageID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
 	}
 
 	testExecution(t, runScriptTester)
@@ -229,7 +234,7 @@ func buildOutputs(testCases []TestCase) (out string, err string, combined string
 }
 
 func testExecuteMultiInput(t *testing.T, testCases []TestCase) {
-	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string) {
 		// setup expectations and correct outputs
 		mockContext := context.NewMockDefault()
 
@@ -264,7 +269,7 @@ func testExecuteMultiInput(t *testing.T, testCases []TestCase) {
 
 // testExecute tests the run command plugin's Execute method.
 func testExecute(t *testing.T, testCase TestCase) {
-	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string) {
 		// setup expectations and correct outputs
 		mockContext := context.NewMockDefault()
 
@@ -313,8 +318,13 @@ func testExecution(t *testing.T, commandtester CommandTester) {
 	p.ShellCommand = "sh"
 	p.ShellArguments = []string{"-c"}
 
+	// write the scripts to a temporary orchestration directory
+	orchestrationDirectory, err := ioutil.TempDir("", "runscript")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDirectory)
+
 	// run inner command tester
-	commandtester(p, mockCancelFlag, mockExecuter, mockIOHandler)
+	commandtester(p, mockCancelFlag, mockExecuter, mockIOHandler, orchestrationDirectory)
 
 	// assert that the expectations were met
 	mockExecuter.AssertExpectations(t)
@@ -323,7 +333,7 @@ func testExecution(t *testing.T, commandtester CommandTester) {
 }
 
 func setExecuterExpectations(mockExecuter *executers.MockCommandExecuter, t TestCase, cancelFlag task.CancelFlag, p *Plugin) {
-	mockExecuter.On("NewExecute", mock.Anything, t.Input.WorkingDirectory, t.Output.StdoutWriter, t.Output.StderrWriter, cancelFlag, mock.Anything, mock.Anything, mock.Anything).Return(
+	mockExecuter.On("NewExecuteWithOptions", mock.Anything, t.Input.WorkingDirectory, t.Output.StdoutWriter, t.Output.StderrWriter, cancelFlag, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
 		t.Output.ExitCode, t.ExecuterError)
 }
 
@@ -343,3 +353,197 @@ func setCancelFlagExpectations(mockCancelFlag *task.MockCancelFlag, times int) {
 	mockCancelFlag.On("Canceled").Return(false).Times(times)
 	mockCancelFlag.On("ShutDown").Return(false).Times(times)
 }
+
+// TestRunScriptsRunAsUnknownUser tests that the commands are not executed when the runAsUser does not exist.
+func TestRunScriptsRunAsUnknownUser(t *testing.T) {
+	testCase := generateTestCaseOk("runas")
+	testCase.Input.RunAsUser = "ssm-agent-user-that-does-not-exist"
+
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string) {
+		mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+		p.runCommands(logger, pluginID, testCase.MessageID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+
+		mockExecuter.AssertNotCalled(t, "NewExecuteWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestRunScriptsRunAsPasswordWithoutUser tests that the commands are not executed when a password is given without runAsUser.
+func TestRunScriptsRunAsPasswordWithoutUser(t *testing.T) {
+	defer func(get func(log.T, string) (string, error)) { getSecureString = get }(getSecureString)
+	getSecureString = func(log.T, string) (string, error) {
+		assert.Fail(t, "the password should not be resolved")
+		return "", nil
+	}
+	testCase := generateTestCaseOk("runas")
+	testCase.Input.RunAsPasswordParameter = "svc/password"
+
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string) {
+		mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+		p.runCommands(logger, pluginID, testCase.MessageID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+
+		mockExecuter.AssertNotCalled(t, "NewExecuteWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestRunScriptsScriptCredentialsError tests that the commands are not executed when their credentials can't be vended.
+func TestRunScriptsScriptCredentialsError(t *testing.T) {
+	defer func(vend func(log.T, appconfig.ScriptCredentialsCfg, string) (*scriptcredentials.Vended, error)) {
+		vendScriptCredentials = vend
+	}(vendScriptCredentials)
+	vendScriptCredentials = func(log.T, appconfig.ScriptCredentialsCfg, string) (*scriptcredentials.Vended, error) {
+		return nil, fmt.Errorf("AccessDenied")
+	}
+	testCase := generateTestCaseOk("credentials")
+
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string) {
+		p.ScriptCredentials = appconfig.ScriptCredentialsCfg{Mode: appconfig.ScriptCredentialsModeEndpoint}
+		mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+		p.runCommands(logger, pluginID, testCase.MessageID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+
+		mockExecuter.AssertNotCalled(t, "NewExecuteWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestRunScriptsStreamToCloudWatch tests that the output is streamed to a log stream of the invocation.
+func TestRunScriptsStreamToCloudWatch(t *testing.T) {
+	testCase := generateTestCaseOk("cloudwatch")
+	testCase.MessageID = "aws.ssm.commandID.instanceID"
+	testCase.Input.CloudWatchLogGroupName = "group"
+	streamPrefix := "aws.ssm.commandID.instanceID/aws-runScript1"
+
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler, orchestrationDirectory string) {
+		setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+		mockIOHandler.On("RegisterOutputSource", logger, testCase.Output.StdoutWriter, []iomodule.IOModule{
+			iomodule.CloudWatchLogs{LogGroupName: "group", LogStreamName: streamPrefix + "/stdout"},
+		}).Return()
+		mockIOHandler.On("RegisterOutputSource", logger, testCase.Output.StderrWriter, []iomodule.IOModule{
+			iomodule.CloudWatchLogs{LogGroupName: "group", LogStreamName: streamPrefix + "/stderr"},
+		}).Return()
+
+		p.runCommands(logger, pluginID, testCase.MessageID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestValidateEnvironment tests the validation of the environment variables input.
+func TestValidateEnvironment(t *testing.T) {
+	assert.NoError(t, validateEnvironment(nil))
+	assert.NoError(t, validateEnvironment(map[string]string{"FOO": "bar", "EMPTY": ""}))
+	assert.Error(t, validateEnvironment(map[string]string{"": "bar"}))
+	assert.Error(t, validateEnvironment(map[string]string{"FOO=BAR": "bar"}))
+	assert.Error(t, validateEnvironment(map[string]string{"FOO": "b\x00ar"}))
+}
+
+// TestResolvePowerShell tests the selection of the PowerShell engine and the fallback to the installed one.
+func TestResolvePowerShell(t *testing.T) {
+	defer func(find func(string) string) { findPowerShell = find }(findPowerShell)
+	installed := map[string]string{appconfig.PowerShellEngineWindows: "powershell.exe"}
+	findPowerShell = func(engine string) string { return installed[engine] }
+
+	path, err := resolvePowerShell(logger, appconfig.PowerShellEngineCore)
+	assert.NoError(t, err)
+	assert.Equal(t, "powershell.exe", path)
+
+	installed[appconfig.PowerShellEngineCore] = "pwsh"
+	path, err = resolvePowerShell(logger, "PWSH")
+	assert.NoError(t, err)
+	assert.Equal(t, "pwsh", path)
+
+	path, err = resolvePowerShell(logger, "")
+	assert.NoError(t, err)
+	assert.Equal(t, installed[appconfig.DefaultPowerShellEngine], path)
+
+	_, err = resolvePowerShell(logger, "bash")
+	assert.Error(t, err)
+
+	installed = map[string]string{}
+	_, err = resolvePowerShell(logger, appconfig.PowerShellEngineAuto)
+	assert.Error(t, err)
+}
+
+// TestResolveInterpreter tests the selection of the interpreter running shell scripts.
+func TestResolveInterpreter(t *testing.T) {
+	defer func(look func(string) (string, error)) { lookPath = look }(lookPath)
+	lookPath = func(file string) (string, error) {
+		if file == appconfig.ShellInterpreterBash {
+			return "/bin/bash", nil
+		}
+		return "", fmt.Errorf("executable file not found in $PATH")
+	}
+
+	path, err := resolveInterpreter("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", path)
+
+	path, err = resolveInterpreter(appconfig.ShellInterpreterSh)
+	assert.NoError(t, err)
+	assert.Equal(t, "", path)
+
+	path, err = resolveInterpreter("Bash")
+	assert.NoError(t, err)
+	assert.Equal(t, "/bin/bash", path)
+
+	_, err = resolveInterpreter(appconfig.ShellInterpreterPython3)
+	assert.Error(t, err)
+
+	_, err = resolveInterpreter("perl")
+	assert.Error(t, err)
+
+	// the test binary is an executable at an absolute path
+	path, err = resolveInterpreter(os.Args[0])
+	assert.NoError(t, err)
+	assert.Equal(t, os.Args[0], path)
+
+	_, err = resolveInterpreter(filepath.Dir(os.Args[0]))
+	assert.Error(t, err)
+}
+
+// TestPowerShellArguments tests the replacement of the execution policy and profile loading in the host arguments.
+func TestPowerShellArguments(t *testing.T) {
+	windowsDefaults := []string{"-InputFormat", "None", "-Noninteractive", "-NoProfile", "-ExecutionPolicy", "unrestricted", "-f"}
+	yes, no := true, false
+
+	arguments, err := powerShellArguments(windowsDefaults, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, windowsDefaults, arguments)
+
+	arguments, err = powerShellArguments(windowsDefaults, "remotesigned", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-InputFormat", "None", "-Noninteractive", "-NoProfile", "-ExecutionPolicy", "RemoteSigned", "-f"}, arguments)
+
+	arguments, err = powerShellArguments(windowsDefaults, "", &no)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-InputFormat", "None", "-Noninteractive", "-ExecutionPolicy", "Unrestricted", "-f"}, arguments)
+
+	arguments, err = powerShellArguments([]string{""}, "Bypass", &yes)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-NoProfile", "-ExecutionPolicy", "Bypass"}, arguments)
+
+	_, err = powerShellArguments(windowsDefaults, "Anything", nil)
+	assert.Error(t, err)
+}
+
+// TestResolvePowerShellArchitecture tests the validation of the requested PowerShell host architecture.
+func TestResolvePowerShellArchitecture(t *testing.T) {
+	_, err := resolvePowerShellArchitecture("", "128")
+	assert.Error(t, err)
+
+	_, err = resolvePowerShellArchitecture(appconfig.PowerShellEngineCore, appconfig.PowerShellArchitecture32)
+	assert.Error(t, err)
+}
+
+// TestStartTranscriptCommand tests the quoting of the transcript path.
+func TestStartTranscriptCommand(t *testing.T) {
+	assert.Equal(t, "Start-Transcript -Path 'C:\\it''s\\transcript.txt' -Force | Out-Null", startTranscriptCommand("C:\\it's\\transcript.txt"))
+}
@@ -16,6 +16,12 @@
 package runscript
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
@@ -46,3 +52,30 @@ func NewRunShellPlugin(log log.T) (*runShellPlugin, error) {
 
 	return &shplugin, nil
 }
+
+// lookPath is assigned to a variable to allow the unit tests to override it
+var lookPath = exec.LookPath
+
+// resolveInterpreter returns the path of the interpreter requested by the document, or an empty string for the
+// default sh, which keeps running the script through "sh -c".
+func resolveInterpreter(interpreter string) (string, error) {
+	switch strings.ToLower(interpreter) {
+	case "", appconfig.ShellInterpreterSh:
+		return "", nil
+	case appconfig.ShellInterpreterBash, appconfig.ShellInterpreterZsh, appconfig.ShellInterpreterPython3:
+		path, err := lookPath(strings.ToLower(interpreter))
+		if err != nil {
+			return "", fmt.Errorf("interpreter %v is not installed on the instance", interpreter)
+		}
+		return path, nil
+	}
+
+	if !filepath.IsAbs(interpreter) {
+		return "", fmt.Errorf("unsupported interpreter %v, expected one of %v, %v, %v, %v or an absolute path",
+			interpreter, appconfig.ShellInterpreterSh, appconfig.ShellInterpreterBash, appconfig.ShellInterpreterZsh, appconfig.ShellInterpreterPython3)
+	}
+	if info, err := os.Stat(interpreter); err != nil || info.IsDir() {
+		return "", fmt.Errorf("interpreter %v does not exist on the instance", interpreter)
+	}
+	return interpreter, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// defaultWorkingDirectoryPermissions is the mode of the working directories created when the document does not specify one
+const defaultWorkingDirectoryPermissions os.FileMode = 0755
+
+// createWorkingDirectory creates the working directory and its missing parents before the commands run.
+// Every created directory gets the requested permissions (octal, e.g. "0750") and owner ("user" or "user:group").
+func createWorkingDirectory(log log.T, path string, permissions string, owner string) (err error) {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("workingDirectory %v must be an absolute path to be created", path)
+	}
+
+	mode := defaultWorkingDirectoryPermissions
+	if permissions != "" {
+		var parsed uint64
+		if parsed, err = strconv.ParseUint(permissions, 8, 32); err != nil || os.FileMode(parsed)&^os.ModePerm != 0 {
+			return fmt.Errorf("invalid workingDirectoryPermissions %v, expected an octal mode such as 0755", permissions)
+		}
+		mode = os.FileMode(parsed)
+	}
+
+	// collect the missing directories from the top most one down to the working directory
+	var missing []string
+	for current := path; ; current = filepath.Dir(current) {
+		if _, err = os.Stat(current); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to access workingDirectory %v: %v", current, err)
+		}
+		missing = append([]string{current}, missing...)
+		if parent := filepath.Dir(current); parent == current {
+			break
+		}
+	}
+
+	for _, dir := range missing {
+		log.Infof("Creating workingDirectory %v", dir)
+		if err = os.Mkdir(dir, mode); err != nil && !os.IsExist(err) {
+			return fmt.Errorf("failed to create workingDirectory %v: %v", dir, err)
+		}
+		if err = setWorkingDirectoryAccess(log, dir, mode, permissions != "", owner); err != nil {
+			return fmt.Errorf("failed to set access of workingDirectory %v: %v", dir, err)
+		}
+	}
+	return nil
+}
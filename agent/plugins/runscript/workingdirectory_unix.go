@@ -0,0 +1,56 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package runscript
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/user"
+)
+
+// setWorkingDirectoryAccess applies the mode, which is otherwise reduced by the umask, and the owner of a created directory.
+func setWorkingDirectoryAccess(log log.T, path string, mode os.FileMode, explicitMode bool, owner string) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	if owner == "" {
+		return nil
+	}
+
+	userName, groupName := owner, ""
+	if i := strings.Index(owner, ":"); i >= 0 {
+		userName, groupName = owner[:i], owner[i+1:]
+	}
+	owningUser, err := user.Lookup(userName)
+	if err != nil {
+		return fmt.Errorf("owner %v does not exist on the instance: %v", userName, err)
+	}
+	gidStr := owningUser.Gid
+	if groupName != "" {
+		owningGroup, err := user.LookupGroup(groupName)
+		if err != nil {
+			return fmt.Errorf("group %v does not exist on the instance: %v", groupName, err)
+		}
+		gidStr = owningGroup.Gid
+	}
+	uid, _ := strconv.Atoi(owningUser.Uid)
+	gid, _ := strconv.Atoi(gidStr)
+	return os.Chown(path, uid, gid)
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package runscript
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWorkingDirectory(t *testing.T) {
+	root, err := ioutil.TempDir("", "workingdir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "parent", "child")
+	assert.NoError(t, createWorkingDirectory(logger, path, "0750", ""))
+
+	for _, dir := range []string{filepath.Join(root, "parent"), path} {
+		fi, err := os.Stat(dir)
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+		assert.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+	}
+
+	// existing directories are left untouched
+	assert.NoError(t, createWorkingDirectory(logger, root, "0700", ""))
+}
+
+func TestCreateWorkingDirectoryInvalidInput(t *testing.T) {
+	assert.Error(t, createWorkingDirectory(logger, "relative/dir", "", ""))
+	assert.Error(t, createWorkingDirectory(logger, "/tmp/does/not/matter", "rwx", ""))
+	assert.Error(t, createWorkingDirectory(logger, "/tmp/does/not/matter", "7777", ""))
+}
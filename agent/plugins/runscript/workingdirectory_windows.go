@@ -0,0 +1,38 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package runscript
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// setWorkingDirectoryAccess sets the owner of a created directory, which otherwise inherits the access of its parent.
+func setWorkingDirectoryAccess(log log.T, path string, mode os.FileMode, explicitMode bool, owner string) error {
+	if explicitMode {
+		log.Warnf("workingDirectoryPermissions is not supported on windows, %v inherits the permissions of its parent", path)
+	}
+	if owner == "" {
+		return nil
+	}
+	if output, err := exec.Command("icacls", path, "/setowner", owner).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to make %v the owner: %v %v", owner, err, string(output))
+	}
+	return nil
+}
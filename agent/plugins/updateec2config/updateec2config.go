@@ -102,7 +102,7 @@ type pluginHelper interface {
 
 // Assign method to global variables to allow unittest to override
 var fileDownload = artifact.Download
-var fileUncompress = fileutil.Uncompress
+var fileUncompress = fileutil.UncompressStrict
 var updateAgent = runUpdateAgent
 var mkDirAll = os.MkdirAll
 
@@ -331,7 +331,7 @@ func (m *updateManager) generateSetupUpdateCmd(log log.T,
 		return "", err
 	}
 
-	cmd = updateutil.BuildUpdateCommand(cmd, MdsEndpointCmd, appConfig.Mds.Endpoint)
+	cmd = updateutil.BuildUpdateCommand(cmd, MdsEndpointCmd, appconfig.GetEndpointOverride(appConfig, appconfig.ServiceNameEc2Messages))
 
 	cmd = updateutil.BuildUpdateCommand(cmd, InstanceID, messageinfo[3])
 
@@ -360,7 +360,7 @@ func (m *updateManager) generateUpdateCmd(log log.T,
 		return "", err
 	}
 
-	cmd = updateutil.BuildUpdateCommand(cmd, MdsEndpointCmd, appConfig.Mds.Endpoint)
+	cmd = updateutil.BuildUpdateCommand(cmd, MdsEndpointCmd, appconfig.GetEndpointOverride(appConfig, appconfig.ServiceNameEc2Messages))
 
 	log.Debug("Update command is ", cmd)
 	return
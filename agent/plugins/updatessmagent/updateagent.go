@@ -30,9 +30,11 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 )
 
@@ -50,6 +52,9 @@ type UpdatePluginInput struct {
 	TargetVersion  string `json:"targetVersion"`
 	Source         string `json:"source"`
 	UpdaterName    string `json:"-"`
+	// VerificationPolicy overrides the verification policy of the agent configuration for this update,
+	// it may only be stricter
+	VerificationPolicy string `json:"verificationPolicy"`
 }
 
 // UpdatePluginConfig is used for initializing update agent plugin with default values
@@ -80,6 +85,7 @@ type pluginHelper interface {
 	downloadUpdater(log log.T,
 		util updateutil.T,
 		updaterPackageName string,
+		policy verification.Policy,
 		manifest *Manifest,
 		out iohandler.IOHandler,
 		context *updateutil.InstanceContext) (version string, err error)
@@ -94,8 +100,10 @@ type pluginHelper interface {
 // Assign method to global variables to allow unittest to override
 var getAppConfig = appconfig.Config
 var fileDownload = artifact.Download
-var fileUncompress = fileutil.Uncompress
+var fileUncompress = fileutil.UncompressStrict
+var verifyArtifact = verification.Verify
 var updateAgent = runUpdateAgent
+var isContainerized = platform.IsContainerized
 
 // NewPlugin returns a new instance of the plugin.
 func NewPlugin(updatePluginConfig UpdatePluginConfig) (*Plugin, error) {
@@ -126,11 +134,33 @@ func runUpdateAgent(
 		return
 	}
 
+	//The agent cannot update itself in a container, the container image must be updated instead
+	if isContainerized(log) {
+		environment, _ := platform.Environment(log)
+		output.MarkAsFailed(fmt.Errorf("%v cannot be updated when running in a container (%v), update the container image instead", pluginInput.AgentName, environment))
+		return
+	}
+
+	var policy verification.Policy
+	if policy, err = verification.EffectivePolicy(pluginInput.VerificationPolicy); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
 	if context, err = util.CreateInstanceContext(log); err != nil {
 		output.MarkAsFailed(err)
 		return
 	}
 
+	//Updates received outside the configured update window are deferred until the window opens
+	var deferUntil time.Time
+	if appConfig, configErr := getAppConfig(false); configErr == nil {
+		if deferUntil, err = updateWindowStart(appConfig.Agent.UpdateWindow); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+	}
+
 	//Use default manifest location is the override is not present
 	if len(pluginInput.Source) == 0 {
 		pluginInput.Source = p.ManifestLocation
@@ -168,7 +198,7 @@ func runUpdateAgent(
 	//Download updater and retrieve the version number
 	updaterVersion := ""
 	if updaterVersion, err = manager.downloadUpdater(
-		log, util, pluginInput.UpdaterName, manifest, output, context); err != nil {
+		log, util, pluginInput.UpdaterName, policy, manifest, output, context); err != nil {
 		output.MarkAsFailed(err)
 		return
 	}
@@ -190,15 +220,10 @@ func runUpdateAgent(
 	}
 	log.Debugf("Update command %v", cmd)
 
-	//Save update plugin result to local file, updater will read it during agent update
 	updatePluginResult := &updateutil.UpdatePluginResult{
 		StandOut:      output.GetStdout(),
 		StartDateTime: startTime,
 	}
-	if err = util.SaveUpdatePluginResult(log, appconfig.UpdaterArtifactsRoot, updatePluginResult); err != nil {
-		output.MarkAsFailed(err)
-		return
-	}
 
 	// If disk space is not sufficient, fail the update to prevent installation and notify user in output
 	// If loading disk space fails, continue to update (agent update is backed by rollback handler)
@@ -208,11 +233,47 @@ func runUpdateAgent(
 		return
 	}
 
+	workDir := updateutil.UpdateArtifactFolder(
+		appconfig.UpdaterArtifactsRoot, pluginInput.UpdaterName, updaterVersion)
+
+	//The update command replaces the updates deferred by earlier commands
+	commandID, _ := messageContracts.GetCommandID(config.MessageId)
+
+	//Save the update for the agent to hand it over to the updater once the update window opens,
+	//the command staying in progress until the updater reports its result
+	if !deferUntil.IsZero() {
+		deferredUpdate := &updateutil.DeferredUpdate{
+			CommandID:     commandID,
+			MessageID:     config.MessageId,
+			UpdateCommand: cmd,
+			WorkingDir:    workDir,
+			StartDateTime: deferUntil,
+			PluginResult:  updatePluginResult,
+		}
+		if err = util.SaveDeferredUpdate(log, appconfig.UpdaterArtifactsRoot, deferredUpdate); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		output.AppendInfof("Update deferred until the update window opens at %v\n", deferUntil.Format(time.RFC3339))
+		log.Infof("Update received outside the update window, deferring it until %v", deferUntil)
+		output.MarkAsInProgress()
+		return
+	}
+
+	if err = util.SupersedeDeferredUpdates(log, appconfig.UpdaterArtifactsRoot, commandID); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	//Save update plugin result to local file, updater will read it during agent update
+	if err = util.SaveUpdatePluginResult(log, appconfig.UpdaterArtifactsRoot, updatePluginResult); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
 	log.Infof("Start Installation")
 	log.Infof("Hand over update process to %v", pluginInput.UpdaterName)
 	//Execute updater, hand over the update process
-	workDir := updateutil.UpdateArtifactFolder(
-		appconfig.UpdaterArtifactsRoot, pluginInput.UpdaterName, updaterVersion)
 
 	if err = util.ExeCommand(
 		log,
@@ -230,6 +291,20 @@ func runUpdateAgent(
 	return
 }
 
+// updateWindowStart returns when the update window allows the update to start, the zero time when it can start now
+func updateWindowStart(config appconfig.UpdateWindowCfg) (start time.Time, err error) {
+	window, err := updateutil.NewUpdateWindow(config)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now()
+	if start = window.NextStart(now); !start.After(now) {
+		return time.Time{}, nil
+	}
+	return start, nil
+}
+
 //generateUpdateCmd generates cmd for the updater
 func (m *updateManager) generateUpdateCmd(log log.T,
 	manifest *Manifest,
@@ -272,6 +347,9 @@ func (m *updateManager) generateUpdateCmd(log log.T,
 	cmd = updateutil.BuildUpdateCommand(cmd, updateutil.OutputKeyPrefixCmd, keyPrefix)
 	cmd = updateutil.BuildUpdateCommand(cmd, updateutil.OutputBucketNameCmd, bucketName)
 
+	// the updater falls back to the policy of the agent configuration when the document doesn't override it
+	cmd = updateutil.BuildUpdateCommand(cmd, updateutil.VerificationPolicyCmd, strings.ToLower(strings.TrimSpace(pluginInput.VerificationPolicy)))
+
 	return
 }
 
@@ -307,6 +385,7 @@ func (m *updateManager) downloadManifest(log log.T,
 func (m *updateManager) downloadUpdater(log log.T,
 	util updateutil.T,
 	updaterPackageName string,
+	policy verification.Policy,
 	manifest *Manifest,
 	out iohandler.IOHandler,
 	context *updateutil.InstanceContext) (version string, err error) {
@@ -343,6 +422,15 @@ func (m *updateManager) downloadUpdater(log log.T,
 		return version, errors.New(errMessage)
 	}
 	out.AppendInfof("Successfully downloaded %v\n", downloadInput.SourceURL)
+	// the updater is executed by the agent, it has to be signed with one of the pinned keys
+	if verifyErr := verifyArtifact(log, policy, verification.Artifact{
+		SourceURL: downloadInput.SourceURL,
+		LocalPath: downloadOutput.LocalFilePath,
+		Checksums: downloadInput.SourceChecksums,
+		Signed:    true,
+	}); verifyErr != nil {
+		return version, verifyErr
+	}
 	if uncompressErr := fileUncompress(
 		downloadOutput.LocalFilePath,
 		updateutil.UpdateArtifactFolder(appconfig.UpdaterArtifactsRoot, updaterPackageName, version)); uncompressErr != nil {
@@ -19,13 +19,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/stretchr/testify/assert"
 )
@@ -90,8 +93,12 @@ func TestDownloadUpdater(t *testing.T) {
 	fileUncompress = func(src, dest string) error {
 		return nil
 	}
+	verifyArtifact = func(log log.T, policy verification.Policy, artifact verification.Artifact) error {
+		return nil
+	}
+	defer func() { verifyArtifact = verification.Verify }()
 
-	_, err := manager.downloadUpdater(logger, &util, plugin.AgentName, manifest, &out, context)
+	_, err := manager.downloadUpdater(logger, &util, plugin.AgentName, verification.PolicyNone, manifest, &out, context)
 
 	assert.NoError(t, err)
 }
@@ -113,7 +120,7 @@ func TestDownloadUpdater_HashDoesNotMatch(t *testing.T) {
 		return result, fmt.Errorf("404")
 	}
 
-	_, err := manager.downloadUpdater(logger, &util, plugin.AgentName, manifest, &out, context)
+	_, err := manager.downloadUpdater(logger, &util, plugin.AgentName, verification.PolicyNone, manifest, &out, context)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to download file reliably")
@@ -139,8 +146,12 @@ func TestDownloadUpdater_FailedDuringUnCompress(t *testing.T) {
 	fileUncompress = func(src, dest string) error {
 		return fmt.Errorf("Failed with uncompress")
 	}
+	verifyArtifact = func(log log.T, policy verification.Policy, artifact verification.Artifact) error {
+		return nil
+	}
+	defer func() { verifyArtifact = verification.Verify }()
 
-	_, err := manager.downloadUpdater(logger, &util, plugin.AgentName, manifest, &out, context)
+	_, err := manager.downloadUpdater(logger, &util, plugin.AgentName, verification.PolicyNone, manifest, &out, context)
 
 	assert.Error(t, err, "Failed with uncompress")
 }
@@ -279,14 +290,36 @@ func TestUpdateAgent(t *testing.T) {
 	pluginInput.TargetVersion = ""
 	mockCancelFlag := new(task.MockCancelFlag)
 	util := fakeUtility{}
+	isContainerized = func(log.T) bool { return false }
+	defer func() { isContainerized = platform.IsContainerized }()
 
 	for _, manager := range testCases {
 		out := iohandler.DefaultIOHandler{}
 		updateAgent(plugin, config, logger, &manager, &util, pluginInput, mockCancelFlag, &out, time.Now())
 		assert.Empty(t, out.GetStderr())
+		// the updates deferred by earlier commands are replaced by the update
+		assert.True(t, util.executed)
+		assert.Equal(t, config.MessageId, util.supersededBy)
 	}
 }
 
+func TestUpdateAgent_InContainer(t *testing.T) {
+	pluginInput := createStubPluginInput()
+	config := contracts.Configuration{}
+	plugin := &Plugin{}
+	mockCancelFlag := new(task.MockCancelFlag)
+	manager := &fakeUpdateManager{}
+	util := &fakeUtility{}
+	isContainerized = func(log.T) bool { return true }
+	defer func() { isContainerized = platform.IsContainerized }()
+
+	out := iohandler.DefaultIOHandler{}
+	updateAgent(plugin, config, logger, manager, util, pluginInput, mockCancelFlag, &out, time.Now())
+
+	assert.Equal(t, contracts.ResultStatusFailed, out.GetStatus())
+	assert.Contains(t, out.GetStderr(), "update the container image instead")
+}
+
 func TestUpdateAgent_NegativeTestCases(t *testing.T) {
 	pluginInput := createStubPluginInput()
 	pluginInput.TargetVersion = ""
@@ -419,7 +452,11 @@ func createStubInstanceContext() *updateutil.InstanceContext {
 	return &context
 }
 
-type fakeUtility struct{}
+type fakeUtility struct {
+	executed       bool
+	deferredUpdate *updateutil.DeferredUpdate
+	supersededBy   string
+}
 
 func (u *fakeUtility) CreateInstanceContext(log log.T) (context *updateutil.InstanceContext, err error) {
 	return createStubInstanceContext(), nil
@@ -441,6 +478,7 @@ func (u *fakeUtility) ExeCommand(
 	stdOut string,
 	stdErr string,
 	isAsync bool) (err error) {
+	u.executed = true
 	return nil
 }
 
@@ -451,6 +489,22 @@ func (u *fakeUtility) SaveUpdatePluginResult(
 	return nil
 }
 
+func (u *fakeUtility) SaveDeferredUpdate(
+	log log.T,
+	updateRoot string,
+	deferredUpdate *updateutil.DeferredUpdate) (err error) {
+	u.deferredUpdate = deferredUpdate
+	return nil
+}
+
+func (u *fakeUtility) SupersedeDeferredUpdates(
+	log log.T,
+	updateRoot string,
+	commandID string) (err error) {
+	u.supersededBy = commandID
+	return nil
+}
+
 func (u *fakeUtility) IsDiskSpaceSufficientForUpdate(log log.T) (bool, error) {
 	return true, nil
 }
@@ -491,6 +545,7 @@ func (u *fakeUpdateManager) downloadManifest(log log.T,
 func (u *fakeUpdateManager) downloadUpdater(log log.T,
 	util updateutil.T,
 	updaterPackageName string,
+	policy verification.Policy,
 	manifest *Manifest,
 	out iohandler.IOHandler,
 	context *updateutil.InstanceContext) (version string, err error) {
@@ -504,3 +559,68 @@ func (u *fakeUpdateManager) validateUpdate(log log.T,
 	out iohandler.IOHandler) (noNeedToUpdate bool, err error) {
 	return u.validateUpdateResult, u.validateUpdateError
 }
+
+// closedUpdateWindow returns a one hour update window opening in two hours
+func closedUpdateWindow() appconfig.UpdateWindowCfg {
+	hour := time.Now().UTC().Hour()
+	return appconfig.UpdateWindowCfg{
+		AllowedHours: fmt.Sprintf("%02d-%02d", (hour+2)%24, (hour+3)%24),
+		TimeZone:     "UTC",
+	}
+}
+
+func TestUpdateWindowStartOpen(t *testing.T) {
+	start, err := updateWindowStart(appconfig.UpdateWindowCfg{})
+
+	assert.NoError(t, err)
+	assert.True(t, start.IsZero())
+}
+
+func TestUpdateWindowStartClosed(t *testing.T) {
+	start, err := updateWindowStart(closedUpdateWindow())
+
+	assert.NoError(t, err)
+	assert.True(t, start.After(time.Now().Add(time.Hour)))
+}
+
+func TestUpdateWindowStartInvalid(t *testing.T) {
+	_, err := updateWindowStart(appconfig.UpdateWindowCfg{AllowedDays: []string{"Someday"}})
+
+	assert.Error(t, err)
+}
+
+func TestUpdateAgent_DeferredOutsideUpdateWindow(t *testing.T) {
+	pluginInput := createStubPluginInput()
+	context := createStubInstanceContext()
+	manager := fakeUpdateManager{
+		generateUpdateCmdResult: "-updater -message id value",
+		downloadManifestResult:  createStubManifest(pluginInput, context, true, true),
+		downloadUpdaterResult:   "updater",
+	}
+	util := fakeUtility{}
+	out := iohandler.DefaultIOHandler{}
+	isContainerized = func(log.T) bool { return false }
+	getAppConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Agent.UpdateWindow = closedUpdateWindow()
+		return config, nil
+	}
+	defer func() {
+		isContainerized = platform.IsContainerized
+		getAppConfig = appconfig.Config
+	}()
+
+	config := contracts.Configuration{MessageId: "aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-57c0a7be"}
+	runUpdateAgent(&Plugin{}, config, logger, &manager, &util, pluginInput, new(task.MockCancelFlag), &out, time.Now())
+
+	// the update is saved for the agent to hand it over to the updater, the worker is not held until the window opens
+	assert.False(t, util.executed)
+	assert.NotNil(t, util.deferredUpdate)
+	assert.Equal(t, "2b196342-d7d4-436e-8f09-3883a1116ac3", util.deferredUpdate.CommandID)
+	assert.Equal(t, config.MessageId, util.deferredUpdate.MessageID)
+	assert.NotNil(t, util.deferredUpdate.PluginResult)
+	assert.Equal(t, "-updater -message id value", util.deferredUpdate.UpdateCommand)
+	assert.True(t, util.deferredUpdate.StartDateTime.After(time.Now()))
+	assert.Equal(t, contracts.ResultStatusInProgress, out.GetStatus())
+	assert.Contains(t, out.GetStdout(), "Update deferred until the update window opens")
+}
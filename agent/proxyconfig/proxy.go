@@ -0,0 +1,190 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package proxyconfig selects the proxies of the http requests of the agent
+package proxyconfig
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// ProxyFunc returns the function selecting the proxy of the requests sent to the given service, as used by
+// http.Transport. The proxy configured for the service in appconfig takes precedence over the proxy selected by the
+// PAC file on Windows, then over the https_proxy and http_proxy environment variables, and requests to the hosts
+// matching the NoProxy list of appconfig or the no_proxy environment variable are sent directly. Use an empty service name for other destinations.
+func ProxyFunc(config appconfig.SsmagentConfig, service string) func(*http.Request) (*url.URL, error) {
+	proxyOverride := appconfig.GetProxyOverride(config, service)
+
+	return func(req *http.Request) (*url.URL, error) {
+		noProxy := append(splitNoProxy(getEnv("no_proxy")), config.Proxy.NoProxy...)
+		if IsProxyBypassed(req.URL.Host, noProxy) {
+			return nil, nil
+		}
+
+		proxy := proxyOverride
+		if proxy == "" {
+			if pacProxy, ok := resolvePacProxy(config, req.URL); ok {
+				if pacProxy == "" {
+					return nil, nil
+				}
+				proxy = pacProxy
+			}
+		}
+		if proxy == "" && req.URL.Scheme == "https" {
+			proxy = getEnv("https_proxy")
+		}
+		if proxy == "" {
+			proxy = getEnv("http_proxy")
+		}
+		if proxy == "" {
+			return nil, nil
+		}
+		return parseProxy(proxy)
+	}
+}
+
+// ServiceProxyFunc is ProxyFunc for the agent configuration, which defaults when it cannot be loaded
+func ServiceProxyFunc(service string) func(*http.Request) (*url.URL, error) {
+	return ProxyFunc(loadConfig(), service)
+}
+
+// loadConfig returns the agent configuration, or the default configuration when it cannot be loaded
+func loadConfig() appconfig.SsmagentConfig {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+	return config
+}
+
+// NewTransport returns an http transport with the settings of http.DefaultTransport, the proxy of the service
+// and the FIPS TLS settings if enabled in appconfig
+func NewTransport(service string) *http.Transport {
+	config := loadConfig()
+	return &http.Transport{
+		Proxy:           ProxyFunc(config, service),
+		TLSClientConfig: TLSConfig(config),
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// IsProxyBypassed returns true if the host, with an optional port, matches one of the no proxy entries.
+// An entry is either "*", an IP address, a CIDR block, or a host name matching itself and its sub domains,
+// optionally followed by the port it applies to.
+func IsProxyBypassed(hostport string, noProxy []string) bool {
+	host, port := splitHostPort(hostport)
+	if host == "" {
+		return false
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := splitHostPort(entry)
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitHostPort splits an optional port from the host, and removes the brackets of IPv6 addresses
+func splitHostPort(hostport string) (host string, port string) {
+	host = hostport
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host, port = h, p
+	}
+	return strings.ToLower(strings.Trim(host, "[]")), port
+}
+
+// splitNoProxy splits the comma separated entries of the no_proxy environment variable
+func splitNoProxy(noProxy string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(noProxy, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// parseProxy parses the proxy url, which defaults to the http scheme. A literal IPv6 address may be given
+// without brackets when no port follows it, e.g. http://fd00::1 which is read as http://[fd00::1].
+func parseProxy(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	return url.Parse(bracketIPv6Host(proxy))
+}
+
+// bracketIPv6Host adds the brackets around the host of the url when it is a literal IPv6 address without port
+func bracketIPv6Host(rawurl string) string {
+	schemeEnd := strings.Index(rawurl, "://") + len("://")
+	authority := rawurl[schemeEnd:]
+	if end := strings.IndexAny(authority, "/?#"); end >= 0 {
+		authority = authority[:end]
+	}
+	host := authority[strings.LastIndex(authority, "@")+1:]
+	if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+		return rawurl
+	}
+	hostStart := schemeEnd + len(authority) - len(host)
+	return rawurl[:hostStart] + "[" + host + "]" + rawurl[hostStart+len(host):]
+}
+
+// getEnv returns the value of the lower case environment variable, or of the upper case one
+var getEnv = func(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToUpper(name))
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proxyconfig
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// WinHTTP constants used to evaluate proxy auto-config (PAC) files
+const (
+	winHttpAccessTypeNoProxy    = 1
+	winHttpAccessTypeNamedProxy = 3
+	winHttpAutoProxyAutoDetect  = 0x00000001
+	winHttpAutoProxyConfigUrl   = 0x00000002
+	winHttpAutoDetectTypeDhcp   = 0x00000001
+	winHttpAutoDetectTypeDnsA   = 0x00000002
+
+	pacCacheDuration = 5 * time.Minute
+	pacUserAgent     = "amazon-ssm-agent"
+)
+
+var (
+	winHttpDll                            = syscall.NewLazyDLL("winhttp.dll")
+	winHttpOpen                           = winHttpDll.NewProc("WinHttpOpen")
+	winHttpGetProxyForUrl                 = winHttpDll.NewProc("WinHttpGetProxyForUrl")
+	winHttpGetIEProxyConfigForCurrentUser = winHttpDll.NewProc("WinHttpGetIEProxyConfigForCurrentUser")
+	kernel32Dll                           = syscall.NewLazyDLL("kernel32.dll")
+	globalFree                            = kernel32Dll.NewProc("GlobalFree")
+)
+
+// WinHttpAutoProxyOptions represents the WINHTTP_AUTOPROXY_OPTIONS structure
+type WinHttpAutoProxyOptions struct {
+	dwFlags                uint32
+	dwAutoDetectFlags      uint32
+	lpszAutoConfigUrl      *uint16
+	lpvReserved            uintptr
+	dwReserved             uint32
+	fAutoLogonIfChallenged int32
+}
+
+// pacResult is a proxy resolved by the PAC file, an empty proxy means the request is sent directly
+type pacResult struct {
+	proxy   string
+	expires time.Time
+}
+
+// pacResolver evaluates the PAC file with WinHTTP, which downloads and caches the script
+type pacResolver struct {
+	mutex   sync.Mutex
+	session uintptr
+	options WinHttpAutoProxyOptions
+	cache   map[string]pacResult
+}
+
+var resolver *pacResolver
+var resolverOnce sync.Once
+
+// resolvePacProxy returns the proxy the PAC file selects for the target url. The PAC file is the PacUrl of
+// appconfig, or the automatic configuration script and auto detection (WPAD) of the system proxy settings.
+// ok is false when there is no PAC file or it cannot be evaluated.
+func resolvePacProxy(config appconfig.SsmagentConfig, target *url.URL) (proxy string, ok bool) {
+	resolverOnce.Do(func() {
+		resolver = newPacResolver(config)
+	})
+	if resolver == nil {
+		return "", false
+	}
+	return resolver.resolve(target)
+}
+
+// newPacResolver returns a resolver of the configured or system PAC file, or nil if there is none
+func newPacResolver(config appconfig.SsmagentConfig) *pacResolver {
+	pacUrl := strings.TrimSpace(config.Proxy.PacUrl)
+	autoDetect := false
+	if pacUrl == "" {
+		if ie, err := ieProxyConfig(); err == nil {
+			pacUrl = ie.config
+			autoDetect = ie.auto
+		}
+	}
+	if pacUrl == "" && !autoDetect {
+		return nil
+	}
+
+	r := &pacResolver{cache: make(map[string]pacResult)}
+	if pacUrl != "" {
+		configUrl, err := syscall.UTF16PtrFromString(pacUrl)
+		if err != nil {
+			return nil
+		}
+		r.options.dwFlags = winHttpAutoProxyConfigUrl
+		r.options.lpszAutoConfigUrl = configUrl
+	} else {
+		r.options.dwFlags = winHttpAutoProxyAutoDetect
+		r.options.dwAutoDetectFlags = winHttpAutoDetectTypeDhcp | winHttpAutoDetectTypeDnsA
+	}
+	r.options.fAutoLogonIfChallenged = 1
+
+	userAgent, _ := syscall.UTF16PtrFromString(pacUserAgent)
+	session, _, _ := winHttpOpen.Call(uintptr(unsafe.Pointer(userAgent)), winHttpAccessTypeNoProxy, 0, 0, 0)
+	if session == 0 {
+		return nil
+	}
+	r.session = session
+	return r
+}
+
+// resolve evaluates the PAC file for the target url, the results are cached per host
+func (r *pacResolver) resolve(target *url.URL) (proxy string, ok bool) {
+	key := target.Scheme + "://" + target.Host
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if result, found := r.cache[key]; found && time.Now().Before(result.expires) {
+		return result.proxy, true
+	}
+
+	targetUrl, err := syscall.UTF16PtrFromString(target.String())
+	if err != nil {
+		return "", false
+	}
+	info := new(WinHttpProxyInfo)
+	ret, _, _ := winHttpGetProxyForUrl.Call(
+		r.session,
+		uintptr(unsafe.Pointer(targetUrl)),
+		uintptr(unsafe.Pointer(&r.options)),
+		uintptr(unsafe.Pointer(info)))
+	if ret != 1 {
+		return "", false
+	}
+	defer freeProxyInfo(info)
+
+	if info.dwAccessType == winHttpAccessTypeNamedProxy {
+		proxy = firstPacProxy(StringFromUTF16Ptr(info.lpszProxy), target.Scheme)
+	}
+	r.cache[key] = pacResult{proxy: proxy, expires: time.Now().Add(pacCacheDuration)}
+	return proxy, true
+}
+
+// firstPacProxy returns the first proxy of the WinHTTP proxy list that applies to the scheme
+func firstPacProxy(proxies string, scheme string) string {
+	for _, f := range strings.Fields(proxies) {
+		for _, s := range strings.Split(f, ";") {
+			if s == "" {
+				continue
+			}
+			if split := strings.SplitN(s, "=", 2); len(split) > 1 {
+				if split[0] != scheme {
+					continue
+				}
+				s = split[1]
+			}
+			return s
+		}
+	}
+	return ""
+}
+
+// freeProxyInfo frees the strings WinHTTP allocated in the proxy info
+func freeProxyInfo(info *WinHttpProxyInfo) {
+	if info.lpszProxy != nil {
+		globalFree.Call(uintptr(unsafe.Pointer(info.lpszProxy)))
+	}
+	if info.lpszProxyBypass != nil {
+		globalFree.Call(uintptr(unsafe.Pointer(info.lpszProxyBypass)))
+	}
+}
+
+// ieProxyConfig returns the automatic proxy settings of the Internet Explorer configuration of the current user
+func ieProxyConfig() (p HttpIEProxyConfig, err error) {
+	settings := new(WinHttpIEProxyConfig)
+	ret, _, err := winHttpGetIEProxyConfigForCurrentUser.Call(uintptr(unsafe.Pointer(settings)))
+	if ret != 1 {
+		return p, err
+	}
+	p = HttpIEProxyConfig{
+		config: StringFromUTF16Ptr(settings.lpszAutoConfigUrl),
+		auto:   settings.fAutoDetect,
+	}
+	if settings.lpszAutoConfigUrl != nil {
+		globalFree.Call(uintptr(unsafe.Pointer(settings.lpszAutoConfigUrl)))
+	}
+	if settings.lpszProxy != nil {
+		globalFree.Call(uintptr(unsafe.Pointer(settings.lpszProxy)))
+	}
+	if settings.lpszProxyBypass != nil {
+		globalFree.Call(uintptr(unsafe.Pointer(settings.lpszProxyBypass)))
+	}
+	return p, nil
+}
@@ -126,11 +126,11 @@ func SetProxySettings(log log.T) {
 		bypass = ie.bypass
 
 		if ie.auto {
-			log.Warnf("IE option 'Automatically  detect settings' is not supported")
+			log.Infof("IE option 'Automatically  detect settings' is enabled, the proxy is resolved by WPAD")
 		}
 
 		if len(ie.config) > 0 {
-			log.Warnf("IE option 'Use automatic configuration script' is not supported")
+			log.Infof("IE option 'Use automatic configuration script' is enabled, the proxy is resolved by the PAC file %v", ie.config)
 		}
 	} else {
 		if df, err = GetDefaultProxySettings(log); len(df.proxy) > 0 && err == nil {
@@ -348,12 +348,8 @@ func ValidateHost(s string) (*url.URL, error) {
 		s = "http:" + s
 	}
 
-	// Forces http when the schema is missing
-	if strings.Index(s, "://") == -1 {
-		s = "http://" + s
-	}
-
-	u, err := url.Parse(s)
+	// Forces http when the schema is missing, and brackets literal IPv6 addresses
+	u, err := parseProxy(s)
 	if err != nil {
 		return nil, errors.New(err.Error() + s + ", skipped")
 	}
@@ -0,0 +1,100 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proxyconfig
+
+import (
+	"crypto/tls"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// tlsVersions maps the TLS versions of appconfig to their protocol versions
+var tlsVersions = map[string]uint16{
+	appconfig.TlsVersion10: tls.VersionTLS10,
+	appconfig.TlsVersion11: tls.VersionTLS11,
+	appconfig.TlsVersion12: tls.VersionTLS12,
+	appconfig.TlsVersion13: tls.VersionTLS13,
+}
+
+// fipsCipherSuites are the FIPS 140-2 approved cipher suites of TLS 1.2
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// fipsCurves are the FIPS 140-2 approved elliptic curves
+var fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// TLSConfig returns the TLS settings of the connections to the AWS services and download locations, with the
+// minimum version and cipher suites of appconfig. Cipher suite names the go runtime doesn't consider secure are
+// ignored. If UseFipsEndpoint is enabled in appconfig, the versions, cipher suites and curves are further restricted
+// to the FIPS approved ones, and TLS 1.3 is disabled as its cipher suites cannot be restricted.
+func TLSConfig(config appconfig.SsmagentConfig) *tls.Config {
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: cipherSuites(config.Tls.CipherSuites),
+	}
+	if version, ok := tlsVersions[config.Tls.MinVersion]; ok {
+		tlsConfig.MinVersion = version
+	}
+
+	if config.Agent.UseFipsEndpoint {
+		tlsConfig.MinVersion = tls.VersionTLS12
+		tlsConfig.MaxVersion = tls.VersionTLS12
+		tlsConfig.CipherSuites = fipsCipherSuitesOf(tlsConfig.CipherSuites)
+		tlsConfig.CurvePreferences = fipsCurves
+	}
+	return tlsConfig
+}
+
+// AgentTLSConfig is TLSConfig for the agent configuration, which defaults when it cannot be loaded
+func AgentTLSConfig() *tls.Config {
+	return TLSConfig(loadConfig())
+}
+
+// cipherSuites returns the ids of the secure cipher suites named, or nil for the defaults of the go runtime
+func cipherSuites(names []string) []uint16 {
+	secure := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+
+	var ids []uint16
+	for _, name := range names {
+		if id, ok := secure[name]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// fipsCipherSuitesOf returns the FIPS approved cipher suites among the given ones, or all of them if none is
+func fipsCipherSuitesOf(ids []uint16) []uint16 {
+	var approved []uint16
+	for _, id := range ids {
+		for _, fipsId := range fipsCipherSuites {
+			if id == fipsId {
+				approved = append(approved, id)
+			}
+		}
+	}
+	if len(approved) == 0 {
+		return fipsCipherSuites
+	}
+	return approved
+}
@@ -14,7 +14,12 @@
 // Package rebooter provides utilities used to reboot a machine.
 package rebooter
 
-import "github.com/aws/amazon-ssm-agent/agent/log"
+import (
+	"os"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+)
 
 type RebootType string
 
@@ -23,14 +28,27 @@ const (
 	RebootRequestTypeUpdate RebootType = "update"
 )
 
+// containerRestartExitCode is the exit code of the agent when a reboot is requested in a container
+const containerRestartExitCode = 194
+
 var ch = make(chan RebootType)
 
+// Makes the checks as variables, so that we can mock them for unit tests
+var isContainerized = platform.IsContainerized
+var exitProcess = os.Exit
+
 func GetChannel() chan RebootType {
 	return ch
 }
 
 //RebootMachine reboots the machine
+//In a container the host cannot be rebooted, the agent exits instead so that the container is restarted
 func RebootMachine(log log.T) {
+	if isContainerized(log) {
+		log.Infof("The agent runs in a container, exiting with code %v so that the container is restarted", containerRestartExitCode)
+		exitProcess(containerRestartExitCode)
+		return
+	}
 
 	if err := reboot(log); err != nil {
 		log.Error("error in rebooting the machine", err)
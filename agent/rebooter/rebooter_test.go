@@ -14,11 +14,13 @@
 package rebooter
 
 import (
+	"os"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -59,3 +61,17 @@ func TestRequestPendingReboot(t *testing.T) {
 	time.Sleep(time.Second)
 	assert.Equal(t, successCount, 1, "Request reboot should only return true once")
 }
+
+func TestRebootMachineInContainer(t *testing.T) {
+	var logger = log.NewMockLog()
+	exitCode := 0
+	isContainerized = func(log.T) bool { return true }
+	exitProcess = func(code int) { exitCode = code }
+	defer func() {
+		isContainerized = platform.IsContainerized
+		exitProcess = os.Exit
+	}()
+
+	RebootMachine(logger)
+	assert.Equal(t, containerRestartExitCode, exitCode)
+}
@@ -21,19 +21,29 @@ import (
 	"os/exec"
 	"syscall"
 
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
 const (
 	timeOutInMinutesBeforeReboot = "+1" // Indicates 1 minute
+	timeOutInSecondsBeforeReboot = "60"
+
+	shutdownCommand = "/sbin/shutdown"
+	// rebootCommand is used when shutdown is not available, e.g. busybox on alpine
+	rebootCommand = "/sbin/reboot"
 )
 
 // reboot is performed by running the following command
 // /sbin/shutdown -r +1
 // The above command will cause the machine to reboot after 1 minute
+// On systems without shutdown, /sbin/reboot -d 60 is run instead
 func reboot(log log.T) (err error) {
 	log.Infof("Rebooting the machine in %v Minutes..", timeOutInMinutesBeforeReboot)
-	command := exec.Command("/sbin/shutdown", "-r", timeOutInMinutesBeforeReboot)
+	command := exec.Command(shutdownCommand, "-r", timeOutInMinutesBeforeReboot)
+	if !fileutil.Exists(shutdownCommand) && fileutil.Exists(rebootCommand) {
+		command = exec.Command(rebootCommand, "-d", timeOutInSecondsBeforeReboot)
+	}
 	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	var stdout, stderr bytes.Buffer
 	command.Stderr = &stderr
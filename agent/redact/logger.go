@@ -0,0 +1,118 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package redact removes sensitive values, such as decrypted SecureString parameters, from text, output streams and logs.
+package redact
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// logger formats the messages and masks the values before delegating them to another logger
+type logger struct {
+	delegate log.T
+	values   Values
+}
+
+// NewLogger returns a logger which masks the values in the messages written to delegate
+func NewLogger(delegate log.T, values Values) log.T {
+	if len(values) == 0 {
+		return delegate
+	}
+	return &logger{delegate: delegate, values: values}
+}
+
+func (l *logger) format(format string, params ...interface{}) string {
+	return l.values.String(fmt.Sprintf(format, params...))
+}
+
+func (l *logger) sprint(v ...interface{}) string {
+	return l.values.String(fmt.Sprint(v...))
+}
+
+// WithContext creates a logger with context which masks the values
+func (l *logger) WithContext(context ...string) log.T {
+	return &logger{delegate: l.delegate.WithContext(context...), values: l.values}
+}
+
+// Tracef writes the masked message to log with level = Trace.
+func (l *logger) Tracef(format string, params ...interface{}) {
+	l.delegate.Tracef("%s", l.format(format, params...))
+}
+
+// Debugf writes the masked message to log with level = Debug.
+func (l *logger) Debugf(format string, params ...interface{}) {
+	l.delegate.Debugf("%s", l.format(format, params...))
+}
+
+// Infof writes the masked message to log with level = Info.
+func (l *logger) Infof(format string, params ...interface{}) {
+	l.delegate.Infof("%s", l.format(format, params...))
+}
+
+// Warnf writes the masked message to log with level = Warn.
+func (l *logger) Warnf(format string, params ...interface{}) error {
+	return l.delegate.Warnf("%s", l.format(format, params...))
+}
+
+// Errorf writes the masked message to log with level = Error.
+func (l *logger) Errorf(format string, params ...interface{}) error {
+	return l.delegate.Errorf("%s", l.format(format, params...))
+}
+
+// Criticalf writes the masked message to log with level = Critical.
+func (l *logger) Criticalf(format string, params ...interface{}) error {
+	return l.delegate.Criticalf("%s", l.format(format, params...))
+}
+
+// Trace writes the masked message to log with level = Trace.
+func (l *logger) Trace(v ...interface{}) {
+	l.delegate.Trace(l.sprint(v...))
+}
+
+// Debug writes the masked message to log with level = Debug.
+func (l *logger) Debug(v ...interface{}) {
+	l.delegate.Debug(l.sprint(v...))
+}
+
+// Info writes the masked message to log with level = Info.
+func (l *logger) Info(v ...interface{}) {
+	l.delegate.Info(l.sprint(v...))
+}
+
+// Warn writes the masked message to log with level = Warn.
+func (l *logger) Warn(v ...interface{}) error {
+	return l.delegate.Warn(l.sprint(v...))
+}
+
+// Error writes the masked message to log with level = Error.
+func (l *logger) Error(v ...interface{}) error {
+	return l.delegate.Error(l.sprint(v...))
+}
+
+// Critical writes the masked message to log with level = Critical.
+func (l *logger) Critical(v ...interface{}) error {
+	return l.delegate.Critical(l.sprint(v...))
+}
+
+// Flush flushes all the messages in the logger.
+func (l *logger) Flush() {
+	l.delegate.Flush()
+}
+
+// Close flushes all the messages in the logger and closes it.
+func (l *logger) Close() {
+	l.delegate.Close()
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package redact removes sensitive values, such as decrypted SecureString parameters, from text, output streams and logs.
+package redact
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Mask replaces the sensitive values
+const Mask = "***"
+
+// Values is a set of sensitive values, the longest first so a value containing another one is entirely masked
+type Values []string
+
+// New returns the set of the non empty values
+func New(values ...string) Values {
+	seen := map[string]bool{}
+	result := Values{}
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return len(result[i]) > len(result[j]) })
+	return result
+}
+
+// String masks the values in the text
+func (v Values) String(text string) string {
+	for _, value := range v {
+		text = strings.Replace(text, value, Mask, -1)
+	}
+	return text
+}
+
+// Bytes masks the values in the content
+func (v Values) Bytes(content []byte) []byte {
+	for _, value := range v {
+		content = bytes.Replace(content, []byte(value), []byte(Mask), -1)
+	}
+	return content
+}
+
+// Directory masks the values in the regular files under the directory, such as the scripts plugins write there
+func (v Values) Directory(dir string) error {
+	if len(v) == 0 {
+		return nil
+	}
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if masked := v.Bytes(content); !bytes.Equal(masked, content) {
+			return ioutil.WriteFile(path, masked, info.Mode())
+		}
+		return nil
+	})
+}
+
+// maxLength returns the length of the longest value
+func (v Values) maxLength() int {
+	if len(v) == 0 {
+		return 0
+	}
+	return len(v[0])
+}
+
+// Writer masks the values of the content written to the underlying writer. As a value may be split between
+// writes, the end of the content which could be the beginning of a value is only written by the next write or Close.
+type Writer struct {
+	w       io.Writer
+	values  Values
+	pending []byte
+}
+
+// NewWriter returns a writer which masks the values in the content written to w
+func NewWriter(w io.Writer, values Values) *Writer {
+	return &Writer{w: w, values: values}
+}
+
+// Write masks the values of the content and writes it, except for the part which may be the beginning of a value
+func (r *Writer) Write(p []byte) (int, error) {
+	r.pending = r.values.Bytes(append(r.pending, p...))
+	keep := r.values.maxLength() - 1
+	if keep < 0 {
+		keep = 0
+	} else if keep > len(r.pending) {
+		keep = len(r.pending)
+	}
+	ready := len(r.pending) - keep
+	if _, err := r.w.Write(r.pending[:ready]); err != nil {
+		return 0, err
+	}
+	r.pending = append(r.pending[:0], r.pending[ready:]...)
+	return len(p), nil
+}
+
+// Close writes the remaining content, it doesn't close the underlying writer
+func (r *Writer) Close() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	_, err := r.w.Write(r.values.Bytes(r.pending))
+	r.pending = nil
+	return err
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package redact removes sensitive values, such as decrypted SecureString parameters, from text, output streams and logs.
+package redact
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_LongestFirst(t *testing.T) {
+	values := New("pass", "", "password", "pass")
+
+	assert.Equal(t, Values{"password", "pass"}, values)
+	assert.Equal(t, "user *** and ***", values.String("user password and pass"))
+}
+
+func TestWriter_ValueSplitBetweenWrites(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, New("s3cr3t"))
+
+	for _, chunk := range []string{"token=s3", "cr", "3t;", " next s3cr3", "t", " end"} {
+		n, err := writer.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.NotContains(t, buf.String(), "end")
+	assert.NoError(t, writer.Close())
+
+	assert.Equal(t, "token=***; next *** end", buf.String())
+}
+
+func TestWriter_NoValues(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, New())
+
+	writer.Write([]byte("content"))
+
+	assert.Equal(t, "content", buf.String())
+	assert.NoError(t, writer.Close())
+}
+
+func TestDirectory(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "redact")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "plugin"), 0700)
+	script := filepath.Join(dir, "plugin", "_script.sh")
+	ioutil.WriteFile(script, []byte("curl -H 'Authorization: s3cr3t'"), 0700)
+	other := filepath.Join(dir, "stdout")
+	ioutil.WriteFile(other, []byte("unrelated"), 0600)
+
+	assert.NoError(t, New("s3cr3t").Directory(dir))
+	assert.NoError(t, New("s3cr3t").Directory(filepath.Join(dir, "missing")))
+
+	content, _ := ioutil.ReadFile(script)
+	assert.Equal(t, "curl -H 'Authorization: ***'", string(content))
+	info, _ := os.Stat(script)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	content, _ = ioutil.ReadFile(other)
+	assert.Equal(t, "unrelated", string(content))
+}
+
+func TestNewLogger(t *testing.T) {
+	delegate := log.NewMockLog()
+	logger := NewLogger(delegate, New("s3cr3t"))
+
+	logger.Infof("config %v", map[string]string{"token": "s3cr3t"})
+	logger.Debug("value ", "s3cr3t")
+
+	delegate.AssertCalled(t, "Infof", "%s", []interface{}{"config map[token:***]"})
+	delegate.AssertCalled(t, "Debug", []interface{}{"value ***"})
+	assert.Equal(t, delegate, NewLogger(delegate, New()))
+}
@@ -35,6 +35,7 @@ type SendCommandPayload struct {
 	DocumentName       string                    `json:"DocumentName"`
 	OutputS3KeyPrefix  string                    `json:"OutputS3KeyPrefix"`
 	OutputS3BucketName string                    `json:"OutputS3BucketName"`
+	OutputS3KmsKeyId   string                    `json:"OutputS3KmsKeyId"`
 }
 
 // SendReplyPayload represents the json structure of a reply sent to MDS.
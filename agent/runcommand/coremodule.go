@@ -15,20 +15,24 @@
 package runcommand
 
 import (
+	"encoding/json"
 	"fmt"
-	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/health/watchdog"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
 	"github.com/carlescere/scheduler"
 )
@@ -47,6 +51,11 @@ var once sync.Once
 var loadDocStateFromSendCommand = parseSendCommandMessage
 var loadDocStateFromCancelCommand = parseCancelCommandMessage
 
+// cancelDeferredUpdate flags the agent update deferred by a command until the update window opens as canceled
+var cancelDeferredUpdate = func(log log.T, commandID string) (bool, error) {
+	return updateutil.CancelDeferredUpdate(log, appconfig.UpdaterArtifactsRoot, commandID)
+}
+
 // Name returns the module name
 func (s *RunCommandService) ModuleName() string {
 	return s.name
@@ -63,6 +72,7 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 		return
 	}
 
+	s.repliesDone = make(chan struct{})
 	go s.listenReply(resultChan)
 
 	if err = s.processor.InitialProcessing(); err != nil {
@@ -74,6 +84,7 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 	if s.messagePollJob, err = scheduler.Every(pollMessageFrequencyMinutes).Minutes().Run(s.messagePollLoop); err != nil {
 		context.Log().Errorf("unable to schedule message poll job. %v", err)
 	}
+	s.pollHeartbeat = watchdog.Register(s.name, pollHeartbeatTimeout, s.restartPolling)
 
 	log.Info("Starting send replies to MDS")
 	if s.sendReplyJob, err = scheduler.Every(sendReplyFrequencyMinutes).Minutes().Run(s.sendReplyLoop); err != nil {
@@ -92,6 +103,8 @@ func (s *RunCommandService) ModuleRequestStop(stopType contracts.StopType) (err
 	s.stop()
 	//second stop the message processor
 	s.processor.Stop(stopType)
+	//then send the final status of the documents which completed or stopped
+	s.waitForFinalReplies()
 
 	//TODO move this out once we have association moved to a different core module
 	if s.assocProcessor != nil {
@@ -100,8 +113,23 @@ func (s *RunCommandService) ModuleRequestStop(stopType contracts.StopType) (err
 	return nil
 }
 
+// waitForFinalReplies waits for the replies of the results left once the processor stopped to be sent
+func (s *RunCommandService) waitForFinalReplies() {
+	if s.repliesDone == nil {
+		return
+	}
+	select {
+	case <-s.repliesDone:
+	case <-time.After(finalRepliesTimeout):
+		s.context.Log().Warnf("the final replies were not sent within %v", finalRepliesTimeout)
+	}
+}
+
 func (s *RunCommandService) listenReply(resultChan chan contracts.DocumentResult) {
 	log := s.context.Log()
+	if s.repliesDone != nil {
+		defer close(s.repliesDone)
+	}
 	//processor guarantees to close this channel upon stop
 	for res := range resultChan {
 		//cloudwatch and refresh association needs to trigger the in-memory component, adding filter here
@@ -111,25 +139,15 @@ func (s *RunCommandService) listenReply(resultChan chan contracts.DocumentResult
 			log.Infof("received plugin: %v result from Processor", res.LastPlugin)
 		} else {
 			log.Infof("command: %v complete", res.MessageID)
-			//Deleting Old Log Files after the execution is over and files have been moved to completed folder
-			//clean completed document state files and orchestration dirs. Takes care of only files generated by RunCommand in the folder
+			s.recordFinalReply(log, res)
+			//Pruning the execution history after the execution is over and files have been moved to completed folder
 			instanceID, _ := platform.InstanceID()
-			go docmanager.DeleteOldOrchestrationFolderLogs(log,
-				instanceID,
-				s.context.AppConfig().Agent.OrchestrationRootDir,
-				s.context.AppConfig().Ssm.RunCommandLogsRetentionDurationHours,
-				isRunCommandLogFile)
+			go docmanager.PruneExecutionHistory(log, instanceID, s.context.AppConfig())
 		}
 		s.sendResponse(res.MessageID, res)
 	}
 }
 
-// isRunCommandLogFile checks whether the file name format satisfies the format for RunCommand generated log files
-func isRunCommandLogFile(fileName string) (matched bool) {
-	matched, _ = regexp.MatchString("^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}$", fileName)
-	return
-}
-
 //temporary solution on plugins with shared responsibility with agent
 func (s *RunCommandService) handleSpecialPlugin(lastPluginID string, pluginRes map[string]*contracts.PluginResult, messageID string) {
 	var newRes contracts.PluginResult
@@ -166,6 +184,11 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 		return
 	}
 
+	if processed, found := s.processedMessages.get(log, *msg.MessageId); found {
+		s.handleRedeliveredMessage(log, *msg.MessageId, processed)
+		return
+	}
+
 	if strings.HasPrefix(*msg.Topic, string(SendCommandTopicPrefix)) {
 		docState, err = loadDocStateFromSendCommand(context, msg, s.orchestrationRootDir)
 		if err != nil {
@@ -192,6 +215,7 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 	}
 
 	log.Debugf("Ack done. Received message - messageId - %v", *msg.MessageId)
+	s.processedMessages.markReceived(log, *msg.MessageId)
 
 	log.Debugf("Processing to send a reply to update the document status to InProgress")
 
@@ -203,6 +227,12 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 	case contracts.SendCommand, contracts.SendCommandOffline:
 		s.processor.Submit(*docState)
 	case contracts.CancelCommand, contracts.CancelCommandOffline:
+		// an agent update waiting for the update window is no longer executing, it is reported canceled by the update scheduler
+		if found, err := cancelDeferredUpdate(log, docState.CancelInformation.CancelCommandID); err != nil {
+			log.Errorf("unable to cancel the deferred update of command %v: %v", docState.CancelInformation.CancelCommandID, err)
+		} else if found {
+			log.Infof("Canceled the update deferred by command %v", docState.CancelInformation.CancelCommandID)
+		}
 		s.processor.Cancel(*docState)
 
 	default:
@@ -211,6 +241,44 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 
 }
 
+// handleRedeliveredMessage acknowledges a message which MDS delivered again because the acknowledgement of its first
+// delivery was lost, and sends the recorded final reply of the first execution instead of executing it again
+func (s *RunCommandService) handleRedeliveredMessage(log log.T, messageID string, processed processedMessage) {
+	log.Warnf("Message %v was already processed at %v, skipping its execution", messageID, processed.ReceivedAt)
+	if err := s.service.AcknowledgeMessage(log, messageID); err != nil {
+		sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
+		return
+	}
+	if processed.CompletedAt == nil {
+		log.Infof("Message %v is still executing, its result is sent once it completes", messageID)
+		return
+	}
+	if processed.Reply == "" {
+		// the final reply was too large to be recorded, only its status is sent again
+		log.Infof("Sending the recorded status %v of message %v", processed.Status, messageID)
+		s.sendDocLevelResponse(messageID, processed.Status, "")
+		return
+	}
+	log.Infof("Sending the recorded reply of message %v", messageID)
+	if err := s.service.SendReply(log, messageID, processed.Reply); err != nil {
+		sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
+	}
+}
+
+// recordFinalReply saves the final reply of a completed message, for it to be sent again if the message is redelivered
+func (s *RunCommandService) recordFinalReply(log log.T, res contracts.DocumentResult) {
+	if s.processedMessages == nil {
+		return
+	}
+	payload := FormatPayload(log, res.LastPlugin, s.config.AgentInfo, res.PluginResults)
+	reply, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("could not marshal the final reply of %v: %v", res.MessageID, err)
+		return
+	}
+	s.processedMessages.markCompleted(log, res.MessageID, payload.DocumentStatus, string(reply))
+}
+
 // sendFailedReplies loads replies from local disk and send it again to the service, if it fails no action is needed
 func (s *RunCommandService) sendFailedReplies() {
 	log := s.context.Log()
@@ -19,11 +19,28 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/runcommand/mock"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// MockContext returns a context mock with the default expectations set.
+func MockContext() *context.Mock {
+	ctx := new(context.Mock)
+	log := log.NewMockLog()
+	config := appconfig.SsmagentConfig{}
+	ctx.On("Log").Return(log)
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
 // TestSendFailedReplies tests the sendFailedReplies function with multiple failed replies
 func TestSendFailedReplies(t *testing.T) {
 	contextMock := MockContext()
@@ -95,3 +112,35 @@ func TestSendFailedRepliesWithSendReplyReturnError(t *testing.T) {
 	mdsMock.AssertNumberOfCalls(t, "SendReplyWithInput", 1)
 	mdsMock.AssertNumberOfCalls(t, "DeleteFailedReply", 0)
 }
+
+// TestModuleRequestStopSendsFinalReplies tests that stopping the service waits for the results left by the processor to be replied
+func TestModuleRequestStopSendsFinalReplies(t *testing.T) {
+	contextMock := MockContext()
+	mdsMock := new(runcommandmock.MockedMDS)
+	mdsMock.On("Stop").Return()
+	resultChan := make(chan contracts.DocumentResult, 1)
+	processorMock := new(processormock.MockedProcessor)
+	processorMock.On("Stop", contracts.StopTypeHardStop).Run(func(args mock.Arguments) {
+		resultChan <- contracts.DocumentResult{MessageID: "messageID", LastPlugin: "plugin1"}
+		close(resultChan)
+	}).Return()
+
+	var replied []string
+	proc := RunCommandService{
+		name:      mdsName,
+		context:   contextMock,
+		service:   mdsMock,
+		processor: processorMock,
+		sendResponse: func(messageID string, res contracts.DocumentResult) {
+			time.Sleep(100 * time.Millisecond)
+			replied = append(replied, res.LastPlugin)
+		},
+		repliesDone: make(chan struct{}),
+	}
+	go proc.listenReply(resultChan)
+
+	proc.ModuleRequestStop(contracts.StopTypeHardStop)
+
+	processorMock.AssertExpectations(t)
+	assert.Equal(t, []string{"plugin1"}, replied)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
 	"github.com/twinj/uuid"
 )
@@ -153,7 +154,7 @@ func (ols *offlineService) AcknowledgeMessage(log log.T, messageID string) error
 }
 
 //offline service bookkeeps the command output to specified disk location
-func (ols *offlineService) SendReply(log log.T, messageID string, payload string) error {
+func (ols *offlineService) SendReply(log log.T, messageID string, payload string, opts ...request.Option) error {
 	commandID, err := messageContracts.GetCommandID(messageID)
 	if err != nil {
 		log.Errorf("failed to parse messageID: %v", err)
@@ -25,10 +25,12 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/atrest"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -56,7 +58,7 @@ const (
 type Service interface {
 	GetMessages(log log.T, instanceID string) (messages *ssmmds.GetMessagesOutput, err error)
 	AcknowledgeMessage(log log.T, messageID string) error
-	SendReply(log log.T, messageID string, payload string) error
+	SendReply(log log.T, messageID string, payload string, opts ...request.Option) error
 	SendReplyWithInput(log log.T, sendReply *ssmmds.SendReplyInput) error
 	FailMessage(log log.T, messageID string, failureType FailureType) error
 	DeleteMessage(log log.T, messageID string) error
@@ -81,6 +83,10 @@ var clientBasedErrorMessages, serverBasedErrorMessages []string
 func NewService(region string, endpoint string, creds *credentials.Credentials, connectionTimeout time.Duration) Service {
 
 	config := sdkutil.AwsConfig()
+	appConfig, err := appconfig.Config(false)
+	if err != nil {
+		appConfig = appconfig.DefaultConfig()
+	}
 
 	if region != "" {
 		config.Region = &region
@@ -90,7 +96,7 @@ func NewService(region string, endpoint string, creds *credentials.Credentials,
 		config.Endpoint = &endpoint
 	} else {
 		if region, err := platform.Region(); err == nil {
-			if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "ec2messages"); defaultEndpoint != "" {
+			if defaultEndpoint := appconfig.GetServiceEndpoint(appConfig, appconfig.ServiceNameEc2Messages, region); defaultEndpoint != "" {
 				config.Endpoint = &defaultEndpoint
 			}
 		}
@@ -102,11 +108,12 @@ func NewService(region string, endpoint string, creds *credentials.Credentials,
 
 	// capture Transport so we can use it to cancel requests
 	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: proxyconfig.ProxyFunc(appConfig, appconfig.ServiceNameEc2Messages),
 		Dial: (&net.Dialer{
 			Timeout:   connectionTimeout,
 			KeepAlive: 0,
 		}).Dial,
+		TLSClientConfig:     proxyconfig.TLSConfig(appConfig),
 		TLSHandshakeTimeout: 10 * time.Second,
 	}
 	config.HTTPClient = &http.Client{Transport: tr, Timeout: connectionTimeout}
@@ -212,8 +219,14 @@ func (mds *sdkService) AcknowledgeMessage(log log.T, messageID string) (err erro
 
 // SendReplyWithInput calls SendReply MDS API given SendReplyInput object
 func (mds *sdkService) SendReplyWithInput(log log.T, sendReply *ssmmds.SendReplyInput) (err error) {
+	return mds.sendReplyWithInput(log, sendReply)
+}
+
+// sendReplyWithInput calls SendReply MDS API with the options of the request, such as its correlation ID
+func (mds *sdkService) sendReplyWithInput(log log.T, sendReply *ssmmds.SendReplyInput, opts ...request.Option) (err error) {
 	log.Debug("Calling SendReply with params", sendReply)
 	req, resp := mds.sdk.SendReplyRequest(sendReply)
+	req.ApplyOptions(opts...)
 	if err = mds.sendRequest(req); err != nil {
 		err = fmt.Errorf("SendReply Error: %v", err)
 		log.Debug(err)
@@ -224,7 +237,7 @@ func (mds *sdkService) SendReplyWithInput(log log.T, sendReply *ssmmds.SendReply
 }
 
 // SendReply transforms payload into SendReplyInput object and calls SendReplyWithInput.
-func (mds *sdkService) SendReply(log log.T, messageID string, payload string) (err error) {
+func (mds *sdkService) SendReply(log log.T, messageID string, payload string, opts ...request.Option) (err error) {
 	uuid.SwitchFormat(uuid.CleanHyphen)
 	replyID := uuid.NewV4().String()
 	replyInput := ssmmds.SendReplyInput{
@@ -232,7 +245,7 @@ func (mds *sdkService) SendReply(log log.T, messageID string, payload string) (e
 		Payload:   aws.String(payload),   // Required
 		ReplyId:   aws.String(replyID),   // Required
 	}
-	if err = mds.SendReplyWithInput(log, &replyInput); err != nil {
+	if err = mds.sendReplyWithInput(log, &replyInput, opts...); err != nil {
 		log.Infof("Saving reply %v to local disk", replyID)
 		mds.PersistFailedReply(log, replyInput)
 	}
@@ -313,7 +326,12 @@ func (mds *sdkService) PersistFailedReply(log log.T, sendReply ssmmds.SendReplyI
 			return
 		}
 		log.Tracef("persisting reply %v in file %v", jsonutil.Indent(content), absoluteFileName)
-		if s, err := fileutil.WriteIntoFileWithPermissions(absoluteFileName, jsonutil.Indent(content), os.FileMode(int(appconfig.ReadWriteAccess))); s && err == nil {
+		sealed, err := atrest.Seal([]byte(jsonutil.Indent(content)))
+		if err != nil {
+			log.Errorf("encountered error with message %v while encrypting reply", err)
+			return err
+		}
+		if s, err := fileutil.WriteIntoFileWithPermissions(absoluteFileName, string(sealed), os.FileMode(int(appconfig.ReadWriteAccess))); s && err == nil {
 			log.Debugf("successfully persisted reply in %v", absoluteFileName)
 		} else {
 			log.Debugf("persisting reply in %v failed with error %v", absoluteFileName, err)
@@ -327,7 +345,7 @@ func (mds *sdkService) GetFailedReply(log log.T, replyId string) (*ssmmds.SendRe
 	absoluteFileName := mds.getFailedReplyLocation(replyId)
 
 	var sendReply ssmmds.SendReplyInput
-	err := jsonutil.UnmarshalFile(absoluteFileName, &sendReply)
+	err := atrest.UnmarshalFile(absoluteFileName, &sendReply)
 	if err != nil {
 		log.Errorf("encountered error with message %v while reading reply input from file - %v", err, absoluteFileName)
 	} else {
@@ -16,6 +16,7 @@ package runcommandmock
 import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
 	"github.com/stretchr/testify/mock"
 )
@@ -40,7 +41,7 @@ func (mdsMock *MockedMDS) AcknowledgeMessage(log log.T, messageID string) error
 }
 
 // SendReply mocks the service function with the same name.
-func (mdsMock *MockedMDS) SendReply(log log.T, messageID string, payload string) error {
+func (mdsMock *MockedMDS) SendReply(log log.T, messageID string, payload string, opts ...request.Option) error {
 	return mdsMock.Called(log, messageID, payload).Error(0)
 }
 
@@ -0,0 +1,182 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/atrest"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+)
+
+const (
+	// processedMessageTTL is how long a processed message is remembered, a message is not redelivered after it expired
+	processedMessageTTL = 7 * 24 * time.Hour
+
+	// maxProcessedMessages bounds the number of remembered messages, the oldest ones are forgotten first
+	maxProcessedMessages = 1000
+
+	// maxRecordedReplyLength bounds the size of a recorded final reply, only the status is recorded for a larger reply
+	maxRecordedReplyLength = 16 * 1024
+)
+
+// processedMessage records a message which was acknowledged and executed. Status is the status of its final reply
+// and Reply the payload of the final reply when it is small enough to be recorded.
+type processedMessage struct {
+	MessageID   string
+	ReceivedAt  time.Time
+	CompletedAt *time.Time             `json:",omitempty"`
+	Status      contracts.ResultStatus `json:",omitempty"`
+	Reply       string                 `json:",omitempty"`
+}
+
+// processedMessageStore persists the messages processed by a service, so that a message which MDS redelivers
+// because its acknowledgement was lost is not executed a second time. A nil store remembers nothing.
+type processedMessageStore struct {
+	path     string
+	lock     sync.Mutex
+	messages map[string]*processedMessage
+}
+
+// newProcessedMessageStore returns a store saved to the given file
+func newProcessedMessageStore(path string) *processedMessageStore {
+	return &processedMessageStore{path: path}
+}
+
+// get returns the record of the given message if it was already processed
+func (s *processedMessageStore) get(log log.T, messageID string) (message processedMessage, found bool) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.load(log)
+	var record *processedMessage
+	if record, found = s.messages[messageID]; found {
+		message = *record
+	}
+	return
+}
+
+// markReceived records that the given message was acknowledged and is about to be executed
+func (s *processedMessageStore) markReceived(log log.T, messageID string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.load(log)
+	s.messages[messageID] = &processedMessage{
+		MessageID:  messageID,
+		ReceivedAt: times.DefaultClock.Now().UTC(),
+	}
+	s.save(log)
+}
+
+// markCompleted records the final status and reply of the given message, which are sent again if the message is redelivered
+func (s *processedMessageStore) markCompleted(log log.T, messageID string, status contracts.ResultStatus, reply string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.load(log)
+	record, found := s.messages[messageID]
+	if !found {
+		return
+	}
+	completedAt := times.DefaultClock.Now().UTC()
+	record.CompletedAt = &completedAt
+	record.Status = status
+	if len(reply) <= maxRecordedReplyLength {
+		record.Reply = reply
+	}
+	s.save(log)
+}
+
+// load reads the saved messages on first use, lock must be held by the caller
+func (s *processedMessageStore) load(log log.T) {
+	if s.messages != nil {
+		return
+	}
+	s.messages = make(map[string]*processedMessage)
+	if !fileutil.Exists(s.path) {
+		return
+	}
+	var messages []*processedMessage
+	if err := atrest.UnmarshalFile(s.path, &messages); err != nil {
+		log.Errorf("failed to read the processed messages from %v: %v", s.path, err)
+		return
+	}
+	for _, message := range messages {
+		s.messages[message.MessageID] = message
+	}
+}
+
+// save drops the expired messages and writes the others, lock must be held by the caller
+func (s *processedMessageStore) save(log log.T) {
+	expiry := times.DefaultClock.Now().UTC().Add(-processedMessageTTL)
+	var messages []*processedMessage
+	for messageID, message := range s.messages {
+		if message.ReceivedAt.Before(expiry) {
+			delete(s.messages, messageID)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ReceivedAt.After(messages[j].ReceivedAt) })
+	if len(messages) > maxProcessedMessages {
+		for _, message := range messages[maxProcessedMessages:] {
+			delete(s.messages, message.MessageID)
+		}
+		messages = messages[:maxProcessedMessages]
+	}
+
+	content, err := json.Marshal(messages)
+	if err != nil {
+		log.Errorf("failed to marshal the processed messages: %v", err)
+		return
+	}
+	// the recorded replies hold the output of the documents, they are encrypted like the document states
+	if content, err = atrest.Seal(content); err != nil {
+		log.Errorf("failed to encrypt the processed messages: %v", err)
+		return
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(s.path)); err != nil {
+		log.Errorf("failed to create directory %v: %v", filepath.Dir(s.path), err)
+		return
+	}
+	// the file is replaced once written, so the messages are not lost if the agent stops while saving them
+	tempPath := s.path + ".tmp"
+	if err = ioutil.WriteFile(tempPath, content, appconfig.ReadWriteAccess); err == nil {
+		err = os.Rename(tempPath, s.path)
+	}
+	if err != nil {
+		log.Errorf("failed to save the processed messages to %v: %v", s.path, err)
+	}
+}
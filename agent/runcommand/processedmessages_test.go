@@ -0,0 +1,211 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestProcessedMessageStore(t *testing.T) (*processedMessageStore, func()) {
+	dir, err := ioutil.TempDir("", "processedmessages")
+	assert.NoError(t, err)
+	return newProcessedMessageStore(filepath.Join(dir, mdsName, "messages.json")), func() { os.RemoveAll(dir) }
+}
+
+func TestProcessedMessageStorePersistsMessages(t *testing.T) {
+	store, cleanup := newTestProcessedMessageStore(t)
+	defer cleanup()
+
+	store.markReceived(loggers, "message1")
+	store.markReceived(loggers, "message2")
+	store.markCompleted(loggers, "message2", contracts.ResultStatusSuccess, `{"DocumentStatus":"Success"}`)
+	// messages which were never received are not recorded
+	store.markCompleted(loggers, "message3", contracts.ResultStatusSuccess, `{"DocumentStatus":"Success"}`)
+
+	reloaded := newProcessedMessageStore(store.path)
+	message, found := reloaded.get(loggers, "message1")
+	assert.True(t, found)
+	assert.Nil(t, message.CompletedAt)
+	assert.Empty(t, message.Reply)
+
+	message, found = reloaded.get(loggers, "message2")
+	assert.True(t, found)
+	assert.NotNil(t, message.CompletedAt)
+	assert.Equal(t, contracts.ResultStatusSuccess, message.Status)
+	assert.Equal(t, `{"DocumentStatus":"Success"}`, message.Reply)
+
+	_, found = reloaded.get(loggers, "message3")
+	assert.False(t, found)
+}
+
+func TestProcessedMessageStoreRecordsStatusOfLargeReply(t *testing.T) {
+	store, cleanup := newTestProcessedMessageStore(t)
+	defer cleanup()
+
+	store.markReceived(loggers, "message1")
+	store.markCompleted(loggers, "message1", contracts.ResultStatusFailed, strings.Repeat("x", maxRecordedReplyLength+1))
+
+	message, found := newProcessedMessageStore(store.path).get(loggers, "message1")
+	assert.True(t, found)
+	assert.NotNil(t, message.CompletedAt)
+	assert.Equal(t, contracts.ResultStatusFailed, message.Status)
+	assert.Empty(t, message.Reply)
+	_, err := os.Stat(store.path + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestProcessedMessageStoreForgetsExpiredMessages(t *testing.T) {
+	store, cleanup := newTestProcessedMessageStore(t)
+	defer cleanup()
+
+	store.markReceived(loggers, "expired")
+	store.messages["expired"].ReceivedAt = time.Now().UTC().Add(-processedMessageTTL - time.Hour)
+	store.markReceived(loggers, "recent")
+
+	reloaded := newProcessedMessageStore(store.path)
+	_, found := reloaded.get(loggers, "expired")
+	assert.False(t, found)
+	_, found = reloaded.get(loggers, "recent")
+	assert.True(t, found)
+}
+
+func TestProcessedMessageStoreForgetsOldestMessages(t *testing.T) {
+	store, cleanup := newTestProcessedMessageStore(t)
+	defer cleanup()
+
+	store.load(loggers)
+	receivedAt := time.Now().UTC().Add(-time.Hour)
+	for i := 0; i < maxProcessedMessages; i++ {
+		messageID := fmt.Sprintf("message%v", i)
+		store.messages[messageID] = &processedMessage{MessageID: messageID, ReceivedAt: receivedAt.Add(time.Duration(i) * time.Second)}
+	}
+	store.markReceived(loggers, "latest")
+
+	assert.Equal(t, maxProcessedMessages, len(store.messages))
+	_, found := store.get(loggers, "message0")
+	assert.False(t, found)
+	_, found = store.get(loggers, "message1")
+	assert.True(t, found)
+	_, found = store.get(loggers, "latest")
+	assert.True(t, found)
+}
+
+func TestNilProcessedMessageStore(t *testing.T) {
+	var store *processedMessageStore
+	store.markReceived(loggers, "message1")
+	store.markCompleted(loggers, "message1", contracts.ResultStatusSuccess, "reply")
+	_, found := store.get(loggers, "message1")
+	assert.False(t, found)
+}
+
+// TestProcessMessageRecordsMessage tests that a submitted message is recorded along with its final reply
+func TestProcessMessageRecordsMessage(t *testing.T) {
+	var fakeDocState = contracts.DocumentState{
+		DocumentType: contracts.SendCommand,
+	}
+	svc, tc := prepareTestProcessMessage(testTopicSend)
+	store, cleanup := newTestProcessedMessageStore(t)
+	defer cleanup()
+	svc.processedMessages = store
+
+	tc.MdsMock.On("AcknowledgeMessage", mock.Anything, testMessageId).Return(nil)
+	tc.ProcessMock.On("Submit", fakeDocState).Return(nil)
+	loadDocStateFromSendCommand = func(context context.T, msg *ssmmds.Message, messagesOrchestrationRootDir string) (*contracts.DocumentState, error) {
+		return &fakeDocState, nil
+	}
+	defer func() { loadDocStateFromSendCommand = parseSendCommandMessage }()
+
+	svc.processMessage(&tc.Message)
+	svc.recordFinalReply(loggers, contracts.DocumentResult{
+		MessageID: testMessageId,
+		PluginResults: map[string]*contracts.PluginResult{
+			"plugin1": {PluginName: "aws:runShellScript", Status: contracts.ResultStatusSuccess},
+		},
+	})
+
+	tc.MdsMock.AssertExpectations(t)
+	tc.ProcessMock.AssertExpectations(t)
+	message, found := store.get(loggers, testMessageId)
+	assert.True(t, found)
+	assert.Contains(t, message.Reply, `"documentStatus":"Success"`)
+}
+
+// TestProcessRedeliveredMessage tests that a redelivered message is acknowledged and replied without being executed again
+func TestProcessRedeliveredMessage(t *testing.T) {
+	svc, tc := prepareTestProcessMessage(testTopicSend)
+	store, cleanup := newTestProcessedMessageStore(t)
+	defer cleanup()
+	svc.processedMessages = store
+	store.markReceived(loggers, testMessageId)
+	store.markCompleted(loggers, testMessageId, contracts.ResultStatusSuccess, `{"DocumentStatus":"Success"}`)
+
+	tc.MdsMock.On("AcknowledgeMessage", mock.Anything, testMessageId).Return(nil)
+	tc.MdsMock.On("SendReply", mock.Anything, testMessageId, `{"DocumentStatus":"Success"}`).Return(nil)
+
+	svc.processMessage(&tc.Message)
+
+	tc.MdsMock.AssertExpectations(t)
+	tc.ProcessMock.AssertNotCalled(t, "Submit", mock.Anything)
+	assert.False(t, *tc.IsDocLevelResponseSent)
+}
+
+// TestProcessRedeliveredMessageWithoutReply tests that only the status is sent again when the final reply was too large to be recorded
+func TestProcessRedeliveredMessageWithoutReply(t *testing.T) {
+	svc, tc := prepareTestProcessMessage(testTopicSend)
+	store, cleanup := newTestProcessedMessageStore(t)
+	defer cleanup()
+	svc.processedMessages = store
+	store.markReceived(loggers, testMessageId)
+	store.markCompleted(loggers, testMessageId, contracts.ResultStatusSuccess, strings.Repeat("x", maxRecordedReplyLength+1))
+
+	tc.MdsMock.On("AcknowledgeMessage", mock.Anything, testMessageId).Return(nil)
+
+	svc.processMessage(&tc.Message)
+
+	tc.MdsMock.AssertExpectations(t)
+	tc.MdsMock.AssertNotCalled(t, "SendReply", mock.Anything, mock.Anything, mock.Anything)
+	tc.ProcessMock.AssertNotCalled(t, "Submit", mock.Anything)
+	assert.True(t, *tc.IsDocLevelResponseSent)
+}
+
+// TestProcessRedeliveredMessageStillExecuting tests that a redelivered message which is still executing is only acknowledged
+func TestProcessRedeliveredMessageStillExecuting(t *testing.T) {
+	svc, tc := prepareTestProcessMessage(testTopicSend)
+	store, cleanup := newTestProcessedMessageStore(t)
+	defer cleanup()
+	svc.processedMessages = store
+	store.markReceived(loggers, testMessageId)
+
+	tc.MdsMock.On("AcknowledgeMessage", mock.Anything, testMessageId).Return(nil)
+
+	svc.processMessage(&tc.Message)
+
+	tc.MdsMock.AssertExpectations(t)
+	tc.MdsMock.AssertNotCalled(t, "SendReply", mock.Anything, mock.Anything, mock.Anything)
+	tc.ProcessMock.AssertNotCalled(t, "Submit", mock.Anything)
+	assert.False(t, *tc.IsDocLevelResponseSent)
+}
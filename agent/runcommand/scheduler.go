@@ -20,8 +20,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/health/checkpoint"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi"
 	"github.com/carlescere/scheduler"
 )
 
@@ -48,6 +50,9 @@ func (s *RunCommandService) sendReplyLoop() {
 	if err := s.checkStopPolicy(log); err != nil {
 		return
 	}
+	if s.name == mdsName && s.endpointFailover.Failback(log) {
+		s.service = newMdsService(s.context.AppConfig(), s.endpointFailover)
+	}
 
 	s.sendFailedReplies()
 
@@ -62,6 +67,7 @@ func (s *RunCommandService) messagePollLoop() {
 	// this is extra insurance to prevent any race condition
 	pollStartTime := time.Now()
 	updateLastPollTime(s.name, pollStartTime)
+	defer s.pollHeartbeat.Beat()
 
 	log := s.context.Log()
 	if err := s.checkStopPolicy(log); err != nil {
@@ -120,7 +126,16 @@ func (s *RunCommandService) reset() {
 	// this is extra insurance to avoid service object getting corrupted - adding resiliency
 	config := s.context.AppConfig()
 	if s.name == mdsName {
-		s.service = newMdsService(config)
+		s.endpointFailover.ReportFailure(log)
+		s.service = newMdsService(config, s.endpointFailover)
+	}
+}
+
+// restartPolling cancels the pending request to MDS and polls again, when the message poller is stuck
+func (s *RunCommandService) restartPolling() {
+	s.service.Stop()
+	if s.messagePollJob != nil {
+		scheduleNextRun(s.messagePollJob)
 	}
 }
 
@@ -128,6 +143,7 @@ func (s *RunCommandService) reset() {
 func (s *RunCommandService) stop() {
 	log := s.context.Log()
 	log.Debugf("Stopping processor:%v", s.name)
+	s.pollHeartbeat.Unregister()
 	s.service.Stop()
 
 	if s.messagePollJob != nil {
@@ -145,10 +161,16 @@ func (s *RunCommandService) pollOnce() {
 		log.Debugf("Polling for messages")
 	}
 	messages, err := s.service.GetMessages(log, s.config.InstanceID)
+	if s.name == mdsName {
+		statusapi.RecordPoll(err)
+	}
 	if err != nil {
 		sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
 		return
 	}
+	if s.name == mdsName {
+		checkpoint.Record(log, checkpoint.MessagesPolled)
+	}
 	if len(messages.Messages) > 0 {
 		log.Debugf("Got %v messages", len(messages.Messages))
 	}
@@ -22,8 +22,6 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
-	"github.com/aws/amazon-ssm-agent/agent/context"
-	"github.com/aws/amazon-ssm-agent/agent/log"
 	mds "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
 	"github.com/aws/amazon-ssm-agent/agent/runcommand/mock"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
@@ -46,17 +44,6 @@ var (
 	stopPolicyTimeout = time.Second * 2
 )
 
-// NewMockDefault returns an instance of Mock with default expectations set.
-func MockContext() *context.Mock {
-	ctx := new(context.Mock)
-	log := log.NewMockLog()
-	config := appconfig.SsmagentConfig{}
-	ctx.On("Log").Return(log)
-	ctx.On("AppConfig").Return(config)
-	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
-	return ctx
-}
-
 func TestLoop_Once(t *testing.T) {
 	// Test loop with valid response
 	contextMock := MockContext()
@@ -65,7 +52,7 @@ func TestLoop_Once(t *testing.T) {
 	// create mocked service and set expectations
 	mdsMock := new(runcommandmock.MockedMDS)
 	mdsMock.On("GetMessages", log, sampleInstanceID).Return(&ssmmds.GetMessagesOutput{}, nil)
-	newMdsService = func(appconfig.SsmagentConfig) mds.Service {
+	newMdsService = func(appconfig.SsmagentConfig, *sdkutil.EndpointFailover) mds.Service {
 		return mdsMock
 	}
 	called := 0
@@ -96,7 +83,7 @@ func TestLoop_Multiple_Serial(t *testing.T) {
 	// create mocked service and set expectations
 	mdsMock := new(runcommandmock.MockedMDS)
 	mdsMock.On("GetMessages", log, sampleInstanceID).Return(&ssmmds.GetMessagesOutput{}, nil)
-	newMdsService = func(appconfig.SsmagentConfig) mds.Service {
+	newMdsService = func(appconfig.SsmagentConfig, *sdkutil.EndpointFailover) mds.Service {
 		return mdsMock
 	}
 	called := 0
@@ -136,7 +123,7 @@ func TestLoop_Multiple_Parallel(t *testing.T) {
 	// create mocked service and set expectations
 	mdsMock := new(runcommandmock.MockedMDS)
 	mdsMock.On("GetMessages", log, sampleInstanceID).Return(&ssmmds.GetMessagesOutput{}, nil)
-	newMdsService = func(appconfig.SsmagentConfig) mds.Service {
+	newMdsService = func(appconfig.SsmagentConfig, *sdkutil.EndpointFailover) mds.Service {
 		return mdsMock
 	}
 	called := 0
@@ -169,7 +156,7 @@ func TestLoop_Once_Error(t *testing.T) {
 	// create mocked service and set expectations
 	mdsMock := new(runcommandmock.MockedMDS)
 	mdsMock.On("GetMessages", log, sampleInstanceID).Return(&ssmmds.GetMessagesOutput{}, errSample)
-	newMdsService = func(appconfig.SsmagentConfig) mds.Service {
+	newMdsService = func(appconfig.SsmagentConfig, *sdkutil.EndpointFailover) mds.Service {
 		return mdsMock
 	}
 	called := 0
@@ -200,7 +187,7 @@ func TestLoop_Multiple_Serial_Error(t *testing.T) {
 	// create mocked service and set expectations
 	mdsMock := new(runcommandmock.MockedMDS)
 	mdsMock.On("GetMessages", log, sampleInstanceID).Return(&ssmmds.GetMessagesOutput{}, errSample)
-	newMdsService = func(appconfig.SsmagentConfig) mds.Service {
+	newMdsService = func(appconfig.SsmagentConfig, *sdkutil.EndpointFailover) mds.Service {
 		return mdsMock
 	}
 	called := 0
@@ -243,7 +230,7 @@ func TestSendReplyLoop_Multiple_Serial_Error(t *testing.T) {
 	mdsMock.On("SendReplyWithInput", mock.AnythingOfType("*log.Mock"), &ssmmds.SendReplyInput{}).Return(errSample)
 	mdsMock.On("LoadFailedReplies", mock.AnythingOfType("*log.Mock")).Return(replies)
 	mdsMock.On("GetFailedReply", mock.AnythingOfType("*log.Mock"), mock.AnythingOfType("string")).Return(&ssmmds.SendReplyInput{}, nil)
-	newMdsService = func(appconfig.SsmagentConfig) mds.Service {
+	newMdsService = func(appconfig.SsmagentConfig, *sdkutil.EndpointFailover) mds.Service {
 		return mdsMock
 	}
 	proc := RunCommandService{
@@ -271,7 +258,7 @@ func TestLoop_Multiple_Parallel_Error(t *testing.T) {
 	// create mocked service and set expectations
 	mdsMock := new(runcommandmock.MockedMDS)
 	mdsMock.On("GetMessages", log, sampleInstanceID).Return(&ssmmds.GetMessagesOutput{}, errSample)
-	newMdsService = func(appconfig.SsmagentConfig) mds.Service {
+	newMdsService = func(appconfig.SsmagentConfig, *sdkutil.EndpointFailover) mds.Service {
 		return mdsMock
 	}
 	called := 0
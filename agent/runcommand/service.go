@@ -23,8 +23,10 @@ import (
 	associationProcessor "github.com/aws/amazon-ssm-agent/agent/association/processor"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/correlation"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
+	"github.com/aws/amazon-ssm-agent/agent/health/watchdog"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
@@ -63,11 +65,17 @@ const (
 	// note: the connection timeout for MDSPoll should be less than this.
 	pollMessageFrequencyMinutes = 15
 
+	// pollHeartbeatTimeout is the time the message poller can go without completing a poll before the watchdog restarts it
+	pollHeartbeatTimeout = (2*pollMessageFrequencyMinutes + 5) * time.Minute
+
 	// sendReplyFrequencyMinutes is the frequency at which to send failed reply requests back to MDS
 	sendReplyFrequencyMinutes = 10
 
 	// the default stoppolicy error threshold. After 10 consecutive errors the plugin will stop for 15 minutes.
 	stopPolicyErrorThreshold = 10
+
+	// finalRepliesTimeout is how long the service waits on stop for the replies of the stopped documents to be sent
+	finalRepliesTimeout = 10 * time.Second
 )
 
 type persistData func(state *contracts.DocumentState, bookkeeping string)
@@ -89,11 +97,17 @@ type RunCommandService struct {
 	orchestrationRootDir string
 	messagePollJob       *scheduler.Job
 	sendReplyJob         *scheduler.Job
+	pollHeartbeat        *watchdog.Heartbeat
 	//TODO move association poller out, we surely have to
 	assocProcessor      *associationProcessor.Processor
 	processorStopPolicy *sdkutil.StopPolicy
+	endpointFailover    *sdkutil.EndpointFailover
 	pollAssociations    bool
 	processor           processor.Processor
+	// repliesDone is closed once all the results of the processor have been replied
+	repliesDone chan struct{}
+	// processedMessages guards against executing a message again when MDS redelivers it
+	processedMessages *processedMessageStore
 }
 
 // NewOfflineProcessor initialize a new offline command document processor
@@ -113,10 +127,18 @@ func NewOfflineService(context context.T) (*RunCommandService, error) {
 // NewMdsProcessor initializes a new mds processor with the given parameters.
 func NewMDSService(context context.T) *RunCommandService {
 	messageContext := context.With("[" + mdsName + "]")
-	mdsService := newMdsService(context.AppConfig())
 	config := context.AppConfig()
+	endpointFailover := sdkutil.NewEndpointFailover(config, appconfig.ServiceNameEc2Messages)
+	if secondary, _ := endpointFailover.Secondary(); endpointFailover.Configured() {
+		messageContext.Log().Infof("%v fails over to the secondary endpoint %v after sustained failures", mdsName, secondary)
+	}
+	mdsService := newMdsService(config, endpointFailover)
 
-	return NewService(messageContext, mdsName, mdsService, config.Mds.CommandWorkersLimit, CancelWorkersLimit, true, []contracts.DocumentType{contracts.SendCommand, contracts.CancelCommand})
+	service := NewService(messageContext, mdsName, mdsService, config.Mds.CommandWorkersLimit, CancelWorkersLimit, true, []contracts.DocumentType{contracts.SendCommand, contracts.CancelCommand})
+	if service != nil {
+		service.endpointFailover = endpointFailover
+	}
+	return service
 }
 
 // NewProcessor performs common initialization for Mds and Offline processors
@@ -145,6 +167,7 @@ func NewService(ctx context.T, serviceName string, service mdsService.Service, c
 
 	// create new message processor
 	orchestrationRootDir := filepath.Join(appconfig.DefaultDataStorePath, instanceID, appconfig.DefaultDocumentRootDirName, config.Agent.OrchestrationRootDir)
+	processedMessagesPath := filepath.Join(appconfig.DefaultDataStorePath, instanceID, appconfig.ProcessedMessagesDirName, serviceName+".json")
 
 	// create a stop policy where we will stop after 10 consecutive errors and if time period expires.
 	stopPolicy := newStopPolicy(serviceName)
@@ -153,12 +176,12 @@ func NewService(ctx context.T, serviceName string, service mdsService.Service, c
 	// Specify a new status of the document
 	sendDocLevelResponse := func(messageID string, resultStatus contracts.ResultStatus, documentTraceOutput string) {
 		payloadDoc := prepareReplyPayloadToUpdateDocumentStatus(agentInfo, resultStatus, documentTraceOutput)
-		processSendReply(log, messageID, service, payloadDoc, stopPolicy)
+		processSendReply(log, messageID, "", service, payloadDoc, stopPolicy)
 	}
 
 	sendResponse := func(messageID string, res contracts.DocumentResult) {
 		pluginID := res.LastPlugin
-		processSendReply(log, messageID, service, FormatPayload(log, pluginID, agentInfo, res.PluginResults), stopPolicy)
+		processSendReply(log, messageID, res.CorrelationID, service, FormatPayload(log, pluginID, agentInfo, res.PluginResults), stopPolicy)
 	}
 
 	var assocProc *associationProcessor.Processor
@@ -179,6 +202,7 @@ func NewService(ctx context.T, serviceName string, service mdsService.Service, c
 		assocProcessor:       assocProc,
 		pollAssociations:     pollAssoc,
 		processor:            processor,
+		processedMessages:    newProcessedMessageStore(processedMessagesPath),
 	}
 }
 
@@ -197,14 +221,15 @@ func prepareReplyPayloadToUpdateDocumentStatus(agentInfo contracts.AgentInfo, do
 	return
 }
 
-func processSendReply(log log.T, messageID string, mdsService mdsService.Service, payloadDoc messageContracts.SendReplyPayload, processorStopPolicy *sdkutil.StopPolicy) {
+// processSendReply sends the reply, tagging the request with the correlation ID of the document when known
+func processSendReply(log log.T, messageID string, correlationID string, mdsService mdsService.Service, payloadDoc messageContracts.SendReplyPayload, processorStopPolicy *sdkutil.StopPolicy) {
 	payloadB, err := json.Marshal(payloadDoc)
 	if err != nil {
 		log.Error("could not marshal reply payload!", err)
 	}
 	payload := string(payloadB)
 	log.Info("Sending reply ", jsonutil.Indent(payload))
-	err = mdsService.SendReply(log, messageID, payload)
+	err = mdsService.SendReply(log, messageID, payload, correlation.RequestOption(correlationID))
 	if err != nil {
 		sdkutil.HandleAwsError(log, err, processorStopPolicy)
 	}
@@ -214,12 +239,17 @@ var newOfflineService = func(log log.T) (mdsService.Service, error) {
 	return mdsService.NewOfflineService(log, string(SendCommandTopicPrefixOffline))
 }
 
-var newMdsService = func(config appconfig.SsmagentConfig) mdsService.Service {
+var newMdsService = func(config appconfig.SsmagentConfig, endpointFailover *sdkutil.EndpointFailover) mdsService.Service {
 	connectionTimeout := time.Duration(config.Mds.StopTimeoutMillis) * time.Millisecond
 
+	region := config.Agent.Region
+	endpoint := appconfig.GetEndpointOverride(config, appconfig.ServiceNameEc2Messages)
+	if secondary, active := endpointFailover.Secondary(); active {
+		region, endpoint = secondary.Region, secondary.Endpoint
+	}
 	return mdsService.NewService(
-		config.Agent.Region,
-		config.Mds.Endpoint,
+		region,
+		endpoint,
 		nil,
 		connectionTimeout,
 	)
@@ -97,10 +97,17 @@ func TestProcessMessageWithCancelCommandTopicPrefix(t *testing.T) {
 	// CancelCommand topic prefix
 	var topic = testTopicCancel
 	var fakeCancelDocState = contracts.DocumentState{
-		DocumentType: contracts.CancelCommand,
+		DocumentType:      contracts.CancelCommand,
+		CancelInformation: contracts.CancelCommandInfo{CancelCommandID: "commandID"},
 	}
 	//prepare processor and test case fields
 	svc, tc := prepareTestProcessMessage(topic)
+	var canceledCommandID string
+	defer func(original func(log.T, string) (bool, error)) { cancelDeferredUpdate = original }(cancelDeferredUpdate)
+	cancelDeferredUpdate = func(log log.T, commandID string) (bool, error) {
+		canceledCommandID = commandID
+		return true, nil
+	}
 
 	// set the expectations
 	tc.MdsMock.On("AcknowledgeMessage", mock.Anything, *tc.Message.MessageId).Return(nil)
@@ -118,6 +125,8 @@ func TestProcessMessageWithCancelCommandTopicPrefix(t *testing.T) {
 	tc.ProcessMock.AssertExpectations(t)
 
 	assert.True(t, *tc.IsDocLevelResponseSent)
+	// an update deferred by the command is canceled too
+	assert.Equal(t, "commandID", canceledCommandID)
 }
 
 // TestProcessMessageWithInvalidCommandTopicPrefix tests processMessage with invalid topic prefix
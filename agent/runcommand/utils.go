@@ -151,6 +151,7 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 		OrchestrationDir: messageOrchestrationDirectory,
 		S3Bucket:         parsedMessage.OutputS3BucketName,
 		S3Prefix:         s3KeyPrefix,
+		S3KmsKeyId:       parsedMessage.OutputS3KmsKeyId,
 		MessageId:        documentInfo.MessageID,
 		DocumentId:       documentInfo.DocumentID,
 	}
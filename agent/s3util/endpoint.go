@@ -0,0 +1,152 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package s3util
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// accelerateCompatibleBucketName matches the bucket names which can be used with S3 Transfer Acceleration,
+// they must be DNS compatible and can't contain periods
+var accelerateCompatibleBucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+
+// Assign to global variables to allow unittest to override
+var getAppConfig = appconfig.Config
+var getAccelerateStatus = bucketAccelerateStatus
+
+var (
+	accelerateLock   sync.Mutex
+	accelerateStatus = make(map[string]bool)
+)
+
+// ConfigureEndpoint sets the S3 endpoint of the aws config used to access the bucket in the given region.
+// An endpoint set in appconfig always wins, with path style addressing if enabled in appconfig as VPC interface
+// endpoints require. Otherwise S3 Transfer Acceleration is used if it is enabled in appconfig and the bucket
+// supports it, and the dual-stack endpoints are used if enabled for S3 or for all the services in appconfig. The FIPS endpoint of the region takes
+// precedence over both when FIPS mode is enabled in appconfig.
+func ConfigureEndpoint(log log.T, config *aws.Config, bucketName string, region string) {
+	appConfig, err := getAppConfig(false)
+	if err != nil {
+		log.Error("failed to read appconfig.")
+		return
+	}
+
+	s3Config := appConfig.S3
+	useDualStack := s3Config.UseDualStackEndpoint || appConfig.Agent.UseDualStackEndpoint
+	if s3Config.ForcePathStyle {
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	if endpoint := appconfig.GetEndpointOverride(appConfig, appconfig.ServiceNameS3); endpoint != "" {
+		if s3Config.UseAccelerateEndpoint || useDualStack {
+			log.Debugf("S3 endpoint %v is configured, ignoring the accelerate and dual-stack settings", endpoint)
+		}
+		config.Endpoint = &endpoint
+		return
+	}
+
+	if appConfig.Agent.UseFipsEndpoint {
+		if endpoint := appconfig.GetFipsEndPoint(region, appconfig.ServiceNameS3); endpoint != "" {
+			log.Debugf("Using the S3 FIPS endpoint %v, ignoring the accelerate and dual-stack settings", endpoint)
+			config.Endpoint = &endpoint
+			return
+		}
+		log.Warnf("S3 has no FIPS endpoint in region %v", region)
+	}
+
+	if s3Config.UseAccelerateEndpoint && s3Config.ForcePathStyle {
+		log.Debugf("S3 Transfer Acceleration doesn't support path style addressing, using the regional endpoint")
+	} else if s3Config.UseAccelerateEndpoint && IsAccelerateEnabled(log, config, bucketName, region) {
+		// the sdk resolves the accelerate endpoint, which doesn't support path style addressing
+		config.Endpoint = nil
+		config.S3UseAccelerate = aws.Bool(true)
+		config.S3ForcePathStyle = aws.Bool(false)
+		config.UseDualStack = aws.Bool(useDualStack)
+		return
+	}
+
+	if useDualStack {
+		endpoint := DualStackEndpoint(region)
+		config.Endpoint = &endpoint
+		return
+	}
+
+	if instanceRegion, err := getRegion(); err == nil {
+		if defaultEndpoint := appconfig.GetDefaultEndPoint(instanceRegion, appconfig.ServiceNameS3); defaultEndpoint != "" {
+			config.Endpoint = &defaultEndpoint
+		}
+	} else {
+		log.Errorf("error fetching the region, %v", err)
+	}
+}
+
+// DualStackEndpoint returns the S3 endpoint of the region reachable over both IPv4 and IPv6
+func DualStackEndpoint(region string) string {
+	return appconfig.GetDualStackEndPoint(region, appconfig.ServiceNameS3)
+}
+
+// IsAccelerateEnabled returns true if S3 Transfer Acceleration can be used to access the bucket.
+// Acceleration isn't available in the China and GovCloud regions, and needs to be enabled on the bucket.
+func IsAccelerateEnabled(log log.T, config *aws.Config, bucketName string, region string) bool {
+	if isChinaRegion(region) || strings.HasPrefix(region, "us-gov-") {
+		log.Debugf("S3 Transfer Acceleration isn't available in %v", region)
+		return false
+	}
+	if !accelerateCompatibleBucketName.MatchString(bucketName) {
+		log.Debugf("bucket name %v is not compatible with S3 Transfer Acceleration", bucketName)
+		return false
+	}
+
+	accelerateLock.Lock()
+	defer accelerateLock.Unlock()
+	if enabled, ok := accelerateStatus[bucketName]; ok {
+		return enabled
+	}
+
+	status, err := getAccelerateStatus(config.Copy(&aws.Config{Region: aws.String(region)}), bucketName)
+	if err != nil {
+		// the status is checked again next time, the permission to read it may be granted later
+		log.Infof("unable to get the S3 Transfer Acceleration status of bucket %v, using the regional endpoint, %v", bucketName, err)
+		return false
+	}
+	enabled := status == s3.BucketAccelerateStatusEnabled
+	if !enabled {
+		log.Infof("S3 Transfer Acceleration isn't enabled on bucket %v, using the regional endpoint", bucketName)
+	}
+	accelerateStatus[bucketName] = enabled
+	return enabled
+}
+
+// bucketAccelerateStatus returns the S3 Transfer Acceleration status of the bucket
+func bucketAccelerateStatus(config *aws.Config, bucketName string) (string, error) {
+	output, err := s3.New(session.New(config)).GetBucketAccelerateConfiguration(&s3.GetBucketAccelerateConfigurationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.Status), nil
+}
+
+// isChinaRegion returns true for the regions of the China partition
+func isChinaRegion(region string) bool {
+	return strings.HasPrefix(region, ChinaRegionPrefix)
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package s3util
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupEndpointConfig stubs the appconfig S3 settings and the acceleration status of the buckets
+func setupEndpointConfig(s3Config appconfig.S3Cfg, status string, statusErr error) {
+	getAppConfig = func(bool) (config appconfig.SsmagentConfig, err error) {
+		config.S3 = s3Config
+		return
+	}
+	getAccelerateStatus = func(config *aws.Config, bucketName string) (string, error) {
+		return status, statusErr
+	}
+	getRegion = func() (string, error) {
+		return "us-west-2", nil
+	}
+	accelerateStatus = make(map[string]bool)
+}
+
+func TestConfigureEndpointCustomEndpointWins(t *testing.T) {
+	setupEndpointConfig(appconfig.S3Cfg{Endpoint: "s3.example.com", UseAccelerateEndpoint: true, UseDualStackEndpoint: true}, s3.BucketAccelerateStatusEnabled, nil)
+	config := &aws.Config{}
+
+	ConfigureEndpoint(logger, config, "bucket", "us-west-2")
+
+	assert.Equal(t, "s3.example.com", aws.StringValue(config.Endpoint))
+	assert.Nil(t, config.S3UseAccelerate)
+}
+
+func TestConfigureEndpointAccelerate(t *testing.T) {
+	setupEndpointConfig(appconfig.S3Cfg{UseAccelerateEndpoint: true, UseDualStackEndpoint: true}, s3.BucketAccelerateStatusEnabled, nil)
+	config := &aws.Config{S3ForcePathStyle: aws.Bool(true)}
+
+	ConfigureEndpoint(logger, config, "bucket", "us-west-2")
+
+	assert.Nil(t, config.Endpoint)
+	assert.True(t, aws.BoolValue(config.S3UseAccelerate))
+	assert.True(t, aws.BoolValue(config.UseDualStack))
+	assert.False(t, aws.BoolValue(config.S3ForcePathStyle))
+}
+
+func TestConfigureEndpointAccelerateNotEnabledOnBucket(t *testing.T) {
+	setupEndpointConfig(appconfig.S3Cfg{UseAccelerateEndpoint: true}, s3.BucketAccelerateStatusSuspended, nil)
+	config := &aws.Config{}
+
+	ConfigureEndpoint(logger, config, "bucket", "us-west-2")
+
+	assert.Nil(t, config.S3UseAccelerate)
+}
+
+func TestConfigureEndpointAccelerateFallsBackToDualStack(t *testing.T) {
+	incompatibleBuckets := []struct {
+		bucket string
+		region string
+		err    error
+	}{
+		{"my.bucket", "us-west-2", nil},
+		{"bucket", "cn-north-1", nil},
+		{"bucket", "us-gov-west-1", nil},
+		{"bucket", "us-west-2", errors.New("access denied")},
+	}
+	for _, test := range incompatibleBuckets {
+		setupEndpointConfig(appconfig.S3Cfg{UseAccelerateEndpoint: true, UseDualStackEndpoint: true}, s3.BucketAccelerateStatusEnabled, test.err)
+		config := &aws.Config{}
+
+		ConfigureEndpoint(logger, config, test.bucket, test.region)
+
+		assert.Nil(t, config.S3UseAccelerate, test.bucket)
+		assert.Equal(t, DualStackEndpoint(test.region), aws.StringValue(config.Endpoint))
+	}
+}
+
+func TestDualStackEndpoint(t *testing.T) {
+	assert.Equal(t, "s3.dualstack.us-east-1.amazonaws.com", DualStackEndpoint("us-east-1"))
+	assert.Equal(t, "s3.dualstack.cn-north-1.amazonaws.com.cn", DualStackEndpoint("cn-north-1"))
+}
+
+func TestConfigureEndpointAgentDualStack(t *testing.T) {
+	setupEndpointConfig(appconfig.S3Cfg{}, s3.BucketAccelerateStatusEnabled, nil)
+	getAppConfig = func(bool) (config appconfig.SsmagentConfig, err error) {
+		config.Agent.UseDualStackEndpoint = true
+		return
+	}
+	config := &aws.Config{}
+
+	ConfigureEndpoint(logger, config, "bucket", "eu-west-1")
+
+	assert.Equal(t, "s3.dualstack.eu-west-1.amazonaws.com", aws.StringValue(config.Endpoint))
+}
+
+func TestIsAccelerateEnabledCachesStatus(t *testing.T) {
+	setupEndpointConfig(appconfig.S3Cfg{}, s3.BucketAccelerateStatusEnabled, nil)
+	calls := 0
+	getAccelerateStatus = func(config *aws.Config, bucketName string) (string, error) {
+		calls++
+		return s3.BucketAccelerateStatusEnabled, nil
+	}
+
+	assert.True(t, IsAccelerateEnabled(logger, &aws.Config{}, "bucket", "us-west-2"))
+	assert.True(t, IsAccelerateEnabled(logger, &aws.Config{}, "bucket", "us-west-2"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestConfigureEndpointInterfaceEndpoint(t *testing.T) {
+	setupEndpointConfig(appconfig.S3Cfg{Endpoint: "s3.example.com", ForcePathStyle: true}, s3.BucketAccelerateStatusEnabled, nil)
+	getAppConfig = func(bool) (config appconfig.SsmagentConfig, err error) {
+		config.S3 = appconfig.S3Cfg{Endpoint: "s3.example.com", ForcePathStyle: true, UseAccelerateEndpoint: true}
+		config.Endpoints.S3 = "bucket.vpce-0123.s3.us-west-2.vpce.amazonaws.com"
+		return
+	}
+	config := &aws.Config{}
+
+	ConfigureEndpoint(logger, config, "bucket", "us-west-2")
+
+	assert.Equal(t, "bucket.vpce-0123.s3.us-west-2.vpce.amazonaws.com", aws.StringValue(config.Endpoint))
+	assert.True(t, aws.BoolValue(config.S3ForcePathStyle))
+	assert.Nil(t, config.S3UseAccelerate)
+}
+
+func TestConfigureEndpointFips(t *testing.T) {
+	setupEndpointConfig(appconfig.S3Cfg{UseAccelerateEndpoint: true, UseDualStackEndpoint: true}, s3.BucketAccelerateStatusEnabled, nil)
+	getAppConfig = func(bool) (config appconfig.SsmagentConfig, err error) {
+		config.S3 = appconfig.S3Cfg{UseAccelerateEndpoint: true, UseDualStackEndpoint: true}
+		config.Agent.UseFipsEndpoint = true
+		return
+	}
+	config := &aws.Config{}
+
+	ConfigureEndpoint(logger, config, "bucket", "us-gov-west-1")
+
+	assert.Equal(t, "s3-fips.us-gov-west-1.amazonaws.com", aws.StringValue(config.Endpoint))
+	assert.Nil(t, config.S3UseAccelerate)
+}
@@ -45,8 +45,16 @@ If the user didn't specify one, it will return the Amazon S3 endpoint in a certa
 */
 func GetS3Endpoint(region string) (s3Endpoint string) {
 	if appConfig, err := appconfig.Config(false); err == nil {
-		if appConfig.S3.Endpoint != "" {
-			return appConfig.S3.Endpoint
+		if endpoint := appconfig.GetEndpointOverride(appConfig, appconfig.ServiceNameS3); endpoint != "" {
+			return endpoint
+		}
+		if appConfig.Agent.UseFipsEndpoint {
+			if endpoint := appconfig.GetFipsEndPoint(region, appconfig.ServiceNameS3); endpoint != "" {
+				return endpoint
+			}
+		}
+		if (appConfig.S3.UseDualStackEndpoint || appConfig.Agent.UseDualStackEndpoint) && region != "" {
+			return DualStackEndpoint(region)
 		}
 	}
 
@@ -55,7 +63,7 @@ func GetS3Endpoint(region string) (s3Endpoint string) {
 	}
 
 	if region, err := platform.Region(); err == nil {
-		if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "s3"); defaultEndpoint != "" {
+		if defaultEndpoint := appconfig.GetDefaultEndPoint(region, appconfig.ServiceNameS3); defaultEndpoint != "" {
 			return defaultEndpoint
 		}
 	}
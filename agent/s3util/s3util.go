@@ -18,14 +18,19 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"os"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
+	"github.com/aws/amazon-ssm-agent/agent/correlation"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -33,6 +38,9 @@ import (
 
 const (
 	s3ResponseRegionHeader = "x-amz-bucket-region"
+
+	// accessDeniedErrorCode is the error code returned by S3 when a request is denied by the bucket policy
+	accessDeniedErrorCode = "AccessDenied"
 )
 
 var getRegion = platform.Region
@@ -45,6 +53,10 @@ type IAmazonS3Util interface {
 
 type AmazonS3Util struct {
 	myUploader *s3manager.Uploader
+	// kmsKeyID is the KMS key used to encrypt the uploaded objects with SSE-KMS, no encryption is requested when empty
+	kmsKeyID string
+	// correlationID is set as the metadata of the uploaded objects and in the user agent of the uploads
+	correlationID string
 }
 
 func NewAmazonS3Util(log log.T, bucketName string) *AmazonS3Util {
@@ -53,30 +65,30 @@ func NewAmazonS3Util(log log.T, bucketName string) *AmazonS3Util {
 	bucketRegion := GetBucketRegion(log, bucketName, httpProvider)
 
 	config := sdkutil.AwsConfig()
-	var appConfig appconfig.SsmagentConfig
-	appConfig, errConfig := appconfig.Config(false)
-	if errConfig != nil {
-		log.Error("failed to read appconfig.")
-	} else {
-		if appConfig.S3.Endpoint != "" {
-			config.Endpoint = &appConfig.S3.Endpoint
-		} else {
-			if region, err := platform.Region(); err == nil {
-				if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "s3"); defaultEndpoint != "" {
-					config.Endpoint = &defaultEndpoint
-				}
-			} else {
-				log.Errorf("error fetching the region, %v", err)
-			}
-		}
-	}
+	config.HTTPClient = &http.Client{Transport: bandwidth.UploadTransport(proxyconfig.NewTransport(appconfig.ServiceNameS3))}
+	ConfigureEndpoint(log, config, bucketName, bucketRegion)
 	config.Region = &bucketRegion
 
 	return &AmazonS3Util{
 		myUploader: s3manager.NewUploader(session.New(config)),
+		kmsKeyID:   configuredKmsKeyID(log),
 	}
 }
 
+// WithKmsKeyID overrides the KMS key configured in appconfig with the key given in the document, if any
+func (u *AmazonS3Util) WithKmsKeyID(kmsKeyID string) *AmazonS3Util {
+	if kmsKeyID != "" {
+		u.kmsKeyID = kmsKeyID
+	}
+	return u
+}
+
+// WithCorrelationID traces the uploads with the correlation ID of the document or step whose output is uploaded
+func (u *AmazonS3Util) WithCorrelationID(correlationID string) *AmazonS3Util {
+	u.correlationID = correlationID
+	return u
+}
+
 // S3Upload uploads a file to s3.
 func (u *AmazonS3Util) S3Upload(log log.T, bucketName string, objectKey string, filePath string) (err error) {
 	file, err := os.Open(filePath)
@@ -93,13 +105,19 @@ func (u *AmazonS3Util) S3Upload(log log.T, bucketName string, objectKey string,
 		Body:        file,
 		ContentType: aws.String("text/plain"),
 	}
-	if result, err := u.myUploader.Upload(params); err == nil {
+	setServerSideEncryption(params, u.kmsKeyID)
+	if u.correlationID != "" {
+		params.Metadata = map[string]*string{correlation.MetadataKey: aws.String(u.correlationID)}
+	}
+	if result, err := u.myUploader.Upload(params, func(uploader *s3manager.Uploader) {
+		uploader.RequestOptions = append(uploader.RequestOptions, correlation.RequestOption(u.correlationID))
+	}); err == nil {
 		log.Infof("Successfully uploaded file to ", result.Location)
-		if _, aclErr := u.myUploader.S3.PutObjectAcl(&s3.PutObjectAclInput{
+		if _, aclErr := u.myUploader.S3.PutObjectAclWithContext(aws.BackgroundContext(), &s3.PutObjectAclInput{
 			Bucket: aws.String(bucketName),
 			Key:    aws.String(objectKey),
 			ACL:    aws.String("bucket-owner-full-control"),
-		}); aclErr == nil {
+		}, correlation.RequestOption(u.correlationID)); aclErr == nil {
 			log.Infof("PutAcl: bucket-owner-full-control succeeded.")
 		} else {
 			// gracefully ignore the error, since the S3 putAcl policy may not be set
@@ -107,10 +125,56 @@ func (u *AmazonS3Util) S3Upload(log log.T, bucketName string, objectKey string,
 		}
 	} else {
 		log.Errorf("Failed uploading %v to s3://%v/%v err:%v", filePath, bucketName, objectKey, err)
+		err = uploadError(err, bucketName, u.kmsKeyID)
 	}
 	return err
 }
 
+// configuredKmsKeyID returns the KMS key configured in appconfig for the S3 uploads
+func configuredKmsKeyID(log log.T) string {
+	appConfig, err := getAppConfig(false)
+	if err != nil {
+		log.Error("failed to read appconfig.")
+		return ""
+	}
+	return appConfig.S3.KmsKeyId
+}
+
+// setServerSideEncryption requests the SSE-KMS encryption of the uploaded object with the given key
+func setServerSideEncryption(params *s3manager.UploadInput, kmsKeyID string) {
+	if kmsKeyID == "" {
+		return
+	}
+	params.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+	params.SSEKMSKeyId = aws.String(kmsKeyID)
+}
+
+// uploadError explains an access denied error when no encryption was requested,
+// as it is what S3 returns when the bucket policy requires the objects to be encrypted
+func uploadError(err error, bucketName string, kmsKeyID string) error {
+	if kmsKeyID != "" || !isAccessDenied(err) {
+		return err
+	}
+	return fmt.Errorf("access denied uploading to bucket %v without server-side encryption, "+
+		"if the bucket policy requires SSE-KMS set S3.KmsKeyId in the agent configuration or OutputS3KmsKeyId in the command: %v",
+		bucketName, err)
+}
+
+// isAccessDenied returns true if the error, or the error it wraps, is an S3 access denied error
+func isAccessDenied(err error) bool {
+	for err != nil {
+		awsErr, ok := err.(awserr.Error)
+		if !ok {
+			return false
+		}
+		if awsErr.Code() == accessDeniedErrorCode {
+			return true
+		}
+		err = awsErr.OrigErr()
+	}
+	return false
+}
+
 // This function returns the Amazon S3 Bucket region based on its name and the EC2 instance region.
 // It will return the same instance region if it failed to guess the bucket region.
 func GetBucketRegion(log log.T, bucketName string, httpProvider HttpProvider) (region string) {
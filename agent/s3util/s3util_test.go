@@ -6,6 +6,10 @@ import (
 
 	"errors"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -113,3 +117,35 @@ func (m *MockedHttpProvider) Head(url string) (*http.Response, error) {
 	args := m.Called(url)
 	return args.Get(0).(*http.Response), args.Error(1)
 }
+
+func TestSetServerSideEncryption(t *testing.T) {
+	params := &s3manager.UploadInput{}
+	setServerSideEncryption(params, "")
+	assert.Nil(t, params.ServerSideEncryption)
+	assert.Nil(t, params.SSEKMSKeyId)
+
+	setServerSideEncryption(params, "key-id")
+	assert.Equal(t, s3.ServerSideEncryptionAwsKms, aws.StringValue(params.ServerSideEncryption))
+	assert.Equal(t, "key-id", aws.StringValue(params.SSEKMSKeyId))
+}
+
+func TestWithKmsKeyID(t *testing.T) {
+	util := &AmazonS3Util{kmsKeyID: "configured"}
+	assert.Equal(t, "configured", util.WithKmsKeyID("").kmsKeyID)
+	assert.Equal(t, "document", util.WithKmsKeyID("document").kmsKeyID)
+}
+
+func TestUploadErrorAccessDenied(t *testing.T) {
+	denied := awserr.New(accessDeniedErrorCode, "Access Denied", nil)
+
+	err := uploadError(denied, "bucket", "")
+	assert.Contains(t, err.Error(), "S3.KmsKeyId")
+
+	assert.Equal(t, denied, uploadError(denied, "bucket", "key-id"))
+
+	multipart := awserr.New("MultipartUpload", "upload multipart failed", denied)
+	assert.Contains(t, uploadError(multipart, "bucket", "").Error(), "server-side encryption")
+
+	other := errors.New("connection reset")
+	assert.Equal(t, other, uploadError(other, "bucket", ""))
+}
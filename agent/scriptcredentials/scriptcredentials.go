@@ -0,0 +1,269 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package scriptcredentials vends short-lived credentials of a scoped role to the scripts of the run command
+// plugins, which the AWS SDKs and CLI of the scripts use instead of the credentials of the instance profile.
+// The scripts run as the agent and can still request the instance metadata service directly, the vended
+// credentials scope the tools of the scripts, they don't isolate the scripts from the instance profile.
+package scriptcredentials
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	envAccessKeyID            = "AWS_ACCESS_KEY_ID"
+	envSecretAccessKey        = "AWS_SECRET_ACCESS_KEY"
+	envSessionToken           = "AWS_SESSION_TOKEN"
+	envContainerRelativeURI   = "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"
+	envContainerFullURI       = "AWS_CONTAINER_CREDENTIALS_FULL_URI"
+	envContainerAuthorization = "AWS_CONTAINER_AUTHORIZATION_TOKEN"
+	envEc2MetadataDisabled    = "AWS_EC2_METADATA_DISABLED"
+	endpointPath              = "/credentials"
+	tokenSize                 = 32
+	maxSessionNameLength      = 64
+	sessionNamePrefix         = "ssm-"
+	messageIDPrefix           = "aws.ssm."
+	stsServiceName            = "sts"
+	endpointReadHeaderTimeout = 10 * time.Second
+)
+
+var (
+	// refreshWindow is how long before their expiration the credentials served by the endpoint are renewed
+	refreshWindow = 5 * time.Minute
+
+	// invalidSessionNameChars are the characters STS does not accept in role session names
+	invalidSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+	newAssumer = newStsAssumer
+)
+
+// assumer is the subset of the STS operations used to vend the credentials
+type assumer interface {
+	AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error)
+}
+
+// Vended are the credentials vended to one script
+type Vended struct {
+	environment map[string]string
+	server      *http.Server
+}
+
+// Vend assumes the role configured for the scripts for the command of the given message, and returns the
+// credentials passed to its script according to the configured mode. Close must be called once the script exits.
+func Vend(log log.T, config appconfig.ScriptCredentialsCfg, messageID string) (*Vended, error) {
+	if config.RoleArn == "" {
+		return nil, fmt.Errorf("script credentials mode %v requires a role arn", config.Mode)
+	}
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(config.RoleArn),
+		RoleSessionName: aws.String(sessionName(messageID)),
+		DurationSeconds: aws.Int64(int64(config.DurationSeconds)),
+	}
+	if config.SessionPolicy != "" {
+		input.Policy = aws.String(config.SessionPolicy)
+	}
+	source := &source{client: newAssumer(), input: input}
+	creds, err := source.get()
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Mode {
+	case appconfig.ScriptCredentialsModeEnvironment:
+		log.Infof("Passing credentials of role %v valid until %v to the script", config.RoleArn, aws.TimeValue(creds.Expiration))
+		return &Vended{environment: map[string]string{
+			envAccessKeyID:            aws.StringValue(creds.AccessKeyId),
+			envSecretAccessKey:        aws.StringValue(creds.SecretAccessKey),
+			envSessionToken:           aws.StringValue(creds.SessionToken),
+			envContainerRelativeURI:   "",
+			envContainerFullURI:       "",
+			envContainerAuthorization: "",
+			envEc2MetadataDisabled:    "true",
+		}}, nil
+	case appconfig.ScriptCredentialsModeEndpoint:
+		return serve(log, config.RoleArn, source)
+	}
+	return nil, fmt.Errorf("unsupported script credentials mode %v", config.Mode)
+}
+
+// Environment returns the given environment of the script with the variables of the vended credentials, which
+// override the variables of the same name
+func (v *Vended) Environment(environment map[string]string) map[string]string {
+	merged := make(map[string]string, len(environment)+len(v.environment))
+	for name, value := range environment {
+		merged[name] = value
+	}
+	for name, value := range v.environment {
+		merged[name] = value
+	}
+	return merged
+}
+
+// Close stops serving the credentials
+func (v *Vended) Close() {
+	if v.server != nil {
+		v.server.Close()
+	}
+}
+
+// serve starts a loopback endpoint serving the credentials in the format of the container credentials provider of
+// the AWS SDKs, to the requests bearing a random token passed to the script with the endpoint url
+func serve(log log.T, roleArn string, source *source) (*Vended, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the script credentials requests: %v", err)
+	}
+	server := &http.Server{
+		Handler:           &endpoint{log: log, token: token, source: source},
+		ReadHeaderTimeout: endpointReadHeaderTimeout,
+	}
+	go server.Serve(listener)
+
+	url := "http://" + listener.Addr().String() + endpointPath
+	log.Infof("Serving credentials of role %v to the script on %v", roleArn, url)
+	return &Vended{
+		environment: map[string]string{
+			envAccessKeyID:            "",
+			envSecretAccessKey:        "",
+			envSessionToken:           "",
+			envContainerRelativeURI:   "",
+			envContainerFullURI:       url,
+			envContainerAuthorization: token,
+			envEc2MetadataDisabled:    "true",
+		},
+		server: server,
+	}, nil
+}
+
+// endpoint serves the credentials of the role to the script
+type endpoint struct {
+	log    log.T
+	token  string
+	source *source
+}
+
+// credentialsResponse is the body expected by the container credentials provider of the AWS SDKs
+type credentialsResponse struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	Token           string
+	Expiration      string
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != endpointPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(e.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	creds, err := e.source.get()
+	if err != nil {
+		e.log.Errorf("failed to renew the script credentials: %v", err)
+		http.Error(w, "failed to get credentials", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credentialsResponse{
+		AccessKeyId:     aws.StringValue(creds.AccessKeyId),
+		SecretAccessKey: aws.StringValue(creds.SecretAccessKey),
+		Token:           aws.StringValue(creds.SessionToken),
+		Expiration:      aws.TimeValue(creds.Expiration).UTC().Format(time.RFC3339),
+	})
+}
+
+// source assumes the role, again whenever its credentials are about to expire
+type source struct {
+	client  assumer
+	input   *sts.AssumeRoleInput
+	lock    sync.Mutex
+	current *sts.Credentials
+}
+
+func (s *source) get() (*sts.Credentials, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.current != nil && time.Now().Add(refreshWindow).Before(aws.TimeValue(s.current.Expiration)) {
+		return s.current, nil
+	}
+	output, err := s.client.AssumeRole(s.input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume role %v for the script: %v", aws.StringValue(s.input.RoleArn), err)
+	}
+	if output.Credentials == nil {
+		return nil, fmt.Errorf("no credentials returned for role %v", aws.StringValue(s.input.RoleArn))
+	}
+	s.current = output.Credentials
+	return s.current, nil
+}
+
+// sessionName returns the role session name identifying the command in CloudTrail,
+// e.g. ssm-d1ab5ba4-5431-4ba8-8d5a-1a0f6d5e7a6c.i-0123456789abcdef0 for message
+// aws.ssm.d1ab5ba4-5431-4ba8-8d5a-1a0f6d5e7a6c.i-0123456789abcdef0
+func sessionName(messageID string) string {
+	name := strings.TrimPrefix(messageID, messageIDPrefix)
+	name = sessionNamePrefix + invalidSessionNameChars.ReplaceAllString(name, "-")
+	if len(name) > maxSessionNameLength {
+		name = name[:maxSessionNameLength]
+	}
+	return name
+}
+
+// newToken returns a random token authorizing the requests of the script
+func newToken() (string, error) {
+	token := make([]byte, tokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate the script credentials token: %v", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// newStsAssumer returns an STS client using the credentials of the agent
+func newStsAssumer() assumer {
+	config := sdkutil.AwsConfig()
+	if appConfig, err := appconfig.Config(false); err == nil && config.Region != nil {
+		if endpoint := appconfig.GetServiceEndpoint(appConfig, stsServiceName, *config.Region); endpoint != "" {
+			config.Endpoint = aws.String(endpoint)
+		}
+	}
+	return sts.New(session.New(config))
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scriptcredentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+const testMessageID = "aws.ssm.d1ab5ba4-5431-4ba8-8d5a-1a0f6d5e7a6c.i-0123456789abcdef0"
+
+type mockAssumer struct {
+	inputs     []*sts.AssumeRoleInput
+	expiration time.Time
+	err        error
+}
+
+func (a *mockAssumer) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	a.inputs = append(a.inputs, input)
+	if a.err != nil {
+		return nil, a.err
+	}
+	return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String(fmt.Sprintf("ASIA%v", len(a.inputs))),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(a.expiration),
+	}}, nil
+}
+
+func mockStsAssumer(client *mockAssumer) func() {
+	original := newAssumer
+	newAssumer = func() assumer { return client }
+	return func() { newAssumer = original }
+}
+
+func TestVendEnvironment(t *testing.T) {
+	client := &mockAssumer{expiration: time.Now().Add(time.Hour)}
+	defer mockStsAssumer(client)()
+
+	config := appconfig.ScriptCredentialsCfg{
+		Mode:            appconfig.ScriptCredentialsModeEnvironment,
+		RoleArn:         "arn:aws:iam::123456789012:role/scripts",
+		SessionPolicy:   `{"Version":"2012-10-17","Statement":[]}`,
+		DurationSeconds: 900,
+	}
+	vended, err := Vend(logger, config, testMessageID)
+	assert.NoError(t, err)
+	defer vended.Close()
+
+	assert.Len(t, client.inputs, 1)
+	assert.Equal(t, config.RoleArn, aws.StringValue(client.inputs[0].RoleArn))
+	assert.Equal(t, config.SessionPolicy, aws.StringValue(client.inputs[0].Policy))
+	assert.Equal(t, int64(900), aws.Int64Value(client.inputs[0].DurationSeconds))
+
+	environment := vended.Environment(map[string]string{"FOO": "bar", envAccessKeyID: "inherited"})
+	assert.Equal(t, "bar", environment["FOO"])
+	assert.Equal(t, "ASIA1", environment[envAccessKeyID])
+	assert.Equal(t, "secret", environment[envSecretAccessKey])
+	assert.Equal(t, "token", environment[envSessionToken])
+	assert.Equal(t, "true", environment[envEc2MetadataDisabled])
+	assert.Equal(t, "", environment[envContainerFullURI])
+}
+
+func TestVendEndpoint(t *testing.T) {
+	client := &mockAssumer{expiration: time.Now().Add(time.Hour)}
+	defer mockStsAssumer(client)()
+
+	config := appconfig.ScriptCredentialsCfg{
+		Mode:            appconfig.ScriptCredentialsModeEndpoint,
+		RoleArn:         "arn:aws:iam::123456789012:role/scripts",
+		DurationSeconds: 900,
+	}
+	vended, err := Vend(logger, config, testMessageID)
+	assert.NoError(t, err)
+	defer vended.Close()
+	assert.Nil(t, client.inputs[0].Policy)
+
+	environment := vended.Environment(nil)
+	assert.Equal(t, "", environment[envAccessKeyID])
+	assert.Equal(t, "true", environment[envEc2MetadataDisabled])
+	url := environment[envContainerFullURI]
+	token := environment[envContainerAuthorization]
+	assert.Contains(t, url, "http://127.0.0.1:")
+	assert.Len(t, token, 2*tokenSize)
+
+	response, err := http.Get(url)
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+	request.Header.Set("Authorization", token)
+	response, err = http.DefaultClient.Do(request)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var body credentialsResponse
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	assert.Equal(t, "ASIA1", body.AccessKeyId)
+	assert.Equal(t, "secret", body.SecretAccessKey)
+	assert.Equal(t, "token", body.Token)
+	assert.Equal(t, client.expiration.UTC().Format(time.RFC3339), body.Expiration)
+	assert.Len(t, client.inputs, 1, "unexpired credentials are reused")
+
+	vended.Close()
+	_, err = http.DefaultClient.Do(request)
+	assert.Error(t, err)
+}
+
+func TestVendRequiresRole(t *testing.T) {
+	client := &mockAssumer{}
+	defer mockStsAssumer(client)()
+
+	_, err := Vend(logger, appconfig.ScriptCredentialsCfg{Mode: appconfig.ScriptCredentialsModeEnvironment}, testMessageID)
+	assert.Error(t, err)
+	assert.Empty(t, client.inputs)
+}
+
+func TestVendAssumeRoleError(t *testing.T) {
+	client := &mockAssumer{err: fmt.Errorf("AccessDenied")}
+	defer mockStsAssumer(client)()
+
+	config := appconfig.ScriptCredentialsCfg{
+		Mode:    appconfig.ScriptCredentialsModeEndpoint,
+		RoleArn: "arn:aws:iam::123456789012:role/scripts",
+	}
+	_, err := Vend(logger, config, testMessageID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "AccessDenied")
+}
+
+func TestSourceRenewsExpiringCredentials(t *testing.T) {
+	client := &mockAssumer{expiration: time.Now().Add(refreshWindow / 2)}
+	source := &source{client: client, input: &sts.AssumeRoleInput{RoleArn: aws.String("role")}}
+
+	first, err := source.get()
+	assert.NoError(t, err)
+	second, err := source.get()
+	assert.NoError(t, err)
+	assert.Len(t, client.inputs, 2)
+	assert.NotEqual(t, aws.StringValue(first.AccessKeyId), aws.StringValue(second.AccessKeyId))
+}
+
+func TestSessionName(t *testing.T) {
+	assert.Equal(t, "ssm-d1ab5ba4-5431-4ba8-8d5a-1a0f6d5e7a6c.i-0123456789abcdef0", sessionName(testMessageID))
+	assert.Equal(t, "ssm-command-id-", sessionName("command id?"))
+	assert.Len(t, sessionName(testMessageID+testMessageID), maxSessionNameLength)
+}
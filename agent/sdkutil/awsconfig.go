@@ -15,12 +15,12 @@
 package sdkutil
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
-	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
-	"github.com/aws/amazon-ssm-agent/agent/managedInstances/rolecreds"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -34,37 +34,26 @@ func AwsConfig() (awsConfig *aws.Config) {
 	awsConfig = &aws.Config{
 		Retryer:    newRetryer(),
 		SleepDelay: sleepDelay,
+		HTTPClient: &http.Client{Transport: proxyconfig.NewTransport("")},
 	}
 
 	// update region from platform
-	region, err := platform.Region()
-	if region != "" {
+	if region, _ := platform.Region(); region != "" {
 		awsConfig.Region = &region
 	}
 
-	// load managed credentials if applicable
-	if isManaged, err := registration.HasManagedInstancesCredentials(); isManaged && err == nil {
-		awsConfig.Credentials =
-			rolecreds.ManagedInstanceCredentialsInstance()
-		return
-	}
-
-	// look for profile credentials
-	appConfig, err := appconfig.Config(false)
-	if err == nil {
-		creds, _ := appConfig.ProfileCredentials()
-		if creds != nil {
-			awsConfig.Credentials = creds
-		}
-	}
+	// all the clients share the refreshing credentials of the agent
+	awsConfig.Credentials = Credentials()
 
 	return
 }
 
 var newRetryer = func() aws.RequestRetryer {
-	r := retryer.SsmRetryer{}
-	r.NumMaxRetries = 3
-	return r
+	appConfig, err := appconfig.Config(false)
+	if err != nil {
+		return retryer.New(appconfig.RetryModeStandard, appconfig.DefaultMaxAttempts)
+	}
+	return retryer.New(appConfig.Agent.RetryMode, appConfig.Agent.MaxAttempts)
 }
 
 var sleepDelay = func(d time.Duration) {
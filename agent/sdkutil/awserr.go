@@ -18,6 +18,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/health/errorsummary"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 )
@@ -45,7 +46,12 @@ func HandleAwsError(log log.T, err error, stopPolicy *StopPolicy) {
 			}
 		}
 
-		log.Errorf("error when calling AWS APIs. error details - %v", err)
+		// identical errors are only logged once per health summary window, repetitions are counted
+		if errorsummary.Report(runtime.FuncForPC(pc).Name(), errorIdentity(err)) {
+			log.Errorf("error when calling AWS APIs. error details - %v", err)
+		} else {
+			log.Debugf("repeated error when calling AWS APIs. error details - %v", err)
+		}
 		if stopPolicy != nil {
 			log.Infof("increasing error count by 1")
 			stopPolicy.AddErrorCount(1)
@@ -67,6 +73,15 @@ func GetAwsErrorCode(err error) string {
 	return errorCode
 }
 
+// errorIdentity returns the part of the error that is identical across repeated occurrences,
+// AWS errors carry a different request id every time so only their code and message are used.
+func errorIdentity(err error) string {
+	if aErr, ok := err.(awserr.Error); ok {
+		return aErr.Code() + ": " + aErr.Message()
+	}
+	return err.Error()
+}
+
 // resetStopPolicy will reset the stoppolicy error count
 func resetStopPolicy(stopPolicy *StopPolicy) {
 	if stopPolicy != nil {
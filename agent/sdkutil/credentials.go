@@ -0,0 +1,135 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdkutil provides utilities used to call awssdk.
+package sdkutil
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/rolecreds"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	// ec2RoleExpiryWindow refreshes the instance profile credentials before they expire, so that long calls such as
+	// the downloads of package installs don't start with credentials about to expire
+	ec2RoleExpiryWindow = 5 * time.Minute
+	// assumeRoleDuration is the lifetime of the credentials of the role assumed by the agent
+	assumeRoleDuration = time.Hour
+	stsServiceName     = "sts"
+)
+
+var (
+	sharedCredentials *credentials.Credentials
+	credentialsLock   sync.Mutex
+)
+
+// Credentials returns the refreshing credentials shared by all the AWS clients of the agent, so that they are
+// refreshed once for the whole agent. They are the managed instance credentials of on-premises instances, the
+// credentials of the role or of the profile configured in appconfig, or the environment, shared credentials file
+// and instance profile credentials otherwise.
+func Credentials() *credentials.Credentials {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+
+	if sharedCredentials == nil {
+		sharedCredentials = newCredentials()
+	}
+	return sharedCredentials
+}
+
+// newCredentials selects the credentials source of the agent
+var newCredentials = func() *credentials.Credentials {
+	// load managed credentials if applicable
+	if isManaged, err := registration.HasManagedInstancesCredentials(); isManaged && err == nil {
+		return rolecreds.ManagedInstanceCredentialsInstance()
+	}
+
+	appConfig, err := appconfig.Config(false)
+	if err != nil {
+		return defaultCredentials()
+	}
+
+	// look for profile credentials
+	creds, _ := appConfig.ProfileCredentials()
+
+	// assume the role configured in appconfig with the profile credentials, or the default ones
+	if appConfig.Profile.RoleArn != "" {
+		if creds == nil {
+			creds = defaultCredentials()
+		}
+		return credentials.NewCredentials(newAssumeRoleProvider(appConfig, creds))
+	}
+
+	if creds != nil {
+		return creds
+	}
+	return defaultCredentials()
+}
+
+// newAssumeRoleProvider returns the provider of the credentials of the role configured in appconfig, the role is
+// assumed with the source credentials
+func newAssumeRoleProvider(config appconfig.SsmagentConfig, source *credentials.Credentials) *stscreds.AssumeRoleProvider {
+	stsConfig := &aws.Config{
+		Credentials: source,
+		HTTPClient:  &http.Client{Transport: proxyconfig.NewTransport("")},
+	}
+	region := config.Agent.Region
+	if region == "" {
+		region, _ = platform.Region()
+	}
+	if region != "" {
+		stsConfig.Region = &region
+		if endpoint := appconfig.GetServiceEndpoint(config, stsServiceName, region); endpoint != "" {
+			stsConfig.Endpoint = &endpoint
+		}
+	}
+
+	provider := &stscreds.AssumeRoleProvider{
+		Client:          sts.New(session.New(stsConfig)),
+		RoleARN:         config.Profile.RoleArn,
+		RoleSessionName: config.Profile.RoleSessionName,
+		Duration:        assumeRoleDuration,
+		ExpiryWindow:    ec2RoleExpiryWindow,
+	}
+	if config.Profile.ExternalId != "" {
+		provider.ExternalID = aws.String(config.Profile.ExternalId)
+	}
+	return provider
+}
+
+// defaultCredentials returns the chain of the environment, shared credentials file and instance profile credentials
+func defaultCredentials() *credentials.Credentials {
+	metadataConfig := platform.Ec2MetadataConfig().WithMaxRetries(10).WithEC2MetadataDisableTimeoutOverride(false)
+	return credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvProvider{},
+		&credentials.SharedCredentialsProvider{},
+		&ec2rolecreds.EC2RoleProvider{
+			Client:       ec2metadata.New(session.New(metadataConfig)),
+			ExpiryWindow: ec2RoleExpiryWindow,
+		},
+	})
+}
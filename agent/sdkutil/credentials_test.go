@@ -0,0 +1,71 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sdkutil
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsAreShared(t *testing.T) {
+	sharedCredentials = nil
+	calls := 0
+	newCredentials = func() *credentials.Credentials {
+		calls++
+		return credentials.NewStaticCredentials("id", "secret", "")
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*credentials.Credentials, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = Credentials()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, calls)
+	for _, creds := range results {
+		assert.True(t, creds == results[0])
+	}
+}
+
+func TestNewAssumeRoleProvider(t *testing.T) {
+	config := appconfig.DefaultConfig()
+	config.Agent.Region = "us-west-2"
+	config.Profile.RoleArn = "arn:aws:iam::123456789012:role/EdgeDevice"
+	config.Profile.ExternalId = "external"
+	source := credentials.NewStaticCredentials("id", "secret", "")
+
+	provider := newAssumeRoleProvider(config, source)
+
+	assert.Equal(t, "arn:aws:iam::123456789012:role/EdgeDevice", provider.RoleARN)
+	assert.Equal(t, appconfig.DefaultRoleSessionName, provider.RoleSessionName)
+	assert.Equal(t, "external", *provider.ExternalID)
+	assert.Equal(t, assumeRoleDuration, provider.Duration)
+	client := provider.Client.(*sts.STS)
+	assert.Equal(t, "us-west-2", *client.Config.Region)
+	assert.True(t, client.Config.Credentials == source)
+
+	config.Profile.ExternalId = ""
+	provider = newAssumeRoleProvider(config, source)
+	assert.Nil(t, provider.ExternalID)
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdkutil provides utilities used to call awssdk.
+package sdkutil
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+var timeNow = time.Now
+
+// Endpoint is the region and endpoint a service client is created with
+type Endpoint struct {
+	Region   string
+	Endpoint string
+}
+
+// String returns the string representation of the endpoint
+func (e Endpoint) String() string {
+	return fmt.Sprintf("%v (%v)", e.Endpoint, e.Region)
+}
+
+// EndpointFailover tracks whether the clients of a service use its secondary endpoint configured in appconfig.
+// The service fails over to the secondary endpoint after sustained failures of the primary one, and fails back
+// once FailbackMinutes elapsed.
+type EndpointFailover struct {
+	service      string
+	secondary    Endpoint
+	failback     time.Duration
+	onSecondary  bool
+	failedOverAt time.Time
+	m            sync.Mutex
+}
+
+// NewEndpointFailover creates the failover of the service from appconfig
+func NewEndpointFailover(config appconfig.SsmagentConfig, service string) *EndpointFailover {
+	region, endpoint := appconfig.GetFailoverEndpoint(config, service)
+	return &EndpointFailover{
+		service:   service,
+		secondary: Endpoint{Region: region, Endpoint: endpoint},
+		failback:  time.Duration(config.Endpoints.Failover.FailbackMinutes) * time.Minute,
+	}
+}
+
+// Configured returns true when a secondary endpoint is configured for the service
+func (f *EndpointFailover) Configured() bool {
+	return f != nil && f.secondary.Endpoint != ""
+}
+
+// Secondary returns the secondary endpoint, and true while the clients of the service should use it
+func (f *EndpointFailover) Secondary() (Endpoint, bool) {
+	if !f.Configured() {
+		return Endpoint{}, false
+	}
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.secondary, f.onSecondary
+}
+
+// ReportFailure switches the service to the secondary endpoint after sustained failures of the primary one,
+// or back to the primary endpoint when the secondary one keeps failing too
+func (f *EndpointFailover) ReportFailure(log log.T) {
+	if !f.Configured() {
+		return
+	}
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.onSecondary {
+		log.Warnf("%v secondary endpoint %v keeps failing, switching back to the primary endpoint", f.service, f.secondary)
+		f.onSecondary = false
+		return
+	}
+	log.Warnf("%v primary endpoint keeps failing, failing over to the secondary endpoint %v for at least %v", f.service, f.secondary, f.failback)
+	f.onSecondary = true
+	f.failedOverAt = timeNow()
+}
+
+// Failback switches the service back to the primary endpoint once the fail-back delay elapsed,
+// it returns true when the clients of the service should be created again
+func (f *EndpointFailover) Failback(log log.T) bool {
+	if !f.Configured() {
+		return false
+	}
+	f.m.Lock()
+	defer f.m.Unlock()
+	if !f.onSecondary || timeNow().Sub(f.failedOverAt) < f.failback {
+		return false
+	}
+	log.Infof("%v used the secondary endpoint %v for %v, failing back to the primary endpoint", f.service, f.secondary, f.failback)
+	f.onSecondary = false
+	return true
+}
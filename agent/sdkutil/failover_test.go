@@ -0,0 +1,74 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sdkutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointFailoverNotConfigured(t *testing.T) {
+	logger := log.NewMockLog()
+	failover := NewEndpointFailover(appconfig.DefaultConfig(), appconfig.ServiceNameEc2Messages)
+
+	failover.ReportFailure(logger)
+	_, active := failover.Secondary()
+	assert.False(t, failover.Configured())
+	assert.False(t, active)
+	assert.False(t, failover.Failback(logger))
+
+	var nilFailover *EndpointFailover
+	nilFailover.ReportFailure(logger)
+	assert.False(t, nilFailover.Failback(logger))
+}
+
+func TestEndpointFailover(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	logger := log.NewMockLog()
+	config := appconfig.DefaultConfig()
+	config.Agent.Region = "us-east-1"
+	config.Endpoints.Failover.Region = "us-west-2"
+	failover := NewEndpointFailover(config, appconfig.ServiceNameEc2Messages)
+	assert.True(t, failover.Configured())
+
+	_, active := failover.Secondary()
+	assert.False(t, active)
+
+	failover.ReportFailure(logger)
+	secondary, active := failover.Secondary()
+	assert.True(t, active)
+	assert.Equal(t, Endpoint{Region: "us-west-2", Endpoint: "ec2messages.us-west-2.amazonaws.com"}, secondary)
+
+	now = now.Add(time.Duration(appconfig.DefaultFailbackMinutes-1) * time.Minute)
+	assert.False(t, failover.Failback(logger))
+
+	now = now.Add(time.Minute)
+	assert.True(t, failover.Failback(logger))
+	_, active = failover.Secondary()
+	assert.False(t, active)
+	assert.False(t, failover.Failback(logger))
+
+	// the secondary endpoint fails too
+	failover.ReportFailure(logger)
+	failover.ReportFailure(logger)
+	_, active = failover.Secondary()
+	assert.False(t, active)
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package retryer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// bucketCapacity is the number of tokens of a full bucket
+	bucketCapacity = 500
+	// retryTokens is the cost of the retry of a failed call
+	retryTokens = 5
+	// throttleRetryTokens is the cost of the retry of a throttled call
+	throttleRetryTokens = 10
+
+	// maxRefillRate is the refill rate in tokens per second of a bucket whose calls are not throttled
+	maxRefillRate = 10
+	// minRefillRate is the lowest refill rate the throttled calls lower the bucket to
+	minRefillRate = 0.5
+	// refillRateRecovery is the increase per second of the refill rate after it was lowered
+	refillRateRecovery = 0.1
+)
+
+// sharedBucket is the bucket shared by the adaptive retryers of the process
+var sharedBucket = NewTokenBucket()
+
+// TokenBucket limits the retries of the calls to the tokens it holds. Every throttled call halves the rate at
+// which it refills, which then recovers linearly, so the retries back off the APIs which throttle the agent.
+type TokenBucket struct {
+	lock       sync.Mutex
+	tokens     float64
+	refillRate float64
+	last       time.Time
+	now        func() time.Time
+}
+
+// NewTokenBucket returns a full bucket refilling at the maximum rate
+func NewTokenBucket() *TokenBucket {
+	return &TokenBucket{
+		tokens:     bucketCapacity,
+		refillRate: maxRefillRate,
+		now:        time.Now,
+	}
+}
+
+// refill adds the tokens and recovers the refill rate for the time elapsed since the last refill
+func (b *TokenBucket) refill() {
+	now := b.now()
+	if !b.last.IsZero() {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens += elapsed * b.refillRate
+		if b.tokens > bucketCapacity {
+			b.tokens = bucketCapacity
+		}
+		b.refillRate += elapsed * refillRateRecovery
+		if b.refillRate > maxRefillRate {
+			b.refillRate = maxRefillRate
+		}
+	}
+	b.last = now
+}
+
+// Take removes the given number of tokens from the bucket, it returns false if the bucket doesn't hold enough
+func (b *TokenBucket) Take(tokens float64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill()
+	if b.tokens < tokens {
+		return false
+	}
+	b.tokens -= tokens
+	return true
+}
+
+// Throttled halves the refill rate of the bucket
+func (b *TokenBucket) Throttled() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill()
+	b.refillRate /= 2
+	if b.refillRate < minRefillRate {
+		b.refillRate = minRefillRate
+	}
+}
+
+// RefillDelay returns the time the bucket takes to refill the given number of tokens at its current rate
+func (b *TokenBucket) RefillDelay(tokens float64) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill()
+	return time.Duration(tokens / b.refillRate * float64(time.Second))
+}
+
+// state returns the tokens in the bucket and its refill rate
+func (b *TokenBucket) state() (tokens float64, refillRate float64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill()
+	return b.tokens, b.refillRate
+}
@@ -20,16 +20,52 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/request"
 )
 
 type SsmRetryer struct {
 	client.DefaultRetryer
+	// Bucket limits the retries in the adaptive retry mode, it is nil in the standard mode
+	Bucket *TokenBucket
+}
+
+// New returns the retryer of the given retry mode, which makes at most maxAttempts attempts of a call.
+// The adaptive retryers share the token bucket of the process.
+func New(retryMode string, maxAttempts int) SsmRetryer {
+	r := SsmRetryer{}
+	r.NumMaxRetries = maxAttempts - 1
+	if retryMode == appconfig.RetryModeAdaptive {
+		r.Bucket = sharedBucket
+	}
+	recordSettings(retryMode, maxAttempts)
+	return r
+}
+
+// ShouldRetry returns true if the request should be retried, and in the adaptive mode takes the tokens of the retry
+func (s SsmRetryer) ShouldRetry(r *request.Request) bool {
+	if !s.DefaultRetryer.ShouldRetry(r) {
+		return false
+	}
+	if s.Bucket == nil || r.RetryCount >= s.MaxRetries() {
+		return true
+	}
+	if !s.Bucket.Take(retryCost(r)) {
+		recordQuotaExhausted()
+		return false
+	}
+	return true
 }
 
 // RetryRules returns the delay duration before retrying this request again
 func (s SsmRetryer) RetryRules(r *request.Request) time.Duration {
+	throttled := isThrottle(r)
+	recordRetry(throttled)
+	if throttled && s.Bucket != nil {
+		s.Bucket.Throttled()
+	}
+
 	// Handle GetMessages Client.Timeout error
 	if r.Operation.Name == "GetMessages" && r.Error != nil && strings.Contains(r.Error.Error(), "Client.Timeout") {
 		// expected error. we will retry with a short 100 ms delay
@@ -38,6 +74,33 @@ func (s SsmRetryer) RetryRules(r *request.Request) time.Duration {
 
 	// retry after a > 1 sec timeout, increasing exponentially with each retry
 	rand.Seed(time.Now().UnixNano())
-	delay := int(math.Pow(2, float64(r.RetryCount))) * (rand.Intn(500) + 1000)
-	return time.Duration(delay) * time.Millisecond
+	delay := time.Duration(int(math.Pow(2, float64(r.RetryCount)))*(rand.Intn(500)+1000)) * time.Millisecond
+
+	// the throttled calls wait at least for the bucket to refill the tokens of a retry, which takes longer as the
+	// refill rate drops
+	if throttled && s.Bucket != nil {
+		if refill := s.Bucket.RefillDelay(throttleRetryTokens); refill > delay {
+			delay = refill
+		}
+	}
+	return delay
+}
+
+// isThrottle returns true if the service throttled the request
+func isThrottle(r *request.Request) bool {
+	if r.HTTPResponse != nil {
+		switch r.HTTPResponse.StatusCode {
+		case 429, 503:
+			return true
+		}
+	}
+	return r.IsErrorThrottle()
+}
+
+// retryCost returns the tokens taken by the retry of the request, the throttled calls cost more
+func retryCost(r *request.Request) float64 {
+	if isThrottle(r) {
+		return throttleRetryTokens
+	}
+	return retryTokens
 }
@@ -0,0 +1,143 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package retryer
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock returns a bucket whose time only moves when the test advances it
+func fakeClock() (*TokenBucket, func(time.Duration)) {
+	now := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+	bucket := NewTokenBucket()
+	bucket.now = func() time.Time { return now }
+	return bucket, func(d time.Duration) { now = now.Add(d) }
+}
+
+func throttledRequest() *request.Request {
+	return &request.Request{
+		Operation:    &request.Operation{Name: "SendReply"},
+		HTTPResponse: &http.Response{StatusCode: 400},
+		Error:        awserr.New("ThrottlingException", "Rate exceeded", nil),
+	}
+}
+
+func TestNew(t *testing.T) {
+	standard := New(appconfig.RetryModeStandard, 4)
+	assert.Equal(t, 3, standard.MaxRetries())
+	assert.Nil(t, standard.Bucket)
+
+	adaptive := New(appconfig.RetryModeAdaptive, 1)
+	assert.Equal(t, 0, adaptive.MaxRetries())
+	assert.Equal(t, sharedBucket, adaptive.Bucket)
+
+	stats := GetStats()
+	assert.Equal(t, appconfig.RetryModeAdaptive, stats.RetryMode)
+	assert.Equal(t, 1, stats.MaxAttempts)
+	assert.Equal(t, float64(bucketCapacity), stats.RetryTokens)
+}
+
+func TestTokenBucketTake(t *testing.T) {
+	bucket, advance := fakeClock()
+	for i := 0; i < bucketCapacity/retryTokens; i++ {
+		assert.True(t, bucket.Take(retryTokens))
+	}
+	assert.False(t, bucket.Take(retryTokens))
+
+	// the bucket refills at the maximum rate
+	advance(time.Second)
+	assert.True(t, bucket.Take(maxRefillRate))
+	assert.False(t, bucket.Take(1))
+}
+
+func TestTokenBucketThrottled(t *testing.T) {
+	bucket, advance := fakeClock()
+	assert.Equal(t, time.Second, bucket.RefillDelay(maxRefillRate))
+
+	bucket.Throttled()
+	assert.Equal(t, 2*time.Second, bucket.RefillDelay(maxRefillRate))
+	for i := 0; i < 10; i++ {
+		bucket.Throttled()
+	}
+	_, rate := bucket.state()
+	assert.Equal(t, minRefillRate, rate)
+
+	// the rate recovers linearly, up to the maximum
+	advance(10 * time.Second)
+	_, rate = bucket.state()
+	assert.InDelta(t, minRefillRate+10*refillRateRecovery, rate, 0.0001)
+	advance(time.Hour)
+	_, rate = bucket.state()
+	assert.Equal(t, float64(maxRefillRate), rate)
+}
+
+func TestShouldRetryAdaptive(t *testing.T) {
+	bucket, _ := fakeClock()
+	r := New(appconfig.RetryModeStandard, 4)
+	r.Bucket = bucket
+
+	req := throttledRequest()
+	assert.True(t, r.ShouldRetry(req))
+	tokens, _ := bucket.state()
+	assert.Equal(t, float64(bucketCapacity-throttleRetryTokens), tokens)
+
+	// the last attempt takes no token
+	req.RetryCount = 3
+	assert.True(t, r.ShouldRetry(req))
+	tokens, _ = bucket.state()
+	assert.Equal(t, float64(bucketCapacity-throttleRetryTokens), tokens)
+
+	// once the bucket is empty the calls are no longer retried
+	req.RetryCount = 0
+	bucket.tokens = 0
+	before := GetStats().RetryQuotaExhausted
+	assert.False(t, r.ShouldRetry(req))
+	assert.Equal(t, before+1, GetStats().RetryQuotaExhausted)
+}
+
+func TestShouldRetryStandard(t *testing.T) {
+	r := New(appconfig.RetryModeStandard, 4)
+	assert.True(t, r.ShouldRetry(throttledRequest()))
+
+	req := &request.Request{
+		HTTPResponse: &http.Response{StatusCode: 400},
+		Error:        awserr.New("ValidationException", "invalid", nil),
+	}
+	assert.False(t, r.ShouldRetry(req))
+}
+
+func TestRetryRulesThrottled(t *testing.T) {
+	bucket, _ := fakeClock()
+	r := New(appconfig.RetryModeStandard, 4)
+	r.Bucket = bucket
+	for i := 0; i < 10; i++ {
+		bucket.Throttled()
+	}
+
+	before := GetStats()
+	delay := r.RetryRules(throttledRequest())
+	after := GetStats()
+
+	// at the minimum rate the bucket takes longer to refill a retry than the backoff
+	assert.Equal(t, time.Duration(throttleRetryTokens/minRefillRate*float64(time.Second)), delay)
+	assert.Equal(t, before.Retries+1, after.Retries)
+	assert.Equal(t, before.Throttles+1, after.Throttles)
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package retryer
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// Stats counts the retries of the AWS service calls of the process, so that large fleets can tune their API footprint
+type Stats struct {
+	RetryMode   string
+	MaxAttempts int
+	// Retries is the number of retried calls, including the throttled ones
+	Retries int64
+	// Throttles is the number of calls the services throttled
+	Throttles int64
+	// RetryQuotaExhausted is the number of failed calls not retried because the bucket ran out of tokens
+	RetryQuotaExhausted int64
+	// RetryTokens and RefillRate describe the token bucket of the adaptive retry mode
+	RetryTokens float64 `json:",omitempty"`
+	RefillRate  float64 `json:",omitempty"`
+}
+
+var (
+	statsLock sync.Mutex
+	stats     = Stats{RetryMode: appconfig.RetryModeStandard}
+)
+
+// recordSettings notes the retry mode and maximum attempts of the last retryer created
+func recordSettings(retryMode string, maxAttempts int) {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	stats.RetryMode = retryMode
+	stats.MaxAttempts = maxAttempts
+}
+
+// recordRetry counts a retry, and a throttle if the call was throttled
+func recordRetry(throttled bool) {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	stats.Retries++
+	if throttled {
+		stats.Throttles++
+	}
+}
+
+// recordQuotaExhausted counts a call not retried for lack of tokens
+func recordQuotaExhausted() {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	stats.RetryQuotaExhausted++
+}
+
+// GetStats returns the retry counters of the process.
+func GetStats() Stats {
+	statsLock.Lock()
+	current := stats
+	statsLock.Unlock()
+
+	if current.RetryMode == appconfig.RetryModeAdaptive {
+		current.RetryTokens, current.RefillRate = sharedBucket.state()
+	}
+	return current
+}
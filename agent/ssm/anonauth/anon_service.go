@@ -47,14 +47,9 @@ func NewAnonymousService(region string) AnonymousService {
 	//parse appConfig override to get ssm endpoint if there is any
 	appConfig, err := appconfig.Config(true)
 	if err == nil {
-		if appConfig.Ssm.Endpoint != "" {
-			awsConfig.Endpoint = &appConfig.Ssm.Endpoint
-		} else {
-			if region, err := platform.Region(); err == nil {
-				if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "ssm"); defaultEndpoint != "" {
-					awsConfig.Endpoint = &defaultEndpoint
-				}
-			}
+		region, _ := platform.Region()
+		if endpoint := appconfig.GetServiceEndpoint(appConfig, appconfig.ServiceNameSsm, region); endpoint != "" {
+			awsConfig.Endpoint = &endpoint
 		}
 	} else {
 		log.Printf("encountered error while loading appconfig - %s", err)
@@ -14,15 +14,16 @@
 package ssm
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -43,6 +44,7 @@ type Service interface {
 		associationID string,
 		instanceID string,
 		executionResult *ssm.InstanceAssociationExecutionResult) (response *ssm.UpdateInstanceAssociationStatusOutput, err error)
+	PutInventory(log log.T, instanceID string, items []*ssm.InventoryItem) (response *ssm.PutInventoryOutput, err error)
 	PutComplianceItems(
 		log log.T,
 		executionTime *time.Time,
@@ -74,6 +76,30 @@ type Service interface {
 
 var ssmStopPolicy *sdkutil.StopPolicy
 
+var endpointFailover *sdkutil.EndpointFailover
+var endpointFailoverLock sync.Mutex
+
+// getEndpointFailover returns the failover of the SSM endpoint, it is created from appconfig by the first client
+func getEndpointFailover(appConfig *appconfig.SsmagentConfig) *sdkutil.EndpointFailover {
+	endpointFailoverLock.Lock()
+	defer endpointFailoverLock.Unlock()
+	if endpointFailover == nil && appConfig != nil {
+		endpointFailover = sdkutil.NewEndpointFailover(*appConfig, appconfig.ServiceNameSsm)
+	}
+	return endpointFailover
+}
+
+// ReportEndpointFailure fails the SSM clients created afterwards over to the secondary endpoint configured
+// in appconfig after sustained failures, or back to the primary endpoint when the secondary one keeps failing
+func ReportEndpointFailure(log log.T) {
+	getEndpointFailover(nil).ReportFailure(log)
+}
+
+// EndpointFailback returns true when the SSM clients should be created again to fail back to the primary endpoint
+func EndpointFailback(log log.T) bool {
+	return getEndpointFailover(nil).Failback(log)
+}
+
 // sdkService is an service wrapper that delegates to the ssm sdk.
 type sdkService struct {
 	sdk *ssm.SSM
@@ -90,24 +116,27 @@ func NewService() Service {
 	// parse appConfig overrides
 	appConfig, err := appconfig.Config(false)
 	if err == nil {
-		if appConfig.Ssm.Endpoint != "" {
-			awsConfig.Endpoint = &appConfig.Ssm.Endpoint
-		} else {
-			if region, err := platform.Region(); err == nil {
-				if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "ssm"); defaultEndpoint != "" {
-					awsConfig.Endpoint = &defaultEndpoint
-				}
-			}
+		region, _ := platform.Region()
+		if endpoint := appconfig.GetServiceEndpoint(appConfig, appconfig.ServiceNameSsm, region); endpoint != "" {
+			awsConfig.Endpoint = &endpoint
 		}
+		awsConfig.HTTPClient = &http.Client{Transport: proxyconfig.NewTransport(appconfig.ServiceNameSsm)}
 		if appConfig.Agent.Region != "" {
 			awsConfig.Region = &appConfig.Agent.Region
 		}
+		if secondary, active := getEndpointFailover(&appConfig).Secondary(); active {
+			awsConfig.Endpoint = &secondary.Endpoint
+			awsConfig.Region = &secondary.Region
+		}
 
 		// TODO: test hook, can be removed before release
-		// this is to skip ssl verification for the beta self signed certs
-		if appConfig.Ssm.InsecureSkipVerify {
+		// this is to skip ssl verification for the beta self signed certs, which FIPS mode doesn't allow
+		if appConfig.Ssm.InsecureSkipVerify && !appConfig.Agent.UseFipsEndpoint {
+			tlsConfig := proxyconfig.TLSConfig(appConfig)
+			tlsConfig.InsecureSkipVerify = true
 			tr := &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				Proxy:           proxyconfig.ProxyFunc(appConfig, appconfig.ServiceNameSsm),
+				TLSClientConfig: tlsConfig,
 			}
 			awsConfig.HTTPClient = &http.Client{Transport: tr}
 		}
@@ -201,6 +230,22 @@ func (svc *sdkService) PutComplianceItems(
 	return
 }
 
+// PutInventory calls the PutInventory SSM API.
+func (svc *sdkService) PutInventory(log log.T, instanceID string, items []*ssm.InventoryItem) (response *ssm.PutInventoryOutput, err error) {
+	params := &ssm.PutInventoryInput{
+		InstanceId: aws.String(instanceID),
+		Items:      items,
+	}
+
+	response, err = svc.sdk.PutInventory(params)
+	if err != nil {
+		sdkutil.HandleAwsError(log, err, ssmStopPolicy)
+		return
+	}
+	log.Debug("PutInventory Response ", response)
+	return
+}
+
 //UpdateInstanceAssociationStatus calls the ListAssociations SSM API.
 func (svc *sdkService) UpdateInstanceAssociationStatus(log log.T, associationID string, instanceID string, executionResult *ssm.InstanceAssociationExecutionResult) (response *ssm.UpdateInstanceAssociationStatusOutput, err error) {
 	params := ssm.UpdateInstanceAssociationStatusInput{
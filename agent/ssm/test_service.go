@@ -145,6 +145,12 @@ func (m *Mock) GetDecryptedParameters(log log.T, paramNames []string) (response
 	return args.Get(0).(*ssm.GetParametersOutput), args.Error(1)
 }
 
+// PutInventory mocks the PutInventory function.
+func (m *Mock) PutInventory(log log.T, instanceID string, items []*ssm.InventoryItem) (response *ssm.PutInventoryOutput, err error) {
+	args := m.Called(log, instanceID, items)
+	return args.Get(0).(*ssm.PutInventoryOutput), args.Error(1)
+}
+
 // PutComplianceItem mocks the PutComplianceItem function
 func (m *Mock) PutComplianceItems(
 	log log.T,
@@ -15,12 +15,12 @@
 package util
 
 import (
-	"crypto/tls"
 	"net/http"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/aws-sdk-go/aws"
 )
@@ -37,23 +37,22 @@ func AwsConfig() *aws.Config {
 	if err != nil {
 		return awsConfig
 	}
-	if appConfig.Ssm.Endpoint != "" {
-		awsConfig.Endpoint = &appConfig.Ssm.Endpoint
-	} else {
-		if region, err := platform.Region(); err == nil {
-			if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "ssm"); defaultEndpoint != "" {
-				awsConfig.Endpoint = &defaultEndpoint
-			}
-		}
+	region, _ := platform.Region()
+	if endpoint := appconfig.GetServiceEndpoint(appConfig, appconfig.ServiceNameSsm, region); endpoint != "" {
+		awsConfig.Endpoint = &endpoint
 	}
+	awsConfig.HTTPClient = &http.Client{Transport: proxyconfig.NewTransport(appconfig.ServiceNameSsm)}
 	if appConfig.Agent.Region != "" {
 		awsConfig.Region = &appConfig.Agent.Region
 	}
 	// TODO: test hook, can be removed before release
-	// this is to skip ssl verification for the beta self signed certs
-	if appConfig.Ssm.InsecureSkipVerify {
+	// this is to skip ssl verification for the beta self signed certs, which FIPS mode doesn't allow
+	if appConfig.Ssm.InsecureSkipVerify && !appConfig.Agent.UseFipsEndpoint {
+		tlsConfig := proxyconfig.TLSConfig(appConfig)
+		tlsConfig.InsecureSkipVerify = true
 		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			Proxy:           proxyconfig.ProxyFunc(appConfig, appconfig.ServiceNameSsm),
+			TLSClientConfig: tlsConfig,
 		}
 		awsConfig.HTTPClient = &http.Client{Transport: tr}
 	}
@@ -63,9 +62,11 @@ func AwsConfig() *aws.Config {
 }
 
 var newRetryer = func() aws.RequestRetryer {
-	r := retryer.SsmRetryer{}
-	r.NumMaxRetries = 3
-	return r
+	appConfig, err := appconfig.Config(false)
+	if err != nil {
+		return retryer.New(appconfig.RetryModeStandard, appconfig.DefaultMaxAttempts)
+	}
+	return retryer.New(appConfig.Agent.RetryMode, appConfig.Agent.MaxAttempts)
 }
 
 var sleepDelay = func(d time.Duration) {
@@ -40,7 +40,7 @@ const (
 func (p *Processor) IsAllowed() bool {
 	// check if metadata is reachable which indicates the instance is in EC2.
 	// maximum retry is 10 to ensure the failure/error is not caused by arbitrary reason.
-	ec2MetadataService := ec2metadata.New(session.New(aws.NewConfig().WithMaxRetries(10)))
+	ec2MetadataService := ec2metadata.New(session.New(platform.Ec2MetadataConfig().WithMaxRetries(10)))
 	if metadata, err := ec2MetadataService.GetMetadata(""); err != nil || metadata == "" {
 		return false
 	}
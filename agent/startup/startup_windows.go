@@ -30,7 +30,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/startup/model"
 	"github.com/aws/amazon-ssm-agent/agent/startup/serialport"
 	"github.com/aws/amazon-ssm-agent/agent/version"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
@@ -154,7 +153,7 @@ func (p *Processor) IsAllowed() bool {
 
 	// check if metadata is rechable which indicates the instance is in EC2.
 	// maximum retry is 10 to ensure the failure/error is not caused by arbitrary reason.
-	ec2MetadataService := ec2metadata.New(session.New(aws.NewConfig().WithMaxRetries(10)))
+	ec2MetadataService := ec2metadata.New(session.New(platform.Ec2MetadataConfig().WithMaxRetries(10)))
 	if metadata, err := ec2MetadataService.GetMetadata(""); err != nil || metadata == "" {
 		// This is as designed to check if instance is in EC2, so it is not an error
 		return false
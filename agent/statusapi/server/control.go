@@ -0,0 +1,133 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
+	managerContracts "github.com/aws/amazon-ssm-agent/agent/longrunning/plugin"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// maxRecentOutputLines is the number of lines of output returned for a plugin
+	maxRecentOutputLines = 50
+	// maxRecentOutputBytes bounds the end of the output read to find the recent lines
+	maxRecentOutputBytes = 64 * 1024
+)
+
+// longRunningPluginManager returns the long running plugin manager
+func longRunningPluginManager() (manager.T, error) {
+	return manager.GetInstance()
+}
+
+// controlPlugin applies an action requested through the status API to a long running plugin, and returns its state
+func (s *Server) controlPlugin(action string, name string) (interface{}, error) {
+	if action == statusapi.PluginActionList {
+		return longRunningPluginStatuses(s.context), nil
+	}
+
+	lrpm, err := pluginManager()
+	if err != nil {
+		return nil, err
+	}
+	plugin, isRegistered := lrpm.GetRegisteredPlugins()[name]
+	if !isRegistered {
+		return nil, fmt.Errorf("%v is not a registered long running plugin", name)
+	}
+
+	switch action {
+	case statusapi.PluginActionStart:
+		err = lrpm.StartRegisteredPlugin(name)
+	case statusapi.PluginActionStop:
+		err = lrpm.StopPlugin(name, task.NewChanneledCancelFlag())
+	case statusapi.PluginActionRestart:
+		err = lrpm.RestartPlugin(name)
+	case statusapi.PluginActionHealth:
+		return s.pluginHealth(name, plugin), nil
+	default:
+		return nil, fmt.Errorf("unknown plugin action %v", action)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %v %v: %v", action, name, err)
+	}
+	return pluginStatus(s.context, name, lrpm.GetRegisteredPlugins()[name]), nil
+}
+
+// pluginStatus describes a long running plugin
+func pluginStatus(context context.T, name string, plugin managerContracts.Plugin) statusapi.LongRunningPluginStatus {
+	status := statusapi.LongRunningPluginStatus{
+		Name:                          name,
+		Enabled:                       plugin.Info.State.IsEnabled,
+		LastConfigurationModifiedTime: timeOrNil(plugin.Info.State.LastConfigurationModifiedTime),
+	}
+	if plugin.Handler != nil {
+		status.Running = plugin.Handler.IsRunning(context)
+	}
+	return status
+}
+
+// pluginHealth describes a long running plugin with the last lines of its output, if it captures it
+func (s *Server) pluginHealth(name string, plugin managerContracts.Plugin) statusapi.LongRunningPluginHealth {
+	health := statusapi.LongRunningPluginHealth{LongRunningPluginStatus: pluginStatus(s.context, name, plugin)}
+	if reporter, ok := plugin.Handler.(managerContracts.OutputReporter); ok {
+		health.OutputPath = reporter.OutputPath()
+		var err error
+		if health.RecentOutput, err = recentOutput(health.OutputPath, maxRecentOutputLines); err != nil {
+			s.context.Log().Debugf("unable to read the output of %v: %v", name, err)
+		}
+	}
+	return health
+}
+
+// recentOutput returns the last lines of an output file
+func recentOutput(path string, maxLines int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - maxRecentOutputBytes
+	if offset > 0 {
+		if _, err = file.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), maxRecentOutputBytes)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if offset > 0 && len(lines) > 0 {
+		// the first line read is likely the end of a line
+		lines = lines[1:]
+	}
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return lines, scanner.Err()
+}
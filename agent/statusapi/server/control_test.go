@@ -0,0 +1,97 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
+	managerContracts "github.com/aws/amazon-ssm-agent/agent/longrunning/plugin"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// daemonStub is a long running plugin capturing its output in a file
+type daemonStub struct {
+	running    bool
+	outputPath string
+}
+
+func (d *daemonStub) IsRunning(context context.T) bool { return d.running }
+
+func (d *daemonStub) Start(context context.T, configuration string, orchestrationDir string, cancelFlag task.CancelFlag, out iohandler.IOHandler) error {
+	return nil
+}
+
+func (d *daemonStub) Stop(context context.T, cancelFlag task.CancelFlag) error { return nil }
+
+func (d *daemonStub) OutputPath() string { return d.outputPath }
+
+func TestControlPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statusapi")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	outputPath := filepath.Join(dir, "daemon.log")
+	assert.NoError(t, ioutil.WriteFile(outputPath, []byte("started\nlistening on 8080\n"), 0600))
+
+	lrpm := manager.NewMockDefault()
+	lrpm.ExpectedCalls = nil
+	lrpm.On("GetRegisteredPlugins").Return(map[string]managerContracts.Plugin{
+		"daemon": {Handler: &daemonStub{running: true, outputPath: outputPath}},
+	})
+	defer func(getManager func() (manager.T, error)) { pluginManager = getManager }(pluginManager)
+	pluginManager = func() (manager.T, error) { return lrpm, nil }
+	s := &Server{context: context.NewMockDefault()}
+
+	status, err := s.controlPlugin(statusapi.PluginActionRestart, "daemon")
+	assert.NoError(t, err)
+	assert.Equal(t, statusapi.LongRunningPluginStatus{Name: "daemon", Running: true}, status)
+
+	status, err = s.controlPlugin(statusapi.PluginActionHealth, "daemon")
+	assert.NoError(t, err)
+	health := status.(statusapi.LongRunningPluginHealth)
+	assert.Equal(t, outputPath, health.OutputPath)
+	assert.Equal(t, []string{"started", "listening on 8080"}, health.RecentOutput)
+
+	_, err = s.controlPlugin(statusapi.PluginActionStop, "unknown")
+	assert.Error(t, err)
+}
+
+func TestRecentOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statusapi")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	lines := make([]string, 0, 5000)
+	for i := 0; i < cap(lines); i++ {
+		lines = append(lines, fmt.Sprintf("line %04d of the daemon output", i))
+	}
+	outputPath := filepath.Join(dir, "daemon.log")
+	assert.NoError(t, ioutil.WriteFile(outputPath, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+	recent, err := recentOutput(outputPath, maxRecentOutputLines)
+	assert.NoError(t, err)
+	assert.Equal(t, lines[len(lines)-maxRecentOutputLines:], recent)
+
+	_, err = recentOutput(filepath.Join(dir, "missing.log"), maxRecentOutputLines)
+	assert.Error(t, err)
+}
@@ -0,0 +1,280 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package server implements the core module which serves the local status API of the agent.
+package server
+
+import (
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/atrest"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
+	managerContracts "github.com/aws/amazon-ssm-agent/agent/longrunning/plugin"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi"
+	"github.com/aws/amazon-ssm-agent/agent/update/processor"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const name = "StatusAPI"
+
+// updateStateNotStarted is reported when the agent was never updated
+const updateStateNotStarted = "NotStarted"
+
+// assign methods to variables to allow the unit tests to override them
+var (
+	listen            = statusapi.Listen
+	getInstanceID     = platform.InstanceID
+	documentStateDir  = docmanager.DocumentStateDir
+	registeredPlugins = longRunningPlugins
+	pluginManager     = longRunningPluginManager
+	updateContextPath = updateutil.UpdateContextFilePath(appconfig.UpdaterArtifactsRoot)
+	loadUpdateContext = processor.LoadUpdateContext
+	documentLocations = []string{appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent}
+	getRetryStats     = retryer.GetStats
+)
+
+// Server answers the requests made to the status API
+type Server struct {
+	context  context.T
+	listener statusapi.Listener
+}
+
+// NewServer creates a new status API core module.
+func NewServer(context context.T) *Server {
+	return &Server{
+		context: context.With("[" + name + "]"),
+	}
+}
+
+// serve handles the clients until the listener is closed
+func (s *Server) serve(listener statusapi.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.context.Log().Debugf("%v stopped accepting requests: %v", name, err)
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle answers a single request, then closes the connection
+func (s *Server) handle(conn io.ReadWriteCloser) {
+	log := s.context.Log()
+	defer conn.Close()
+
+	request, err := statusapi.ReadRequest(conn)
+	var status interface{}
+	if err == nil && request.PluginAction != "" {
+		log.Infof("status API request to %v long running plugin %v", request.PluginAction, request.PluginName)
+		status, err = s.controlPlugin(request.PluginAction, request.PluginName)
+	} else if err == nil {
+		log.Debugf("status API request for section %v", request.Section)
+		status = s.collect(request.Section)
+	}
+	if err = statusapi.WriteResponse(conn, status, err); err != nil {
+		log.Warnf("failed to answer status API request: %v", err)
+	}
+}
+
+// collect gathers the requested section of the status
+func (s *Server) collect(section string) interface{} {
+	log := s.context.Log()
+	switch section {
+	case statusapi.SectionConnectivity:
+		return statusapi.Connectivity()
+	case statusapi.SectionDocuments:
+		return documents(log)
+	case statusapi.SectionLongRunningPlugins:
+		return longRunningPluginStatuses(s.context)
+	case statusapi.SectionUpdate:
+		return update(log)
+	case statusapi.SectionRetries:
+		return getRetryStats()
+	}
+	return statusapi.AgentStatus{
+		AgentVersion:       version.Version,
+		Connectivity:       statusapi.Connectivity(),
+		Documents:          documents(log),
+		LongRunningPlugins: longRunningPluginStatuses(s.context),
+		Update:             update(log),
+		Retries:            getRetryStats(),
+	}
+}
+
+// documents lists the documents which are pending or running, with the state of their steps
+func documents(log log.T) []statusapi.DocumentStatus {
+	statuses := []statusapi.DocumentStatus{}
+	instanceID, err := getInstanceID()
+	if err != nil {
+		log.Errorf("unable to list documents, failed to get instance id: %v", err)
+		return statuses
+	}
+
+	for _, location := range documentLocations {
+		dir := documentStateDir(instanceID, location)
+		fileNames, err := fileutil.GetFileNames(dir)
+		if err != nil {
+			continue
+		}
+		for _, fileName := range fileNames {
+			var state contracts.DocumentState
+			if err = atrest.UnmarshalFile(filepath.Join(dir, fileName), &state); err != nil {
+				log.Debugf("unable to read document state %v: %v", fileName, err)
+				continue
+			}
+			statuses = append(statuses, documentStatus(state, location))
+		}
+	}
+	return statuses
+}
+
+// documentStatus describes a document from its interim state
+func documentStatus(state contracts.DocumentState, location string) statusapi.DocumentStatus {
+	info := state.DocumentInformation
+	status := statusapi.DocumentStatus{
+		DocumentID:    info.DocumentID,
+		DocumentName:  info.DocumentName,
+		DocumentType:  state.DocumentType,
+		CommandID:     info.CommandID,
+		AssociationID: info.AssociationID,
+		CorrelationID: info.CorrelationID,
+		Status:        info.DocumentStatus,
+		Steps:         []statusapi.StepStatus{},
+	}
+	if status.Status == "" {
+		if location == appconfig.DefaultLocationOfPending {
+			status.Status = contracts.ResultStatusNotStarted
+		} else {
+			status.Status = contracts.ResultStatusInProgress
+		}
+	}
+	for _, plugin := range state.InstancePluginsInformation {
+		step := statusapi.StepStatus{
+			ID:            plugin.Id,
+			Name:          plugin.Name,
+			CorrelationID: plugin.Configuration.CorrelationID,
+			Status:        plugin.Result.Status,
+			StartDateTime: timeOrNil(plugin.Result.StartDateTime),
+			EndDateTime:   timeOrNil(plugin.Result.EndDateTime),
+		}
+		if step.Status == "" {
+			step.Status = contracts.ResultStatusNotStarted
+		}
+		status.Steps = append(status.Steps, step)
+	}
+	return status
+}
+
+// longRunningPlugins returns the plugins registered with the long running plugin manager
+func longRunningPlugins() (map[string]managerContracts.Plugin, error) {
+	lrpm, err := manager.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	return lrpm.GetRegisteredPlugins(), nil
+}
+
+// longRunningPluginStatuses describes the long running plugins, sorted by name
+func longRunningPluginStatuses(context context.T) []statusapi.LongRunningPluginStatus {
+	statuses := []statusapi.LongRunningPluginStatus{}
+	plugins, err := registeredPlugins()
+	if err != nil {
+		context.Log().Debugf("unable to list long running plugins: %v", err)
+		return statuses
+	}
+
+	names := make([]string, 0, len(plugins))
+	for pluginName := range plugins {
+		names = append(names, pluginName)
+	}
+	sort.Strings(names)
+
+	for _, pluginName := range names {
+		statuses = append(statuses, pluginStatus(context, pluginName, plugins[pluginName]))
+	}
+	return statuses
+}
+
+// update describes the update in progress or, if there is none, the last one
+func update(log log.T) statusapi.UpdateStatus {
+	updateContext, err := loadUpdateContext(log, updateContextPath)
+	if err != nil {
+		log.Debugf("unable to load the update context: %v", err)
+		return statusapi.UpdateStatus{State: updateStateNotStarted}
+	}
+
+	detail := updateContext.Current
+	if (detail == nil || detail.State == "") && len(updateContext.Histories) > 0 {
+		detail = updateContext.Histories[len(updateContext.Histories)-1]
+	}
+	if detail == nil || detail.State == "" {
+		return statusapi.UpdateStatus{State: updateStateNotStarted}
+	}
+	return statusapi.UpdateStatus{
+		State:         string(detail.State),
+		Result:        detail.Result,
+		SourceVersion: detail.SourceVersion,
+		TargetVersion: detail.TargetVersion,
+		StartDateTime: timeOrNil(detail.StartDateTime),
+		EndDateTime:   timeOrNil(detail.EndDateTime),
+	}
+}
+
+// timeOrNil omits the times which were never set
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the module name
+func (s *Server) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts serving the status API
+func (s *Server) ModuleExecute(context context.T) (err error) {
+	log := s.context.Log()
+	if s.listener, err = listen(); err != nil {
+		log.Errorf("unable to start the status API: %v", err)
+		return
+	}
+	log.Infof("status API listening on %v", appconfig.StatusAPIAddress)
+	go s.serve(s.listener)
+	return nil
+}
+
+// ModuleRequestStop stops serving the status API
+func (s *Server) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if s.listener != nil {
+		s.context.Log().Info("stopping the status API.")
+		err = s.listener.Close()
+	}
+	return
+}
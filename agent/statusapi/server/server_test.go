@@ -0,0 +1,150 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	managerContracts "github.com/aws/amazon-ssm-agent/agent/longrunning/plugin"
+	"github.com/aws/amazon-ssm-agent/agent/statusapi"
+	"github.com/aws/amazon-ssm-agent/agent/update/processor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocuments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statusapi")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(getID func() (string, error), stateDir func(string, string) string) {
+		getInstanceID = getID
+		documentStateDir = stateDir
+	}(getInstanceID, documentStateDir)
+	getInstanceID = func() (string, error) { return "i-1234", nil }
+	documentStateDir = func(instanceID, location string) string { return filepath.Join(dir, instanceID, location) }
+
+	start := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+	pending := contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{DocumentID: "command-1", CommandID: "command-1", DocumentName: "AWS-RunShellScript"},
+		DocumentType:        contracts.SendCommand,
+		InstancePluginsInformation: []contracts.PluginState{
+			{Id: "runShellScript", Name: "aws:runShellScript"},
+		},
+	}
+	current := contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{DocumentID: "association-1.run", AssociationID: "association-1", DocumentName: "AWS-UpdateSSMAgent", DocumentStatus: contracts.ResultStatusInProgress},
+		DocumentType:        contracts.Association,
+		InstancePluginsInformation: []contracts.PluginState{
+			{Id: "updateAgent", Name: "aws:updateSsmAgent", Result: contracts.PluginResult{Status: contracts.ResultStatusInProgress, StartDateTime: start}},
+		},
+	}
+	writeState(t, filepath.Join(dir, "i-1234", appconfig.DefaultLocationOfPending, "command-1"), pending)
+	writeState(t, filepath.Join(dir, "i-1234", appconfig.DefaultLocationOfCurrent, "association-1.run"), current)
+
+	statuses := documents(log.NewMockLog())
+	assert.Len(t, statuses, 2)
+
+	assert.Equal(t, "command-1", statuses[0].CommandID)
+	assert.Equal(t, contracts.ResultStatusNotStarted, statuses[0].Status)
+	assert.Equal(t, []statusapi.StepStatus{{ID: "runShellScript", Name: "aws:runShellScript", Status: contracts.ResultStatusNotStarted}}, statuses[0].Steps)
+
+	assert.Equal(t, "association-1", statuses[1].AssociationID)
+	assert.Equal(t, contracts.ResultStatusInProgress, statuses[1].Status)
+	assert.Equal(t, contracts.ResultStatusInProgress, statuses[1].Steps[0].Status)
+	assert.Equal(t, start, *statuses[1].Steps[0].StartDateTime)
+	assert.Nil(t, statuses[1].Steps[0].EndDateTime)
+}
+
+func TestDocumentsNoInstanceID(t *testing.T) {
+	defer func(getID func() (string, error)) { getInstanceID = getID }(getInstanceID)
+	getInstanceID = func() (string, error) { return "", errors.New("no instance id") }
+
+	assert.Empty(t, documents(log.NewMockLog()))
+}
+
+func TestLongRunningPluginStatuses(t *testing.T) {
+	defer func(plugins func() (map[string]managerContracts.Plugin, error)) { registeredPlugins = plugins }(registeredPlugins)
+	modified := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+	registeredPlugins = func() (map[string]managerContracts.Plugin, error) {
+		return map[string]managerContracts.Plugin{
+			"aws:cloudWatch": {Info: managerContracts.PluginInfo{State: managerContracts.PluginState{IsEnabled: true, LastConfigurationModifiedTime: modified}}},
+			"aws:amazon":     {},
+		}, nil
+	}
+
+	statuses := longRunningPluginStatuses(context.NewMockDefault())
+	assert.Equal(t, []statusapi.LongRunningPluginStatus{
+		{Name: "aws:amazon"},
+		{Name: "aws:cloudWatch", Enabled: true, LastConfigurationModifiedTime: &modified},
+	}, statuses)
+}
+
+func TestUpdate(t *testing.T) {
+	defer func(load func(log.T, string) (*processor.UpdateContext, error)) { loadUpdateContext = load }(loadUpdateContext)
+	start := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		updateContext *processor.UpdateContext
+		err           error
+		expected      statusapi.UpdateStatus
+	}{
+		{
+			updateContext: &processor.UpdateContext{},
+			expected:      statusapi.UpdateStatus{State: updateStateNotStarted},
+		},
+		{
+			err:      errors.New("corrupt update context"),
+			expected: statusapi.UpdateStatus{State: updateStateNotStarted},
+		},
+		{
+			updateContext: &processor.UpdateContext{
+				Current: &processor.UpdateDetail{State: processor.Installed, SourceVersion: "2.2.0.0", TargetVersion: "2.3.0.0", StartDateTime: start},
+			},
+			expected: statusapi.UpdateStatus{State: string(processor.Installed), SourceVersion: "2.2.0.0", TargetVersion: "2.3.0.0", StartDateTime: &start},
+		},
+		{
+			updateContext: &processor.UpdateContext{
+				Current: &processor.UpdateDetail{},
+				Histories: []*processor.UpdateDetail{
+					{State: processor.Completed, Result: contracts.ResultStatusFailed},
+					{State: processor.Completed, Result: contracts.ResultStatusSuccess, TargetVersion: "2.3.0.0"},
+				},
+			},
+			expected: statusapi.UpdateStatus{State: string(processor.Completed), Result: contracts.ResultStatusSuccess, TargetVersion: "2.3.0.0"},
+		},
+	}
+	for _, testCase := range testCases {
+		loadUpdateContext = func(log.T, string) (*processor.UpdateContext, error) {
+			return testCase.updateContext, testCase.err
+		}
+		assert.Equal(t, testCase.expected, update(log.NewMockLog()))
+	}
+}
+
+func writeState(t *testing.T, path string, state contracts.DocumentState) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	content, err := jsonutil.Marshal(state)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
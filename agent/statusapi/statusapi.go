@@ -0,0 +1,294 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package statusapi implements the local API through which the agent reports its status,
+// served on a unix socket or a named pipe, and the client used by ssm-cli.
+package statusapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
+)
+
+const (
+	// SectionAll requests the complete status of the agent
+	SectionAll = "all"
+	// SectionConnectivity requests the state of the connection to the Systems Manager service
+	SectionConnectivity = "connectivity"
+	// SectionDocuments requests the documents pending or running and their steps
+	SectionDocuments = "documents"
+	// SectionLongRunningPlugins requests the state of the long running plugins
+	SectionLongRunningPlugins = "long-running-plugins"
+	// SectionUpdate requests the state of the last agent update
+	SectionUpdate = "update"
+	// SectionRetries requests the retry and throttle counters of the AWS service calls of the agent
+	SectionRetries = "retries"
+
+	// PluginRequest prefixes the requests controlling the long running plugins, e.g. "plugin restart aws:cloudWatch"
+	PluginRequest = "plugin"
+	// PluginActionList lists the registered long running plugins
+	PluginActionList = "list"
+	// PluginActionStart starts a long running plugin with its last configuration
+	PluginActionStart = "start"
+	// PluginActionStop stops a long running plugin
+	PluginActionStop = "stop"
+	// PluginActionRestart stops then starts a long running plugin
+	PluginActionRestart = "restart"
+	// PluginActionHealth returns the state of a long running plugin and its recent output
+	PluginActionHealth = "health"
+
+	// ConnectivityConnected is the connectivity state after a successful poll
+	ConnectivityConnected = "Connected"
+	// ConnectivityDisconnected is the connectivity state after a failed poll
+	ConnectivityDisconnected = "Disconnected"
+	// ConnectivityUnknown is the connectivity state before the first poll
+	ConnectivityUnknown = "Unknown"
+
+	// maxRequestLength is the maximum length of a request, which is a single line
+	maxRequestLength = 256
+	// requestTimeout is the time given to a client to send its request and to the agent to answer
+	requestTimeout = 10 * time.Second
+)
+
+// Sections lists the sections of the status which can be requested
+var Sections = []string{SectionConnectivity, SectionDocuments, SectionLongRunningPlugins, SectionUpdate, SectionRetries}
+
+// PluginActions lists the actions which can be requested on the long running plugins
+var PluginActions = []string{PluginActionList, PluginActionStart, PluginActionStop, PluginActionRestart, PluginActionHealth}
+
+// Request is a request of a client, either for a section of the status or for an action on the long running plugins
+type Request struct {
+	Section string
+	// PluginAction is set for the requests controlling the long running plugins
+	PluginAction string
+	// PluginName is the plugin the action applies to, it is empty to list the plugins
+	PluginName string
+}
+
+// AgentStatus is the complete status of the agent
+type AgentStatus struct {
+	AgentVersion       string
+	Connectivity       ConnectivityStatus
+	Documents          []DocumentStatus
+	LongRunningPlugins []LongRunningPluginStatus
+	Update             UpdateStatus
+	Retries            retryer.Stats
+}
+
+// ConnectivityStatus describes the connection to the message delivery service
+type ConnectivityStatus struct {
+	State                  string
+	LastPollTime           *time.Time `json:",omitempty"`
+	LastSuccessfulPollTime *time.Time `json:",omitempty"`
+	LastError              string     `json:",omitempty"`
+}
+
+// DocumentStatus describes a document which is pending or running
+type DocumentStatus struct {
+	DocumentID    string
+	DocumentName  string
+	DocumentType  contracts.DocumentType
+	CommandID     string `json:",omitempty"`
+	AssociationID string `json:",omitempty"`
+	CorrelationID string `json:",omitempty"`
+	Status        contracts.ResultStatus
+	Steps         []StepStatus
+}
+
+// StepStatus describes a step of a document
+type StepStatus struct {
+	ID            string
+	Name          string
+	CorrelationID string `json:",omitempty"`
+	Status        contracts.ResultStatus
+	StartDateTime *time.Time `json:",omitempty"`
+	EndDateTime   *time.Time `json:",omitempty"`
+}
+
+// LongRunningPluginStatus describes a long running plugin
+type LongRunningPluginStatus struct {
+	Name                          string
+	Enabled                       bool
+	Running                       bool
+	LastConfigurationModifiedTime *time.Time `json:",omitempty"`
+}
+
+// LongRunningPluginHealth describes a long running plugin and its recent output
+type LongRunningPluginHealth struct {
+	LongRunningPluginStatus
+	OutputPath   string   `json:",omitempty"`
+	RecentOutput []string `json:",omitempty"`
+}
+
+// UpdateStatus describes the last agent update
+type UpdateStatus struct {
+	State         string
+	Result        contracts.ResultStatus `json:",omitempty"`
+	SourceVersion string                 `json:",omitempty"`
+	TargetVersion string                 `json:",omitempty"`
+	StartDateTime *time.Time             `json:",omitempty"`
+	EndDateTime   *time.Time             `json:",omitempty"`
+}
+
+// errorResponse is returned instead of the status when the request fails
+type errorResponse struct {
+	Error string
+}
+
+// Listener accepts the connections of the clients of the status API
+type Listener interface {
+	Accept() (io.ReadWriteCloser, error)
+	Close() error
+}
+
+var (
+	connectivityLock sync.RWMutex
+	connectivity     = ConnectivityStatus{State: ConnectivityUnknown}
+)
+
+// RecordPoll notes the result of a poll of the message delivery service.
+func RecordPoll(err error) {
+	connectivityLock.Lock()
+	defer connectivityLock.Unlock()
+
+	now := time.Now().UTC()
+	connectivity.LastPollTime = &now
+	if err != nil {
+		connectivity.State = ConnectivityDisconnected
+		connectivity.LastError = err.Error()
+		return
+	}
+	connectivity.State = ConnectivityConnected
+	connectivity.LastSuccessfulPollTime = &now
+	connectivity.LastError = ""
+}
+
+// Connectivity returns the connectivity state recorded by the last poll.
+func Connectivity() ConnectivityStatus {
+	connectivityLock.RLock()
+	defer connectivityLock.RUnlock()
+	return connectivity
+}
+
+// IsSection returns true if the section can be requested
+func IsSection(section string) bool {
+	if section == SectionAll {
+		return true
+	}
+	for _, known := range Sections {
+		if section == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadRequest reads the request of a client, the request is a single line: a section of the status, or
+// "plugin" followed by an action and the name of the plugin it applies to.
+func ReadRequest(conn io.Reader) (request Request, err error) {
+	line, err := bufio.NewReader(io.LimitReader(conn, maxRequestLength)).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return request, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == PluginRequest {
+		return readPluginRequest(fields[1:])
+	}
+
+	request.Section = strings.TrimSpace(line)
+	if request.Section == "" {
+		request.Section = SectionAll
+	}
+	if !IsSection(request.Section) {
+		return request, fmt.Errorf("unknown section %v, valid sections are %v", request.Section, strings.Join(Sections, ", "))
+	}
+	return request, nil
+}
+
+// readPluginRequest validates the action requested on the long running plugins and its arguments
+func readPluginRequest(arguments []string) (request Request, err error) {
+	if len(arguments) == 0 {
+		return request, fmt.Errorf("missing plugin action, valid actions are %v", strings.Join(PluginActions, ", "))
+	}
+	request.PluginAction = arguments[0]
+	switch request.PluginAction {
+	case PluginActionList:
+		if len(arguments) != 1 {
+			return request, fmt.Errorf("plugin action %v takes no argument", request.PluginAction)
+		}
+	case PluginActionStart, PluginActionStop, PluginActionRestart, PluginActionHealth:
+		if len(arguments) != 2 {
+			return request, fmt.Errorf("plugin action %v takes the name of a plugin", request.PluginAction)
+		}
+		request.PluginName = arguments[1]
+	default:
+		return request, fmt.Errorf("unknown plugin action %v, valid actions are %v", request.PluginAction, strings.Join(PluginActions, ", "))
+	}
+	return request, nil
+}
+
+// WriteResponse writes the status, or the error, to the client.
+func WriteResponse(conn io.Writer, status interface{}, statusErr error) error {
+	if statusErr != nil {
+		status = errorResponse{Error: statusErr.Error()}
+	}
+	content, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(content)
+	return err
+}
+
+// Query requests a section of the status from the agent and returns it in JSON format.
+func Query(section string) (string, error) {
+	return send(section)
+}
+
+// ControlPlugin requests an action on a long running plugin from the agent and returns the result in JSON format.
+func ControlPlugin(action string, name string) (string, error) {
+	return send(strings.TrimSpace(strings.Join([]string{PluginRequest, action, name}, " ")))
+}
+
+// send sends a request to the agent and returns its response, or the error it answered with
+func send(request string) (string, error) {
+	conn, err := dial(requestTimeout)
+	if err != nil {
+		return "", fmt.Errorf("unable to connect to the agent, make sure it is running and the command is run as an administrator: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte(request + "\n")); err != nil {
+		return "", err
+	}
+	content, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+
+	var failure errorResponse
+	if json.Unmarshal(content, &failure) == nil && failure.Error != "" {
+		return "", errors.New(failure.Error)
+	}
+	return string(content), nil
+}
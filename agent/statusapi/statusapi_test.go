@@ -0,0 +1,83 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statusapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordPoll(t *testing.T) {
+	RecordPoll(errors.New("connection refused"))
+	status := Connectivity()
+	assert.Equal(t, ConnectivityDisconnected, status.State)
+	assert.Equal(t, "connection refused", status.LastError)
+	assert.NotNil(t, status.LastPollTime)
+
+	RecordPoll(nil)
+	status = Connectivity()
+	assert.Equal(t, ConnectivityConnected, status.State)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, status.LastPollTime, status.LastSuccessfulPollTime)
+}
+
+func TestReadRequest(t *testing.T) {
+	testCases := []struct {
+		request  string
+		expected Request
+		hasError bool
+	}{
+		{"", Request{Section: SectionAll}, false},
+		{"\n", Request{Section: SectionAll}, false},
+		{"documents\n", Request{Section: SectionDocuments}, false},
+		{" update \r\n", Request{Section: SectionUpdate}, false},
+		{"retries\n", Request{Section: SectionRetries}, false},
+		{"long-running-plugins", Request{Section: SectionLongRunningPlugins}, false},
+		{"unknown\n", Request{Section: "unknown"}, true},
+		{"plugin list\n", Request{PluginAction: PluginActionList}, false},
+		{"plugin restart aws:cloudWatch\n", Request{PluginAction: PluginActionRestart, PluginName: "aws:cloudWatch"}, false},
+		{"plugin health\n", Request{PluginAction: PluginActionHealth}, true},
+		{"plugin list aws:cloudWatch\n", Request{PluginAction: PluginActionList}, true},
+		{"plugin remove aws:cloudWatch\n", Request{PluginAction: "remove"}, true},
+		{"plugin\n", Request{}, true},
+	}
+	for _, testCase := range testCases {
+		request, err := ReadRequest(strings.NewReader(testCase.request))
+		assert.Equal(t, testCase.expected, request, testCase.request)
+		assert.Equal(t, testCase.hasError, err != nil, testCase.request)
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteResponse(&buffer, UpdateStatus{State: "NotStarted"}, nil)
+	assert.NoError(t, err)
+
+	var update UpdateStatus
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &update))
+	assert.Equal(t, "NotStarted", update.State)
+
+	buffer.Reset()
+	err = WriteResponse(&buffer, UpdateStatus{}, errors.New("failed"))
+	assert.NoError(t, err)
+
+	var failure errorResponse
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &failure))
+	assert.Equal(t, "failed", failure.Error)
+}
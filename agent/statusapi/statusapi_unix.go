@@ -0,0 +1,72 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package statusapi
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// socketPath is the unix socket of the status API, a variable for the unit tests
+var socketPath = appconfig.StatusAPIAddress
+
+type socketListener struct {
+	net.Listener
+}
+
+// Accept waits for the next client, which has to send its request before the request timeout.
+func (l socketListener) Accept() (io.ReadWriteCloser, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+	return conn, nil
+}
+
+// Listen creates the unix socket of the status API, which only root can connect to.
+func Listen() (Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return nil, err
+	}
+	// remove the socket left by an agent which did not stop cleanly
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return socketListener{listener}, nil
+}
+
+func dial(timeout time.Duration) (io.ReadWriteCloser, error) {
+	conn, err := net.DialTimeout("unix", socketPath, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	return conn, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package statusapi
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statusapi")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(path string) { socketPath = path }(socketPath)
+	socketPath = filepath.Join(dir, "ipc", "status.sock")
+
+	listener, err := Listen()
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Stat(socketPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			request, err := ReadRequest(conn)
+			if err == nil && request.Section != SectionUpdate {
+				err = errors.New("unexpected section " + request.Section)
+			}
+			WriteResponse(conn, UpdateStatus{State: "NotStarted"}, err)
+			conn.Close()
+		}
+	}()
+
+	response, err := Query(SectionUpdate)
+	assert.NoError(t, err)
+	assert.Contains(t, response, `"State": "NotStarted"`)
+
+	_, err = Query(SectionDocuments)
+	assert.EqualError(t, err, "unexpected section documents")
+}
@@ -0,0 +1,193 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package statusapi
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	pipeAccessDuplex        = 0x3
+	pipeTypeByte            = 0x0
+	pipeWait                = 0x0
+	pipeRejectRemoteClients = 0x8
+	pipeUnlimitedInstances  = 255
+	pipeBufferSize          = 64 * 1024
+	sddlRevision1           = 1
+
+	errorPipeBusy      = syscall.Errno(231)
+	errorPipeConnected = syscall.Errno(535)
+
+	// pipeSecurityDescriptor only grants access to LocalSystem and the administrators
+	pipeSecurityDescriptor = "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+
+	// dialRetryInterval is the time waited for an instance of the pipe when all of them are busy
+	dialRetryInterval = 100 * time.Millisecond
+)
+
+var (
+	modkernel32 = syscall.NewLazyDLL("kernel32.dll")
+	modadvapi32 = syscall.NewLazyDLL("advapi32.dll")
+
+	procCreateNamedPipeW = modkernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = modkernel32.NewProc("ConnectNamedPipe")
+
+	procConvertStringSecurityDescriptorToSecurityDescriptorW = modadvapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+)
+
+// pipeName is the named pipe of the status API, a variable for the unit tests
+var pipeName = appconfig.StatusAPIAddress
+
+var errListenerClosed = errors.New("status API listener is closed")
+
+// pipeListener creates a new instance of the named pipe for each client.
+type pipeListener struct {
+	name               string
+	securityAttributes *syscall.SecurityAttributes
+	lock               sync.Mutex
+	closed             bool
+}
+
+// pipeConn is the server end of a named pipe, the pipe is flushed before it is closed
+// so that the client can read the whole response.
+type pipeConn struct {
+	*os.File
+}
+
+func (c pipeConn) Close() error {
+	syscall.FlushFileBuffers(syscall.Handle(c.Fd()))
+	return c.File.Close()
+}
+
+// Listen creates the named pipe of the status API, which only administrators can connect to.
+func Listen() (Listener, error) {
+	securityAttributes, err := securityAttributesFromSDDL(pipeSecurityDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	return &pipeListener{name: pipeName, securityAttributes: securityAttributes}, nil
+}
+
+// Accept waits for the next client.
+func (l *pipeListener) Accept() (io.ReadWriteCloser, error) {
+	if l.isClosed() {
+		return nil, errListenerClosed
+	}
+	handle, err := createNamedPipe(l.name, l.securityAttributes)
+	if err != nil {
+		return nil, err
+	}
+	if r, _, callErr := procConnectNamedPipe.Call(uintptr(handle), 0); r == 0 && callErr != errorPipeConnected {
+		syscall.CloseHandle(handle)
+		return nil, callErr
+	}
+	if l.isClosed() {
+		syscall.CloseHandle(handle)
+		return nil, errListenerClosed
+	}
+	return pipeConn{os.NewFile(uintptr(handle), l.name)}, nil
+}
+
+// Close stops the listener, the pending Accept is released by connecting to the pipe.
+func (l *pipeListener) Close() error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.lock.Unlock()
+
+	if conn, err := openPipe(l.name); err == nil {
+		conn.Close()
+	}
+	syscall.LocalFree(syscall.Handle(l.securityAttributes.SecurityDescriptor))
+	return nil
+}
+
+func (l *pipeListener) isClosed() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.closed
+}
+
+func createNamedPipe(name string, securityAttributes *syscall.SecurityAttributes) (syscall.Handle, error) {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	handle, _, callErr := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		pipeAccessDuplex,
+		pipeTypeByte|pipeWait|pipeRejectRemoteClients,
+		pipeUnlimitedInstances,
+		pipeBufferSize,
+		pipeBufferSize,
+		0,
+		uintptr(unsafe.Pointer(securityAttributes)))
+	if syscall.Handle(handle) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, callErr
+	}
+	return syscall.Handle(handle), nil
+}
+
+func securityAttributesFromSDDL(sddl string) (*syscall.SecurityAttributes, error) {
+	sddlPtr, err := syscall.UTF16PtrFromString(sddl)
+	if err != nil {
+		return nil, err
+	}
+	var securityDescriptor uintptr
+	if r, _, callErr := procConvertStringSecurityDescriptorToSecurityDescriptorW.Call(
+		uintptr(unsafe.Pointer(sddlPtr)),
+		sddlRevision1,
+		uintptr(unsafe.Pointer(&securityDescriptor)),
+		0); r == 0 {
+		return nil, callErr
+	}
+	securityAttributes := &syscall.SecurityAttributes{SecurityDescriptor: securityDescriptor}
+	securityAttributes.Length = uint32(unsafe.Sizeof(*securityAttributes))
+	return securityAttributes, nil
+}
+
+func openPipe(name string) (*os.File, error) {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := syscall.CreateFile(namePtr, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(handle), name), nil
+}
+
+func dial(timeout time.Duration) (conn io.ReadWriteCloser, err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if conn, err = openPipe(pipeName); err != errorPipeBusy || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(dialRetryInterval)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"sync"
+)
+
+// Limiter limits how many jobs run at the same time, possibly across several pools.
+// The jobs waiting for a slot get it in the order they asked for it.
+type Limiter struct {
+	mut     sync.Mutex
+	limit   int
+	running int
+	waiting []chan struct{}
+}
+
+// NewLimiter creates a limiter allowing limit jobs to run at the same time, a limit of 0 or less does not limit them.
+func NewLimiter(limit int) *Limiter {
+	return &Limiter{limit: limit}
+}
+
+// Acquire blocks until a slot is free for the job or the job is canceled or shut down.
+// Returns true if the slot was acquired, in which case the caller must Release it once the job is done.
+func (l *Limiter) Acquire(cancelFlag CancelFlag) bool {
+	l.mut.Lock()
+	if l.limit <= 0 || (l.running < l.limit && len(l.waiting) == 0) {
+		l.running++
+		l.mut.Unlock()
+		return true
+	}
+	slot := make(chan struct{})
+	l.waiting = append(l.waiting, slot)
+	l.mut.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		cancelFlag.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-slot:
+		return true
+	case <-stopped:
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	for i, waiting := range l.waiting {
+		if waiting == slot {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			return false
+		}
+	}
+	// the slot was handed over while the job was stopping, pass it on
+	l.release()
+	return false
+}
+
+// Release frees the slot of a job, starting the job which has been waiting the longest.
+func (l *Limiter) Release() {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.release()
+}
+
+// Waiting returns the number of jobs waiting for a slot.
+func (l *Limiter) Waiting() int {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return len(l.waiting)
+}
+
+// release frees a slot, the lock must be held by the caller.
+func (l *Limiter) release() {
+	if len(l.waiting) > 0 {
+		close(l.waiting[0])
+		l.waiting = l.waiting[1:]
+		return
+	}
+	l.running--
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForWaiting waits until the given number of jobs wait for a slot of the limiter
+func waitForWaiting(t *testing.T, limiter *Limiter, count int) {
+	for i := 0; i < 100 && limiter.Waiting() != count; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, count, limiter.Waiting())
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	limiter := NewLimiter(0)
+	for i := 0; i < 10; i++ {
+		assert.True(t, limiter.Acquire(NewChanneledCancelFlag()))
+	}
+}
+
+func TestLimiterArrivalOrder(t *testing.T) {
+	limiter := NewLimiter(1)
+	assert.True(t, limiter.Acquire(NewChanneledCancelFlag()))
+
+	started := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(job int) {
+			if limiter.Acquire(NewChanneledCancelFlag()) {
+				started <- job
+			}
+		}(i)
+		waitForWaiting(t, limiter, i+1)
+	}
+
+	for i := 0; i < 3; i++ {
+		limiter.Release()
+		assert.Equal(t, i, <-started)
+	}
+}
+
+func TestLimiterCanceledWhileWaiting(t *testing.T) {
+	limiter := NewLimiter(1)
+	assert.True(t, limiter.Acquire(NewChanneledCancelFlag()))
+
+	cancelFlag := NewChanneledCancelFlag()
+	acquired := make(chan bool)
+	go func() {
+		acquired <- limiter.Acquire(cancelFlag)
+	}()
+	waitForWaiting(t, limiter, 1)
+
+	cancelFlag.Set(Canceled)
+	assert.False(t, <-acquired)
+	assert.Equal(t, 0, limiter.Waiting())
+
+	limiter.Release()
+	assert.True(t, limiter.Acquire(NewChanneledCancelFlag()))
+}
@@ -24,11 +24,12 @@ import (
 
 // Pool is a pool of jobs.
 type Pool interface {
-	// Submit schedules a job to be executed in the associated worker pool.
-	// Returns an error if a job with the same name already exists.
+	// Submit schedules a job to be executed in the associated worker pool, without waiting for a worker to be free.
+	// Returns an error if a job with the same name already exists or if the pool is shut down.
 	Submit(log log.T, jobID string, job Job) error
 
-	// Cancel cancels the given job. Jobs that have not started yet will never be started.
+	// Cancel cancels the given job. Jobs that have not started yet are started with their CancelFlag
+	// already set to the Canceled state so that they can report their cancellation right away.
 	// Jobs that are running will have their CancelFlag set to the Canceled state.
 	// It is the responsibility of the job to terminate within a reasonable time.
 	// If the job fails to terminate after a Cancel, the job may be abandoned.
@@ -51,6 +52,8 @@ type Pool interface {
 type pool struct {
 	log            log.T
 	jobQueue       chan JobToken
+	pending        []JobToken
+	pendingCond    *sync.Cond
 	nWorkers       int
 	doneWorker     chan struct{}
 	isShutdown     bool
@@ -82,6 +85,7 @@ func NewPool(log log.T, maxParallel int, cancelWaitDuration time.Duration, clock
 	}
 
 	p.jobStore = NewJobStore()
+	p.pendingCond = sync.NewCond(&p.mut)
 
 	// defines the job processing function.
 	processor := func(j JobToken) {
@@ -91,24 +95,25 @@ func NewPool(log log.T, maxParallel int, cancelWaitDuration time.Duration, clock
 
 	// start the workers
 	p.start(processor)
+	go p.dispatch()
 
 	return p
 }
 
 // Shutdown cancels all the jobs in this pool and shuts down the workers.
 func (p *pool) Shutdown() {
-	// ShutDown and delete all jobs
-	p.ShutDownAll()
-
 	p.mut.Lock()
-	defer p.mut.Unlock()
 	if !p.isShutdown {
-		// close the channel to makes all workers terminate once the pending
-		// jobs have been consumed (the pending jobs are in the Canceled state
+		// the dispatcher closes the channel to make all workers terminate once the pending
+		// jobs have been consumed (the pending jobs are in the ShutDown state
 		// so they will simply be discarded)
-		close(p.jobQueue)
 		p.isShutdown = true
+		p.pendingCond.Broadcast()
 	}
+	p.mut.Unlock()
+
+	// ShutDown and delete all jobs, no job can be submitted anymore
+	p.ShutDownAll()
 }
 
 // ShutdownAndWait calls Shutdown then waits until all the workers have exited
@@ -153,6 +158,26 @@ func (p *pool) start(jobProcessor func(JobToken)) {
 	}
 }
 
+// dispatch hands the submitted jobs to the workers in submission order, so that Submit never waits for a free worker
+func (p *pool) dispatch() {
+	for {
+		p.mut.Lock()
+		for len(p.pending) == 0 && !p.isShutdown {
+			p.pendingCond.Wait()
+		}
+		if len(p.pending) == 0 {
+			p.mut.Unlock()
+			close(p.jobQueue)
+			return
+		}
+		token := p.pending[0]
+		p.pending = p.pending[1:]
+		p.mut.Unlock()
+
+		p.jobQueue <- token
+	}
+}
+
 // workerDone signals that a worker has terminated.
 func (p *pool) workerDone() {
 	p.doneWorker <- struct{}{}
@@ -161,7 +186,7 @@ func (p *pool) workerDone() {
 // worker processes jobs from a channel.
 func worker(workerName string, queue chan JobToken, processor func(JobToken)) {
 	for token := range queue {
-		if !token.cancelFlag.Canceled() {
+		if !token.cancelFlag.ShutDown() {
 			processor(token)
 		}
 	}
@@ -175,11 +200,17 @@ func (p *pool) Submit(log log.T, jobID string, job Job) (err error) {
 		cancelFlag: NewChanneledCancelFlag(),
 		log:        log,
 	}
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if p.isShutdown {
+		return fmt.Errorf("pool is shut down, job %v is not submitted", jobID)
+	}
 	err = p.jobStore.AddJob(jobID, &token)
 	if err != nil {
 		return
 	}
-	p.jobQueue <- token
+	p.pending = append(p.pending, token)
+	p.pendingCond.Signal()
 	return
 }
 
@@ -119,3 +119,30 @@ func exercisePool(t *testing.T, pool Pool, jobID string, shouldCancel bool) {
 	// see that job completes
 	assert.True(t, <-jobState)
 }
+
+func TestPoolSubmitDoesNotWaitForWorkers(t *testing.T) {
+	clock := times.NewMockedClock()
+	pool := NewPool(logger, 1, 100*time.Millisecond, clock)
+
+	release := make(chan bool)
+	assert.Nil(t, pool.Submit(logger, "running", func(CancelFlag) { <-release }))
+
+	// the second job waits for the worker, submitting a third one does not block
+	states := make(chan State, 2)
+	assert.Nil(t, pool.Submit(logger, "queued", func(cancelFlag CancelFlag) { states <- cancelFlag.State() }))
+	assert.Nil(t, pool.Submit(logger, "canceled", func(cancelFlag CancelFlag) { states <- cancelFlag.State() }))
+
+	// a queued job which is canceled is still started so that it can report its cancellation
+	assert.True(t, pool.Cancel("canceled"))
+	release <- true
+	assert.Equal(t, State(0), <-states)
+	assert.Equal(t, Canceled, <-states)
+}
+
+func TestPoolSubmitAfterShutdown(t *testing.T) {
+	clock := times.NewMockedClock()
+	pool := NewPool(logger, 1, 100*time.Millisecond, clock)
+	pool.Shutdown()
+
+	assert.NotNil(t, pool.Submit(logger, "job", func(CancelFlag) {}))
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testharness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+// AgentBinaryEnvVariable is the environment variable holding the path of the agent binary used by the end-to-end tests
+const AgentBinaryEnvVariable = "SSM_AGENT_BINARY"
+
+// stopTimeout is how long the agent is given to shut down before it is killed
+const stopTimeout = 30 * time.Second
+
+// Agent is an agent process connected to a mock service.
+// The agent reads its configuration from the fixed location of the platform,
+// running it requires the same privileges as the installed agent.
+type Agent struct {
+	cmd           *exec.Cmd
+	output        lockedBuffer
+	done          chan error
+	restoreConfig func() error
+}
+
+// StartAgent writes an agent configuration pointing all endpoints to the mock service and starts the agent binary.
+// The original configuration is restored when the agent is stopped.
+func StartAgent(binary string, region string, service *MockService) (agent *Agent, err error) {
+	agent = &Agent{done: make(chan error, 1)}
+	if agent.restoreConfig, err = writeAppConfig(service.URL(), region); err != nil {
+		return nil, err
+	}
+
+	agent.cmd = exec.Command(binary, "-i", service.InstanceID, "-r", region)
+	agent.cmd.Stdout = &agent.output
+	agent.cmd.Stderr = &agent.output
+	// the mock service does not verify signatures, static credentials avoid calls to the instance metadata
+	agent.cmd.Env = append(os.Environ(),
+		"AWS_ACCESS_KEY_ID=AKIAHARNESS",
+		"AWS_SECRET_ACCESS_KEY=harness",
+	)
+	if err = agent.cmd.Start(); err != nil {
+		agent.restoreConfig()
+		return nil, fmt.Errorf("failed to start agent %v: %v", binary, err)
+	}
+	go func() { agent.done <- agent.cmd.Wait() }()
+	return agent, nil
+}
+
+// Output returns what the agent wrote to its console so far.
+func (a *Agent) Output() string {
+	return a.output.String()
+}
+
+// Stop interrupts the agent, kills it if it does not exit in time and restores the original configuration.
+func (a *Agent) Stop() (err error) {
+	defer func() {
+		if restoreErr := a.restoreConfig(); err == nil {
+			err = restoreErr
+		}
+	}()
+
+	if err = interrupt(a.cmd.Process); err != nil {
+		a.cmd.Process.Kill()
+	}
+	select {
+	case <-a.done:
+		return nil
+	case <-time.After(stopTimeout):
+		a.cmd.Process.Kill()
+		<-a.done
+		return fmt.Errorf("agent did not stop within %v", stopTimeout)
+	}
+}
+
+// writeAppConfig replaces the agent configuration and returns a function restoring the previous one.
+func writeAppConfig(endpoint string, region string) (restore func() error, err error) {
+	config := appconfig.DefaultConfig()
+	config.Mds.Endpoint = endpoint
+	config.Ssm.Endpoint = endpoint
+	config.S3.Endpoint = endpoint
+	config.Agent.Region = region
+
+	content, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	path := appconfig.AppConfigPath
+	original, readErr := ioutil.ReadFile(path)
+	if err = fileutil.MakeDirs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(path, content, appconfig.ReadWriteAccess); err != nil {
+		return nil, fmt.Errorf("failed to write agent configuration %v: %v", path, err)
+	}
+
+	return func() error {
+		if readErr != nil {
+			return os.Remove(path)
+		}
+		return ioutil.WriteFile(path, original, appconfig.ReadWriteAccess)
+	}, nil
+}
+
+// lockedBuffer is a buffer safe for concurrent writes by the process and reads by the test
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
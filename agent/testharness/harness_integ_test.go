@@ -0,0 +1,60 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build integration
+
+package testharness
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunShellScriptEndToEnd sends a document to the agent binary named by SSM_AGENT_BINARY and checks its reply.
+func TestRunShellScriptEndToEnd(t *testing.T) {
+	binary := os.Getenv(AgentBinaryEnvVariable)
+	if binary == "" {
+		t.Skipf("%v is not set", AgentBinaryEnvVariable)
+	}
+
+	mock := NewMockService("i-0123456789abcdef0")
+	defer mock.Close()
+	agent, err := StartAgent(binary, "us-east-1", mock)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer agent.Stop()
+
+	document := contracts.DocumentContent{
+		SchemaVersion: "2.2",
+		MainSteps: []*contracts.InstancePluginConfig{{
+			Action: "aws:runShellScript",
+			Name:   "echo",
+			Inputs: map[string]interface{}{"runCommand": []string{"echo harness"}},
+		}},
+	}
+	commandID, err := mock.SendCommand(document, nil)
+	assert.NoError(t, err)
+
+	reply, err := mock.WaitForCompletion(commandID, 2*time.Minute)
+	if !assert.NoError(t, err, agent.Output()) {
+		return
+	}
+	assert.Equal(t, contracts.ResultStatusSuccess, reply.DocumentStatus)
+	assert.True(t, strings.Contains(reply.RuntimeStatus["echo"].Output, "harness"))
+}
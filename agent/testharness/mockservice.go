@@ -0,0 +1,353 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package testharness stands up an in-process mock of the services the agent talks to
+// and drives the agent binary through document scenarios for end-to-end tests.
+package testharness
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// mdsTargetPrefix is the target prefix of the json requests sent to the message delivery service
+	mdsTargetPrefix = "EC2WindowsMessageDeliveryService"
+	// ssmTargetPrefix is the target prefix of the json requests sent to ssm
+	ssmTargetPrefix = "AmazonSSM"
+
+	sendCommandTopic   = "aws.ssm.sendCommand.harness"
+	cancelCommandTopic = "aws.ssm.cancelCommand.harness"
+)
+
+// GetMessagesWait is how long GetMessages waits for a message before returning an empty response
+var GetMessagesWait = time.Second
+
+// MockService is an http server implementing the subset of MDS, SSM and S3 used by the agent.
+// Requests are not authenticated, any credentials can be configured on the agent.
+type MockService struct {
+	InstanceID string
+
+	server   *httptest.Server
+	mu       sync.Mutex
+	notify   chan struct{}
+	pending  []*ssmmds.Message
+	acked    map[string]bool
+	replies  map[string][]messageContracts.SendReplyPayload
+	failures map[string]string
+	calls    map[string]int
+	objects  map[string][]byte
+}
+
+// NewMockService starts a mock service delivering messages to the given instance.
+func NewMockService(instanceID string) *MockService {
+	m := &MockService{
+		InstanceID: instanceID,
+		notify:     make(chan struct{}, 1),
+		acked:      make(map[string]bool),
+		replies:    make(map[string][]messageContracts.SendReplyPayload),
+		failures:   make(map[string]string),
+		calls:      make(map[string]int),
+		objects:    make(map[string][]byte),
+	}
+	m.server = httptest.NewServer(m)
+	return m
+}
+
+// URL returns the endpoint to configure for MDS, SSM and S3.
+func (m *MockService) URL() string {
+	return m.server.URL
+}
+
+// Close shuts the server down.
+func (m *MockService) Close() {
+	m.server.Close()
+}
+
+// SendCommand queues a send command message for the agent and returns the command id.
+func (m *MockService) SendCommand(document contracts.DocumentContent, parameters map[string]interface{}) (commandID string, err error) {
+	commandID = uuid.NewV4().String()
+	payload := messageContracts.SendCommandPayload{
+		CommandID:       commandID,
+		DocumentName:    "Harness-" + commandID,
+		DocumentContent: document,
+		Parameters:      parameters,
+	}
+	if err = m.queue(sendCommandTopic, commandID, payload); err != nil {
+		return "", err
+	}
+	return commandID, nil
+}
+
+// CancelCommand queues a cancel command message for a command sent earlier.
+func (m *MockService) CancelCommand(commandID string) error {
+	payload := messageContracts.CancelPayload{CancelMessageID: m.messageID(commandID)}
+	return m.queue(cancelCommandTopic, uuid.NewV4().String(), payload)
+}
+
+// Replies returns the replies the agent sent for a command, in the order they were received.
+func (m *MockService) Replies(commandID string) []messageContracts.SendReplyPayload {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]messageContracts.SendReplyPayload(nil), m.replies[commandID]...)
+}
+
+// WaitForCompletion waits until the agent reports a final status for the command and returns that reply.
+func (m *MockService) WaitForCompletion(commandID string, timeout time.Duration) (reply messageContracts.SendReplyPayload, err error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		failure, failed := m.failures[commandID]
+		replies := m.replies[commandID]
+		m.mu.Unlock()
+
+		if failed {
+			return reply, fmt.Errorf("agent failed the message of command %v: %v", commandID, failure)
+		}
+		if len(replies) > 0 {
+			if reply = replies[len(replies)-1]; isFinal(reply.DocumentStatus) {
+				return reply, nil
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return reply, fmt.Errorf("command %v did not complete within %v", commandID, timeout)
+}
+
+// Acknowledged returns whether the agent acknowledged the message of a command.
+func (m *MockService) Acknowledged(commandID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.acked[m.messageID(commandID)]
+}
+
+// Calls returns how many times an operation (e.g. "UpdateInstanceInformation" or "PutObject") was called.
+func (m *MockService) Calls(operation string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[operation]
+}
+
+// Object returns the content of an object uploaded to the mock S3 with a path style request.
+func (m *MockService) Object(bucket, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	content, found := m.objects[bucket+"/"+key]
+	return content, found
+}
+
+// ServeHTTP dispatches json requests by their target and handles the other requests as S3 requests.
+func (m *MockService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target := r.Header.Get("X-Amz-Target")
+	if target == "" {
+		m.serveS3(w, r, body)
+		return
+	}
+	parts := strings.SplitN(target, ".", 2)
+	if len(parts) != 2 {
+		http.Error(w, "invalid target "+target, http.StatusBadRequest)
+		return
+	}
+	m.record(parts[1])
+
+	var response interface{}
+	switch parts[0] {
+	case mdsTargetPrefix:
+		response, err = m.serveMds(parts[1], body)
+	case ssmTargetPrefix:
+		// instance information, associations and compliance are accepted without any data
+		response = struct{}{}
+	default:
+		err = fmt.Errorf("unsupported target %v", target)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(response)
+}
+
+// serveMds implements the message delivery service operations.
+func (m *MockService) serveMds(operation string, body []byte) (interface{}, error) {
+	switch operation {
+	case "GetMessages":
+		var input ssmmds.GetMessagesInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			return nil, err
+		}
+		return &ssmmds.GetMessagesOutput{
+			Destination:       input.Destination,
+			MessagesRequestId: input.MessagesRequestId,
+			Messages:          m.nextMessages(),
+		}, nil
+	case "AcknowledgeMessage":
+		var input ssmmds.AcknowledgeMessageInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		m.acked[aws.StringValue(input.MessageId)] = true
+		m.mu.Unlock()
+	case "SendReply":
+		var input ssmmds.SendReplyInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			return nil, err
+		}
+		var payload messageContracts.SendReplyPayload
+		if err := json.Unmarshal([]byte(aws.StringValue(input.Payload)), &payload); err != nil {
+			return nil, err
+		}
+		commandID, err := messageContracts.GetCommandID(aws.StringValue(input.MessageId))
+		if err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		m.replies[commandID] = append(m.replies[commandID], payload)
+		m.mu.Unlock()
+	case "FailMessage":
+		var input ssmmds.FailMessageInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			return nil, err
+		}
+		if commandID, err := messageContracts.GetCommandID(aws.StringValue(input.MessageId)); err == nil {
+			m.mu.Lock()
+			m.failures[commandID] = aws.StringValue(input.FailureType)
+			m.mu.Unlock()
+		}
+	case "DeleteMessage":
+	case "GetEndpoint":
+		return &ssmmds.GetEndpointOutput{Endpoint: aws.String(m.server.URL)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operation %v", operation)
+	}
+	return struct{}{}, nil
+}
+
+// serveS3 stores and returns objects addressed with path style requests.
+func (m *MockService) serveS3(w http.ResponseWriter, r *http.Request, body []byte) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPut:
+		m.record("PutObject")
+		m.mu.Lock()
+		m.objects[key] = body
+		m.mu.Unlock()
+	case http.MethodGet:
+		m.record("GetObject")
+		if content, found := m.Object(splitObjectKey(key)); found {
+			w.Write(content)
+		} else {
+			http.NotFound(w, r)
+		}
+	default:
+		m.record(r.Method)
+	}
+}
+
+// queue adds a message for the instance and wakes up a pending GetMessages request.
+func (m *MockService) queue(topic string, commandID string, payload interface{}) error {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(content)
+
+	m.mu.Lock()
+	m.pending = append(m.pending, &ssmmds.Message{
+		CreatedDate:   aws.String(times.ToIso8601UTC(time.Now())),
+		Destination:   aws.String(m.InstanceID),
+		MessageId:     aws.String(m.messageID(commandID)),
+		Payload:       aws.String(string(content)),
+		PayloadDigest: aws.String(hex.EncodeToString(digest[:])),
+		Topic:         aws.String(topic),
+	})
+	m.mu.Unlock()
+
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// nextMessages returns the pending messages, waiting up to GetMessagesWait for one to be queued.
+func (m *MockService) nextMessages() []*ssmmds.Message {
+	timer := time.NewTimer(GetMessagesWait)
+	defer timer.Stop()
+	for {
+		m.mu.Lock()
+		messages := m.pending
+		m.pending = nil
+		m.mu.Unlock()
+		if len(messages) > 0 {
+			return messages
+		}
+
+		select {
+		case <-m.notify:
+		case <-timer.C:
+			return []*ssmmds.Message{}
+		}
+	}
+}
+
+func (m *MockService) messageID(commandID string) string {
+	return fmt.Sprintf("aws.ssm.%v.%v", commandID, m.InstanceID)
+}
+
+func (m *MockService) record(operation string) {
+	m.mu.Lock()
+	m.calls[operation]++
+	m.mu.Unlock()
+}
+
+// splitObjectKey splits a path style key into the bucket name and the object key
+func splitObjectKey(path string) (bucket, key string) {
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// isFinal returns whether the document status is not going to change anymore
+func isFinal(status contracts.ResultStatus) bool {
+	switch status {
+	case "", contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testharness
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testInstanceID = "i-0123456789abcdef0"
+	testRegion     = "us-east-1"
+)
+
+// TestMockServiceWithMdsClient verifies the mock service speaks the protocol of the agent's MDS client.
+func TestMockServiceWithMdsClient(t *testing.T) {
+	logger := log.NewMockLog()
+	platform.SetRegion(testRegion)
+	GetMessagesWait = 10 * time.Millisecond
+
+	mock := NewMockService(testInstanceID)
+	defer mock.Close()
+	client := mdsService.NewService(testRegion, mock.URL(), credentials.NewStaticCredentials("id", "secret", ""), time.Second)
+
+	document := contracts.DocumentContent{SchemaVersion: "2.2"}
+	commandID, err := mock.SendCommand(document, map[string]interface{}{"commands": []string{"echo hello"}})
+	assert.NoError(t, err)
+
+	output, err := client.GetMessages(logger, testInstanceID)
+	assert.NoError(t, err)
+	assert.Len(t, output.Messages, 1)
+	message := output.Messages[0]
+	assert.Equal(t, testInstanceID, *message.Destination)
+
+	var payload messageContracts.SendCommandPayload
+	assert.NoError(t, json.Unmarshal([]byte(*message.Payload), &payload))
+	assert.Equal(t, commandID, payload.CommandID)
+
+	assert.NoError(t, client.AcknowledgeMessage(logger, *message.MessageId))
+	assert.True(t, mock.Acknowledged(commandID))
+
+	reply, _ := json.Marshal(messageContracts.SendReplyPayload{DocumentStatus: contracts.ResultStatusSuccess})
+	assert.NoError(t, client.SendReply(logger, *message.MessageId, string(reply)))
+
+	final, err := mock.WaitForCompletion(commandID, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.ResultStatusSuccess, final.DocumentStatus)
+
+	// queued messages are only delivered once
+	output, err = client.GetMessages(logger, testInstanceID)
+	assert.NoError(t, err)
+	assert.Empty(t, output.Messages)
+	assert.Equal(t, 2, mock.Calls("GetMessages"))
+}
@@ -92,10 +92,13 @@ type UpdateDetail struct {
 	TargetHash         string                 `json:"TargetHash"`
 	PackageName        string                 `json:"PackageName"`
 	StartDateTime      time.Time              `json:"StartDateTime"`
+	InstallDateTime    time.Time              `json:"InstallDateTime"`
 	EndDateTime        time.Time              `json:"EndDateTime"`
 	MessageID          string                 `json:"MessageId"`
 	UpdateRoot         string                 `json:"UpdateRoot"`
 	RequiresUninstall  bool                   `json:"RequiresUninstall"`
+	RollbackErrorCode  updateutil.ErrorCode   `json:"RollbackErrorCode,omitempty"`
+	VerificationPolicy string                 `json:"VerificationPolicy,omitempty"`
 }
 
 // UpdateContext holds the book keeping details for Update context
@@ -17,6 +17,7 @@ package processor
 
 import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/health/checkpoint"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 )
@@ -39,6 +40,7 @@ type verify func(mgr *updateManager, log log.T, context *UpdateContext, isRollba
 type rollback func(mgr *updateManager, log log.T, context *UpdateContext) (err error)
 type uninstall func(mgr *updateManager, log log.T, version string, context *UpdateContext) (err error)
 type install func(mgr *updateManager, log log.T, version string, context *UpdateContext) (err error)
+type healthCheck func(mgr *updateManager, log log.T, context *UpdateContext) (missing []checkpoint.Check)
 type download func(mgr *updateManager, log log.T, downloadInput artifact.DownloadInput, context *UpdateContext, version string) (err error)
 
 type updateManager struct {
@@ -52,6 +54,8 @@ type updateManager struct {
 	uninstall uninstall
 	install   install
 	download  download
+	// healthCheck waits for the updated agent to pass its health checks
+	healthCheck healthCheck
 }
 
 // Updater contains logic for performing agent update
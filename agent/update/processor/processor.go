@@ -21,34 +21,46 @@ import (
 
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/health/checkpoint"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 )
 
+// healthCheckPollInterval is the interval at which the health checks of the updated agent are checked
+var healthCheckPollInterval = 10 * time.Second
+
+// healthCheckpointsMinimumVersion is the first agent version writing the health checkpoints, the older versions
+// being only checked for running after the update
+const healthCheckpointsMinimumVersion = "2.2.494.0"
+
 var minimumSupportedVersions map[string]string
 var once sync.Once
 
 var (
 	downloadArtifact = artifact.Download
-	uncompress       = fileutil.Uncompress
+	uncompress       = fileutil.UncompressStrict
+	verifyArtifact   = verification.Verify
 )
 
 // NewUpdater creates an instance of Updater and other services it requires
 func NewUpdater() *Updater {
 	updater := &Updater{
 		mgr: &updateManager{
-			util:      &updateutil.Utility{},
-			svc:       &svcManager{},
-			ctxMgr:    &contextManager{},
-			prepare:   prepareInstallationPackages,
-			update:    proceedUpdate,
-			verify:    verifyInstallation,
-			rollback:  rollbackInstallation,
-			uninstall: uninstallAgent,
-			install:   installAgent,
-			download:  downloadAndUnzipArtifact,
+			util:        &updateutil.Utility{},
+			svc:         &svcManager{},
+			ctxMgr:      &contextManager{},
+			prepare:     prepareInstallationPackages,
+			update:      proceedUpdate,
+			verify:      verifyInstallation,
+			rollback:    rollbackInstallation,
+			uninstall:   uninstallAgent,
+			install:     installAgent,
+			download:    downloadAndUnzipArtifact,
+			healthCheck: waitForAgentHealth,
 		},
 	}
 
@@ -171,7 +183,7 @@ func prepareInstallationPackages(mgr *updateManager, log log.T, context *UpdateC
 	}
 
 	if err = mgr.download(mgr, log, downloadInput, context, context.Current.SourceVersion); err != nil {
-		return mgr.failed(context, log, updateutil.ErrorInvalidPackage, err.Error(), true)
+		return mgr.failed(context, log, downloadErrorCode(err), err.Error(), true)
 	}
 
 	// Download target
@@ -184,7 +196,7 @@ func prepareInstallationPackages(mgr *updateManager, log log.T, context *UpdateC
 	}
 
 	if err = mgr.download(mgr, log, downloadInput, context, context.Current.TargetVersion); err != nil {
-		return mgr.failed(context, log, updateutil.ErrorInvalidPackage, err.Error(), true)
+		return mgr.failed(context, log, downloadErrorCode(err), err.Error(), true)
 	}
 
 	// Update stdout
@@ -222,6 +234,8 @@ func proceedUpdate(mgr *updateManager, log log.T, context *UpdateContext) (err e
 		}
 	}
 
+	// the updated agent has to pass its health checks after this time
+	context.Current.InstallDateTime = time.Now().UTC()
 	if err = mgr.install(mgr, log, context.Current.TargetVersion, context); err != nil {
 		// Install target failed with err
 		// log the error and initiating rollback to the source version
@@ -298,12 +312,70 @@ func verifyInstallation(mgr *updateManager, log log.T, context *UpdateContext, i
 
 	log.Infof("%v is running", context.Current.PackageName)
 	if !isRollback {
+		if missing := mgr.healthCheck(mgr, log, context); len(missing) > 0 {
+			context.Current.AppendError(log,
+				"failed to update %v to %v, the agent didn't pass the health checks %v",
+				context.Current.PackageName,
+				context.Current.TargetVersion,
+				missing)
+			context.Current.AppendInfo(
+				log,
+				"Initiating rollback %v to %v",
+				context.Current.PackageName,
+				context.Current.SourceVersion)
+			context.Current.RollbackErrorCode = updateutil.ErrorHealthCheckFailed
+			// Update state to rollback
+			if err = mgr.inProgress(context, log, Rollback); err != nil {
+				return err
+			}
+			return mgr.rollback(mgr, log, context)
+		}
 		return mgr.succeeded(context, log)
 	}
 
 	message := fmt.Sprintf("rolledback %v to %v", context.Current.PackageName, context.Current.SourceVersion)
 	log.Infof("message is %v", message)
-	return mgr.failed(context, log, updateutil.ErrorCannotStartService, message, false)
+	code := updateutil.ErrorCannotStartService
+	if context.Current.RollbackErrorCode != "" {
+		code = context.Current.RollbackErrorCode
+	}
+	return mgr.failed(context, log, code, message, false)
+}
+
+// waitForAgentHealth waits for the updated agent to pass all its health checks, returning the checks
+// which weren't passed within the configured time from the installation. Agent versions which don't write
+// the health checkpoints pass once they are running.
+func waitForAgentHealth(mgr *updateManager, log log.T, context *UpdateContext) (missing []checkpoint.Check) {
+	if !writesHealthCheckpoints(context.Current.TargetVersion) {
+		log.Infof("%v %v doesn't report its health checks, skipping them", context.Current.PackageName, context.Current.TargetVersion)
+		return nil
+	}
+	window := time.Duration(appconfig.DefaultUpdateHealthCheckMinutes) * time.Minute
+	if config, err := getAppConfig(false); err == nil {
+		window = time.Duration(config.Agent.UpdateHealthCheckMinutes) * time.Minute
+	}
+	installed := context.Current.InstallDateTime
+	if installed.IsZero() {
+		installed = context.Current.StartDateTime
+	}
+
+	log.Infof("Waiting up to %v for %v %v to pass its health checks", window, context.Current.PackageName, context.Current.TargetVersion)
+	for deadline := installed.Add(window); ; time.Sleep(healthCheckPollInterval) {
+		if missing = checkpoint.MissingSince(installed); len(missing) == 0 {
+			log.Infof("%v %v passed its health checks", context.Current.PackageName, context.Current.TargetVersion)
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Errorf("%v %v didn't pass the health checks %v", context.Current.PackageName, context.Current.TargetVersion, missing)
+			return
+		}
+	}
+}
+
+// writesHealthCheckpoints tells if the agent version records the health checkpoints waited for after an update
+func writesHealthCheckpoints(version string) bool {
+	compareResult, err := updateutil.VersionCompare(version, healthCheckpointsMinimumVersion)
+	return err == nil && compareResult >= 0
 }
 
 // rollbackInstallation rollback installation to the source version
@@ -397,6 +469,14 @@ func installAgent(mgr *updateManager, log log.T, version string, context *Update
 	return nil
 }
 
+// downloadErrorCode returns the error code reported when an installation package couldn't be prepared
+func downloadErrorCode(err error) updateutil.ErrorCode {
+	if _, ok := err.(*updateutil.SignatureError); ok {
+		return updateutil.ErrorInvalidSignature
+	}
+	return updateutil.ErrorInvalidPackage
+}
+
 // downloadAndUnzipArtifact downloads installation package and unzips it
 func downloadAndUnzipArtifact(
 	mgr *updateManager,
@@ -420,6 +500,20 @@ func downloadAndUnzipArtifact(
 	// downloaded successfully, append message
 	context.Current.AppendInfo(log, "Successfully downloaded %v", downloadInput.SourceURL)
 
+	// refuse to install a package which isn't signed with one of the pinned keys
+	var policy verification.Policy
+	if policy, err = verification.EffectivePolicy(context.Current.VerificationPolicy); err != nil {
+		return err
+	}
+	if err = verifyArtifact(log, policy, verification.Artifact{
+		SourceURL: downloadInput.SourceURL,
+		LocalPath: downloadOutput.LocalFilePath,
+		Checksums: downloadInput.SourceChecksums,
+		Signed:    true,
+	}); err != nil {
+		return err
+	}
+
 	// uncompress installation package
 	if err = uncompress(
 		downloadOutput.LocalFilePath,
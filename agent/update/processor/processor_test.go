@@ -18,11 +18,14 @@ package processor
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/health/checkpoint"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/aws/amazon-ssm-agent/agent/verification"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +33,7 @@ var logger = log.NewMockLog()
 
 type serviceStub struct {
 	Service
+	errorCode string
 }
 
 func (s *serviceStub) SendReply(log log.T, update *UpdateDetail) error {
@@ -41,6 +45,7 @@ func (s *serviceStub) DeleteMessage(log log.T, update *UpdateDetail) error {
 }
 
 func (s *serviceStub) UpdateHealthCheck(log log.T, update *UpdateDetail, errorCode string) error {
+	s.errorCode = errorCode
 	return nil
 }
 
@@ -397,6 +402,50 @@ func TestVerifyInstallationCannotStartAgent(t *testing.T) {
 	assert.Equal(t, context.Current.State, Rollback)
 }
 
+func TestVerifyInstallationFailedHealthCheck(t *testing.T) {
+	// setup
+	control := &stubControl{serviceIsRunning: true}
+	updater := createUpdaterStubs(control)
+	context := createUpdateContext(Installed)
+	isRollbackCalled := false
+
+	updater.mgr.healthCheck = func(mgr *updateManager, log log.T, context *UpdateContext) []checkpoint.Check {
+		return []checkpoint.Check{checkpoint.MessagesPolled}
+	}
+	updater.mgr.rollback = func(mgr *updateManager, log log.T, context *UpdateContext) (err error) {
+		isRollbackCalled = true
+		return nil
+	}
+
+	// action
+	err := verifyInstallation(updater.mgr, logger, context, false)
+
+	// assert
+	assert.NoError(t, err)
+	assert.True(t, isRollbackCalled)
+	assert.Equal(t, context.Current.State, Rollback)
+	assert.Equal(t, updateutil.ErrorHealthCheckFailed, context.Current.RollbackErrorCode)
+}
+
+func TestVerifyRollbackAfterFailedHealthCheck(t *testing.T) {
+	// setup
+	control := &stubControl{serviceIsRunning: true}
+	updater := createUpdaterStubs(control)
+	context := createUpdateContext(RolledBack)
+	context.Current.RollbackErrorCode = updateutil.ErrorHealthCheckFailed
+	svc := &serviceStub{}
+	updater.mgr.svc = svc
+
+	// action
+	err := verifyInstallation(updater.mgr, logger, context, true)
+
+	// assert
+	assert.NoError(t, err)
+	assert.Equal(t, context.Histories[0].State, Completed)
+	assert.Equal(t, context.Histories[0].Result, contracts.ResultStatusFailed)
+	assert.Equal(t, string(updateutil.ErrorHealthCheckFailed), svc.errorCode)
+}
+
 func TestVerifyRollback(t *testing.T) {
 	// setup
 	control := &stubControl{serviceIsRunning: true}
@@ -620,6 +669,61 @@ func TestDownloadWithError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDownloadWithInvalidSignature(t *testing.T) {
+	// setup
+	control := &stubControl{failExeCommand: true}
+	updater := createUpdaterStubs(control)
+	context := createUpdateContext(Initialized)
+	downloadOutput := artifact.DownloadOutput{
+		IsHashMatched: true,
+		LocalFilePath: "filepath",
+	}
+
+	downloadArtifact = func(log log.T, input artifact.DownloadInput) (output artifact.DownloadOutput, err error) {
+		return downloadOutput, nil
+	}
+	verifyArtifact = func(log log.T, policy verification.Policy, artifact verification.Artifact) error {
+		return &updateutil.SignatureError{PackagePath: artifact.LocalPath, Reason: fmt.Errorf("signature mismatch")}
+	}
+	defer func() { verifyArtifact = verification.Verify }()
+	uncompressed := false
+	uncompress = func(src, dest string) error {
+		uncompressed = true
+		return nil
+	}
+
+	// action
+	err := downloadAndUnzipArtifact(updater.mgr, logger, artifact.DownloadInput{}, context, context.Current.TargetVersion)
+
+	// assert
+	assert.Error(t, err)
+	assert.False(t, uncompressed)
+	assert.Equal(t, updateutil.ErrorInvalidSignature, downloadErrorCode(err))
+	assert.Equal(t, updateutil.ErrorInvalidPackage, downloadErrorCode(fmt.Errorf("failed to download file reliably")))
+}
+
+func TestWriteHealthCheckpoints(t *testing.T) {
+	assert.False(t, writesHealthCheckpoints("2.2.493.0"))
+	assert.False(t, writesHealthCheckpoints("1.0.187.0"))
+	assert.True(t, writesHealthCheckpoints(healthCheckpointsMinimumVersion))
+	assert.True(t, writesHealthCheckpoints("3.0.0.0"))
+	assert.False(t, writesHealthCheckpoints("invalid"))
+}
+
+func TestWaitForAgentHealthWithoutCheckpoints(t *testing.T) {
+	// setup
+	updater := createDefaultUpdaterStub()
+	context := createUpdateContext(Installed)
+	context.Current.TargetVersion = "2.2.493.0"
+	context.Current.InstallDateTime = time.Now().Add(-time.Hour)
+
+	// action
+	missing := waitForAgentHealth(updater.mgr, logger, context)
+
+	// assert
+	assert.Empty(t, missing)
+}
+
 // createUpdaterWithStubs creates stubs updater and it's manager, util and service
 func createDefaultUpdaterStub() *Updater {
 	return createUpdaterStubs(&stubControl{})
@@ -630,6 +734,9 @@ func createUpdaterStubs(control *stubControl) *Updater {
 	updater.mgr.svc = &serviceStub{}
 	updater.mgr.util = &utilityStub{controller: control}
 	updater.mgr.ctxMgr = &contextMgrStub{}
+	updater.mgr.healthCheck = func(mgr *updateManager, log log.T, context *UpdateContext) []checkpoint.Check {
+		return nil
+	}
 
 	return updater
 }
@@ -45,6 +45,11 @@ type Service interface {
 
 type svcManager struct{}
 
+// NewService returns the service sending the replies of the update commands
+func NewService() Service {
+	return &svcManager{}
+}
+
 // SendReply sends message back to the service
 func (s *svcManager) SendReply(log log.T, update *UpdateDetail) (err error) {
 	var svc messageService.Service
@@ -88,7 +93,7 @@ func getMsgSvc(config appconfig.SsmagentConfig) (svc messageService.Service, err
 		connectionTimeout := time.Duration(config.Mds.StopTimeoutMillis) * time.Millisecond
 		msgSvc = newMsgSvc(
 			config.Agent.Region,
-			config.Mds.Endpoint,
+			appconfig.GetEndpointOverride(config, appconfig.ServiceNameEc2Messages),
 			nil,
 			connectionTimeout)
 	})
@@ -139,7 +144,7 @@ func prepareRuntimeStatus(update *UpdateDetail) contracts.PluginRuntimeStatus {
 		update.StandardError,
 		iohandler.MaximumPluginOutputSize)
 
-	return contracts.PluginRuntimeStatus{
+	runtimeStatus := contracts.PluginRuntimeStatus{
 		Code:               code,
 		Status:             pluginStatus,
 		Output:             output,
@@ -148,4 +153,10 @@ func prepareRuntimeStatus(update *UpdateDetail) contracts.PluginRuntimeStatus {
 		StartDateTime:      times.ToIso8601UTC(update.StartDateTime),
 		EndDateTime:        times.ToIso8601UTC(time.Now()),
 	}
+	if pluginStatus == contracts.ResultStatusSuccess {
+		runtimeStatus.Result = map[string]interface{}{
+			contracts.ResultFieldInstalledVersion: update.TargetVersion,
+		}
+	}
+	return runtimeStatus
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	messageService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
 	"github.com/stretchr/testify/assert"
 )
@@ -38,7 +39,7 @@ func (s *stubSdkService) AcknowledgeMessage(log log.T, messageID string) error {
 	return nil
 }
 
-func (s *stubSdkService) SendReply(log log.T, messageID string, payload string) error {
+func (s *stubSdkService) SendReply(log log.T, messageID string, payload string, opts ...request.Option) error {
 	return nil
 }
 
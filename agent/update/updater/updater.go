@@ -39,19 +39,20 @@ var (
 )
 
 var (
-	update          *bool
-	sourceVersion   *string
-	sourceLocation  *string
-	sourceHash      *string
-	targetVersion   *string
-	targetLocation  *string
-	targetHash      *string
-	packageName     *string
-	messageID       *string
-	stdout          *string
-	stderr          *string
-	outputKeyPrefix *string
-	outputBucket    *string
+	update             *bool
+	sourceVersion      *string
+	sourceLocation     *string
+	sourceHash         *string
+	targetVersion      *string
+	targetLocation     *string
+	targetHash         *string
+	packageName        *string
+	messageID          *string
+	stdout             *string
+	stderr             *string
+	outputKeyPrefix    *string
+	outputBucket       *string
+	verificationPolicy *string
 )
 
 func init() {
@@ -76,6 +77,7 @@ func init() {
 	stderr = flag.String(updateutil.StderrFileName, "", "standard error file path")
 	outputKeyPrefix = flag.String(updateutil.OutputKeyPrefixCmd, "", "output key prefix")
 	outputBucket = flag.String(updateutil.OutputBucketNameCmd, "", "output bucket name")
+	verificationPolicy = flag.String(updateutil.VerificationPolicyCmd, "", "verification policy of the update packages")
 }
 
 // Config holds Runtime info of plugins.
@@ -126,6 +128,7 @@ func main() {
 		MessageID:          *messageID,
 		StartDateTime:      time.Now().UTC(),
 		RequiresUninstall:  false,
+		VerificationPolicy: *verificationPolicy,
 	}
 
 	if err := resolveUpdateDetail(detail); err != nil {
@@ -0,0 +1,83 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updateutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// DefaultInstanceContextTTL is how long a detected InstanceContext is reused before it is refreshed
+const DefaultInstanceContextTTL = 10 * time.Minute
+
+var defaultInstanceContextCache = NewInstanceContextCache(detectInstanceContext, DefaultInstanceContextTTL)
+
+var timeNow = time.Now
+
+// InstanceContextCache keeps the last detected InstanceContext so that documents with many
+// steps don't repeat the metadata calls and platform detection for every plugin invocation.
+// It is safe for concurrent use.
+type InstanceContextCache struct {
+	mut       sync.Mutex
+	detect    func(log log.T) (*InstanceContext, error)
+	ttl       time.Duration
+	context   *InstanceContext
+	expiresAt time.Time
+}
+
+// NewInstanceContextCache creates a cache that refreshes its value through detect once ttl has elapsed.
+// A ttl of zero or less disables caching.
+func NewInstanceContextCache(detect func(log log.T) (*InstanceContext, error), ttl time.Duration) *InstanceContextCache {
+	return &InstanceContextCache{
+		detect: detect,
+		ttl:    ttl,
+	}
+}
+
+// Get returns a copy of the cached InstanceContext, detecting it again when it is missing or expired.
+// Failed detections are not cached.
+func (c *InstanceContextCache) Get(log log.T) (*InstanceContext, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := timeNow()
+	if c.context == nil || !now.Before(c.expiresAt) {
+		context, err := c.detect(log)
+		if err != nil {
+			c.context = nil
+			return nil, err
+		}
+		c.context = context
+		c.expiresAt = now.Add(c.ttl)
+	}
+
+	context := *c.context
+	return &context, nil
+}
+
+// Invalidate drops the cached InstanceContext so the next Get detects it again
+func (c *InstanceContextCache) Invalidate() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.context = nil
+}
+
+// InvalidateInstanceContext drops the process wide cached InstanceContext, e.g. after the
+// platform or the agent installation has changed.
+func InvalidateInstanceContext() {
+	defaultInstanceContextCache.Invalidate()
+}
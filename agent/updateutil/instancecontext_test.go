@@ -0,0 +1,108 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updateutil
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingDetector struct {
+	mut   sync.Mutex
+	calls int
+	err   error
+}
+
+func (d *countingDetector) detect(log log.T) (*InstanceContext, error) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	d.calls++
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &InstanceContext{Region: "us-east-1", Platform: PlatformLinux, Arch: "amd64"}, nil
+}
+
+func TestInstanceContextCacheReusesValueUntilExpired(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	detector := &countingDetector{}
+	cache := NewInstanceContextCache(detector.detect, time.Minute)
+
+	first, err := cache.Get(logger)
+	assert.NoError(t, err)
+	second, err := cache.Get(logger)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, detector.calls)
+	assert.Equal(t, first, second)
+
+	// callers get their own copy
+	first.Region = "eu-west-1"
+	third, _ := cache.Get(logger)
+	assert.Equal(t, "us-east-1", third.Region)
+
+	now = now.Add(time.Minute)
+	_, err = cache.Get(logger)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, detector.calls)
+}
+
+func TestInstanceContextCacheInvalidate(t *testing.T) {
+	detector := &countingDetector{}
+	cache := NewInstanceContextCache(detector.detect, time.Hour)
+
+	cache.Get(logger)
+	cache.Invalidate()
+	cache.Get(logger)
+	assert.Equal(t, 2, detector.calls)
+}
+
+func TestInstanceContextCacheDoesNotCacheErrors(t *testing.T) {
+	detector := &countingDetector{err: fmt.Errorf("metadata unavailable")}
+	cache := NewInstanceContextCache(detector.detect, time.Hour)
+
+	_, err := cache.Get(logger)
+	assert.Error(t, err)
+
+	detector.err = nil
+	context, err := cache.Get(logger)
+	assert.NoError(t, err)
+	assert.NotNil(t, context)
+	assert.Equal(t, 2, detector.calls)
+}
+
+func TestInstanceContextCacheConcurrentGet(t *testing.T) {
+	detector := &countingDetector{}
+	cache := NewInstanceContextCache(detector.detect, time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			context, err := cache.Get(logger)
+			assert.NoError(t, err)
+			assert.Equal(t, "us-east-1", context.Region)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, detector.calls)
+}
@@ -17,6 +17,8 @@ package updateutil
 import (
 	"encoding/json"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -63,3 +65,125 @@ func (util *Utility) SaveUpdatePluginResult(
 
 	return nil
 }
+
+//DeferredUpdate represents an update received outside the update window, it is handed over to the updater
+//by the agent once the window opens. The command stays in progress until the updater reports its result, or
+//the agent reports it canceled or superseded by a newer update command.
+type DeferredUpdate struct {
+	CommandID     string              `json:"CommandID"`
+	MessageID     string              `json:"MessageID"`
+	UpdateCommand string              `json:"UpdateCommand"`
+	WorkingDir    string              `json:"WorkingDir"`
+	StartDateTime time.Time           `json:"StartDateTime"`
+	PluginResult  *UpdatePluginResult `json:"PluginResult"`
+	// Canceled tells the command was canceled before the update window opened
+	Canceled bool `json:"Canceled,omitempty"`
+	// SupersededBy is the update command received after this one, which replaces it
+	SupersededBy string `json:"SupersededBy,omitempty"`
+}
+
+//LoadDeferredUpdates loads the deferred updates from local storage
+func LoadDeferredUpdates(
+	log log.T, updateRoot string) (deferredUpdates []*DeferredUpdate, err error) {
+
+	files, err := ioutil.ReadDir(DeferredUpdatesDir(updateRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		var deferredUpdate *DeferredUpdate
+		if deferredUpdate, err = loadDeferredUpdate(filepath.Join(DeferredUpdatesDir(updateRoot), file.Name())); err != nil {
+			log.Warnf("unable to load deferred update %v: %v", file.Name(), err)
+			continue
+		}
+		deferredUpdates = append(deferredUpdates, deferredUpdate)
+	}
+	return deferredUpdates, nil
+}
+
+func loadDeferredUpdate(filePath string) (deferredUpdate *DeferredUpdate, err error) {
+	result, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(result, &deferredUpdate); err != nil {
+		return nil, err
+	}
+	return deferredUpdate, nil
+}
+
+func saveDeferredUpdate(updateRoot string, deferredUpdate *DeferredUpdate) (err error) {
+	var jsonData = []byte{}
+	if jsonData, err = json.Marshal(deferredUpdate); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(DeferredUpdatesDir(updateRoot), appconfig.ReadWriteExecuteAccess); err != nil {
+		return err
+	}
+
+	// the update is written to a temporary file first so an interrupted write never loses the deferred update
+	filePath := DeferredUpdateFilePath(updateRoot, deferredUpdate.CommandID)
+	if err = ioutil.WriteFile(filePath+".tmp", jsonData, appconfig.ReadWriteAccess); err != nil {
+		return err
+	}
+	return os.Rename(filePath+".tmp", filePath)
+}
+
+//SaveDeferredUpdate saves DeferredUpdate to the local storage, the updates deferred by other commands being superseded by it
+func (util *Utility) SaveDeferredUpdate(
+	log log.T, updateRoot string, deferredUpdate *DeferredUpdate) (err error) {
+	if err = saveDeferredUpdate(updateRoot, deferredUpdate); err != nil {
+		return err
+	}
+	return util.SupersedeDeferredUpdates(log, updateRoot, deferredUpdate.CommandID)
+}
+
+//SupersedeDeferredUpdates flags the updates deferred by other commands as superseded by the given command,
+//the agent reporting them as failed
+func (util *Utility) SupersedeDeferredUpdates(
+	log log.T, updateRoot string, commandID string) (err error) {
+	deferredUpdates, err := LoadDeferredUpdates(log, updateRoot)
+	if err != nil {
+		return err
+	}
+	for _, deferredUpdate := range deferredUpdates {
+		if deferredUpdate.CommandID == commandID || deferredUpdate.Canceled || deferredUpdate.SupersededBy != "" {
+			continue
+		}
+		log.Infof("Update deferred by command %v is superseded by command %v", deferredUpdate.CommandID, commandID)
+		deferredUpdate.SupersededBy = commandID
+		if err = saveDeferredUpdate(updateRoot, deferredUpdate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//CancelDeferredUpdate flags the update deferred by the command as canceled, the agent reporting it as canceled.
+//It returns false when the command didn't defer an update.
+func CancelDeferredUpdate(
+	log log.T, updateRoot string, commandID string) (found bool, err error) {
+	deferredUpdate, err := loadDeferredUpdate(DeferredUpdateFilePath(updateRoot, commandID))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	deferredUpdate.Canceled = true
+	return true, saveDeferredUpdate(updateRoot, deferredUpdate)
+}
+
+//RemoveDeferredUpdate removes the update deferred by the command from the local storage
+func RemoveDeferredUpdate(updateRoot string, commandID string) (err error) {
+	if err = os.Remove(DeferredUpdateFilePath(updateRoot, commandID)); os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
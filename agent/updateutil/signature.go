@@ -0,0 +1,218 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updateutil contains updater specific utilities.
+package updateutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// SignatureFileSuffix is appended to the location of an update package to find its detached signature
+const SignatureFileSuffix = ".sig"
+
+// signingKeyFileExtension is the extension of the PEM encoded public keys pinned in the signing keys folder
+const signingKeyFileExtension = ".pem"
+
+// PackageSignature represents the detached signature of an update package.
+// It names the key it was created with so signing keys can be rotated, every pinned key remaining trusted.
+type PackageSignature struct {
+	KeyId     string `json:"keyId"`
+	Signature string `json:"signature"`
+}
+
+// SignatureError is returned when an update package can't be verified against the pinned signing keys
+type SignatureError struct {
+	PackagePath string
+	Reason      error
+}
+
+// Error returns the package and the reason its verification failed
+func (err *SignatureError) Error() string {
+	return fmt.Sprintf("signature verification of %v failed, %v", err.PackagePath, err.Reason)
+}
+
+// signingKeysDir returns the folder the public keys update packages are signed with are pinned in
+var signingKeysDir = func() string {
+	return filepath.Join(appconfig.DefaultProgramFolder, appconfig.UpdateSigningKeysDirName)
+}
+
+// downloadSignature downloads the detached signature of the update package and returns its local path
+var downloadSignature = func(log log.T, sourceURL string, destinationDirectory string) (string, error) {
+	output, err := artifact.Download(log, artifact.DownloadInput{
+		SourceURL:            sourceURL + SignatureFileSuffix,
+		DestinationDirectory: destinationDirectory,
+	})
+	if err != nil {
+		return "", err
+	}
+	return output.LocalFilePath, nil
+}
+
+// LoadSigningKeys loads the public keys pinned in the folder, each file <key id>.pem holding one PEM encoded key.
+// The folder has to be writable by administrators only.
+func LoadSigningKeys(dir string) (keys map[string]crypto.PublicKey, err error) {
+	keys = make(map[string]crypto.PublicKey)
+	if !fileutil.Exists(dir) {
+		return keys, nil
+	}
+	if err = fileutil.CheckSecureDirectory(dir); err != nil {
+		return nil, fmt.Errorf("update signing keys folder is not secure, %v", err)
+	}
+
+	var names []string
+	if names, err = fileutil.GetFileNames(dir); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if filepath.Ext(name) != signingKeyFileExtension {
+			continue
+		}
+		var content []byte
+		if content, err = ioutil.ReadFile(filepath.Join(dir, name)); err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(content)
+		if block == nil {
+			return nil, fmt.Errorf("update signing key %v is not PEM encoded", name)
+		}
+		var key interface{}
+		if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("invalid update signing key %v, %v", name, err)
+		}
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			keys[strings.TrimSuffix(name, signingKeyFileExtension)] = key
+		default:
+			return nil, fmt.Errorf("unsupported type of update signing key %v", name)
+		}
+	}
+	return keys, nil
+}
+
+// VerifyPackageSignature verifies the downloaded update package against its detached signature, downloaded
+// from the location of the package, before the package is used. The failure is logged as a security event.
+// Verification is skipped when no signing key is pinned.
+func VerifyPackageSignature(log log.T, sourceURL string, packagePath string) error {
+	return verifyPackageSignature(log, sourceURL, packagePath, false)
+}
+
+// RequirePackageSignature verifies the downloaded artifact against its detached signature like VerifyPackageSignature,
+// but fails the verification when no signing key is pinned.
+func RequirePackageSignature(log log.T, sourceURL string, packagePath string) error {
+	return verifyPackageSignature(log, sourceURL, packagePath, true)
+}
+
+// verifyPackageSignature verifies the package against the pinned signing keys, required tells if the package
+// has to be signed even when no key is pinned
+func verifyPackageSignature(log log.T, sourceURL string, packagePath string, required bool) error {
+	dir := signingKeysDir()
+	keys, err := LoadSigningKeys(dir)
+	if err != nil {
+		return signatureSecurityEvent(log, packagePath, err)
+	}
+	if len(keys) == 0 {
+		if required {
+			return signatureSecurityEvent(log, packagePath, fmt.Errorf("a signature is required but no signing key is pinned in %v", dir))
+		}
+		log.Warnf("No update signing key is pinned in %v, skipping signature verification of %v", dir, packagePath)
+		return nil
+	}
+	if sourceURL == "" {
+		return signatureSecurityEvent(log, packagePath, errors.New("the location of its signature is unknown"))
+	}
+
+	signaturePath, err := downloadSignature(log, sourceURL, filepath.Dir(packagePath))
+	if err != nil {
+		return signatureSecurityEvent(log, packagePath, fmt.Errorf("failed to download signature, %v", err))
+	}
+	keyID, err := verifySignature(keys, packagePath, signaturePath)
+	if err != nil {
+		return signatureSecurityEvent(log, packagePath, err)
+	}
+	log.Infof("Signature of %v verified with update signing key %v", packagePath, keyID)
+	return nil
+}
+
+// verifySignature verifies the SHA-256 digest of the package against the signature, returning the id of the key used
+func verifySignature(keys map[string]crypto.PublicKey, packagePath string, signaturePath string) (keyID string, err error) {
+	var signature PackageSignature
+	if err = jsonutil.UnmarshalFile(signaturePath, &signature); err != nil {
+		return "", fmt.Errorf("invalid signature file, %v", err)
+	}
+	key, ok := keys[signature.KeyId]
+	if !ok {
+		return "", fmt.Errorf("package is signed with key %v which is not pinned", signature.KeyId)
+	}
+	var sig []byte
+	if sig, err = base64.StdEncoding.DecodeString(signature.Signature); err != nil {
+		return "", fmt.Errorf("invalid signature encoding, %v", err)
+	}
+
+	var digest []byte
+	if digest, err = sha256Digest(packagePath); err != nil {
+		return "", err
+	}
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest, sig)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(publicKey, digest, sig) {
+			err = errors.New("ecdsa verification error")
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("package doesn't match the signature of key %v, %v", signature.KeyId, err)
+	}
+	return signature.KeyId, nil
+}
+
+// sha256Digest returns the SHA-256 digest of the file
+func sha256Digest(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// signatureSecurityEvent logs the failed verification of the package as a security event
+func signatureSecurityEvent(log log.T, packagePath string, reason error) error {
+	err := &SignatureError{PackagePath: packagePath, Reason: reason}
+	log.Criticalf("Security event: refusing to use update package, %v", err)
+	return err
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updateutil contains updater specific utilities.
+package updateutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newSecurityLog returns a mock log accepting the critical messages security events are logged with
+func newSecurityLog() *log.Mock {
+	logger := log.NewMockLog()
+	logger.On("Criticalf", mock.Anything, mock.Anything).Return(nil)
+	return logger
+}
+
+// signingTest holds a temporary signing keys folder and a package with its signature
+type signingTest struct {
+	dir           string
+	packagePath   string
+	signaturePath string
+}
+
+func newSigningTest(t *testing.T) *signingTest {
+	dir, err := ioutil.TempDir("", "signature")
+	assert.NoError(t, err)
+	test := &signingTest{
+		dir:           dir,
+		packagePath:   filepath.Join(dir, "amazon-ssm-agent.tar.gz"),
+		signaturePath: filepath.Join(dir, "amazon-ssm-agent.tar.gz"+SignatureFileSuffix),
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "keys"), 0700))
+	assert.NoError(t, ioutil.WriteFile(test.packagePath, []byte("agent package"), 0600))
+
+	signingKeysDir = func() string { return filepath.Join(dir, "keys") }
+	downloadSignature = func(log log.T, sourceURL string, destinationDirectory string) (string, error) {
+		return test.signaturePath, nil
+	}
+	return test
+}
+
+func (test *signingTest) cleanup() {
+	os.RemoveAll(test.dir)
+}
+
+// pinKey writes the public key in the signing keys folder
+func (test *signingTest) pinKey(t *testing.T, keyID string, publicKey crypto.PublicKey) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NoError(t, err)
+	content := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(test.dir, "keys", keyID+signingKeyFileExtension), content, 0600))
+}
+
+// sign writes the signature of the package created with the private key
+func (test *signingTest) sign(t *testing.T, keyID string, privateKey crypto.Signer) {
+	content, err := ioutil.ReadFile(test.packagePath)
+	assert.NoError(t, err)
+	digest := sha256.Sum256(content)
+	sig, err := privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.NoError(t, err)
+	signature, err := jsonutil.Marshal(PackageSignature{KeyId: keyID, Signature: base64.StdEncoding.EncodeToString(sig)})
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(test.signaturePath, []byte(signature), 0600))
+}
+
+func TestVerifyPackageSignature(t *testing.T) {
+	test := newSigningTest(t)
+	defer test.cleanup()
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	test.pinKey(t, "2018-01", &ecdsaKey.PublicKey)
+	test.pinKey(t, "2018-06", &rsaKey.PublicKey)
+
+	test.sign(t, "2018-01", ecdsaKey)
+	assert.NoError(t, VerifyPackageSignature(log.NewMockLog(), "https://bucket/amazon-ssm-agent.tar.gz", test.packagePath))
+
+	// packages signed with the rotated key are verified as well
+	test.sign(t, "2018-06", rsaKey)
+	assert.NoError(t, VerifyPackageSignature(log.NewMockLog(), "https://bucket/amazon-ssm-agent.tar.gz", test.packagePath))
+}
+
+func TestVerifyPackageSignatureMismatch(t *testing.T) {
+	test := newSigningTest(t)
+	defer test.cleanup()
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.pinKey(t, "2018-01", &ecdsaKey.PublicKey)
+	test.sign(t, "2018-01", ecdsaKey)
+	assert.NoError(t, ioutil.WriteFile(test.packagePath, []byte("tampered package"), 0600))
+	logger := newSecurityLog()
+
+	err := VerifyPackageSignature(logger, "https://bucket/amazon-ssm-agent.tar.gz", test.packagePath)
+
+	assert.Error(t, err)
+	_, isSignatureError := err.(*SignatureError)
+	assert.True(t, isSignatureError)
+	logger.AssertCalled(t, "Criticalf", mock.Anything, mock.Anything)
+}
+
+func TestVerifyPackageSignatureWithUnpinnedKey(t *testing.T) {
+	test := newSigningTest(t)
+	defer test.cleanup()
+	pinnedKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.pinKey(t, "2018-01", &pinnedKey.PublicKey)
+	test.sign(t, "2018-02", otherKey)
+
+	err := VerifyPackageSignature(newSecurityLog(), "https://bucket/amazon-ssm-agent.tar.gz", test.packagePath)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "2018-02 which is not pinned")
+}
+
+func TestVerifyPackageSignatureWithoutPinnedKeys(t *testing.T) {
+	test := newSigningTest(t)
+	defer test.cleanup()
+	downloaded := false
+	downloadSignature = func(log log.T, sourceURL string, destinationDirectory string) (string, error) {
+		downloaded = true
+		return "", nil
+	}
+
+	assert.NoError(t, VerifyPackageSignature(log.NewMockLog(), "https://bucket/amazon-ssm-agent.tar.gz", test.packagePath))
+	assert.False(t, downloaded)
+}
+
+func TestRequirePackageSignatureWithoutPinnedKeys(t *testing.T) {
+	test := newSigningTest(t)
+	defer test.cleanup()
+
+	err := RequirePackageSignature(newSecurityLog(), "https://bucket/amazon-ssm-agent.tar.gz", test.packagePath)
+
+	assert.Error(t, err)
+	assert.IsType(t, &SignatureError{}, err)
+	assert.Contains(t, err.Error(), "no signing key is pinned")
+}
+
+func TestLoadSigningKeysRejectsInvalidKey(t *testing.T) {
+	test := newSigningTest(t)
+	defer test.cleanup()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(test.dir, "keys", "broken.pem"), []byte("not a key"), 0600))
+
+	_, err := LoadSigningKeys(signingKeysDir())
+
+	assert.Error(t, err)
+}
@@ -55,3 +55,15 @@ func (m *Mock) SaveUpdatePluginResult(log log.T, updaterRoot string, updateResul
 	args := m.Called(log, updaterRoot, updateResult)
 	return args.Error(0)
 }
+
+// SaveDeferredUpdate mocks the SaveDeferredUpdate function.
+func (m *Mock) SaveDeferredUpdate(log log.T, updaterRoot string, deferredUpdate *DeferredUpdate) (err error) {
+	args := m.Called(log, updaterRoot, deferredUpdate)
+	return args.Error(0)
+}
+
+// SupersedeDeferredUpdates mocks the SupersedeDeferredUpdates function.
+func (m *Mock) SupersedeDeferredUpdates(log log.T, updaterRoot string, commandID string) (err error) {
+	args := m.Called(log, updaterRoot, commandID)
+	return args.Error(0)
+}
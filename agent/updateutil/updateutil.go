@@ -52,6 +52,10 @@ const (
 	// UpdatePluginResultFileName represents Update plugin result file name
 	UpdatePluginResultFileName = "updatepluginresult.json"
 
+	// DeferredUpdatesDirName represents the folder the updates deferred until the update window opens are saved in,
+	// one file per command
+	DeferredUpdatesDirName = "deferredupdates"
+
 	// DefaultOutputFolder represents default location for storing output files
 	DefaultOutputFolder = "awsupdateSsmAgent"
 
@@ -106,6 +110,24 @@ const (
 	// PlatformSuse represents Raspbian
 	PlatformRaspbian = "raspbian"
 
+	// PlatformAlpine represents Alpine Linux
+	PlatformAlpine = "alpine"
+
+	// PlatformLinuxStatic represents the statically linked linux agent
+	PlatformLinuxStatic = "linux-static"
+
+	// PlatformMacOsX represents macOS, named Mac OS X before 10.12
+	PlatformMacOsX = "mac os x"
+
+	// PlatformMacOs represents macOS
+	PlatformMacOs = "macos"
+
+	// PlatformDarwin represents the darwin agent installed on macOS
+	PlatformDarwin = "darwin"
+
+	// LaunchdServiceLabel is the label of the launchd job running the agent on macOS
+	LaunchdServiceLabel = "com.amazon.aws.ssm"
+
 	// PlatformWindows represents windows
 	PlatformWindows = "windows"
 
@@ -144,6 +166,9 @@ const (
 	// ErrorInvalidCertificate represents Installation package file doesn't contain valid certificate
 	ErrorInvalidCertificate ErrorCode = "ErrorInvalidCertificate"
 
+	// ErrorInvalidSignature represents Installation package file doesn't match its signature
+	ErrorInvalidSignature ErrorCode = "ErrorInvalidSignature"
+
 	// ErrorInvalidManifest represents Invalid manifest file
 	ErrorInvalidManifest ErrorCode = "ErrorInvalidManifest"
 
@@ -156,6 +181,9 @@ const (
 	// ErrorInstallFailed represents Install failed
 	ErrorInstallFailed ErrorCode = "ErrorInstallFailed"
 
+	// ErrorHealthCheckFailed represents the updated agent didn't pass its health checks in time
+	ErrorHealthCheckFailed ErrorCode = "ErrorHealthCheckFailed"
+
 	// ErrorCannotStartService represents Cannot start Ec2Config service
 	ErrorCannotStartService ErrorCode = "ErrorCannotStartService"
 
@@ -195,6 +223,8 @@ type T interface {
 	ExeCommand(log log.T, cmd string, workingDir string, updaterRoot string, stdOut string, stdErr string, isAsync bool) (err error)
 	IsServiceRunning(log log.T, i *InstanceContext) (result bool, err error)
 	SaveUpdatePluginResult(log log.T, updaterRoot string, updateResult *UpdatePluginResult) (err error)
+	SaveDeferredUpdate(log log.T, updaterRoot string, deferredUpdate *DeferredUpdate) (err error)
+	SupersedeDeferredUpdates(log log.T, updaterRoot string, commandID string) (err error)
 	IsDiskSpaceSufficientForUpdate(log log.T) (bool, error)
 }
 
@@ -238,8 +268,14 @@ var possiblyUsingSystemD = map[string]bool{
 	PlatformLinux:    true,
 }
 
-// CreateInstanceContext create instance related information such as region, platform and arch
+// CreateInstanceContext create instance related information such as region, platform and arch.
+// The result is served from a process wide cache, see InstanceContextCache.
 func (util *Utility) CreateInstanceContext(log log.T) (context *InstanceContext, err error) {
+	return defaultInstanceContextCache.Get(log)
+}
+
+// detectInstanceContext queries metadata and the platform to build a fresh InstanceContext
+func detectInstanceContext(log log.T) (context *InstanceContext, err error) {
 	region := ""
 	if region, err = getRegion(); region == "" {
 		return context, fmt.Errorf("Failed to get region, %v", err)
@@ -288,6 +324,16 @@ func (util *Utility) CreateInstanceContext(log log.T) (context *InstanceContext,
 		installerName = PlatformUbuntu
 		Installer = InstallScript
 		UnInstaller = UninstallScript
+	} else if strings.Contains(platformName, PlatformAlpine) {
+		platformName = PlatformAlpine
+		installerName = PlatformLinuxStatic
+		Installer = InstallScript
+		UnInstaller = UninstallScript
+	} else if strings.Contains(platformName, PlatformMacOsX) || strings.Contains(platformName, PlatformMacOs) {
+		platformName = PlatformMacOs
+		installerName = PlatformDarwin
+		Installer = InstallScript
+		UnInstaller = UninstallScript
 	} else if isNano, _ := platform.IsPlatformNanoServer(log); isNano {
 		//TODO move this logic to instance context
 		platformName = PlatformWindowsNano
@@ -521,6 +567,17 @@ func (util *Utility) IsServiceRunning(log log.T, i *InstanceContext) (result boo
 				return false, err
 			}
 		}
+	} else if i.IsPlatformUsingOpenRC() {
+		expectedOutput = "status: started"
+		if commandOutput, err = execCommand("rc-service", "amazon-ssm-agent", "status").Output(); err != nil {
+			return false, err
+		}
+	} else if i.IsPlatformUsingLaunchd() {
+		// launchctl only reports a PID for a loaded job while its process is running
+		expectedOutput = "\"PID\" ="
+		if commandOutput, err = execCommand("launchctl", "list", LaunchdServiceLabel).Output(); err != nil {
+			return false, err
+		}
 	} else {
 		expectedOutput = agentExpectedStatus()
 		if commandOutput, err = agentStatusOutput(); err != nil {
@@ -584,6 +641,16 @@ func (i *InstanceContext) IsPlatformUsingSystemD(log log.T) (result bool, err er
 	return false, nil
 }
 
+// IsPlatformUsingOpenRC returns if OpenRC is the default Init for the Linux platform
+func (i *InstanceContext) IsPlatformUsingOpenRC() bool {
+	return i.Platform == PlatformAlpine
+}
+
+// IsPlatformUsingLaunchd returns if launchd manages the agent, which is the case on macOS
+func (i *InstanceContext) IsPlatformUsingLaunchd() bool {
+	return i.Platform == PlatformMacOs
+}
+
 func getMinimumVersionForSystemD() (systemDMap *map[string]string) {
 	once.Do(func() {
 		isUsingSystemD = make(map[string]string)
@@ -677,6 +744,16 @@ func UpdatePluginResultFilePath(updateRoot string) (filePath string) {
 	return filepath.Join(updateRoot, UpdatePluginResultFileName)
 }
 
+// DeferredUpdatesDir returns the deferred updates folder path
+func DeferredUpdatesDir(updateRoot string) (dirPath string) {
+	return filepath.Join(updateRoot, DeferredUpdatesDirName)
+}
+
+// DeferredUpdateFilePath returns the file path of the update deferred by the command
+func DeferredUpdateFilePath(updateRoot string, commandID string) (filePath string) {
+	return filepath.Join(DeferredUpdatesDir(updateRoot), commandID+".json")
+}
+
 // UpdaterFilePath returns updater file path
 func UpdaterFilePath(updateRoot string, updaterPackageName string, version string) (filePath string) {
 	return filepath.Join(UpdateArtifactFolder(updateRoot, updaterPackageName, version), Updater)
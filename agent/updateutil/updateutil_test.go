@@ -16,6 +16,7 @@ package updateutil
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"strings"
@@ -96,6 +97,9 @@ func TestCreateInstanceContext(t *testing.T) {
 		{"us-east-1", PlatformSuseOS, nil, "12", nil, PlatformSuseOS, PlatformLinux, false},
 		{"us-east-1", PlatformRedHat, nil, "6.8", nil, PlatformRedHat, PlatformLinux, false},
 		{"us-east-1", PlatformUbuntu, nil, "12", nil, PlatformUbuntu, PlatformUbuntu, false},
+		{"us-east-1", "Alpine Linux", nil, "3.8.1", nil, PlatformAlpine, PlatformLinuxStatic, false},
+		{"us-east-1", "Mac OS X", nil, "10.11.6", nil, PlatformMacOs, PlatformDarwin, false},
+		{"us-east-1", "macOS", nil, "10.14.6", nil, PlatformMacOs, PlatformDarwin, false},
 		{"us-east-1", PlatformWindows, nil, "5", nil, PlatformWindows, PlatformWindows, false},
 		{"us-east-1", "", fmt.Errorf("error"), "", nil, "", "", true},
 		{"us-east-1", "", nil, "", fmt.Errorf("error"), "", "", true},
@@ -110,6 +114,7 @@ func TestCreateInstanceContext(t *testing.T) {
 	for _, test := range testCases {
 		// Setup stubs
 		context = test
+		InvalidateInstanceContext()
 
 		context, err := util.CreateInstanceContext(logger)
 
@@ -381,6 +386,10 @@ func TestIsServiceRunning(t *testing.T) {
 		{InstanceContext{"us-east-1", PlatformRedHat, "6.5", "linux", "amd64", "tar.gz"}, true},
 		// test system with systemD
 		{InstanceContext{"us-east-1", PlatformRedHat, "7.1", "linux", "amd64", "tar.gz"}, true},
+		// test system with openrc
+		{InstanceContext{"us-east-1", PlatformAlpine, "3.8.1", "linux-static", "amd64", "tar.gz"}, true},
+		// test system with launchd
+		{InstanceContext{"us-east-1", PlatformMacOs, "10.14.6", "darwin", "amd64", "tar.gz"}, true},
 	}
 
 	// Stub exec.Command
@@ -401,6 +410,10 @@ func TestIsServiceRunningWithErrorMessageFromCommandExec(t *testing.T) {
 		{InstanceContext{"us-east-1", PlatformRedHat, "6.5", "linux", "amd64", "tar.gz"}},
 		// test system with systemD
 		{InstanceContext{"us-east-1", PlatformRedHat, "7.1", "linux", "amd64", "tar.gz"}},
+		// test system with openrc
+		{InstanceContext{"us-east-1", PlatformAlpine, "3.8.1", "linux-static", "amd64", "tar.gz"}},
+		// test system with launchd
+		{InstanceContext{"us-east-1", PlatformMacOs, "10.14.6", "darwin", "amd64", "tar.gz"}},
 	}
 
 	// Stub exec.Command
@@ -535,6 +548,10 @@ func TestExecCommandHelperProcess(*testing.T) {
 			fmt.Println("Active: active (running)")
 		case "status":
 			fmt.Println("amazon-ssm-agent start/running")
+		case "rc-service":
+			fmt.Println(" * status: started")
+		case "launchctl":
+			fmt.Println("{\n\t\"Label\" = \"com.amazon.aws.ssm\";\n\t\"PID\" = 312;\n};")
 		case "update":
 			fmt.Println("test update")
 		}
@@ -651,3 +668,33 @@ func TestCompareVersion(t *testing.T) {
 	assert.NotNil(t, err)
 
 }
+
+func TestSupersedeDeferredUpdates(t *testing.T) {
+	logger := log.NewMockLog()
+	util := Utility{}
+	updateRoot, err := ioutil.TempDir("", "updateutil")
+	assert.NoError(t, err)
+	defer os.RemoveAll(updateRoot)
+
+	assert.NoError(t, util.SaveDeferredUpdate(logger, updateRoot, &DeferredUpdate{CommandID: "command1"}))
+	assert.NoError(t, util.SaveDeferredUpdate(logger, updateRoot, &DeferredUpdate{CommandID: "command2"}))
+	found, err := CancelDeferredUpdate(logger, updateRoot, "command2")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	// an update started right away supersedes the pending deferred updates
+	assert.NoError(t, util.SupersedeDeferredUpdates(logger, updateRoot, "command3"))
+
+	deferredUpdates, err := LoadDeferredUpdates(logger, updateRoot)
+	assert.NoError(t, err)
+	assert.Len(t, deferredUpdates, 2)
+	for _, deferredUpdate := range deferredUpdates {
+		switch deferredUpdate.CommandID {
+		case "command1":
+			assert.Equal(t, "command2", deferredUpdate.SupersededBy)
+		case "command2":
+			assert.True(t, deferredUpdate.Canceled)
+			assert.Empty(t, deferredUpdate.SupersededBy)
+		}
+	}
+}
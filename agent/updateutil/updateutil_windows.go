@@ -64,6 +64,9 @@ const (
 
 	// OutputBucketNameCmd represents the command argument for output bucket name
 	OutputBucketNameCmd = "output-bucket"
+
+	// VerificationPolicyCmd represents the command argument for the verification policy of the update packages
+	VerificationPolicyCmd = "verification-policy"
 )
 
 const (
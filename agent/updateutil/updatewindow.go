@@ -0,0 +1,146 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updateutil
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// hoursPerDay is the number of hours of a full day window
+const hoursPerDay = 24
+
+// UpdateWindow is the maintenance window in which the agent applies self-updates.
+// A window starting at a later hour than it ends, such as 22-04, spans midnight and belongs to the day it starts on.
+type UpdateWindow struct {
+	days      map[time.Weekday]bool
+	startHour int
+	duration  time.Duration
+	location  *time.Location
+	splay     time.Duration
+}
+
+// NewUpdateWindow parses the update window configured in appconfig
+func NewUpdateWindow(config appconfig.UpdateWindowCfg) (window *UpdateWindow, err error) {
+	window = &UpdateWindow{
+		days:     make(map[time.Weekday]bool),
+		duration: hoursPerDay * time.Hour,
+		location: time.Local,
+		splay:    time.Duration(config.SplayMinutes) * time.Minute,
+	}
+
+	for _, day := range config.AllowedDays {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return nil, fmt.Errorf("invalid day %v in update window", day)
+		}
+		window.days[weekday] = true
+	}
+
+	if config.AllowedHours != "" {
+		var endHour int
+		if window.startHour, endHour, err = parseHours(config.AllowedHours); err != nil {
+			return nil, err
+		}
+		// an equal start and end hour is a full day window starting at that hour
+		if hours := (endHour - window.startHour + hoursPerDay) % hoursPerDay; hours != 0 {
+			window.duration = time.Duration(hours) * time.Hour
+		}
+	}
+
+	if config.TimeZone != "" {
+		if window.location, err = time.LoadLocation(config.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid time zone %v in update window: %v", config.TimeZone, err)
+		}
+	}
+	return window, nil
+}
+
+// IsRestricted returns true if updates are only allowed at some days or hours
+func (w *UpdateWindow) IsRestricted() bool {
+	return len(w.days) > 0 || w.duration < hoursPerDay*time.Hour
+}
+
+// NextStart returns the time an update received at the given time can start at.
+// An update received inside the window starts right away, others are deferred until the window opens
+// and delayed by a random splay which keeps the update inside the window.
+func (w *UpdateWindow) NextStart(now time.Time) time.Time {
+	local := now.In(w.location)
+	// the window which started the day before may still be open
+	for day := -1; day <= 7; day++ {
+		year, month, date := local.AddDate(0, 0, day).Date()
+		start := time.Date(year, month, date, w.startHour, 0, 0, 0, w.location)
+		end := start.Add(w.duration)
+		if len(w.days) > 0 && !w.days[start.Weekday()] || !now.Before(end) {
+			continue
+		}
+		if !now.Before(start) {
+			return now
+		}
+		return start.Add(w.randomSplay(w.duration))
+	}
+	// unreachable as long as at least one day of the week is allowed
+	return now
+}
+
+// randomSplay returns a random delay up to the configured splay, limited to the given window duration
+func (w *UpdateWindow) randomSplay(duration time.Duration) time.Duration {
+	splay := w.splay
+	if splay >= duration {
+		splay = duration - time.Minute
+	}
+	if splay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(splay)))
+}
+
+// parseWeekday parses a day name, either in full or abbreviated to three letters
+func parseWeekday(day string) (time.Weekday, bool) {
+	day = strings.ToLower(strings.TrimSpace(day))
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if day == name || day == name[:3] {
+			return weekday, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// parseHours parses a range of hours formatted as start-end, the end hour being excluded
+func parseHours(hours string) (startHour int, endHour int, err error) {
+	bounds := strings.Split(hours, "-")
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("invalid hours %v in update window, expected a range such as 02-05", hours)
+	}
+	if startHour, err = parseHour(bounds[0]); err != nil {
+		return
+	}
+	endHour, err = parseHour(bounds[1])
+	return
+}
+
+// parseHour parses an hour of the day between 0 and 24
+func parseHour(hour string) (int, error) {
+	value, err := strconv.Atoi(strings.TrimSpace(hour))
+	if err != nil || value < 0 || value > hoursPerDay {
+		return 0, fmt.Errorf("invalid hour %v in update window", hour)
+	}
+	return value % hoursPerDay, nil
+}
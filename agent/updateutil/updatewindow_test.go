@@ -0,0 +1,89 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updateutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// saturday is a Saturday at 10:30 UTC
+var saturday = time.Date(2018, time.June, 2, 10, 30, 0, 0, time.UTC)
+
+func newTestUpdateWindow(t *testing.T, config appconfig.UpdateWindowCfg) *UpdateWindow {
+	config.TimeZone = "UTC"
+	window, err := NewUpdateWindow(config)
+	assert.NoError(t, err)
+	return window
+}
+
+func TestUpdateWindowUnrestricted(t *testing.T) {
+	window := newTestUpdateWindow(t, appconfig.UpdateWindowCfg{})
+
+	assert.False(t, window.IsRestricted())
+	assert.Equal(t, saturday, window.NextStart(saturday))
+}
+
+func TestUpdateWindowOpen(t *testing.T) {
+	window := newTestUpdateWindow(t, appconfig.UpdateWindowCfg{AllowedDays: []string{"sat", "Sunday"}, AllowedHours: "10-12"})
+
+	assert.True(t, window.IsRestricted())
+	assert.Equal(t, saturday, window.NextStart(saturday))
+}
+
+func TestUpdateWindowDeferredToLaterHour(t *testing.T) {
+	window := newTestUpdateWindow(t, appconfig.UpdateWindowCfg{AllowedHours: "22-04"})
+
+	assert.Equal(t, time.Date(2018, time.June, 2, 22, 0, 0, 0, time.UTC), window.NextStart(saturday))
+}
+
+func TestUpdateWindowSpanningMidnightStillOpen(t *testing.T) {
+	window := newTestUpdateWindow(t, appconfig.UpdateWindowCfg{AllowedDays: []string{"Fri"}, AllowedHours: "22-04"})
+	earlySaturday := time.Date(2018, time.June, 2, 3, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, earlySaturday, window.NextStart(earlySaturday))
+}
+
+func TestUpdateWindowDeferredToNextAllowedDay(t *testing.T) {
+	window := newTestUpdateWindow(t, appconfig.UpdateWindowCfg{AllowedDays: []string{"Sat"}, AllowedHours: "02-05"})
+
+	assert.Equal(t, time.Date(2018, time.June, 9, 2, 0, 0, 0, time.UTC), window.NextStart(saturday))
+}
+
+func TestUpdateWindowSplayStaysInsideWindow(t *testing.T) {
+	window := newTestUpdateWindow(t, appconfig.UpdateWindowCfg{AllowedHours: "22-23", SplayMinutes: 120})
+	opens := time.Date(2018, time.June, 2, 22, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 20; i++ {
+		start := window.NextStart(saturday)
+		assert.False(t, start.Before(opens))
+		assert.True(t, start.Before(opens.Add(time.Hour)))
+	}
+}
+
+func TestUpdateWindowInvalidConfiguration(t *testing.T) {
+	invalidConfigs := []appconfig.UpdateWindowCfg{
+		{AllowedDays: []string{"Someday"}},
+		{AllowedHours: "2"},
+		{AllowedHours: "02-25"},
+		{TimeZone: "Nowhere/Nothing"},
+	}
+	for _, config := range invalidConfigs {
+		_, err := NewUpdateWindow(config)
+		assert.Error(t, err)
+	}
+}
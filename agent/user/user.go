@@ -22,3 +22,13 @@ import (
 func Current() (*user.User, error) {
 	return current()
 }
+
+// Lookup looks up a user by username.
+func Lookup(username string) (*user.User, error) {
+	return lookup(username)
+}
+
+// LookupGroup looks up a group by name.
+func LookupGroup(name string) (*user.Group, error) {
+	return lookupGroup(name)
+}
@@ -29,6 +29,7 @@ import (
 
 const (
 	PASSWD_PATH       = "/etc/passwd"
+	GROUP_PATH        = "/etc/group"
 	CURRENT_ERROR_MSG = "failed to get the current user from the system user database"
 
 	PASSWD_USERNAME_INDEX = 0
@@ -36,6 +37,9 @@ const (
 	PASSWD_GID_INDEX      = 3
 	PASSWD_GEOCS_INDEX    = 4
 	PASSWD_HOME_DIR_INDEX = 5
+
+	GROUP_NAME_INDEX = 0
+	GROUP_GID_INDEX  = 2
 )
 
 func current() (*user.User, error) {
@@ -96,3 +100,68 @@ func parsePasswdUser(passwdUserStr string) (*user.User, error) {
 	}, nil
 
 }
+
+func lookup(username string) (*user.User, error) {
+	f, err := os.Open(PASSWD_PATH)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the system user database - %v", err)
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		user, err := parsePasswdUser(scanner.Text())
+		if err != nil {
+			continue
+		}
+
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return nil, user.UnknownUserError(username)
+}
+
+func lookupGroup(name string) (*user.Group, error) {
+	f, err := os.Open(GROUP_PATH)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the system group database - %v", err)
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		group, err := parseGroup(scanner.Text())
+		if err != nil {
+			continue
+		}
+
+		if group.Name == name {
+			return group, nil
+		}
+	}
+
+	return nil, user.UnknownGroupError(name)
+}
+
+func parseGroup(groupStr string) (*user.Group, error) {
+	// format - group_name:password:GID:user_list
+	parsed_str := strings.Split(groupStr, ":")
+	if len(parsed_str) != 4 {
+		return nil, errors.New("invalid format to parse Group")
+	}
+
+	if _, err := strconv.Atoi(parsed_str[GROUP_GID_INDEX]); err != nil {
+		return nil, errors.New("invalid GID to parse Group")
+	}
+
+	return &user.Group{
+		Gid:  parsed_str[GROUP_GID_INDEX],  // GID
+		Name: parsed_str[GROUP_NAME_INDEX], // group name
+	}, nil
+}
@@ -52,3 +52,21 @@ func TestParseUser_InvalidFormat(t *testing.T) {
 	_, err := parsePasswdUser("root-*-0-0-root-/root-/bin/sh")
 	assert.NotNil(t, err)
 }
+
+func TestParseGroup(t *testing.T) {
+	group, err := parseGroup("wheel:x:10:root,ec2-user")
+
+	assert.Nil(t, err)
+	assert.Equal(t, group.Name, "wheel")
+	assert.Equal(t, group.Gid, "10")
+}
+
+func TestParseGroup_InvalidGID(t *testing.T) {
+	_, err := parseGroup("wheel:x:a:root")
+	assert.NotNil(t, err)
+}
+
+func TestLookup_UnknownUser(t *testing.T) {
+	_, err := Lookup("ssm-agent-user-that-does-not-exist")
+	assert.NotNil(t, err)
+}
@@ -22,3 +22,13 @@ func current() (*user.User, error) {
 	// calls the Current function of os/user
 	return Current()
 }
+
+func lookup(username string) (*user.User, error) {
+	// calls the Lookup function of os/user
+	return user.Lookup(username)
+}
+
+func lookupGroup(name string) (*user.Group, error) {
+	// calls the LookupGroup function of os/user
+	return user.LookupGroup(name)
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package verification
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	providersLock sync.RWMutex
+
+	// providers creates the hash of each supported checksum algorithm
+	providers = map[string]func() hash.Hash{
+		"sha256": sha256.New,
+		"sha512": sha512.New,
+		"sha1":   sha1.New,
+		"md5":    md5.New,
+	}
+)
+
+// RegisterChecksumProvider adds support for the checksum algorithm, or replaces the hash it is computed with
+func RegisterChecksumProvider(algorithm string, provider func() hash.Hash) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+	providers[strings.ToLower(algorithm)] = provider
+}
+
+// NewHash returns the hash computing the checksum algorithm, or nil if it isn't supported
+func NewHash(algorithm string) hash.Hash {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+	if provider, ok := providers[strings.ToLower(strings.TrimSpace(algorithm))]; ok {
+		return provider()
+	}
+	return nil
+}
+
+// Checksum returns the hex encoded checksum of the file
+func Checksum(algorithm string, path string) (string, error) {
+	hasher := NewHash(algorithm)
+	if hasher == nil {
+		return "", fmt.Errorf("unsupported checksum algorithm %v", algorithm)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// VerifyChecksums verifies the file against the checksums keyed by algorithm, an empty algorithm being sha256.
+// It returns whether any checksum was verified, the pairs without a value being ignored, and fails when
+// a checksum doesn't match or when none of the algorithms is supported.
+func VerifyChecksums(path string, checksums map[string]string) (verified bool, err error) {
+	unsupported := []string{}
+	for algorithm, expected := range checksums {
+		if strings.TrimSpace(expected) == "" {
+			continue
+		}
+		if algorithm == "" {
+			algorithm = "sha256"
+		}
+		if NewHash(algorithm) == nil {
+			unsupported = append(unsupported, algorithm)
+			continue
+		}
+		var actual string
+		if actual, err = Checksum(algorithm, path); err != nil {
+			return false, fmt.Errorf("failed to compute the %v checksum of %v, %v", algorithm, path, err)
+		}
+		if !strings.EqualFold(strings.TrimSpace(expected), actual) {
+			return false, fmt.Errorf("%v checksum of %v doesn't match, expected %v but found %v", algorithm, path, expected, actual)
+		}
+		verified = true
+	}
+	if !verified && len(unsupported) > 0 {
+		return false, fmt.Errorf("none of the checksum algorithms %v of %v is supported", strings.Join(unsupported, ", "), path)
+	}
+	return verified, nil
+}
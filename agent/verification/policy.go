@@ -0,0 +1,99 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package verification implements the policy deciding how the downloaded artifacts are verified before they are used.
+package verification
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// Policy is how a downloaded artifact is verified
+type Policy string
+
+const (
+	// PolicyNone verifies the checksums of the artifact when they are known, and nothing else
+	PolicyNone Policy = appconfig.VerificationPolicyNone
+
+	// PolicyChecksum requires the checksum of the artifact
+	PolicyChecksum Policy = appconfig.VerificationPolicyChecksum
+
+	// PolicySignature requires the artifact to be signed with one of the pinned signing keys
+	PolicySignature Policy = appconfig.VerificationPolicySignature
+
+	// PolicyBoth requires both the checksum and the signature of the artifact
+	PolicyBoth Policy = appconfig.VerificationPolicyBoth
+)
+
+// getAppConfig loads the agent configuration holding the policy of the fleet
+var getAppConfig = func() (appconfig.SsmagentConfig, error) {
+	return appconfig.Config(false)
+}
+
+// ParsePolicy parses the name of the policy, an empty name being PolicyNone
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return PolicyNone, nil
+	case PolicyNone, PolicyChecksum, PolicySignature, PolicyBoth:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unsupported verification policy %v, expected %v, %v, %v or %v",
+		name, PolicyNone, PolicyChecksum, PolicySignature, PolicyBoth)
+}
+
+// RequiresChecksum tells if the policy fails the artifacts whose checksum isn't known
+func (policy Policy) RequiresChecksum() bool {
+	return policy == PolicyChecksum || policy == PolicyBoth
+}
+
+// RequiresSignature tells if the policy fails the artifacts which aren't signed with a pinned key
+func (policy Policy) RequiresSignature() bool {
+	return policy == PolicySignature || policy == PolicyBoth
+}
+
+// IsAtLeast tells if the policy requires every verification the other policy requires
+func (policy Policy) IsAtLeast(other Policy) bool {
+	return (policy.RequiresChecksum() || !other.RequiresChecksum()) &&
+		(policy.RequiresSignature() || !other.RequiresSignature())
+}
+
+// Resolve returns the policy of the fleet unless the document overrides it. The override is refused
+// when it would skip a verification the policy of the fleet requires.
+func Resolve(fleet Policy, override string) (Policy, error) {
+	if strings.TrimSpace(override) == "" {
+		return fleet, nil
+	}
+	policy, err := ParsePolicy(override)
+	if err != nil {
+		return "", err
+	}
+	if !policy.IsAtLeast(fleet) {
+		return "", fmt.Errorf("verification policy %v is less strict than the %v policy of the agent configuration", policy, fleet)
+	}
+	return policy, nil
+}
+
+// EffectivePolicy resolves the override of the document against the policy of the agent configuration
+func EffectivePolicy(override string) (Policy, error) {
+	fleet := PolicyNone
+	if config, err := getAppConfig(); err == nil {
+		if fleet, err = ParsePolicy(config.Agent.VerificationPolicy); err != nil {
+			return "", err
+		}
+	}
+	return Resolve(fleet, override)
+}
@@ -0,0 +1,85 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package verification
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := map[string]Policy{
+		"":           PolicyNone,
+		"none":       PolicyNone,
+		" Checksum ": PolicyChecksum,
+		"SIGNATURE":  PolicySignature,
+		"both":       PolicyBoth,
+	}
+	for name, expected := range tests {
+		policy, err := ParsePolicy(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, policy, name)
+	}
+
+	_, err := ParsePolicy("strict")
+	assert.Error(t, err)
+}
+
+func TestResolveOnlyAllowsStricterOverrides(t *testing.T) {
+	tests := []struct {
+		fleet    Policy
+		override string
+		expected Policy
+		allowed  bool
+	}{
+		{PolicyNone, "", PolicyNone, true},
+		{PolicyChecksum, "", PolicyChecksum, true},
+		{PolicyNone, "checksum", PolicyChecksum, true},
+		{PolicyChecksum, "both", PolicyBoth, true},
+		{PolicySignature, "signature", PolicySignature, true},
+		{PolicyChecksum, "none", "", false},
+		{PolicyChecksum, "signature", "", false},
+		{PolicySignature, "checksum", "", false},
+		{PolicyBoth, "signature", "", false},
+		{PolicyNone, "invalid", "", false},
+	}
+	for _, test := range tests {
+		policy, err := Resolve(test.fleet, test.override)
+		assert.Equal(t, test.allowed, err == nil, "%v overridden with %v", test.fleet, test.override)
+		assert.Equal(t, test.expected, policy, "%v overridden with %v", test.fleet, test.override)
+	}
+}
+
+func TestEffectivePolicyUsesAgentConfiguration(t *testing.T) {
+	defer func() { getAppConfig = func() (appconfig.SsmagentConfig, error) { return appconfig.Config(false) } }()
+	config := appconfig.DefaultConfig()
+	config.Agent.VerificationPolicy = appconfig.VerificationPolicyChecksum
+	getAppConfig = func() (appconfig.SsmagentConfig, error) { return config, nil }
+
+	policy, err := EffectivePolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, PolicyChecksum, policy)
+
+	_, err = EffectivePolicy("none")
+	assert.Error(t, err)
+
+	// the agent configuration can't be loaded, documents decide
+	getAppConfig = func() (appconfig.SsmagentConfig, error) { return config, errors.New("no config") }
+	policy, err = EffectivePolicy("signature")
+	assert.NoError(t, err)
+	assert.Equal(t, PolicySignature, policy)
+}
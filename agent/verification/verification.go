@@ -0,0 +1,65 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package verification
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/lowpriority"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+// Artifact is a downloaded file to verify
+type Artifact struct {
+	// SourceURL is the location the file was downloaded from, its detached signature being next to it
+	SourceURL string
+	// LocalPath is the path of the downloaded file
+	LocalPath string
+	// Checksums are the expected checksums of the file keyed by algorithm
+	Checksums map[string]string
+	// Signed tells the artifact is expected to be signed whatever the policy, as the agent update packages are,
+	// the signature being verified whenever a signing key is pinned
+	Signed bool
+}
+
+// verifySignature verifies the artifact against its detached signature, failing when no signing key is pinned
+var verifySignature = updateutil.RequirePackageSignature
+
+// verifySignatureIfPinned verifies the artifact against its detached signature when a signing key is pinned
+var verifySignatureIfPinned = updateutil.VerifyPackageSignature
+
+// Verify verifies the downloaded artifact as the policy requires. The checksums of the artifact are verified
+// whenever they are known, while signature failures are returned as *updateutil.SignatureError.
+func Verify(log log.T, policy Policy, artifact Artifact) error {
+	var verified bool
+	var err error
+	lowpriority.Run(log, func() {
+		verified, err = VerifyChecksums(artifact.LocalPath, artifact.Checksums)
+	})
+	if err != nil {
+		return err
+	}
+	if !verified && policy.RequiresChecksum() {
+		return fmt.Errorf("verification policy %v requires the checksum of %v", policy, artifact.LocalPath)
+	}
+
+	if policy.RequiresSignature() {
+		return verifySignature(log, artifact.SourceURL, artifact.LocalPath)
+	}
+	if artifact.Signed {
+		return verifySignatureIfPinned(log, artifact.SourceURL, artifact.LocalPath)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package verification
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const artifactContent = "artifact content"
+
+// verificationTest holds a downloaded artifact and the signature verifications it went through
+type verificationTest struct {
+	dir      string
+	artifact Artifact
+	required []string
+	ifPinned []string
+}
+
+func newVerificationTest(t *testing.T) *verificationTest {
+	dir, err := ioutil.TempDir("", "verification")
+	assert.NoError(t, err)
+	path := filepath.Join(dir, "artifact.zip")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(artifactContent), 0600))
+
+	test := &verificationTest{
+		dir:      dir,
+		artifact: Artifact{SourceURL: "https://bucket/artifact.zip", LocalPath: path},
+	}
+	verifySignature = func(log log.T, sourceURL string, packagePath string) error {
+		test.required = append(test.required, sourceURL)
+		return nil
+	}
+	verifySignatureIfPinned = func(log log.T, sourceURL string, packagePath string) error {
+		test.ifPinned = append(test.ifPinned, sourceURL)
+		return nil
+	}
+	return test
+}
+
+func (test *verificationTest) cleanup() {
+	verifySignature = updateutil.RequirePackageSignature
+	verifySignatureIfPinned = updateutil.VerifyPackageSignature
+	os.RemoveAll(test.dir)
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	test := newVerificationTest(t)
+	defer test.cleanup()
+	path := test.artifact.LocalPath
+
+	verified, err := VerifyChecksums(path, map[string]string{"SHA256": sha256Hex(artifactContent)})
+	assert.NoError(t, err)
+	assert.True(t, verified)
+
+	// an empty algorithm is sha256
+	verified, err = VerifyChecksums(path, map[string]string{"": sha256Hex(artifactContent)})
+	assert.NoError(t, err)
+	assert.True(t, verified)
+
+	// pairs without a value aren't checksums
+	verified, err = VerifyChecksums(path, map[string]string{"sha256": ""})
+	assert.NoError(t, err)
+	assert.False(t, verified)
+
+	_, err = VerifyChecksums(path, map[string]string{"sha256": sha256Hex("other content")})
+	assert.Error(t, err)
+
+	_, err = VerifyChecksums(path, map[string]string{"crc32": "abc"})
+	assert.Error(t, err)
+
+	// unsupported algorithms are ignored when another checksum is verified
+	verified, err = VerifyChecksums(path, map[string]string{"crc32": "abc", "sha256": sha256Hex(artifactContent)})
+	assert.NoError(t, err)
+	assert.True(t, verified)
+}
+
+func TestRegisterChecksumProvider(t *testing.T) {
+	test := newVerificationTest(t)
+	defer test.cleanup()
+	assert.Nil(t, NewHash("sha256-custom"))
+
+	RegisterChecksumProvider("SHA256-Custom", func() hash.Hash { return sha256.New() })
+
+	assert.NotNil(t, NewHash("sha256-custom"))
+	verified, err := VerifyChecksums(test.artifact.LocalPath, map[string]string{"sha256-custom": sha256Hex(artifactContent)})
+	assert.NoError(t, err)
+	assert.True(t, verified)
+}
+
+func TestVerifyWithoutChecksum(t *testing.T) {
+	test := newVerificationTest(t)
+	defer test.cleanup()
+
+	assert.NoError(t, Verify(log.NewMockLog(), PolicyNone, test.artifact))
+	assert.NoError(t, Verify(log.NewMockLog(), PolicySignature, test.artifact))
+	assert.Error(t, Verify(log.NewMockLog(), PolicyChecksum, test.artifact))
+	assert.Error(t, Verify(log.NewMockLog(), PolicyBoth, test.artifact))
+}
+
+func TestVerifyChecksumMismatchFailsWhateverThePolicy(t *testing.T) {
+	test := newVerificationTest(t)
+	defer test.cleanup()
+	test.artifact.Checksums = map[string]string{"sha256": sha256Hex("other content")}
+
+	assert.Error(t, Verify(log.NewMockLog(), PolicyNone, test.artifact))
+	assert.Empty(t, test.required)
+}
+
+func TestVerifySignature(t *testing.T) {
+	test := newVerificationTest(t)
+	defer test.cleanup()
+	test.artifact.Checksums = map[string]string{"sha256": sha256Hex(artifactContent)}
+
+	assert.NoError(t, Verify(log.NewMockLog(), PolicyChecksum, test.artifact))
+	assert.Empty(t, test.required)
+
+	assert.NoError(t, Verify(log.NewMockLog(), PolicyBoth, test.artifact))
+	assert.Equal(t, []string{test.artifact.SourceURL}, test.required)
+
+	verifySignature = func(log log.T, sourceURL string, packagePath string) error {
+		return &updateutil.SignatureError{PackagePath: packagePath, Reason: errors.New("signature mismatch")}
+	}
+	err := Verify(log.NewMockLog(), PolicySignature, test.artifact)
+	assert.IsType(t, &updateutil.SignatureError{}, err)
+}
+
+func TestVerifySignedArtifact(t *testing.T) {
+	test := newVerificationTest(t)
+	defer test.cleanup()
+	test.artifact.Signed = true
+
+	// signed artifacts are verified whenever a key is pinned, the policy deciding if a key has to be pinned
+	assert.NoError(t, Verify(log.NewMockLog(), PolicyNone, test.artifact))
+	assert.Equal(t, []string{test.artifact.SourceURL}, test.ifPinned)
+	assert.Empty(t, test.required)
+
+	assert.NoError(t, Verify(log.NewMockLog(), PolicySignature, test.artifact))
+	assert.Equal(t, []string{test.artifact.SourceURL}, test.required)
+	assert.Len(t, test.ifPinned, 1)
+}
@@ -17,7 +17,9 @@ package websocketutil
 import (
 	"errors"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/gorilla/websocket"
 )
 
@@ -39,8 +41,11 @@ func NewWebsocketUtil(logger log.T, dialerInput *websocket.Dialer) *WebsocketUti
 	var websocketUtil *WebsocketUtil
 
 	if dialerInput == nil {
+		dialer := *websocket.DefaultDialer
+		dialer.Proxy = proxyconfig.ServiceProxyFunc(appconfig.ServiceNameSsmMessages)
+		dialer.TLSClientConfig = proxyconfig.AgentTLSConfig()
 		websocketUtil = &WebsocketUtil{
-			dialer: websocket.DefaultDialer,
+			dialer: &dialer,
 			log:    logger,
 		}
 	} else {
@@ -66,6 +66,9 @@ if [ $1 -eq 0 ] ; then
 fi
 
 %posttrans
+# Record the hashes of the installed binaries for the agent integrity check
+/usr/bin/amazon-ssm-agent -integrityManifest || true
+
 # Start the agent after initial install or upgrade
 if [ $1 -ge 0 ]; then
     /sbin/init --version &> stdout.txt